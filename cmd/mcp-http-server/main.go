@@ -7,8 +7,14 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	contextpkg "github.com/rcliao/teeny-orb/internal/context"
+	"github.com/rcliao/teeny-orb/internal/mcp/metrics"
 	"github.com/rcliao/teeny-orb/internal/mcp/security"
 	"github.com/rcliao/teeny-orb/internal/mcp/server"
 	"github.com/rcliao/teeny-orb/internal/mcp/tools"
@@ -17,11 +23,27 @@ import (
 
 func main() {
 	var (
-		port    = flag.String("port", "8080", "HTTP server port")
-		host    = flag.String("host", "localhost", "HTTP server host")
-		name    = flag.String("name", "teeny-orb-mcp-http-server", "Server name")
-		version = flag.String("version", "0.1.0", "Server version")
-		debug   = flag.Bool("debug", false, "Enable debug logging")
+		port        = flag.String("port", "8080", "HTTP server port")
+		host        = flag.String("host", "localhost", "HTTP server host")
+		name        = flag.String("name", "teeny-orb-mcp-http-server", "Server name")
+		version     = flag.String("version", "0.1.0", "Server version")
+		debug       = flag.Bool("debug", false, "Enable debug logging")
+		redactPaths = flag.Bool("redact-paths", true, "Rewrite absolute workspace paths to relative in tool output and audit logs")
+		restTools   = flag.Bool("rest-tools", false, "Serve GET /tools and POST /tools/{name} alongside JSON-RPC; with -sessions, calls must send the Mcp-Session-Id of an initialized session")
+		withMetrics = flag.Bool("metrics", true, "Serve Prometheus metrics at GET /metrics")
+		warmup      = flag.Bool("warmup", false, "Analyze the workspace on startup; /ready reports 503 until done")
+		sessions    = flag.Bool("sessions", true, "Give each client its own MCP session, security validator, and audit trail")
+		sessionRoot = flag.String("session-root", "", "With -sessions, give each session a scratch directory under this path, removed when the session ends")
+		sessionIdle = flag.Duration("session-idle-timeout", 30*time.Minute, "With -sessions, end sessions that send no requests for this long. 0 keeps them until DELETE")
+		maxSessions = flag.Int("max-sessions", 100, "With -sessions, refuse to initialize more sessions than this once none are idle. 0 for no limit")
+		requireCmds = flag.String("require-commands", "", "Comma-separated executables the command tool needs on PATH before /ready passes, e.g. git,go")
+		auditFile   = flag.String("audit-file", "", "Append every audited operation from all sessions to this file as JSON lines")
+		auditMaxMB  = flag.Int("audit-max-mb", 100, "Rotate the audit file when it reaches this many megabytes")
+		auditKeep   = flag.Int("audit-keep", 5, "Number of rotated audit files to keep")
+		auditGzip   = flag.Bool("audit-compress", false, "Gzip rotated audit files")
+		commandRate = flag.Int("commands-per-minute", 0, "Limit each session, and tool calls outside sessions, to this many command executions per minute. 0 for no limit")
+		fileOpRate  = flag.Int("file-ops-per-minute", 0, "Limit each session, and tool calls outside sessions, to this many file operations per minute. 0 for no limit")
+		symlinks    = flag.String("symlinks", string(security.SymlinkPolicyDenyOutside), "Symlinks the filesystem tool follows: deny_outside, deny (also hard-linked files) or allow")
 	)
 	flag.Parse()
 
@@ -36,18 +58,93 @@ func main() {
 	// Create MCP server
 	mcpServer := server.NewServer(*name, *version)
 
+	// Set up metrics
+	transportConfig := &transport.HTTPTransportConfig{
+		EnableToolEndpoints: *restTools,
+		Sessions:            *sessions,
+	}
+	var serverMetrics *metrics.Metrics
+	if *withMetrics {
+		var err error
+		serverMetrics, err = metrics.New(prometheus.NewRegistry())
+		if err != nil {
+			log.Fatalf("Failed to set up metrics: %v", err)
+		}
+		mcpServer.SetMetrics(serverMetrics)
+		transportConfig.MetricsHandler = serverMetrics.Handler()
+	}
+
+	// Every session's validator shares one audit file
+	var auditSink security.AuditSink
+	if *auditFile != "" {
+		fileSink, err := security.NewFileAuditSink(&security.AuditFileConfig{
+			Path:       *auditFile,
+			MaxBytes:   int64(*auditMaxMB) * 1024 * 1024,
+			MaxBackups: *auditKeep,
+			Compress:   *auditGzip,
+		})
+		if err != nil {
+			log.Fatalf("Failed to open audit file: %v", err)
+		}
+		defer fileSink.Close()
+		auditSink = fileSink
+	}
+
+	symlinkPolicy, err := security.ParseSymlinkPolicy(*symlinks)
+	if err != nil {
+		log.Fatalf("Invalid -symlinks: %v", err)
+	}
+
 	// Register tools
-	if err := registerTools(mcpServer, *debug); err != nil {
+	workDir := workspaceDir()
+	rateLimits := security.RateLimits{CommandsPerMinute: *commandRate, FileOperationsPerMinute: *fileOpRate}
+	validator, err := registerTools(mcpServer, workDir, splitList(*requireCmds), *debug, *redactPaths, symlinkPolicy, rateLimits, serverMetrics, auditSink)
+	if err != nil {
 		log.Fatalf("Failed to register tools: %v", err)
 	}
+	if *sessions {
+		mcpServer.SetWorkspaceFactory(sessionWorkspaces(workDir, *sessionRoot, *redactPaths, rateLimits, serverMetrics, auditSink))
+		mcpServer.SetSessionLimits(*sessionIdle, *maxSessions)
+	}
+
+	// Context tools share one analyzer so warmup and repeat requests reuse analyses
+	analyzer := contextpkg.NewCachingAnalyzer(contextpkg.NewDefaultAnalyzer(contextpkg.NewSimpleTokenCounter(), nil), 0)
+	optimizer := contextpkg.NewDefaultOptimizer(analyzer, contextpkg.NewInMemoryContextCache(nil), nil, nil)
+	if err := registerContextTools(mcpServer, analyzer, optimizer, workDir, validator, *redactPaths); err != nil {
+		log.Fatalf("Failed to register context tools: %v", err)
+	}
+	if *debug {
+		logRegisteredTools(mcpServer)
+	}
+
+	var warmer *contextpkg.Warmer
+	if *warmup {
+		warmer = contextpkg.NewWarmer(analyzer, &contextpkg.WarmupConfig{RootPath: workDir})
+		transportConfig.Ready = warmer.Ready
+	}
 
 	// Create HTTP transport
 	addr := fmt.Sprintf("%s:%s", *host, *port)
-	httpTransport := transport.NewHTTPTransport(addr, mcpServer, *debug)
+	httpTransport := transport.NewHTTPTransportWithConfig(addr, mcpServer, *debug, transportConfig)
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	go mcpServer.RunSessionReaper(ctx)
+
+	// Warm up in the background so the server can report progress on /readyz
+	if warmer != nil {
+		go func() {
+			result, err := warmer.Run(ctx)
+			if err != nil {
+				log.Printf("Warmup failed, continuing without it: %v", err)
+				return
+			}
+			if *debug {
+				log.Printf("Warmup finished in %v (%d files)", result.Duration, result.Project.TotalFiles)
+			}
+		}()
+	}
 
 	// Handle signals for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -63,8 +160,16 @@ func main() {
 	// Start HTTP server
 	fmt.Printf("🚀 MCP HTTP Server starting on http://%s\n", addr)
 	fmt.Printf("📡 MCP endpoint: http://%s/mcp\n", addr)
+	fmt.Printf("📶 Streaming (SSE): http://%s/mcp/stream\n", addr)
 	fmt.Printf("💚 Health check: http://%s/health\n", addr)
+	fmt.Printf("🚦 Readiness: http://%s/ready\n", addr)
 	fmt.Printf("📊 Status info: http://%s/status\n", addr)
+	if *restTools {
+		fmt.Printf("🔧 REST tools: http://%s/tools\n", addr)
+	}
+	if *withMetrics {
+		fmt.Printf("📈 Metrics: http://%s/metrics\n", addr)
+	}
 	fmt.Println()
 
 	if err := httpTransport.Start(ctx); err != nil {
@@ -76,9 +181,9 @@ func main() {
 	}
 }
 
-// registerTools registers all available tools with the server
-func registerTools(server *server.Server, debug bool) error {
-	// Get working directory - check environment variable first, then current directory
+// workspaceDir returns the workspace the tools operate on
+func workspaceDir() string {
+	// Check environment variable first, then current directory
 	workDir := os.Getenv("WORKSPACE_PATH")
 	if workDir == "" {
 		var err error
@@ -87,13 +192,86 @@ func registerTools(server *server.Server, debug bool) error {
 			workDir = "."
 		}
 	}
+	return workDir
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
 
+// registerTools registers the filesystem, command, and refactor tools with the
+// server, along with readiness probes for the workspace and security policy
+func registerTools(mcpServer *server.Server, workDir string, requiredCommands []string, debug, redactPaths bool, symlinkPolicy security.SymlinkPolicy, rateLimits security.RateLimits, serverMetrics *metrics.Metrics, auditSink security.AuditSink) (*security.SecurityValidator, error) {
 	if debug {
 		log.Printf("Setting up tools with working directory: %s", workDir)
 	}
 
-	// Create security policy - permissive for development but with key restrictions
-	policy := &security.SecurityPolicy{
+	// Create security validator
+	validator := security.NewSecurityValidator(workspacePolicy(workDir, rateLimits), "mcp-http-server", "main-session")
+	configureValidator(validator, redactPaths, serverMetrics, auditSink)
+
+	mcpServer.AddReadinessProbe("workspace", server.WritableDirProbe(workDir))
+	mcpServer.AddReadinessProbe("security_policy", func(ctx context.Context) error {
+		return validator.CheckPolicy()
+	})
+
+	// Register real filesystem tool with security
+	fsTools := tools.NewRealFileSystemTool(workDir, validator)
+	fsTools.SetPathRedaction(redactPaths)
+	fsTools.SetSymlinkPolicy(symlinkPolicy)
+	if err := mcpServer.RegisterTool(fsTools); err != nil {
+		return nil, fmt.Errorf("failed to register filesystem tool: %w", err)
+	}
+
+	// Register real command tool with security
+	cmdTool := tools.NewRealCommandTool(validator, workDir)
+	cmdTool.SetPathRedaction(redactPaths)
+	cmdTool.SetRequiredCommands(requiredCommands)
+	if err := mcpServer.RegisterTool(cmdTool); err != nil {
+		return nil, fmt.Errorf("failed to register command tool: %w", err)
+	}
+
+	// Register transactional multi-file refactor tool
+	refactorTool := tools.NewRefactorTool(workDir, validator)
+	refactorTool.SetPathRedaction(redactPaths)
+	refactorTool.SetSymlinkPolicy(symlinkPolicy)
+	if err := mcpServer.RegisterTool(refactorTool); err != nil {
+		return nil, fmt.Errorf("failed to register refactor tool: %w", err)
+	}
+
+	// Register all-or-nothing multi-file edit tool
+	applyEditsTool := tools.NewApplyEditsTool(workDir, validator)
+	applyEditsTool.SetPathRedaction(redactPaths)
+	applyEditsTool.SetSymlinkPolicy(symlinkPolicy)
+	if err := mcpServer.RegisterTool(applyEditsTool); err != nil {
+		return nil, fmt.Errorf("failed to register apply edits tool: %w", err)
+	}
+
+	return validator, nil
+}
+
+// logRegisteredTools logs the name of every registered tool
+func logRegisteredTools(mcpServer *server.Server) {
+	registered := mcpServer.ListRegisteredTools()
+	names := make([]string, 0, len(registered))
+	for _, tool := range registered {
+		names = append(names, tool.Name)
+	}
+	log.Printf("Registered %d tools: %s", len(names), strings.Join(names, ", "))
+}
+
+// workspacePolicy is the security policy for tools working in workDir:
+// permissive for development but with key restrictions, rate limited as
+// configured
+func workspacePolicy(workDir string, rateLimits security.RateLimits) *security.SecurityPolicy {
+	return &security.SecurityPolicy{
 		AllowedPermissions: []security.Permission{
 			security.PermissionReadFile,
 			security.PermissionWriteFile,
@@ -133,26 +311,79 @@ func registerTools(server *server.Server, debug bool) error {
 			MaxExecutionSec: 300, // 5 minutes for longer operations
 			MaxFileSize:     50 * 1024 * 1024, // 50MB
 		},
-		AuditLog: true,
+		RateLimits: rateLimits,
+		AuditLog:   true,
+	}
+}
+
+// configureValidator applies the redaction, metrics, and audit settings to a validator
+func configureValidator(validator *security.SecurityValidator, redactPaths bool, serverMetrics *metrics.Metrics, auditSink security.AuditSink) {
+	if !redactPaths {
+		validator.SetPathRedactor(nil)
 	}
+	if serverMetrics != nil {
+		validator.SetDenialObserver(serverMetrics.ObserveDenial)
+	}
+	validator.SetAuditSink(auditSink)
+}
 
-	// Create security validator
-	validator := security.NewSecurityValidator(policy, "mcp-http-server", "main-session")
+// sessionWorkspaces creates each session's workspace: workDir with a
+// validator of its own, or a scratch directory under sessionRoot when set
+func sessionWorkspaces(workDir, sessionRoot string, redactPaths bool, rateLimits security.RateLimits, serverMetrics *metrics.Metrics, auditSink security.AuditSink) server.WorkspaceFactory {
+	return func(sessionID string) (*security.Workspace, error) {
+		var workspace *security.Workspace
+		if sessionRoot == "" {
+			workspace = security.NewWorkspace(workspacePolicy(workDir, rateLimits), "mcp-http-server", sessionID, workDir)
+		} else {
+			if sessionID == "" || filepath.Base(sessionID) != sessionID || sessionID == ".." {
+				return nil, fmt.Errorf("invalid session ID %q", sessionID)
+			}
+			root, err := filepath.Abs(filepath.Join(sessionRoot, sessionID))
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve session directory: %w", err)
+			}
+			if workspace, err = security.NewScratchWorkspace(workspacePolicy(root, rateLimits), "mcp-http-server", sessionID, root); err != nil {
+				return nil, err
+			}
+		}
+		configureValidator(workspace.Validator, redactPaths, serverMetrics, auditSink)
+		return workspace, nil
+	}
+}
 
-	// Register real filesystem tool with security
-	fsTools := tools.NewRealFileSystemTool(workDir, validator)
-	if err := server.RegisterTool(fsTools); err != nil {
-		return fmt.Errorf("failed to register filesystem tool: %w", err)
+// registerContextTools registers the context analysis, token counting,
+// context optimization, and dependencies tools with the server. Like the
+// other tools they only read within workDir, checked by validator, or the
+// session's workspace. Nothing watches the workspace for changes, so they
+// re-read it on every request, reusing cached work for unchanged files.
+func registerContextTools(server *server.Server, analyzer *contextpkg.CachingAnalyzer, optimizer contextpkg.ContextOptimizer, workDir string, validator *security.SecurityValidator, redactPaths bool) error {
+	current := analyzer.Revalidating()
+
+	contextAnalysisTool := tools.NewContextAnalysisHandler(current)
+	contextAnalysisTool.SetPathRedaction(redactPaths)
+	contextAnalysisTool.SetWorkspace(workDir, validator)
+	if err := server.RegisterTool(contextAnalysisTool); err != nil {
+		return fmt.Errorf("failed to register context analysis tool: %w", err)
 	}
 
-	// Register real command tool with security
-	cmdTool := tools.NewRealCommandTool(validator, workDir)
-	if err := server.RegisterTool(cmdTool); err != nil {
-		return fmt.Errorf("failed to register command tool: %w", err)
+	tokenCountTool := tools.NewTokenCountHandler(current)
+	tokenCountTool.SetWorkspace(workDir, validator)
+	if err := server.RegisterTool(tokenCountTool); err != nil {
+		return fmt.Errorf("failed to register token count tool: %w", err)
 	}
 
-	if debug {
-		log.Printf("Successfully registered %d tools", 2)
+	contextOptimizationTool := tools.NewContextOptimizationHandler(optimizer, current)
+	contextOptimizationTool.SetPathRedaction(redactPaths)
+	contextOptimizationTool.SetWorkspace(workDir, validator)
+	if err := server.RegisterTool(contextOptimizationTool); err != nil {
+		return fmt.Errorf("failed to register context optimization tool: %w", err)
+	}
+
+	dependenciesTool := tools.NewDependenciesHandler(analyzer)
+	dependenciesTool.SetPathRedaction(redactPaths)
+	dependenciesTool.SetWorkspace(workDir, validator)
+	if err := server.RegisterTool(dependenciesTool); err != nil {
+		return fmt.Errorf("failed to register dependencies tool: %w", err)
 	}
 
 	return nil
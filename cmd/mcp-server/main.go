@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
 	contextpkg "github.com/rcliao/teeny-orb/internal/context"
 	"github.com/rcliao/teeny-orb/internal/mcp"
@@ -20,9 +24,22 @@ import (
 
 func main() {
 	var (
-		name    = flag.String("name", "teeny-orb-mcp-server", "Server name")
-		version = flag.String("version", "0.1.0", "Server version")
-		debug   = flag.Bool("debug", false, "Enable debug logging")
+		name          = flag.String("name", "teeny-orb-mcp-server", "Server name")
+		version       = flag.String("version", "0.1.0", "Server version")
+		debug         = flag.Bool("debug", false, "Enable debug logging")
+		redactPaths   = flag.Bool("redact-paths", true, "Rewrite absolute workspace paths to relative in tool output and audit logs")
+		watch         = flag.Bool("watch", false, "Re-analyze the workspace when its files change and notify the client; costly on large trees")
+		debounce      = flag.Duration("watch-debounce", 500*time.Millisecond, "Quiet period after the last file change before re-analyzing")
+		notifyDenials = flag.Bool("notify-denials", false, "Send a notifications/security/denied notification whenever a security check blocks an operation")
+		auditFile     = flag.String("audit-file", "", "Append every audited operation to this file as JSON lines")
+		auditMaxMB    = flag.Int("audit-max-mb", 100, "Rotate the audit file when it reaches this many megabytes")
+		auditKeep     = flag.Int("audit-keep", 5, "Number of rotated audit files to keep")
+		auditGzip     = flag.Bool("audit-compress", false, "Gzip rotated audit files")
+		snapshotFile  = flag.String("analysis-snapshot", "", "Load the workspace analysis from this file at startup, re-analyzing only what changed, and save it on shutdown")
+		symlinks      = flag.String("symlinks", string(security.SymlinkPolicyDenyOutside), "Symlinks the filesystem tool follows: deny_outside, deny (also hard-linked files) or allow")
+		commandRate   = flag.Int("commands-per-minute", 0, "Limit command executions to this many per minute. 0 for no limit")
+		fileOpRate    = flag.Int("file-ops-per-minute", 0, "Limit file operations to this many per minute. 0 for no limit")
+		toolCacheTTL  = flag.Duration("tool-cache-ttl", 0, "Answer repeated file reads and listings from a cache for this long; any other tool call or file change empties it. Requires -watch. Hits are still checked against the security policy and rate limits. 0 disables")
 	)
 	flag.Parse()
 
@@ -34,13 +51,71 @@ func main() {
 		log.SetOutput(io.Discard)
 	}
 
+	// Only watching notices edits made outside the server
+	if *toolCacheTTL > 0 && !*watch {
+		log.Fatalf("-tool-cache-ttl requires -watch")
+	}
+
 	// Create MCP server
 	mcpServer := server.NewServer(*name, *version)
+	mcpServer.SetToolCacheTTL(*toolCacheTTL)
+
+	workDir := workspaceDir()
+	// Context tools share one analyzer, so the dependencies tool can answer
+	// from the analysis analyze_context produced
+	analyzer := contextpkg.NewCachingAnalyzer(contextpkg.NewDefaultAnalyzer(contextpkg.NewSimpleTokenCounter(), nil), 0)
+	if *snapshotFile != "" {
+		restoreAnalysis(analyzer, *snapshotFile, workDir)
+		defer saveAnalysis(analyzer, *snapshotFile, workDir)
+	}
+
+	// Watching keeps the cached analysis current, so tools see edits without
+	// a full re-analysis per request
+	var watcher *contextpkg.ProjectWatcher
+	if *watch {
+		var err error
+		watcher, err = contextpkg.NewProjectWatcher(analyzer, workDir, &contextpkg.WatcherConfig{
+			Debounce:   *debounce,
+			IgnoreDirs: []string{".git", "node_modules", "vendor", "build", "dist"},
+		})
+		if err != nil {
+			log.Fatalf("Failed to watch workspace: %v", err)
+		}
+		mcpServer.EnableResourceNotifications()
+	}
+
+	var denialSink, fileSink security.AuditSink
+	if *notifyDenials {
+		denialSink = mcpServer.DenialNotifier()
+	}
+	if *auditFile != "" {
+		sink, err := security.NewFileAuditSink(&security.AuditFileConfig{
+			Path:       *auditFile,
+			MaxBytes:   int64(*auditMaxMB) * 1024 * 1024,
+			MaxBackups: *auditKeep,
+			Compress:   *auditGzip,
+		})
+		if err != nil {
+			log.Fatalf("Failed to open audit file: %v", err)
+		}
+		defer sink.Close()
+		fileSink = sink
+	}
+	auditSink := security.MultiAuditSink(denialSink, fileSink)
+
+	symlinkPolicy, err := security.ParseSymlinkPolicy(*symlinks)
+	if err != nil {
+		log.Fatalf("Invalid -symlinks: %v", err)
+	}
 
 	// Register tools
-	if err := registerTools(mcpServer); err != nil {
+	rateLimits := security.RateLimits{CommandsPerMinute: *commandRate, FileOperationsPerMinute: *fileOpRate}
+	if err := registerTools(mcpServer, workDir, analyzer, auditSink, *redactPaths, symlinkPolicy, rateLimits); err != nil {
 		log.Fatalf("Failed to register tools: %v", err)
 	}
+	if *debug {
+		logRegisteredTools(mcpServer)
+	}
 
 	// Create stdio transport
 	transport := transport.NewStdioTransport()
@@ -61,6 +136,25 @@ func main() {
 		cancel()
 	}()
 
+	mcpServer.SetNotificationSender(transport.Send)
+	if watcher != nil {
+		// Drop cached reads when a file changes, not once re-analysis is done
+		watcher.OnEvent(func(path string) {
+			mcpServer.InvalidateToolCache()
+		})
+		watcher.OnChange(func(ctx context.Context, change contextpkg.ProjectChange) {
+			if change.Err != nil {
+				log.Printf("Watch: %v", change.Err)
+			} else if *debug {
+				log.Printf("Watch: re-analyzed after %d changes", len(change.Paths))
+			}
+			if err := mcpServer.Notify(ctx, "notifications/resources/list_changed", nil); err != nil {
+				log.Printf("Watch: %v", err)
+			}
+		})
+		go watcher.Run(ctx)
+	}
+
 	// Run server
 	if err := runServer(ctx, mcpServer, transport, *debug); err != nil {
 		log.Fatalf("Server error: %v", err)
@@ -71,9 +165,48 @@ func main() {
 	}
 }
 
-// registerTools registers all available tools with the server
-func registerTools(server *server.Server) error {
-	// Get working directory - check environment variable first, then current directory
+// restoreAnalysis caches the saved analysis of workDir, if there is one.
+// Failures only cost the startup time the snapshot would have saved.
+func restoreAnalysis(analyzer *contextpkg.CachingAnalyzer, snapshotFile, workDir string) {
+	snapshot, err := contextpkg.LoadProjectContext(snapshotFile)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Ignoring analysis snapshot: %v", err)
+		}
+		return
+	}
+	root, _ := filepath.Abs(snapshot.Project.RootPath)
+	if dir, _ := filepath.Abs(workDir); root != dir {
+		log.Printf("Ignoring analysis snapshot of %s", snapshot.Project.RootPath)
+		return
+	}
+	project, err := analyzer.Restore(context.Background(), snapshot)
+	if err != nil {
+		log.Printf("Failed to restore analysis snapshot: %v", err)
+		return
+	}
+	// Save the patched analysis now, since the cached one may have expired
+	// by shutdown
+	if project != snapshot.Project {
+		saveAnalysis(analyzer, snapshotFile, workDir)
+	}
+}
+
+// saveAnalysis writes the cached analysis of workDir, if any, for the next
+// start
+func saveAnalysis(analyzer *contextpkg.CachingAnalyzer, snapshotFile, workDir string) {
+	project, ok := analyzer.Cached(workDir)
+	if !ok {
+		return
+	}
+	if err := contextpkg.SaveProjectContext(snapshotFile, project); err != nil {
+		log.Printf("Failed to save analysis snapshot: %v", err)
+	}
+}
+
+// workspaceDir returns the directory tools work in: WORKSPACE_PATH, else the
+// current directory
+func workspaceDir() string {
 	workDir := os.Getenv("WORKSPACE_PATH")
 	if workDir == "" {
 		var err error
@@ -82,7 +215,11 @@ func registerTools(server *server.Server) error {
 			workDir = "."
 		}
 	}
+	return workDir
+}
 
+// registerTools registers all available tools with the server
+func registerTools(server *server.Server, workDir string, analyzer *contextpkg.CachingAnalyzer, auditSink security.AuditSink, redactPaths bool, symlinkPolicy security.SymlinkPolicy, rateLimits security.RateLimits) error {
 	// Create security policy - permissive for demo but with some restrictions
 	policy := &security.SecurityPolicy{
 		AllowedPermissions: []security.Permission{
@@ -116,30 +253,51 @@ func registerTools(server *server.Server) error {
 			MaxExecutionSec: 60,
 			MaxFileSize:     10 * 1024 * 1024, // 10MB
 		},
-		AuditLog: true,
+		RateLimits: rateLimits,
+		AuditLog:   true,
 	}
 
 	// Create security validator
 	validator := security.NewSecurityValidator(policy, "mcp-server", "main-session")
+	if !redactPaths {
+		validator.SetPathRedactor(nil)
+	}
+	validator.SetAuditSink(auditSink)
 
 	// Register real filesystem tool with security
 	fsTools := tools.NewRealFileSystemTool(workDir, validator)
+	fsTools.SetPathRedaction(redactPaths)
+	fsTools.SetSymlinkPolicy(symlinkPolicy)
 	if err := server.RegisterTool(fsTools); err != nil {
 		return fmt.Errorf("failed to register filesystem tool: %w", err)
 	}
 
 	// Register real command tool with security
 	cmdTool := tools.NewRealCommandTool(validator, workDir)
+	cmdTool.SetPathRedaction(redactPaths)
 	if err := server.RegisterTool(cmdTool); err != nil {
 		return fmt.Errorf("failed to register command tool: %w", err)
 	}
 
-	// Create context analysis tools
-	tokenCounter := contextpkg.NewSimpleTokenCounter()
-	analyzer := contextpkg.NewDefaultAnalyzer(tokenCounter, nil)
-	
+	// Register transactional multi-file refactor tool
+	refactorTool := tools.NewRefactorTool(workDir, validator)
+	refactorTool.SetPathRedaction(redactPaths)
+	refactorTool.SetSymlinkPolicy(symlinkPolicy)
+	if err := server.RegisterTool(refactorTool); err != nil {
+		return fmt.Errorf("failed to register refactor tool: %w", err)
+	}
+
+	// Register all-or-nothing multi-file edit tool
+	applyEditsTool := tools.NewApplyEditsTool(workDir, validator)
+	applyEditsTool.SetPathRedaction(redactPaths)
+	applyEditsTool.SetSymlinkPolicy(symlinkPolicy)
+	if err := server.RegisterTool(applyEditsTool); err != nil {
+		return fmt.Errorf("failed to register apply edits tool: %w", err)
+	}
+
 	// Register context analysis tool
 	contextAnalysisTool := tools.NewContextAnalysisHandler(analyzer)
+	contextAnalysisTool.SetPathRedaction(redactPaths)
 	if err := server.RegisterTool(contextAnalysisTool); err != nil {
 		return fmt.Errorf("failed to register context analysis tool: %w", err)
 	}
@@ -153,13 +311,31 @@ func registerTools(server *server.Server) error {
 	// Create and register context optimization tool
 	optimizer := contextpkg.NewDefaultOptimizer(analyzer, nil, nil, nil)
 	contextOptimizationTool := tools.NewContextOptimizationHandler(optimizer, analyzer)
+	contextOptimizationTool.SetPathRedaction(redactPaths)
 	if err := server.RegisterTool(contextOptimizationTool); err != nil {
 		return fmt.Errorf("failed to register context optimization tool: %w", err)
 	}
 
+	// Register dependencies tool
+	dependenciesTool := tools.NewDependenciesHandler(analyzer)
+	dependenciesTool.SetPathRedaction(redactPaths)
+	if err := server.RegisterTool(dependenciesTool); err != nil {
+		return fmt.Errorf("failed to register dependencies tool: %w", err)
+	}
+
 	return nil
 }
 
+// logRegisteredTools logs the name of every registered tool
+func logRegisteredTools(mcpServer *server.Server) {
+	registered := mcpServer.ListRegisteredTools()
+	names := make([]string, 0, len(registered))
+	for _, tool := range registered {
+		names = append(names, tool.Name)
+	}
+	log.Printf("Registered %d tools: %s", len(names), strings.Join(names, ", "))
+}
+
 // runServer runs the MCP server with the given transport
 func runServer(ctx context.Context, server *server.Server, transport mcp.Transport, debug bool) error {
 	for {
@@ -169,8 +345,15 @@ func runServer(ctx context.Context, server *server.Server, transport mcp.Transpo
 		default:
 		}
 
-		// Receive message
-		msg, err := transport.Receive(ctx)
+		// Receive message, or a batch when the transport carries them
+		var msg *mcp.Message
+		var batch mcp.Batch
+		var err error
+		if batchTransport, ok := transport.(mcp.BatchTransport); ok {
+			msg, batch, err = batchTransport.ReceiveFrame(ctx)
+		} else {
+			msg, err = transport.Receive(ctx)
+		}
 		if err != nil {
 			if err == io.EOF {
 				if debug {
@@ -181,6 +364,19 @@ func runServer(ctx context.Context, server *server.Server, transport mcp.Transpo
 			return fmt.Errorf("failed to receive message: %w", err)
 		}
 
+		if batch != nil {
+			if debug {
+				log.Printf("Received batch of %d messages", len(batch))
+			}
+			// A batch of only notifications gets no response
+			if responses := server.HandleBatch(ctx, batch); len(responses) > 0 {
+				if err := transport.(mcp.BatchTransport).SendBatch(ctx, responses); err != nil {
+					return fmt.Errorf("failed to send batch response: %w", err)
+				}
+			}
+			continue
+		}
+
 		if debug {
 			log.Printf("Received: %s %v", msg.Method, msg.ID)
 		}
@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/rcliao/teeny-orb/experiments/framework"
+	"github.com/rcliao/teeny-orb/internal/mcp/tools"
 	"github.com/rcliao/teeny-orb/internal/providers"
+	"github.com/rcliao/teeny-orb/internal/providers/bridge"
 	"github.com/rcliao/teeny-orb/internal/providers/direct"
 )
 
@@ -29,7 +31,7 @@ func (e *Week1Experiment) RunDirectBaseline(ctx context.Context) (*framework.Met
 	collector := framework.NewMetricsCollector()
 	
 	// Register tools
-	fsTools := providers.NewFileSystemTool("/workspace")
+	fsTools := bridge.NewHandlerTool(tools.NewRealFileSystemTool(".", nil))
 	cmdTool := providers.NewCommandTool([]string{"ls", "pwd", "echo"})
 	
 	if err := e.directProvider.RegisterTool(fsTools); err != nil {
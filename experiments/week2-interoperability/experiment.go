@@ -4,12 +4,13 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/rcliao/teeny-orb/experiments/framework"
+	"github.com/rcliao/teeny-orb/internal/mcp/tools"
 	"github.com/rcliao/teeny-orb/internal/providers"
 	"github.com/rcliao/teeny-orb/internal/providers/bridge"
-	"github.com/rcliao/teeny-orb/internal/providers/direct"
 	"github.com/rcliao/teeny-orb/internal/providers/gemini"
 )
 
@@ -19,12 +20,27 @@ type Week2Experiment struct {
 	mcpProvider    providers.ToolProvider
 }
 
-// NewWeek2Experiment creates a new Week 2 experiment
-func NewWeek2Experiment() *Week2Experiment {
-	return &Week2Experiment{
-		directProvider: direct.NewDirectToolProvider(),
-		mcpProvider:    bridge.NewMCPToolProvider(),
+// NewWeek2Experiment creates a new Week 2 experiment. Both providers serve
+// the same filesystem tool from one registry.
+func NewWeek2Experiment() (*Week2Experiment, error) {
+	registry := bridge.NewRegistry()
+	if err := registry.Register(tools.NewRealFileSystemTool(".", nil)); err != nil {
+		return nil, fmt.Errorf("failed to register filesystem tool: %w", err)
+	}
+	
+	directProvider, err := registry.DirectProvider()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create direct provider: %w", err)
 	}
+	mcpProvider, err := registry.MCPProvider()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create MCP provider: %w", err)
+	}
+	
+	return &Week2Experiment{
+		directProvider: directProvider,
+		mcpProvider:    mcpProvider,
+	}, nil
 }
 
 // RunCrossProviderTest tests the same tools across different AI providers
@@ -72,19 +88,11 @@ func (e *Week2Experiment) RunCrossProviderTest(ctx context.Context) (*Interopera
 func (e *Week2Experiment) testProvider(ctx context.Context, providerType string, provider providers.ToolProvider, aiProvider string) (*ProviderResults, error) {
 	startTime := time.Now()
 	
-	// For MCP provider, tools are already registered
-	if providerType != "mcp" {
-		// Register standard tools for direct provider
-		fsTools := providers.NewFileSystemTool("/workspace")
-		cmdTool := providers.NewCommandTool([]string{"ls", "pwd", "echo"})
-		
-		if err := provider.RegisterTool(fsTools); err != nil {
-			return nil, fmt.Errorf("failed to register filesystem tool: %w", err)
-		}
-		
-		if err := provider.RegisterTool(cmdTool); err != nil {
-			return nil, fmt.Errorf("failed to register command tool: %w", err)
-		}
+	// The filesystem tool comes from the shared registry; the command tool
+	// is still a direct-only implementation, bridged to MCP on registration
+	cmdTool := providers.NewCommandTool([]string{"ls", "pwd", "echo"})
+	if err := provider.RegisterTool(cmdTool); err != nil {
+		return nil, fmt.Errorf("failed to register command tool: %w", err)
 	}
 	
 	setupTime := time.Since(startTime)
@@ -160,7 +168,7 @@ func (e *Week2Experiment) testGeminiWithMCP(ctx context.Context) (*ProviderResul
 	startTime := time.Now()
 	
 	// Create Gemini provider with MCP bridge
-	geminiProvider := gemini.NewGeminiToolProvider("fake-api-key", "gemini-1.5-pro", "mcp", e.mcpProvider)
+	geminiProvider := gemini.NewGeminiToolProvider(os.Getenv("GEMINI_API_KEY"), "gemini-1.5-pro", "mcp", e.mcpProvider)
 	defer geminiProvider.Close()
 	
 	setupTime := time.Since(startTime)
@@ -210,7 +218,7 @@ func (e *Week2Experiment) testGeminiWithDirect(ctx context.Context) (*ProviderRe
 	startTime := time.Now()
 	
 	// Create Gemini provider with direct tools
-	geminiProvider := gemini.NewGeminiToolProvider("fake-api-key", "gemini-1.5-pro", "direct", e.directProvider)
+	geminiProvider := gemini.NewGeminiToolProvider(os.Getenv("GEMINI_API_KEY"), "gemini-1.5-pro", "direct", e.directProvider)
 	defer geminiProvider.Close()
 	
 	setupTime := time.Since(startTime)
@@ -446,7 +454,10 @@ type ToolTestResult struct {
 
 func main() {
 	ctx := context.Background()
-	experiment := NewWeek2Experiment()
+	experiment, err := NewWeek2Experiment()
+	if err != nil {
+		log.Fatalf("Experiment setup failed: %v", err)
+	}
 	
 	fmt.Println("Running Week 2 Experiment: Cross-Provider Tool Interoperability")
 	
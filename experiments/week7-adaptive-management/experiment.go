@@ -149,7 +149,7 @@ func NewWeek7Experiment() *Week7Experiment {
 	adaptiveManager := contextpkg.NewDefaultAdaptiveManager(optimizer, analyzer, cache, nil)
 	
 	// Create feedback collector
-	feedbackStore := contextpkg.NewSimpleFeedbackStore("./feedback_data")
+	feedbackStore := contextpkg.NewIndexedFeedbackStore("./feedback_data")
 	feedbackCollector := contextpkg.NewDefaultFeedbackCollector(feedbackStore, adaptiveManager, nil)
 	
 	return &Week7Experiment{
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -309,6 +310,7 @@ func (e *Week8Experiment) runBaselineComparison(ctx context.Context, projectCtx
 	totalOptimizedFiles := 0
 	totalBaselineTime := time.Duration(0)
 	totalOptimizedTime := time.Duration(0)
+	comparedTasks := 0
 	
 	for _, realTask := range tasks {
 		// Convert to context task
@@ -349,6 +351,7 @@ func (e *Week8Experiment) runBaselineComparison(ctx context.Context, projectCtx
 		comparison.TokenReductionByTask[string(realTask.Type)] = reduction * 100
 		
 		// Track totals
+		comparedTasks++
 		totalBaselineTokens += baselineTokens
 		totalOptimizedTokens += adaptedContext.TotalTokens
 		totalBaselineFiles += baselineFiles
@@ -372,11 +375,10 @@ func (e *Week8Experiment) runBaselineComparison(ctx context.Context, projectCtx
 		e.results.TaskBreakdown = append(e.results.TaskBreakdown, taskResult)
 	}
 	
-	// Calculate averages
-	taskCount := len(tasks)
-	if taskCount > 0 {
-		comparison.BaselineTokensAvg = totalBaselineTokens / taskCount
-		comparison.OptimizedTokensAvg = totalOptimizedTokens / taskCount
+	// Calculate averages over the tasks that were actually compared
+	if comparedTasks > 0 {
+		comparison.BaselineTokensAvg = totalBaselineTokens / comparedTasks
+		comparison.OptimizedTokensAvg = totalOptimizedTokens / comparedTasks
 		
 		if totalBaselineTokens > 0 {
 			comparison.TokenReductionPercent = (1.0 - float64(totalOptimizedTokens)/float64(totalBaselineTokens)) * 100
@@ -775,7 +777,7 @@ func (e *Week8Experiment) generateSummary() {
 	}
 	
 	// Performance insights
-	if profile := e.results.PerformanceProfile; profile != nil {
+	if profile := e.results.PerformanceProfile; profile != nil && len(profile.AlgorithmTimings) > 0 {
 		fastestStrategy := e.findFastestStrategy(profile.AlgorithmTimings)
 		summary.KeyFindings = append(summary.KeyFindings,
 			fmt.Sprintf("'%s' strategy provides best performance/quality balance", fastestStrategy))
@@ -810,7 +812,7 @@ func (e *Week8Experiment) generateRecommendations() {
 
 // findFastestStrategy identifies the fastest selection strategy
 func (e *Week8Experiment) findFastestStrategy(timings map[string]time.Duration) string {
-	fastest := ""
+	fastest := notAvailable
 	minTime := time.Duration(1<<63 - 1) // Max duration
 	
 	for strategy, duration := range timings {
@@ -833,8 +835,35 @@ func (e *Week8Experiment) SaveResults(outputPath string) error {
 	return os.WriteFile(outputPath, data, 0644)
 }
 
-// GenerateLabReport creates a comprehensive lab report
+// GenerateLabReport creates a comprehensive lab report. Sections whose results
+// are missing, e.g. after a partial run, are rendered as N/A.
 func (e *Week8Experiment) GenerateLabReport(outputPath string) error {
+	if e.results == nil {
+		return fmt.Errorf("no experiment results to report")
+	}
+	
+	baseline := e.results.BaselineComparison
+	hypothesis := e.results.HypothesisValidation
+	quality := e.results.QualityValidation
+	
+	tokenReduction := formatPercent(0, false)
+	if baseline != nil {
+		tokenReduction = formatPercent(baseline.TokenReductionPercent, true)
+	}
+	
+	minContextTasks, avgContext := formatPercent(0, false), formatPercent(0, false)
+	if hypothesis != nil {
+		minContextTasks = formatPercent(hypothesis.TasksNeedingMinContext, true)
+		avgContext = formatPercent(hypothesis.AvgContextNeeded, true)
+	}
+	
+	overallQuality, completionRate, missingRate := formatPercent(0, false), formatPercent(0, false), formatPercent(0, false)
+	if quality != nil {
+		overallQuality = formatPercent(quality.OverallQualityScore*100, true)
+		completionRate = formatPercent(quality.TaskCompletionRate*100, true)
+		missingRate = formatPercent(quality.MissingContextRate*100, true)
+	}
+	
 	report := fmt.Sprintf(`# Lab Report: The Context Goldilocks Zone
 ## Phase 2 Performance Validation & Hypothesis Testing
 
@@ -851,14 +880,14 @@ The Phase 2 hypothesis stating that "80%% of coding tasks require only 10%% of a
 ## Key Findings
 
 ### Token Reduction Achievement
-- **Average Token Reduction**: %.1f%%
-- **Tasks Using ≤10%% Context**: %.1f%%
-- **Average Context Usage**: %.1f%%
+- **Average Token Reduction**: %s
+- **Tasks Using ≤10%% Context**: %s
+- **Average Context Usage**: %s
 
 ### Quality Metrics
-- **Overall Quality Score**: %.1f%%
-- **Task Completion Rate**: %.1f%%
-- **Missing Context Rate**: %.1f%%
+- **Overall Quality Score**: %s
+- **Task Completion Rate**: %s
+- **Missing Context Rate**: %s
 
 ### Performance Profile
 %s
@@ -897,12 +926,12 @@ The experiment demonstrates that intelligent context selection can dramatically
 		e.results.TasksEvaluated,
 		e.generateSummaryText(),
 		e.getHypothesisStatus(),
-		e.results.BaselineComparison.TokenReductionPercent,
-		e.results.HypothesisValidation.TasksNeedingMinContext,
-		e.results.HypothesisValidation.AvgContextNeeded,
-		e.results.QualityValidation.OverallQualityScore*100,
-		e.results.QualityValidation.TaskCompletionRate*100,
-		e.results.QualityValidation.MissingContextRate*100,
+		tokenReduction,
+		minContextTasks,
+		avgContext,
+		overallQuality,
+		completionRate,
+		missingRate,
 		e.generatePerformanceText(),
 		e.getHypothesisStatus(),
 		e.generateEvidenceText(),
@@ -922,6 +951,18 @@ The experiment demonstrates that intelligent context selection can dramatically
 
 // Helper methods for report generation
 
+// notAvailable is the report placeholder for results that are missing
+const notAvailable = "N/A"
+
+// formatPercent formats a percentage for the report, or N/A when the value is
+// missing or not a finite number
+func formatPercent(value float64, available bool) string {
+	if !available || math.IsNaN(value) || math.IsInf(value, 0) {
+		return notAvailable
+	}
+	return fmt.Sprintf("%.1f%%", value)
+}
+
 func (e *Week8Experiment) generateSummaryText() string {
 	if e.results.Summary == nil {
 		return "Summary not available"
@@ -932,10 +973,15 @@ func (e *Week8Experiment) generateSummaryText() string {
 		status = "SUCCESSFUL"
 	}
 	
-	return fmt.Sprintf(`Phase 2 validation was %s. The experiment achieved %.1f%% token reduction while maintaining %.1f%% task completion quality.`,
+	completionRate := formatPercent(0, false)
+	if e.results.QualityValidation != nil {
+		completionRate = formatPercent(e.results.QualityValidation.TaskCompletionRate*100, true)
+	}
+	
+	return fmt.Sprintf(`Phase 2 validation was %s. The experiment achieved %s token reduction while maintaining %s task completion quality.`,
 		status,
-		e.results.Summary.TokenReductionAchieved,
-		e.results.QualityValidation.TaskCompletionRate*100)
+		formatPercent(e.results.Summary.TokenReductionAchieved, true),
+		completionRate)
 }
 
 func (e *Week8Experiment) getHypothesisStatus() string {
@@ -971,10 +1017,16 @@ func (e *Week8Experiment) generatePerformanceText() string {
 	for _, t := range timings {
 		text.WriteString(fmt.Sprintf("  - %s: %v\n", t.name, t.time))
 	}
+	if len(timings) == 0 {
+		text.WriteString("  - " + notAvailable + "\n")
+	}
 	
 	text.WriteString("\n- **Hot Paths**:\n")
 	for _, hp := range e.results.PerformanceProfile.HotPaths {
-		text.WriteString(fmt.Sprintf("  - %s: %.1f%% of execution time\n", hp.Function, hp.PercentOfTotal))
+		text.WriteString(fmt.Sprintf("  - %s: %s of execution time\n", hp.Function, formatPercent(hp.PercentOfTotal, true)))
+	}
+	if len(e.results.PerformanceProfile.HotPaths) == 0 {
+		text.WriteString("  - " + notAvailable + "\n")
 	}
 	
 	return text.String()
@@ -985,6 +1037,10 @@ func (e *Week8Experiment) generateEvidenceText() string {
 		return "No evidence collected"
 	}
 	
+	if len(e.results.HypothesisValidation.EvidencePoints) == 0 {
+		return "No evidence collected"
+	}
+	
 	var text strings.Builder
 	for _, evidence := range e.results.HypothesisValidation.EvidencePoints {
 		text.WriteString(fmt.Sprintf("- %s\n", evidence))
@@ -994,24 +1050,42 @@ func (e *Week8Experiment) generateEvidenceText() string {
 }
 
 func (e *Week8Experiment) generateTaskBreakdownText() string {
-	if e.results.QualityValidation == nil {
+	if e.results.QualityValidation == nil || len(e.results.QualityValidation.QualityByTaskType) == 0 {
 		return "Task breakdown not available"
 	}
 	
+	// Report task types in a stable order
+	taskTypes := make([]string, 0, len(e.results.QualityValidation.QualityByTaskType))
+	for taskType := range e.results.QualityValidation.QualityByTaskType {
+		taskTypes = append(taskTypes, taskType)
+	}
+	sort.Strings(taskTypes)
+	
 	var text strings.Builder
 	
 	// Group by task type
-	for taskType, quality := range e.results.QualityValidation.QualityByTaskType {
-		avgReduction := e.results.BaselineComparison.TokenReductionByTask[taskType]
+	for _, taskType := range taskTypes {
+		quality := e.results.QualityValidation.QualityByTaskType[taskType]
+		
+		avgReduction := formatPercent(0, false)
+		if e.results.BaselineComparison != nil {
+			reduction, found := e.results.BaselineComparison.TokenReductionByTask[taskType]
+			avgReduction = formatPercent(reduction, found)
+		}
+		
 		text.WriteString(fmt.Sprintf("### %s Tasks\n", strings.Title(taskType)))
-		text.WriteString(fmt.Sprintf("- Average Quality: %.1f%%\n", quality*100))
-		text.WriteString(fmt.Sprintf("- Average Token Reduction: %.1f%%\n\n", avgReduction))
+		text.WriteString(fmt.Sprintf("- Average Quality: %s\n", formatPercent(quality*100, true)))
+		text.WriteString(fmt.Sprintf("- Average Token Reduction: %s\n\n", avgReduction))
 	}
 	
 	return text.String()
 }
 
 func (e *Week8Experiment) generateRecommendationsText() string {
+	if len(e.results.Recommendations) == 0 {
+		return "No recommendations available"
+	}
+	
 	var text strings.Builder
 	for _, rec := range e.results.Recommendations {
 		text.WriteString(fmt.Sprintf("%s\n", rec))
@@ -1020,7 +1094,7 @@ func (e *Week8Experiment) generateRecommendationsText() string {
 }
 
 func (e *Week8Experiment) generateNextStepsText() string {
-	if e.results.Summary == nil {
+	if e.results.Summary == nil || len(e.results.Summary.NextSteps) == 0 {
 		return "Next steps not defined"
 	}
 	
@@ -1033,6 +1107,11 @@ func (e *Week8Experiment) generateNextStepsText() string {
 
 // PrintSummary prints experiment summary
 func (e *Week8Experiment) PrintSummary() {
+	if e.results == nil {
+		fmt.Println("\n=== Week 8 Performance Validation Experiment: no results ===")
+		return
+	}
+	
 	fmt.Println("\n=== Week 8 Performance Validation Experiment Results ===")
 	fmt.Printf("Duration: %v\n", e.results.Duration)
 	fmt.Printf("Tasks Evaluated: %d\n", e.results.TasksEvaluated)
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGenerateLabReportPartialResults tests report generation when sub-results are missing
+func TestGenerateLabReportPartialResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		results *PerformanceValidationResults
+		expect  []string
+	}{
+		{
+			name:    "Empty results",
+			results: &PerformanceValidationResults{StartTime: time.Now()},
+			expect: []string{
+				"**Average Token Reduction**: N/A",
+				"**Overall Quality Score**: N/A",
+				"Summary not available",
+				"Performance profiling data not available",
+				"No evidence collected",
+				"Task breakdown not available",
+				"No recommendations available",
+				"Next steps not defined",
+			},
+		},
+		{
+			name: "Quality without baseline comparison",
+			results: &PerformanceValidationResults{
+				StartTime: time.Now(),
+				QualityValidation: &QualityValidationResults{
+					OverallQualityScore: 0.8,
+					TaskCompletionRate:  0.9,
+					QualityByTaskType:   map[string]float64{"debug": 0.75},
+				},
+				Summary: &ValidationSummary{TokenReductionAchieved: 42},
+			},
+			expect: []string{
+				"**Average Token Reduction**: N/A",
+				"**Overall Quality Score**: 80.0%",
+				"- Average Quality: 75.0%",
+				"- Average Token Reduction: N/A",
+				"achieved 42.0% token reduction while maintaining 90.0% task completion",
+			},
+		},
+		{
+			name: "Summary without quality validation",
+			results: &PerformanceValidationResults{
+				StartTime:          time.Now(),
+				BaselineComparison: &BaselineComparisonResults{TokenReductionPercent: 95},
+				PerformanceProfile: &PerformanceProfileResults{},
+				Summary:            &ValidationSummary{TokenReductionAchieved: 95},
+			},
+			expect: []string{
+				"**Average Token Reduction**: 95.0%",
+				"**Tasks Using ≤10% Context**: N/A",
+				"while maintaining N/A task completion",
+				"  - N/A",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			experiment := &Week8Experiment{results: tt.results}
+			reportPath := filepath.Join(t.TempDir(), "report.md")
+
+			if err := experiment.GenerateLabReport(reportPath); err != nil {
+				t.Fatalf("GenerateLabReport failed: %v", err)
+			}
+
+			data, err := os.ReadFile(reportPath)
+			if err != nil {
+				t.Fatalf("failed to read report: %v", err)
+			}
+			report := string(data)
+
+			for _, want := range tt.expect {
+				if !strings.Contains(report, want) {
+					t.Errorf("report missing %q", want)
+				}
+			}
+			if strings.Contains(report, "NaN") || strings.Contains(report, "%!") {
+				t.Errorf("report contains malformed values:\n%s", report)
+			}
+		})
+	}
+}
+
+// TestSummaryHelpersPartialResults tests summary helpers with missing sub-results
+func TestSummaryHelpersPartialResults(t *testing.T) {
+	experiment := &Week8Experiment{results: &PerformanceValidationResults{}}
+
+	experiment.validateHypothesis()
+	experiment.generateSummary()
+	experiment.PrintSummary()
+
+	if experiment.results.HypothesisValidation.HypothesisSupported {
+		t.Errorf("hypothesis should not be supported without any tasks")
+	}
+	if experiment.results.Summary.Phase2Success {
+		t.Errorf("phase 2 should not succeed without results")
+	}
+	if fastest := experiment.findFastestStrategy(nil); fastest != notAvailable {
+		t.Errorf("findFastestStrategy(nil) = %q, expected %q", fastest, notAvailable)
+	}
+
+	(&Week8Experiment{}).PrintSummary()
+}
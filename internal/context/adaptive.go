@@ -3,6 +3,9 @@ package context
 import (
 	"context"
 	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,6 +22,12 @@ type AdaptiveContextManager interface {
 	
 	// PredictOptimalBudget suggests optimal token budget for a task
 	PredictOptimalBudget(task *Task, projectCtx *ProjectContext) int
+
+	// RecommendCompression suggests the compression strategy for a task type
+	RecommendCompression(taskType TaskType) CompressionStrategy
+
+	// PredictStrategy suggests the selection strategy for a task in a project
+	PredictStrategy(task *Task, projectCtx *ProjectContext) SelectionStrategy
 }
 
 // AdaptedContext extends SelectedContext with adaptive features
@@ -27,6 +36,7 @@ type AdaptedContext struct {
 	AdaptationReasons []string              `json:"adaptation_reasons"`
 	BudgetAdjustment  int                   `json:"budget_adjustment"`
 	StrategyOverride  *SelectionStrategy    `json:"strategy_override,omitempty"`
+	Arm               ExperimentArm         `json:"arm,omitempty"` // Experiment arm the task was assigned, when enrolled
 	QualityPrediction float64               `json:"quality_prediction"`
 	AdaptiveMetadata  map[string]interface{} `json:"adaptive_metadata"`
 }
@@ -43,6 +53,9 @@ type ContextFeedback struct {
 	MissingFiles     []string              `json:"missing_files"`     // Files that should have been included
 	UnnecessaryFiles []string              `json:"unnecessary_files"` // Files that weren't needed
 	UserRating       float64               `json:"user_rating"`       // Optional user feedback
+	PreferredStrategy SelectionStrategy    `json:"preferred_strategy,omitempty"` // Strategy the user chose, e.g. in an A/B comparison
+	CompressionStrategy CompressionStrategy `json:"compression_strategy,omitempty"` // Compression applied to the selected context, if any
+	Arm              ExperimentArm         `json:"arm,omitempty"` // Experiment arm of the selection, when the task was enrolled
 	Timestamp        time.Time             `json:"timestamp"`
 }
 
@@ -51,11 +64,13 @@ type TaskProfile struct {
 	TaskType              TaskType               `json:"task_type"`
 	OptimalTokenBudget    int                    `json:"optimal_token_budget"`
 	PreferredStrategy     SelectionStrategy      `json:"preferred_strategy"`
+	PreferredCompression  CompressionStrategy    `json:"preferred_compression,omitempty"`
 	ImportantFileTypes    []string               `json:"important_file_types"`
 	TypicalFileCount      int                    `json:"typical_file_count"`
 	AvgQualityScore       float64                `json:"avg_quality_score"`
 	SuccessRate           float64                `json:"success_rate"`
 	AdaptationFactors     map[string]float64     `json:"adaptation_factors"`
+	BalancedWeights       *BalancedWeights       `json:"balanced_weights,omitempty"` // Learned from missing and unnecessary files; replaced, never modified
 	LastUpdated           time.Time              `json:"last_updated"`
 	SampleCount           int                    `json:"sample_count"`
 }
@@ -67,7 +82,9 @@ type DefaultAdaptiveManager struct {
 	cache         ContextCache
 	profiles      map[TaskType]*TaskProfile
 	feedbackLog   []ContextFeedback
+	predictor     *StrategyPredictor
 	config        *AdaptiveConfig
+	mutex         sync.RWMutex // Guards profiles, their contents and feedbackLog
 }
 
 // AdaptiveConfig configures the adaptive context manager
@@ -80,6 +97,24 @@ type AdaptiveConfig struct {
 	QualityThreshold        float64     `json:"quality_threshold"`
 	MaxBudgetAdjustment     int         `json:"max_budget_adjustment"`
 	AdaptationAggressiveness float64    `json:"adaptation_aggressiveness"`
+	CompressionDefaults     map[TaskType]CompressionStrategy `json:"compression_defaults"` // Used until feedback shows a preference
+	BudgetCeiling           int         `json:"budget_ceiling"` // Upper bound on predicted and adapted budgets, e.g. from ModelRegistry.AvailableBudget; 0 for none
+	StrategyPrediction      *StrategyPredictorConfig `json:"strategy_prediction,omitempty"` // Thresholds for choosing a strategy from the project's shape; nil for defaults
+	Experiment              *ExperimentConfig `json:"experiment,omitempty"` // Compares adapted selections with the static baseline; nil adapts every task
+	BalancedWeights         map[TaskType]BalancedWeights `json:"balanced_weights,omitempty"` // Balanced strategy weights by task type; types without any use the defaults
+}
+
+// defaultTaskCompression returns how much compression each task type tolerates
+// before anything has been learned
+func defaultTaskCompression() map[TaskType]CompressionStrategy {
+	return map[TaskType]CompressionStrategy{
+		TaskTypeGeneral:       CompressionSnippet,
+		TaskTypeDebug:         CompressionMinify,   // Bugs hide in function bodies
+		TaskTypeRefactor:      CompressionSemantic, // Signatures and types matter most
+		TaskTypeFeature:       CompressionSnippet,
+		TaskTypeTest:          CompressionSnippet,
+		TaskTypeDocumentation: CompressionSummary,
+	}
 }
 
 // NewDefaultAdaptiveManager creates a new adaptive context manager
@@ -94,6 +129,7 @@ func NewDefaultAdaptiveManager(optimizer ContextOptimizer, analyzer ContextAnaly
 			QualityThreshold:         0.7,
 			MaxBudgetAdjustment:      4000,
 			AdaptationAggressiveness: 0.5,
+			CompressionDefaults:      defaultTaskCompression(),
 		}
 	}
 
@@ -103,21 +139,30 @@ func NewDefaultAdaptiveManager(optimizer ContextOptimizer, analyzer ContextAnaly
 		cache:       cache,
 		profiles:    make(map[TaskType]*TaskProfile),
 		feedbackLog: []ContextFeedback{},
+		predictor:   NewStrategyPredictor(config.StrategyPrediction),
 		config:      config,
 	}
 }
 
-// AdaptOptimalContext provides adaptive context selection
+// AdaptOptimalContext provides adaptive context selection. Tasks on the
+// baseline arm of an experiment get the static defaults for their type
+// instead, apart from the budget ceiling.
 func (m *DefaultAdaptiveManager) AdaptOptimalContext(ctx context.Context, project *ProjectContext, task *Task, budget int) (*AdaptedContext, error) {
 	adaptedBudget := budget
 	adaptationReasons := []string{}
 	var strategyOverride *SelectionStrategy
 
-	// Get or create task profile
-	profile := m.getOrCreateTaskProfile(task.Type)
+	// Adapt from a copy, so feedback arriving meanwhile can't race with it
+	profile := m.profileSnapshot(task.Type)
+
+	arm := m.ExperimentArm(task.ID)
+	adapt := arm != ArmBaseline
+	if !adapt {
+		adaptationReasons = append(adaptationReasons, "Baseline arm of the adaptation experiment; learned adaptations skipped")
+	}
 
 	// Adapt budget based on learning
-	if m.config.EnableBudgetAdaptation && profile.SampleCount >= m.config.MinSamplesForAdaptation {
+	if adapt && m.config.EnableBudgetAdaptation && profile.SampleCount >= m.config.MinSamplesForAdaptation {
 		if profile.OptimalTokenBudget > 0 {
 			budgetAdjustment := int(float64(profile.OptimalTokenBudget-budget) * m.config.AdaptationAggressiveness)
 			
@@ -137,8 +182,13 @@ func (m *DefaultAdaptiveManager) AdaptOptimalContext(ctx context.Context, projec
 		}
 	}
 
+	if capped := m.clampBudget(adaptedBudget); capped != adaptedBudget {
+		adaptationReasons = append(adaptationReasons, fmt.Sprintf("Budget capped at the %d token ceiling", capped))
+		adaptedBudget = capped
+	}
+
 	// Adapt strategy based on success patterns
-	if m.config.EnableStrategyAdaptation && profile.SampleCount >= m.config.MinSamplesForAdaptation {
+	if adapt && m.config.EnableStrategyAdaptation && profile.SampleCount >= m.config.MinSamplesForAdaptation {
 		if profile.SuccessRate > m.config.QualityThreshold && profile.PreferredStrategy != "" {
 			strategyOverride = &profile.PreferredStrategy
 			adaptationReasons = append(adaptationReasons, 
@@ -148,19 +198,31 @@ func (m *DefaultAdaptiveManager) AdaptOptimalContext(ctx context.Context, projec
 	}
 
 	// Get adaptive constraints
-	constraints := m.GetAdaptiveConstraints(task, adaptedBudget, project)
+	constraints := m.constraintsFor(task, adaptedBudget, project, profile, adapt)
 	if strategyOverride != nil {
 		constraints.Strategy = *strategyOverride
 	}
 
 	// Apply task-specific adaptations
-	m.applyTaskSpecificAdaptations(constraints, task, profile, project)
+	if adapt {
+		m.applyTaskSpecificAdaptations(constraints, task, profile, project)
+	}
 	
 	// Perform context selection
 	selectedContext, err := m.optimizer.SelectOptimalContext(ctx, project, task, constraints)
 	if err != nil {
 		return nil, err
 	}
+	if arm != "" {
+		// The selection may be cached and shared, so it's tagged on a copy
+		tagged := *selectedContext
+		tagged.Metadata = make(map[string]interface{}, len(selectedContext.Metadata)+1)
+		for key, value := range selectedContext.Metadata {
+			tagged.Metadata[key] = value
+		}
+		tagged.Metadata[experimentArmKey] = string(arm)
+		selectedContext = &tagged
+	}
 
 	// Predict quality based on historical data
 	qualityPrediction := m.predictQuality(selectedContext, task, profile)
@@ -171,8 +233,10 @@ func (m *DefaultAdaptiveManager) AdaptOptimalContext(ctx context.Context, projec
 		AdaptationReasons: adaptationReasons,
 		BudgetAdjustment:  adaptedBudget - budget,
 		StrategyOverride:  strategyOverride,
+		Arm:               arm,
 		QualityPrediction: qualityPrediction,
 		AdaptiveMetadata: map[string]interface{}{
+			"experiment_arm":     string(arm),
 			"profile_samples":    profile.SampleCount,
 			"profile_success":    profile.SuccessRate,
 			"optimal_budget":     profile.OptimalTokenBudget,
@@ -185,8 +249,12 @@ func (m *DefaultAdaptiveManager) AdaptOptimalContext(ctx context.Context, projec
 
 // GetAdaptiveConstraints returns task-optimized constraints
 func (m *DefaultAdaptiveManager) GetAdaptiveConstraints(task *Task, budget int, projectCtx *ProjectContext) *ContextConstraints {
-	profile := m.getOrCreateTaskProfile(task.Type)
-	
+	return m.constraintsFor(task, budget, projectCtx, m.profileSnapshot(task.Type), true)
+}
+
+// constraintsFor builds the constraints for a task type, applying what has
+// been learned about it, as profile records, only when learned is set
+func (m *DefaultAdaptiveManager) constraintsFor(task *Task, budget int, projectCtx *ProjectContext, profile *TaskProfile, learned bool) *ContextConstraints {
 	constraints := &ContextConstraints{
 		MaxTokens:         budget,
 		MaxFiles:          50,
@@ -196,7 +264,7 @@ func (m *DefaultAdaptiveManager) GetAdaptiveConstraints(task *Task, budget int,
 		IncludeDocs:       false,
 		FreshnessBias:     0.2,
 		DependencyDepth:   2,
-		Strategy:          StrategyBalanced, // Default
+		Strategy:          m.predictStrategy(task, projectCtx, profile, learned),
 	}
 
 	// Task-specific constraint adaptations
@@ -206,7 +274,6 @@ func (m *DefaultAdaptiveManager) GetAdaptiveConstraints(task *Task, budget int,
 		constraints.IncludeTests = false
 		constraints.IncludeDocs = false
 		constraints.FreshnessBias = 0.3
-		constraints.Strategy = StrategyRelevance
 		
 	case TaskTypeDebug:
 		constraints.PreferredTypes = []string{"source"}
@@ -214,7 +281,6 @@ func (m *DefaultAdaptiveManager) GetAdaptiveConstraints(task *Task, budget int,
 		constraints.IncludeDocs = false
 		constraints.FreshnessBias = 0.4 // Recent changes more important for debugging
 		constraints.DependencyDepth = 3 // Deeper dependency analysis
-		constraints.Strategy = StrategyDependency
 		
 	case TaskTypeRefactor:
 		constraints.PreferredTypes = []string{"source"}
@@ -222,30 +288,32 @@ func (m *DefaultAdaptiveManager) GetAdaptiveConstraints(task *Task, budget int,
 		constraints.IncludeDocs = false
 		constraints.FreshnessBias = 0.1 // Less bias toward recent files
 		constraints.DependencyDepth = 4 // Maximum dependency analysis
-		constraints.Strategy = StrategyDependency
 		
 	case TaskTypeTest:
 		constraints.PreferredTypes = []string{"source", "test"}
 		constraints.IncludeTests = true
 		constraints.IncludeDocs = false
 		constraints.FreshnessBias = 0.2
-		constraints.Strategy = StrategyRelevance
 		
 	case TaskTypeDocumentation:
 		constraints.PreferredTypes = []string{"source", "documentation"}
 		constraints.IncludeTests = false
 		constraints.IncludeDocs = true
 		constraints.FreshnessBias = 0.2
-		constraints.Strategy = StrategyRelevance
+	}
+
+	if weights, ok := m.config.BalancedWeights[task.Type]; ok {
+		constraints.BalancedWeights = &weights
 	}
 
 	// Apply learned preferences from profile
-	if profile.SampleCount >= m.config.MinSamplesForAdaptation {
+	if learned && profile.SampleCount >= m.config.MinSamplesForAdaptation {
 		if len(profile.ImportantFileTypes) > 0 {
 			constraints.PreferredTypes = profile.ImportantFileTypes
 		}
-		if profile.PreferredStrategy != "" {
-			constraints.Strategy = profile.PreferredStrategy
+		if profile.BalancedWeights != nil {
+			weights := *profile.BalancedWeights
+			constraints.BalancedWeights = &weights
 		}
 	}
 
@@ -254,7 +322,7 @@ func (m *DefaultAdaptiveManager) GetAdaptiveConstraints(task *Task, budget int,
 
 // PredictOptimalBudget suggests optimal token budget for a task
 func (m *DefaultAdaptiveManager) PredictOptimalBudget(task *Task, projectCtx *ProjectContext) int {
-	profile := m.getOrCreateTaskProfile(task.Type)
+	profile := m.profileSnapshot(task.Type)
 	
 	// Base prediction on project size
 	baseBudget := 8000 // Default budget
@@ -273,17 +341,69 @@ func (m *DefaultAdaptiveManager) PredictOptimalBudget(task *Task, projectCtx *Pr
 		baseBudget = int(float64(baseBudget)*(1-weight) + float64(profile.OptimalTokenBudget)*weight)
 	}
 	
-	return baseBudget
+	return m.clampBudget(baseBudget)
+}
+
+// PredictStrategy returns the strategy learned for the task type once
+// enough feedback has been collected, and otherwise the one the project's
+// shape suggests. Without a project only the task type is considered.
+func (m *DefaultAdaptiveManager) PredictStrategy(task *Task, projectCtx *ProjectContext) SelectionStrategy {
+	return m.predictStrategy(task, projectCtx, m.profileSnapshot(task.Type), true)
+}
+
+// predictStrategy is PredictStrategy for the task type's profile, skipping
+// the learned preference unless learned is set
+func (m *DefaultAdaptiveManager) predictStrategy(task *Task, projectCtx *ProjectContext, profile *TaskProfile, learned bool) SelectionStrategy {
+	if learned && profile.PreferredStrategy != "" && profile.SampleCount >= m.config.MinSamplesForAdaptation {
+		return profile.PreferredStrategy
+	}
+	if projectCtx == nil {
+		return defaultTaskStrategy(task.Type)
+	}
+	return m.predictor.Predict(task, MeasureProject(projectCtx))
+}
+
+// clampBudget caps a budget at the configured ceiling
+func (m *DefaultAdaptiveManager) clampBudget(budget int) int {
+	if m.config.BudgetCeiling > 0 && budget > m.config.BudgetCeiling {
+		return m.config.BudgetCeiling
+	}
+	return budget
+}
+
+// RecommendCompression returns the compression strategy learned for the task
+// type, or its default until enough feedback has been collected. Types a
+// config's CompressionDefaults leave out keep the built-in default.
+func (m *DefaultAdaptiveManager) RecommendCompression(taskType TaskType) CompressionStrategy {
+	if profile := m.profileSnapshot(taskType); profile.PreferredCompression != "" &&
+		profile.SampleCount >= m.config.MinSamplesForAdaptation {
+		return profile.PreferredCompression
+	}
+	if strategy, exists := m.config.CompressionDefaults[taskType]; exists {
+		return strategy
+	}
+	if strategy, exists := defaultTaskCompression()[taskType]; exists {
+		return strategy
+	}
+	return CompressionSnippet
 }
 
 // LearnFromFeedback incorporates feedback to improve future selections
 func (m *DefaultAdaptiveManager) LearnFromFeedback(feedback *ContextFeedback) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
 	// Add to feedback log
 	m.feedbackLog = append(m.feedbackLog, *feedback)
 	
 	// Clean old feedback
 	m.cleanOldFeedback()
 	
+	// Feedback without a task can't be attributed to a profile
+	if feedback.Task == nil {
+		return nil
+	}
+
 	// Update task profile
 	profile := m.getOrCreateTaskProfile(feedback.Task.Type)
 	m.updateTaskProfile(profile, feedback)
@@ -356,13 +476,44 @@ func (m *DefaultAdaptiveManager) predictQuality(selectedContext *SelectedContext
 	return max(0.0, min(1.0, prediction))
 }
 
-// getOrCreateTaskProfile gets or creates a profile for a task type
+// profileSnapshot returns a copy of the task type's profile, or an empty one
+// before any feedback for the type. Later feedback leaves the copy as it is.
+func (m *DefaultAdaptiveManager) profileSnapshot(taskType TaskType) *TaskProfile {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	if profile, exists := m.profiles[taskType]; exists {
+		return copyTaskProfile(profile)
+	}
+	return newTaskProfile(taskType)
+}
+
+// copyTaskProfile copies a profile along with its slice and map;
+// BalancedWeights is replaced rather than modified, so it can be shared
+func copyTaskProfile(profile *TaskProfile) *TaskProfile {
+	copied := *profile
+	copied.ImportantFileTypes = append([]string{}, profile.ImportantFileTypes...)
+	copied.AdaptationFactors = make(map[string]float64, len(profile.AdaptationFactors))
+	for key, value := range profile.AdaptationFactors {
+		copied.AdaptationFactors[key] = value
+	}
+	return &copied
+}
+
+// getOrCreateTaskProfile gets or creates a profile for a task type. The
+// caller must hold the write lock.
 func (m *DefaultAdaptiveManager) getOrCreateTaskProfile(taskType TaskType) *TaskProfile {
 	if profile, exists := m.profiles[taskType]; exists {
 		return profile
 	}
 	
-	profile := &TaskProfile{
+	profile := newTaskProfile(taskType)
+	m.profiles[taskType] = profile
+	return profile
+}
+
+// newTaskProfile returns a profile for a task type without any samples
+func newTaskProfile(taskType TaskType) *TaskProfile {
+	return &TaskProfile{
 		TaskType:           taskType,
 		OptimalTokenBudget: 0,
 		PreferredStrategy:  "",
@@ -374,9 +525,6 @@ func (m *DefaultAdaptiveManager) getOrCreateTaskProfile(taskType TaskType) *Task
 		LastUpdated:        time.Now(),
 		SampleCount:        0,
 	}
-	
-	m.profiles[taskType] = profile
-	return profile
 }
 
 // updateTaskProfile updates a task profile with new feedback
@@ -405,6 +553,22 @@ func (m *DefaultAdaptiveManager) updateTaskProfile(profile *TaskProfile, feedbac
 		profile.SuccessRate = alpha*successValue + (1-alpha)*profile.SuccessRate
 	}
 	
+	// Learn from an explicit strategy preference
+	if feedback.PreferredStrategy != "" {
+		m.updateStrategyPreference(profile, feedback.PreferredStrategy)
+	}
+
+	if feedback.CompressionStrategy != "" {
+		m.updateCompressionPreference(profile, feedback.CompressionStrategy,
+			feedback.TaskSuccess && feedback.QualityScore >= m.config.QualityThreshold)
+	}
+
+	if feedback.SelectedContext == nil {
+		return
+	}
+
+	m.updateBalancedWeights(profile, feedback)
+
 	// Update optimal token budget
 	if feedback.TaskSuccess && feedback.QualityScore > m.config.QualityThreshold {
 		if profile.OptimalTokenBudget == 0 {
@@ -422,12 +586,132 @@ func (m *DefaultAdaptiveManager) updateTaskProfile(profile *TaskProfile, feedbac
 	}
 	
 	// Update preferred strategy if this one was successful
-	if feedback.TaskSuccess && feedback.QualityScore > profile.AvgQualityScore {
+	if feedback.PreferredStrategy == "" && feedback.TaskSuccess && feedback.QualityScore > profile.AvgQualityScore {
 		profile.PreferredStrategy = feedback.SelectedContext.Strategy
 	}
 }
 
-// cleanOldFeedback removes feedback older than retention period
+// strategyFactorPrefix namespaces strategy preferences in AdaptationFactors
+const strategyFactorPrefix = "strategy:"
+
+// updateStrategyPreference moves the profile's strategy preferences toward the
+// chosen strategy and prefers the strategy with the highest learned weight
+func (m *DefaultAdaptiveManager) updateStrategyPreference(profile *TaskProfile, chosen SelectionStrategy) {
+	if profile.AdaptationFactors == nil {
+		profile.AdaptationFactors = make(map[string]float64)
+	}
+
+	alpha := m.config.LearningRate
+	chosenKey := strategyFactorPrefix + string(chosen)
+	if _, exists := profile.AdaptationFactors[chosenKey]; !exists {
+		profile.AdaptationFactors[chosenKey] = 0
+	}
+
+	bestKey := ""
+	for key, weight := range profile.AdaptationFactors {
+		if !strings.HasPrefix(key, strategyFactorPrefix) {
+			continue
+		}
+
+		target := 0.0
+		if key == chosenKey {
+			target = 1.0
+		}
+		weight = alpha*target + (1-alpha)*weight
+		profile.AdaptationFactors[key] = weight
+
+		if bestKey == "" || weight > profile.AdaptationFactors[bestKey] || (weight == profile.AdaptationFactors[bestKey] && key < bestKey) {
+			bestKey = key
+		}
+	}
+
+	profile.PreferredStrategy = SelectionStrategy(strings.TrimPrefix(bestKey, strategyFactorPrefix))
+}
+
+// compressionFactorPrefix namespaces compression preferences in AdaptationFactors
+const compressionFactorPrefix = "compression:"
+
+// updateCompressionPreference moves the weight of the used compression toward
+// 1 when the task went well and toward 0 otherwise. Strategies start out
+// neutral at 0.5, and only one weighted above that is preferred.
+func (m *DefaultAdaptiveManager) updateCompressionPreference(profile *TaskProfile, used CompressionStrategy, succeeded bool) {
+	if profile.AdaptationFactors == nil {
+		profile.AdaptationFactors = make(map[string]float64)
+	}
+
+	usedKey := compressionFactorPrefix + string(used)
+	weight, exists := profile.AdaptationFactors[usedKey]
+	if !exists {
+		weight = 0.5
+	}
+	target := 0.0
+	if succeeded {
+		target = 1.0
+	}
+	profile.AdaptationFactors[usedKey] = m.config.LearningRate*target + (1-m.config.LearningRate)*weight
+
+	bestKey := ""
+	for key, weight := range profile.AdaptationFactors {
+		if !strings.HasPrefix(key, compressionFactorPrefix) || weight <= 0.5 {
+			continue
+		}
+		if bestKey == "" || weight > profile.AdaptationFactors[bestKey] || (weight == profile.AdaptationFactors[bestKey] && key < bestKey) {
+			bestKey = key
+		}
+	}
+	profile.PreferredCompression = CompressionStrategy(strings.TrimPrefix(bestKey, compressionFactorPrefix))
+}
+
+// Targets the balanced weights move toward: missing files mean the dependency
+// graph wasn't followed far enough, and unnecessary files that selection
+// should have been pickier about relevance and size
+var (
+	missingFilesWeights     = BalancedWeights{Centrality: 1}
+	unnecessaryFilesWeights = BalancedWeights{Relevance: 0.6, Size: 0.4}
+)
+
+// updateBalancedWeights moves the profile's balanced strategy weights toward
+// missingFilesWeights and unnecessaryFilesWeights, as a moving average with
+// the learning rate scaled by the share of files the selection got wrong
+func (m *DefaultAdaptiveManager) updateBalancedWeights(profile *TaskProfile, feedback *ContextFeedback) {
+	selected := feedback.SelectedContext.TotalFiles
+	missing, unnecessary := len(feedback.MissingFiles), len(feedback.UnnecessaryFiles)
+	if missing == 0 && unnecessary == 0 {
+		return
+	}
+
+	missingRate := float64(missing) / float64(selected+missing)
+	unnecessaryRate := 0.0
+	if selected > 0 {
+		unnecessaryRate = math.Min(float64(unnecessary)/float64(selected), 1.0)
+	}
+
+	weights := DefaultBalancedWeights()
+	if configured, ok := m.config.BalancedWeights[profile.TaskType]; ok {
+		weights = configured
+	}
+	if profile.BalancedWeights != nil {
+		weights = *profile.BalancedWeights
+	}
+
+	alpha := m.config.LearningRate
+	weights = blendWeights(weights, missingFilesWeights, alpha*missingRate)
+	weights = blendWeights(weights, unnecessaryFilesWeights, alpha*unnecessaryRate).Normalized()
+	profile.BalancedWeights = &weights
+}
+
+// blendWeights returns the weights moved toward target by rate
+func blendWeights(weights, target BalancedWeights, rate float64) BalancedWeights {
+	return BalancedWeights{
+		Relevance:  rate*target.Relevance + (1-rate)*weights.Relevance,
+		Centrality: rate*target.Centrality + (1-rate)*weights.Centrality,
+		Freshness:  rate*target.Freshness + (1-rate)*weights.Freshness,
+		Size:       rate*target.Size + (1-rate)*weights.Size,
+	}
+}
+
+// cleanOldFeedback removes feedback older than retention period. The caller
+// must hold the write lock.
 func (m *DefaultAdaptiveManager) cleanOldFeedback() {
 	cutoff := time.Now().AddDate(0, 0, -m.config.FeedbackRetentionDays)
 	
@@ -443,11 +727,13 @@ func (m *DefaultAdaptiveManager) cleanOldFeedback() {
 
 // GetProfileStatistics returns statistics about learned profiles
 func (m *DefaultAdaptiveManager) GetProfileStatistics() map[TaskType]*TaskProfile {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
 	result := make(map[TaskType]*TaskProfile)
 	for taskType, profile := range m.profiles {
 		// Return a copy to prevent external modification
-		profileCopy := *profile
-		result[taskType] = &profileCopy
+		result[taskType] = copyTaskProfile(profile)
 	}
 	return result
 }
@@ -0,0 +1,54 @@
+package context
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// ExperimentArm names the side of the adaptation experiment a task is on
+type ExperimentArm string
+
+const (
+	ArmAdapted  ExperimentArm = "adapted"  // Learned budget, strategy, and constraints apply
+	ArmBaseline ExperimentArm = "baseline" // The static per-task-type defaults apply
+)
+
+// experimentArmKey is the SelectedContext metadata key holding the arm
+const experimentArmKey = "experiment_arm"
+
+// ExperimentConfig splits tasks between the adapted and baseline paths so
+// their outcomes can be compared
+type ExperimentConfig struct {
+	AdaptedFraction float64 `json:"adapted_fraction"` // Share of tasks, 0-1, on the adapted arm
+	Salt            string  `json:"salt,omitempty"`   // Changing it reassigns tasks to arms
+}
+
+// ExperimentArm returns the arm of the task with the given ID. The split
+// hashes the ID, so a retried task lands on the same arm. Without an
+// experiment, or for a task without an ID, nothing is enrolled and the
+// result is empty; such tasks take the adapted path.
+func (m *DefaultAdaptiveManager) ExperimentArm(taskID string) ExperimentArm {
+	experiment := m.config.Experiment
+	if experiment == nil || taskID == "" {
+		return ""
+	}
+
+	// IDs often differ in a single character, which a cryptographic hash
+	// still spreads evenly; the top 53 bits give a uniform float in [0, 1)
+	sum := sha256.Sum256([]byte(experiment.Salt + "\x00" + taskID))
+	position := float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+	if position < experiment.AdaptedFraction {
+		return ArmAdapted
+	}
+	return ArmBaseline
+}
+
+// selectionArm reads the arm AdaptOptimalContext recorded on a selection
+func selectionArm(selection *SelectedContext) ExperimentArm {
+	if selection == nil {
+		return ""
+	}
+	// Stored as a string so it survives a JSON round trip
+	arm, _ := selection.Metadata[experimentArmKey].(string)
+	return ExperimentArm(arm)
+}
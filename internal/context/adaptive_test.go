@@ -0,0 +1,411 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRecommendCompressionDefaults tests the per-task-type defaults used
+// before any feedback has been collected
+func TestRecommendCompressionDefaults(t *testing.T) {
+	manager := NewDefaultAdaptiveManager(nil, nil, nil, nil)
+
+	tests := []struct {
+		taskType TaskType
+		expected CompressionStrategy
+	}{
+		{TaskTypeDocumentation, CompressionSummary},
+		{TaskTypeDebug, CompressionMinify},
+		{TaskTypeRefactor, CompressionSemantic},
+		{TaskType("unknown"), CompressionSnippet},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.taskType), func(t *testing.T) {
+			if got := manager.RecommendCompression(tt.taskType); got != tt.expected {
+				t.Errorf("RecommendCompression(%s) = %q, expected %q", tt.taskType, got, tt.expected)
+			}
+		})
+	}
+
+	// A config of its own overrides only the types it names
+	manager = NewDefaultAdaptiveManager(nil, nil, nil, &AdaptiveConfig{
+		CompressionDefaults: map[TaskType]CompressionStrategy{TaskTypeDebug: CompressionNone},
+	})
+	if got := manager.RecommendCompression(TaskTypeDebug); got != CompressionNone {
+		t.Errorf("RecommendCompression(debug) = %q, expected the configured %q", got, CompressionNone)
+	}
+	if got := manager.RecommendCompression(TaskTypeDocumentation); got != CompressionSummary {
+		t.Errorf("RecommendCompression(documentation) = %q, expected the default %q", got, CompressionSummary)
+	}
+	manager = NewDefaultAdaptiveManager(nil, nil, nil, &AdaptiveConfig{})
+	if got := manager.RecommendCompression(TaskTypeDocumentation); got != CompressionSummary {
+		t.Errorf("RecommendCompression(documentation) = %q without defaults configured, expected %q", got, CompressionSummary)
+	}
+}
+
+// TestRecommendCompressionLearnsFromFeedback tests that a compression strategy
+// that keeps working for a task type replaces its default, and one that keeps
+// failing does not
+func TestRecommendCompressionLearnsFromFeedback(t *testing.T) {
+	tests := []struct {
+		name     string
+		success  bool
+		expected CompressionStrategy
+	}{
+		{name: "successful", success: true, expected: CompressionSnippet},
+		{name: "failing", success: false, expected: CompressionSummary},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewDefaultAdaptiveManager(nil, nil, nil, nil)
+			task := &Task{Type: TaskTypeDocumentation}
+
+			for i := 0; i < manager.config.MinSamplesForAdaptation; i++ {
+				if got := manager.RecommendCompression(task.Type); got != CompressionSummary {
+					t.Fatalf("after %d samples RecommendCompression = %q, expected the default", i, got)
+				}
+				err := manager.LearnFromFeedback(&ContextFeedback{
+					Task:                task,
+					TaskSuccess:         tt.success,
+					QualityScore:        0.9,
+					CompressionStrategy: CompressionSnippet,
+				})
+				if err != nil {
+					t.Fatalf("LearnFromFeedback failed: %v", err)
+				}
+			}
+
+			if got := manager.RecommendCompression(task.Type); got != tt.expected {
+				t.Errorf("RecommendCompression = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestLearnBalancedWeightsFromFeedback tests that missing files move the
+// balanced weights toward centrality and unnecessary files toward relevance
+// and size, and that the learned weights reach the adaptive constraints once
+// enough feedback has come in
+func TestLearnBalancedWeightsFromFeedback(t *testing.T) {
+	tests := []struct {
+		name        string
+		missing     []string
+		unnecessary []string
+		increased   func(before, after BalancedWeights) bool
+	}{
+		{
+			name:      "missing files raise centrality",
+			missing:   []string{"/project/store.go", "/project/db.go"},
+			increased: func(before, after BalancedWeights) bool { return after.Centrality > before.Centrality },
+		},
+		{
+			name:        "unnecessary files raise relevance and size",
+			unnecessary: []string{"/project/util.go", "/project/log.go"},
+			increased: func(before, after BalancedWeights) bool {
+				return after.Relevance > before.Relevance && after.Size > before.Size && after.Centrality < before.Centrality
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewDefaultAdaptiveManager(nil, nil, nil, nil)
+			task := &Task{Type: TaskTypeDebug}
+			defaults := DefaultBalancedWeights()
+
+			for i := 0; i < manager.config.MinSamplesForAdaptation; i++ {
+				if constraints := manager.GetAdaptiveConstraints(task, 4000, nil); constraints.BalancedWeights != nil {
+					t.Fatalf("after %d samples BalancedWeights = %+v, expected none before adaptation", i, *constraints.BalancedWeights)
+				}
+				err := manager.LearnFromFeedback(&ContextFeedback{
+					Task:             task,
+					SelectedContext:  &SelectedContext{TotalFiles: 4, Strategy: StrategyBalanced},
+					QualityScore:     0.6,
+					MissingFiles:     tt.missing,
+					UnnecessaryFiles: tt.unnecessary,
+				})
+				if err != nil {
+					t.Fatalf("LearnFromFeedback failed: %v", err)
+				}
+			}
+
+			constraints := manager.GetAdaptiveConstraints(task, 4000, nil)
+			if constraints.BalancedWeights == nil {
+				t.Fatal("expected learned BalancedWeights in the adaptive constraints")
+			}
+			learned := *constraints.BalancedWeights
+			if !tt.increased(defaults, learned) {
+				t.Errorf("learned weights %+v, from defaults %+v", learned, defaults)
+			}
+			if err := learned.Validate(); err != nil {
+				t.Errorf("learned weights are invalid: %v", err)
+			}
+		})
+	}
+}
+
+// newShapedProject builds a project of files with edgesPerFile dependency
+// edges each
+func newShapedProject(files, tokensPerFile, edgesPerFile int) *ProjectContext {
+	tokens := make(map[string]int, files)
+	for i := 0; i < files; i++ {
+		tokens[fmt.Sprintf("/project/file%02d.go", i)] = tokensPerFile
+	}
+	project := newTestProject(tokens)
+	project.DependencyGraph = &DependencyGraph{Nodes: make(map[string]*DependencyNode)}
+	for i := 0; i < files; i++ {
+		for j := 1; j <= edgesPerFile; j++ {
+			project.DependencyGraph.Edges = append(project.DependencyGraph.Edges, DependencyEdge{
+				From: fmt.Sprintf("/project/file%02d.go", i),
+				To:   fmt.Sprintf("/project/file%02d.go", (i+j)%files),
+				Type: "import",
+			})
+		}
+	}
+	return project
+}
+
+// TestPredictStrategy tests that the project's shape picks the strategy
+// within a task type, and that a learned preference wins over it
+func TestPredictStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		taskType TaskType
+		project  *ProjectContext
+		expected SelectionStrategy
+	}{
+		{name: "debug in dense graph", taskType: TaskTypeDebug, project: newShapedProject(20, 500, 2), expected: StrategyDependency},
+		{name: "debug in flat project", taskType: TaskTypeDebug, project: newShapedProject(20, 500, 0), expected: StrategyRelevance},
+		{name: "feature in dense graph", taskType: TaskTypeFeature, project: newShapedProject(20, 500, 2), expected: StrategyDependency},
+		{name: "flat project of large files", taskType: TaskTypeDebug, project: newShapedProject(20, 5000, 0), expected: StrategyCompactness},
+		{name: "moderate density", taskType: TaskTypeRefactor, project: newShapedProject(20, 500, 1), expected: StrategyDependency},
+		{name: "too small to judge", taskType: TaskTypeDebug, project: newShapedProject(4, 500, 0), expected: StrategyDependency},
+		{name: "documentation", taskType: TaskTypeDocumentation, project: newShapedProject(20, 500, 2), expected: StrategyRelevance},
+		{name: "no project", taskType: TaskTypeGeneral, expected: StrategyBalanced},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewDefaultAdaptiveManager(nil, nil, nil, nil)
+			task := &Task{Type: tt.taskType}
+			if got := manager.PredictStrategy(task, tt.project); got != tt.expected {
+				t.Errorf("PredictStrategy = %q, expected %q", got, tt.expected)
+			}
+			if got := manager.GetAdaptiveConstraints(task, 8000, tt.project).Strategy; got != tt.expected {
+				t.Errorf("constraint strategy = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("learned preference", func(t *testing.T) {
+		manager := NewDefaultAdaptiveManager(nil, nil, nil, nil)
+		task := &Task{Type: TaskTypeDebug}
+		project := newShapedProject(20, 500, 2)
+		for i := 0; i < manager.config.MinSamplesForAdaptation; i++ {
+			err := manager.LearnFromFeedback(&ContextFeedback{
+				Task:              task,
+				TaskSuccess:       true,
+				QualityScore:      0.9,
+				PreferredStrategy: StrategyFreshness,
+			})
+			if err != nil {
+				t.Fatalf("LearnFromFeedback failed: %v", err)
+			}
+		}
+		if got := manager.PredictStrategy(task, project); got != StrategyFreshness {
+			t.Errorf("PredictStrategy = %q, expected the learned %q", got, StrategyFreshness)
+		}
+	})
+}
+
+// TestExperimentArmSplit tests that tasks are split between arms in the
+// configured proportion and stay on their arm when retried
+func TestExperimentArmSplit(t *testing.T) {
+	tests := []struct {
+		fraction float64
+		min, max int // Adapted tasks expected out of 1000
+	}{
+		{fraction: 0, min: 0, max: 0},
+		{fraction: 0.3, min: 250, max: 350},
+		{fraction: 0.5, min: 450, max: 550},
+		{fraction: 1, min: 1000, max: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%.1f", tt.fraction), func(t *testing.T) {
+			manager := NewDefaultAdaptiveManager(nil, nil, nil, &AdaptiveConfig{
+				Experiment: &ExperimentConfig{AdaptedFraction: tt.fraction},
+			})
+			adapted := 0
+			for i := 0; i < 1000; i++ {
+				id := fmt.Sprintf("task-%d", i)
+				arm := manager.ExperimentArm(id)
+				if arm == ArmAdapted {
+					adapted++
+				} else if arm != ArmBaseline {
+					t.Fatalf("ExperimentArm(%s) = %q, expected an arm", id, arm)
+				}
+				if again := manager.ExperimentArm(id); again != arm {
+					t.Fatalf("ExperimentArm(%s) moved from %q to %q", id, arm, again)
+				}
+			}
+			if adapted < tt.min || adapted > tt.max {
+				t.Errorf("%d of 1000 tasks adapted, expected %d-%d", adapted, tt.min, tt.max)
+			}
+		})
+	}
+
+	t.Run("salt reassigns", func(t *testing.T) {
+		first := NewDefaultAdaptiveManager(nil, nil, nil, &AdaptiveConfig{Experiment: &ExperimentConfig{AdaptedFraction: 0.5}})
+		second := NewDefaultAdaptiveManager(nil, nil, nil, &AdaptiveConfig{Experiment: &ExperimentConfig{AdaptedFraction: 0.5, Salt: "v2"}})
+		moved := 0
+		for i := 0; i < 100; i++ {
+			id := fmt.Sprintf("task-%d", i)
+			if first.ExperimentArm(id) != second.ExperimentArm(id) {
+				moved++
+			}
+		}
+		if moved == 0 {
+			t.Error("changing the salt left every task on its arm")
+		}
+	})
+
+	t.Run("not enrolled", func(t *testing.T) {
+		withExperiment := NewDefaultAdaptiveManager(nil, nil, nil, &AdaptiveConfig{Experiment: &ExperimentConfig{AdaptedFraction: 0.5}})
+		if arm := withExperiment.ExperimentArm(""); arm != "" {
+			t.Errorf("task without an ID assigned to %q", arm)
+		}
+		if arm := NewDefaultAdaptiveManager(nil, nil, nil, nil).ExperimentArm("task-1"); arm != "" {
+			t.Errorf("task assigned to %q without an experiment", arm)
+		}
+	})
+}
+
+// TestExperimentArmsReachFeedback tests that baseline tasks skip learned
+// preferences and that collected feedback reports quality by arm
+func TestExperimentArmsReachFeedback(t *testing.T) {
+	project := newTestProject(map[string]int{"/project/main.go": 100, "/project/util.go": 100})
+	manager := NewDefaultAdaptiveManager(newTestOptimizer(map[string]float64{"/project/main.go": 0.9, "/project/util.go": 0.5}), nil, nil, &AdaptiveConfig{
+		MinSamplesForAdaptation:  1,
+		EnableStrategyAdaptation: true,
+		QualityThreshold:         0.5,
+		Experiment:               &ExperimentConfig{AdaptedFraction: 0.5},
+	})
+	if err := manager.LearnFromFeedback(&ContextFeedback{
+		Task:              &Task{Type: TaskTypeDebug},
+		TaskSuccess:       true,
+		QualityScore:      0.9,
+		PreferredStrategy: StrategyFreshness,
+	}); err != nil {
+		t.Fatalf("LearnFromFeedback failed: %v", err)
+	}
+
+	ids := map[ExperimentArm]string{}
+	for i := 0; len(ids) < 2; i++ {
+		id := fmt.Sprintf("task-%d", i)
+		ids[manager.ExperimentArm(id)] = id
+	}
+
+	store := &sliceFeedbackStore{}
+	collector := NewDefaultFeedbackCollector(store, nil, nil)
+	for arm, expected := range map[ExperimentArm]SelectionStrategy{ArmAdapted: StrategyFreshness, ArmBaseline: StrategyDependency} {
+		task := &Task{ID: ids[arm], Type: TaskTypeDebug}
+		adapted, err := manager.AdaptOptimalContext(context.Background(), project, task, 8000)
+		if err != nil {
+			t.Fatalf("AdaptOptimalContext failed: %v", err)
+		}
+		if adapted.Arm != arm || adapted.Constraints.Strategy != expected {
+			t.Errorf("%s task selected with %q on arm %q, expected %q", arm, adapted.Constraints.Strategy, adapted.Arm, expected)
+		}
+
+		err = collector.CollectImplicitFeedback(task, adapted.SelectedContext, &TaskExecutionData{TaskID: task.ID, CompletionStatus: "success"})
+		if err != nil {
+			t.Fatalf("CollectImplicitFeedback failed: %v", err)
+		}
+	}
+
+	analysis, err := collector.AnalyzeFeedbackTrends(time.Hour)
+	if err != nil {
+		t.Fatalf("AnalyzeFeedbackTrends failed: %v", err)
+	}
+	for _, arm := range []ExperimentArm{ArmAdapted, ArmBaseline} {
+		if estimate := analysis.ArmEstimates[string(arm)]; estimate.Samples != 1 {
+			t.Errorf("%s estimate = %+v, expected one sample", arm, estimate)
+		}
+	}
+}
+
+// TestAdaptiveManagerConcurrentUse tests that adapting and learning from
+// feedback at the same time is safe; run it with -race
+func TestAdaptiveManagerConcurrentUse(t *testing.T) {
+	project := newTestProject(map[string]int{"/project/main.go": 100, "/project/util.go": 100})
+	manager := NewDefaultAdaptiveManager(newTestOptimizer(map[string]float64{"/project/main.go": 0.9, "/project/util.go": 0.5}), nil, nil, &AdaptiveConfig{
+		LearningRate:             0.1,
+		MinSamplesForAdaptation:  2,
+		FeedbackRetentionDays:    30,
+		EnableBudgetAdaptation:   true,
+		EnableStrategyAdaptation: true,
+		QualityThreshold:         0.5,
+		MaxBudgetAdjustment:      4000,
+		AdaptationAggressiveness: 0.5,
+	})
+	taskTypes := []TaskType{TaskTypeDebug, TaskTypeFeature, TaskTypeRefactor}
+	strategies := []SelectionStrategy{StrategyRelevance, StrategyFreshness, StrategyBalanced}
+	compressions := []CompressionStrategy{CompressionMinify, CompressionSummary}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				task := &Task{ID: fmt.Sprintf("adapt-%d-%d", worker, i), Type: taskTypes[i%len(taskTypes)]}
+				if _, err := manager.AdaptOptimalContext(context.Background(), project, task, 8000); err != nil {
+					errs <- err
+					return
+				}
+				manager.RecommendCompression(task.Type)
+				manager.PredictOptimalBudget(task, project)
+				manager.GetProfileStatistics()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				err := manager.LearnFromFeedback(&ContextFeedback{
+					Task:                &Task{Type: taskTypes[i%len(taskTypes)]},
+					SelectedContext:     &SelectedContext{TotalFiles: 2, TotalTokens: 200, Strategy: strategies[i%len(strategies)]},
+					TaskSuccess:         i%4 != 0,
+					QualityScore:        0.8,
+					PreferredStrategy:   strategies[(i+worker)%len(strategies)],
+					CompressionStrategy: compressions[i%len(compressions)],
+					MissingFiles:        []string{"/project/util.go"},
+					Timestamp:           time.Now(),
+				})
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent use failed: %v", err)
+	}
+
+	samples := 0
+	for _, profile := range manager.GetProfileStatistics() {
+		samples += profile.SampleCount
+	}
+	if samples != 200 {
+		t.Errorf("profiles hold %d samples, expected 200", samples)
+	}
+}
@@ -0,0 +1,219 @@
+package context
+
+import (
+	"context"
+	"strings"
+)
+
+// minAllocationTokens is the smallest share worth giving a file; files whose
+// share would be smaller are dropped so the rest get more
+const minAllocationTokens = 64
+
+// allocationTruncationMarker ends content cut to fit its allocation
+const allocationTruncationMarker = "// ... truncated to fit token allocation ..."
+
+// allocateBudget splits the token budget across the top MaxFiles candidates in
+// proportion to their relevance, compressing or truncating each file that
+// doesn't fit its share. Each selected file records "allocated_tokens",
+// "original_tokens" and "actual_tokens" in its metadata.
+func (o *DefaultOptimizer) allocateBudget(ranked []ContextFile, task *Task, constraints *ContextConstraints) []ContextFile {
+	pool := ranked
+	if len(pool) > constraints.MaxFiles {
+		pool = pool[:constraints.MaxFiles]
+	}
+
+	allocations := computeAllocations(pool, constraints.MaxTokens)
+	selected := make([]ContextFile, 0, len(pool))
+	for i, file := range pool {
+		if allocations[i] <= 0 {
+			continue
+		}
+
+		original := file.FileInfo.TokenCount
+		method := string(CompressionNone)
+		if original > allocations[i] {
+			fitted, fittedMethod, ok := o.fitToAllocation(file, task, allocations[i])
+			if !ok {
+				continue
+			}
+			file, method = fitted, fittedMethod
+		}
+
+		file.Metadata = copyMetadata(file.Metadata)
+		file.Metadata["allocated_tokens"] = allocations[i]
+		file.Metadata["original_tokens"] = original
+		file.Metadata["actual_tokens"] = file.FileInfo.TokenCount
+		file.Metadata["allocation_method"] = method
+		selected = append(selected, file)
+	}
+	return selected
+}
+
+// computeAllocations returns each file's token cap, 0 for dropped files. Shares
+// are proportional to relevance; files smaller than their share keep their
+// full size and the surplus is shared among the rest. While the smallest
+// share is below minAllocationTokens the least relevant file is dropped.
+func computeAllocations(files []ContextFile, budget int) []int {
+	allocations := make([]int, len(files))
+	weight := func(i int) float64 {
+		return max(files[i].RelevanceScore, 0.01)
+	}
+
+	active := make([]int, 0, len(files))
+	for i := range files {
+		active = append(active, i)
+	}
+
+	for len(active) > 0 && budget > 0 {
+		totalWeight := 0.0
+		for _, i := range active {
+			totalWeight += weight(i)
+		}
+		share := func(i int) int {
+			return int(float64(budget) * weight(i) / totalWeight)
+		}
+
+		// Files within their share are taken whole
+		var remaining []int
+		for _, i := range active {
+			if tokens := files[i].FileInfo.TokenCount; tokens <= share(i) {
+				allocations[i] = tokens
+				budget -= tokens
+			} else {
+				remaining = append(remaining, i)
+			}
+		}
+		if len(remaining) < len(active) {
+			active = remaining
+			continue
+		}
+
+		// Drop the least relevant file, the lowest ranked among ties
+		lowest := active[0]
+		for _, i := range active {
+			if weight(i) <= weight(lowest) {
+				lowest = i
+			}
+		}
+		if share(lowest) < minAllocationTokens {
+			active = removeIndex(active, lowest)
+			continue
+		}
+
+		for _, i := range active {
+			allocations[i] = share(i)
+		}
+		break
+	}
+	return allocations
+}
+
+// fitToAllocation shrinks a file to at most allocation tokens with the least
+// lossy compression that fits, truncating when none does. Task-aware
+// compression keeps what the task asks about. It reports false when the
+// file's content can't be read.
+func (o *DefaultOptimizer) fitToAllocation(file ContextFile, task *Task, allocation int) (ContextFile, string, bool) {
+	content, ok := loadContextFileContent(file)
+	if !ok {
+		return file, "", false
+	}
+	file.Content = content
+
+	fitted, method, tokens := "", "", 0
+	if o.compressor != nil {
+		for _, strategy := range budgetCompressionLadder {
+			compressed, err := o.compressor.Compress(context.Background(), &SelectedContext{Task: task, Files: []ContextFile{file}}, strategy)
+			if err != nil || len(compressed.CompressedFiles) != 1 {
+				continue
+			}
+			result := compressed.CompressedFiles[0].CompressedContent
+			if count, err := o.analyzer.CountTokens(result); err == nil && count <= allocation {
+				fitted, method, tokens = result, string(strategy), count
+				break
+			}
+		}
+	}
+	if method == "" {
+		fitted, tokens = o.truncateToTokens(content, allocation)
+		method = "truncate"
+	}
+
+	info := *file.FileInfo
+	info.TokenCount = tokens
+	file.FileInfo = &info
+	file.Content = fitted
+	return file, method, true
+}
+
+// truncateToTokens keeps whole leading lines of content, plus a marker, within
+// limit tokens and returns the result with its token count
+func (o *DefaultOptimizer) truncateToTokens(content string, limit int) (string, int) {
+	markerTokens, _ := o.analyzer.CountTokens(allocationTruncationMarker)
+	budget := limit - markerTokens
+
+	var kept []string
+	for _, line := range strings.Split(content, "\n") {
+		tokens, err := o.analyzer.CountTokens(line)
+		if err != nil || tokens > budget {
+			break
+		}
+		budget -= tokens
+		kept = append(kept, line)
+	}
+
+	// Per-line counts can undercount the joined text, so verify and back off
+	for {
+		truncated := strings.Join(append(kept, allocationTruncationMarker), "\n")
+		tokens, err := o.analyzer.CountTokens(truncated)
+		if err == nil && tokens <= limit || len(kept) == 0 {
+			return truncated, tokens
+		}
+		kept = kept[:len(kept)-1]
+	}
+}
+
+// removeIndex returns indices without value, preserving order
+func removeIndex(indices []int, value int) []int {
+	result := make([]int, 0, len(indices))
+	for _, i := range indices {
+		if i != value {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// budgetOverflowKey is the SelectedContext metadata key holding a
+// BudgetOverflow
+const budgetOverflowKey = "budget_exceeded_by_single_file"
+
+// BudgetOverflow explains a selection whose best candidate alone was larger
+// than the whole token budget
+type BudgetOverflow struct {
+	Path       string `json:"path"`
+	FileTokens int    `json:"file_tokens"` // Size of the file before it was cut down
+	MaxTokens  int    `json:"max_tokens"`
+	Method     string `json:"method,omitempty"` // How the file was cut to fit; empty when its content couldn't be read and it was left out
+}
+
+// fitOversizedFile cuts a file larger than the budget down to it, so the
+// selection isn't left empty
+func (o *DefaultOptimizer) fitOversizedFile(file ContextFile, task *Task, budget int) ([]ContextFile, *BudgetOverflow) {
+	overflow := &BudgetOverflow{
+		Path:       file.FileInfo.Path,
+		FileTokens: file.FileInfo.TokenCount,
+		MaxTokens:  budget,
+	}
+	// Even a truncation marker can be over a tiny budget
+	fitted, method, ok := o.fitToAllocation(file, task, budget)
+	if !ok || fitted.FileInfo.TokenCount > budget {
+		return []ContextFile{}, overflow
+	}
+	overflow.Method = method
+
+	fitted.Metadata = copyMetadata(fitted.Metadata)
+	fitted.Metadata["original_tokens"] = overflow.FileTokens
+	fitted.Metadata["actual_tokens"] = fitted.FileInfo.TokenCount
+	fitted.Metadata["allocation_method"] = method
+	return []ContextFile{fitted}, overflow
+}
@@ -0,0 +1,199 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// alternativeStrategies are tried after the requested strategy when generating alternatives
+var alternativeStrategies = []SelectionStrategy{
+	StrategyBalanced,
+	StrategyRelevance,
+	StrategyDependency,
+	StrategyFreshness,
+	StrategyCompactness,
+}
+
+// diversityPenalty scales down a candidate's score for each alternative that
+// already includes it during diversity-penalized re-selection
+const diversityPenalty = 0.5
+
+// SelectAlternatives returns up to n distinct selections that each fit the
+// constraints, ranked by predicted quality. Alternatives come from each
+// selection strategy first, then from re-selecting with files already used
+// by earlier alternatives penalized. Fewer than n are returned when the
+// project doesn't allow more distinct selections.
+func (o *DefaultOptimizer) SelectAlternatives(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints, n int) ([]*SelectedContext, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("number of alternatives must be positive, got %d", n)
+	}
+	if constraints == nil {
+		constraints = o.getDefaultConstraints()
+	}
+	task = withKeywords(task)
+
+	alternatives := []*SelectedContext{}
+	seen := make(map[string]bool)
+	usage := make(map[string]int)
+
+	add := func(selection *SelectedContext) {
+		key := selectionKey(selection)
+		if len(selection.Files) == 0 || seen[key] {
+			return
+		}
+		seen[key] = true
+		alternatives = append(alternatives, selection)
+		for _, file := range selection.Files {
+			usage[file.FileInfo.Path]++
+		}
+	}
+
+	// One selection per strategy, starting with the requested one
+	strategies := []SelectionStrategy{constraints.Strategy}
+	for _, strategy := range alternativeStrategies {
+		if strategy != constraints.Strategy {
+			strategies = append(strategies, strategy)
+		}
+	}
+	for _, strategy := range strategies {
+		if len(alternatives) >= n {
+			break
+		}
+
+		strategyConstraints := *constraints
+		strategyConstraints.Strategy = strategy
+		selection, err := o.selectAlternative(ctx, project, task, &strategyConstraints, nil)
+		if err != nil {
+			return nil, err
+		}
+		add(selection)
+	}
+
+	// Re-select with the requested strategy while penalizing reused files,
+	// stopping once a round yields nothing new
+	for round := 1; len(alternatives) < n; round++ {
+		count := len(alternatives)
+		selection, err := o.selectAlternative(ctx, project, task, constraints, usage)
+		if err != nil {
+			return nil, err
+		}
+		selection.Metadata["diversity_round"] = round
+		add(selection)
+		if len(alternatives) == count {
+			break
+		}
+	}
+
+	// Rank by a strategy-independent quality estimate
+	eligible := o.eligibleRelevance(project, task, constraints)
+	for _, selection := range alternatives {
+		selection.Metadata["predicted_quality"] = o.predictSelectionQuality(selection, task, eligible)
+	}
+	sort.SliceStable(alternatives, func(i, j int) bool {
+		return alternatives[i].Metadata["predicted_quality"].(float64) > alternatives[j].Metadata["predicted_quality"].(float64)
+	})
+	for i, selection := range alternatives {
+		selection.Metadata["alternative_rank"] = i + 1
+	}
+
+	return alternatives, nil
+}
+
+// selectAlternative selects files without caching or recording session
+// history. Candidates used by earlier alternatives have their scores reduced
+// by diversityPenalty per use.
+func (o *DefaultOptimizer) selectAlternative(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints, usage map[string]int) (*SelectedContext, error) {
+	startTime := time.Now()
+
+	candidates, err := o.rankCandidates(ctx, project, task, constraints)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select files: %w", err)
+	}
+
+	if len(usage) > 0 {
+		penalized := make([]ContextFile, len(candidates))
+		copy(penalized, candidates)
+		for i := range penalized {
+			for uses := usage[penalized[i].FileInfo.Path]; uses > 0; uses-- {
+				penalized[i].RelevanceScore *= diversityPenalty
+			}
+		}
+		sort.SliceStable(penalized, func(i, j int) bool {
+			return penalized[i].RelevanceScore > penalized[j].RelevanceScore
+		})
+		candidates = penalized
+	}
+
+	files, overflow := o.applyTokenBudget(candidates, task, constraints)
+	selection := &SelectedContext{
+		Task:           task,
+		Files:          files,
+		TotalTokens:    o.calculateTotalTokens(files),
+		TotalFiles:     len(files),
+		SelectionScore: o.calculateSelectionScore(files, task),
+		Strategy:       constraints.Strategy,
+		Constraints:    constraints,
+		Metadata:       make(map[string]interface{}),
+		CreatedAt:      time.Now(),
+		SelectionTime:  time.Since(startTime),
+	}
+	if overflow != nil {
+		selection.Metadata[budgetOverflowKey] = overflow
+	}
+	return selection, nil
+}
+
+// eligibleRelevance returns the task relevance of every file the constraints allow
+func (o *DefaultOptimizer) eligibleRelevance(project *ProjectContext, task *Task, constraints *ContextConstraints) map[string]float64 {
+	relevance := make(map[string]float64)
+	for i := range project.Files {
+		file := &project.Files[i]
+		if o.shouldIncludeFile(file, task, constraints) {
+			relevance[file.Path] = o.analyzer.ScoreFileRelevance(file, task)
+		}
+	}
+	return relevance
+}
+
+// predictSelectionQuality estimates how useful a selection is regardless of
+// the strategy that produced it: the mean relevance of its files (precision)
+// averaged with the share of all eligible relevance it covers (recall)
+func (o *DefaultOptimizer) predictSelectionQuality(selection *SelectedContext, task *Task, eligible map[string]float64) float64 {
+	if len(selection.Files) == 0 {
+		return 0.0
+	}
+
+	totalEligible := 0.0
+	for _, score := range eligible {
+		totalEligible += score
+	}
+
+	selected := 0.0
+	for _, file := range selection.Files {
+		score, ok := eligible[file.FileInfo.Path]
+		if !ok {
+			score = o.analyzer.ScoreFileRelevance(file.FileInfo, task)
+		}
+		selected += score
+	}
+
+	precision := selected / float64(len(selection.Files))
+	recall := 0.0
+	if totalEligible > 0 {
+		recall = min(selected/totalEligible, 1.0)
+	}
+	return (precision + recall) / 2
+}
+
+// selectionKey identifies a selection by its set of file paths
+func selectionKey(selection *SelectedContext) string {
+	paths := make([]string, 0, len(selection.Files))
+	for _, file := range selection.Files {
+		paths = append(paths, file.FileInfo.Path)
+	}
+	sort.Strings(paths)
+	return strings.Join(paths, "\x00")
+}
@@ -0,0 +1,92 @@
+package context
+
+import (
+	"context"
+	"testing"
+)
+
+// TestSelectAlternatives tests that alternatives are distinct, within budget and ranked
+func TestSelectAlternatives(t *testing.T) {
+	scores := map[string]float64{
+		"handler.go": 0.9,
+		"service.go": 0.8,
+		"store.go":   0.7,
+		"model.go":   0.6,
+		"util.go":    0.4,
+		"config.go":  0.3,
+	}
+	project := newTestProject(map[string]int{
+		"handler.go": 400,
+		"service.go": 300,
+		"store.go":   300,
+		"model.go":   100,
+		"util.go":    200,
+		"config.go":  100,
+	})
+	task := &Task{Type: TaskTypeFeature, Description: "add endpoint"}
+
+	tests := []struct {
+		name        string
+		constraints *ContextConstraints
+		n           int
+		expected    int
+	}{
+		{
+			name:        "tight budget",
+			constraints: &ContextConstraints{MaxTokens: 700, MaxFiles: 10, MinRelevanceScore: 0.1, Strategy: StrategyRelevance},
+			n:           4,
+			expected:    4,
+		},
+		{
+			name:        "file limit",
+			constraints: &ContextConstraints{MaxTokens: 10000, MaxFiles: 2, MinRelevanceScore: 0.1, Strategy: StrategyBalanced},
+			n:           3,
+			expected:    3,
+		},
+		{
+			name:        "only one possible selection",
+			constraints: &ContextConstraints{MaxTokens: 10000, MaxFiles: 10, MinRelevanceScore: 0.1, Strategy: StrategyRelevance},
+			n:           3,
+			expected:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optimizer := newTestOptimizer(scores)
+			alternatives, err := optimizer.SelectAlternatives(context.Background(), project, task, tt.constraints, tt.n)
+			if err != nil {
+				t.Fatalf("SelectAlternatives failed: %v", err)
+			}
+			if len(alternatives) != tt.expected {
+				t.Fatalf("got %d alternatives, expected %d", len(alternatives), tt.expected)
+			}
+
+			seen := make(map[string]bool)
+			for i, alternative := range alternatives {
+				key := selectionKey(alternative)
+				if seen[key] {
+					t.Errorf("alternative %d duplicates an earlier one: %v", i, selectedPaths(alternative))
+				}
+				seen[key] = true
+
+				if alternative.TotalTokens > tt.constraints.MaxTokens || alternative.TotalFiles > tt.constraints.MaxFiles {
+					t.Errorf("alternative %d exceeds budget: %d tokens, %d files", i, alternative.TotalTokens, alternative.TotalFiles)
+				}
+				if i > 0 {
+					prev := alternatives[i-1].Metadata["predicted_quality"].(float64)
+					if quality := alternative.Metadata["predicted_quality"].(float64); quality > prev {
+						t.Errorf("alternative %d quality %.3f ranks above %.3f", i, quality, prev)
+					}
+				}
+				if rank := alternative.Metadata["alternative_rank"]; rank != i+1 {
+					t.Errorf("alternative %d has rank %v", i, rank)
+				}
+			}
+		})
+	}
+
+	if _, err := newTestOptimizer(scores).SelectAlternatives(context.Background(), project, task, nil, 0); err == nil {
+		t.Error("expected an error for n = 0")
+	}
+}
@@ -0,0 +1,138 @@
+package context
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// defaultAnalysisTTL is how long a cached project analysis is reused
+const defaultAnalysisTTL = 5 * time.Minute
+
+// CachingAnalyzer reuses project analyses by root path until they expire.
+// All other methods go straight to the wrapped analyzer.
+type CachingAnalyzer struct {
+	ContextAnalyzer
+	ttl     time.Duration
+	entries map[string]analysisEntry
+	mutex   sync.RWMutex
+}
+
+// analysisEntry is a cached project analysis
+type analysisEntry struct {
+	project    *ProjectContext
+	analyzedAt time.Time
+}
+
+// Ensure CachingAnalyzer implements ContextAnalyzer interface
+var _ ContextAnalyzer = (*CachingAnalyzer)(nil)
+
+// NewCachingAnalyzer wraps analyzer with a cache whose entries live for ttl,
+// or five minutes when ttl is zero
+func NewCachingAnalyzer(analyzer ContextAnalyzer, ttl time.Duration) *CachingAnalyzer {
+	if ttl <= 0 {
+		ttl = defaultAnalysisTTL
+	}
+	return &CachingAnalyzer{
+		ContextAnalyzer: analyzer,
+		ttl:             ttl,
+		entries:         make(map[string]analysisEntry),
+	}
+}
+
+// AnalyzeProject returns the cached analysis of rootPath, analyzing it when
+// there is none or it has expired
+func (c *CachingAnalyzer) AnalyzeProject(ctx context.Context, rootPath string) (*ProjectContext, error) {
+	key := analysisKey(rootPath)
+	if project, ok := c.Cached(key); ok {
+		return project, nil
+	}
+
+	project, err := c.ContextAnalyzer.AnalyzeProject(ctx, rootPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	c.entries[key] = analysisEntry{project: project, analyzedAt: time.Now()}
+	c.mutex.Unlock()
+	return project, nil
+}
+
+// Cached returns the unexpired analysis of rootPath, if any
+func (c *CachingAnalyzer) Cached(rootPath string) (*ProjectContext, bool) {
+	c.mutex.RLock()
+	entry, exists := c.entries[analysisKey(rootPath)]
+	c.mutex.RUnlock()
+
+	if !exists || time.Since(entry.analyzedAt) > c.ttl {
+		return nil, false
+	}
+	return entry.project, true
+}
+
+// Invalidate drops the cached analysis of rootPath
+func (c *CachingAnalyzer) Invalidate(rootPath string) {
+	c.mutex.Lock()
+	delete(c.entries, analysisKey(rootPath))
+	c.mutex.Unlock()
+}
+
+// Revalidating returns an analyzer that re-reads the project on every
+// AnalyzeProject, reusing the cached analysis only for files that haven't
+// changed, and caches the result. It suits servers with no watcher to
+// invalidate the cache when files change.
+func (c *CachingAnalyzer) Revalidating() ContextAnalyzer {
+	return revalidatingAnalyzer{c}
+}
+
+// revalidatingAnalyzer refreshes cached analyses instead of returning them
+type revalidatingAnalyzer struct {
+	*CachingAnalyzer
+}
+
+// AnalyzeProject re-analyzes rootPath, reusing its cached analysis
+func (r revalidatingAnalyzer) AnalyzeProject(ctx context.Context, rootPath string) (*ProjectContext, error) {
+	return r.Refresh(ctx, rootPath)
+}
+
+// analysisKey normalizes a root path so equivalent spellings share an entry
+func analysisKey(rootPath string) string {
+	if abs, err := filepath.Abs(rootPath); err == nil {
+		return abs
+	}
+	return filepath.Clean(rootPath)
+}
+
+// projectRefresher re-analyzes a project reusing an earlier analysis of it
+type projectRefresher interface {
+	RefreshProject(ctx context.Context, project *ProjectContext) (*ProjectContext, error)
+}
+
+// Refresh re-analyzes rootPath and caches the result, reusing the previous
+// analysis, even an expired one, when the wrapped analyzer supports it
+func (c *CachingAnalyzer) Refresh(ctx context.Context, rootPath string) (*ProjectContext, error) {
+	key := analysisKey(rootPath)
+	c.mutex.RLock()
+	entry, exists := c.entries[key]
+	c.mutex.RUnlock()
+
+	var project *ProjectContext
+	var err error
+	if refresher, ok := c.ContextAnalyzer.(projectRefresher); ok && exists {
+		project, err = refresher.RefreshProject(ctx, entry.project)
+	} else {
+		project, err = c.ContextAnalyzer.AnalyzeProject(ctx, key)
+	}
+	if err != nil {
+		// A stale analysis is worse than none once the files are known to have changed
+		c.Invalidate(key)
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	c.entries[key] = analysisEntry{project: project, analyzedAt: time.Now()}
+	c.mutex.Unlock()
+	return project, nil
+}
@@ -0,0 +1,38 @@
+package context
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRevalidatingAnalyzer tests that a revalidating analyzer sees files
+// written since the cached analysis, which the cache itself keeps serving
+func TestRevalidatingAnalyzer(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{"main.go": "package main\n"})
+
+	analyzer := NewCachingAnalyzer(NewDefaultAnalyzer(NewSimpleTokenCounter(), nil), time.Hour)
+	if _, err := analyzer.AnalyzeProject(context.Background(), root); err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+	writeProjectFiles(t, root, map[string]string{"util.go": "package main\n"})
+
+	if project, _ := analyzer.AnalyzeProject(context.Background(), root); project.TotalFiles != 1 {
+		t.Fatalf("cached analysis has %d files, expected the 1 analyzed first", project.TotalFiles)
+	}
+	project, err := analyzer.Revalidating().AnalyzeProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+	if project.TotalFiles != 2 {
+		t.Errorf("revalidated analysis has %d files, expected 2", project.TotalFiles)
+	}
+	if cached, ok := analyzer.Cached(root); !ok || cached != project {
+		t.Error("expected the revalidated analysis to replace the cached one")
+	}
+	if _, err := analyzer.Revalidating().GetFileInfo(context.Background(), filepath.Join(root, "util.go")); err != nil {
+		t.Errorf("GetFileInfo failed: %v", err)
+	}
+}
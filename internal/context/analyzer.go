@@ -2,13 +2,22 @@ package context
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 )
 
+// ErrNoAnalyzableFiles is returned when a project has no files left after
+// ignore patterns and size limits are applied
+var ErrNoAnalyzableFiles = errors.New("no analyzable files in project")
+
 // TaskType represents different types of coding tasks for context optimization
 type TaskType string
 
@@ -27,16 +36,32 @@ type FileInfo struct {
 	Size         int64             `json:"size"`
 	TokenCount   int               `json:"token_count"`
 	LastModified time.Time         `json:"last_modified"`
+	LastCommit   time.Time         `json:"last_commit"` // Zero when the file isn't committed in git
+	Churn        int               `json:"churn"`       // Commits touching the file within the churn window
+	ContentHash  string            `json:"content_hash"` // SHA-256 of the content FileType and Language were detected from
 	FileType     string            `json:"file_type"`
 	Language     string            `json:"language"`
 	RelevanceScore float64         `json:"relevance_score"`
 	Dependencies []string          `json:"dependencies"`
+	Root         string            `json:"root,omitempty"` // The project root the file was found under, set in multi-root analyses
+	Generated    bool              `json:"generated,omitempty"` // Output of a code generator or minifier, by name or header
 	Metadata     map[string]interface{} `json:"metadata"`
+	files        *projectFiles          // Where the content is read from; the local disk when nil
+}
+
+// FreshnessTime returns when the file last changed, preferring its last commit
+// time over the filesystem modification time
+func (f *FileInfo) FreshnessTime() time.Time {
+	if !f.LastCommit.IsZero() {
+		return f.LastCommit
+	}
+	return f.LastModified
 }
 
 // ProjectContext represents the analyzed context of a project
 type ProjectContext struct {
 	RootPath      string                `json:"root_path"`
+	Roots         []string              `json:"roots,omitempty"` // Analyzed roots under RootPath, set in multi-root analyses
 	TotalFiles    int                   `json:"total_files"`
 	TotalTokens   int                   `json:"total_tokens"`
 	Files         []FileInfo            `json:"files"`
@@ -59,6 +84,7 @@ type DependencyNode struct {
 	Exports      []string `json:"exports"`
 	Dependencies []string `json:"dependencies"`
 	Dependents   []string `json:"dependents"`
+	ExternalImports []string `json:"external_imports,omitempty"` // Imports outside the project, which create no edges
 }
 
 // DependencyEdge represents a dependency relationship
@@ -85,8 +111,8 @@ type ContextAnalyzer interface {
 	// AnalyzeProject performs comprehensive project analysis
 	AnalyzeProject(ctx context.Context, rootPath string) (*ProjectContext, error)
 	
-	// ScoreFileRelevance calculates relevance score for a file given a task type
-	ScoreFileRelevance(file *FileInfo, taskType TaskType, taskDescription string) float64
+	// ScoreFileRelevance calculates relevance score for a file given a task
+	ScoreFileRelevance(file *FileInfo, task *Task) float64
 	
 	// BuildDependencyGraph constructs dependency relationships between files
 	BuildDependencyGraph(ctx context.Context, files []FileInfo) (*DependencyGraph, error)
@@ -110,6 +136,9 @@ type DefaultAnalyzer struct {
 	depAnalyzer  DependencyAnalyzer
 	scorer       RelevanceScorer
 	config       *AnalyzerConfig
+	gitHistory   gitHistorySource
+	detect       func(filePath string, content []byte) (fileType, language string)
+	fsys         fs.FS // Project tree analyses read instead of the local disk, when set
 }
 
 // AnalyzerConfig contains configuration for the context analyzer
@@ -119,6 +148,43 @@ type AnalyzerConfig struct {
 	SupportedLanguages map[string][]string `json:"supported_languages"`
 	TokenCountCache   bool              `json:"token_count_cache"`
 	EnableProfiling   bool              `json:"enable_profiling"`
+	EnableGitFreshness bool             `json:"enable_git_freshness"` // Populate FileInfo.LastCommit from git history
+	ChurnWindowDays   int               `json:"churn_window_days"`    // Populate FileInfo.Churn over this many days; 0 disables
+	FileTypeRules     []FileTypeRule    `json:"file_type_rules"`      // Checked in order before the built-in classification
+	SkipBinaryFiles   bool              `json:"skip_binary_files"`    // Leave out files whose content is binary instead of tokenizing them
+	DetectGeneratedFiles bool           `json:"detect_generated_files"`  // Mark generated files and lower their relevance
+	ExcludeGeneratedFiles bool          `json:"exclude_generated_files"` // Leave generated files out of project analyses entirely
+}
+
+// FileTypeRule assigns FileType to files matching Pattern. A pattern starting
+// with "." matches a file name suffix such as ".proto" or ".pb.go", a pattern
+// containing "/" is a glob matched against that many trailing path elements,
+// and anything else is a glob matched against the file name, so "BUILD" and
+// "*_spec.rb" both work.
+type FileTypeRule struct {
+	Pattern  string `json:"pattern"`
+	FileType string `json:"file_type"`
+}
+
+// AnalysisProgress is how far a project analysis has got
+type AnalysisProgress struct {
+	FilesScanned  int    // Files visited, including ignored and oversized ones
+	FilesAnalyzed int    // Files added to the project
+	BytesRead     int64  // Size of the files analyzed
+	CurrentPath   string // File visited last
+}
+
+// AnalysisProgressFunc is told how far an analysis has got
+type AnalysisProgressFunc func(progress AnalysisProgress)
+
+// analysisProgressKey carries an AnalysisProgressFunc in a context
+type analysisProgressKey struct{}
+
+// WithAnalysisProgress returns a context under which project analyses call
+// progress after each file they visit, so callers can show progress through
+// large trees
+func WithAnalysisProgress(ctx context.Context, progress AnalysisProgressFunc) context.Context {
+	return context.WithValue(ctx, analysisProgressKey{}, progress)
 }
 
 // TokenCounter provides token counting capabilities
@@ -149,6 +215,10 @@ func NewDefaultAnalyzer(tokenCounter TokenCounter, config *AnalyzerConfig) *Defa
 			},
 			TokenCountCache: true,
 			EnableProfiling: false,
+			EnableGitFreshness: true,
+			ChurnWindowDays:    90,
+			SkipBinaryFiles:      true,
+			DetectGeneratedFiles: true,
 		}
 	}
 	
@@ -158,64 +228,82 @@ func NewDefaultAnalyzer(tokenCounter TokenCounter, config *AnalyzerConfig) *Defa
 	// Create relevance scorer
 	scorer := NewSemanticRelevanceScorer(nil)
 	
-	return &DefaultAnalyzer{
+	analyzer := &DefaultAnalyzer{
 		tokenCounter: tokenCounter,
 		depAnalyzer:  depAnalyzer,
 		scorer:       scorer,
 		config:       config,
+		gitHistory:   newGitHistoryCache().read,
 	}
+	analyzer.detect = analyzer.detectFile
+	return analyzer
 }
 
 // AnalyzeProject performs comprehensive project analysis
 func (a *DefaultAnalyzer) AnalyzeProject(ctx context.Context, rootPath string) (*ProjectContext, error) {
+	return a.analyzeProject(ctx, rootPath, nil, nil, nil)
+}
+
+// RefreshProject re-analyzes a previously analyzed project. Files whose
+// content is unchanged keep their detected type and language, and the
+// dependency graph is patched for the files that changed.
+func (a *DefaultAnalyzer) RefreshProject(ctx context.Context, project *ProjectContext) (*ProjectContext, error) {
+	previous := make(map[string]*FileInfo, len(project.Files))
+	for i := range project.Files {
+		previous[project.Files[i].Path] = &project.Files[i]
+	}
+	return a.analyzeProject(ctx, project.RootPath, project.Roots, previous, project.DependencyGraph)
+}
+
+// analyzeProject walks rootPath, or each of roots under it when given,
+// reusing detection results from previous by path and patching previousGraph
+// when one is given
+func (a *DefaultAnalyzer) analyzeProject(ctx context.Context, rootPath string, roots []string, previous map[string]*FileInfo, previousGraph *DependencyGraph) (*ProjectContext, error) {
 	startTime := time.Now()
 	
 	projectCtx := &ProjectContext{
 		RootPath:    rootPath,
+		Roots:       roots,
 		Files:       []FileInfo{},
 		Languages:   make(map[string]int),
 		CreatedAt:   startTime,
 	}
 	
-	err := filepath.Walk(rootPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		
-		// Skip directories and ignored files
-		if info.IsDir() || a.shouldIgnoreFile(path) {
-			return nil
-		}
-		
-		// Skip files that are too large
-		if info.Size() > a.config.MaxFileSize {
-			return nil
+	walkRoots := roots
+	if len(walkRoots) == 0 {
+		walkRoots = []string{rootPath}
+	}
+	source := a.projectFiles(rootPath)
+	var progress AnalysisProgress
+	for _, root := range walkRoots {
+		start := len(projectCtx.Files)
+		if err := a.walkRoot(ctx, source, root, previous, projectCtx, &progress); err != nil {
+			return nil, err
 		}
 		
-		fileInfo, err := a.GetFileInfo(ctx, path)
-		if err != nil {
-			// Log error but continue processing
-			return nil
+		rootFiles := projectCtx.Files[start:]
+		if len(roots) > 0 {
+			for i := range rootFiles {
+				rootFiles[i].Root = root
+			}
 		}
 		
-		projectCtx.Files = append(projectCtx.Files, *fileInfo)
-		projectCtx.TotalFiles++
-		projectCtx.TotalTokens += fileInfo.TokenCount
-		
-		// Update language statistics
-		if fileInfo.Language != "" {
-			projectCtx.Languages[fileInfo.Language]++
+		// Modification times are meaningless after a fresh clone, so prefer commit
+		// history for freshness and churn. An injected file system has no
+		// checkout to read history from.
+		if len(rootFiles) > 0 && source == nil {
+			a.applyGitHistory(ctx, root, rootFiles)
 		}
-		
-		return nil
-	})
+	}
 	
-	if err != nil {
-		return nil, fmt.Errorf("failed to walk project directory: %w", err)
+	// An empty project would otherwise look like a selection that found nothing relevant
+	if projectCtx.TotalFiles == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoAnalyzableFiles, rootPath)
 	}
 	
-	// Build dependency graph
-	dependencyGraph, err := a.BuildDependencyGraph(ctx, projectCtx.Files)
+	// Build dependency graph relative to the project root
+	depAnalyzer := newDependencyAnalyzer(rootPath, roots, source)
+	dependencyGraph, err := a.buildProjectGraph(ctx, depAnalyzer, projectCtx.Files, previous, previousGraph)
 	if err != nil {
 		// Don't fail the entire analysis if dependency graph fails
 		dependencyGraph = &DependencyGraph{
@@ -232,36 +320,252 @@ func (a *DefaultAnalyzer) AnalyzeProject(ctx context.Context, rootPath string) (
 	return projectCtx, nil
 }
 
+// projectFiles returns where analyses of rootPath read files from: the
+// injected file system as the tree under rootPath, or nil for the local disk
+func (a *DefaultAnalyzer) projectFiles(rootPath string) *projectFiles {
+	if a.fsys == nil {
+		return nil
+	}
+	return &projectFiles{root: rootPath, fsys: a.fsys}
+}
+
+// walkRoot adds the analyzable files under root to projectCtx, along with
+// their tokens and languages, counting every file visited in progress. Files
+// are read from source, or from os.DirFS(root) when it's nil.
+func (a *DefaultAnalyzer) walkRoot(ctx context.Context, source *projectFiles, root string, previous map[string]*FileInfo, projectCtx *ProjectContext, progress *AnalysisProgress) error {
+	report, _ := ctx.Value(analysisProgressKey{}).(AnalysisProgressFunc)
+	
+	fsys, dir, base := os.DirFS(root), ".", root
+	if source != nil {
+		name, err := source.name(root)
+		if err != nil {
+			return fmt.Errorf("failed to walk project directory: %w", err)
+		}
+		fsys, dir, base = source.fsys, name, source.root
+	}
+	
+	err := fs.WalkDir(fsys, dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		
+		path := filepath.Join(base, filepath.FromSlash(name))
+		progress.FilesScanned++
+		progress.CurrentPath = path
+		if a.addFile(ctx, source, path, info, previous, projectCtx) {
+			progress.FilesAnalyzed++
+			progress.BytesRead += info.Size()
+		}
+		if report != nil {
+			report(*progress)
+		}
+		
+		return nil
+	})
+	
+	if err != nil {
+		return fmt.Errorf("failed to walk project directory: %w", err)
+	}
+	return nil
+}
+
+// addFile analyzes the file at path into projectCtx, reporting false when
+// it's ignored, too large, unreadable or an excluded generated file
+func (a *DefaultAnalyzer) addFile(ctx context.Context, source *projectFiles, path string, info fs.FileInfo, previous map[string]*FileInfo, projectCtx *ProjectContext) bool {
+	if a.shouldIgnoreFile(path) || info.Size() > a.config.MaxFileSize {
+		return false
+	}
+	
+	fileInfo, err := a.readFileInfo(source, path, previous[path])
+	if err != nil {
+		// Skip files that can't be analyzed but continue processing
+		return false
+	}
+	if fileInfo.Generated && a.config.ExcludeGeneratedFiles {
+		return false
+	}
+	
+	projectCtx.Files = append(projectCtx.Files, *fileInfo)
+	projectCtx.TotalFiles++
+	projectCtx.TotalTokens += fileInfo.TokenCount
+	
+	// Update language statistics
+	if fileInfo.Language != "" {
+		projectCtx.Languages[fileInfo.Language]++
+	}
+	return true
+}
+
+// buildProjectGraph patches a copy of previousGraph for the files that
+// changed since previous, or builds the graph from scratch without one. A
+// changed go.mod can move every import, so it forces a rebuild.
+func (a *DefaultAnalyzer) buildProjectGraph(ctx context.Context, depAnalyzer *MultilanguageDependencyAnalyzer, files []FileInfo, previous map[string]*FileInfo, previousGraph *DependencyGraph) (*DependencyGraph, error) {
+	if previousGraph == nil {
+		return depAnalyzer.AnalyzeDependencies(ctx, files)
+	}
+	
+	current := make(map[string]bool, len(files))
+	var changed []string
+	for _, file := range files {
+		current[file.Path] = true
+		if prior, exists := previous[file.Path]; !exists || prior.ContentHash != file.ContentHash || prior.Language != file.Language {
+			changed = append(changed, file.Path)
+		}
+	}
+	for path := range previous {
+		if !current[path] {
+			changed = append(changed, path)
+		}
+	}
+	for _, path := range changed {
+		if filepath.Base(path) == "go.mod" {
+			return depAnalyzer.AnalyzeDependencies(ctx, files)
+		}
+	}
+	
+	graph := previousGraph.Clone()
+	if err := depAnalyzer.UpdateDependencies(ctx, graph, files, changed); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
 // GetFileInfo analyzes a single file
 func (a *DefaultAnalyzer) GetFileInfo(ctx context.Context, filePath string) (*FileInfo, error) {
-	stat, err := os.Stat(filePath)
+	return a.RefreshFileInfo(ctx, filePath, nil)
+}
+
+// RefreshFileInfo analyzes a single file on the local disk, reusing the file
+// type and language detected for previous when the content hash hasn't
+// changed. A nil previous always detects.
+func (a *DefaultAnalyzer) RefreshFileInfo(ctx context.Context, filePath string, previous *FileInfo) (*FileInfo, error) {
+	return a.readFileInfo(nil, filePath, previous)
+}
+
+// readFileInfo is RefreshFileInfo reading the file from source
+func (a *DefaultAnalyzer) readFileInfo(source *projectFiles, filePath string, previous *FileInfo) (*FileInfo, error) {
+	stat, err := source.Stat(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat file %s: %w", filePath, err)
 	}
 	
-	content, err := os.ReadFile(filePath)
+	content, err := source.ReadFile(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
 	}
 	
+	// Token counts of binary content are meaningless, so don't spend time on them
+	if a.config.SkipBinaryFiles && isBinaryContent(content) {
+		return nil, fmt.Errorf("%w: %s", ErrBinaryFile, filePath)
+	}
+	
 	tokenCount := 0
 	if a.tokenCounter != nil {
 		tokenCount, _ = a.tokenCounter.CountTokens(string(content))
 	}
 	
+	sum := sha256.Sum256(content)
+	contentHash := hex.EncodeToString(sum[:])
+	
+	var fileType, language string
+	if previous != nil && previous.ContentHash == contentHash && previous.FileType != "" {
+		fileType, language = previous.FileType, previous.Language
+	} else {
+		fileType, language = a.detect(filePath, content)
+	}
+	
 	fileInfo := &FileInfo{
 		Path:         filePath,
 		Size:         stat.Size(),
 		TokenCount:   tokenCount,
 		LastModified: stat.ModTime(),
-		FileType:     a.getFileType(filePath),
-		Language:     a.detectLanguage(filePath),
+		FileType:     fileType,
+		Language:     language,
+		ContentHash:  contentHash,
+		Generated:    (a.config.DetectGeneratedFiles || a.config.ExcludeGeneratedFiles) && isGeneratedFile(filePath, content),
 		Metadata:     make(map[string]interface{}),
+		files:        source,
 	}
 	
 	return fileInfo, nil
 }
 
+// detectFile determines a file's type and language. A matching
+// FileTypeRule overrides the built-in type; the language is always detected.
+func (a *DefaultAnalyzer) detectFile(filePath string, content []byte) (string, string) {
+	fileType, language := a.detectBuiltin(filePath, content)
+	if ruleType, ok := a.classifyByRules(filePath); ok {
+		fileType = ruleType
+	}
+	return fileType, language
+}
+
+// detectBuiltin applies the built-in checks. Well-known file names take
+// precedence over the extension, and content is checked when the extension
+// is missing or unrecognized.
+func (a *DefaultAnalyzer) detectBuiltin(filePath string, content []byte) (string, string) {
+	if detection, ok := detectKnownFilename(filePath); ok {
+		return detection.fileType, detection.language
+	}
+
+	fileType, language := a.getFileType(filePath), a.detectLanguage(filePath)
+	if language != "unknown" {
+		return fileType, language
+	}
+
+	if detection, ok := detectFromContent(content); ok {
+		language = detection.language
+		if fileType == "unknown" || filepath.Ext(filePath) == "" {
+			fileType = detection.fileType
+		}
+	}
+	return fileType, language
+}
+
+// classifyByRules returns the file type of the first configured rule
+// matching filePath
+func (a *DefaultAnalyzer) classifyByRules(filePath string) (string, bool) {
+	if len(a.config.FileTypeRules) == 0 {
+		return "", false
+	}
+	slashPath := filepath.ToSlash(filePath)
+	base := path.Base(slashPath)
+	for _, rule := range a.config.FileTypeRules {
+		if rule.Pattern == "" || rule.FileType == "" {
+			continue
+		}
+		var matched bool
+		switch {
+		case strings.HasPrefix(rule.Pattern, ".") && !strings.ContainsAny(rule.Pattern, "*?[/"):
+			matched = strings.HasSuffix(strings.ToLower(base), strings.ToLower(rule.Pattern))
+		case strings.Contains(rule.Pattern, "/"):
+			matched, _ = path.Match(rule.Pattern, trailingElements(slashPath, strings.Count(rule.Pattern, "/")+1))
+		default:
+			matched, _ = path.Match(rule.Pattern, base)
+		}
+		if matched {
+			return rule.FileType, true
+		}
+	}
+	return "", false
+}
+
+// trailingElements returns the last n elements of a slash-separated path
+func trailingElements(slashPath string, n int) string {
+	elements := strings.Split(slashPath, "/")
+	if len(elements) > n {
+		elements = elements[len(elements)-n:]
+	}
+	return strings.Join(elements, "/")
+}
+
 // shouldIgnoreFile checks if a file should be ignored based on patterns
 func (a *DefaultAnalyzer) shouldIgnoreFile(path string) bool {
 	for _, pattern := range a.config.IgnorePatterns {
@@ -352,16 +656,33 @@ func (a *DefaultAnalyzer) analyzeProjectStructure(projectCtx *ProjectContext) *C
 	return analysis
 }
 
-// ScoreFileRelevance calculates relevance score using the semantic scorer
-func (a *DefaultAnalyzer) ScoreFileRelevance(file *FileInfo, taskType TaskType, taskDescription string) float64 {
-	// Create a task object for scoring
-	task := &Task{
-		Type:        taskType,
-		Description: taskDescription,
-		Keywords:    []string{}, // Will be extracted from description
+// SetRelevanceScorer replaces the scorer behind ScoreFileRelevance, e.g. with
+// an EmbeddingRelevanceScorer. A nil scorer restores the default heuristic.
+func (a *DefaultAnalyzer) SetRelevanceScorer(scorer RelevanceScorer) {
+	if scorer == nil {
+		scorer = NewSemanticRelevanceScorer(nil)
 	}
-	
-	return a.scorer.ScoreFile(file, task)
+	a.scorer = scorer
+}
+
+// SetFileSystem makes project analyses read the tree under the analyzed root
+// from fsys instead of the local disk, so tests can use an fstest.MapFS and
+// remote checkouts can be analyzed in place. File paths keep the root as a
+// prefix, and content loaded later for selection comes from fsys too. A nil
+// fsys restores reading os.DirFS of the root.
+func (a *DefaultAnalyzer) SetFileSystem(fsys fs.FS) {
+	a.fsys = fsys
+}
+
+// ScoreFileRelevance calculates relevance score using the configured scorer.
+// Callers scoring many files should extract the task's keywords once up
+// front; a task without any has them extracted from its description per call.
+func (a *DefaultAnalyzer) ScoreFileRelevance(file *FileInfo, task *Task) float64 {
+	score := a.scorer.ScoreFile(file, withKeywords(task))
+	if file.Generated {
+		score *= generatedRelevanceFactor
+	}
+	return score
 }
 
 func (a *DefaultAnalyzer) BuildDependencyGraph(ctx context.Context, files []FileInfo) (*DependencyGraph, error) {
@@ -2,9 +2,14 @@ package context
 
 import (
 	"context"
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 // TestSimpleTokenCounter tests basic token counting functionality
@@ -175,10 +180,635 @@ func TestFileRelevanceScoring(t *testing.T) {
 	
 	for _, tt := range tests {
 		t.Run(string(tt.taskType), func(t *testing.T) {
-			score := analyzer.ScoreFileRelevance(file, tt.taskType, tt.description)
+			score := analyzer.ScoreFileRelevance(file, &Task{Type: tt.taskType, Description: tt.description})
 			if score < tt.minScore {
 				t.Errorf("Expected score >= %f, got %f", tt.minScore, score)
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// TestAnalyzeProjectNoAnalyzableFiles tests the empty-project signal
+func TestAnalyzeProjectNoAnalyzableFiles(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]int // path -> size in bytes
+	}{
+		{name: "Empty directory", files: map[string]int{}},
+		{name: "Only ignored files", files: map[string]int{"node_modules/lib/index.js": 100, "vendor/dep/dep.go": 100}},
+		{name: "Only oversized files", files: map[string]int{"data.bin": 2048}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			for name, size := range tt.files {
+				path := filepath.Join(tmpDir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("Failed to create dir: %v", err)
+				}
+				if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+					t.Fatalf("Failed to write %s: %v", name, err)
+				}
+			}
+
+			config := &AnalyzerConfig{
+				MaxFileSize:    1024,
+				IgnorePatterns: []string{"node_modules/*", "vendor/*"},
+			}
+			analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), config)
+
+			projectCtx, err := analyzer.AnalyzeProject(context.Background(), tmpDir)
+			if !errors.Is(err, ErrNoAnalyzableFiles) {
+				t.Fatalf("AnalyzeProject error = %v, expected ErrNoAnalyzableFiles", err)
+			}
+			if projectCtx != nil {
+				t.Errorf("expected no project context, got %d files", projectCtx.TotalFiles)
+			}
+		})
+	}
+}
+
+// TestAnalyzeProjectGitFreshness tests that commit times are preferred over
+// modification times, which are all equal after a fresh checkout
+func TestAnalyzeProjectGitFreshness(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	root := t.TempDir()
+	git := func(date string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", root}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+			"GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	git("", "init", "-q")
+	write("old/old.go", "package old\n")
+	write("recent.go", "package main\n")
+	git("2020-01-01T00:00:00Z", "add", ".")
+	git("2020-01-01T00:00:00Z", "commit", "-q", "-m", "initial")
+	write("recent.go", "package main\n\nfunc main() {}\n")
+	git("2024-06-01T00:00:00Z", "commit", "-q", "-am", "update")
+	write("dirty.go", "package main\n")
+	git("2024-06-01T00:00:00Z", "add", "dirty.go")
+	git("2024-06-01T00:00:00Z", "commit", "-q", "-m", "add dirty")
+	write("dirty.go", "package main\n\n// edited\n")
+	write("untracked.go", "package main\n")
+
+	// Simulate a fresh checkout where every file has the same mtime
+	checkout := time.Now()
+	for _, name := range []string{"old/old.go", "recent.go", "dirty.go", "untracked.go"} {
+		if err := os.Chtimes(filepath.Join(root, name), checkout, checkout); err != nil {
+			t.Fatalf("Failed to set mtime: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		disabled bool
+		expected map[string]string // file -> expected LastCommit, "" for zero
+	}{
+		{
+			name: "enabled",
+			expected: map[string]string{
+				"old/old.go":   "2020-01-01T00:00:00Z",
+				"recent.go":    "2024-06-01T00:00:00Z",
+				"dirty.go":     "",
+				"untracked.go": "",
+			},
+		},
+		{
+			name:     "disabled",
+			disabled: true,
+			expected: map[string]string{"old/old.go": "", "recent.go": "", "dirty.go": "", "untracked.go": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+			analyzer.config.EnableGitFreshness = !tt.disabled
+
+			project, err := analyzer.AnalyzeProject(context.Background(), root)
+			if err != nil {
+				t.Fatalf("AnalyzeProject failed: %v", err)
+			}
+
+			for _, file := range project.Files {
+				rel, _ := filepath.Rel(root, file.Path)
+				want, exists := tt.expected[filepath.ToSlash(rel)]
+				if !exists {
+					continue
+				}
+				if want == "" {
+					if !file.LastCommit.IsZero() || !file.FreshnessTime().Equal(file.LastModified) {
+						t.Errorf("%s: LastCommit = %v, expected fallback to mtime", rel, file.LastCommit)
+					}
+					continue
+				}
+				expected, _ := time.Parse(time.RFC3339, want)
+				if !file.LastCommit.Equal(expected) || !file.FreshnessTime().Equal(expected) {
+					t.Errorf("%s: LastCommit = %v, expected %v", rel, file.LastCommit, expected)
+				}
+			}
+		})
+	}
+
+	// The log is read only as far back as the analyzed files need, and
+	// re-analyses at the same HEAD reuse it
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	cache := newGitHistoryCache()
+	reads := 0
+	cache.readLog = func(ctx context.Context, root string, files map[string]bool, since time.Time) (*gitHistory, error) {
+		reads++
+		return readGitLog(ctx, root, files, since)
+	}
+	analyzer.gitHistory = cache.read
+	for i := 0; i < 2; i++ {
+		if _, err := analyzer.AnalyzeProject(context.Background(), root); err != nil {
+			t.Fatalf("AnalyzeProject failed: %v", err)
+		}
+	}
+	git("2024-07-01T00:00:00Z", "commit", "-q", "-am", "edit dirty")
+	if _, err := analyzer.AnalyzeProject(context.Background(), root); err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+	if reads != 2 {
+		t.Errorf("read the log %d times, expected once and again after the commit", reads)
+	}
+
+	history, err := readGitLog(context.Background(), root, map[string]bool{"recent.go": true}, time.Time{})
+	if err != nil {
+		t.Fatalf("readGitLog failed: %v", err)
+	}
+	if len(history.Commits) != 3 || history.Complete {
+		t.Errorf("read %d commits, expected to stop before the initial commit", len(history.Commits))
+	}
+	history, err = readGitLog(context.Background(), root, nil, time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("readGitLog failed: %v", err)
+	}
+	if len(history.Commits) != 1 || history.Complete {
+		t.Errorf("read %d commits, expected only the one in the churn window", len(history.Commits))
+	}
+}
+
+// TestApplyGitHistoryOutsideRepo tests the fallback when root isn't a git repository
+func TestApplyGitHistoryOutsideRepo(t *testing.T) {
+	files := []FileInfo{{Path: filepath.Join(t.TempDir(), "main.go"), LastModified: time.Now()}}
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	analyzer.applyGitHistory(context.Background(), filepath.Dir(files[0].Path), files)
+	if !files[0].LastCommit.IsZero() || files[0].Churn != 0 {
+		t.Errorf("LastCommit = %v, Churn = %d, expected zero values outside a repository", files[0].LastCommit, files[0].Churn)
+	}
+}
+
+// TestChurnRanksDebugCandidates tests that files changed often in recent
+// history rank higher for debug tasks
+func TestChurnRanksDebugCandidates(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{
+		"calm.go":   "package main\n",
+		"churny.go": "package main\n",
+	})
+
+	now := time.Now()
+	commit := func(daysAgo int, files ...string) gitCommit {
+		return gitCommit{Time: now.AddDate(0, 0, -daysAgo), Files: files}
+	}
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	analyzer.config.ChurnWindowDays = 30
+	analyzer.gitHistory = func(ctx context.Context, root string, files map[string]bool, since time.Time) (*gitHistory, error) {
+		return &gitHistory{Commits: []gitCommit{
+			commit(1, "churny.go"),
+			commit(3, "churny.go", "calm.go"),
+			commit(7, "churny.go"),
+			commit(12, "churny.go"),
+			commit(200, "calm.go"), // Outside the window
+			commit(300, "calm.go"),
+		}}, nil
+	}
+
+	project, err := analyzer.AnalyzeProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+
+	calmPath, churnyPath := filepath.Join(root, "calm.go"), filepath.Join(root, "churny.go")
+	churn := make(map[string]int)
+	for _, file := range project.Files {
+		churn[file.Path] = file.Churn
+	}
+	if churn[calmPath] != 1 || churn[churnyPath] != 4 {
+		t.Fatalf("churn = %v, expected calm.go 1 and churny.go 4", churn)
+	}
+
+	tests := []struct {
+		name     string
+		taskType TaskType
+		bias     float64
+		first    string
+	}{
+		{name: "debug task prefers churn", taskType: TaskTypeDebug, bias: 0.5, first: churnyPath},
+		{name: "zero bias ignores churn", taskType: TaskTypeDebug, bias: 0, first: calmPath},
+		{name: "feature task ignores churn", taskType: TaskTypeFeature, bias: 0.5, first: calmPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optimizer := newTestOptimizer(map[string]float64{calmPath: 0.6, churnyPath: 0.5})
+			constraints := &ContextConstraints{
+				MaxTokens:         1000,
+				MaxFiles:          10,
+				MinRelevanceScore: 0.1,
+				ChurnBias:         tt.bias,
+				Strategy:          StrategyRelevance,
+			}
+
+			selection, err := optimizer.SelectOptimalContext(context.Background(), project, &Task{Type: tt.taskType}, constraints)
+			if err != nil {
+				t.Fatalf("SelectOptimalContext failed: %v", err)
+			}
+			if paths := selectedPaths(selection); len(paths) != 2 || paths[0] != tt.first {
+				t.Errorf("selected %v, expected %s first", paths, tt.first)
+			}
+		})
+	}
+}
+
+// TestRefreshProjectReusesDetection tests that unchanged files keep their cached
+// detection while changed files are detected again
+func TestRefreshProjectReusesDetection(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{
+		"main.go":   "package main\n",
+		"README.md": "# Project\n",
+	})
+
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	detections := make(map[string]int)
+	analyzer.detect = func(filePath string, content []byte) (string, string) {
+		detections[filepath.Base(filePath)]++
+		return analyzer.detectFile(filePath, content)
+	}
+
+	project, err := analyzer.AnalyzeProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+
+	writeProjectFiles(t, root, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	refreshed, err := analyzer.RefreshProject(context.Background(), project)
+	if err != nil {
+		t.Fatalf("RefreshProject failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		detections int
+		fileType   string
+		language   string
+	}{
+		{name: "README.md", detections: 1, fileType: "documentation", language: "markdown"},
+		{name: "main.go", detections: 2, fileType: "source", language: "go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if detections[tt.name] != tt.detections {
+				t.Errorf("detected %d times, expected %d", detections[tt.name], tt.detections)
+			}
+			for _, file := range refreshed.Files {
+				if filepath.Base(file.Path) != tt.name {
+					continue
+				}
+				if file.FileType != tt.fileType || file.Language != tt.language {
+					t.Errorf("detection = %s/%s, expected %s/%s", file.FileType, file.Language, tt.fileType, tt.language)
+				}
+				if file.ContentHash == "" {
+					t.Error("ContentHash not set")
+				}
+			}
+		})
+	}
+}
+
+// TestDetectFileFromContent tests detection of files without standard extensions
+func TestDetectFileFromContent(t *testing.T) {
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+
+	tests := []struct {
+		name     string
+		path     string
+		content  string
+		fileType string
+		language string
+	}{
+		{name: "python shebang", path: "/project/bin/deploy", content: "#!/usr/bin/env python3\nprint('hi')\n", fileType: "script", language: "python"},
+		{name: "node shebang with env flags", path: "/project/bin/serve", content: "#!/usr/bin/env -S node --no-warnings\nconsole.log(1)\n", fileType: "script", language: "javascript"},
+		{name: "shell shebang on .sh", path: "/project/build.sh", content: "#!/bin/bash\nset -e\n", fileType: "script", language: "shell"},
+		{name: "dockerfile", path: "/project/Dockerfile", content: "FROM golang:1.24\n", fileType: "configuration", language: "dockerfile"},
+		{name: "dockerfile variant", path: "/project/Dockerfile.dev", content: "FROM golang:1.24\n", fileType: "configuration", language: "dockerfile"},
+		{name: "makefile", path: "/project/Makefile", content: "build:\n\tgo build ./...\n", fileType: "script", language: "makefile"},
+		{name: "go.mod", path: "/project/go.mod", content: "module example.com/app\n", fileType: "configuration", language: "go-module"},
+		{name: "known name beats extension", path: "/project/requirements.txt", content: "requests==2.31\n", fileType: "configuration", language: "pip-requirements"},
+		{name: "go signature", path: "/project/gen/model", content: "// Code generated. DO NOT EDIT.\n\npackage model\n", fileType: "source", language: "go"},
+		{name: "json signature", path: "/project/.eslintrc", content: "{\"root\": true}\n", fileType: "configuration", language: "json"},
+		{name: "extension wins", path: "/project/main.go", content: "#!/bin/sh\n", fileType: "source", language: "go"},
+		{name: "unrecognized", path: "/project/notes", content: "remember the milk\n", fileType: "unknown", language: "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileType, language := analyzer.detectFile(tt.path, []byte(tt.content))
+			if fileType != tt.fileType || language != tt.language {
+				t.Errorf("detectFile(%s) = (%s, %s), expected (%s, %s)", tt.path, fileType, language, tt.fileType, tt.language)
+			}
+		})
+	}
+}
+
+// TestGetFileInfoDetectsExtensionlessScript tests that analyzed files carry the
+// content-detected type and language
+func TestGetFileInfoDetectsExtensionlessScript(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{"tool": "#!/usr/bin/python3.12\nimport sys\n"})
+
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	info, err := analyzer.GetFileInfo(context.Background(), filepath.Join(root, "tool"))
+	if err != nil {
+		t.Fatalf("GetFileInfo failed: %v", err)
+	}
+	if info.FileType != "script" || info.Language != "python" {
+		t.Errorf("FileType/Language = %s/%s, expected script/python", info.FileType, info.Language)
+	}
+}
+
+// TestFileTypeRulesOverrideDefaults tests that configured rules reclassify
+// files and that the optimizer filters on the reclassified type
+func TestFileTypeRulesOverrideDefaults(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{
+		"main.go":            "package main\n\nfunc main() {}\n",
+		"walkthrough.doc.go": "package main\n\n// Walkthrough of the API\n",
+		"examples/client.go": "package examples\n",
+		"api/service.proto":  "syntax = \"proto3\";\n",
+		"spec/user_spec.rb":  "describe User do\nend\n",
+		"docs/notes.go":      "package docs\n",
+	})
+
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), &AnalyzerConfig{
+		MaxFileSize:        1024 * 1024,
+		SupportedLanguages: map[string][]string{"go": {".go"}, "ruby": {".rb"}},
+		FileTypeRules: []FileTypeRule{
+			{Pattern: ".doc.go", FileType: "documentation"},
+			{Pattern: "examples/*.go", FileType: "documentation"},
+			{Pattern: ".PROTO", FileType: "source"},
+			{Pattern: "*_spec.rb", FileType: "test"},
+			{Pattern: "notes.go", FileType: "documentation"},
+			{Pattern: "*.go", FileType: "test"}, // Later rules lose to earlier ones
+		},
+	})
+
+	project, err := analyzer.AnalyzeProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+	files := make(map[string]FileInfo)
+	for _, file := range project.Files {
+		relative, _ := filepath.Rel(root, file.Path)
+		files[filepath.ToSlash(relative)] = file
+	}
+
+	tests := []struct {
+		path     string
+		fileType string
+		language string
+		included bool // Kept by constraints that exclude docs and tests
+	}{
+		{path: "walkthrough.doc.go", fileType: "documentation", language: "go", included: false},
+		{path: "examples/client.go", fileType: "documentation", language: "go", included: false},
+		{path: "api/service.proto", fileType: "source", language: "unknown", included: true},
+		{path: "spec/user_spec.rb", fileType: "test", language: "ruby", included: false},
+		{path: "docs/notes.go", fileType: "documentation", language: "go", included: false},
+		{path: "main.go", fileType: "test", language: "go", included: false},
+	}
+
+	optimizer := NewDefaultOptimizer(analyzer, nil, nil, nil)
+	constraints := &ContextConstraints{PreferredTypes: []string{"source"}}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			file, ok := files[tt.path]
+			if !ok {
+				t.Fatalf("%s not analyzed", tt.path)
+			}
+			if file.FileType != tt.fileType || file.Language != tt.language {
+				t.Errorf("detection = %s/%s, expected %s/%s", file.FileType, file.Language, tt.fileType, tt.language)
+			}
+			if included := optimizer.shouldIncludeFile(&file, nil, constraints); included != tt.included {
+				t.Errorf("shouldIncludeFile = %v, expected %v", included, tt.included)
+			}
+		})
+	}
+}
+
+// TestAnalyzeProjectBinaryAndGeneratedFiles tests that binary files are
+// skipped and generated files marked, down-weighted or excluded as configured
+func TestAnalyzeProjectBinaryAndGeneratedFiles(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{
+		"main.go":          "package main\n\nfunc main() {}\n",
+		"api/api.pb.go":    "package api\n\ntype Request struct{}\n",
+		"stringer.go":      "// Code generated by \"stringer -type=Kind\"; DO NOT EDIT.\n\npackage main\n",
+		"assets/logo.dat":  "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"static/app.js":    "var a=1;" + strings.Repeat("a+=1;", 300) + "\n",
+		"docs/generate.md": "# Generating\n\nRun go generate; its output says Code generated by stringer.\n",
+	})
+
+	tests := []struct {
+		name      string
+		configure func(config *AnalyzerConfig)
+		expected  map[string]bool // Analyzed path -> generated
+	}{
+		{
+			name:      "defaults",
+			configure: func(config *AnalyzerConfig) {},
+			expected: map[string]bool{
+				"main.go": false, "api/api.pb.go": true, "stringer.go": true,
+				"static/app.js": true, "docs/generate.md": false,
+			},
+		},
+		{
+			name:      "exclude generated",
+			configure: func(config *AnalyzerConfig) { config.ExcludeGeneratedFiles = true },
+			expected:  map[string]bool{"main.go": false, "docs/generate.md": false},
+		},
+		{
+			name: "detection off",
+			configure: func(config *AnalyzerConfig) {
+				config.SkipBinaryFiles, config.DetectGeneratedFiles = false, false
+			},
+			expected: map[string]bool{
+				"main.go": false, "api/api.pb.go": false, "stringer.go": false,
+				"assets/logo.dat": false, "static/app.js": false, "docs/generate.md": false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+			tt.configure(analyzer.config)
+			project, err := analyzer.AnalyzeProject(context.Background(), root)
+			if err != nil {
+				t.Fatalf("AnalyzeProject failed: %v", err)
+			}
+
+			analyzed := make(map[string]FileInfo)
+			for _, file := range project.Files {
+				relative, _ := filepath.Rel(root, file.Path)
+				analyzed[filepath.ToSlash(relative)] = file
+			}
+			if len(analyzed) != len(tt.expected) {
+				t.Errorf("analyzed %d files, expected %d", len(analyzed), len(tt.expected))
+			}
+			for path, generated := range tt.expected {
+				file, ok := analyzed[path]
+				if !ok {
+					t.Errorf("%s not analyzed", path)
+					continue
+				}
+				if file.Generated != generated {
+					t.Errorf("%s Generated = %v, expected %v", path, file.Generated, generated)
+				}
+			}
+		})
+	}
+
+	t.Run("generated files score lower", func(t *testing.T) {
+		analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+		file := &FileInfo{Path: filepath.Join(root, "api", "api.pb.go"), FileType: "source", Language: "go"}
+		original := analyzer.ScoreFileRelevance(file, &Task{Type: TaskTypeFeature, Description: "add request"})
+		file.Generated = true
+		if score := analyzer.ScoreFileRelevance(file, &Task{Type: TaskTypeFeature, Description: "add request"}); score >= original {
+			t.Errorf("generated score = %v, expected less than %v", score, original)
+		}
+	})
+
+	t.Run("binary files are reported", func(t *testing.T) {
+		analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+		if _, err := analyzer.GetFileInfo(context.Background(), filepath.Join(root, "assets", "logo.dat")); !errors.Is(err, ErrBinaryFile) {
+			t.Errorf("GetFileInfo error = %v, expected ErrBinaryFile", err)
+		}
+	})
+}
+
+// TestAnalyzeProjectReportsProgress tests that the progress callback fires
+// for every file visited, counting ignored files as scanned but not analyzed
+func TestAnalyzeProjectReportsProgress(t *testing.T) {
+	files := map[string]string{
+		"main.go":                   "package main\n\nfunc main() {}\n",
+		"util/util.go":              "package util\n\nfunc Helper() {}\n",
+		"README.md":                 "# Project\n",
+		"node_modules/lib/index.js": "module.exports = {}\n",
+	}
+	root := t.TempDir()
+	writeProjectFiles(t, root, files)
+
+	var reports []AnalysisProgress
+	ctx := WithAnalysisProgress(context.Background(), func(progress AnalysisProgress) {
+		reports = append(reports, progress)
+	})
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	if _, err := analyzer.AnalyzeProject(ctx, root); err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+
+	if len(reports) == 0 {
+		t.Fatal("expected the progress callback to fire")
+	}
+	last := reports[len(reports)-1]
+	var bytes int64
+	for name, content := range files {
+		if !strings.HasPrefix(name, "node_modules/") {
+			bytes += int64(len(content))
+		}
+	}
+	if last.FilesScanned != 4 || last.FilesAnalyzed != 3 || last.BytesRead != bytes {
+		t.Errorf("final progress = %+v, expected 4 scanned, 3 analyzed and %d bytes", last, bytes)
+	}
+	if !strings.HasPrefix(last.CurrentPath, root) {
+		t.Errorf("CurrentPath = %q, expected a path under %s", last.CurrentPath, root)
+	}
+}
+
+// TestAnalyzeProjectFromFileSystem tests that an injected file system is
+// walked and read in place of the local disk, for analysis, dependencies and
+// content loaded later
+func TestAnalyzeProjectFromFileSystem(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"go.mod":                    {Data: []byte("module example.com/app\n\ngo 1.22\n")},
+		"main.go":                   {Data: []byte("package main\n\nimport \"example.com/app/util\"\n\nfunc main() { util.Helper() }\n"), ModTime: modified},
+		"util/util.go":              {Data: []byte("package util\n\nfunc Helper() {}\n"), ModTime: modified},
+		"node_modules/lib/index.js": {Data: []byte("module.exports = {}\n")},
+	}
+	// The root doesn't exist on disk, so anything read from it came from fsys
+	root := filepath.Join(t.TempDir(), "missing")
+
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	analyzer.SetFileSystem(fsys)
+	project, err := analyzer.AnalyzeProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+
+	mainPath := filepath.Join(root, "main.go")
+	utilPath := filepath.Join(root, "util", "util.go")
+	var paths []string
+	var mainFile *FileInfo
+	for i := range project.Files {
+		paths = append(paths, project.Files[i].Path)
+		if project.Files[i].Path == mainPath {
+			mainFile = &project.Files[i]
+		}
+	}
+	if project.TotalFiles != 3 || mainFile == nil || !containsPath(paths, utilPath) {
+		t.Fatalf("analyzed files = %v, expected go.mod, main.go and util/util.go under %s", paths, root)
+	}
+	if mainFile.Language != "go" || mainFile.TokenCount == 0 || !mainFile.LastModified.Equal(modified) {
+		t.Errorf("main.go = %+v, expected a Go file with tokens modified at %v", mainFile, modified)
+	}
+
+	node, exists := project.DependencyGraph.Nodes["main.go"]
+	if !exists || !containsPath(node.Dependencies, "util/util.go") {
+		t.Errorf("main.go node = %+v, expected a dependency on util/util.go resolved through go.mod", node)
+	}
+
+	content, ok := loadContextFileContent(ContextFile{FileInfo: mainFile})
+	if !ok || content != string(fsys["main.go"].Data) {
+		t.Errorf("loaded content = %q (%v), expected main.go from the file system", content, ok)
+	}
+
+	// A file read from disk is unaffected by the injected file system
+	if _, err := analyzer.GetFileInfo(context.Background(), mainPath); err == nil {
+		t.Error("expected GetFileInfo to read the local disk and fail")
+	}
+}
@@ -0,0 +1,70 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// AnalyzeProjects analyzes several project roots, such as the modules of a
+// monorepo, into one project rooted at their closest common directory. Each
+// file records the root it was found under, and dependency edges cross
+// between roots wherever imports resolve, so selection works across the whole
+// workspace.
+func (a *DefaultAnalyzer) AnalyzeProjects(ctx context.Context, paths []string) (*ProjectContext, error) {
+	roots, err := workspaceRoots(paths)
+	if err != nil {
+		return nil, err
+	}
+	return a.analyzeProject(ctx, commonDir(roots), roots, nil, nil)
+}
+
+// workspaceRoots returns paths as absolute, cleaned roots without duplicates.
+// Nested roots are rejected, since their files would be analyzed twice.
+func workspaceRoots(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no project roots given")
+	}
+
+	roots := make([]string, 0, len(paths))
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		root, err := filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve project root %s: %w", path, err)
+		}
+		if seen[root] {
+			continue
+		}
+		for _, other := range roots {
+			if withinDir(other, root) || withinDir(root, other) {
+				return nil, fmt.Errorf("project roots %s and %s overlap", other, root)
+			}
+		}
+		seen[root] = true
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+// commonDir returns the closest directory containing every path
+func commonDir(paths []string) string {
+	common := paths[0]
+	for _, path := range paths[1:] {
+		for !withinDir(common, path) {
+			parent := filepath.Dir(common)
+			if parent == common {
+				break
+			}
+			common = parent
+		}
+	}
+	return common
+}
+
+// withinDir reports whether path is dir or lies under it
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
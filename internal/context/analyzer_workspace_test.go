@@ -0,0 +1,151 @@
+package context
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestAnalyzeProjectsAcrossRoots tests that two Go modules importing each
+// other are analyzed into one project with edges between them, per-root
+// attribution, and totals that add up to those of the roots analyzed alone
+func TestAnalyzeProjectsAcrossRoots(t *testing.T) {
+	workspace := t.TempDir()
+	apiRoot := filepath.Join(workspace, "api")
+	webRoot := filepath.Join(workspace, "web")
+	writeProjectFiles(t, apiRoot, map[string]string{
+		"go.mod":           "module example.com/api\n\ngo 1.24\n",
+		"model/model.go":   "package model\n\n// User is an account\ntype User struct{ Name string }\n",
+		"server/server.go": "package server\n\nimport \"example.com/web/render\"\n\nfunc Serve() string { return render.Page() }\n",
+	})
+	writeProjectFiles(t, webRoot, map[string]string{
+		"go.mod":           "module example.com/web\n\ngo 1.24\n",
+		"render/render.go": "package render\n\nimport \"example.com/api/model\"\n\nfunc Page() string { return model.User{}.Name }\n",
+		"README.md":        "# Web\n",
+	})
+
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	ctx := context.Background()
+	project, err := analyzer.AnalyzeProjects(ctx, []string{apiRoot, webRoot})
+	if err != nil {
+		t.Fatalf("AnalyzeProjects failed: %v", err)
+	}
+
+	if project.RootPath != workspace {
+		t.Errorf("RootPath = %s, expected the common directory %s", project.RootPath, workspace)
+	}
+
+	t.Run("cross-root edges", func(t *testing.T) {
+		expected := map[string]string{
+			"api/server/server.go": "web/render/render.go",
+			"web/render/render.go": "api/model/model.go",
+		}
+		for from, to := range expected {
+			node, exists := project.DependencyGraph.Nodes[from]
+			if !exists {
+				t.Fatalf("graph has no node %s", from)
+			}
+			if !containsPath(node.Dependencies, to) {
+				t.Errorf("%s dependencies = %v, expected %s", from, node.Dependencies, to)
+			}
+			if len(node.ExternalImports) > 0 {
+				t.Errorf("%s has unresolved imports %v", from, node.ExternalImports)
+			}
+		}
+	})
+
+	t.Run("per-root attribution", func(t *testing.T) {
+		for _, file := range project.Files {
+			expected := apiRoot
+			if strings.HasPrefix(file.Path, webRoot) {
+				expected = webRoot
+			}
+			if file.Root != expected {
+				t.Errorf("%s Root = %q, expected %q", file.Path, file.Root, expected)
+			}
+		}
+	})
+
+	t.Run("aggregated totals", func(t *testing.T) {
+		files, tokens := 0, 0
+		languages := make(map[string]int)
+		for _, root := range []string{apiRoot, webRoot} {
+			single, err := analyzer.AnalyzeProject(ctx, root)
+			if err != nil {
+				t.Fatalf("AnalyzeProject(%s) failed: %v", root, err)
+			}
+			files += single.TotalFiles
+			tokens += single.TotalTokens
+			for language, count := range single.Languages {
+				languages[language] += count
+			}
+		}
+		if project.TotalFiles != files || project.TotalTokens != tokens {
+			t.Errorf("totals = %d files, %d tokens; expected %d files, %d tokens", project.TotalFiles, project.TotalTokens, files, tokens)
+		}
+		if project.Languages["go"] != 3 || project.Languages["markdown"] != 1 {
+			t.Errorf("Languages = %v, expected 3 go and 1 markdown", project.Languages)
+		}
+		for language, count := range languages {
+			if project.Languages[language] != count {
+				t.Errorf("Languages[%s] = %d, expected %d", language, project.Languages[language], count)
+			}
+		}
+	})
+
+	t.Run("selection follows edges across roots", func(t *testing.T) {
+		server := filepath.Join(apiRoot, "server", "server.go")
+		optimizer := newTestOptimizer(map[string]float64{server: 0.9})
+		constraints := validConstraints()
+		constraints.Strategy = StrategyDependency
+		constraints.MinRelevanceScore = 0
+		selection, err := optimizer.SelectOptimalContext(ctx, project, &Task{Type: TaskTypeFeature}, constraints)
+		if err != nil {
+			t.Fatalf("SelectOptimalContext failed: %v", err)
+		}
+		paths := selectedPaths(selection)
+		for _, expected := range []string{server, filepath.Join(webRoot, "render", "render.go")} {
+			if !containsPath(paths, expected) {
+				t.Errorf("selected %v, expected %s", paths, expected)
+			}
+		}
+	})
+
+	t.Run("refresh keeps roots", func(t *testing.T) {
+		refreshed, err := analyzer.RefreshProject(ctx, project)
+		if err != nil {
+			t.Fatalf("RefreshProject failed: %v", err)
+		}
+		if refreshed.TotalFiles != project.TotalFiles || len(refreshed.Roots) != 2 {
+			t.Errorf("refreshed %d files over roots %v, expected %d files over 2 roots", refreshed.TotalFiles, refreshed.Roots, project.TotalFiles)
+		}
+	})
+}
+
+// TestWorkspaceRoots tests that roots are deduplicated and that nested roots
+// are rejected
+func TestWorkspaceRoots(t *testing.T) {
+	workspace := t.TempDir()
+	a, b := filepath.Join(workspace, "a"), filepath.Join(workspace, "b")
+
+	roots, err := workspaceRoots([]string{a, b, a + string(filepath.Separator)})
+	if err != nil {
+		t.Fatalf("workspaceRoots failed: %v", err)
+	}
+	sort.Strings(roots)
+	if len(roots) != 2 || roots[0] != a || roots[1] != b {
+		t.Errorf("roots = %v, expected [%s %s]", roots, a, b)
+	}
+	if dir := commonDir(roots); dir != workspace {
+		t.Errorf("commonDir = %s, expected %s", dir, workspace)
+	}
+
+	if _, err := workspaceRoots([]string{a, filepath.Join(a, "sub")}); err == nil {
+		t.Error("expected nested roots to be rejected")
+	}
+	if _, err := workspaceRoots(nil); err == nil {
+		t.Error("expected an empty root list to be rejected")
+	}
+}
@@ -0,0 +1,82 @@
+package context
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// balancedWeightTolerance is how far the balanced weights may sum from 1
+const balancedWeightTolerance = 0.01
+
+// BalancedWeights weighs the signals the balanced strategy combines into a
+// file's score. The weights are each between 0 and 1 and sum to 1.
+type BalancedWeights struct {
+	Relevance  float64 `json:"relevance"`
+	Centrality float64 `json:"centrality"` // Dependency graph centrality
+	Freshness  float64 `json:"freshness"`  // Scaled further by FreshnessBias
+	Size       float64 `json:"size"`       // Favors files under the large-file threshold
+}
+
+// DefaultBalancedWeights returns the weights used when constraints don't set
+// any: 50% relevance, 20% centrality, 15% freshness and 15% size
+func DefaultBalancedWeights() BalancedWeights {
+	return BalancedWeights{Relevance: 0.5, Centrality: 0.2, Freshness: 0.15, Size: 0.15}
+}
+
+// Sum returns the total of the weights
+func (w BalancedWeights) Sum() float64 {
+	return w.Relevance + w.Centrality + w.Freshness + w.Size
+}
+
+// Validate reports weights outside [0, 1] and a sum too far from 1
+func (w BalancedWeights) Validate() error {
+	var errs []error
+	for _, weight := range w.named() {
+		if math.IsNaN(weight.value) || weight.value < 0 || weight.value > 1 {
+			errs = append(errs, fmt.Errorf("balanced_weights.%s must be between 0 and 1, got %v", weight.name, weight.value))
+		}
+	}
+	if len(errs) == 0 && math.Abs(w.Sum()-1) > balancedWeightTolerance {
+		errs = append(errs, fmt.Errorf("balanced_weights must sum to 1, got %.3f", w.Sum()))
+	}
+	return errors.Join(errs...)
+}
+
+// Normalized returns the weights with negatives dropped to 0 and the rest
+// scaled to sum to 1. Weights that are all zero are returned as they are.
+func (w BalancedWeights) Normalized() BalancedWeights {
+	w.Relevance = math.Max(w.Relevance, 0)
+	w.Centrality = math.Max(w.Centrality, 0)
+	w.Freshness = math.Max(w.Freshness, 0)
+	w.Size = math.Max(w.Size, 0)
+	sum := w.Sum()
+	if sum <= 0 {
+		return w
+	}
+	return BalancedWeights{Relevance: w.Relevance / sum, Centrality: w.Centrality / sum, Freshness: w.Freshness / sum, Size: w.Size / sum}
+}
+
+// named lists the weights by their JSON names
+func (w BalancedWeights) named() []struct {
+	name  string
+	value float64
+} {
+	return []struct {
+		name  string
+		value float64
+	}{
+		{"relevance", w.Relevance},
+		{"centrality", w.Centrality},
+		{"freshness", w.Freshness},
+		{"size", w.Size},
+	}
+}
+
+// balancedWeights returns the constraints' balanced weights, or the defaults
+func (c *ContextConstraints) balancedWeights() BalancedWeights {
+	if c.BalancedWeights == nil {
+		return DefaultBalancedWeights()
+	}
+	return *c.BalancedWeights
+}
@@ -0,0 +1,68 @@
+package context
+
+import (
+	"context"
+	"testing"
+)
+
+// TestBalancedWeightsChangeRanking tests that the balanced strategy ranks
+// by the constraints' weights, so weighing size over relevance puts a small
+// file ahead of a more relevant large one
+func TestBalancedWeightsChangeRanking(t *testing.T) {
+	project := newTestProject(map[string]int{"/project/large.go": 8000, "/project/small.go": 400})
+	optimizer := newTestOptimizer(map[string]float64{"/project/large.go": 0.9, "/project/small.go": 0.5})
+	task := &Task{Type: TaskTypeFeature}
+
+	tests := []struct {
+		name     string
+		weights  *BalancedWeights
+		expected string // Path ranked first
+	}{
+		{name: "default weights favor relevance", expected: "/project/large.go"},
+		{
+			name:     "size weighed over relevance",
+			weights:  &BalancedWeights{Relevance: 0.3, Centrality: 0.1, Freshness: 0.05, Size: 0.55},
+			expected: "/project/small.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraints := validConstraints()
+			constraints.Strategy = StrategyBalanced
+			constraints.BalancedWeights = tt.weights
+			ranked, err := optimizer.selectByBalanced(context.Background(), project, task, constraints)
+			if err != nil {
+				t.Fatalf("selectByBalanced failed: %v", err)
+			}
+			if len(ranked) != 2 || ranked[0].FileInfo.Path != tt.expected {
+				t.Fatalf("ranking = %v, expected %s first", ranked, tt.expected)
+			}
+		})
+	}
+
+	t.Run("relevance alone scores by relevance", func(t *testing.T) {
+		constraints := validConstraints()
+		constraints.BalancedWeights = &BalancedWeights{Relevance: 1}
+		ranked, _ := optimizer.selectByBalanced(context.Background(), project, task, constraints)
+		if ranked[0].RelevanceScore != 0.9 || ranked[1].RelevanceScore != 0.5 {
+			t.Errorf("scores = %v, %v, expected the relevance scores 0.9, 0.5", ranked[0].RelevanceScore, ranked[1].RelevanceScore)
+		}
+	})
+}
+
+// TestBalancedWeightsNormalized tests that normalization rescales weights to
+// sum to 1 and drops negative ones
+func TestBalancedWeightsNormalized(t *testing.T) {
+	normalized := BalancedWeights{Relevance: 2, Centrality: 1, Freshness: 1, Size: -1}.Normalized()
+	expected := BalancedWeights{Relevance: 0.5, Centrality: 0.25, Freshness: 0.25}
+	if normalized != expected {
+		t.Errorf("Normalized() = %+v, expected %+v", normalized, expected)
+	}
+	if err := normalized.Validate(); err != nil {
+		t.Errorf("normalized weights are invalid: %v", err)
+	}
+	if err := DefaultBalancedWeights().Validate(); err != nil {
+		t.Errorf("default weights are invalid: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrBudgetInfeasible is matched by a BudgetInfeasibleError
+var ErrBudgetInfeasible = errors.New("token budget is too small for the task's mandatory files")
+
+// BudgetInfeasibleError reports that the files a task names explicitly don't
+// fit in a token budget, even compressed as far as the compressor goes. The
+// caller can raise the budget to MinimumBudget or split the task.
+type BudgetInfeasibleError struct {
+	Budget        int
+	MinimumBudget int      // Smallest budget the mandatory files fit in
+	Files         []string // Paths of the mandatory files
+}
+
+func (e *BudgetInfeasibleError) Error() string {
+	return fmt.Sprintf("%s: %d files need at least %d tokens, budget is %d", ErrBudgetInfeasible, len(e.Files), e.MinimumBudget, e.Budget)
+}
+
+func (e *BudgetInfeasibleError) Unwrap() error {
+	return ErrBudgetInfeasible
+}
+
+// mandatoryFiles returns the project files the task names explicitly that
+// constraints don't rule out, which a selection is wrong without
+func mandatoryFiles(project *ProjectContext, task *Task, constraints *ContextConstraints) []*FileInfo {
+	if task == nil {
+		return nil
+	}
+	var mandatory []*FileInfo
+	seen := make(map[string]bool)
+	for _, name := range task.Files {
+		for i := range project.Files {
+			file := &project.Files[i]
+			if seen[file.Path] || !matchesExplicitFile(project.RootPath, file.Path, name) || excludedExplicitly(file, constraints) {
+				continue
+			}
+			mandatory = append(mandatory, file)
+			seen[file.Path] = true
+		}
+	}
+	return mandatory
+}
+
+// includesFiles reports whether selection has every one of files
+func includesFiles(selection *SelectedContext, files []*FileInfo) bool {
+	selected := make(map[string]bool, len(selection.Files))
+	for _, file := range selection.Files {
+		selected[file.FileInfo.Path] = true
+	}
+	for _, file := range files {
+		if !selected[file.Path] {
+			return false
+		}
+	}
+	return true
+}
+
+// fitMandatoryFiles compresses the mandatory files alone, from the least to
+// the most lossy strategy, and returns selection holding just them with the
+// first strategy that fits tokenBudget. When none does, it returns a
+// BudgetInfeasibleError with the smallest size any strategy reached.
+func (o *DefaultOptimizer) fitMandatoryFiles(ctx context.Context, selection *SelectedContext, mandatory []*FileInfo, tokenBudget int) (*SelectedContext, error) {
+	files := make([]ContextFile, 0, len(mandatory))
+	paths := make([]string, 0, len(mandatory))
+	minimum := 0
+	readable := true
+	for _, info := range mandatory {
+		file := ContextFile{FileInfo: info, InclusionReason: "explicit", Priority: 1}
+		if content, ok := loadContextFileContent(file); ok {
+			file.Content = content
+		} else {
+			readable = false
+		}
+		files = append(files, file)
+		paths = append(paths, info.Path)
+		minimum += info.TokenCount
+	}
+
+	if o.compressor != nil && readable {
+		mandatorySelection := &SelectedContext{Task: selection.Task, Files: files, TotalTokens: minimum, TotalFiles: len(files)}
+		for _, strategy := range budgetCompressionLadder {
+			compressed, err := o.compressor.Compress(ctx, mandatorySelection, strategy)
+			if err != nil || len(compressed.CompressedFiles) != len(files) {
+				continue
+			}
+			tokens := 0
+			for _, file := range compressed.CompressedFiles {
+				tokens += file.CompressedTokens
+			}
+			if tokens <= tokenBudget {
+				fitted := compressedSelection(selection, files, compressed)
+				fitted.Metadata["mandatory_compression"] = string(compressed.Strategy)
+				return fitted, nil
+			}
+			if tokens < minimum {
+				minimum = tokens
+			}
+		}
+	}
+
+	return nil, &BudgetInfeasibleError{Budget: tokenBudget, MinimumBudget: minimum, Files: paths}
+}
+
+// compressedSelection returns a copy of selection holding files with the
+// content and token counts they were compressed to
+func compressedSelection(selection *SelectedContext, files []ContextFile, compressed *CompressedContext) *SelectedContext {
+	fitted := *selection
+	fitted.Files = make([]ContextFile, 0, len(files))
+	fitted.TotalTokens = 0
+	for i, file := range files {
+		result := compressed.CompressedFiles[i]
+		info := *file.FileInfo
+		info.TokenCount = result.CompressedTokens
+		file.FileInfo = &info
+		file.Content = result.CompressedContent
+		file.Metadata = copyMetadata(file.Metadata)
+		file.Metadata["original_tokens"] = result.OriginalTokens
+		fitted.Files = append(fitted.Files, file)
+		fitted.TotalTokens += result.CompressedTokens
+	}
+	fitted.TotalFiles = len(fitted.Files)
+	fitted.Metadata = copyMetadata(selection.Metadata)
+	return &fitted
+}
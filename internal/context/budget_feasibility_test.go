@@ -0,0 +1,203 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOptimizeForTokenBudgetInfeasible tests that mandatory files over the
+// budget produce a BudgetInfeasibleError with the budget they'd need, instead
+// of a selection without them
+func TestOptimizeForTokenBudgetInfeasible(t *testing.T) {
+	project := newTestProject(map[string]int{"/project/a.go": 600, "/project/b.go": 600, "/project/c.go": 100})
+	task := &Task{Type: TaskTypeFeature, Files: []string{"a.go", "b.go"}}
+	optimizer := newTestOptimizer(map[string]float64{"/project/a.go": 0.9, "/project/b.go": 0.8, "/project/c.go": 0.7})
+
+	tests := []struct {
+		name     string
+		budget   int
+		feasible bool
+	}{
+		{name: "mandatory files over budget", budget: 1000},
+		{name: "mandatory files exactly fit", budget: 1200, feasible: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := optimizer.OptimizeForTokenBudget(context.Background(), project, tt.budget, task)
+			if tt.feasible {
+				if err != nil {
+					t.Fatalf("OptimizeForTokenBudget failed: %v", err)
+				}
+				paths := selectedPaths(selection)
+				if !containsPath(paths, "/project/a.go") || !containsPath(paths, "/project/b.go") {
+					t.Errorf("selected %v, expected both mandatory files", paths)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrBudgetInfeasible) {
+				t.Fatalf("OptimizeForTokenBudget error = %v, expected ErrBudgetInfeasible", err)
+			}
+			var infeasible *BudgetInfeasibleError
+			if !errors.As(err, &infeasible) {
+				t.Fatalf("error %v is not a BudgetInfeasibleError", err)
+			}
+			if infeasible.MinimumBudget != 1200 || infeasible.Budget != tt.budget || len(infeasible.Files) != 2 {
+				t.Errorf("error = %+v, expected a minimum of 1200 for 2 files against %d", infeasible, tt.budget)
+			}
+		})
+	}
+}
+
+// TestOptimizeForTokenBudgetCompressesMandatoryFiles tests that mandatory
+// files over the budget are compressed to fit when a strategy gets them
+// there, and that the minimum reported otherwise accounts for compression
+func TestOptimizeForTokenBudgetCompressesMandatoryFiles(t *testing.T) {
+	root := t.TempDir()
+	var source strings.Builder
+	source.WriteString("package orders\n\n")
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&source, "// Step%d documents a step of order processing at length, so that\n// the comment outweighs the code it describes many times over\n// and stripping it saves most of the file.\nfunc Step%d() int {\n\treturn %d\n}\n\n", i, i, i)
+	}
+	writeProjectFiles(t, root, map[string]string{"orders.go": source.String(), "billing.go": strings.Replace(source.String(), "orders", "billing", 1)})
+
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	project, err := analyzer.AnalyzeProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	optimizer := NewDefaultOptimizer(analyzer, nil, compressor, nil)
+	task := &Task{Type: TaskTypeFeature, Files: []string{"orders.go", "billing.go"}}
+	budget := project.TotalTokens - 1
+
+	selection, err := optimizer.OptimizeForTokenBudget(context.Background(), project, budget, task)
+	if err != nil {
+		t.Fatalf("OptimizeForTokenBudget failed: %v", err)
+	}
+	if selection.TotalFiles != 2 || selection.TotalTokens > budget {
+		t.Errorf("selection has %d files and %d tokens, expected 2 files within %d", selection.TotalFiles, selection.TotalTokens, budget)
+	}
+	if _, ok := selection.Metadata["mandatory_compression"]; !ok {
+		t.Error("expected the compression strategy used in the metadata")
+	}
+
+	var infeasible *BudgetInfeasibleError
+	if _, err := optimizer.OptimizeForTokenBudget(context.Background(), project, 10, task); !errors.As(err, &infeasible) {
+		t.Fatalf("OptimizeForTokenBudget error = %v, expected a BudgetInfeasibleError", err)
+	}
+	if infeasible.MinimumBudget <= 10 || infeasible.MinimumBudget >= project.TotalTokens {
+		t.Errorf("MinimumBudget = %d, expected it between the budget and the uncompressed %d", infeasible.MinimumBudget, project.TotalTokens)
+	}
+}
+
+// recommendingAdvisor recommends the same compression for every task type
+type recommendingAdvisor CompressionStrategy
+
+func (a recommendingAdvisor) RecommendCompression(taskType TaskType) CompressionStrategy {
+	return CompressionStrategy(a)
+}
+
+// commentedSource returns a Go file whose doc comments outweigh its code
+func commentedSource(pkg string) string {
+	var source strings.Builder
+	fmt.Fprintf(&source, "package %s\n\n", pkg)
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&source, "// %sStep%d documents a step of %s processing at length, so that\n// the comment outweighs the code it describes many times over\n// and stripping it saves most of the file.\nfunc %sStep%d() int {\n\treturn %d\n}\n\n", pkg, i, pkg, pkg, i, i)
+	}
+	return source.String()
+}
+
+// budgetTestOptimizer analyzes files written to a temp dir and returns an
+// optimizer over them that compresses with advisor's recommendation
+func budgetTestOptimizer(t *testing.T, files map[string]string, advisor CompressionAdvisor) (*DefaultOptimizer, *ProjectContext) {
+	t.Helper()
+	root := t.TempDir()
+	writeProjectFiles(t, root, files)
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	project, err := analyzer.AnalyzeProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+	optimizer := NewDefaultOptimizer(analyzer, nil, NewDefaultContextCompressor(NewSimpleTokenCounter(), nil), &OptimizerConfig{
+		DefaultTokenBudget: 8000,
+		MaxSelectionTime:   5 * time.Second,
+		DefaultStrategy:    StrategyRelevance,
+		CompressionAdvisor: advisor,
+	})
+	return optimizer, project
+}
+
+// TestCompressToBudgetAppliesRecommendedCompression tests that a selection
+// over budget comes back compressed within the budget, with the strategy the
+// advisor recommends for the task
+func TestCompressToBudgetAppliesRecommendedCompression(t *testing.T) {
+	optimizer, project := budgetTestOptimizer(t, map[string]string{"orders.go": commentedSource("orders"), "billing.go": commentedSource("billing")}, recommendingAdvisor(CompressionSummary))
+	selection := &SelectedContext{
+		Task:        &Task{Type: TaskTypeDocumentation, Description: "document the processing steps"},
+		TotalTokens: project.TotalTokens,
+		TotalFiles:  len(project.Files),
+		Metadata:    map[string]interface{}{},
+	}
+	for i := range project.Files {
+		selection.Files = append(selection.Files, ContextFile{FileInfo: &project.Files[i], InclusionReason: "relevance_score"})
+	}
+	budget := project.TotalTokens / 2
+
+	fitted, err := optimizer.compressToBudget(context.Background(), selection, budget)
+	if err != nil {
+		t.Fatalf("compressToBudget failed: %v", err)
+	}
+	if fitted.TotalFiles != 2 || fitted.TotalTokens > budget {
+		t.Errorf("selection has %d files and %d tokens, expected 2 files within %d", fitted.TotalFiles, fitted.TotalTokens, budget)
+	}
+	if strategy := fitted.Metadata["budget_compression"]; strategy != string(CompressionSummary) {
+		t.Errorf("budget_compression = %v, expected the recommended %s", strategy, CompressionSummary)
+	}
+	if selection.TotalTokens != project.TotalTokens {
+		t.Error("expected the original selection left as it was")
+	}
+}
+
+// TestOptimizeForTokenBudgetCompressesForCoverage tests that files the budget
+// would drop are kept compressed instead, and that the strategy chosen decides
+// what the returned selection holds
+func TestOptimizeForTokenBudgetCompressesForCoverage(t *testing.T) {
+	files := map[string]string{}
+	for _, pkg := range []string{"orders", "billing", "shipping", "returns"} {
+		files[pkg+".go"] = commentedSource(pkg)
+	}
+	task := &Task{Type: TaskTypeFeature, Description: "add a processing step to orders billing shipping and returns"}
+
+	optimizer, project := budgetTestOptimizer(t, files, nil)
+	budget := project.TotalTokens / 2
+	uncompressed := *optimizer
+	uncompressed.compressor = nil
+	dropped, err := uncompressed.OptimizeForTokenBudget(context.Background(), project, budget, task)
+	if err != nil {
+		t.Fatalf("OptimizeForTokenBudget failed: %v", err)
+	}
+
+	contents := make(map[CompressionStrategy]string)
+	for _, advisor := range []CompressionAdvisor{nil, recommendingAdvisor(CompressionSummary)} {
+		optimizer.config.CompressionAdvisor = advisor
+		selection, err := optimizer.OptimizeForTokenBudget(context.Background(), project, budget, task)
+		if err != nil {
+			t.Fatalf("OptimizeForTokenBudget failed: %v", err)
+		}
+		if selection.TotalFiles <= dropped.TotalFiles || selection.TotalTokens > budget {
+			t.Errorf("selection has %d files and %d tokens, expected more than the %d uncompressed files within %d",
+				selection.TotalFiles, selection.TotalTokens, dropped.TotalFiles, budget)
+		}
+		strategy, _ := selection.Metadata["budget_compression"].(string)
+		contents[CompressionStrategy(strategy)] = selection.Files[0].Content
+	}
+	if len(contents) != 2 || contents[CompressionMinify] == "" || contents[CompressionMinify] == contents[CompressionSummary] {
+		t.Errorf("expected minify by default and summary when recommended to return different content, got %v", contents)
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"time"
 )
 
-// InMemoryContextCache provides in-memory caching of context selections
+// InMemoryContextCache provides in-memory caching of context selections. It
+// is safe for concurrent use, so one cache can back an optimizer serving
+// concurrent requests.
 type InMemoryContextCache struct {
 	cache    map[string]*CacheEntry
 	mutex    sync.RWMutex
@@ -203,10 +205,12 @@ func (c *InMemoryContextCache) Clear() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	if c.config.EnableStats {
+		c.stats.Invalidations += int64(len(c.cache))
+	}
 	c.cache = make(map[string]*CacheEntry)
 	
 	if c.config.EnableStats {
-		c.stats.Invalidations += int64(len(c.cache))
 		c.updateMemoryUsage()
 	}
 
@@ -0,0 +1,114 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestInMemoryContextCacheConcurrentAccess tests that concurrent Set, Get,
+// Delete and Clear calls, alongside the cleanup routine, are race-free and
+// hit for every entry still cached; run it with -race
+func TestInMemoryContextCacheConcurrentAccess(t *testing.T) {
+	cache := NewInMemoryContextCache(&CacheConfig{
+		MaxEntries:      10000,
+		DefaultTTL:      time.Minute,
+		EnableStats:     true,
+		CleanupInterval: time.Millisecond,
+	})
+
+	const workers, iterations = 8, 100
+	var wg sync.WaitGroup
+	errs := make(chan string, workers)
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				key := fmt.Sprintf("worker-%d-%d", worker, i)
+				selection := &SelectedContext{TotalTokens: i, Files: []ContextFile{{FileInfo: &FileInfo{Path: key}}}}
+				if err := cache.Set(key, selection, 0); err != nil {
+					errs <- fmt.Sprintf("Set(%s) failed: %v", key, err)
+					return
+				}
+				if cached, ok := cache.Get(key); !ok || cached != selection {
+					errs <- fmt.Sprintf("Get(%s) = %v, %t, expected the selection just set", key, cached, ok)
+					return
+				}
+				if _, ok := cache.Get("missing-" + key); ok {
+					errs <- fmt.Sprintf("Get(missing-%s) hit", key)
+					return
+				}
+				if i%10 == 0 {
+					cache.Delete(key)
+				}
+				cache.GetStatistics()
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	stats := cache.GetStatistics()
+	if stats.Hits != workers*iterations || stats.Misses != workers*iterations {
+		t.Errorf("stats = %d hits and %d misses, expected %d of each", stats.Hits, stats.Misses, workers*iterations)
+	}
+	if err := cache.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	// Deletes took every tenth entry and the clear the rest
+	if stats := cache.GetStatistics(); stats.Invalidations != workers*iterations {
+		t.Errorf("invalidations = %d, expected %d", stats.Invalidations, workers*iterations)
+	}
+}
+
+// TestOptimizerSharedCacheConcurrentSelections tests that concurrent
+// selections through an optimizer sharing a cache agree and reuse cached
+// selections
+func TestOptimizerSharedCacheConcurrentSelections(t *testing.T) {
+	project := newTestProject(map[string]int{"/project/a.go": 100, "/project/b.go": 100, "/project/c.go": 100})
+	cache := NewInMemoryContextCache(&CacheConfig{MaxEntries: 100, DefaultTTL: time.Minute, EnableStats: true})
+	optimizer := NewDefaultOptimizer(newStubAnalyzer(map[string]float64{"/project/a.go": 0.9, "/project/b.go": 0.6, "/project/c.go": 0.3}), cache, nil, &OptimizerConfig{
+		EnableCaching:      true,
+		CacheExpiryMinutes: 5,
+		DefaultStrategy:    StrategyRelevance,
+	})
+	constraints := &ContextConstraints{MaxTokens: 1000, MaxFiles: 10, Strategy: StrategyRelevance}
+	expected := []string{"/project/a.go", "/project/b.go", "/project/c.go"}
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 8)
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				task := &Task{Type: TaskTypeFeature, Description: fmt.Sprintf("task %d", i%5)}
+				selection, err := optimizer.SelectOptimalContext(context.Background(), project, task, constraints)
+				if err != nil {
+					errs <- fmt.Sprintf("SelectOptimalContext failed: %v", err)
+					return
+				}
+				if paths := selectedPaths(selection); !reflect.DeepEqual(paths, expected) {
+					errs <- fmt.Sprintf("selected %v, expected %v", paths, expected)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if stats := cache.GetStatistics(); stats.Hits == 0 {
+		t.Errorf("stats = %+v, expected repeated tasks to hit the cache", stats)
+	}
+}
@@ -0,0 +1,185 @@
+package context
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stripComments removes comments from source code, skipping over string,
+// character, and regex literals so sequences like "http://" inside them
+// survive. Line structure is preserved: comments are dropped but the newlines
+// they contain are kept. Content in other languages is returned unchanged.
+func stripComments(content, language string) string {
+	switch language {
+	case "go", "javascript", "typescript":
+		return stripCStyleComments(content, language != "go")
+	case "python":
+		return stripHashComments(content)
+	default:
+		return content
+	}
+}
+
+// stripCStyleComments removes // and /* */ comments. JavaScript adds template
+// literals with escapes and regex literals, which Go's raw strings lack.
+func stripCStyleComments(content string, javascript bool) string {
+	var out strings.Builder
+	out.Grow(len(content))
+
+	var previous rune // Last non-space rune written, used to spot regex literals
+	for i := 0; i < len(content); {
+		ch := content[i]
+		switch {
+		case strings.HasPrefix(content[i:], "//"):
+			end := strings.IndexByte(content[i:], '\n')
+			if end == -1 {
+				return out.String()
+			}
+			i += end
+
+		case strings.HasPrefix(content[i:], "/*"):
+			end := strings.Index(content[i+2:], "*/")
+			if end == -1 {
+				end = len(content) - i - 2
+			}
+			comment := content[i : i+2+end]
+			out.WriteString(strings.Repeat("\n", strings.Count(comment, "\n")))
+			i += 2 + end + 2
+			if i > len(content) {
+				i = len(content)
+			}
+
+		case ch == '"' || ch == '\'':
+			end := quotedLiteralEnd(content, i, ch, true)
+			out.WriteString(content[i:end])
+			previous = rune(ch)
+			i = end
+
+		case ch == '`':
+			end := quotedLiteralEnd(content, i, ch, javascript)
+			out.WriteString(content[i:end])
+			previous = '`'
+			i = end
+
+		case ch == '/' && javascript && regexAllowedAfter(previous):
+			end := regexLiteralEnd(content, i)
+			out.WriteString(content[i:end])
+			previous = '/'
+			i = end
+
+		default:
+			out.WriteByte(ch)
+			if !unicode.IsSpace(rune(ch)) {
+				previous = rune(ch)
+			}
+			i++
+		}
+	}
+	return out.String()
+}
+
+// stripHashComments removes Python # comments, skipping single-, double-, and
+// triple-quoted strings
+func stripHashComments(content string) string {
+	var out strings.Builder
+	out.Grow(len(content))
+
+	for i := 0; i < len(content); {
+		ch := content[i]
+		switch {
+		case ch == '#':
+			end := strings.IndexByte(content[i:], '\n')
+			if end == -1 {
+				return out.String()
+			}
+			i += end
+
+		case strings.HasPrefix(content[i:], `"""`) || strings.HasPrefix(content[i:], "'''"):
+			delimiter := content[i : i+3]
+			end := i + 3
+			for end < len(content) && !strings.HasPrefix(content[end:], delimiter) {
+				if content[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end += 3; end > len(content) {
+				end = len(content)
+			}
+			out.WriteString(content[i:end])
+			i = end
+
+		case ch == '"' || ch == '\'':
+			end := quotedLiteralEnd(content, i, ch, true)
+			out.WriteString(content[i:end])
+			i = end
+
+		default:
+			out.WriteByte(ch)
+			i++
+		}
+	}
+	return out.String()
+}
+
+// quotedLiteralEnd returns the index just past the literal opened by quote at
+// start. Literals other than backquoted ones end at an unescaped newline, so
+// an unbalanced quote can't swallow the rest of the file.
+func quotedLiteralEnd(content string, start int, quote byte, escapes bool) int {
+	for i := start + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			if escapes {
+				i++
+			}
+		case quote:
+			return i + 1
+		case '\n':
+			if quote != '`' {
+				return i
+			}
+		}
+	}
+	return len(content)
+}
+
+// regexLiteralEnd returns the index just past the JavaScript regex literal
+// opened at start, including its flags. A slash inside a character class
+// doesn't close the literal.
+func regexLiteralEnd(content string, start int) int {
+	inClass := false
+	for i := start + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if !inClass {
+				i++
+				for i < len(content) && isIdentifierByte(content[i]) {
+					i++
+				}
+				return i
+			}
+		case '\n':
+			return i
+		}
+	}
+	return len(content)
+}
+
+// regexAllowedAfter reports whether a slash following previous starts a regex
+// literal rather than a division
+func regexAllowedAfter(previous rune) bool {
+	if previous == 0 {
+		return true
+	}
+	return strings.ContainsRune("(,=:[!&|?{};+-*%<>~^", previous)
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b == '$' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
@@ -0,0 +1,100 @@
+package context
+
+import (
+	"testing"
+)
+
+// TestStripComments tests that comment removal leaves literals intact
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		input    string
+		expected string
+	}{
+		{
+			name:     "go url in string",
+			language: "go",
+			input:    "url := \"http://example.com\" // The endpoint\n",
+			expected: "url := \"http://example.com\" \n",
+		},
+		{
+			name:     "go raw string and rune",
+			language: "go",
+			input:    "pattern := `a//b /* c */` + string('/') // Joined\nquote := '\"' // Quote\n",
+			expected: "pattern := `a//b /* c */` + string('/') \nquote := '\"' \n",
+		},
+		{
+			name:     "go escaped quote",
+			language: "go",
+			input:    "s := \"say \\\"//hi\\\"\" /* note */ + x\n",
+			expected: "s := \"say \\\"//hi\\\"\"  + x\n",
+		},
+		{
+			name:     "go block comment keeps lines",
+			language: "go",
+			input:    "a := 1 /* first\nsecond */ b := 2\n",
+			expected: "a := 1 \n b := 2\n",
+		},
+		{
+			name:     "javascript regex literal",
+			language: "javascript",
+			input:    "const re = /a\\/*b/g; // Matches a/b\nconst cls = /[/*]+/.test(s)\n",
+			expected: "const re = /a\\/*b/g; \nconst cls = /[/*]+/.test(s)\n",
+		},
+		{
+			name:     "javascript division is not a regex",
+			language: "javascript",
+			input:    "const half = total / 2 // Rounded later\nconst q = (a) / b / c\n",
+			expected: "const half = total / 2 \nconst q = (a) / b / c\n",
+		},
+		{
+			name:     "typescript template literal",
+			language: "typescript",
+			input:    "const u = `https://${host}/*path*/` /* base */\n",
+			expected: "const u = `https://${host}/*path*/` \n",
+		},
+		{
+			name:     "python hash in strings",
+			language: "python",
+			input:    "color = \"#fff\"  # White\nfmt = '%d#' # Suffix\n",
+			expected: "color = \"#fff\"  \nfmt = '%d#' \n",
+		},
+		{
+			name:     "python triple-quoted string",
+			language: "python",
+			input:    "doc = \"\"\"Usage:\n  # not a comment\n\"\"\"  # Module docs\n",
+			expected: "doc = \"\"\"Usage:\n  # not a comment\n\"\"\"  \n",
+		},
+		{
+			name:     "unknown language unchanged",
+			language: "yaml",
+			input:    "url: http://example.com # Endpoint\n",
+			expected: "url: http://example.com # Endpoint\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripComments(tt.input, tt.language); got != tt.expected {
+				t.Errorf("stripComments() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestMinifyKeepsStringLiterals tests that minified output keeps URLs in strings
+func TestMinifyKeepsStringLiterals(t *testing.T) {
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	content := "package client\n\n// baseURL is the API root\nconst baseURL = \"https://api.example.com/v1\"\n"
+
+	minified, _, _, err := compressor.minifyContent(content, &FileInfo{Path: "client.go", Language: "go"})
+	if err != nil {
+		t.Fatalf("minifyContent failed: %v", err)
+	}
+
+	expected := "package client\nconst baseURL = \"https://api.example.com/v1\""
+	if minified != expected {
+		t.Errorf("minifyContent() = %q, expected %q", minified, expected)
+	}
+}
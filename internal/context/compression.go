@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,6 +25,14 @@ type ContextCompressor interface {
 type DefaultContextCompressor struct {
 	tokenCounter TokenCounter
 	config       *CompressionConfig
+
+	compiledRules map[string]compiledRulesEntry // By language, compiled on first use
+	debugRules    []*regexp.Regexp              // DebugPreservePatterns, compiled on first use
+	debugErr      error
+	debugCompiled bool
+	rulesMutex    sync.Mutex
+
+	cache *compressionCache // Compressed files by content, strategy and config
 }
 
 // CompressionConfig configures compression behavior
@@ -36,10 +45,20 @@ type CompressionConfig struct {
 	SnippetContextLines  int               `json:"snippet_context_lines"`
 	SummaryMaxTokens     int               `json:"summary_max_tokens"`
 	EnableSemanticCompr  bool              `json:"enable_semantic_compression"`
+	AutoTargetRatio      float64           `json:"auto_target_ratio"`       // Compressed/original tokens the auto strategy aims for
+	AutoKeepWholeTokens  int               `json:"auto_keep_whole_tokens"`  // Relevant files up to this size stay uncompressed
+	AutoSnippetTokens    int               `json:"auto_snippet_tokens"`     // Files from this size are snippeted
+	WindowMaxTokens      int               `json:"window_max_tokens"`       // Per-file cap for the window strategy
+	DebugPreservePatterns []string         `json:"debug_preserve_patterns"` // Lines snippets keep from truncated bodies for debug tasks
 	LanguageRules        map[string]*LanguageCompressionRules `json:"language_rules"`
 }
 
-// LanguageCompressionRules defines compression rules for specific languages
+// LanguageCompressionRules defines compression rules for specific languages.
+// They drive comment removal, import, class and function detection, and
+// summaries for languages without built-in handling, such as Rust or Ruby.
+// Import, function and class patterns match trimmed lines; comment, preserve
+// and removable patterns match whole lines, and preserved lines are never
+// stripped or removed.
 type LanguageCompressionRules struct {
 	ImportPatterns      []string `json:"import_patterns"`
 	FunctionPatterns    []string `json:"function_patterns"`
@@ -82,6 +101,11 @@ func NewDefaultContextCompressor(tokenCounter TokenCounter, config *CompressionC
 			SnippetContextLines:  2,
 			SummaryMaxTokens:     200,
 			EnableSemanticCompr:  true,
+			AutoTargetRatio:      defaultAutoTargetRatio,
+			AutoKeepWholeTokens:  defaultAutoKeepWholeTokens,
+			AutoSnippetTokens:    defaultAutoSnippetTokens,
+			WindowMaxTokens:      defaultWindowMaxTokens,
+			DebugPreservePatterns: defaultDebugPreservePatterns(),
 			LanguageRules:        getDefaultLanguageRules(),
 		}
 	}
@@ -89,12 +113,22 @@ func NewDefaultContextCompressor(tokenCounter TokenCounter, config *CompressionC
 	return &DefaultContextCompressor{
 		tokenCounter: tokenCounter,
 		config:       config,
+		cache:        newCompressionCache(defaultCompressionCacheEntries),
 	}
 }
 
 // Compress applies compression to a selected context
 func (c *DefaultContextCompressor) Compress(ctx context.Context, selection *SelectedContext, strategy CompressionStrategy) (*CompressedContext, error) {
 	startTime := time.Now()
+	if strategy == CompressionAuto {
+		return c.compressAuto(selection, startTime), nil
+	}
+	if strategy == CompressionWindow {
+		return c.compressWindow(selection, startTime), nil
+	}
+	if strategy == CompressionUsage {
+		return c.compressUsage(selection, startTime), nil
+	}
 	
 	compressed := &CompressedContext{
 		Original:         selection,
@@ -108,31 +142,17 @@ func (c *DefaultContextCompressor) Compress(ctx context.Context, selection *Sele
 
 	totalOriginalTokens := 0
 	totalCompressedTokens := 0
+	validated := 0.0 // Validation factors weighted by original tokens
 
 	for _, contextFile := range selection.Files {
-		// Read file content if not already loaded
-		content := contextFile.Content
-		if content == "" {
-			// In a real implementation, you would load the content here
-			// For now, we'll simulate with the file info
-			content = fmt.Sprintf("// File: %s\n// Tokens: %d\n// Type: %s\n", 
-				contextFile.FileInfo.Path, 
-				contextFile.FileInfo.TokenCount,
-				contextFile.FileInfo.FileType)
-		}
-
-		originalTokens := contextFile.FileInfo.TokenCount
-		if originalTokens == 0 {
-			if c.tokenCounter != nil {
-				originalTokens, _ = c.tokenCounter.CountTokens(content)
-			}
-		}
+		content, originalTokens := c.fileContent(contextFile)
 
-		compressedContent, compressedTokens, _, err := c.compressFileContent(content, contextFile.FileInfo, strategy)
+		compressedContent, compressedTokens, techniques, err := c.compressFileContent(content, contextFile.FileInfo, strategy, selection.Task)
 		if err != nil {
 			// If compression fails, use original content
 			compressedContent = content
 			compressedTokens = originalTokens
+			techniques = []string{string(CompressionNone)}
 		}
 
 		compressedFile := CompressedFile{
@@ -142,11 +162,13 @@ func (c *DefaultContextCompressor) Compress(ctx context.Context, selection *Sele
 			CompressedTokens: compressedTokens,
 			CompressionRatio: 1.0,
 			Method:           string(strategy),
+			Metadata:         map[string]interface{}{"techniques": techniques},
 		}
 
 		if originalTokens > 0 {
 			compressedFile.CompressionRatio = float64(compressedTokens) / float64(originalTokens)
 		}
+		validated += c.validateCompressedFile(&compressedFile, content, contextFile.FileInfo.Language, strategy) * float64(originalTokens)
 
 		compressed.CompressedFiles = append(compressed.CompressedFiles, compressedFile)
 		
@@ -160,29 +182,63 @@ func (c *DefaultContextCompressor) Compress(ctx context.Context, selection *Sele
 		compressed.TokenReduction = totalOriginalTokens - totalCompressedTokens
 	}
 
-	// Estimate quality impact
+	// Estimate quality impact, then scale it by what validation found intact
 	compressed.QualityScore = c.estimateQualityImpact(strategy, compressed.CompressionRatio)
+	if totalOriginalTokens > 0 {
+		compressed.QualityScore *= validated / float64(totalOriginalTokens)
+	}
 	compressed.CompressionTime = time.Since(startTime)
 
 	return compressed, nil
 }
 
-// EstimateCompression estimates compression ratio without actually compressing
+// fileContent returns a file's content and its token count before
+// compression. Content that wasn't loaded is read from the file system the
+// file was analyzed from; an unreadable file compresses to nothing.
+func (c *DefaultContextCompressor) fileContent(contextFile ContextFile) (string, int) {
+	content, _ := loadContextFileContent(contextFile)
+
+	originalTokens := contextFile.FileInfo.TokenCount
+	if originalTokens == 0 {
+		if c.tokenCounter != nil {
+			originalTokens, _ = c.tokenCounter.CountTokens(content)
+		}
+	}
+	return content, originalTokens
+}
+
+// EstimateCompression estimates compression ratio without actually compressing.
+// Ratios come from a cheap scan of the selection's largest files, falling back
+// to typical ratios when their content can't be read.
 func (c *DefaultContextCompressor) EstimateCompression(selection *SelectedContext, strategy CompressionStrategy) (float64, error) {
 	switch strategy {
 	case CompressionNone:
 		return 1.0, nil
-	case CompressionSummary:
-		return 0.3, nil // Summaries typically achieve 70% reduction
-	case CompressionSnippet:
-		return 0.4, nil // Snippets achieve ~60% reduction
-	case CompressionMinify:
-		return 0.8, nil // Minification achieves ~20% reduction
-	case CompressionSemantic:
-		return 0.5, nil // Semantic compression achieves ~50% reduction
-	default:
+	case CompressionAuto:
+		// Auto escalates per file until it reaches the target, but can't go below summaries
+		summary, _ := c.EstimateCompression(selection, CompressionSummary)
+		return max(c.autoTargetRatio(), summary), nil
+	case CompressionWindow:
+		return c.estimateWindow(selection), nil
+	case CompressionUsage:
+		// Which bodies are kept depends on the whole selection, and parsing
+		// is cheap, so compress for real
+		if selection == nil {
+			return 0.7, nil
+		}
+		return c.compressUsage(selection, time.Now()).CompressionRatio, nil
+	}
+
+	fallback, known := fallbackCompressionRatios[strategy]
+	if !known {
 		return 0.7, nil // Conservative estimate
 	}
+	if selection != nil {
+		if ratio, ok := c.estimateFromContent(selection, strategy); ok {
+			return ratio, nil
+		}
+	}
+	return fallback, nil
 }
 
 // GetCompressionStrategies returns available compression strategies
@@ -193,11 +249,41 @@ func (c *DefaultContextCompressor) GetCompressionStrategies() []CompressionStrat
 		CompressionSnippet,
 		CompressionMinify,
 		CompressionSemantic,
+		CompressionAuto,
+		CompressionWindow,
+		CompressionUsage,
 	}
 }
 
-// compressFileContent compresses content of a single file
-func (c *DefaultContextCompressor) compressFileContent(content string, fileInfo *FileInfo, strategy CompressionStrategy) (string, int, []string, error) {
+// compressFileContent compresses content of a single file for task, which may
+// be nil, reusing the cached result of the same compression
+func (c *DefaultContextCompressor) compressFileContent(content string, fileInfo *FileInfo, strategy CompressionStrategy, task *Task) (string, int, []string, error) {
+	if strategy == CompressionNone {
+		return c.compressFile(content, fileInfo, strategy, task)
+	}
+
+	key, err := c.compressionKey(content, fileInfo, strategy, task)
+	if err != nil {
+		return c.compressFile(content, fileInfo, strategy, task)
+	}
+	if cached, ok := c.cache.get(key); ok {
+		entry := cached.(compressedEntry)
+		return entry.content, entry.tokens, append([]string{}, entry.techniques...), nil
+	}
+
+	compressed, tokens, techniques, err := c.compressFile(content, fileInfo, strategy, task)
+	if err == nil {
+		c.cache.put(key, compressedEntry{content: compressed, tokens: tokens, techniques: append([]string{}, techniques...)})
+	}
+	return compressed, tokens, techniques, err
+}
+
+// compressFile compresses content of a single file without the cache
+func (c *DefaultContextCompressor) compressFile(content string, fileInfo *FileInfo, strategy CompressionStrategy, task *Task) (string, int, []string, error) {
+	if _, err := c.languageRules(fileInfo.Language); err != nil && strategy != CompressionNone {
+		return content, fileInfo.TokenCount, nil, err
+	}
+
 	switch strategy {
 	case CompressionNone:
 		return content, fileInfo.TokenCount, []string{"none"}, nil
@@ -206,7 +292,7 @@ func (c *DefaultContextCompressor) compressFileContent(content string, fileInfo
 		return c.createSummary(content, fileInfo)
 		
 	case CompressionSnippet:
-		return c.extractSnippets(content, fileInfo)
+		return c.extractSnippets(content, fileInfo, task)
 		
 	case CompressionMinify:
 		return c.minifyContent(content, fileInfo)
@@ -237,7 +323,11 @@ func (c *DefaultContextCompressor) createSummary(content string, fileInfo *FileI
 	case "python":
 		summary.WriteString(c.summarizePythonFile(content))
 	default:
-		summary.WriteString(c.summarizeGenericFile(content))
+		if rules := c.customRules(fileInfo.Language); rules != nil {
+			summary.WriteString(rules.summarize(content))
+		} else {
+			summary.WriteString(c.summarizeGenericFile(content))
+		}
 	}
 	
 	summaryContent := summary.String()
@@ -249,10 +339,19 @@ func (c *DefaultContextCompressor) createSummary(content string, fileInfo *FileI
 	return summaryContent, tokens, techniques, nil
 }
 
-// extractSnippets extracts relevant code snippets
-func (c *DefaultContextCompressor) extractSnippets(content string, fileInfo *FileInfo) (string, int, []string, error) {
+// extractSnippets extracts relevant code snippets. For debug tasks, lines
+// matching DebugPreservePatterns are kept from the truncated bodies.
+func (c *DefaultContextCompressor) extractSnippets(content string, fileInfo *FileInfo, task *Task) (string, int, []string, error) {
 	var result strings.Builder
 	techniques := []string{"snippets"}
+	preserve, err := c.debugPatterns(task)
+	if err != nil {
+		return content, fileInfo.TokenCount, nil, err
+	}
+	if len(preserve) > 0 {
+		techniques = append(techniques, "preserve_debug_lines")
+	}
+	const truncated = "    // ... function body truncated ...\n"
 	
 	result.WriteString(fmt.Sprintf("// SNIPPETS from %s\n", fileInfo.Path))
 	
@@ -277,14 +376,31 @@ func (c *DefaultContextCompressor) extractSnippets(content string, fileInfo *Fil
 			for j := i; j < len(lines) && j < i+c.config.MinFunctionLines+1; j++ {
 				result.WriteString(lines[j] + "\n")
 			}
-			result.WriteString("    // ... function body truncated ...\n")
 			
-			// Find and include function end
-			for j := i + c.config.MinFunctionLines + 1; j < len(lines); j++ {
-				if c.isFunctionEnd(lines[j], fileInfo.Language) {
-					result.WriteString(lines[j] + "\n")
+			// Find and include function end, marking each stretch of the
+			// body left out. Braces close at the signature's indentation, so
+			// nested blocks don't end the function early.
+			indent := leadingWhitespace(line)
+			skipped, ended := false, false
+			for j := i + c.config.MinFunctionLines + 1; j < len(lines) && !ended; j++ {
+				python := fileInfo.Language == "python"
+				sameLevel := python || leadingWhitespace(lines[j]) == indent
+				if !python && sameLevel && c.isFunctionStart(lines[j], fileInfo.Language) {
 					break
 				}
+				ended = sameLevel && c.isFunctionEnd(lines[j], fileInfo.Language)
+				if !ended && !matchesAny(preserve, lines[j]) {
+					skipped = true
+					continue
+				}
+				if skipped {
+					result.WriteString(truncated)
+					skipped = false
+				}
+				result.WriteString(lines[j] + "\n")
+			}
+			if skipped {
+				result.WriteString(truncated)
 			}
 			result.WriteString("\n")
 		}
@@ -307,6 +423,10 @@ func (c *DefaultContextCompressor) minifyContent(content string, fileInfo *FileI
 	if !c.config.PreserveComments {
 		content = c.removeComments(content, fileInfo.Language)
 		techniques = append(techniques, "remove_comments")
+		if rules := c.customRules(fileInfo.Language); rules != nil && len(rules.removable) > 0 {
+			content = rules.dropRemovable(content)
+			techniques = append(techniques, "remove_rule_lines")
+		}
 	}
 	
 	// Remove excessive whitespace
@@ -345,7 +465,7 @@ func (c *DefaultContextCompressor) semanticCompression(content string, fileInfo
 	}
 	
 	// Extract imports with grouping
-	imports := c.extractAndGroupImports(content, fileInfo.Language)
+	imports := c.extractAndGroupImports(content, fileInfo)
 	if len(imports) > 0 {
 		result.WriteString("// Imports:\n")
 		for _, imp := range imports {
@@ -476,6 +596,9 @@ func (c *DefaultContextCompressor) isImportLine(line, language string) bool {
 	case "python":
 		return strings.HasPrefix(trimmed, "import ") || strings.HasPrefix(trimmed, "from ")
 	default:
+		if rules := c.customRules(language); rules != nil && len(rules.imports) > 0 {
+			return matchesAny(rules.imports, trimmed)
+		}
 		return strings.Contains(strings.ToLower(trimmed), "import") ||
 			   strings.Contains(strings.ToLower(trimmed), "include")
 	}
@@ -493,6 +616,9 @@ func (c *DefaultContextCompressor) isFunctionStart(line, language string) bool {
 	case "python":
 		return strings.HasPrefix(trimmed, "def ")
 	default:
+		if rules := c.customRules(language); rules != nil {
+			return matchesAny(rules.functions, trimmed)
+		}
 		return false
 	}
 }
@@ -513,38 +639,18 @@ func (c *DefaultContextCompressor) isFunctionEnd(line, language string) bool {
 	}
 }
 
+// leadingWhitespace returns the indentation of line
+func leadingWhitespace(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// removeComments strips comments with the built-in stripper, or with the
+// language's comment patterns when it has no built-in handling
 func (c *DefaultContextCompressor) removeComments(content, language string) string {
-	switch language {
-	case "go", "javascript", "typescript":
-		// Remove // comments
-		lines := strings.Split(content, "\n")
-		filtered := []string{}
-		for _, line := range lines {
-			if idx := strings.Index(line, "//"); idx != -1 {
-				line = line[:idx]
-			}
-			filtered = append(filtered, line)
-		}
-		content = strings.Join(filtered, "\n")
-		
-		// Remove /* */ comments (simplified)
-		re := regexp.MustCompile(`/\*.*?\*/`)
-		content = re.ReplaceAllString(content, "")
-		
-	case "python":
-		// Remove # comments
-		lines := strings.Split(content, "\n")
-		filtered := []string{}
-		for _, line := range lines {
-			if idx := strings.Index(line, "#"); idx != -1 {
-				line = line[:idx]
-			}
-			filtered = append(filtered, line)
-		}
-		content = strings.Join(filtered, "\n")
+	if rules := c.customRules(language); rules != nil && len(rules.comments) > 0 {
+		return rules.stripComments(content)
 	}
-	
-	return content
+	return stripComments(content, language)
 }
 
 func (c *DefaultContextCompressor) removeExcessiveWhitespace(content string) string {
@@ -575,19 +681,6 @@ func (c *DefaultContextCompressor) extractPackageDeclaration(content, language s
 	return ""
 }
 
-func (c *DefaultContextCompressor) extractAndGroupImports(content, language string) []string {
-	imports := []string{}
-	lines := strings.Split(content, "\n")
-	
-	for _, line := range lines {
-		if c.isImportLine(line, language) {
-			imports = append(imports, strings.TrimSpace(line))
-		}
-	}
-	
-	return imports
-}
-
 func (c *DefaultContextCompressor) extractTypeDefinitions(content, language string) []string {
 	types := []string{}
 	lines := strings.Split(content, "\n")
@@ -599,6 +692,10 @@ func (c *DefaultContextCompressor) extractTypeDefinitions(content, language stri
 			if strings.HasPrefix(trimmed, "type ") {
 				types = append(types, line)
 			}
+		default:
+			if rules := c.customRules(language); rules != nil && matchesAny(rules.classes, trimmed) {
+				types = append(types, line)
+			}
 		}
 	}
 	
@@ -632,6 +729,10 @@ func (c *DefaultContextCompressor) estimateQualityImpact(strategy CompressionStr
 		return 0.6 - (1.0-ratio)*0.2
 	case CompressionSemantic:
 		return 0.75 - (1.0-ratio)*0.25
+	case CompressionWindow:
+		return 0.85 - (1.0-ratio)*0.2 // Keeps the code the task mentions
+	case CompressionUsage:
+		return 0.85 - (1.0-ratio)*0.15 // Keeps the code the rest of the context calls
 	default:
 		return 0.7
 	}
@@ -0,0 +1,51 @@
+package context
+
+import (
+	"context"
+	"fmt"
+)
+
+// AnalyzeCompression compresses selection with strategy and reports what was
+// done to each file: the techniques applied, the tokens saved, and the
+// estimated quality lost. Under the auto strategy files may use different
+// methods, so techniques are reported per file as well as overall.
+func (c *DefaultContextCompressor) AnalyzeCompression(ctx context.Context, selection *SelectedContext, strategy CompressionStrategy) (*CompressionAnalysis, error) {
+	compressed, err := c.Compress(ctx, selection, strategy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress selection: %w", err)
+	}
+
+	analysis := &CompressionAnalysis{
+		CompressionRatio:  compressed.CompressionRatio,
+		TokenSavings:      compressed.TokenReduction,
+		QualityEstimate:   compressed.QualityScore,
+		TechniquesApplied: []string{},
+		FileAnalysis:      make([]FileCompressionInfo, 0, len(compressed.CompressedFiles)),
+	}
+
+	seen := make(map[string]bool)
+	for _, file := range compressed.CompressedFiles {
+		techniques, _ := file.Metadata["techniques"].([]string)
+		if len(techniques) == 0 {
+			techniques = []string{file.Method}
+		}
+		for _, technique := range techniques {
+			if !seen[technique] {
+				seen[technique] = true
+				analysis.TechniquesApplied = append(analysis.TechniquesApplied, technique)
+			}
+		}
+
+		analysis.FileAnalysis = append(analysis.FileAnalysis, FileCompressionInfo{
+			FilePath:         file.OriginalPath,
+			OriginalTokens:   file.OriginalTokens,
+			CompressedTokens: file.CompressedTokens,
+			CompressionRatio: file.CompressionRatio,
+			TechniquesUsed:   techniques,
+			QualityImpact:    1.0 - fileQuality(file, c.estimateQualityImpact(CompressionStrategy(file.Method), file.CompressionRatio)),
+		})
+		analysis.OriginalTokens += file.OriginalTokens
+		analysis.CompressedTokens += file.CompressedTokens
+	}
+	return analysis, nil
+}
@@ -0,0 +1,179 @@
+package context
+
+import (
+	"sort"
+	"time"
+)
+
+// Defaults for the auto compression strategy
+const (
+	defaultAutoTargetRatio     = 0.5
+	defaultAutoKeepWholeTokens = 500
+	defaultAutoSnippetTokens   = 2000
+
+	// autoKeepWholeRelevance is the relevance a small file needs to stay whole
+	autoKeepWholeRelevance = 0.5
+)
+
+// Escalation ladders for the auto strategy, from least to most lossy. Data and
+// prose have no functions to snippet, so they go straight to a summary.
+var (
+	autoCodeLadder = []CompressionStrategy{CompressionNone, CompressionMinify, CompressionSnippet, CompressionSummary}
+	autoTextLadder = []CompressionStrategy{CompressionNone, CompressionMinify, CompressionSummary}
+)
+
+// autoFile tracks the method chosen for one file
+type autoFile struct {
+	file       ContextFile
+	content    string
+	original   int
+	ladder     []CompressionStrategy
+	step       int
+	method     CompressionStrategy // Method that produced result
+	techniques []string            // Techniques method applied
+	result     string
+	tokens     int
+	relevance  float64
+}
+
+// compressAuto picks a compression method per file. Small relevant files stay
+// whole, large files are snippeted and the rest minified. While the overall
+// ratio is above the target, the least relevant files are moved to lossier
+// methods one step at a time.
+func (c *DefaultContextCompressor) compressAuto(selection *SelectedContext, startTime time.Time) *CompressedContext {
+	files := make([]*autoFile, 0, len(selection.Files))
+	totalOriginal, totalCompressed := 0, 0
+
+	for _, contextFile := range selection.Files {
+		content, originalTokens := c.fileContent(contextFile)
+		file := &autoFile{
+			file:      contextFile,
+			content:   content,
+			original:  originalTokens,
+			ladder:    autoCodeLadder,
+			relevance: contextFile.RelevanceScore,
+		}
+		if fileType := contextFile.FileInfo.FileType; fileType == "configuration" || fileType == "documentation" {
+			file.ladder = autoTextLadder
+		}
+
+		file.method, file.result, file.tokens = CompressionNone, content, originalTokens
+		file.techniques = []string{string(CompressionNone)}
+		c.applyAutoStep(file, c.initialAutoStep(file), selection.Task)
+		files = append(files, file)
+		totalOriginal += file.original
+		totalCompressed += file.tokens
+	}
+
+	// Escalate the least relevant files first, larger files breaking ties
+	order := make([]*autoFile, len(files))
+	copy(order, files)
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].relevance != order[j].relevance {
+			return order[i].relevance < order[j].relevance
+		}
+		return order[i].original > order[j].original
+	})
+
+	target := c.autoTargetRatio()
+	for totalOriginal > 0 && float64(totalCompressed)/float64(totalOriginal) > target {
+		escalated := false
+		for _, file := range order {
+			if file.step+1 < len(file.ladder) {
+				before := file.tokens
+				c.applyAutoStep(file, file.step+1, selection.Task)
+				totalCompressed += file.tokens - before
+				escalated = true
+				break
+			}
+		}
+		if !escalated {
+			break
+		}
+	}
+
+	compressed := &CompressedContext{
+		Original:         selection,
+		CompressedFiles:  make([]CompressedFile, 0, len(files)),
+		CompressionRatio: 1.0,
+		Strategy:         CompressionAuto,
+		QualityScore:     1.0,
+	}
+
+	weightedQuality := 0.0
+	for _, file := range files {
+		compressedFile := CompressedFile{
+			OriginalPath:      file.file.FileInfo.Path,
+			CompressedContent: file.result,
+			OriginalTokens:    file.original,
+			CompressedTokens:  file.tokens,
+			CompressionRatio:  1.0,
+			Method:            string(file.method),
+			Metadata:          map[string]interface{}{"techniques": file.techniques},
+		}
+		if file.original > 0 {
+			compressedFile.CompressionRatio = float64(file.tokens) / float64(file.original)
+		}
+		factor := c.validateCompressedFile(&compressedFile, file.content, file.file.FileInfo.Language, file.method)
+		compressed.CompressedFiles = append(compressed.CompressedFiles, compressedFile)
+		weightedQuality += c.estimateQualityImpact(file.method, compressedFile.CompressionRatio) * factor * float64(file.original)
+	}
+
+	if totalOriginal > 0 {
+		compressed.CompressionRatio = float64(totalCompressed) / float64(totalOriginal)
+		compressed.TokenReduction = totalOriginal - totalCompressed
+		compressed.QualityScore = weightedQuality / float64(totalOriginal)
+	}
+	compressed.CompressionTime = time.Since(startTime)
+
+	return compressed
+}
+
+// initialAutoStep picks a file's starting position on its ladder from its size and relevance
+func (c *DefaultContextCompressor) initialAutoStep(file *autoFile) int {
+	keepWhole := c.config.AutoKeepWholeTokens
+	if keepWhole <= 0 {
+		keepWhole = defaultAutoKeepWholeTokens
+	}
+	snippetFrom := c.config.AutoSnippetTokens
+	if snippetFrom <= 0 {
+		snippetFrom = defaultAutoSnippetTokens
+	}
+
+	switch {
+	case file.original <= keepWhole && file.relevance >= autoKeepWholeRelevance:
+		return 0
+	case file.original >= snippetFrom:
+		return len(file.ladder) - 2 // Snippet for code, minify for text
+	default:
+		return 1
+	}
+}
+
+// applyAutoStep compresses a file for task with the method at step on its ladder. A
+// method that doesn't shrink the file keeps the previous method and result,
+// so no file ends up larger than its original.
+func (c *DefaultContextCompressor) applyAutoStep(file *autoFile, step int, task *Task) {
+	method := file.ladder[step]
+	content, tokens := file.content, file.original
+	techniques := []string{string(CompressionNone)}
+	if method != CompressionNone {
+		compressed, compressedTokens, applied, err := c.compressFileContent(file.content, file.file.FileInfo, method, task)
+		if err == nil {
+			content, tokens, techniques = compressed, compressedTokens, applied
+		}
+	}
+
+	if tokens < file.tokens {
+		file.method, file.result, file.tokens, file.techniques = method, content, tokens, techniques
+	}
+	file.step = step
+}
+
+// autoTargetRatio returns the configured target ratio for the auto strategy
+func (c *DefaultContextCompressor) autoTargetRatio() float64 {
+	if c.config.AutoTargetRatio > 0 {
+		return c.config.AutoTargetRatio
+	}
+	return defaultAutoTargetRatio
+}
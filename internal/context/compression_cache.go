@@ -0,0 +1,119 @@
+package context
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// defaultCompressionCacheEntries bounds the results a compressor keeps
+const defaultCompressionCacheEntries = 1024
+
+// compressedEntry is a cached compression of one file
+type compressedEntry struct {
+	content    string
+	tokens     int
+	techniques []string
+}
+
+// compressionCache holds compressed files and declaration checks by content
+// address. When full, the oldest entry makes room for the newest.
+type compressionCache struct {
+	maxEntries int
+	entries    map[[sha256.Size]byte]interface{}
+	order      [][sha256.Size]byte // Keys, oldest first
+	hits       int64
+	misses     int64
+	mutex      sync.Mutex
+}
+
+// newCompressionCache creates a cache of up to maxEntries results
+func newCompressionCache(maxEntries int) *compressionCache {
+	return &compressionCache{
+		maxEntries: maxEntries,
+		entries:    make(map[[sha256.Size]byte]interface{}),
+	}
+}
+
+// get returns the result cached under key, if any
+func (c *compressionCache) get(key [sha256.Size]byte) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	return entry, ok
+}
+
+// put caches a result under key
+func (c *compressionCache) put(key [sha256.Size]byte, entry interface{}) {
+	if c == nil || c.maxEntries <= 0 {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.entries[key]; !exists {
+		if len(c.order) >= c.maxEntries {
+			delete(c.entries, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = entry
+}
+
+// ClearCache drops every cached compression
+func (c *DefaultContextCompressor) ClearCache() {
+	if c.cache == nil {
+		return
+	}
+	c.cache.mutex.Lock()
+	defer c.cache.mutex.Unlock()
+	c.cache.entries = make(map[[sha256.Size]byte]interface{})
+	c.cache.order = nil
+}
+
+// compressionKey addresses a compression by everything its result depends
+// on: the content, the strategy, the file's path, language and token count
+// that summaries report, whether the task is a debug task, and the config.
+// The config is part of the key rather than fixed per compressor, so changing
+// it between calls never returns stale output.
+func (c *DefaultContextCompressor) compressionKey(content string, fileInfo *FileInfo, strategy CompressionStrategy, task *Task) ([sha256.Size]byte, error) {
+	config, err := json.Marshal(c.config)
+	if err != nil {
+		return [sha256.Size]byte{}, fmt.Errorf("failed to encode compression config: %w", err)
+	}
+	debug := task != nil && task.Type == TaskTypeDebug
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%d\x00%t\x00", strategy, fileInfo.Path, fileInfo.Language, fileInfo.TokenCount, debug)
+	hash.Write(config)
+	hash.Write([]byte{0})
+	hash.Write([]byte(content))
+
+	var key [sha256.Size]byte
+	copy(key[:], hash.Sum(nil))
+	return key, nil
+}
+
+// declarationKey addresses a declaration check by the original, its
+// compression and what the compression claims to keep
+func declarationKey(original, compressed string, claims compressionClaims) [sha256.Size]byte {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "declarations\x00%t\x00%t\x00%t\x00%t\x00%d\x00", claims.imports, claims.types, claims.funcs, claims.source, len(original))
+	hash.Write([]byte(original))
+	hash.Write([]byte(compressed))
+
+	var key [sha256.Size]byte
+	copy(key[:], hash.Sum(nil))
+	return key
+}
@@ -0,0 +1,46 @@
+package context
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// defaultDebugPreservePatterns match the lines that explain failures: notes
+// left about known problems, error checks and returns, panics, and logging
+func defaultDebugPreservePatterns() []string {
+	return []string{
+		`\b(TODO|FIXME|XXX|HACK|BUG)\b`,
+		`^\s*if err != nil`,
+		`\breturn\b.*\berr(or)?\b`,
+		`\b(fmt\.Errorf|errors\.New)\(`,
+		`\bpanic\(`,
+		`\b(log|logger|slog)\.\w+\(`,
+		`\bconsole\.(error|warn)\(`,
+		`^\s*(raise|throw)\b`,
+		`^\s*(except|catch)\b`,
+	}
+}
+
+// debugPatterns returns the compiled patterns of lines to keep in truncated
+// bodies, or nil unless task is a debug task. Compilation errors are cached
+// with the result.
+func (c *DefaultContextCompressor) debugPatterns(task *Task) ([]*regexp.Regexp, error) {
+	if task == nil || task.Type != TaskTypeDebug || len(c.config.DebugPreservePatterns) == 0 {
+		return nil, nil
+	}
+
+	c.rulesMutex.Lock()
+	defer c.rulesMutex.Unlock()
+	if !c.debugCompiled {
+		c.debugCompiled = true
+		for _, pattern := range c.config.DebugPreservePatterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				c.debugRules, c.debugErr = nil, fmt.Errorf("invalid debug preserve pattern %q: %w", pattern, err)
+				break
+			}
+			c.debugRules = append(c.debugRules, re)
+		}
+	}
+	return c.debugRules, c.debugErr
+}
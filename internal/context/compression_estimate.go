@@ -0,0 +1,177 @@
+package context
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Limits that keep EstimateCompression cheap on large selections
+const (
+	estimateSampleFiles = 20        // Largest files sampled per selection
+	estimateSampleBytes = 64 * 1024 // Bytes read from each sampled file
+)
+
+// fallbackCompressionRatios are typical ratios used when no content can be sampled
+var fallbackCompressionRatios = map[CompressionStrategy]float64{
+	CompressionNone:     1.0,
+	CompressionSummary:  0.3, // Summaries typically achieve 70% reduction
+	CompressionSnippet:  0.4, // Snippets achieve ~60% reduction
+	CompressionMinify:   0.8, // Minification achieves ~20% reduction
+	CompressionSemantic: 0.5, // Semantic compression achieves ~50% reduction
+}
+
+// contentProfile tallies how much of a file each strategy would keep. Sizes
+// count non-whitespace bytes, which track token counts closely enough for an
+// estimate without tokenizing.
+type contentProfile struct {
+	total    int
+	minify   int
+	summary  int
+	snippet  int
+	semantic int
+}
+
+// estimateFromContent returns the token-weighted ratio the strategy would
+// achieve on a sample of the selection's largest files, and false when none
+// of them could be read
+func (c *DefaultContextCompressor) estimateFromContent(selection *SelectedContext, strategy CompressionStrategy) (float64, bool) {
+	files := make([]ContextFile, 0, len(selection.Files))
+	for _, file := range selection.Files {
+		if file.FileInfo != nil {
+			files = append(files, file)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].FileInfo.TokenCount > files[j].FileInfo.TokenCount
+	})
+	if len(files) > estimateSampleFiles {
+		files = files[:estimateSampleFiles]
+	}
+
+	weightedKept, totalWeight := 0.0, 0.0
+	for _, file := range files {
+		content, ok := loadContextFileContent(file)
+		if !ok {
+			continue
+		}
+		if len(content) > estimateSampleBytes {
+			content = content[:estimateSampleBytes]
+		}
+
+		profile := c.profileContent(content, file.FileInfo)
+		if profile.total == 0 {
+			continue
+		}
+
+		var kept int
+		switch strategy {
+		case CompressionMinify:
+			kept = profile.minify
+		case CompressionSummary:
+			kept = profile.summary
+		case CompressionSnippet:
+			kept = profile.snippet
+		case CompressionSemantic:
+			kept = profile.semantic
+		default:
+			kept = profile.total
+		}
+
+		weight := float64(file.FileInfo.TokenCount)
+		if weight <= 0 {
+			weight = float64(profile.total)
+		}
+		weightedKept += min(float64(kept)/float64(profile.total), 1.0) * weight
+		totalWeight += weight
+	}
+
+	if totalWeight == 0 {
+		return 0, false
+	}
+	return weightedKept / totalWeight, true
+}
+
+// profileContent mirrors what each compression method keeps, line by line,
+// without building the compressed output
+func (c *DefaultContextCompressor) profileContent(content string, fileInfo *FileInfo) contentProfile {
+	var profile contentProfile
+	language := fileInfo.Language
+	lines := strings.Split(content, "\n")
+	sizes := make([]int, len(lines))
+
+	header := nonSpaceLen(fileInfo.Path) + 4 // The "// SUMMARY of <path>" style heading
+	profile.summary = header + nonSpaceLen(language) + 4
+	profile.snippet = header
+	profile.semantic = header
+
+	for i, line := range lines {
+		sizes[i] = nonSpaceLen(line)
+		profile.total += sizes[i]
+
+		trimmed := strings.TrimSpace(line)
+		isImport := c.isImportLine(line, language)
+		isFunction := c.isFunctionStart(line, language)
+		isDeclaration := language == "go" && (strings.HasPrefix(trimmed, "package ") || strings.HasPrefix(trimmed, "type "))
+
+		if isImport || isDeclaration {
+			profile.semantic += sizes[i]
+		}
+		if isFunction {
+			profile.semantic += sizes[i] + 9 // " { /* ... */ }"
+		}
+		if isImport && c.config.PreserveImports {
+			profile.snippet += sizes[i]
+		}
+		if language == "go" && (isImport || isDeclaration || isFunction) {
+			profile.summary += sizes[i]
+			if isFunction {
+				profile.summary += 9
+			}
+		}
+	}
+
+	// Snippets keep each function's first lines and its closing line
+	if c.config.MinFunctionLines >= 0 {
+		for i, line := range lines {
+			if !c.isFunctionStart(line, language) {
+				continue
+			}
+			for j := i; j < len(lines) && j < i+c.config.MinFunctionLines+1; j++ {
+				profile.snippet += sizes[j]
+			}
+			profile.snippet += 24 // Truncation marker and closing line
+		}
+	}
+
+	// Only Go files get a structural summary; other languages keep a heading
+	// and, for unrecognized languages, the first and last lines
+	switch language {
+	case "go", "javascript", "typescript", "python":
+	default:
+		if len(lines) > 10 {
+			for _, size := range append(sizes[:3:3], sizes[len(sizes)-3:]...) {
+				profile.summary += size
+			}
+		} else {
+			profile.summary += profile.total
+		}
+	}
+
+	profile.minify = profile.total
+	if !c.config.PreserveComments {
+		profile.minify = nonSpaceLen(c.removeComments(content, language))
+	}
+	return profile
+}
+
+// nonSpaceLen counts the bytes of s outside whitespace
+func nonSpaceLen(s string) int {
+	n := 0
+	for _, r := range s {
+		if !unicode.IsSpace(r) {
+			n += len(string(r))
+		}
+	}
+	return n
+}
@@ -0,0 +1,225 @@
+package context
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// importGroup orders the sections of a grouped import list
+type importGroup int
+
+const (
+	importGroupStandard importGroup = iota // Go's standard library, Node builtins
+	importGroupExternal                    // Third-party modules and packages
+	importGroupLocal                       // The project's own packages and relative imports
+)
+
+// groupedImport is one import statement and the module it names
+type groupedImport struct {
+	group  importGroup
+	module string
+	line   string
+}
+
+// nodeBuiltinModules are the core modules Node.js resolves before packages
+var nodeBuiltinModules = map[string]bool{
+	"assert": true, "async_hooks": true, "buffer": true, "child_process": true,
+	"cluster": true, "console": true, "constants": true, "crypto": true,
+	"dgram": true, "dns": true, "events": true, "fs": true, "http": true,
+	"http2": true, "https": true, "module": true, "net": true, "os": true,
+	"path": true, "perf_hooks": true, "process": true, "querystring": true,
+	"readline": true, "stream": true, "string_decoder": true, "timers": true,
+	"tls": true, "tty": true, "url": true, "util": true, "v8": true, "vm": true,
+	"worker_threads": true, "zlib": true,
+}
+
+// javaScriptImportModule finds the module a JavaScript import or require names
+var javaScriptImportModule = regexp.MustCompile(`(?:\bfrom\s*|^import\s*|\brequire\(\s*)['"]([^'"]+)['"]`)
+
+// extractAndGroupImports returns a file's imports grouped into standard
+// library, third-party and project-local sections, each sorted and without
+// duplicates. Go imports come out as one import declaration; languages
+// without grouping rules keep their import lines in file order.
+func (c *DefaultContextCompressor) extractAndGroupImports(content string, fileInfo *FileInfo) []string {
+	var imports []groupedImport
+	switch fileInfo.Language {
+	case "go":
+		if goImports, ok := groupGoImports(content, fileInfo); ok {
+			return formatGoImports(goImports)
+		}
+	case "javascript", "typescript":
+		imports = groupJavaScriptImports(c.importStatements(content, fileInfo.Language))
+		return formatImportGroups(imports)
+	case "python":
+		for _, line := range c.importStatements(content, fileInfo.Language) {
+			group := importGroupExternal
+			if strings.HasPrefix(line, "from .") {
+				group = importGroupLocal
+			}
+			imports = append(imports, groupedImport{group: group, module: line, line: line})
+		}
+		return formatImportGroups(imports)
+	}
+
+	var lines []string
+	seen := make(map[string]bool)
+	for _, line := range c.importStatements(content, fileInfo.Language) {
+		if !seen[line] {
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// importStatements returns the trimmed import lines of content. A JavaScript
+// import spread over several lines is joined into one.
+func (c *DefaultContextCompressor) importStatements(content, language string) []string {
+	var statements []string
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		if !c.isImportLine(lines[i], language) {
+			continue
+		}
+		statement := strings.TrimSpace(lines[i])
+		if language == "javascript" || language == "typescript" {
+			for !strings.ContainsAny(statement, `'"`) && i+1 < len(lines) {
+				i++
+				statement += " " + strings.TrimSpace(lines[i])
+			}
+		}
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// groupGoImports parses the imports of a Go file, classifying paths within
+// the file's module as local. Without a module every import outside the
+// standard library is external. It returns false when the imports don't parse.
+func groupGoImports(content string, fileInfo *FileInfo) ([]groupedImport, bool) {
+	parsed, err := parser.ParseFile(token.NewFileSet(), "", content, parser.ImportsOnly)
+	if err != nil {
+		return nil, false
+	}
+
+	module, found := findGoModule(fileInfo)
+	var imports []groupedImport
+	for _, spec := range parsed.Imports {
+		path := strings.Trim(spec.Path.Value, "\"`")
+		line := spec.Path.Value
+		if spec.Name != nil {
+			line = spec.Name.Name + " " + line
+		}
+
+		group := importGroupExternal
+		switch {
+		case !strings.Contains(strings.Split(path, "/")[0], "."):
+			group = importGroupStandard
+		case found && (path == module.info.ModulePath || strings.HasPrefix(path, module.info.ModulePath+"/")):
+			group = importGroupLocal
+		}
+		imports = append(imports, groupedImport{group: group, module: path, line: line})
+	}
+	return imports, true
+}
+
+// findGoModule returns the module of the closest go.mod above a Go file,
+// read from wherever the file was analyzed from. The search stops at the
+// project root when the file came from a project file system.
+func findGoModule(fileInfo *FileInfo) (goModuleRoot, bool) {
+	dir := filepath.Dir(fileInfo.Path)
+	for {
+		if info := loadGoModuleInfo(fileInfo.files, dir); info != nil {
+			return goModuleRoot{info: info, dir: dir}, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir || (fileInfo.files != nil && dir == fileInfo.files.root) {
+			return goModuleRoot{}, false
+		}
+		dir = parent
+	}
+}
+
+// groupJavaScriptImports classifies import statements as Node builtins,
+// packages, or relative imports of project files
+func groupJavaScriptImports(statements []string) []groupedImport {
+	imports := make([]groupedImport, 0, len(statements))
+	for _, statement := range statements {
+		module := statement
+		if match := javaScriptImportModule.FindStringSubmatch(statement); match != nil {
+			module = match[1]
+		}
+
+		group := importGroupExternal
+		switch {
+		case isRelativeJavaScriptImport(module):
+			group = importGroupLocal
+		case strings.HasPrefix(module, "node:") || nodeBuiltinModules[strings.Split(module, "/")[0]]:
+			group = importGroupStandard
+		}
+		imports = append(imports, groupedImport{group: group, module: module, line: statement})
+	}
+	return imports
+}
+
+// sortImports orders imports by group, then module and line, and drops
+// repeated lines
+func sortImports(imports []groupedImport) []groupedImport {
+	sort.SliceStable(imports, func(i, j int) bool {
+		if imports[i].group != imports[j].group {
+			return imports[i].group < imports[j].group
+		}
+		if imports[i].module != imports[j].module {
+			return imports[i].module < imports[j].module
+		}
+		return imports[i].line < imports[j].line
+	})
+
+	unique := imports[:0]
+	for _, imp := range imports {
+		if len(unique) > 0 && imp.line == unique[len(unique)-1].line {
+			continue
+		}
+		unique = append(unique, imp)
+	}
+	return unique
+}
+
+// formatImportGroups returns the sorted import lines with a blank line
+// between groups
+func formatImportGroups(imports []groupedImport) []string {
+	var lines []string
+	sorted := sortImports(imports)
+	for i, imp := range sorted {
+		if i > 0 && imp.group != sorted[i-1].group {
+			lines = append(lines, "")
+		}
+		lines = append(lines, imp.line)
+	}
+	return lines
+}
+
+// formatGoImports returns the imports as a single gofmt-style import
+// declaration
+func formatGoImports(imports []groupedImport) []string {
+	imports = sortImports(imports)
+	switch len(imports) {
+	case 0:
+		return nil
+	case 1:
+		return []string{"import " + imports[0].line}
+	}
+
+	lines := []string{"import ("}
+	for i, imp := range imports {
+		if i > 0 && imp.group != imports[i-1].group {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "\t"+imp.line)
+	}
+	return append(lines, ")")
+}
@@ -0,0 +1,169 @@
+package context
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compiledRules holds a language's compression rules as regular expressions
+type compiledRules struct {
+	imports   []*regexp.Regexp
+	functions []*regexp.Regexp
+	classes   []*regexp.Regexp
+	comments  []*regexp.Regexp
+	preserve  []*regexp.Regexp
+	removable []*regexp.Regexp
+}
+
+// Validate reports the first pattern that isn't a valid regular expression
+func (r *LanguageCompressionRules) Validate() error {
+	_, err := compileLanguageRules(r)
+	return err
+}
+
+// compileLanguageRules compiles every pattern of rules
+func compileLanguageRules(rules *LanguageCompressionRules) (*compiledRules, error) {
+	compiled := &compiledRules{}
+	for _, group := range []struct {
+		name     string
+		patterns []string
+		target   *[]*regexp.Regexp
+	}{
+		{"import", rules.ImportPatterns, &compiled.imports},
+		{"function", rules.FunctionPatterns, &compiled.functions},
+		{"class", rules.ClassPatterns, &compiled.classes},
+		{"comment", rules.CommentPatterns, &compiled.comments},
+		{"preserve", rules.PreservePatterns, &compiled.preserve},
+		{"removable", rules.RemovablePatterns, &compiled.removable},
+	} {
+		for _, pattern := range group.patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s pattern %q: %w", group.name, pattern, err)
+			}
+			*group.target = append(*group.target, re)
+		}
+	}
+	return compiled, nil
+}
+
+// hasBuiltinRules reports whether the compressor has hand-written handling
+// for a language. Built-in handling skips string literals when stripping
+// comments, which line patterns can't, so it takes precedence over rules.
+func hasBuiltinRules(language string) bool {
+	switch language {
+	case "go", "javascript", "typescript", "python":
+		return true
+	}
+	return false
+}
+
+// languageRules returns a language's compiled rules, or nil when the config
+// has none for it. Compilation errors are cached with the result.
+func (c *DefaultContextCompressor) languageRules(language string) (*compiledRules, error) {
+	rules, exists := c.config.LanguageRules[language]
+	if !exists || rules == nil {
+		return nil, nil
+	}
+
+	c.rulesMutex.Lock()
+	defer c.rulesMutex.Unlock()
+	if c.compiledRules == nil {
+		c.compiledRules = make(map[string]compiledRulesEntry)
+	}
+	entry, cached := c.compiledRules[language]
+	if !cached {
+		entry.rules, entry.err = compileLanguageRules(rules)
+		if entry.err != nil {
+			entry.err = fmt.Errorf("compression rules for %s: %w", language, entry.err)
+		}
+		c.compiledRules[language] = entry
+	}
+	return entry.rules, entry.err
+}
+
+// compiledRulesEntry caches the outcome of compiling a language's rules
+type compiledRulesEntry struct {
+	rules *compiledRules
+	err   error
+}
+
+// customRules returns the rules that drive compression of a language the
+// compressor has no built-in handling for, or nil
+func (c *DefaultContextCompressor) customRules(language string) *compiledRules {
+	if hasBuiltinRules(language) {
+		return nil
+	}
+	rules, _ := c.languageRules(language)
+	return rules
+}
+
+// preserved reports whether a line matches one of the preserve patterns
+func (r *compiledRules) preserved(line string) bool {
+	return matchesAny(r.preserve, line)
+}
+
+// stripComments removes matches of the comment patterns from each line,
+// leaving preserved lines untouched. Lines keep their place, so a line that
+// held only a comment becomes blank.
+func (r *compiledRules) stripComments(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if r.preserved(line) {
+			continue
+		}
+		for _, comment := range r.comments {
+			line = comment.ReplaceAllString(line, "")
+		}
+		if strings.TrimSpace(line) == "" {
+			line = ""
+		}
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dropRemovable removes lines matching the removable patterns unless they
+// are preserved
+func (r *compiledRules) dropRemovable(content string) string {
+	if len(r.removable) == 0 {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if matchesAny(r.removable, line) && !r.preserved(line) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// summarize keeps preserved lines, imports, class declarations, and function
+// signatures
+func (r *compiledRules) summarize(content string) string {
+	var summary strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case matchesAny(r.functions, trimmed):
+			summary.WriteString(line + " ...\n")
+		case r.preserved(line), matchesAny(r.imports, trimmed), matchesAny(r.classes, trimmed):
+			summary.WriteString(line + "\n")
+		}
+	}
+	return summary.String()
+}
+
+// matchesAny reports whether s matches any of patterns
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
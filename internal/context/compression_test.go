@@ -0,0 +1,812 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// newAutoCompressionSelection returns a selection with a small relevant source
+// file, a large service file, a commented helper file, and a config file, the
+// last two of low relevance
+func newAutoCompressionSelection() *SelectedContext {
+	var service strings.Builder
+	service.WriteString("package service\n\nimport \"fmt\"\n\n")
+	for i := 0; i < 60; i++ {
+		fmt.Fprintf(&service, "// Handle%d processes request %d\nfunc Handle%d(input string) string {\n", i, i, i)
+		for j := 0; j < 12; j++ {
+			fmt.Fprintf(&service, "\tinput = fmt.Sprintf(\"%%s-%d\", input)\n", j)
+		}
+		service.WriteString("\treturn input\n}\n\n")
+	}
+
+	config := "server:\n\n\n  host:     localhost\n\n\n  port:     8080\n\n\nlogging:\n\n\n  level:    debug\n"
+
+	return &SelectedContext{
+		Files: []ContextFile{
+			{
+				FileInfo:       &FileInfo{Path: "/project/user.go", FileType: "source", Language: "go"},
+				RelevanceScore: 0.9,
+				Content:        "package model\n\n// User is an account holder\ntype User struct {\n\tName string\n}\n",
+			},
+			{
+				FileInfo:       &FileInfo{Path: "/project/service.go", FileType: "source", Language: "go"},
+				RelevanceScore: 0.7,
+				Content:        service.String(),
+			},
+			{
+				FileInfo:       &FileInfo{Path: "/project/helpers.go", FileType: "source", Language: "go"},
+				RelevanceScore: 0.3,
+				Content:        "package service\n\n// trim removes surrounding whitespace from every\n// value before it is stored\nfunc trim(values []string) []string {\n\tfor i := range values {\n\t\tvalues[i] = strings.TrimSpace(values[i]) // In place\n\t}\n\treturn values\n}\n",
+			},
+			{
+				FileInfo:       &FileInfo{Path: "/project/config.yaml", FileType: "configuration", Language: "yaml"},
+				RelevanceScore: 0.2,
+				Content:        config,
+			},
+		},
+	}
+}
+
+// compressedMethods maps each compressed file's path to its method
+func compressedMethods(compressed *CompressedContext) map[string]string {
+	methods := make(map[string]string)
+	for _, file := range compressed.CompressedFiles {
+		methods[file.OriginalPath] = file.Method
+	}
+	return methods
+}
+
+// TestAutoCompressionMixesMethods tests that the auto strategy picks a method per file
+func TestAutoCompressionMixesMethods(t *testing.T) {
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	compressor.config.AutoTargetRatio = 1.0 // Keep the initial choices
+
+	compressed, err := compressor.Compress(context.Background(), newAutoCompressionSelection(), CompressionAuto)
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"/project/user.go":     string(CompressionNone),
+		"/project/service.go":  string(CompressionSnippet),
+		"/project/helpers.go":  string(CompressionMinify),
+		"/project/config.yaml": string(CompressionNone), // Minifying doesn't save tokens
+	}
+	methods := compressedMethods(compressed)
+	for path, method := range expected {
+		if methods[path] != method {
+			t.Errorf("%s compressed with %q, expected %q", path, methods[path], method)
+		}
+	}
+
+	if compressed.Strategy != CompressionAuto {
+		t.Errorf("Strategy = %q, expected %q", compressed.Strategy, CompressionAuto)
+	}
+	if compressed.CompressedFiles[0].CompressedContent != newAutoCompressionSelection().Files[0].Content {
+		t.Error("small relevant file should be kept whole")
+	}
+	if compressed.CompressionRatio >= 1.0 || compressed.QualityScore <= 0 || compressed.QualityScore > 1.0 {
+		t.Errorf("ratio = %v, quality = %v", compressed.CompressionRatio, compressed.QualityScore)
+	}
+}
+
+// TestAutoCompressionTargetRatio tests that files are escalated, least relevant
+// first, until the target ratio is met
+func TestAutoCompressionTargetRatio(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   float64
+		expected map[string]string
+	}{
+		{
+			name:   "loose target keeps initial methods",
+			target: 0.4,
+			expected: map[string]string{
+				"/project/user.go":    string(CompressionNone),
+				"/project/service.go": string(CompressionSnippet),
+				"/project/helpers.go": string(CompressionMinify),
+			},
+		},
+		{
+			name:   "less relevant files escalate first",
+			target: 0.3,
+			expected: map[string]string{
+				"/project/user.go":    string(CompressionNone),
+				"/project/service.go": string(CompressionSummary),
+				"/project/helpers.go": string(CompressionMinify), // Lossier methods don't shrink it further
+			},
+		},
+		{
+			name:   "unreachable target compresses everything",
+			target: 0.05,
+			expected: map[string]string{
+				"/project/user.go":    string(CompressionMinify),
+				"/project/service.go": string(CompressionSummary),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+			compressor.config.AutoTargetRatio = tt.target
+
+			compressed, err := compressor.Compress(context.Background(), newAutoCompressionSelection(), CompressionAuto)
+			if err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+
+			methods := compressedMethods(compressed)
+			for path, method := range tt.expected {
+				if methods[path] != method {
+					t.Errorf("%s compressed with %q, expected %q", path, methods[path], method)
+				}
+			}
+			for _, file := range compressed.CompressedFiles {
+				if file.CompressedTokens > file.OriginalTokens {
+					t.Errorf("%s grew from %d to %d tokens", file.OriginalPath, file.OriginalTokens, file.CompressedTokens)
+				}
+			}
+		})
+	}
+}
+
+// TestEstimateCompressionFromContent tests that estimates track the ratio the
+// compressor actually achieves on the selection
+func TestEstimateCompressionFromContent(t *testing.T) {
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	selection := newAutoCompressionSelection()
+	selection.Files = selection.Files[1:2] // The service file has every kind of line
+	selection.Files[0].FileInfo.TokenCount, _ = compressor.tokenCounter.CountTokens(selection.Files[0].Content)
+
+	for _, strategy := range []CompressionStrategy{CompressionMinify, CompressionSummary, CompressionSnippet, CompressionSemantic} {
+		t.Run(string(strategy), func(t *testing.T) {
+			estimate, err := compressor.EstimateCompression(selection, strategy)
+			if err != nil {
+				t.Fatalf("EstimateCompression failed: %v", err)
+			}
+			compressed, err := compressor.Compress(context.Background(), selection, strategy)
+			if err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+			if diff := estimate - compressed.CompressionRatio; diff < -0.1 || diff > 0.1 {
+				t.Errorf("estimate = %.3f, actual ratio = %.3f", estimate, compressed.CompressionRatio)
+			}
+		})
+	}
+}
+
+// TestEstimateCompressionMinify tests that the minify estimate reflects how
+// much of the content is comments
+func TestEstimateCompressionMinify(t *testing.T) {
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	estimate := func(content string) float64 {
+		selection := &SelectedContext{Files: []ContextFile{{
+			FileInfo: &FileInfo{Path: "/project/main.go", Language: "go", TokenCount: 100},
+			Content:  content,
+		}}}
+		ratio, err := compressor.EstimateCompression(selection, CompressionMinify)
+		if err != nil {
+			t.Fatalf("EstimateCompression failed: %v", err)
+		}
+		return ratio
+	}
+
+	plain := estimate("package main\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n")
+	commented := estimate("package main\n\n// main prints a greeting to standard output so that\n// callers can verify the binary was built and runs\nfunc main() {\n\tfmt.Println(\"hello\") // The greeting\n}\n")
+
+	if plain != 1.0 {
+		t.Errorf("comment-free estimate = %.3f, expected 1.0", plain)
+	}
+	if commented > 0.6 {
+		t.Errorf("comment-heavy estimate = %.3f, expected most content to be removed", commented)
+	}
+}
+
+// TestEstimateCompressionFallback tests that typical ratios are used when no
+// content can be read
+func TestEstimateCompressionFallback(t *testing.T) {
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	selection := &SelectedContext{Files: []ContextFile{{
+		FileInfo: &FileInfo{Path: "/nonexistent/main.go", Language: "go", TokenCount: 100},
+	}}}
+
+	for strategy, expected := range fallbackCompressionRatios {
+		ratio, err := compressor.EstimateCompression(selection, strategy)
+		if err != nil {
+			t.Fatalf("EstimateCompression(%s) failed: %v", strategy, err)
+		}
+		if ratio != expected {
+			t.Errorf("EstimateCompression(%s) = %v, expected %v", strategy, ratio, expected)
+		}
+	}
+}
+
+// TestWindowCompressionKeepsMatchingFunction tests that the window strategy
+// keeps the one function matching the task and records where it came from
+func TestWindowCompressionKeepsMatchingFunction(t *testing.T) {
+	var source strings.Builder
+	source.WriteString("package billing\n\nimport \"math\"\n\n")
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&source, "// Charge%d bills plan %d\nfunc Charge%d(amount float64) float64 {\n\ttotal := amount * %d\n\treturn math.Round(total)\n}\n\n", i, i, i, i+1)
+	}
+	source.WriteString("// Refund returns money for a cancelled order\nfunc Refund(amount float64) float64 {\n\tfee := amount * 0.03\n\treturn math.Floor(amount - fee)\n}\n\n")
+	for i := 30; i < 60; i++ {
+		fmt.Fprintf(&source, "// Charge%d bills plan %d\nfunc Charge%d(amount float64) float64 {\n\ttotal := amount * %d\n\treturn math.Round(total)\n}\n\n", i, i, i, i+1)
+	}
+
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	compressor.config.WindowMaxTokens = 150
+	selection := &SelectedContext{
+		Task: &Task{Type: TaskTypeDebug, Description: "fix refund rounding"},
+		Files: []ContextFile{{
+			FileInfo: &FileInfo{Path: "/project/billing.go", Language: "go"},
+			Content:  source.String(),
+		}},
+	}
+
+	compressed, err := compressor.Compress(context.Background(), selection, CompressionWindow)
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	file := compressed.CompressedFiles[0]
+
+	if !strings.Contains(file.CompressedContent, "// Refund returns money for a cancelled order\nfunc Refund(amount float64) float64 {\n\tfee := amount * 0.03\n\treturn math.Floor(amount - fee)\n}") {
+		t.Errorf("Refund function not kept whole:\n%s", file.CompressedContent)
+	}
+	if strings.Contains(file.CompressedContent, "func Charge") {
+		t.Errorf("unrelated functions kept:\n%s", file.CompressedContent)
+	}
+	if file.CompressedTokens > 150 || compressed.CompressionRatio >= 0.1 {
+		t.Errorf("compressed to %d tokens, ratio %.3f", file.CompressedTokens, compressed.CompressionRatio)
+	}
+
+	ranges, ok := file.Metadata["line_ranges"].([]LineRange)
+	if !ok || len(ranges) != 1 {
+		t.Fatalf("line_ranges = %v, expected the Refund function's range", file.Metadata["line_ranges"])
+	}
+	lines := strings.Split(source.String(), "\n")
+	if lines[ranges[0].Start-1] != "// Refund returns money for a cancelled order" || lines[ranges[0].End-1] != "}" {
+		t.Errorf("range %+v covers %q to %q", ranges[0], lines[ranges[0].Start-1], lines[ranges[0].End-1])
+	}
+	if !strings.Contains(file.CompressedContent, fmt.Sprintf("// ... lines 1-%d omitted ...", ranges[0].Start-1)) {
+		t.Errorf("missing omission marker before the kept range:\n%s", file.CompressedContent)
+	}
+
+	// Without keyword hits the head of the file is kept
+	selection.Task.Description = "update documentation"
+	compressed, err = compressor.Compress(context.Background(), selection, CompressionWindow)
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	if ranges := compressed.CompressedFiles[0].Metadata["line_ranges"].([]LineRange); len(ranges) != 1 || ranges[0].Start != 1 {
+		t.Errorf("line_ranges without hits = %v, expected the head", ranges)
+	}
+}
+
+// TestUsageCompressionKeepsCalledFunction tests that of three functions in
+// one file, only the one another selected file calls keeps its body
+func TestUsageCompressionKeepsCalledFunction(t *testing.T) {
+	var store strings.Builder
+	store.WriteString("package store\n\nimport \"strings\"\n\n// Prefix is prepended to every key\nconst Prefix = \"k:\"\n\n")
+	for _, name := range []string{"Open", "Lookup", "Close"} {
+		fmt.Fprintf(&store, "// %s does the %s work\nfunc %s(key string) string {\n", name, strings.ToLower(name), name)
+		store.WriteString("\tkey = strings.TrimSpace(key)\n\tkey = strings.ToLower(key)\n\tkey = Prefix + key\n\treturn key\n}\n\n")
+	}
+	handler := "package api\n\nimport \"example.com/app/internal/store\"\n\n// Get looks a key up\nfunc Get(key string) string {\n\treturn store.Lookup(key)\n}\n"
+
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	selection := &SelectedContext{
+		Task: &Task{Type: TaskTypeFeature, Description: "add caching"},
+		Files: []ContextFile{
+			{FileInfo: &FileInfo{Path: "/project/internal/store/store.go", Language: "go"}, Content: store.String()},
+			{FileInfo: &FileInfo{Path: "/project/internal/api/api.go", Language: "go"}, Content: handler},
+		},
+	}
+
+	compressed, err := compressor.Compress(context.Background(), selection, CompressionUsage)
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	storeFile, apiFile := compressed.CompressedFiles[0], compressed.CompressedFiles[1]
+
+	if !strings.Contains(storeFile.CompressedContent, "// Lookup does the lookup work\nfunc Lookup(key string) string {\n\tkey = strings.TrimSpace(key)") {
+		t.Errorf("Lookup not kept whole:\n%s", storeFile.CompressedContent)
+	}
+	for _, name := range []string{"Open", "Close"} {
+		if !strings.Contains(storeFile.CompressedContent, fmt.Sprintf("func %s(key string) string { ... }", name)) {
+			t.Errorf("%s not reduced to its signature:\n%s", name, storeFile.CompressedContent)
+		}
+		if strings.Contains(storeFile.CompressedContent, fmt.Sprintf("// %s does", name)) {
+			t.Errorf("doc comment of summarized %s kept:\n%s", name, storeFile.CompressedContent)
+		}
+	}
+	if !strings.Contains(storeFile.CompressedContent, "const Prefix = \"k:\"") {
+		t.Errorf("constant dropped:\n%s", storeFile.CompressedContent)
+	}
+	if kept := storeFile.Metadata["kept_symbols"].([]string); len(kept) != 1 || kept[0] != "Lookup" {
+		t.Errorf("kept_symbols = %v, expected [Lookup]", kept)
+	}
+	if summarized := storeFile.Metadata["summarized_symbols"].([]string); len(summarized) != 2 {
+		t.Errorf("summarized_symbols = %v, expected Close and Open", summarized)
+	}
+
+	// Get is short enough that summarizing it would save nothing
+	if apiFile.CompressedContent != handler {
+		t.Errorf("api.go changed:\n%s", apiFile.CompressedContent)
+	}
+	if storeFile.CompressedTokens >= storeFile.OriginalTokens || compressed.Strategy != CompressionUsage {
+		t.Errorf("store.go went from %d to %d tokens with strategy %s", storeFile.OriginalTokens, storeFile.CompressedTokens, compressed.Strategy)
+	}
+}
+
+// TestAnalyzeCompressionRecordsTechniques tests that the analysis reports the
+// techniques each file went through and totals that match the files
+func TestAnalyzeCompressionRecordsTechniques(t *testing.T) {
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	compressor.config.AutoTargetRatio = 1.0 // Keep the initial choices
+
+	analysis, err := compressor.AnalyzeCompression(context.Background(), newAutoCompressionSelection(), CompressionAuto)
+	if err != nil {
+		t.Fatalf("AnalyzeCompression failed: %v", err)
+	}
+
+	expected := map[string][]string{
+		"/project/user.go":     {"none"},
+		"/project/service.go":  {"snippets"},
+		"/project/helpers.go":  {"minify", "remove_comments", "remove_whitespace", "remove_empty_lines"},
+		"/project/config.yaml": {"none"},
+	}
+	if len(analysis.FileAnalysis) != len(expected) {
+		t.Fatalf("analyzed %d files, expected %d", len(analysis.FileAnalysis), len(expected))
+	}
+
+	original, compressed := 0, 0
+	for _, file := range analysis.FileAnalysis {
+		if got := strings.Join(file.TechniquesUsed, ","); got != strings.Join(expected[file.FilePath], ",") {
+			t.Errorf("%s techniques = %s, expected %v", file.FilePath, got, expected[file.FilePath])
+		}
+		if lossless := file.TechniquesUsed[0] == "none"; lossless != (file.QualityImpact == 0) {
+			t.Errorf("%s quality impact = %v", file.FilePath, file.QualityImpact)
+		}
+		original += file.OriginalTokens
+		compressed += file.CompressedTokens
+	}
+
+	if got := strings.Join(analysis.TechniquesApplied, ","); got != "none,snippets,minify,remove_comments,remove_whitespace,remove_empty_lines" {
+		t.Errorf("techniques applied = %s", got)
+	}
+	if analysis.OriginalTokens != original || analysis.CompressedTokens != compressed || analysis.TokenSavings != original-compressed {
+		t.Errorf("totals = %d -> %d saving %d, expected %d -> %d", analysis.OriginalTokens, analysis.CompressedTokens, analysis.TokenSavings, original, compressed)
+	}
+	if analysis.TokenSavings <= 0 || analysis.QualityEstimate <= 0 || analysis.QualityEstimate > 1.0 {
+		t.Errorf("savings = %d, quality = %v", analysis.TokenSavings, analysis.QualityEstimate)
+	}
+}
+
+// TestLanguageRulesForNewLanguage tests that rules added for a language
+// without built-in handling strip its comments, keep preserved lines, and
+// drive its summary
+func TestLanguageRulesForNewLanguage(t *testing.T) {
+	config := &CompressionConfig{
+		LanguageRules: map[string]*LanguageCompressionRules{
+			"ruby": {
+				ImportPatterns:    []string{`^require\s+`},
+				FunctionPatterns:  []string{`^def\s+`},
+				ClassPatterns:     []string{`^(class|module)\s+`},
+				CommentPatterns:   []string{`#.*$`},
+				PreservePatterns:  []string{`^#!`, `^# frozen_string_literal:`},
+				RemovablePatterns: []string{`^\s*$`},
+			},
+		},
+	}
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), config)
+	content := "#!/usr/bin/env ruby\n# frozen_string_literal: true\nrequire \"json\"\n\n# Greeter says hello\nclass Greeter\n  def greet(name) # Called by the CLI\n    puts name\n  end\nend\n"
+	selection := &SelectedContext{Files: []ContextFile{
+		{FileInfo: &FileInfo{Path: "/project/greeter.rb", Language: "ruby"}, Content: content},
+	}}
+
+	minified, err := compressor.Compress(context.Background(), selection, CompressionMinify)
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	result := minified.CompressedFiles[0].CompressedContent
+	for _, kept := range []string{"#!/usr/bin/env ruby\n# frozen_string_literal: true\n", "class Greeter", "def greet(name)\n"} {
+		if !strings.Contains(result, kept) {
+			t.Errorf("minified content missing %q:\n%s", kept, result)
+		}
+	}
+	for _, stripped := range []string{"says hello", "Called by the CLI", "\n\n"} {
+		if strings.Contains(result, stripped) {
+			t.Errorf("minified content kept %q:\n%s", stripped, result)
+		}
+	}
+
+	summarized, err := compressor.Compress(context.Background(), selection, CompressionSummary)
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	summary := summarized.CompressedFiles[0].CompressedContent
+	for _, kept := range []string{"require \"json\"", "class Greeter", "def greet(name) # Called by the CLI ..."} {
+		if !strings.Contains(summary, kept) {
+			t.Errorf("summary missing %q:\n%s", kept, summary)
+		}
+	}
+	if strings.Contains(summary, "puts name") {
+		t.Errorf("summary kept a function body:\n%s", summary)
+	}
+
+	// Invalid rules leave the file uncompressed
+	config.LanguageRules["ruby"].CommentPatterns = []string{`#(`}
+	if err := config.LanguageRules["ruby"].Validate(); err == nil {
+		t.Error("Validate accepted an invalid pattern")
+	}
+	broken, _ := NewDefaultContextCompressor(NewSimpleTokenCounter(), config).Compress(context.Background(), selection, CompressionMinify)
+	if broken.CompressedFiles[0].CompressedContent != content {
+		t.Error("invalid rules should leave the content as is")
+	}
+}
+
+// TestCompressionQualityValidation tests that quality reflects the Go
+// declarations a compressed file keeps intact. The sample's raw string holds
+// lines that look like declarations, which the line-based summary lifts out
+// of the literal and so breaks the declarations around them.
+func TestCompressionQualityValidation(t *testing.T) {
+	source := "package sample\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n\nconst usage = `\ntype Options struct {\n\timport \"os\"\n`\n\n" +
+		"// Greeter says hello\ntype Greeter struct {\n\tName string\n}\n\n" +
+		"func (g *Greeter) Greet() string {\n\treturn fmt.Sprintf(\"hello %s\", strings.TrimSpace(g.Name))\n}\n\n" +
+		"// NewGreeter creates a greeter\nfunc NewGreeter(name string) *Greeter {\n\tif name == \"\" {\n\t\tname = \"world\"\n\t}\n\treturn &Greeter{Name: name}\n}\n"
+
+	tests := []struct {
+		name     string
+		language string
+		strategy CompressionStrategy
+		intact   bool // Claimed declarations all survive, so the estimate stands
+	}{
+		{name: "minify keeps the source", language: "go", strategy: CompressionMinify, intact: true},
+		{name: "usage elides bodies only", language: "go", strategy: CompressionUsage, intact: true},
+		{name: "summary corrupts declarations", language: "go", strategy: CompressionSummary},
+		{name: "languages without a parser are skipped", language: "python", strategy: CompressionSummary, intact: true},
+	}
+
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection := &SelectedContext{Files: []ContextFile{{
+				FileInfo: &FileInfo{Path: "/project/greeter.go", FileType: "source", Language: tt.language},
+				Content:  source,
+			}}}
+			compressed, err := compressor.Compress(context.Background(), selection, tt.strategy)
+			if err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+
+			estimate := compressor.estimateQualityImpact(tt.strategy, compressed.CompressionRatio)
+			missing, _ := compressed.CompressedFiles[0].Metadata["declarations_missing"].([]string)
+			if tt.intact {
+				if compressed.QualityScore != estimate || len(missing) > 0 {
+					t.Errorf("quality = %v with %v missing, expected the estimate %v", compressed.QualityScore, missing, estimate)
+				}
+				return
+			}
+			if compressed.QualityScore >= estimate {
+				t.Errorf("quality = %v, expected below the estimate %v", compressed.QualityScore, estimate)
+			}
+			if joined := strings.Join(missing, ", "); !strings.Contains(joined, "type Greeter") || !strings.Contains(joined, `import "fmt"`) {
+				t.Errorf("declarations_missing = %v, expected the corrupted type and imports", missing)
+			}
+		})
+	}
+}
+
+// TestSnippetCompressionKeepsDebugLines tests that snippets for debug tasks
+// keep the error handling and notes of truncated bodies, and drop the rest
+func TestSnippetCompressionKeepsDebugLines(t *testing.T) {
+	content := "package orders\n\n" +
+		"func Total(path string) (int, error) {\n" +
+		"\tdata, err := os.ReadFile(path)\n" +
+		"\tif err != nil {\n" +
+		"\t\treturn 0, fmt.Errorf(\"failed to read orders: %w\", err)\n" +
+		"\t}\n" +
+		"\tvar items []Item\n" +
+		"\tif err := json.Unmarshal(data, &items); err != nil {\n" +
+		"\t\treturn 0, fmt.Errorf(\"failed to parse orders: %w\", err)\n" +
+		"\t}\n" +
+		"\ttotal := 0\n" +
+		"\tfor _, item := range items {\n" +
+		"\t\ttotal += item.Price * item.Quantity\n" +
+		"\t}\n" +
+		"\t// TODO: apply discounts\n" +
+		"\treturn total, nil\n" +
+		"}\n"
+	// The first lines of a body are kept for every task
+	errorLines := []string{"failed to parse orders", "TODO: apply discounts"}
+	happyLines := []string{"total += item.Price", "for _, item := range items"}
+
+	tests := []struct {
+		name     string
+		taskType TaskType
+		kept     []string
+		dropped  []string
+	}{
+		{name: "debug task keeps error handling", taskType: TaskTypeDebug, kept: errorLines, dropped: happyLines},
+		{name: "other tasks truncate it", taskType: TaskTypeFeature, dropped: append(errorLines, happyLines...)},
+	}
+
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection := &SelectedContext{
+				Task: &Task{Type: tt.taskType},
+				Files: []ContextFile{{
+					FileInfo: &FileInfo{Path: "/project/orders.go", FileType: "source", Language: "go"},
+					Content:  content,
+				}},
+			}
+			compressed, err := compressor.Compress(context.Background(), selection, CompressionSnippet)
+			if err != nil {
+				t.Fatalf("Compress failed: %v", err)
+			}
+			snippet := compressed.CompressedFiles[0].CompressedContent
+			for _, line := range tt.kept {
+				if !strings.Contains(snippet, line) {
+					t.Errorf("snippet missing %q:\n%s", line, snippet)
+				}
+			}
+			for _, line := range tt.dropped {
+				if strings.Contains(snippet, line) {
+					t.Errorf("snippet kept %q:\n%s", line, snippet)
+				}
+			}
+			if !strings.HasSuffix(strings.TrimSpace(snippet), "}") {
+				t.Errorf("snippet should end with the function's closing brace:\n%s", snippet)
+			}
+		})
+	}
+}
+
+// TestSemanticCompressionGroupsImports tests that semantic compression emits
+// imports grouped into standard, third-party and project sections, sorted and
+// without duplicates
+func TestSemanticCompressionGroupsImports(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{"go.mod": "module example.com/shop\n\ngo 1.24\n"})
+
+	tests := []struct {
+		name     string
+		path     string
+		language string
+		content  string
+		expected string
+	}{
+		{
+			name:     "go",
+			path:     filepath.Join(root, "orders", "orders.go"),
+			language: "go",
+			content: "package orders\n\nimport (\n\t\"strings\"\n\t\"example.com/shop/internal/store\"\n\t\"github.com/google/uuid\"\n\t\"fmt\"\n)\n\n" +
+				"import \"fmt\"\nimport db \"example.com/shop/internal/db\"\n\nfunc New() string { return fmt.Sprint(uuid.New()) }\n",
+			expected: "import (\n\t\"fmt\"\n\t\"strings\"\n\n\t\"github.com/google/uuid\"\n\n\tdb \"example.com/shop/internal/db\"\n\t\"example.com/shop/internal/store\"\n)\n",
+		},
+		{
+			name:     "javascript",
+			path:     filepath.Join(root, "web", "app.js"),
+			language: "javascript",
+			content: "import { render } from './render.js';\nimport express from 'express';\nimport fs from 'node:fs';\n" +
+				"import {\n  join,\n} from 'path';\nimport express from 'express';\n\nfunction start() {}\n",
+			expected: "import fs from 'node:fs';\nimport { join, } from 'path';\n\nimport express from 'express';\n\nimport { render } from './render.js';\n",
+		},
+	}
+
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, _, _, err := compressor.semanticCompression(tt.content, &FileInfo{Path: tt.path, Language: tt.language})
+			if err != nil {
+				t.Fatalf("semanticCompression failed: %v", err)
+			}
+			if !strings.Contains(compressed, "// Imports:\n"+tt.expected+"\n") {
+				t.Errorf("compressed output:\n%s\nexpected imports:\n%s", compressed, tt.expected)
+			}
+		})
+	}
+}
+
+// TestGroupGoImportsFindsModule tests that Go imports are classified against
+// the go.mod read from the file's own file system, and that without one no
+// import counts as local, whatever module the working directory is in
+func TestGroupGoImportsFindsModule(t *testing.T) {
+	content := "package orders\n\nimport (\n\t\"fmt\"\n\t\"example.com/shop/store\"\n\t\"github.com/rcliao/teeny-orb/internal/context\"\n)\n"
+	tests := []struct {
+		name     string
+		fileInfo *FileInfo
+		expected map[string]importGroup
+	}{
+		{
+			name:     "no module",
+			fileInfo: &FileInfo{Path: filepath.Join(t.TempDir(), "orders", "orders.go"), Language: "go"},
+			expected: map[string]importGroup{
+				"fmt":                    importGroupStandard,
+				"example.com/shop/store": importGroupExternal,
+				"github.com/rcliao/teeny-orb/internal/context": importGroupExternal,
+			},
+		},
+		{
+			name: "module in project file system",
+			fileInfo: &FileInfo{Path: "/project/orders/orders.go", Language: "go", files: &projectFiles{
+				root: "/project",
+				fsys: fstest.MapFS{"go.mod": {Data: []byte("module example.com/shop\n\ngo 1.24\n")}},
+			}},
+			expected: map[string]importGroup{
+				"fmt":                    importGroupStandard,
+				"example.com/shop/store": importGroupLocal,
+				"github.com/rcliao/teeny-orb/internal/context": importGroupExternal,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imports, ok := groupGoImports(content, tt.fileInfo)
+			if !ok {
+				t.Fatal("expected the imports to parse")
+			}
+			for _, imported := range imports {
+				if imported.group != tt.expected[imported.module] {
+					t.Errorf("%s grouped as %d, expected %d", imported.module, imported.group, tt.expected[imported.module])
+				}
+			}
+		})
+	}
+}
+
+// compressionCacheSource returns a Go file with the given number of functions
+func compressionCacheSource(functions int) string {
+	var source strings.Builder
+	source.WriteString("package orders\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n\n")
+	for i := 0; i < functions; i++ {
+		fmt.Fprintf(&source, "// Total%d sums the orders in batch %d\nfunc Total%d(items []string) (int, error) {\n", i, i, i)
+		source.WriteString("\ttotal := 0\n\tfor _, item := range items {\n\t\tif strings.TrimSpace(item) == \"\" {\n")
+		source.WriteString("\t\t\treturn 0, fmt.Errorf(\"empty item\")\n\t\t}\n\t\ttotal += len(item)\n\t}\n\treturn total, nil\n}\n\n")
+	}
+	return source.String()
+}
+
+// TestCompressionCache tests that repeated compressions are served from the
+// cache, and that changed content or config are compressed again
+func TestCompressionCache(t *testing.T) {
+	content := compressionCacheSource(5)
+	fileInfo := &FileInfo{Path: "orders.go", Language: "go", TokenCount: 400}
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	compress := func(content string) string {
+		t.Helper()
+		compressed, _, _, err := compressor.compressFileContent(content, fileInfo, CompressionSnippet, nil)
+		if err != nil {
+			t.Fatalf("compression failed: %v", err)
+		}
+		return compressed
+	}
+
+	first := compress(content)
+	if second := compress(content); second != first || compressor.cache.hits != 1 {
+		t.Fatalf("expected the repeated compression from the cache, got %d hits", compressor.cache.hits)
+	}
+
+	compress(content + "\nfunc Extra() {}\n")
+	if compressor.cache.hits != 1 {
+		t.Errorf("expected changed content to be compressed again, got %d hits", compressor.cache.hits)
+	}
+
+	compressor.config.MinFunctionLines = 6
+	if changed := compress(content); changed == first || compressor.cache.hits != 1 {
+		t.Errorf("expected a config change to bust the cache, got %d hits", compressor.cache.hits)
+	}
+	if compressor.cache.misses != 3 {
+		t.Errorf("expected 3 misses, got %d", compressor.cache.misses)
+	}
+}
+
+// BenchmarkCompressionCache compares compressing a large file from the cache
+// with compressing it again
+func BenchmarkCompressionCache(b *testing.B) {
+	content := compressionCacheSource(300)
+	selection := &SelectedContext{
+		Task:  &Task{Type: TaskTypeFeature},
+		Files: []ContextFile{{FileInfo: &FileInfo{Path: "orders.go", Language: "go", TokenCount: 20000}, Content: content}},
+	}
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+
+	b.Run("hit", func(b *testing.B) {
+		compressor.Compress(context.Background(), selection, CompressionSemantic)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			compressor.Compress(context.Background(), selection, CompressionSemantic)
+		}
+	})
+	b.Run("recompress", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			compressor.ClearCache()
+			compressor.Compress(context.Background(), selection, CompressionSemantic)
+		}
+	})
+}
+
+// TestCompressionLoadsFileContent tests that files selected without their
+// content are compressed from the file system they were analyzed from
+func TestCompressionLoadsFileContent(t *testing.T) {
+	fsys := fstest.MapFS{
+		"util.go": {Data: []byte("package util\n\n// Helper helps\nfunc Helper() int {\n\n\treturn 1\n}\n")},
+	}
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	analyzer.SetFileSystem(fsys)
+	project, err := analyzer.AnalyzeProject(context.Background(), filepath.Join(t.TempDir(), "missing"))
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+
+	compressor := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	selection := &SelectedContext{
+		Task:  &Task{Type: TaskTypeFeature, Description: "add a helper"},
+		Files: []ContextFile{{FileInfo: &project.Files[0], RelevanceScore: 0.9}},
+	}
+	compressed, err := compressor.Compress(context.Background(), selection, CompressionMinify)
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	content := compressed.CompressedFiles[0].CompressedContent
+	if !strings.Contains(content, "func Helper() int {") || strings.Contains(content, "// Tokens:") {
+		t.Errorf("compressed content = %q, expected util.go minified", content)
+	}
+}
+
+// TestUsageCompressionOfSelection tests usage compression on a selection as
+// the optimizer returns it, with content read from the selected files
+func TestUsageCompressionOfSelection(t *testing.T) {
+	var store strings.Builder
+	store.WriteString("package store\n\nimport \"strings\"\n\n// Prefix is prepended to every key\nconst Prefix = \"k:\"\n\n")
+	for _, name := range []string{"Open", "Lookup", "Close"} {
+		fmt.Fprintf(&store, "// %s does the %s work\nfunc %s(key string) string {\n", name, strings.ToLower(name), name)
+		store.WriteString("\tkey = strings.TrimSpace(key)\n\tkey = strings.ToLower(key)\n\tkey = Prefix + key\n\treturn key\n}\n\n")
+	}
+	handler := "package api\n\nimport \"example.com/app/internal/store\"\n\n// Get looks a key up\nfunc Get(key string) string {\n\treturn store.Lookup(key)\n}\n"
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{
+		"internal/store/store.go": store.String(),
+		"internal/api/api.go":     handler,
+	})
+	storePath := filepath.Join(root, "internal", "store", "store.go")
+	apiPath := filepath.Join(root, "internal", "api", "api.go")
+
+	counter := NewSimpleTokenCounter()
+	storeTokens, _ := counter.CountTokens(store.String())
+	apiTokens, _ := counter.CountTokens(handler)
+	project := newTestProject(map[string]int{storePath: storeTokens, apiPath: apiTokens})
+	task := &Task{Type: TaskTypeFeature, Description: "add caching"}
+	constraints := &ContextConstraints{MaxTokens: 1000, MaxFiles: 10, MinRelevanceScore: 0.1, Strategy: StrategyRelevance}
+	selection, err := newTestOptimizer(map[string]float64{storePath: 0.9, apiPath: 0.8}).SelectOptimalContext(context.Background(), project, task, constraints)
+	if err != nil {
+		t.Fatalf("SelectOptimalContext failed: %v", err)
+	}
+	if selection.TotalFiles != 2 {
+		t.Fatalf("selected %v, expected both files", selectedPaths(selection))
+	}
+
+	compressed, err := NewDefaultContextCompressor(NewSimpleTokenCounter(), nil).Compress(context.Background(), selection, CompressionUsage)
+	if err != nil {
+		t.Fatalf("Compress failed: %v", err)
+	}
+	contents := map[string]string{}
+	for _, file := range compressed.CompressedFiles {
+		contents[file.OriginalPath] = file.CompressedContent
+	}
+	storeContent := contents[storePath]
+	if !strings.Contains(storeContent, "func Lookup(key string) string {\n\tkey = strings.TrimSpace(key)") {
+		t.Errorf("Lookup not kept whole:\n%s", storeContent)
+	}
+	if !strings.Contains(storeContent, "func Open(key string) string { ... }") {
+		t.Errorf("Open not reduced to its signature:\n%s", storeContent)
+	}
+}
@@ -0,0 +1,282 @@
+package context
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// usageFile is a selected Go file's declarations and the names it uses
+type usageFile struct {
+	dir       string
+	pkgName   string
+	source    string
+	funcs     []usageFunc
+	idents    map[string]bool            // Every identifier in the file
+	selectors map[string]bool            // Every name selected with x.Name
+	qualified map[string]map[string]bool // Names selected from each package-like identifier
+	imports   []usageImport
+}
+
+// usageFunc is a function or method declaration and its place in the source
+type usageFunc struct {
+	name     string // Methods are named Type.Method
+	method   string // Method name, empty for functions
+	start    int    // Offset of the doc comment, or of the declaration without one
+	decl     int    // Offset of the func keyword
+	bodyFrom int    // Offset of the opening brace of the body
+	end      int
+	lines    int
+}
+
+// usageImport is an import of a usageFile
+type usageImport struct {
+	name string // Explicit local name, empty when the package name is used
+	path string
+}
+
+// compressUsage keeps the bodies of functions that other selected files use
+// and reduces the rest to their signatures. A file sees another's functions
+// when they share a directory, and so a package, or when it imports a path
+// naming the other's directory. Types, variables, and constants are kept,
+// as are functions no longer than MinFunctionLines. Files that aren't Go, or
+// don't parse, are summarized.
+func (c *DefaultContextCompressor) compressUsage(selection *SelectedContext, startTime time.Time) *CompressedContext {
+	compressed := &CompressedContext{
+		Original:         selection,
+		CompressedFiles:  make([]CompressedFile, 0, len(selection.Files)),
+		CompressionRatio: 1.0,
+		Strategy:         CompressionUsage,
+		QualityScore:     1.0,
+	}
+
+	contents := make([]string, len(selection.Files))
+	originals := make([]int, len(selection.Files))
+	parsed := make([]*usageFile, len(selection.Files))
+	for i, contextFile := range selection.Files {
+		contents[i], originals[i] = c.fileContent(contextFile)
+		if contextFile.FileInfo.Language == "go" {
+			parsed[i] = parseUsageFile(contextFile.FileInfo.Path, contents[i])
+		}
+	}
+
+	totalOriginal, totalCompressed := 0, 0
+	validated := 0.0
+	for i, contextFile := range selection.Files {
+		compressedFile := CompressedFile{
+			OriginalPath:   contextFile.FileInfo.Path,
+			OriginalTokens: originals[i],
+			Method:         string(CompressionUsage),
+		}
+
+		if parsed[i] == nil {
+			summary, tokens, techniques, err := c.createSummary(contents[i], contextFile.FileInfo)
+			if err != nil {
+				summary, tokens, techniques = contents[i], originals[i], []string{string(CompressionNone)}
+			}
+			compressedFile.CompressedContent, compressedFile.CompressedTokens = summary, tokens
+			compressedFile.Metadata = map[string]interface{}{"techniques": techniques}
+		} else {
+			content, kept, summarized := c.usageContent(i, parsed)
+			compressedFile.CompressedContent = content
+			compressedFile.CompressedTokens = c.countTokens(content)
+			compressedFile.Metadata = map[string]interface{}{
+				"techniques":         []string{string(CompressionUsage)},
+				"kept_symbols":       kept,
+				"summarized_symbols": summarized,
+			}
+		}
+
+		compressedFile.CompressionRatio = 1.0
+		if originals[i] > 0 {
+			compressedFile.CompressionRatio = float64(compressedFile.CompressedTokens) / float64(originals[i])
+		}
+		validated += c.validateCompressedFile(&compressedFile, contents[i], contextFile.FileInfo.Language, CompressionUsage) * float64(originals[i])
+		compressed.CompressedFiles = append(compressed.CompressedFiles, compressedFile)
+		totalOriginal += originals[i]
+		totalCompressed += compressedFile.CompressedTokens
+	}
+
+	if totalOriginal > 0 {
+		compressed.CompressionRatio = float64(totalCompressed) / float64(totalOriginal)
+		compressed.TokenReduction = totalOriginal - totalCompressed
+	}
+	compressed.QualityScore = c.estimateQualityImpact(CompressionUsage, compressed.CompressionRatio)
+	if totalOriginal > 0 {
+		compressed.QualityScore *= validated / float64(totalOriginal)
+	}
+	compressed.CompressionTime = time.Since(startTime)
+	return compressed
+}
+
+// usageContent rewrites files[index] with the bodies of functions no other
+// file uses replaced by "{ ... }", returning the kept and summarized
+// function names
+func (c *DefaultContextCompressor) usageContent(index int, files []*usageFile) (string, []string, []string) {
+	file := files[index]
+	kept, summarized := []string{}, []string{}
+
+	var result strings.Builder
+	last := 0
+	for _, fn := range file.funcs {
+		if fn.lines <= c.config.MinFunctionLines || usedElsewhere(index, fn, files) {
+			kept = append(kept, fn.name)
+			continue
+		}
+		summarized = append(summarized, fn.name)
+		result.WriteString(file.source[last:fn.start])
+		// The doc comment goes with the body; the signature stays
+		signature := file.source[fn.decl:fn.bodyFrom]
+		result.WriteString(strings.TrimRight(signature, " \t") + " { ... }")
+		last = fn.end
+	}
+	result.WriteString(file.source[last:])
+
+	sort.Strings(kept)
+	sort.Strings(summarized)
+	return result.String(), kept, summarized
+}
+
+// usedElsewhere reports whether any other file that can see fn refers to it
+func usedElsewhere(index int, fn usageFunc, files []*usageFile) bool {
+	owner := files[index]
+	for i, other := range files {
+		if i == index || other == nil {
+			continue
+		}
+
+		if other.dir == owner.dir && other.pkgName == owner.pkgName {
+			if fn.method != "" && other.selectors[fn.method] || fn.method == "" && other.idents[fn.name] {
+				return true
+			}
+			continue
+		}
+
+		for _, imp := range other.imports {
+			if path.Base(imp.path) != filepath.Base(owner.dir) {
+				continue
+			}
+			switch {
+			case fn.method != "":
+				// Methods are selected from values, whose package can't be
+				// told without type checking
+				if other.selectors[fn.method] {
+					return true
+				}
+			case imp.name == ".":
+				if other.idents[fn.name] {
+					return true
+				}
+			default:
+				local := imp.name
+				if local == "" {
+					local = owner.pkgName
+				}
+				if other.qualified[local][fn.name] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// parseUsageFile collects a Go file's functions and the names it uses, or
+// returns nil when it doesn't parse
+func parseUsageFile(filePath, source string) *usageFile {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, filePath, source, parser.ParseComments)
+	if err != nil {
+		return nil
+	}
+
+	file := &usageFile{
+		dir:       filepath.Dir(filePath),
+		pkgName:   parsed.Name.Name,
+		source:    source,
+		idents:    make(map[string]bool),
+		selectors: make(map[string]bool),
+		qualified: make(map[string]map[string]bool),
+	}
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+
+	for _, imp := range parsed.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		usage := usageImport{path: importPath}
+		if imp.Name != nil {
+			usage.name = imp.Name.Name
+		}
+		file.imports = append(file.imports, usage)
+	}
+
+	for _, decl := range parsed.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Body == nil {
+			continue
+		}
+		fn := usageFunc{
+			name:     funcDecl.Name.Name,
+			start:    offset(funcDecl.Pos()),
+			decl:     offset(funcDecl.Pos()),
+			bodyFrom: offset(funcDecl.Body.Lbrace),
+			end:      offset(funcDecl.End()),
+			lines:    fset.Position(funcDecl.End()).Line - fset.Position(funcDecl.Body.Lbrace).Line - 1,
+		}
+		if funcDecl.Doc != nil {
+			fn.start = offset(funcDecl.Doc.Pos())
+		}
+		if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
+			fn.method = fn.name
+			fn.name = receiverTypeName(funcDecl.Recv.List[0].Type) + "." + fn.name
+		}
+		file.funcs = append(file.funcs, fn)
+	}
+
+	// Selected names are kept apart from plain identifiers, so x.Foo doesn't
+	// count as a use of a function Foo in the same package
+	var visit func(node ast.Node) bool
+	visit = func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.Ident:
+			file.idents[n.Name] = true
+		case *ast.SelectorExpr:
+			file.selectors[n.Sel.Name] = true
+			if x, ok := n.X.(*ast.Ident); ok {
+				if file.qualified[x.Name] == nil {
+					file.qualified[x.Name] = make(map[string]bool)
+				}
+				file.qualified[x.Name][n.Sel.Name] = true
+			}
+			ast.Inspect(n.X, visit)
+			return false
+		}
+		return true
+	}
+	ast.Inspect(parsed, visit)
+	return file
+}
+
+// receiverTypeName names a method receiver's type without pointer or type
+// parameters
+func receiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
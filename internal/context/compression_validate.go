@@ -0,0 +1,196 @@
+package context
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"sort"
+)
+
+// unparseableSourcePenalty scales the quality of output claimed to be
+// complete source that no longer parses, on top of any lost declarations
+const unparseableSourcePenalty = 0.5
+
+// compressionClaims lists what a compression strategy promises to keep of a
+// Go file
+type compressionClaims struct {
+	imports bool
+	types   bool // Exported types
+	funcs   bool // Signatures of exported functions and methods
+	source  bool // The whole output is still valid Go
+}
+
+// goCompressionClaims returns the claims of strategy, or false for
+// strategies whose output isn't checked: none leaves files as they are and
+// window keeps excerpts that promise no declarations
+func (c *DefaultContextCompressor) goCompressionClaims(strategy CompressionStrategy) (compressionClaims, bool) {
+	switch strategy {
+	case CompressionMinify:
+		return compressionClaims{imports: true, types: true, funcs: true, source: true}, true
+	case CompressionSummary, CompressionSemantic, CompressionUsage:
+		return compressionClaims{imports: true, types: true, funcs: true}, true
+	case CompressionSnippet:
+		return compressionClaims{imports: c.config.PreserveImports, funcs: true}, true
+	}
+	return compressionClaims{}, false
+}
+
+// validateCompressedFile measures how much of its original a compressed file
+// kept and returns the factor, from 0 to 1, by which the file's estimated
+// quality is scaled. The file's metadata records the measured "quality",
+// which the estimate alone is unless the file could be checked.
+func (c *DefaultContextCompressor) validateCompressedFile(file *CompressedFile, original, language string, strategy CompressionStrategy) float64 {
+	if file.Metadata == nil {
+		file.Metadata = make(map[string]interface{})
+	}
+	factor := c.declarationFactor(file, original, language, strategy)
+	file.Metadata["quality"] = c.estimateQualityImpact(strategy, file.CompressionRatio) * factor
+	return factor
+}
+
+// declarationFactor parses a compressed Go file and checks that the
+// declarations its strategy claims to keep are intact, recording the result
+// in the file's metadata, which must exist. Checks are cached like
+// compressions. The factor is the share of claimed declarations
+// that survived, penalized further when output meant to be complete source
+// doesn't parse. Files that aren't Go, strategies that claim nothing, and
+// originals that don't parse themselves are skipped with a factor of 1.
+func (c *DefaultContextCompressor) declarationFactor(file *CompressedFile, original, language string, strategy CompressionStrategy) float64 {
+	if language != "go" {
+		return 1.0
+	}
+	claims, checked := c.goCompressionClaims(strategy)
+	if !checked {
+		return 1.0
+	}
+
+	key := declarationKey(original, file.CompressedContent, claims)
+	check, cached := c.cache.get(key)
+	if !cached {
+		check = checkDeclarations(original, file.CompressedContent, claims)
+		c.cache.put(key, check)
+	}
+	result := check.(declarationCheck)
+	if result.skipped {
+		return 1.0
+	}
+
+	file.Metadata["declarations_claimed"] = result.claimed
+	file.Metadata["declarations_missing"] = append([]string{}, result.missing...)
+	file.Metadata["parse_errors"] = result.parseErrors
+	return result.factor
+}
+
+// declarationCheck is the outcome of checking a compressed Go file's
+// declarations against its original
+type declarationCheck struct {
+	skipped     bool // The original doesn't parse or there was nothing to check
+	factor      float64
+	claimed     int
+	missing     []string // Sorted
+	parseErrors int
+}
+
+// checkDeclarations checks that the declarations claims promises survived
+// compressing original into compressed
+func checkDeclarations(original, compressed string, claims compressionClaims) declarationCheck {
+	expected, _, err := goDeclarations(original, claims, false)
+	if err != nil || len(expected) == 0 && !claims.source {
+		return declarationCheck{skipped: true}
+	}
+
+	source := compressed
+	// Fragments like snippets may leave out the package clause, which isn't
+	// one of the claimed declarations
+	if _, err := parser.ParseFile(token.NewFileSet(), "", source, parser.PackageClauseOnly); err != nil {
+		source = "package p\n" + source
+	}
+	intact, parseErrors, _ := goDeclarations(source, claims, true)
+
+	missing := []string{}
+	for name := range expected {
+		if !intact[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+
+	factor := 1.0
+	if len(expected) > 0 {
+		factor = float64(len(expected)-len(missing)) / float64(len(expected))
+	}
+	if claims.source && parseErrors > 0 {
+		factor *= unparseableSourcePenalty
+	}
+
+	return declarationCheck{factor: factor, claimed: len(expected), missing: missing, parseErrors: parseErrors}
+}
+
+// fileQuality returns the quality validation recorded for a compressed file,
+// or estimate when it has none
+func fileQuality(file CompressedFile, estimate float64) float64 {
+	if quality, ok := file.Metadata["quality"].(float64); ok {
+		return quality
+	}
+	return estimate
+}
+
+// goDeclarations parses Go source and returns the claimed declarations it
+// contains, keyed like `import "fmt"`, "type Name", "func Name" and
+// "func Type.Method", along with the number of parse errors. Unless partial
+// is set, source that fails to parse is an error; otherwise declarations are
+// read from what the parser recovered, and one counts only when no error
+// falls within it. A function's body isn't part of it, so elided bodies
+// don't cost the signature.
+func goDeclarations(source string, claims compressionClaims, partial bool) (map[string]bool, int, error) {
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, "", source, parser.AllErrors|parser.SkipObjectResolution)
+	errorList, _ := err.(scanner.ErrorList)
+	if err != nil && (!partial || errorList == nil || parsed == nil) {
+		return nil, len(errorList), err
+	}
+
+	offsets := make([]int, 0, len(errorList))
+	for _, parseError := range errorList {
+		offsets = append(offsets, parseError.Pos.Offset)
+	}
+	intact := func(from, to token.Pos) bool {
+		start, end := fset.Position(from).Offset, fset.Position(to).Offset
+		for _, offset := range offsets {
+			if offset >= start && offset < end {
+				return false
+			}
+		}
+		return true
+	}
+
+	declarations := make(map[string]bool)
+	for _, decl := range parsed.Decls {
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.ImportSpec:
+					if claims.imports && s.Path != nil && intact(s.Pos(), s.End()) {
+						declarations["import "+s.Path.Value] = true
+					}
+				case *ast.TypeSpec:
+					if claims.types && s.Name.IsExported() && intact(s.Pos(), s.End()) {
+						declarations["type "+s.Name.Name] = true
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if !claims.funcs || !d.Name.IsExported() || !intact(d.Pos(), d.Type.End()) {
+				continue
+			}
+			name := d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name = receiverTypeName(d.Recv.List[0].Type) + "." + name
+			}
+			declarations["func "+name] = true
+		}
+	}
+	return declarations, len(errorList), nil
+}
@@ -0,0 +1,325 @@
+package context
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultWindowMaxTokens caps each file compressed with the window strategy
+const defaultWindowMaxTokens = 800
+
+// LineRange is an inclusive, 1-based range of lines kept from a file
+type LineRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// windowRegion is a candidate range of lines, 0-based and inclusive, ranked by
+// how many keyword hits it contains
+type windowRegion struct {
+	start, end int
+	hits       int
+}
+
+// compressWindow keeps, for each file, the functions and blocks that mention
+// the task's keywords, up to WindowMaxTokens per file. Kept line ranges are
+// recorded in each compressed file's "line_ranges" metadata.
+func (c *DefaultContextCompressor) compressWindow(selection *SelectedContext, startTime time.Time) *CompressedContext {
+	keywords := windowKeywords(selection.Task)
+	compressed := &CompressedContext{
+		Original:         selection,
+		CompressedFiles:  make([]CompressedFile, 0, len(selection.Files)),
+		CompressionRatio: 1.0,
+		Strategy:         CompressionWindow,
+		QualityScore:     1.0,
+	}
+
+	totalOriginal, totalCompressed := 0, 0
+	for _, contextFile := range selection.Files {
+		content, originalTokens := c.fileContent(contextFile)
+		result, tokens, ranges := c.windowFile(content, contextFile.FileInfo, keywords, c.windowMaxTokens())
+
+		compressedFile := CompressedFile{
+			OriginalPath:      contextFile.FileInfo.Path,
+			CompressedContent: result,
+			OriginalTokens:    originalTokens,
+			CompressedTokens:  tokens,
+			CompressionRatio:  1.0,
+			Method:            string(CompressionWindow),
+			Metadata:          map[string]interface{}{"line_ranges": ranges, "techniques": []string{string(CompressionWindow)}},
+		}
+		if originalTokens > 0 {
+			compressedFile.CompressionRatio = float64(tokens) / float64(originalTokens)
+		}
+		compressed.CompressedFiles = append(compressed.CompressedFiles, compressedFile)
+		totalOriginal += originalTokens
+		totalCompressed += tokens
+	}
+
+	if totalOriginal > 0 {
+		compressed.CompressionRatio = float64(totalCompressed) / float64(totalOriginal)
+		compressed.TokenReduction = totalOriginal - totalCompressed
+	}
+	compressed.QualityScore = c.estimateQualityImpact(CompressionWindow, compressed.CompressionRatio)
+	compressed.CompressionTime = time.Since(startTime)
+	return compressed
+}
+
+// windowFile keeps the regions of content with the most keyword hits within
+// maxTokens, marking omitted lines. Files with no hits keep their head.
+func (c *DefaultContextCompressor) windowFile(content string, fileInfo *FileInfo, keywords []string, maxTokens int) (string, int, []LineRange) {
+	lines := strings.Split(content, "\n")
+	if tokens := c.countTokens(content); tokens <= maxTokens {
+		return content, tokens, []LineRange{{Start: 1, End: len(lines)}}
+	}
+
+	lineTokens := make([]int, len(lines))
+	for i, line := range lines {
+		lineTokens[i] = c.countTokens(line)
+	}
+	regionTokens := func(region windowRegion) int {
+		total := 0
+		for i := region.start; i <= region.end; i++ {
+			total += lineTokens[i]
+		}
+		return total
+	}
+
+	// Take the regions with the most hits first; a block too large for what's
+	// left contributes just the lines around its hits
+	remaining := maxTokens
+	var kept []windowRegion
+	for _, region := range c.windowRegions(lines, fileInfo.Language, keywords) {
+		candidates := []windowRegion{region}
+		if regionTokens(region) > remaining {
+			candidates = hitWindows(lines, region, keywords, c.config.SnippetContextLines)
+		}
+		for _, candidate := range candidates {
+			if tokens := regionTokens(candidate); tokens <= remaining {
+				kept = append(kept, candidate)
+				remaining -= tokens
+			}
+		}
+	}
+
+	// Without hits the beginning of the file is the best guess
+	if len(kept) == 0 {
+		head := windowRegion{start: 0, end: -1}
+		for head.end+1 < len(lines) && lineTokens[head.end+1] <= remaining {
+			head.end++
+			remaining -= lineTokens[head.end]
+		}
+		if head.end >= 0 {
+			kept = append(kept, head)
+		}
+	}
+
+	// Omission markers cost tokens too, so drop the lowest-ranked regions
+	// until the rendered result fits
+	for {
+		ranges := mergeLineRanges(kept)
+		result := renderWindows(lines, ranges, fileInfo.Language)
+		tokens := c.countTokens(result)
+		if tokens <= maxTokens || len(kept) <= 1 {
+			return result, tokens, ranges
+		}
+		kept = kept[:len(kept)-1]
+	}
+}
+
+// windowRegions splits lines into blocks, each function with its leading
+// comments and the code before the first function, and returns those with
+// keyword hits, most hits first. Files without recognizable functions yield
+// the lines around each hit instead.
+func (c *DefaultContextCompressor) windowRegions(lines []string, language string, keywords []string) []windowRegion {
+	var starts []int
+	for i, line := range lines {
+		if c.isFunctionStart(line, language) {
+			// Doc comments belong to the function they describe
+			start := i
+			for start > 0 && isCommentLine(lines[start-1]) {
+				start--
+			}
+			starts = append(starts, start)
+		}
+	}
+
+	var regions []windowRegion
+	if len(starts) == 0 {
+		regions = hitWindows(lines, windowRegion{start: 0, end: len(lines) - 1}, keywords, c.config.SnippetContextLines)
+	} else {
+		if starts[0] > 0 {
+			regions = append(regions, hitWindows(lines, windowRegion{start: 0, end: starts[0] - 1}, keywords, c.config.SnippetContextLines)...)
+		}
+		for i, start := range starts {
+			end := len(lines) - 1
+			if i+1 < len(starts) {
+				end = starts[i+1] - 1
+			}
+			for end > start && strings.TrimSpace(lines[end]) == "" {
+				end--
+			}
+			block := windowRegion{start: start, end: end}
+			if block.hits = countHits(lines[start:end+1], keywords); block.hits > 0 {
+				regions = append(regions, block)
+			}
+		}
+	}
+
+	sort.SliceStable(regions, func(i, j int) bool {
+		return regions[i].hits > regions[j].hits
+	})
+	return regions
+}
+
+// hitWindows returns the lines around each keyword hit within region,
+// overlapping windows merged
+func hitWindows(lines []string, region windowRegion, keywords []string, context int) []windowRegion {
+	var windows []windowRegion
+	for i := region.start; i <= region.end; i++ {
+		hits := countHits(lines[i:i+1], keywords)
+		if hits == 0 {
+			continue
+		}
+		window := windowRegion{start: i - context, end: i + context, hits: hits}
+		if window.start < region.start {
+			window.start = region.start
+		}
+		if window.end > region.end {
+			window.end = region.end
+		}
+		if last := len(windows) - 1; last >= 0 && window.start <= windows[last].end+1 {
+			windows[last].end = window.end
+			windows[last].hits += hits
+			continue
+		}
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+// countHits counts keyword occurrences in lines, ignoring case
+func countHits(lines []string, keywords []string) int {
+	hits := 0
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		for _, keyword := range keywords {
+			hits += strings.Count(lower, keyword)
+		}
+	}
+	return hits
+}
+
+// mergeLineRanges sorts regions and merges overlapping or adjacent ones into
+// 1-based line ranges
+func mergeLineRanges(regions []windowRegion) []LineRange {
+	sorted := make([]windowRegion, len(regions))
+	copy(sorted, regions)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start < sorted[j].start })
+
+	var ranges []LineRange
+	for _, region := range sorted {
+		if last := len(ranges) - 1; last >= 0 && region.start+1 <= ranges[last].End+1 {
+			if region.end+1 > ranges[last].End {
+				ranges[last].End = region.end + 1
+			}
+			continue
+		}
+		ranges = append(ranges, LineRange{Start: region.start + 1, End: region.end + 1})
+	}
+	return ranges
+}
+
+// renderWindows joins the kept ranges with markers for the omitted lines
+func renderWindows(lines []string, ranges []LineRange, language string) string {
+	comment := "//"
+	switch language {
+	case "python", "shell", "yaml", "ruby", "perl", "makefile", "dockerfile":
+		comment = "#"
+	}
+	omitted := func(from, to int) string {
+		return fmt.Sprintf("%s ... lines %d-%d omitted ...\n", comment, from, to)
+	}
+
+	var result strings.Builder
+	next := 1
+	for _, r := range ranges {
+		if r.Start > next {
+			result.WriteString(omitted(next, r.Start-1))
+		}
+		result.WriteString(strings.Join(lines[r.Start-1:r.End], "\n"))
+		result.WriteString("\n")
+		next = r.End + 1
+	}
+	if next <= len(lines) {
+		result.WriteString(omitted(next, len(lines)))
+	}
+	return result.String()
+}
+
+// windowKeywords returns the task's explicit keywords and those in its description
+func windowKeywords(task *Task) []string {
+	if task == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var keywords []string
+	for _, keyword := range append(task.Keywords, NewSemanticRelevanceScorer(nil).extractKeywords(task.Description)...) {
+		keyword = strings.ToLower(keyword)
+		if keyword != "" && !seen[keyword] {
+			seen[keyword] = true
+			keywords = append(keywords, keyword)
+		}
+	}
+	return keywords
+}
+
+// isCommentLine reports whether a line holds only a comment
+func isCommentLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "#") ||
+		strings.HasPrefix(trimmed, "/*") || strings.HasPrefix(trimmed, "*")
+}
+
+// estimateWindow returns the ratio from capping each file at WindowMaxTokens
+func (c *DefaultContextCompressor) estimateWindow(selection *SelectedContext) float64 {
+	if selection == nil {
+		return 1.0
+	}
+	original, capped := 0, 0
+	for _, file := range selection.Files {
+		if file.FileInfo == nil {
+			continue
+		}
+		original += file.FileInfo.TokenCount
+		if file.FileInfo.TokenCount > c.windowMaxTokens() {
+			capped += c.windowMaxTokens()
+		} else {
+			capped += file.FileInfo.TokenCount
+		}
+	}
+	if original == 0 {
+		return 1.0
+	}
+	return float64(capped) / float64(original)
+}
+
+// windowMaxTokens returns the per-file cap for the window strategy
+func (c *DefaultContextCompressor) windowMaxTokens() int {
+	if c.config.WindowMaxTokens > 0 {
+		return c.config.WindowMaxTokens
+	}
+	return defaultWindowMaxTokens
+}
+
+// countTokens counts tokens with the configured counter, or approximates
+// them by words when there is none
+func (c *DefaultContextCompressor) countTokens(content string) int {
+	if c.tokenCounter == nil {
+		return len(strings.Fields(content))
+	}
+	tokens, _ := c.tokenCounter.CountTokens(content)
+	return tokens
+}
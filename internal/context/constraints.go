@@ -0,0 +1,98 @@
+package context
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Validate reports every out-of-range or contradictory value in the
+// constraints, so a caller learns why a selection would come back empty or
+// skewed instead of getting one. Empty Strategy and PackingMode fall back to
+// the balanced strategy and greedy packing, and are valid.
+func (c *ContextConstraints) Validate() error {
+	var errs []error
+	if c.MaxTokens <= 0 {
+		errs = append(errs, fmt.Errorf("max_tokens must be positive, got %d", c.MaxTokens))
+	}
+	if c.MaxFiles <= 0 {
+		errs = append(errs, fmt.Errorf("max_files must be positive, got %d", c.MaxFiles))
+	}
+	if c.DependencyDepth < 0 {
+		errs = append(errs, fmt.Errorf("dependency_depth must not be negative, got %d", c.DependencyDepth))
+	}
+
+	weights := []struct {
+		name  string
+		value float64
+	}{
+		{"min_relevance_score", c.MinRelevanceScore},
+		{"freshness_bias", c.FreshnessBias},
+		{"churn_bias", c.ChurnBias},
+		{"diversity_weight", c.DiversityWeight},
+	}
+	for _, weight := range weights {
+		if math.IsNaN(weight.value) || weight.value < 0 || weight.value > 1 {
+			errs = append(errs, fmt.Errorf("%s must be between 0 and 1, got %v", weight.name, weight.value))
+		}
+	}
+
+	switch c.Strategy {
+	case "", StrategyRelevance, StrategyDependency, StrategyFreshness, StrategyCompactness, StrategyBalanced:
+	default:
+		errs = append(errs, fmt.Errorf("unknown strategy %q", c.Strategy))
+	}
+	switch c.PackingMode {
+	case "", PackingGreedy, PackingKnapsack, PackingAllocate:
+	default:
+		errs = append(errs, fmt.Errorf("unknown packing_mode %q", c.PackingMode))
+	}
+	if c.BalancedWeights != nil {
+		if err := c.BalancedWeights.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Preferring a file type that is filtered out can never take effect
+	for _, fileType := range c.PreferredTypes {
+		if fileType == "test" && !c.IncludeTests {
+			errs = append(errs, fmt.Errorf("preferred_types includes %q but include_tests is false", fileType))
+		}
+		if fileType == "documentation" && !c.IncludeDocs {
+			errs = append(errs, fmt.Errorf("preferred_types includes %q but include_docs is false", fileType))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Normalized returns a copy of the constraints with the forgiving cases
+// corrected: weights are clamped to [0, 1] and a negative dependency depth
+// becomes 0, and balanced weights are rescaled to sum to 1. Values that can't
+// be guessed, like a zero budget, are left for Validate to reject.
+func (c *ContextConstraints) Normalized() *ContextConstraints {
+	normalized := *c
+	normalized.MinRelevanceScore = clampUnit(c.MinRelevanceScore)
+	normalized.FreshnessBias = clampUnit(c.FreshnessBias)
+	normalized.ChurnBias = clampUnit(c.ChurnBias)
+	normalized.DiversityWeight = clampUnit(c.DiversityWeight)
+	if normalized.DependencyDepth < 0 {
+		normalized.DependencyDepth = 0
+	}
+	if c.BalancedWeights != nil {
+		weights := c.BalancedWeights.Normalized()
+		normalized.BalancedWeights = &weights
+	}
+	return &normalized
+}
+
+// clampUnit clamps value to [0, 1]. NaN isn't clamped, so Validate still
+// rejects it.
+func clampUnit(value float64) float64 {
+	if value < 0 {
+		return 0
+	}
+	if value > 1 {
+		return 1
+	}
+	return value
+}
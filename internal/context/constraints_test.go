@@ -0,0 +1,137 @@
+package context
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+// validConstraints returns constraints that pass validation
+func validConstraints() *ContextConstraints {
+	return &ContextConstraints{
+		MaxTokens:         1000,
+		MaxFiles:          10,
+		MinRelevanceScore: 0.1,
+		FreshnessBias:     0.2,
+		DependencyDepth:   2,
+		Strategy:          StrategyRelevance,
+	}
+}
+
+// TestConstraintsValidate tests that each invalid field, and preferring a
+// file type that is filtered out, is reported by name
+func TestConstraintsValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(c *ContextConstraints)
+		expected []string // Fragments of the error, none when valid
+	}{
+		{name: "valid", modify: func(c *ContextConstraints) {}},
+		{name: "zero max tokens", modify: func(c *ContextConstraints) { c.MaxTokens = 0 }, expected: []string{"max_tokens"}},
+		{name: "zero max files", modify: func(c *ContextConstraints) { c.MaxFiles = 0 }, expected: []string{"max_files"}},
+		{name: "relevance above 1", modify: func(c *ContextConstraints) { c.MinRelevanceScore = 1.5 }, expected: []string{"min_relevance_score"}},
+		{name: "freshness bias above 1", modify: func(c *ContextConstraints) { c.FreshnessBias = 2.0 }, expected: []string{"freshness_bias"}},
+		{name: "negative churn bias", modify: func(c *ContextConstraints) { c.ChurnBias = -0.1 }, expected: []string{"churn_bias"}},
+		{name: "NaN diversity weight", modify: func(c *ContextConstraints) { c.DiversityWeight = math.NaN() }, expected: []string{"diversity_weight"}},
+		{name: "negative dependency depth", modify: func(c *ContextConstraints) { c.DependencyDepth = -1 }, expected: []string{"dependency_depth"}},
+		{name: "unknown strategy", modify: func(c *ContextConstraints) { c.Strategy = "fastest" }, expected: []string{`strategy "fastest"`}},
+		{name: "unknown packing mode", modify: func(c *ContextConstraints) { c.PackingMode = "tight" }, expected: []string{`packing_mode "tight"`}},
+		{
+			name: "balanced weights not summing to 1",
+			modify: func(c *ContextConstraints) {
+				c.BalancedWeights = &BalancedWeights{Relevance: 0.5, Centrality: 0.5, Freshness: 0.5}
+			},
+			expected: []string{"balanced_weights must sum to 1"},
+		},
+		{
+			name: "balanced weight above 1",
+			modify: func(c *ContextConstraints) {
+				c.BalancedWeights = &BalancedWeights{Relevance: 1.5, Size: -0.5}
+			},
+			expected: []string{"balanced_weights.relevance", "balanced_weights.size"},
+		},
+		{
+			name: "balanced weights within tolerance",
+			modify: func(c *ContextConstraints) {
+				c.BalancedWeights = &BalancedWeights{Relevance: 0.333, Centrality: 0.333, Freshness: 0.333}
+			},
+		},
+		{
+			name: "preferred types that are excluded",
+			modify: func(c *ContextConstraints) {
+				c.PreferredTypes = []string{"source", "test", "documentation"}
+			},
+			expected: []string{"include_tests", "include_docs"},
+		},
+		{
+			name: "preferred types that are included",
+			modify: func(c *ContextConstraints) {
+				c.PreferredTypes = []string{"test", "documentation"}
+				c.IncludeTests, c.IncludeDocs = true, true
+			},
+		},
+		{
+			name: "every problem is reported",
+			modify: func(c *ContextConstraints) {
+				c.MaxFiles = 0
+				c.FreshnessBias = 2.0
+			},
+			expected: []string{"max_files", "freshness_bias"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			constraints := validConstraints()
+			tt.modify(constraints)
+			err := constraints.Validate()
+			if len(tt.expected) == 0 {
+				if err != nil {
+					t.Errorf("Validate() = %v, expected no error", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate() = nil, expected an error")
+			}
+			for _, fragment := range tt.expected {
+				if !strings.Contains(err.Error(), fragment) {
+					t.Errorf("Validate() = %q, expected it to mention %q", err, fragment)
+				}
+			}
+		})
+	}
+}
+
+// TestSelectOptimalContextValidatesConstraints tests that invalid constraints
+// are rejected before selection, unless normalization can correct them
+func TestSelectOptimalContextValidatesConstraints(t *testing.T) {
+	project := newTestProject(map[string]int{"a.go": 100, "b.go": 100})
+	task := &Task{Type: TaskTypeFeature}
+	constraints := validConstraints()
+	constraints.FreshnessBias = 2.0
+
+	optimizer := newTestOptimizer(map[string]float64{"a.go": 0.9, "b.go": 0.5})
+	if _, err := optimizer.SelectOptimalContext(context.Background(), project, task, constraints); err == nil || !strings.Contains(err.Error(), "freshness_bias") {
+		t.Errorf("SelectOptimalContext error = %v, expected freshness_bias to be rejected", err)
+	}
+
+	optimizer.config.NormalizeConstraints = true
+	selection, err := optimizer.SelectOptimalContext(context.Background(), project, task, constraints)
+	if err != nil {
+		t.Fatalf("SelectOptimalContext with normalization failed: %v", err)
+	}
+	if selection.Constraints.FreshnessBias != 1.0 {
+		t.Errorf("FreshnessBias = %v, expected it clamped to 1", selection.Constraints.FreshnessBias)
+	}
+	if constraints.FreshnessBias != 2.0 {
+		t.Error("normalization modified the caller's constraints")
+	}
+
+	// A zero budget can't be guessed, so it is rejected either way
+	constraints.MaxTokens = 0
+	if _, err := optimizer.SelectOptimalContext(context.Background(), project, task, constraints); err == nil {
+		t.Error("expected a zero budget to be rejected despite normalization")
+	}
+}
@@ -0,0 +1,71 @@
+package context
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrCostLimitExceeded is returned when a selection's projected input cost is
+// above the configured ceiling and the guard is set to abort
+var ErrCostLimitExceeded = errors.New("selection exceeds cost limit")
+
+// ModelPricing describes what a model charges for input tokens
+type ModelPricing struct {
+	Model                 string  `json:"model"`
+	InputPerMillionTokens float64 `json:"input_per_million_tokens"` // USD
+}
+
+// CostGuardAction defines what happens when a selection would cost too much
+type CostGuardAction string
+
+const (
+	CostGuardAbort  CostGuardAction = "abort"  // Fail the selection with ErrCostLimitExceeded
+	CostGuardShrink CostGuardAction = "shrink" // Lower the token budget until the selection is affordable
+)
+
+// CostGuard caps the projected input cost of each selection
+type CostGuard struct {
+	Pricing ModelPricing    `json:"pricing"`
+	MaxCost float64         `json:"max_cost"` // USD per model call
+	Action  CostGuardAction `json:"action"`
+}
+
+// ProjectedCost returns the input cost of sending tokens to the model
+func (g *CostGuard) ProjectedCost(tokens int) float64 {
+	return float64(tokens) * g.Pricing.InputPerMillionTokens / 1_000_000
+}
+
+// AffordableTokens returns the most tokens that stay within MaxCost
+func (g *CostGuard) AffordableTokens() int {
+	if g.Pricing.InputPerMillionTokens <= 0 {
+		return math.MaxInt
+	}
+	return int(g.MaxCost / g.Pricing.InputPerMillionTokens * 1_000_000)
+}
+
+// shrinkConstraints returns constraints whose token budget is affordable. The
+// original constraints are returned unchanged when they already are.
+func (g *CostGuard) shrinkConstraints(constraints *ContextConstraints) *ContextConstraints {
+	affordable := g.AffordableTokens()
+	if g.Action != CostGuardShrink || constraints.MaxTokens <= affordable {
+		return constraints
+	}
+
+	shrunk := *constraints
+	shrunk.MaxTokens = affordable
+	return &shrunk
+}
+
+// check records the projected cost on the selection and fails it when the
+// cost is over the ceiling
+func (g *CostGuard) check(selection *SelectedContext) error {
+	cost := g.ProjectedCost(selection.TotalTokens)
+	selection.Metadata["projected_cost"] = cost
+
+	if cost > g.MaxCost {
+		return fmt.Errorf("%w: %d tokens cost $%.4f on %s, limit is $%.4f",
+			ErrCostLimitExceeded, selection.TotalTokens, cost, g.Pricing.Model, g.MaxCost)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestCostGuard tests aborting and shrinking selections that cost too much
+func TestCostGuard(t *testing.T) {
+	scores := map[string]float64{"a.go": 0.9, "b.go": 0.8, "c.go": 0.7}
+	project := newTestProject(map[string]int{"a.go": 400, "b.go": 300, "c.go": 300})
+	pricing := ModelPricing{Model: "test-model", InputPerMillionTokens: 3.0}
+
+	tests := []struct {
+		name      string
+		maxCost   float64
+		action    CostGuardAction
+		expectErr bool
+		maxTokens int
+	}{
+		{name: "under limit", maxCost: 0.01, action: CostGuardAbort, maxTokens: 1000},
+		{name: "abort over limit", maxCost: 0.0015, action: CostGuardAbort, expectErr: true},
+		{name: "shrink over limit", maxCost: 0.0015, action: CostGuardShrink, maxTokens: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optimizer := NewDefaultOptimizer(newStubAnalyzer(scores), nil, nil, &OptimizerConfig{
+				DefaultTokenBudget: 8000,
+				MaxSelectionTime:   5 * time.Second,
+				DefaultStrategy:    StrategyRelevance,
+				CostGuard:          &CostGuard{Pricing: pricing, MaxCost: tt.maxCost, Action: tt.action},
+			})
+			constraints := &ContextConstraints{MaxTokens: 1000, MaxFiles: 10, MinRelevanceScore: 0.1, Strategy: StrategyRelevance}
+
+			selection, err := optimizer.SelectOptimalContext(context.Background(), project, &Task{Type: TaskTypeFeature}, constraints)
+			if tt.expectErr {
+				if !errors.Is(err, ErrCostLimitExceeded) {
+					t.Fatalf("err = %v, expected ErrCostLimitExceeded", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectOptimalContext failed: %v", err)
+			}
+
+			if selection.TotalTokens == 0 || selection.TotalTokens > tt.maxTokens {
+				t.Errorf("TotalTokens = %d, expected 1-%d", selection.TotalTokens, tt.maxTokens)
+			}
+			if cost := selection.Metadata["projected_cost"].(float64); cost > tt.maxCost {
+				t.Errorf("projected cost %.4f exceeds %.4f", cost, tt.maxCost)
+			}
+			if constraints.MaxTokens != 1000 {
+				t.Errorf("caller's constraints were modified: MaxTokens = %d", constraints.MaxTokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package context
+
+import (
+	"crypto/sha256"
+	"hash/fnv"
+	"strings"
+)
+
+// dedupShingleSize is the number of consecutive words hashed into one shingle
+const dedupShingleSize = 5
+
+// dedupEntry holds the fingerprint of a representative file
+type dedupEntry struct {
+	index    int
+	shingles map[uint64]struct{}
+}
+
+// deduplicateFiles drops candidates whose content duplicates a higher ranked
+// candidate. Content is compared by hash after whitespace normalization and,
+// when threshold is below 1, by Jaccard similarity of word shingles. The paths
+// of dropped files are recorded in the representative's "duplicates" metadata.
+// Files whose content can't be read are always kept.
+func deduplicateFiles(candidates []ContextFile, threshold float64) []ContextFile {
+	if len(candidates) < 2 {
+		return candidates
+	}
+
+	hashes := make(map[[sha256.Size]byte]int)
+	var representatives []dedupEntry
+	result := make([]ContextFile, 0, len(candidates))
+
+	for _, file := range candidates {
+		content, ok := loadContextFileContent(file)
+		if !ok {
+			result = append(result, file)
+			continue
+		}
+
+		normalized := normalizeForDedup(content)
+		hash := sha256.Sum256([]byte(normalized))
+
+		// Identical content after normalization
+		if idx, exists := hashes[hash]; exists {
+			markDuplicate(&result[idx], file)
+			continue
+		}
+
+		// Near-identical content
+		var shingles map[uint64]struct{}
+		if threshold > 0 && threshold < 1 {
+			shingles = shingleSet(normalized)
+			if idx := findSimilar(representatives, shingles, threshold); idx >= 0 {
+				markDuplicate(&result[idx], file)
+				continue
+			}
+		}
+
+		result = append(result, file)
+		hashes[hash] = len(result) - 1
+		if shingles != nil {
+			representatives = append(representatives, dedupEntry{index: len(result) - 1, shingles: shingles})
+		}
+	}
+
+	return result
+}
+
+// loadContextFileContent returns the loaded content of a file or reads it
+// from the file system it was analyzed from
+func loadContextFileContent(file ContextFile) (string, bool) {
+	if file.Content != "" {
+		return file.Content, true
+	}
+	if file.FileInfo == nil {
+		return "", false
+	}
+
+	data, err := readFileContent(file.FileInfo)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// normalizeForDedup collapses whitespace and drops blank lines so formatting
+// differences don't hide duplicates
+func normalizeForDedup(content string) string {
+	lines := strings.Split(content, "\n")
+	normalized := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			normalized = append(normalized, strings.Join(fields, " "))
+		}
+	}
+	return strings.Join(normalized, "\n")
+}
+
+// shingleSet hashes every run of dedupShingleSize consecutive words
+func shingleSet(content string) map[uint64]struct{} {
+	words := strings.Fields(content)
+	shingles := make(map[uint64]struct{})
+
+	if len(words) < dedupShingleSize {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words, " ")))
+		shingles[h.Sum64()] = struct{}{}
+		return shingles
+	}
+
+	for i := 0; i+dedupShingleSize <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+dedupShingleSize], " ")))
+		shingles[h.Sum64()] = struct{}{}
+	}
+	return shingles
+}
+
+// findSimilar returns the result index of the first representative whose
+// shingle similarity reaches threshold, or -1
+func findSimilar(representatives []dedupEntry, shingles map[uint64]struct{}, threshold float64) int {
+	for _, rep := range representatives {
+		// Jaccard similarity can't exceed the ratio of the set sizes
+		smaller, larger := len(shingles), len(rep.shingles)
+		if smaller > larger {
+			smaller, larger = larger, smaller
+		}
+		if larger == 0 || float64(smaller)/float64(larger) < threshold {
+			continue
+		}
+
+		if jaccardSimilarity(shingles, rep.shingles) >= threshold {
+			return rep.index
+		}
+	}
+	return -1
+}
+
+// jaccardSimilarity returns |a ∩ b| / |a ∪ b|
+func jaccardSimilarity(a, b map[uint64]struct{}) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	intersection := 0
+	for shingle := range a {
+		if _, exists := b[shingle]; exists {
+			intersection++
+		}
+	}
+
+	union := len(a) + len(b) - intersection
+	if union == 0 {
+		return 1.0
+	}
+	return float64(intersection) / float64(union)
+}
+
+// markDuplicate records duplicate as represented by representative
+func markDuplicate(representative *ContextFile, duplicate ContextFile) {
+	if representative.Metadata == nil {
+		representative.Metadata = make(map[string]interface{})
+	}
+	duplicates, _ := representative.Metadata["duplicates"].([]string)
+	representative.Metadata["duplicates"] = append(duplicates, duplicate.FileInfo.Path)
+}
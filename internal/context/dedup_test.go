@@ -0,0 +1,109 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSelectionDeduplicatesIdenticalFiles tests that duplicate content only consumes budget once
+func TestSelectionDeduplicatesIdenticalFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "dedup_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	generated := "package api\n\ntype User struct {\n\tName string\n\tEmail string\n}\n"
+	files := map[string]string{
+		"api/user.pb.go":    generated,
+		"api/user_v2.pb.go": strings.ReplaceAll(generated, "\t", "    ") + "\n\n", // Same code, different formatting
+		"service.go":        "package service\n\nfunc Register(name string) error {\n\treturn nil\n}\n",
+	}
+
+	project := &ProjectContext{RootPath: tempDir, CreatedAt: time.Now()}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		project.Files = append(project.Files, FileInfo{Path: path, TokenCount: 100, FileType: "source"})
+	}
+
+	scores := map[string]float64{
+		filepath.Join(tempDir, "api/user.pb.go"):    0.9,
+		filepath.Join(tempDir, "api/user_v2.pb.go"): 0.8,
+		filepath.Join(tempDir, "service.go"):        0.5,
+	}
+	constraints := &ContextConstraints{
+		MaxTokens:         200, // Room for two files
+		MaxFiles:          10,
+		MinRelevanceScore: 0.1,
+		Strategy:          StrategyRelevance,
+	}
+	task := &Task{Type: TaskTypeFeature, Description: "register users"}
+
+	optimizer := NewDefaultOptimizer(newStubAnalyzer(scores), nil, nil, &OptimizerConfig{
+		DefaultStrategy:          StrategyRelevance,
+		EnableDeduplication:      true,
+		DedupSimilarityThreshold: 0.9,
+	})
+
+	selection, err := optimizer.SelectOptimalContext(context.Background(), project, task, constraints)
+	if err != nil {
+		t.Fatalf("SelectOptimalContext failed: %v", err)
+	}
+
+	paths := selectedPaths(selection)
+	if len(paths) != 2 || paths[0] != filepath.Join(tempDir, "api/user.pb.go") || paths[1] != filepath.Join(tempDir, "service.go") {
+		t.Fatalf("selected %v, expected the representative and service.go", paths)
+	}
+
+	duplicates, _ := selection.Files[0].Metadata["duplicates"].([]string)
+	if len(duplicates) != 1 || duplicates[0] != filepath.Join(tempDir, "api/user_v2.pb.go") {
+		t.Errorf("duplicates metadata = %v, expected user_v2.pb.go", selection.Files[0].Metadata["duplicates"])
+	}
+}
+
+// TestDeduplicateFilesThreshold tests near-duplicate detection against the configured threshold
+func TestDeduplicateFilesThreshold(t *testing.T) {
+	var lines []string
+	for i := 0; i < 40; i++ {
+		lines = append(lines, fmt.Sprintf("ALTER TABLE users ADD COLUMN field_%d TEXT NOT NULL;", i))
+	}
+	migration := strings.Join(lines, "\n")
+	nearCopy := strings.Join(append(lines[:39], "ALTER TABLE users DROP COLUMN legacy;"), "\n")
+
+	candidates := func() []ContextFile {
+		return []ContextFile{
+			{FileInfo: &FileInfo{Path: "001_users.sql"}, Content: migration},
+			{FileInfo: &FileInfo{Path: "002_users.sql"}, Content: nearCopy},
+			{FileInfo: &FileInfo{Path: "c.go"}, Content: "package other\n\nconst Version = 1\n"},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		expected  int
+	}{
+		{name: "Near copy collapsed at 0.9", threshold: 0.9, expected: 2},
+		{name: "Identical only keeps near copy", threshold: 1.0, expected: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := deduplicateFiles(candidates(), tt.threshold)
+			if len(result) != tt.expected {
+				t.Errorf("deduplicateFiles kept %d files, expected %d", len(result), tt.expected)
+			}
+		})
+	}
+}
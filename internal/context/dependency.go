@@ -2,12 +2,12 @@ package context
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
-	"os"
 	"path/filepath"
 	"strings"
 )
@@ -31,6 +31,14 @@ type DependencyAnalyzer interface {
 type GoDependencyAnalyzer struct {
 	projectRoot string
 	moduleInfo  *GoModuleInfo
+	modules     []goModuleRoot // Modules imports resolve against; the root's own by default
+	files       *projectFiles  // Where sources and go.mod files are read from; the local disk when nil
+}
+
+// goModuleRoot is a Go module and the directory holding its go.mod
+type goModuleRoot struct {
+	info *GoModuleInfo
+	dir  string
 }
 
 // GoModuleInfo contains Go module information
@@ -41,13 +49,40 @@ type GoModuleInfo struct {
 
 // NewGoDependencyAnalyzer creates a new Go dependency analyzer
 func NewGoDependencyAnalyzer(projectRoot string) *GoDependencyAnalyzer {
+	return newGoDependencyAnalyzer(projectRoot, nil, nil)
+}
+
+// NewGoWorkspaceDependencyAnalyzer creates a Go dependency analyzer for a
+// workspace of several module roots under workspaceRoot, like a go.work
+// file. Imports resolve against every root's module, so a package in one
+// root can depend on a package in another.
+func NewGoWorkspaceDependencyAnalyzer(workspaceRoot string, roots []string) *GoDependencyAnalyzer {
+	return newGoDependencyAnalyzer(workspaceRoot, roots, nil)
+}
+
+// newGoDependencyAnalyzer creates a Go dependency analyzer resolving imports
+// against the modules of projectRoot and of each of roots, reading files
+// through files
+func newGoDependencyAnalyzer(projectRoot string, roots []string, files *projectFiles) *GoDependencyAnalyzer {
 	analyzer := &GoDependencyAnalyzer{
 		projectRoot: projectRoot,
+		files:       files,
 	}
 	
 	// Try to load module info
 	analyzer.moduleInfo = analyzer.loadModuleInfo()
+	if analyzer.moduleInfo != nil {
+		analyzer.modules = []goModuleRoot{{info: analyzer.moduleInfo, dir: projectRoot}}
+	}
 	
+	for _, root := range roots {
+		if root == projectRoot {
+			continue
+		}
+		if info := loadGoModuleInfo(files, root); info != nil {
+			analyzer.modules = append(analyzer.modules, goModuleRoot{info: info, dir: root})
+		}
+	}
 	return analyzer
 }
 
@@ -59,19 +94,9 @@ func (a *GoDependencyAnalyzer) AnalyzeDependencies(ctx context.Context, files []
 	}
 	
 	// First pass: Create nodes for all Go files
-	goFiles := []FileInfo{}
-	for _, file := range files {
-		if file.Language == "go" && !strings.Contains(file.Path, "_test.go") {
-			relPath, _ := filepath.Rel(a.projectRoot, file.Path)
-			graph.Nodes[relPath] = &DependencyNode{
-				Path:         relPath,
-				Imports:      []string{},
-				Exports:      []string{},
-				Dependencies: []string{},
-				Dependents:   []string{},
-			}
-			goFiles = append(goFiles, file)
-		}
+	goFiles := a.graphFiles(files)
+	for _, file := range goFiles {
+		graph.addNode(dependencyNodeKey(a.projectRoot, file.Path))
 	}
 	
 	// Second pass: Analyze imports and build edges
@@ -82,39 +107,61 @@ func (a *GoDependencyAnalyzer) AnalyzeDependencies(ctx context.Context, files []
 		default:
 		}
 		
-		imports, exports, err := a.analyzeGoFile(file.Path)
-		if err != nil {
-			continue // Skip files with parse errors
+		a.linkFile(graph, file, goFiles)
+	}
+	
+	return graph, nil
+}
+
+// graphFiles returns the files that get nodes: Go sources other than tests
+func (a *GoDependencyAnalyzer) graphFiles(files []FileInfo) []FileInfo {
+	goFiles := []FileInfo{}
+	for _, file := range files {
+		if file.Language == "go" && !strings.Contains(file.Path, "_test.go") {
+			goFiles = append(goFiles, file)
+		}
+	}
+	return goFiles
+}
+
+// linkFile parses a Go file and points its node at the files of the local
+// packages it imports. Files that don't parse are left without edges.
+func (a *GoDependencyAnalyzer) linkFile(graph *DependencyGraph, file FileInfo, goFiles []FileInfo) {
+	relPath := dependencyNodeKey(a.projectRoot, file.Path)
+	imports, exports, err := a.analyzeGoFile(file.Path)
+	if err != nil {
+		node := graph.UpdateFile(relPath, nil)
+		node.Imports, node.Exports, node.ExternalImports = []string{}, []string{}, nil
+		return
+	}
+	
+	// Map imports to local files; a package import depends on every file in the package
+	var dependencies, external []string
+	for _, imp := range imports {
+		depFiles := a.resolveImportToFiles(imp, goFiles)
+		if len(depFiles) == 0 {
+			external = append(external, imp)
+			continue
 		}
 		
-		relPath, _ := filepath.Rel(a.projectRoot, file.Path)
-		node := graph.Nodes[relPath]
-		node.Imports = imports
-		node.Exports = exports
-		
-		// Map imports to local files
-		for _, imp := range imports {
-			if depFile := a.resolveImportToFile(imp, goFiles); depFile != "" {
-				depRelPath, _ := filepath.Rel(a.projectRoot, depFile)
-				
-				// Update dependency relationships
-				node.Dependencies = append(node.Dependencies, depRelPath)
-				if depNode, exists := graph.Nodes[depRelPath]; exists {
-					depNode.Dependents = append(depNode.Dependents, relPath)
-				}
-				
-				// Create edge
-				graph.Edges = append(graph.Edges, DependencyEdge{
-					From:     relPath,
-					To:       depRelPath,
-					Type:     "import",
-					Strength: 1.0, // Direct import has full strength
-				})
-			}
+		for _, depFile := range depFiles {
+			dependencies = append(dependencies, dependencyNodeKey(a.projectRoot, depFile))
 		}
 	}
 	
-	return graph, nil
+	node := graph.UpdateFile(relPath, dependencies)
+	node.Imports, node.Exports, node.ExternalImports = imports, exports, external
+}
+
+// mayResolve re-resolves a node's unresolved imports, which is cheap next to
+// parsing the file again
+func (a *GoDependencyAnalyzer) mayResolve(node *DependencyNode, goFiles []FileInfo) bool {
+	for _, imp := range node.ExternalImports {
+		if len(a.resolveImportToFiles(imp, goFiles)) > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // GetFileDependencies returns direct dependencies for a single file
@@ -137,9 +184,7 @@ func (a *GoDependencyAnalyzer) GetFileDependencies(ctx context.Context, filePath
 
 // GetDependents returns files that depend on the given file
 func (a *GoDependencyAnalyzer) GetDependents(graph *DependencyGraph, filePath string) []string {
-	relPath, _ := filepath.Rel(a.projectRoot, filePath)
-	
-	if node, exists := graph.Nodes[relPath]; exists {
+	if node, exists := graph.Nodes[dependencyNodeKey(a.projectRoot, filePath)]; exists {
 		return node.Dependents
 	}
 	
@@ -148,9 +193,7 @@ func (a *GoDependencyAnalyzer) GetDependents(graph *DependencyGraph, filePath st
 
 // CalculateCentrality calculates the importance of a file in the dependency graph
 func (a *GoDependencyAnalyzer) CalculateCentrality(graph *DependencyGraph, filePath string) float64 {
-	relPath, _ := filepath.Rel(a.projectRoot, filePath)
-	
-	node, exists := graph.Nodes[relPath]
+	node, exists := graph.Nodes[dependencyNodeKey(a.projectRoot, filePath)]
 	if !exists {
 		return 0.0
 	}
@@ -176,7 +219,7 @@ func (a *GoDependencyAnalyzer) CalculateCentrality(graph *DependencyGraph, fileP
 func (a *GoDependencyAnalyzer) analyzeGoFile(filePath string) (imports []string, exports []string, err error) {
 	fset := token.NewFileSet()
 	
-	src, err := os.ReadFile(filePath)
+	src, err := a.files.ReadFile(filePath)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -226,39 +269,34 @@ func (a *GoDependencyAnalyzer) analyzeGoFile(filePath string) (imports []string,
 	return imports, exports, nil
 }
 
-// resolveImportToFile maps an import path to a local file path
-func (a *GoDependencyAnalyzer) resolveImportToFile(importPath string, files []FileInfo) string {
+// resolveImportToFiles maps an import path to the files of the local package it names
+func (a *GoDependencyAnalyzer) resolveImportToFiles(importPath string, files []FileInfo) []string {
 	// Skip standard library and external imports
 	if !a.isLocalImport(importPath) {
-		return ""
+		return nil
 	}
 	
-	// Convert import path to potential file paths
-	var searchPath string
-	if a.moduleInfo != nil && strings.HasPrefix(importPath, a.moduleInfo.ModulePath) {
+	// Convert import path to the package directory
+	var packageDir string
+	if module, ok := a.importModule(importPath); ok {
 		// Module-relative import
-		relPath := strings.TrimPrefix(importPath, a.moduleInfo.ModulePath)
+		relPath := strings.TrimPrefix(importPath, module.info.ModulePath)
 		relPath = strings.TrimPrefix(relPath, "/")
-		searchPath = filepath.Join(a.projectRoot, relPath)
+		packageDir = filepath.Join(module.dir, relPath)
 	} else {
 		// Try as relative path
-		searchPath = filepath.Join(a.projectRoot, importPath)
+		packageDir = filepath.Join(a.projectRoot, importPath)
 	}
 	
-	// Look for matching files
+	// Only files directly in the package directory belong to the package
+	var matches []string
 	for _, file := range files {
-		dir := filepath.Dir(file.Path)
-		if strings.HasPrefix(dir, searchPath) {
-			// Check if this is the main file of the package
-			if filepath.Base(file.Path) == "doc.go" || 
-			   strings.HasSuffix(file.Path, "_test.go") {
-				continue
-			}
-			return file.Path
+		if filepath.Dir(file.Path) == packageDir && !strings.HasSuffix(file.Path, "_test.go") {
+			matches = append(matches, file.Path)
 		}
 	}
 	
-	return ""
+	return matches
 }
 
 // isLocalImport checks if an import is from the local project
@@ -268,9 +306,10 @@ func (a *GoDependencyAnalyzer) isLocalImport(importPath string) bool {
 		return false
 	}
 	
-	// Check if it's our module
-	if a.moduleInfo != nil {
-		return strings.HasPrefix(importPath, a.moduleInfo.ModulePath)
+	// Check if it's one of our modules
+	if len(a.modules) > 0 {
+		_, ok := a.importModule(importPath)
+		return ok
 	}
 	
 	// Check common patterns
@@ -280,17 +319,34 @@ func (a *GoDependencyAnalyzer) isLocalImport(importPath string) bool {
 		!strings.HasPrefix(importPath, "gopkg.in/")
 }
 
+// importModule returns the module an import path belongs to, preferring the
+// longest module path when modules nest
+func (a *GoDependencyAnalyzer) importModule(importPath string) (goModuleRoot, bool) {
+	var match goModuleRoot
+	found := false
+	for _, module := range a.modules {
+		if strings.HasPrefix(importPath, module.info.ModulePath) && (!found || len(module.info.ModulePath) > len(match.info.ModulePath)) {
+			match, found = module, true
+		}
+	}
+	return match, found
+}
+
 // loadModuleInfo loads Go module information from go.mod
 func (a *GoDependencyAnalyzer) loadModuleInfo() *GoModuleInfo {
-	goModPath := filepath.Join(a.projectRoot, "go.mod")
-	file, err := os.Open(goModPath)
+	return loadGoModuleInfo(a.files, a.projectRoot)
+}
+
+// loadGoModuleInfo loads Go module information from the go.mod in dir
+func loadGoModuleInfo(files *projectFiles, dir string) *GoModuleInfo {
+	goModPath := filepath.Join(dir, "go.mod")
+	data, err := files.ReadFile(goModPath)
 	if err != nil {
 		return nil
 	}
-	defer file.Close()
 	
 	info := &GoModuleInfo{}
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -324,20 +380,42 @@ func min(a, b float64) float64 {
 
 // MultilanguageDependencyAnalyzer can analyze dependencies for multiple languages
 type MultilanguageDependencyAnalyzer struct {
-	analyzers map[string]DependencyAnalyzer
+	projectRoot string
+	analyzers   map[string]DependencyAnalyzer
 }
 
 // NewMultilanguageDependencyAnalyzer creates a dependency analyzer that supports multiple languages
 func NewMultilanguageDependencyAnalyzer(projectRoot string) *MultilanguageDependencyAnalyzer {
+	return newDependencyAnalyzer(projectRoot, nil, nil)
+}
+
+// NewWorkspaceDependencyAnalyzer creates a dependency analyzer for files
+// spanning several project roots under workspaceRoot, which graph keys are
+// relative to. Go imports resolve against each root's module and Python
+// imports against each root, so edges cross between roots.
+func NewWorkspaceDependencyAnalyzer(workspaceRoot string, roots []string) *MultilanguageDependencyAnalyzer {
+	return newDependencyAnalyzer(workspaceRoot, roots, nil)
+}
+
+// newDependencyAnalyzer creates a dependency analyzer for the files under
+// projectRoot, spanning roots under it when given, that reads them through
+// files
+func newDependencyAnalyzer(projectRoot string, roots []string, files *projectFiles) *MultilanguageDependencyAnalyzer {
+	javascript := NewJavaScriptDependencyAnalyzer(projectRoot)
+	python := NewPythonDependencyAnalyzer(projectRoot)
+	python.sourceRoots = roots
+	javascript.files, python.files = files, files
 	return &MultilanguageDependencyAnalyzer{
+		projectRoot: projectRoot,
 		analyzers: map[string]DependencyAnalyzer{
-			"go": NewGoDependencyAnalyzer(projectRoot),
-			// Can add more language analyzers here
+			"go":         newGoDependencyAnalyzer(projectRoot, roots, files),
+			"javascript": javascript,
+			"python":     python,
 		},
 	}
 }
 
-// AnalyzeDependencies delegates to appropriate language analyzer
+// AnalyzeDependencies runs the analyzer for each language and merges the graphs
 func (m *MultilanguageDependencyAnalyzer) AnalyzeDependencies(ctx context.Context, files []FileInfo) (*DependencyGraph, error) {
 	// Group files by language
 	filesByLang := make(map[string][]FileInfo)
@@ -345,26 +423,30 @@ func (m *MultilanguageDependencyAnalyzer) AnalyzeDependencies(ctx context.Contex
 		filesByLang[file.Language] = append(filesByLang[file.Language], file)
 	}
 	
-	// Use the analyzer for the dominant language
-	// In the future, could merge graphs from multiple analyzers
-	var dominantLang string
-	maxFiles := 0
-	for lang, langFiles := range filesByLang {
-		if len(langFiles) > maxFiles {
-			maxFiles = len(langFiles)
-			dominantLang = lang
-		}
+	graph := &DependencyGraph{
+		Nodes: make(map[string]*DependencyNode),
+		Edges: []DependencyEdge{},
 	}
 	
-	if analyzer, exists := m.analyzers[dominantLang]; exists {
-		return analyzer.AnalyzeDependencies(ctx, filesByLang[dominantLang])
+	for lang, langFiles := range filesByLang {
+		analyzer, exists := m.analyzers[lang]
+		if !exists {
+			continue
+		}
+		
+		langGraph, err := analyzer.AnalyzeDependencies(ctx, langFiles)
+		if err != nil {
+			return nil, fmt.Errorf("failed to analyze %s dependencies: %w", lang, err)
+		}
+		
+		// Languages never share files, so nodes don't collide
+		for path, node := range langGraph.Nodes {
+			graph.Nodes[path] = node
+		}
+		graph.Edges = append(graph.Edges, langGraph.Edges...)
 	}
 	
-	// Return empty graph if no suitable analyzer
-	return &DependencyGraph{
-		Nodes: make(map[string]*DependencyNode),
-		Edges: []DependencyEdge{},
-	}, nil
+	return graph, nil
 }
 
 // GetFileDependencies is not implemented for multilanguage analyzer
@@ -374,25 +456,55 @@ func (m *MultilanguageDependencyAnalyzer) GetFileDependencies(ctx context.Contex
 
 // GetDependents returns files that depend on the given file
 func (m *MultilanguageDependencyAnalyzer) GetDependents(graph *DependencyGraph, filePath string) []string {
-	relPath, _ := filepath.Rel(".", filePath)
-	
-	if node, exists := graph.Nodes[relPath]; exists {
+	if node, exists := graph.Nodes[dependencyNodeKey(m.projectRoot, filePath)]; exists {
 		return node.Dependents
 	}
 	
 	return []string{}
 }
 
-// CalculateCentrality delegates to appropriate analyzer
+// CalculateCentrality calculates the importance of a file in the dependency graph
 func (m *MultilanguageDependencyAnalyzer) CalculateCentrality(graph *DependencyGraph, filePath string) float64 {
-	// Use generic centrality calculation
-	relPath, _ := filepath.Rel(".", filePath)
-	
-	node, exists := graph.Nodes[relPath]
+	node, exists := graph.Nodes[dependencyNodeKey(m.projectRoot, filePath)]
 	if !exists {
 		return 0.0
 	}
 	
+	return nodeCentrality(graph, node)
+}
+
+// dependencyNodeKey returns the graph key for a file: its path relative to
+// the project root. Graphs are built and looked up with it alone, so a file
+// has the same key however its path is spelled. A root and path that differ
+// in being absolute are compared relative to the working directory.
+func dependencyNodeKey(projectRoot, filePath string) string {
+	if projectRoot == "" {
+		return filePath
+	}
+	
+	relPath, err := filepath.Rel(projectRoot, filePath)
+	if err != nil && filepath.IsAbs(projectRoot) != filepath.IsAbs(filePath) {
+		absRoot, rootErr := filepath.Abs(projectRoot)
+		absPath, pathErr := filepath.Abs(filePath)
+		if rootErr == nil && pathErr == nil {
+			relPath, err = filepath.Rel(absRoot, absPath)
+		}
+	}
+	
+	// Paths outside the root are kept as-is
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return filePath
+	}
+	return relPath
+}
+
+// GraphKey returns the key of a file in the project's dependency graph
+func (p *ProjectContext) GraphKey(filePath string) string {
+	return dependencyNodeKey(p.RootPath, filePath)
+}
+
+// nodeCentrality weighs in-degree over out-degree, normalized by graph size
+func nodeCentrality(graph *DependencyGraph, node *DependencyNode) float64 {
 	totalNodes := len(graph.Nodes)
 	if totalNodes <= 1 {
 		return 0.5
@@ -404,4 +516,43 @@ func (m *MultilanguageDependencyAnalyzer) CalculateCentrality(graph *DependencyG
 	centrality := (inDegree*2 + outDegree) / float64(3*(totalNodes-1))
 	
 	return min(1.0, centrality)
-}
\ No newline at end of file
+}
+
+// Centrality returns the centrality of the file with graph key path, 0 when
+// the graph doesn't have it
+func (g *DependencyGraph) Centrality(path string) float64 {
+	node, exists := g.Nodes[path]
+	if !exists {
+		return 0.0
+	}
+	return nodeCentrality(g, node)
+}
+
+// addDependencyEdge records that from imports to, ignoring self and repeated edges
+func addDependencyEdge(graph *DependencyGraph, from, to string) {
+	if from == to {
+		return
+	}
+	
+	node, exists := graph.Nodes[from]
+	if !exists {
+		return
+	}
+	for _, dep := range node.Dependencies {
+		if dep == to {
+			return
+		}
+	}
+	
+	node.Dependencies = append(node.Dependencies, to)
+	if depNode, exists := graph.Nodes[to]; exists {
+		depNode.Dependents = append(depNode.Dependents, from)
+	}
+	
+	graph.Edges = append(graph.Edges, DependencyEdge{
+		From:     from,
+		To:       to,
+		Type:     "import",
+		Strength: 1.0, // Direct import has full strength
+	})
+}
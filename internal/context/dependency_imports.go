@@ -0,0 +1,362 @@
+package context
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ImportDependencyAnalyzer builds dependency graphs from import statements
+// found by scanning source text, for languages without a Go-style parser
+type ImportDependencyAnalyzer struct {
+	projectRoot string
+	sourceRoots []string // Roots absolute imports resolve from, when not just projectRoot
+	extract     func(content string) []importSpec
+	resolve     func(projectRoot, fromFile, spec string, files map[string]bool) []string
+	files       *projectFiles // Where sources are read from; the local disk when nil
+}
+
+// importSpec is one imported module. Members lists names imported from it that
+// may themselves be modules, as in Python's `from pkg import module`.
+type importSpec struct {
+	module  string
+	members []string
+}
+
+// NewJavaScriptDependencyAnalyzer creates an analyzer for JavaScript and TypeScript
+// import, export-from and require statements
+func NewJavaScriptDependencyAnalyzer(projectRoot string) *ImportDependencyAnalyzer {
+	return &ImportDependencyAnalyzer{
+		projectRoot: projectRoot,
+		extract:     extractJavaScriptImports,
+		resolve:     resolveJavaScriptImport,
+	}
+}
+
+// NewPythonDependencyAnalyzer creates an analyzer for Python import statements
+func NewPythonDependencyAnalyzer(projectRoot string) *ImportDependencyAnalyzer {
+	return &ImportDependencyAnalyzer{
+		projectRoot: projectRoot,
+		extract:     extractPythonImports,
+		resolve:     resolvePythonImport,
+	}
+}
+
+// AnalyzeDependencies builds a dependency graph for the given files
+func (a *ImportDependencyAnalyzer) AnalyzeDependencies(ctx context.Context, files []FileInfo) (*DependencyGraph, error) {
+	graph := &DependencyGraph{
+		Nodes: make(map[string]*DependencyNode),
+		Edges: []DependencyEdge{},
+	}
+
+	for _, file := range files {
+		graph.addNode(dependencyNodeKey(a.projectRoot, file.Path))
+	}
+
+	fileSet := importFileSet(files)
+	for _, file := range files {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		a.linkFileInSet(graph, file, fileSet)
+	}
+
+	return graph, nil
+}
+
+// graphFiles returns the files that get nodes, which is all of them
+func (a *ImportDependencyAnalyzer) graphFiles(files []FileInfo) []FileInfo {
+	return files
+}
+
+// linkFile scans a file's imports and points its node at the project files
+// they resolve to. Unreadable files are left without edges.
+func (a *ImportDependencyAnalyzer) linkFile(graph *DependencyGraph, file FileInfo, files []FileInfo) {
+	a.linkFileInSet(graph, file, importFileSet(files))
+}
+
+// linkFileInSet is linkFile with the project files as a set of cleaned paths
+func (a *ImportDependencyAnalyzer) linkFileInSet(graph *DependencyGraph, file FileInfo, fileSet map[string]bool) {
+	key := dependencyNodeKey(a.projectRoot, file.Path)
+	imports, external := []string{}, []string(nil)
+	var dependencies []string
+
+	if content, err := a.files.ReadFile(file.Path); err == nil {
+		for _, spec := range a.extract(string(content)) {
+			imports = append(imports, spec.module)
+
+			depFiles := a.resolveModule(file.Path, spec.module, fileSet)
+			for _, member := range spec.members {
+				depFiles = append(depFiles, a.resolveModule(file.Path, joinPythonModule(spec.module, member), fileSet)...)
+			}
+			if len(depFiles) == 0 {
+				external = append(external, spec.module)
+				continue
+			}
+			for _, depFile := range depFiles {
+				dependencies = append(dependencies, dependencyNodeKey(a.projectRoot, depFile))
+			}
+		}
+	}
+
+	node := graph.UpdateFile(key, dependencies)
+	node.Imports, node.ExternalImports = imports, external
+}
+
+// resolveModule resolves a module imported by fromFile from the first source
+// root it is found under
+func (a *ImportDependencyAnalyzer) resolveModule(fromFile, module string, fileSet map[string]bool) []string {
+	roots := a.sourceRoots
+	if len(roots) == 0 {
+		roots = []string{a.projectRoot}
+	}
+	for _, root := range roots {
+		if depFiles := a.resolve(root, fromFile, module, fileSet); len(depFiles) > 0 {
+			return depFiles
+		}
+	}
+	return nil
+}
+
+// mayResolve reports whether a node has any unresolved imports. Python
+// members aren't kept on the node, so rather than re-resolving what's left,
+// such files are scanned again; scanning is cheap.
+func (a *ImportDependencyAnalyzer) mayResolve(node *DependencyNode, files []FileInfo) bool {
+	return len(node.ExternalImports) > 0
+}
+
+// importFileSet returns the cleaned paths of files for import resolution
+func importFileSet(files []FileInfo) map[string]bool {
+	fileSet := make(map[string]bool, len(files))
+	for _, file := range files {
+		fileSet[filepath.Clean(file.Path)] = true
+	}
+	return fileSet
+}
+
+// GetFileDependencies returns the modules imported by a single file
+func (a *ImportDependencyAnalyzer) GetFileDependencies(ctx context.Context, filePath string) ([]string, error) {
+	content, err := a.files.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := []string{}
+	for _, spec := range a.extract(string(content)) {
+		modules = append(modules, spec.module)
+	}
+	return modules, nil
+}
+
+// GetDependents returns files that depend on the given file
+func (a *ImportDependencyAnalyzer) GetDependents(graph *DependencyGraph, filePath string) []string {
+	if node, exists := graph.Nodes[dependencyNodeKey(a.projectRoot, filePath)]; exists {
+		return node.Dependents
+	}
+	return []string{}
+}
+
+// CalculateCentrality calculates the importance of a file in the dependency graph
+func (a *ImportDependencyAnalyzer) CalculateCentrality(graph *DependencyGraph, filePath string) float64 {
+	node, exists := graph.Nodes[dependencyNodeKey(a.projectRoot, filePath)]
+	if !exists {
+		return 0.0
+	}
+	return nodeCentrality(graph, node)
+}
+
+var (
+	// Matches `import x from 'y'`, `import 'y'`, `export { x } from 'y'` and
+	// `import type { T } from 'y'`, including clauses spanning several lines
+	jsImportFromPattern = regexp.MustCompile(`(?m)^\s*(?:import|export)\s+(?:type\s+)?(?:[\w*{}\s,$]+?\s+from\s+)?['"]([^'"]+)['"]`)
+	// Matches require('y') and dynamic import('y')
+	jsRequirePattern = regexp.MustCompile(`\b(?:require|import)\s*\(\s*['"]([^'"]+)['"]\s*\)`)
+
+	// Extensions tried, in order, when a JavaScript specifier omits one
+	jsResolveExtensions = []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs"}
+)
+
+// extractJavaScriptImports returns module specifiers in source order without duplicates
+func extractJavaScriptImports(content string) []importSpec {
+	type match struct {
+		offset int
+		spec   string
+	}
+	var matches []match
+	for _, pattern := range []*regexp.Regexp{jsImportFromPattern, jsRequirePattern} {
+		for _, m := range pattern.FindAllStringSubmatchIndex(content, -1) {
+			matches = append(matches, match{offset: m[2], spec: content[m[2]:m[3]]})
+		}
+	}
+
+	// Keep source order so Imports reads like the file
+	for i := 1; i < len(matches); i++ {
+		for j := i; j > 0 && matches[j].offset < matches[j-1].offset; j-- {
+			matches[j], matches[j-1] = matches[j-1], matches[j]
+		}
+	}
+
+	seen := make(map[string]bool)
+	imports := []importSpec{}
+	for _, m := range matches {
+		if !seen[m.spec] {
+			seen[m.spec] = true
+			imports = append(imports, importSpec{module: m.spec})
+		}
+	}
+	return imports
+}
+
+// resolveJavaScriptImport resolves relative specifiers the way Node and
+// TypeScript do: the exact file, then added extensions, then an index file
+func resolveJavaScriptImport(projectRoot, fromFile, spec string, files map[string]bool) []string {
+	// Bare specifiers are packages
+	if !isRelativeJavaScriptImport(spec) {
+		return nil
+	}
+
+	base := filepath.Join(filepath.Dir(fromFile), spec)
+	candidates := []string{base}
+
+	// TypeScript sources are imported with a .js extension under ESM resolution
+	if ext := filepath.Ext(base); ext == ".js" || ext == ".jsx" {
+		trimmed := strings.TrimSuffix(base, ext)
+		candidates = append(candidates, trimmed+".ts", trimmed+".tsx")
+	}
+	for _, ext := range jsResolveExtensions {
+		candidates = append(candidates, base+ext)
+	}
+	for _, ext := range jsResolveExtensions {
+		candidates = append(candidates, filepath.Join(base, "index"+ext))
+	}
+
+	for _, candidate := range candidates {
+		if files[candidate] {
+			return []string{candidate}
+		}
+	}
+	return nil
+}
+
+var (
+	pythonImportPattern     = regexp.MustCompile(`^import\s+(.+)$`)
+	pythonFromImportPattern = regexp.MustCompile(`^from\s+(\.*[\w.]*)\s+import\s+(.+)$`)
+)
+
+// extractPythonImports returns imported modules in source order. Names from
+// `from pkg import name` are kept as members since they may be submodules;
+// relative imports keep their leading dots.
+func extractPythonImports(content string) []importSpec {
+	seen := make(map[string]int)
+	imports := []importSpec{}
+	add := func(module string, members ...string) {
+		if module == "" {
+			return
+		}
+		if idx, exists := seen[module]; exists {
+			imports[idx].members = append(imports[idx].members, members...)
+			return
+		}
+		seen[module] = len(imports)
+		imports = append(imports, importSpec{module: module, members: members})
+	}
+
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(stripPythonComment(lines[i]))
+
+		// Join parenthesized and backslash-continued import lists
+		if strings.HasPrefix(line, "from ") || strings.HasPrefix(line, "import ") {
+			for (strings.Contains(line, "(") && !strings.Contains(line, ")")) || strings.HasSuffix(line, "\\") {
+				if i+1 >= len(lines) {
+					break
+				}
+				i++
+				line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(stripPythonComment(lines[i]))
+			}
+		}
+
+		if m := pythonFromImportPattern.FindStringSubmatch(line); m != nil {
+			var members []string
+			names := strings.Trim(strings.TrimSpace(m[2]), "()")
+			for _, name := range strings.Split(names, ",") {
+				name = strings.TrimSpace(strings.SplitN(strings.TrimSpace(name), " ", 2)[0])
+				if name != "" && name != "*" {
+					members = append(members, name)
+				}
+			}
+			add(m[1], members...)
+			continue
+		}
+
+		if m := pythonImportPattern.FindStringSubmatch(line); m != nil {
+			for _, module := range strings.Split(m[1], ",") {
+				add(strings.SplitN(strings.TrimSpace(module), " ", 2)[0])
+			}
+		}
+	}
+
+	return imports
+}
+
+// joinPythonModule appends a member name to a possibly relative module path
+func joinPythonModule(module, member string) string {
+	if strings.HasSuffix(module, ".") {
+		return module + member
+	}
+	return module + "." + member
+}
+
+// stripPythonComment removes a trailing # comment
+func stripPythonComment(line string) string {
+	if idx := strings.Index(line, "#"); idx >= 0 {
+		return line[:idx]
+	}
+	return line
+}
+
+// isRelativeJavaScriptImport reports whether a module specifier names a
+// project file rather than a package
+func isRelativeJavaScriptImport(spec string) bool {
+	return strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") || spec == "." || spec == ".."
+}
+
+// resolvePythonImport maps a module to module.py or module/__init__.py.
+// Relative modules resolve from the importing file's package; absolute ones
+// from the project root or a src/ layout.
+func resolvePythonImport(projectRoot, fromFile, spec string, files map[string]bool) []string {
+	var bases []string
+
+	if strings.HasPrefix(spec, ".") {
+		dots := len(spec) - len(strings.TrimLeft(spec, "."))
+		dir := filepath.Dir(fromFile)
+		for i := 1; i < dots; i++ {
+			dir = filepath.Dir(dir)
+		}
+		rest := strings.TrimLeft(spec, ".")
+		if rest == "" {
+			bases = append(bases, dir)
+		} else {
+			bases = append(bases, filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(rest, ".", "/"))))
+		}
+	} else {
+		modulePath := filepath.FromSlash(strings.ReplaceAll(spec, ".", "/"))
+		bases = append(bases, filepath.Join(projectRoot, modulePath), filepath.Join(projectRoot, "src", modulePath))
+	}
+
+	for _, base := range bases {
+		for _, candidate := range []string{base + ".py", filepath.Join(base, "__init__.py")} {
+			if files[candidate] {
+				return []string{candidate}
+			}
+		}
+	}
+	return nil
+}
+
+// Ensure ImportDependencyAnalyzer implements DependencyAnalyzer interface
+var _ DependencyAnalyzer = (*ImportDependencyAnalyzer)(nil)
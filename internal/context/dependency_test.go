@@ -0,0 +1,324 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeProjectFiles writes a map of relative path to content under root
+func writeProjectFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// analyzeTestProject writes files to a temp dir and analyzes it
+func analyzeTestProject(t *testing.T, files map[string]string) (*ProjectContext, string) {
+	t.Helper()
+	root := t.TempDir()
+	writeProjectFiles(t, root, files)
+
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	project, err := analyzer.AnalyzeProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+	return project, root
+}
+
+func sortedStrings(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// TestGoDependencyGraph tests import resolution for a small multi-package Go module
+func TestGoDependencyGraph(t *testing.T) {
+	project, root := analyzeTestProject(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.21\n",
+		"main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/app/internal/store"
+	"github.com/spf13/cobra"
+)
+
+func main() { fmt.Println(store.New(), cobra.Command{}) }
+`,
+		"internal/store/store.go": `package store
+
+import "example.com/app/internal/model"
+
+func New() *model.User { return &model.User{} }
+`,
+		"internal/store/cache.go":      "package store\n\nvar cache = map[string]int{}\n",
+		"internal/store/store_test.go": "package store\n\nimport \"testing\"\n\nfunc TestNew(t *testing.T) {}\n",
+		"internal/store/sub/helper.go": "package sub\n\nfunc Help() {}\n",
+		"internal/model/model.go":      "package model\n\ntype User struct{ Name string }\n",
+	})
+	graph := project.DependencyGraph
+
+	tests := []struct {
+		path         string
+		dependencies []string
+		dependents   []string
+		external     []string
+	}{
+		{
+			path:         "main.go",
+			dependencies: []string{"internal/store/cache.go", "internal/store/store.go"},
+			dependents:   []string{},
+			external:     []string{"fmt", "github.com/spf13/cobra"},
+		},
+		{
+			path:         "internal/store/store.go",
+			dependencies: []string{"internal/model/model.go"},
+			dependents:   []string{"main.go"},
+		},
+		{
+			path:         "internal/store/cache.go",
+			dependencies: []string{},
+			dependents:   []string{"main.go"},
+		},
+		{
+			path:         "internal/model/model.go",
+			dependencies: []string{},
+			dependents:   []string{"internal/store/store.go"},
+		},
+		{
+			path:         "internal/store/sub/helper.go",
+			dependencies: []string{},
+			dependents:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			node, exists := graph.Nodes[filepath.FromSlash(tt.path)]
+			if !exists {
+				t.Fatalf("no node for %s in %v", tt.path, graph.Nodes)
+			}
+			if got := sortedStrings(node.Dependencies); !reflect.DeepEqual(got, sortedStrings(tt.dependencies)) {
+				t.Errorf("Dependencies = %v, expected %v", got, tt.dependencies)
+			}
+			if got := sortedStrings(node.Dependents); !reflect.DeepEqual(got, sortedStrings(tt.dependents)) {
+				t.Errorf("Dependents = %v, expected %v", got, tt.dependents)
+			}
+			if got := sortedStrings(node.ExternalImports); len(tt.external) > 0 && !reflect.DeepEqual(got, tt.external) {
+				t.Errorf("ExternalImports = %v, expected %v", got, tt.external)
+			}
+		})
+	}
+
+	if _, exists := graph.Nodes[filepath.FromSlash("internal/store/store_test.go")]; exists {
+		t.Error("test files should not be graph nodes")
+	}
+	if len(graph.Edges) != 3 {
+		t.Errorf("got %d edges, expected 3: %v", len(graph.Edges), graph.Edges)
+	}
+
+	// The optimizer looks nodes up by absolute file path
+	optimizer := newTestOptimizer(nil)
+	modelPath := filepath.Join(root, "internal/model/model.go")
+	if centrality := optimizer.calculateDependencyCentrality(graph, project.RootPath, modelPath); centrality <= 0 {
+		t.Errorf("centrality of model.go = %v, expected > 0", centrality)
+	}
+}
+
+// TestScriptDependencyGraphs tests JavaScript/TypeScript and Python import resolution
+func TestScriptDependencyGraphs(t *testing.T) {
+	project, _ := analyzeTestProject(t, map[string]string{
+		"web/app.ts": `import React from 'react'
+import {
+  formatDate,
+  parseDate,
+} from './utils/date'
+import type { User } from "./models"
+export { api } from '../shared/api.js'
+const legacy = require('./legacy')
+`,
+		"web/utils/date.ts":   "export const formatDate = () => ''\n",
+		"web/models/index.ts": "export interface User { name: string }\n",
+		"web/legacy.js":       "module.exports = {}\n",
+		"shared/api.ts":       "export const api = {}\n",
+		"pkg/__init__.py":     "",
+		"pkg/service.py": `import os, json
+from pkg.models import User
+from . import helpers  # relative submodule
+from .helpers import (
+    slugify,
+    titleize,
+)
+`,
+		"pkg/models.py":  "class User:\n    pass\n",
+		"pkg/helpers.py": "def slugify(s):\n    return s\n",
+	})
+	graph := project.DependencyGraph
+
+	tests := []struct {
+		path         string
+		dependencies []string
+		external     []string
+	}{
+		{
+			path:         "web/app.ts",
+			dependencies: []string{"shared/api.ts", "web/legacy.js", "web/models/index.ts", "web/utils/date.ts"},
+			external:     []string{"react"},
+		},
+		{
+			path:         "pkg/service.py",
+			dependencies: []string{"pkg/__init__.py", "pkg/helpers.py", "pkg/models.py"}, // `from . import` loads the package
+			external:     []string{"json", "os"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			node, exists := graph.Nodes[filepath.FromSlash(tt.path)]
+			if !exists {
+				t.Fatalf("no node for %s", tt.path)
+			}
+			if got := sortedStrings(node.Dependencies); !reflect.DeepEqual(got, tt.dependencies) {
+				t.Errorf("Dependencies = %v, expected %v", got, tt.dependencies)
+			}
+			if got := sortedStrings(node.ExternalImports); !reflect.DeepEqual(got, tt.external) {
+				t.Errorf("ExternalImports = %v, expected %v", got, tt.external)
+			}
+		})
+	}
+}
+
+// normalizedGraph describes a graph independent of node and edge order
+func normalizedGraph(graph *DependencyGraph) map[string][]string {
+	described := make(map[string][]string, len(graph.Nodes))
+	for key, node := range graph.Nodes {
+		described[key] = []string{
+			"deps:" + fmt.Sprint(sortedStrings(node.Dependencies)),
+			"dependents:" + fmt.Sprint(sortedStrings(node.Dependents)),
+			"imports:" + fmt.Sprint(sortedStrings(node.Imports)),
+			"exports:" + fmt.Sprint(sortedStrings(node.Exports)),
+			"external:" + fmt.Sprint(sortedStrings(node.ExternalImports)),
+		}
+	}
+	edges := make([]string, 0, len(graph.Edges))
+	for _, edge := range graph.Edges {
+		edges = append(edges, edge.From+"->"+edge.To)
+	}
+	described["<edges>"] = sortedStrings(edges)
+	return described
+}
+
+// TestRefreshProjectPatchesDependencyGraph tests that patching the graph for
+// changed, added and deleted files matches a full rebuild
+func TestRefreshProjectPatchesDependencyGraph(t *testing.T) {
+	project, root := analyzeTestProject(t, map[string]string{
+		"go.mod":          "module example.com/app\n\ngo 1.21\n",
+		"main.go":         "package main\n\nimport \"example.com/app/store\"\n\nfunc main() { store.Open() }\n",
+		"store/store.go":  "package store\n\nfunc Open() {}\n",
+		"store/legacy.go": "package store\n\nfunc Legacy() {}\n",
+		"model/model.go":  "package model\n\ntype User struct{}\n",
+		"web/app.js":      "import { api } from './api'\n",
+		"web/api.js":      "export const api = {}\n",
+	})
+
+	tests := []struct {
+		name   string
+		write  map[string]string
+		remove []string
+	}{
+		{
+			name:  "imports change",
+			write: map[string]string{"main.go": "package main\n\nimport \"example.com/app/model\"\n\nfunc main() { _ = model.User{} }\n"},
+		},
+		{
+			name:   "package gains and loses files",
+			write:  map[string]string{"store/cache.go": "package store\n\nimport \"example.com/app/model\"\n\nvar cache []model.User\n"},
+			remove: []string{"store/legacy.go"},
+		},
+		{
+			name:   "import moves to a replacement file",
+			write:  map[string]string{"web/api.ts": "export const api = {}\n", "main.go": "package main\n\nimport \"example.com/app/store\"\n\nfunc main() {}\n"},
+			remove: []string{"web/api.js"},
+		},
+	}
+
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeProjectFiles(t, root, tt.write)
+			for _, name := range tt.remove {
+				if err := os.Remove(filepath.Join(root, name)); err != nil {
+					t.Fatalf("Failed to remove %s: %v", name, err)
+				}
+			}
+
+			refreshed, err := analyzer.RefreshProject(context.Background(), project)
+			if err != nil {
+				t.Fatalf("RefreshProject failed: %v", err)
+			}
+			rebuilt, err := analyzer.AnalyzeProject(context.Background(), root)
+			if err != nil {
+				t.Fatalf("AnalyzeProject failed: %v", err)
+			}
+
+			if got, expected := normalizedGraph(refreshed.DependencyGraph), normalizedGraph(rebuilt.DependencyGraph); !reflect.DeepEqual(got, expected) {
+				t.Errorf("patched graph differs from rebuild:\npatched: %v\nrebuilt: %v", got, expected)
+			}
+			if reflect.DeepEqual(normalizedGraph(refreshed.DependencyGraph), normalizedGraph(project.DependencyGraph)) {
+				t.Error("graph unchanged after editing imports")
+			}
+			project = refreshed
+		})
+	}
+}
+
+// TestCentralityOfNestedFiles tests that files deep in the tree resolve to
+// their graph nodes, however the root and the file's path are spelled
+func TestCentralityOfNestedFiles(t *testing.T) {
+	project, root := analyzeTestProject(t, map[string]string{
+		"go.mod": "module example.com/app\n\ngo 1.24\n",
+		"internal/platform/storage/sql/driver/conn.go": "package driver\n\ntype Conn struct{}\n",
+		"internal/platform/storage/sql/pool.go":        "package sql\n\nimport \"example.com/app/internal/platform/storage/sql/driver\"\n\nvar Pool []driver.Conn\n",
+		"cmd/api/server/routes/users.go":               "package routes\n\nimport \"example.com/app/internal/platform/storage/sql/driver\"\n\nvar Users driver.Conn\n",
+	})
+	conn := filepath.Join(root, "internal", "platform", "storage", "sql", "driver", "conn.go")
+	optimizer := newTestOptimizer(nil)
+
+	if key := project.GraphKey(conn); key != filepath.Join("internal", "platform", "storage", "sql", "driver", "conn.go") {
+		t.Errorf("GraphKey = %s, expected the path relative to the root", key)
+	}
+
+	t.Chdir(root)
+	tests := []struct {
+		name string
+		root string
+		path string
+	}{
+		{name: "absolute root and path", root: root, path: conn},
+		{name: "unclean path", root: root, path: filepath.Join(root, "internal", "platform", "storage", "..", "storage", "sql", "driver", "conn.go")},
+		{name: "relative root, absolute path", root: ".", path: conn},
+		{name: "absolute root, relative path", root: root, path: filepath.Join("internal", "platform", "storage", "sql", "driver", "conn.go")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			centrality := optimizer.calculateDependencyCentrality(project.DependencyGraph, tt.root, tt.path)
+			if centrality <= 0 {
+				t.Errorf("centrality = %v, expected the imported file to be central", centrality)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package context
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+// fileLinker is implemented by dependency analyzers that can link a single
+// file's node, so graphs can be patched instead of rebuilt
+type fileLinker interface {
+	// graphFiles returns the files among files that get graph nodes
+	graphFiles(files []FileInfo) []FileInfo
+
+	// linkFile re-reads file and replaces its node's imports and edges;
+	// files is the result of graphFiles for the whole project
+	linkFile(graph *DependencyGraph, file FileInfo, files []FileInfo)
+
+	// mayResolve reports whether any of a node's unresolved imports might
+	// resolve among files, without re-reading the file
+	mayResolve(node *DependencyNode, files []FileInfo) bool
+}
+
+// Ensure the language analyzers support incremental updates
+var (
+	_ fileLinker = (*GoDependencyAnalyzer)(nil)
+	_ fileLinker = (*ImportDependencyAnalyzer)(nil)
+)
+
+// UpdateFile sets the files a file depends on, given as graph keys, adding
+// its node if the graph doesn't have one. Edges and the dependents of old and
+// new dependencies are patched in place. Centrality is derived from the
+// current edges whenever it's asked for, so nothing else goes stale.
+func (g *DependencyGraph) UpdateFile(path string, dependencies []string) *DependencyNode {
+	node := g.addNode(path)
+	if len(node.Dependencies) > 0 {
+		for _, dep := range node.Dependencies {
+			if depNode, exists := g.Nodes[dep]; exists {
+				depNode.Dependents = slices.DeleteFunc(depNode.Dependents, func(key string) bool { return key == path })
+			}
+		}
+		g.Edges = slices.DeleteFunc(g.Edges, func(edge DependencyEdge) bool { return edge.From == path })
+		node.Dependencies = []string{}
+	}
+
+	for _, dep := range dependencies {
+		addDependencyEdge(g, path, dep)
+	}
+	return node
+}
+
+// RemoveFile drops a file's node and every edge to or from it, returning the
+// keys of the files that depended on it
+func (g *DependencyGraph) RemoveFile(path string) []string {
+	node, exists := g.Nodes[path]
+	if !exists {
+		return nil
+	}
+
+	isPath := func(key string) bool { return key == path }
+	for _, dep := range node.Dependencies {
+		if depNode, exists := g.Nodes[dep]; exists {
+			depNode.Dependents = slices.DeleteFunc(depNode.Dependents, isPath)
+		}
+	}
+	for _, dependent := range node.Dependents {
+		if dependentNode, exists := g.Nodes[dependent]; exists {
+			dependentNode.Dependencies = slices.DeleteFunc(dependentNode.Dependencies, isPath)
+		}
+	}
+	g.Edges = slices.DeleteFunc(g.Edges, func(edge DependencyEdge) bool {
+		return edge.From == path || edge.To == path
+	})
+	delete(g.Nodes, path)
+
+	return slices.Clone(node.Dependents)
+}
+
+// Clone returns a deep copy of the graph that can be patched without
+// affecting readers of the original
+func (g *DependencyGraph) Clone() *DependencyGraph {
+	clone := &DependencyGraph{
+		Nodes: make(map[string]*DependencyNode, len(g.Nodes)),
+		Edges: slices.Clone(g.Edges),
+	}
+	for key, node := range g.Nodes {
+		clone.Nodes[key] = &DependencyNode{
+			Path:            node.Path,
+			Imports:         slices.Clone(node.Imports),
+			Exports:         slices.Clone(node.Exports),
+			Dependencies:    slices.Clone(node.Dependencies),
+			Dependents:      slices.Clone(node.Dependents),
+			ExternalImports: slices.Clone(node.ExternalImports),
+		}
+	}
+	return clone
+}
+
+// addNode returns a file's node, creating an empty one if needed
+func (g *DependencyGraph) addNode(path string) *DependencyNode {
+	if node, exists := g.Nodes[path]; exists {
+		return node
+	}
+	node := &DependencyNode{
+		Path:         path,
+		Imports:      []string{},
+		Exports:      []string{},
+		Dependencies: []string{},
+		Dependents:   []string{},
+	}
+	g.Nodes[path] = node
+	return node
+}
+
+// UpdateDependencies patches a graph built from an earlier version of files
+// after the files at the changed paths were added, modified or deleted.
+// Besides the changed files, files whose imports may now resolve differently
+// are re-linked: those that depended on a deleted file, those with unresolved
+// imports that may resolve now, and those depending on a file in the
+// directory of an added one, such as the rest of a Go package.
+func (m *MultilanguageDependencyAnalyzer) UpdateDependencies(ctx context.Context, graph *DependencyGraph, files []FileInfo, changed []string) error {
+	type linkTarget struct {
+		linker fileLinker
+		file   FileInfo
+		files  []FileInfo
+	}
+
+	filesByLang := make(map[string][]FileInfo)
+	for _, file := range files {
+		filesByLang[file.Language] = append(filesByLang[file.Language], file)
+	}
+	targets := make(map[string]linkTarget)
+	for lang, langFiles := range filesByLang {
+		linker, ok := m.analyzers[lang].(fileLinker)
+		if !ok {
+			continue
+		}
+		graphFiles := linker.graphFiles(langFiles)
+		for _, file := range graphFiles {
+			targets[dependencyNodeKey(m.projectRoot, file.Path)] = linkTarget{linker: linker, file: file, files: graphFiles}
+		}
+	}
+
+	relink := make(map[string]bool)
+	addedDirs := make(map[string]bool)
+	structural := false
+	for _, path := range changed {
+		key := dependencyNodeKey(m.projectRoot, path)
+		if _, ok := targets[key]; ok {
+			if _, exists := graph.Nodes[key]; !exists {
+				graph.addNode(key)
+				addedDirs[filepath.Dir(key)] = true
+				structural = true
+			}
+			relink[key] = true
+			continue
+		}
+		if _, exists := graph.Nodes[key]; exists {
+			for _, dependent := range graph.RemoveFile(key) {
+				relink[dependent] = true
+			}
+			structural = true
+		}
+	}
+
+	if structural {
+		for key, node := range graph.Nodes {
+			target, ok := targets[key]
+			if !ok || relink[key] {
+				continue
+			}
+			if len(node.ExternalImports) > 0 && target.linker.mayResolve(node, target.files) {
+				relink[key] = true
+				continue
+			}
+			for _, dep := range node.Dependencies {
+				if addedDirs[filepath.Dir(dep)] {
+					relink[key] = true
+					break
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(relink))
+	for key := range relink {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if target, ok := targets[key]; ok {
+			target.linker.linkFile(graph, target.file, target.files)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package context
+
+import (
+	"hash/fnv"
+)
+
+// mmrCandidateLimit bounds the candidates re-ranked for diversity, since each
+// pick compares against every remaining candidate. Lower-ranked candidates
+// keep their order after the re-ranked ones.
+const mmrCandidateLimit = 100
+
+// importSimilarityWeight discounts shared imports relative to shared content;
+// files importing the same packages often do unrelated things with them
+const importSimilarityWeight = 0.5
+
+// diversityFeatures are the fingerprints used to compare two candidates
+type diversityFeatures struct {
+	shingles map[uint64]struct{}
+	imports  map[uint64]struct{}
+}
+
+// diversifyCandidates re-ranks candidates by maximal marginal relevance: each
+// pick maximizes (1-weight)*relevance - weight*similarity, where relevance is
+// normalized to the best candidate and similarity is the candidate's highest
+// similarity to any file picked before it. The similarity is recorded in the
+// "max_similarity" metadata of files that overlap an earlier pick. Weights
+// above 1 count as 1, since they'd rank the least relevant files first.
+func diversifyCandidates(candidates []ContextFile, project *ProjectContext, weight float64) []ContextFile {
+	if len(candidates) < 2 || weight <= 0 {
+		return candidates
+	}
+	weight = clampUnit(weight)
+
+	pool := candidates
+	var rest []ContextFile
+	if len(pool) > mmrCandidateLimit {
+		pool, rest = candidates[:mmrCandidateLimit], candidates[mmrCandidateLimit:]
+	}
+
+	features := make([]diversityFeatures, len(pool))
+	maxRelevance := 0.0
+	for i, file := range pool {
+		features[i] = candidateFeatures(file, project)
+		maxRelevance = max(maxRelevance, file.RelevanceScore)
+	}
+	if maxRelevance <= 0 {
+		maxRelevance = 1.0
+	}
+
+	picked := make([]bool, len(pool))
+	maxSimilarity := make([]float64, len(pool))
+	result := make([]ContextFile, 0, len(candidates))
+
+	for len(result) < len(pool) {
+		best, bestScore := -1, 0.0
+		for i, file := range pool {
+			if picked[i] {
+				continue
+			}
+			score := (1-weight)*file.RelevanceScore/maxRelevance - weight*maxSimilarity[i]
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		picked[best] = true
+		file := pool[best]
+		if maxSimilarity[best] > 0 {
+			file.Metadata = copyMetadata(file.Metadata)
+			file.Metadata["max_similarity"] = maxSimilarity[best]
+		}
+		result = append(result, file)
+
+		for i := range pool {
+			if !picked[i] {
+				maxSimilarity[i] = max(maxSimilarity[i], candidateSimilarity(features[best], features[i]))
+			}
+		}
+	}
+
+	return append(result, rest...)
+}
+
+// candidateFeatures fingerprints a candidate's content and imports
+func candidateFeatures(file ContextFile, project *ProjectContext) diversityFeatures {
+	var features diversityFeatures
+	if content, ok := loadContextFileContent(file); ok {
+		features.shingles = shingleSet(normalizeForDedup(content))
+	}
+
+	if project != nil && project.DependencyGraph != nil && file.FileInfo != nil {
+		if node, exists := project.DependencyGraph.Nodes[dependencyNodeKey(project.RootPath, file.FileInfo.Path)]; exists {
+			features.imports = make(map[uint64]struct{})
+			for _, imports := range [][]string{node.Dependencies, node.ExternalImports} {
+				for _, name := range imports {
+					h := fnv.New64a()
+					h.Write([]byte(name))
+					features.imports[h.Sum64()] = struct{}{}
+				}
+			}
+		}
+	}
+	return features
+}
+
+// candidateSimilarity returns how redundant two candidates are, from 0 to 1
+func candidateSimilarity(a, b diversityFeatures) float64 {
+	similarity := 0.0
+	if len(a.shingles) > 0 && len(b.shingles) > 0 {
+		similarity = jaccardSimilarity(a.shingles, b.shingles)
+	}
+	if len(a.imports) > 0 && len(b.imports) > 0 {
+		similarity = max(similarity, importSimilarityWeight*jaccardSimilarity(a.imports, b.imports))
+	}
+	return similarity
+}
+
+// copyMetadata returns a copy of metadata that can be modified without
+// affecting other candidates sharing the map
+func copyMetadata(metadata map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(metadata)+1)
+	for key, value := range metadata {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,134 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDiversityWeightSelectsDistinctFiles tests that MMR re-ranking trades
+// near-copies of the top file for complementary files
+func TestDiversityWeightSelectsDistinctFiles(t *testing.T) {
+	handler := func(entity string) string {
+		var b strings.Builder
+		fmt.Fprintf(&b, "package api\n\n// Handle%s serves the %s endpoint\n", entity, entity)
+		b.WriteString("func handle(w http.ResponseWriter, r *http.Request) {\n")
+		b.WriteString("\tif r.Method != http.MethodGet {\n\t\thttp.Error(w, \"method not allowed\", http.StatusMethodNotAllowed)\n\t\treturn\n\t}\n")
+		b.WriteString("\tid := r.URL.Query().Get(\"id\")\n\tif id == \"\" {\n\t\thttp.Error(w, \"missing id\", http.StatusBadRequest)\n\t\treturn\n\t}\n")
+		b.WriteString("\tw.Header().Set(\"Content-Type\", \"application/json\")\n\tjson.NewEncoder(w).Encode(map[string]string{\"id\": id})\n}\n")
+		return b.String()
+	}
+
+	root := t.TempDir()
+	files := map[string]string{
+		"api/users.go":    handler("Users"),
+		"api/orders.go":   handler("Orders"),
+		"api/invoices.go": handler("Invoices"),
+		"store/store.go":  "package store\n\n// Store persists records in a SQL database\ntype Store struct {\n\tdb *sql.DB\n}\n\nfunc (s *Store) Save(record Record) error {\n\t_, err := s.db.Exec(\"INSERT INTO records VALUES (?)\", record.ID)\n\treturn err\n}\n",
+		"auth/token.go":   "package auth\n\n// Verify checks a bearer token signature and expiry\nfunc Verify(token string, key []byte) (Claims, error) {\n\tparts := strings.Split(token, \".\")\n\tif len(parts) != 3 {\n\t\treturn Claims{}, ErrMalformed\n\t}\n\treturn decode(parts, key)\n}\n",
+	}
+	writeProjectFiles(t, root, files)
+
+	scores := map[string]float64{
+		"api/users.go":    0.9,
+		"api/orders.go":   0.88,
+		"api/invoices.go": 0.86,
+		"store/store.go":  0.7,
+		"auth/token.go":   0.65,
+	}
+	tokens := make(map[string]int)
+	absScores := make(map[string]float64)
+	for name := range files {
+		path := filepath.Join(root, name)
+		tokens[path] = 100
+		absScores[path] = scores[name]
+	}
+
+	project := newTestProject(tokens)
+	optimizer := newTestOptimizer(absScores)
+	task := &Task{Type: TaskTypeFeature, Description: "add an endpoint"}
+
+	distinct := func(paths []string) int {
+		groups := make(map[string]bool)
+		for _, path := range paths {
+			groups[filepath.Base(filepath.Dir(path))] = true
+		}
+		return len(groups)
+	}
+
+	tests := []struct {
+		name     string
+		weight   float64
+		distinct int
+	}{
+		{name: "pure relevance", weight: 0, distinct: 1},
+		{name: "mmr", weight: 0.5, distinct: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selection, err := optimizer.SelectOptimalContext(context.Background(), project, task, &ContextConstraints{
+				MaxTokens:       1000,
+				MaxFiles:        3,
+				Strategy:        StrategyRelevance,
+				DiversityWeight: tt.weight,
+			})
+			if err != nil {
+				t.Fatalf("SelectOptimalContext failed: %v", err)
+			}
+
+			paths := selectedPaths(selection)
+			if got := distinct(paths); got != tt.distinct {
+				t.Errorf("selected %v spanning %d packages, expected %d", paths, got, tt.distinct)
+			}
+			if paths[0] != filepath.Join(root, "api/users.go") {
+				t.Errorf("first file = %s, expected the most relevant file", paths[0])
+			}
+		})
+	}
+}
+
+// TestDiversifyCandidatesRecordsSimilarity tests that a near-copy picked after
+// its original carries its similarity in metadata
+func TestDiversifyCandidatesRecordsSimilarity(t *testing.T) {
+	shared := "func Load(path string) (Config, error) { data, err := os.ReadFile(path); if err != nil { return Config{}, err }; return parse(data) }"
+	candidates := []ContextFile{
+		{FileInfo: &FileInfo{Path: "a.go"}, RelevanceScore: 0.9, Content: shared},
+		{FileInfo: &FileInfo{Path: "b.go"}, RelevanceScore: 0.8, Content: shared + " // copy"},
+	}
+
+	ranked := diversifyCandidates(candidates, nil, 0.3)
+	if ranked[0].FileInfo.Path != "a.go" {
+		t.Fatalf("first = %s, expected a.go", ranked[0].FileInfo.Path)
+	}
+	similarity, ok := ranked[1].Metadata["max_similarity"].(float64)
+	if !ok || similarity < 0.8 {
+		t.Errorf("max_similarity = %v, expected a high similarity", ranked[1].Metadata["max_similarity"])
+	}
+	if candidates[1].Metadata != nil {
+		t.Error("diversifyCandidates modified the input's metadata")
+	}
+}
+
+// TestDiversifyCandidatesClampsWeight tests that a weight above 1 ranks like
+// 1 instead of preferring the least relevant files
+func TestDiversifyCandidatesClampsWeight(t *testing.T) {
+	candidates := []ContextFile{
+		{FileInfo: &FileInfo{Path: "a.go"}, RelevanceScore: 0.9, Content: "func Open() error { return nil }"},
+		{FileInfo: &FileInfo{Path: "b.go"}, RelevanceScore: 0.5, Content: "type Store struct { rows map[string]int }"},
+		{FileInfo: &FileInfo{Path: "c.go"}, RelevanceScore: 0.1, Content: "const Version = 3"},
+	}
+
+	expected := diversifyCandidates(candidates, nil, 1)
+	ranked := diversifyCandidates(candidates, nil, 5)
+	for i := range expected {
+		if ranked[i].FileInfo.Path != expected[i].FileInfo.Path {
+			t.Fatalf("weight 5 ranked %s at %d, expected %s as with weight 1", ranked[i].FileInfo.Path, i, expected[i].FileInfo.Path)
+		}
+	}
+	if ranked[0].FileInfo.Path != "a.go" {
+		t.Errorf("first = %s, expected the most relevant a.go", ranked[0].FileInfo.Path)
+	}
+}
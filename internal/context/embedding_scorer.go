@@ -0,0 +1,259 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Embedder turns texts into embedding vectors, one per text, e.g. by calling
+// an embeddings API
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// EmbeddingScorerConfig configures the embedding relevance scorer
+type EmbeddingScorerConfig struct {
+	SimilarityWeight float64       `json:"similarity_weight"` // Share of the score from embedding similarity; the rest comes from the fallback scorer
+	MaxContentBytes  int           `json:"max_content_bytes"` // File content embedded per file
+	Timeout          time.Duration `json:"timeout"`           // Per Embed call
+	FailureBackoff   time.Duration `json:"failure_backoff"`   // How long a failed Embed call keeps scoring on the heuristic alone; 30s when zero
+}
+
+// defaultEmbeddingBackoff is how long the embedder rests after a failure
+// when the config doesn't say
+const defaultEmbeddingBackoff = 30 * time.Second
+
+var (
+	// errEmbedderBackoff skips the embedder while it recovers from a failure
+	errEmbedderBackoff = errors.New("embedder failed recently")
+	// errNoFileVector is returned for files that couldn't be embedded, e.g.
+	// because they couldn't be read
+	errNoFileVector = errors.New("file has no embedding")
+)
+
+// EmbeddingRelevanceScorer scores files by the cosine similarity between the
+// task description's embedding and each file's embedding, blended with a
+// heuristic scorer. File vectors are cached by content hash, so unchanged
+// files are embedded once. Scoring falls back to the heuristic alone when the
+// embedder fails, without calling it again until FailureBackoff has passed,
+// and for files it has no vector for.
+type EmbeddingRelevanceScorer struct {
+	embedder Embedder
+	fallback RelevanceScorer
+	config   *EmbeddingScorerConfig
+
+	fileVectors  map[string][]float64
+	queryVectors map[string][]float64
+	retryAt      time.Time // The embedder isn't called before then, after a failure
+	mutex        sync.RWMutex
+}
+
+// Ensure EmbeddingRelevanceScorer implements RelevanceScorer interface
+var _ RelevanceScorer = (*EmbeddingRelevanceScorer)(nil)
+
+// NewEmbeddingRelevanceScorer creates an embedding scorer. A nil fallback uses
+// the default heuristic scorer.
+func NewEmbeddingRelevanceScorer(embedder Embedder, fallback RelevanceScorer, config *EmbeddingScorerConfig) *EmbeddingRelevanceScorer {
+	if config == nil {
+		config = &EmbeddingScorerConfig{
+			SimilarityWeight: 0.6,
+			MaxContentBytes:  8 * 1024,
+			Timeout:          10 * time.Second,
+			FailureBackoff:   defaultEmbeddingBackoff,
+		}
+	}
+	if fallback == nil {
+		fallback = NewSemanticRelevanceScorer(nil)
+	}
+	return &EmbeddingRelevanceScorer{
+		embedder:     embedder,
+		fallback:     fallback,
+		config:       config,
+		fileVectors:  make(map[string][]float64),
+		queryVectors: make(map[string][]float64),
+	}
+}
+
+// ScoreFile blends the file's embedding similarity to the task with the
+// fallback scorer's score
+func (s *EmbeddingRelevanceScorer) ScoreFile(file *FileInfo, task *Task) float64 {
+	heuristic := s.fallback.ScoreFile(file, task)
+
+	similarity, err := s.similarity(file, task)
+	if err != nil {
+		return heuristic
+	}
+	return s.config.SimilarityWeight*similarity + (1-s.config.SimilarityWeight)*heuristic
+}
+
+// ScoreFiles embeds all uncached files in one call and returns them sorted by
+// score, highest first
+func (s *EmbeddingRelevanceScorer) ScoreFiles(files []FileInfo, task *Task) []ScoredFile {
+	// Failures here only mean each file is scored, or falls back, separately
+	_ = s.embedFiles(files)
+
+	scored := make([]ScoredFile, len(files))
+	for i := range files {
+		scored[i] = ScoredFile{
+			File:    &files[i],
+			Score:   s.ScoreFile(&files[i], task),
+			Factors: s.GetScoringFactors(&files[i], task),
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	return scored
+}
+
+// GetScoringFactors returns the fallback scorer's factors along with the
+// embedding similarity
+func (s *EmbeddingRelevanceScorer) GetScoringFactors(file *FileInfo, task *Task) ScoringFactors {
+	factors := s.fallback.GetScoringFactors(file, task)
+	if similarity, err := s.similarity(file, task); err == nil {
+		factors.EmbeddingSimilarity = similarity
+	}
+	return factors
+}
+
+// similarity returns the cosine similarity between the task and file
+// embeddings, clamped to [0, 1]
+func (s *EmbeddingRelevanceScorer) similarity(file *FileInfo, task *Task) (float64, error) {
+	query, err := s.queryVector(task)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.embedFiles([]FileInfo{*file}); err != nil {
+		return 0, err
+	}
+
+	s.mutex.RLock()
+	vector, exists := s.fileVectors[fileVectorKey(file)]
+	s.mutex.RUnlock()
+	if !exists {
+		return 0, errNoFileVector
+	}
+	return math.Max(0, cosineSimilarity(query, vector)), nil
+}
+
+// queryVector returns the cached embedding of the task description
+func (s *EmbeddingRelevanceScorer) queryVector(task *Task) ([]float64, error) {
+	text := string(task.Type) + ": " + task.Description
+
+	s.mutex.RLock()
+	vector, exists := s.queryVectors[text]
+	s.mutex.RUnlock()
+	if exists {
+		return vector, nil
+	}
+
+	vectors, err := s.embed([]string{text})
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	s.queryVectors[text] = vectors[0]
+	s.mutex.Unlock()
+	return vectors[0], nil
+}
+
+// embedFiles embeds the files that aren't cached yet in a single call
+func (s *EmbeddingRelevanceScorer) embedFiles(files []FileInfo) error {
+	var keys, texts []string
+	s.mutex.RLock()
+	for _, file := range files {
+		key := fileVectorKey(&file)
+		if _, exists := s.fileVectors[key]; exists {
+			continue
+		}
+		content, err := readFileContent(&file)
+		if err != nil {
+			continue
+		}
+		if len(content) > s.config.MaxContentBytes {
+			content = content[:s.config.MaxContentBytes]
+		}
+		keys = append(keys, key)
+		texts = append(texts, file.Path+"\n"+string(content))
+	}
+	s.mutex.RUnlock()
+
+	if len(texts) == 0 {
+		return nil
+	}
+	vectors, err := s.embed(texts)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	for i, key := range keys {
+		s.fileVectors[key] = vectors[i]
+	}
+	s.mutex.Unlock()
+	return nil
+}
+
+// embed calls the embedder with the configured timeout and checks that it
+// returned a vector per text. After a failure it fails fast until the
+// backoff has passed, so an outage costs one timeout rather than one per file.
+func (s *EmbeddingRelevanceScorer) embed(texts []string) ([][]float64, error) {
+	s.mutex.RLock()
+	retryAt := s.retryAt
+	s.mutex.RUnlock()
+	if time.Now().Before(retryAt) {
+		return nil, errEmbedderBackoff
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout)
+	defer cancel()
+
+	vectors, err := s.embedder.Embed(ctx, texts)
+	if err == nil && len(vectors) != len(texts) {
+		err = fmt.Errorf("embedder returned %d vectors for %d texts", len(vectors), len(texts))
+	}
+	if err != nil {
+		backoff := s.config.FailureBackoff
+		if backoff <= 0 {
+			backoff = defaultEmbeddingBackoff
+		}
+		s.mutex.Lock()
+		s.retryAt = time.Now().Add(backoff)
+		s.mutex.Unlock()
+		return nil, fmt.Errorf("failed to embed %d texts: %w", len(texts), err)
+	}
+	return vectors, nil
+}
+
+// fileVectorKey identifies a file version; files without a content hash fall
+// back to their path and modification time
+func fileVectorKey(file *FileInfo) string {
+	if file.ContentHash != "" {
+		return file.ContentHash
+	}
+	return fmt.Sprintf("%s@%d", file.Path, file.LastModified.UnixNano())
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0 when
+// either is empty or their dimensions differ
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,182 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// conceptEmbedder embeds texts over a few concepts, each recognized by any of
+// several words, so related texts match without sharing literal terms
+type conceptEmbedder struct {
+	calls int
+	texts int
+	err   error
+}
+
+var testConcepts = [][]string{
+	{"login", "authentication", "password", "session"},
+	{"invoice", "billing", "payment"},
+	{"render", "template", "html"},
+}
+
+func (e *conceptEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	e.calls++
+	e.texts += len(texts)
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vector := make([]float64, len(testConcepts))
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			for concept, words := range testConcepts {
+				for _, w := range words {
+					if strings.Trim(word, ".,:()") == w {
+						vector[concept]++
+					}
+				}
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// TestEmbeddingScorerRanksSemanticMatches tests that a file related to the task
+// by meaning outranks one sharing no concepts, with file vectors cached
+func TestEmbeddingScorerRanksSemanticMatches(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{
+		"internal/a.go": "package internal\n\n// Verify checks the password and starts a session\nfunc Verify() {}\n",
+		"internal/b.go": "package internal\n\n// Total sums billing lines for an invoice\nfunc Total() {}\n",
+	})
+
+	embedder := &conceptEmbedder{}
+	analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+	analyzer.SetRelevanceScorer(NewEmbeddingRelevanceScorer(embedder, nil, nil))
+
+	var files []FileInfo
+	for _, name := range []string{"internal/a.go", "internal/b.go"} {
+		info, err := analyzer.GetFileInfo(context.Background(), filepath.Join(root, name))
+		if err != nil {
+			t.Fatalf("GetFileInfo failed: %v", err)
+		}
+		files = append(files, *info)
+	}
+
+	description := "fix login bug"
+	auth := analyzer.ScoreFileRelevance(&files[0], &Task{Type: TaskTypeDebug, Description: description})
+	billing := analyzer.ScoreFileRelevance(&files[1], &Task{Type: TaskTypeDebug, Description: description})
+	if auth <= billing {
+		t.Errorf("authentication file scored %.3f, billing file %.3f; expected the authentication file higher", auth, billing)
+	}
+
+	embedded := embedder.texts
+	analyzer.ScoreFileRelevance(&files[0], &Task{Type: TaskTypeDebug, Description: description})
+	analyzer.ScoreFileRelevance(&files[1], &Task{Type: TaskTypeDebug, Description: "update invoice totals"})
+	if embedder.texts != embedded+1 {
+		t.Errorf("embedded %d texts on rescoring, expected only the new description", embedder.texts-embedded)
+	}
+
+	scored := NewEmbeddingRelevanceScorer(embedder, nil, nil).ScoreFiles(files, &Task{Type: TaskTypeDebug, Description: "billing error"})
+	if scored[0].File.Path != files[1].Path || scored[0].Factors.EmbeddingSimilarity <= 0 {
+		t.Errorf("top file = %s with similarity %.3f, expected the billing file", scored[0].File.Path, scored[0].Factors.EmbeddingSimilarity)
+	}
+}
+
+// TestEmbeddingScorerFallback tests that embedder failures fall back to the
+// heuristic score
+func TestEmbeddingScorerFallback(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{"auth.go": "package auth\n"})
+
+	heuristic := NewSemanticRelevanceScorer(nil)
+	scorer := NewEmbeddingRelevanceScorer(&conceptEmbedder{err: errors.New("unavailable")}, heuristic, nil)
+	file := &FileInfo{Path: filepath.Join(root, "auth.go"), FileType: "source", Language: "go", TokenCount: 10}
+	task := &Task{Type: TaskTypeDebug, Description: "fix auth"}
+
+	if got, expected := scorer.ScoreFile(file, task), heuristic.ScoreFile(file, task); got != expected {
+		t.Errorf("ScoreFile = %v, expected the heuristic score %v", got, expected)
+	}
+}
+
+// TestEmbeddingScorerBacksOff tests that after a failure the embedder isn't
+// called again for each file, only once the backoff has passed
+func TestEmbeddingScorerBacksOff(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{
+		"auth.go":    "package auth\n",
+		"billing.go": "package billing\n",
+		"render.go":  "package render\n",
+	})
+	var files []FileInfo
+	for _, name := range []string{"auth.go", "billing.go", "render.go"} {
+		files = append(files, FileInfo{Path: filepath.Join(root, name), FileType: "source", Language: "go", TokenCount: 10})
+	}
+	task := &Task{Type: TaskTypeDebug, Description: "fix login"}
+
+	heuristic := NewSemanticRelevanceScorer(nil)
+	embedder := &conceptEmbedder{err: errors.New("unavailable")}
+	scorer := NewEmbeddingRelevanceScorer(embedder, heuristic, &EmbeddingScorerConfig{
+		SimilarityWeight: 0.6,
+		MaxContentBytes:  1024,
+		Timeout:          time.Second,
+		FailureBackoff:   50 * time.Millisecond,
+	})
+
+	for _, scored := range scorer.ScoreFiles(files, task) {
+		if expected := heuristic.ScoreFile(scored.File, task); scored.Score != expected {
+			t.Errorf("%s scored %v during the outage, expected the heuristic score %v", scored.File.Path, scored.Score, expected)
+		}
+	}
+	if embedder.calls != 1 {
+		t.Errorf("embedder called %d times during the outage, expected once", embedder.calls)
+	}
+
+	embedder.err = nil
+	time.Sleep(60 * time.Millisecond)
+	scorer.ScoreFiles(files, task)
+	if embedder.calls == 1 {
+		t.Error("embedder not called again after the backoff")
+	}
+}
+
+// TestEmbeddingScorerUnreadableFile tests that a file that can't be embedded
+// gets the heuristic score rather than a zero similarity
+func TestEmbeddingScorerUnreadableFile(t *testing.T) {
+	heuristic := NewSemanticRelevanceScorer(nil)
+	scorer := NewEmbeddingRelevanceScorer(&conceptEmbedder{}, heuristic, nil)
+	file := &FileInfo{Path: filepath.Join(t.TempDir(), "missing.go"), FileType: "source", Language: "go", TokenCount: 10}
+	task := &Task{Type: TaskTypeDebug, Description: "fix login"}
+
+	if got, expected := scorer.ScoreFile(file, task), heuristic.ScoreFile(file, task); got != expected {
+		t.Errorf("ScoreFile = %v, expected the heuristic score %v", got, expected)
+	}
+}
+
+// TestCosineSimilarity tests vector similarity edge cases
+func TestCosineSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     []float64
+		expected float64
+	}{
+		{name: "identical", a: []float64{1, 2}, b: []float64{2, 4}, expected: 1},
+		{name: "orthogonal", a: []float64{1, 0}, b: []float64{0, 1}, expected: 0},
+		{name: "zero vector", a: []float64{0, 0}, b: []float64{1, 1}, expected: 0},
+		{name: "mismatched dimensions", a: []float64{1}, b: []float64{1, 1}, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cosineSimilarity(tt.a, tt.b); got < tt.expected-1e-9 || got > tt.expected+1e-9 {
+				t.Errorf("cosineSimilarity = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
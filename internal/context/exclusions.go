@@ -0,0 +1,171 @@
+package context
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// ExclusionReason says why a project file was left out of a selection
+type ExclusionReason string
+
+const (
+	ExclusionBelowThreshold  ExclusionReason = "below_threshold"  // Scored under MinRelevanceScore
+	ExclusionFilteredByType  ExclusionReason = "filtered_by_type" // Not a preferred type, or a test, doc or denied extension
+	ExclusionExcludedPattern ExclusionReason = "excluded_pattern" // Path contains one of ExcludedPatterns
+	ExclusionDuplicate       ExclusionReason = "duplicate"        // Collapsed into a file with the same content
+	ExclusionBudget          ExclusionReason = "budget"           // Ranked, but didn't fit in MaxTokens or MaxFiles
+)
+
+// exclusionReportKey is the SelectedContext metadata key holding an
+// ExclusionReport
+const exclusionReportKey = "exclusion_report"
+
+// FileExclusion is why one file was left out of a selection
+type FileExclusion struct {
+	Path   string          `json:"path"`
+	Reason ExclusionReason `json:"reason"`
+	Score  float64         `json:"score"` // Score the strategy gave the file; zero when it was filtered before scoring
+	Tokens int             `json:"tokens"`
+	Detail string          `json:"detail,omitempty"` // The file type or pattern that filtered the file out
+}
+
+// ExclusionReport lists the project files a selection considered and left
+// out, ordered by path
+type ExclusionReport struct {
+	Files []FileExclusion `json:"files"`
+}
+
+// Lookup returns why the file at path was left out, if it was
+func (r *ExclusionReport) Lookup(path string) (FileExclusion, bool) {
+	for _, file := range r.Files {
+		if file.Path == path {
+			return file, true
+		}
+	}
+	return FileExclusion{}, false
+}
+
+// Exclusions returns the report of files the selection left out. There is
+// one only when the optimizer was configured with ReportExclusions.
+func (s *SelectedContext) Exclusions() (*ExclusionReport, bool) {
+	report, ok := s.Metadata[exclusionReportKey].(*ExclusionReport)
+	return report, ok
+}
+
+// exclusionRecorder collects what a selection learns about the files it
+// doesn't keep, so it can be reported afterwards
+type exclusionRecorder struct {
+	scores map[string]float64 // Strategy scores checked against MinRelevanceScore, by path
+	ranked []ContextFile      // Candidates before the token budget was applied
+}
+
+// exclusionRecorderKey carries an exclusionRecorder in a context
+type exclusionRecorderKey struct{}
+
+// withExclusionRecorder returns a context under which selection records
+// scores and candidates in a new recorder
+func withExclusionRecorder(ctx context.Context) (context.Context, *exclusionRecorder) {
+	recorder := &exclusionRecorder{scores: make(map[string]float64)}
+	return context.WithValue(ctx, exclusionRecorderKey{}, recorder), recorder
+}
+
+// exclusionRecorderFrom returns ctx's recorder, or nil when exclusions aren't
+// being reported
+func exclusionRecorderFrom(ctx context.Context) *exclusionRecorder {
+	recorder, _ := ctx.Value(exclusionRecorderKey{}).(*exclusionRecorder)
+	return recorder
+}
+
+// scored records the score a strategy compared against MinRelevanceScore
+func (r *exclusionRecorder) scored(file *FileInfo, score float64) {
+	if r == nil {
+		return
+	}
+	r.scores[file.Path] = score
+}
+
+// report explains why each project file missing from selected was left out.
+// A ranked candidate lost out to the budget; anything else was filtered,
+// scored too low or collapsed as a duplicate.
+func (r *exclusionRecorder) report(project *ProjectContext, constraints *ContextConstraints, selected []ContextFile) *ExclusionReport {
+	kept := make(map[string]bool, len(selected))
+	for _, file := range selected {
+		kept[file.FileInfo.Path] = true
+	}
+	ranked := make(map[string]float64, len(r.ranked))
+	for _, file := range r.ranked {
+		ranked[file.FileInfo.Path] = file.RelevanceScore
+	}
+
+	report := &ExclusionReport{Files: []FileExclusion{}}
+	for i := range project.Files {
+		file := &project.Files[i]
+		if kept[file.Path] {
+			continue
+		}
+
+		exclusion := FileExclusion{Path: file.Path, Tokens: file.TokenCount}
+		score, wasScored := r.scores[file.Path]
+		if rankScore, wasRanked := ranked[file.Path]; wasRanked {
+			exclusion.Reason, exclusion.Score = ExclusionBudget, rankScore
+		} else if reason, detail := filterExclusion(file, constraints); reason != "" {
+			exclusion.Reason, exclusion.Detail = reason, detail
+		} else if !wasScored {
+			continue
+		} else if score < constraints.MinRelevanceScore {
+			exclusion.Reason, exclusion.Score = ExclusionBelowThreshold, score
+		} else {
+			exclusion.Reason, exclusion.Score = ExclusionDuplicate, score
+		}
+		report.Files = append(report.Files, exclusion)
+	}
+
+	sort.Slice(report.Files, func(i, j int) bool {
+		return report.Files[i].Path < report.Files[j].Path
+	})
+	return report
+}
+
+// filterExclusion returns why the constraints filter file out before it's
+// scored, with the file type or pattern responsible, or an empty reason when
+// they don't
+func filterExclusion(file *FileInfo, constraints *ContextConstraints) (ExclusionReason, string) {
+	// Check file type preferences
+	if len(constraints.PreferredTypes) > 0 {
+		found := false
+		for _, preferredType := range constraints.PreferredTypes {
+			if file.FileType == preferredType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ExclusionFilteredByType, file.FileType
+		}
+	}
+
+	// Check excluded patterns
+	for _, pattern := range constraints.ExcludedPatterns {
+		if strings.Contains(file.Path, pattern) {
+			return ExclusionExcludedPattern, pattern
+		}
+	}
+
+	// Check denied extensions
+	if hasDeniedExtension(file.Path, constraints.DeniedExtensions) {
+		return ExclusionFilteredByType, "denied extension"
+	}
+
+	// Check test file inclusion
+	if !constraints.IncludeTests && file.FileType == "test" {
+		return ExclusionFilteredByType, file.FileType
+	}
+
+	// Check documentation inclusion
+	if !constraints.IncludeDocs && file.FileType == "documentation" {
+		return ExclusionFilteredByType, file.FileType
+	}
+
+	return "", ""
+}
@@ -0,0 +1,88 @@
+package context
+
+import (
+	"context"
+	"testing"
+)
+
+// TestExclusionReport tests that every file left out of a selection is
+// reported with the reason it was dropped and, once scored, its score
+func TestExclusionReport(t *testing.T) {
+	project := newTestProject(map[string]int{
+		"/project/main.go":       300,
+		"/project/handler.go":    300,
+		"/project/util.go":       100,
+		"/project/vendor/lib.go": 100,
+		"/project/README.md":     100,
+	})
+	for i := range project.Files {
+		if project.Files[i].Path == "/project/README.md" {
+			project.Files[i].FileType = "documentation"
+		}
+	}
+	scores := map[string]float64{
+		"/project/main.go":       0.9,
+		"/project/handler.go":    0.8,
+		"/project/util.go":       0.1,
+		"/project/vendor/lib.go": 0.9,
+		"/project/README.md":     0.9,
+	}
+	constraints := &ContextConstraints{
+		MaxTokens:         400,
+		MaxFiles:          10,
+		MinRelevanceScore: 0.3,
+		ExcludedPatterns:  []string{"vendor/"},
+		Strategy:          StrategyRelevance,
+	}
+	task := &Task{Type: TaskTypeFeature, Description: "handle requests"}
+
+	optimizer := newTestOptimizer(scores)
+	optimizer.config.ReportExclusions = true
+	selection, err := optimizer.SelectOptimalContext(context.Background(), project, task, constraints)
+	if err != nil {
+		t.Fatalf("SelectOptimalContext failed: %v", err)
+	}
+	if paths := selectedPaths(selection); len(paths) != 1 || paths[0] != "/project/main.go" {
+		t.Fatalf("selected %v, expected only main.go", paths)
+	}
+
+	report, ok := selection.Exclusions()
+	if !ok {
+		t.Fatal("expected an exclusion report")
+	}
+	tests := []struct {
+		path   string
+		reason ExclusionReason
+		score  float64
+		detail string
+	}{
+		{path: "/project/handler.go", reason: ExclusionBudget, score: 0.8},
+		{path: "/project/util.go", reason: ExclusionBelowThreshold, score: 0.1},
+		{path: "/project/vendor/lib.go", reason: ExclusionExcludedPattern, detail: "vendor/"},
+		{path: "/project/README.md", reason: ExclusionFilteredByType, detail: "documentation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			exclusion, found := report.Lookup(tt.path)
+			if !found {
+				t.Fatalf("%s missing from report %+v", tt.path, report.Files)
+			}
+			if exclusion.Reason != tt.reason || exclusion.Score != tt.score || exclusion.Detail != tt.detail {
+				t.Errorf("exclusion = %+v, expected reason %s, score %v and detail %q", exclusion, tt.reason, tt.score, tt.detail)
+			}
+		})
+	}
+	if len(report.Files) != len(tests) {
+		t.Errorf("report has %d files, expected %d: %+v", len(report.Files), len(tests), report.Files)
+	}
+
+	// Without the flag, selection doesn't pay for a report
+	optimizer.config.ReportExclusions = false
+	selection, err = optimizer.SelectOptimalContext(context.Background(), project, task, constraints)
+	if err != nil {
+		t.Fatalf("SelectOptimalContext failed: %v", err)
+	}
+	if _, ok := selection.Exclusions(); ok {
+		t.Error("expected no exclusion report when ReportExclusions is off")
+	}
+}
@@ -71,7 +71,11 @@ type FeedbackAnalysis struct {
 	SuccessRate         float64                `json:"success_rate"`
 	TopMissingFiles     []FileRelevanceInfo    `json:"top_missing_files"`
 	TopIrrelevantFiles  []FileRelevanceInfo    `json:"top_irrelevant_files"`
+	QualityEstimate     Estimate               `json:"quality_estimate"` // AvgContextQuality with its sample count and confidence interval
+	SuccessEstimate     Estimate               `json:"success_estimate"` // SuccessRate with its sample count and confidence interval
 	StrategyEffectiveness map[string]float64   `json:"strategy_effectiveness"`
+	StrategyEstimates   map[string]Estimate    `json:"strategy_estimates"` // Context quality by selection strategy, behind StrategyEffectiveness
+	ArmEstimates        map[string]Estimate    `json:"arm_estimates"`      // Context quality by adaptation experiment arm
 	TaskTypeInsights    map[string]*TaskTypeInsight `json:"task_type_insights"`
 	QualityTrends       []QualityDataPoint     `json:"quality_trends"`
 	Recommendations     []string               `json:"recommendations"`
@@ -93,6 +97,8 @@ type TaskTypeInsight struct {
 	AvgQuality          float64       `json:"avg_quality"`
 	AvgDuration         time.Duration `json:"avg_duration"`
 	SuccessRate         float64       `json:"success_rate"`
+	QualityEstimate     Estimate      `json:"quality_estimate"`
+	SuccessEstimate     Estimate      `json:"success_estimate"`
 	OptimalTokenBudget  int           `json:"optimal_token_budget"`
 	PreferredStrategy   string        `json:"preferred_strategy"`
 	CommonMissingFiles  []string      `json:"common_missing_files"`
@@ -128,6 +134,7 @@ type DefaultFeedbackCollector struct {
 	mutex            sync.RWMutex
 	analysisCache    map[string]*FeedbackAnalysis
 	cacheExpiry      map[string]time.Time
+	qualityScorer    *ContextQualityScorer
 }
 
 // FeedbackConfig configures feedback collection behavior
@@ -187,6 +194,12 @@ func NewDefaultFeedbackCollector(store FeedbackStore, adaptiveManager AdaptiveCo
 	}
 }
 
+// SetQualityScorer makes implicit feedback weigh the selection's own quality
+// equally with what execution suggests; nil infers quality from execution alone
+func (f *DefaultFeedbackCollector) SetQualityScorer(scorer *ContextQualityScorer) {
+	f.qualityScorer = scorer
+}
+
 // CollectImplicitFeedback collects feedback from task execution patterns
 func (f *DefaultFeedbackCollector) CollectImplicitFeedback(task *Task, context *SelectedContext, executionData *TaskExecutionData) error {
 	if !f.config.EnableImplicitCollection {
@@ -199,12 +212,13 @@ func (f *DefaultFeedbackCollector) CollectImplicitFeedback(task *Task, context *
 		Task:            task,
 		SelectedContext: context,
 		TaskSuccess:     executionData.CompletionStatus == "success",
-		QualityScore:    f.inferQualityFromExecution(executionData),
+		QualityScore:    f.inferImplicitQuality(executionData, context),
 		CompletionTime:  executionData.Duration,
 		TokensUsed:      executionData.TokensConsumed,
 		MissingFiles:    f.inferMissingFiles(executionData, context),
 		UnnecessaryFiles: f.inferUnnecessaryFiles(executionData, context),
 		UserRating:      0, // No explicit user rating for implicit feedback
+		Arm:             selectionArm(context),
 		Timestamp:       time.Now(),
 	}
 
@@ -271,6 +285,8 @@ func (f *DefaultFeedbackCollector) AnalyzeFeedbackTrends(timeWindow time.Duratio
 		TopMissingFiles:     []FileRelevanceInfo{},
 		TopIrrelevantFiles:  []FileRelevanceInfo{},
 		StrategyEffectiveness: make(map[string]float64),
+		StrategyEstimates:   make(map[string]Estimate),
+		ArmEstimates:        make(map[string]Estimate),
 		TaskTypeInsights:    make(map[string]*TaskTypeInsight),
 		QualityTrends:       []QualityDataPoint{},
 		Recommendations:     []string{},
@@ -334,6 +350,20 @@ func (f *DefaultFeedbackCollector) ExportFeedbackData(outputPath string) error {
 
 // Helper methods for feedback analysis
 
+// inferImplicitQuality blends the quality execution suggests with the
+// selection's scored quality when a scorer is set
+func (f *DefaultFeedbackCollector) inferImplicitQuality(data *TaskExecutionData, selection *SelectedContext) float64 {
+	quality := f.inferQualityFromExecution(data)
+	if f.qualityScorer == nil || selection == nil {
+		return quality
+	}
+	scored := f.qualityScorer.Score(selection)
+	if len(scored.Measured) == 0 {
+		return quality
+	}
+	return (quality + scored.Score) / 2
+}
+
 func (f *DefaultFeedbackCollector) inferQualityFromExecution(data *TaskExecutionData) float64 {
 	baseQuality := 0.5
 
@@ -420,60 +450,134 @@ func (f *DefaultFeedbackCollector) convertExplicitToContextFeedback(explicit *Ex
 	// Convert 1-5 rating to 0-1 quality score
 	qualityScore := float64(explicit.ContextQuality-1) / 4.0
 
+	// The task type travels in metadata since explicit feedback has no task
+	var task *Task
+	if taskType, ok := explicit.AdditionalMetadata["task_type"].(string); ok && taskType != "" {
+		task = &Task{Type: TaskType(taskType)}
+	}
+
 	return &ContextFeedback{
-		TaskID:           explicit.TaskID,
-		TaskSuccess:      explicit.ContextQuality >= 3, // 3+ out of 5 is success
-		QualityScore:     qualityScore,
-		MissingFiles:     explicit.MissingFiles,
-		UnnecessaryFiles: explicit.IrrelevantFiles,
-		UserRating:       qualityScore,
-		Timestamp:        explicit.Timestamp,
+		TaskID:            explicit.TaskID,
+		Task:              task,
+		TaskSuccess:       explicit.ContextQuality >= 3, // 3+ out of 5 is success
+		QualityScore:      qualityScore,
+		MissingFiles:      explicit.MissingFiles,
+		UnnecessaryFiles:  explicit.IrrelevantFiles,
+		UserRating:        qualityScore,
+		PreferredStrategy: SelectionStrategy(explicit.PreferredStrategy),
+		Timestamp:         explicit.Timestamp,
 	}
 }
 
+// analyzeFeedbackData estimates quality and success overall, per strategy,
+// per experiment arm, and per task type from the implicit feedback records
 func (f *DefaultFeedbackCollector) analyzeFeedbackData(analysis *FeedbackAnalysis, feedbackData []interface{}) {
-	qualitySum := 0.0
-	successCount := 0
+	overall := &feedbackBucket{}
+	strategies := make(map[string]*feedbackBucket)
+	arms := make(map[string]*feedbackBucket)
+	taskTypes := make(map[string]*feedbackBucket)
 	
 	for _, data := range feedbackData {
 		switch feedback := data.(type) {
 		case *ContextFeedback:
-			qualitySum += feedback.QualityScore
-			if feedback.TaskSuccess {
-				successCount++
-			}
+			overall.add(feedback.QualityScore, feedback.TaskSuccess)
 			
 			// Track quality trends
-			analysis.QualityTrends = append(analysis.QualityTrends, QualityDataPoint{
+			dataPoint := QualityDataPoint{
 				Timestamp: feedback.Timestamp,
 				Quality:   feedback.QualityScore,
-				Strategy:  string(feedback.SelectedContext.Strategy),
-				TaskType:  string(feedback.Task.Type),
-			})
+			}
+			if feedback.SelectedContext != nil {
+				dataPoint.Strategy = string(feedback.SelectedContext.Strategy)
+			}
+			if feedback.Task != nil {
+				dataPoint.TaskType = string(feedback.Task.Type)
+			}
+			analysis.QualityTrends = append(analysis.QualityTrends, dataPoint)
+
+			if dataPoint.Strategy != "" {
+				bucketFor(strategies, dataPoint.Strategy).add(feedback.QualityScore, feedback.TaskSuccess)
+			}
+			if feedback.Arm != "" {
+				bucketFor(arms, string(feedback.Arm)).add(feedback.QualityScore, feedback.TaskSuccess)
+			}
+			if dataPoint.TaskType != "" {
+				bucketFor(taskTypes, dataPoint.TaskType).add(feedback.QualityScore, feedback.TaskSuccess)
+			}
 		}
 	}
 
-	if len(feedbackData) > 0 {
-		analysis.AvgContextQuality = qualitySum / float64(len(feedbackData))
-		analysis.SuccessRate = float64(successCount) / float64(len(feedbackData))
+	analysis.QualityEstimate = overall.quality()
+	analysis.SuccessEstimate = overall.successRate()
+	analysis.AvgContextQuality = analysis.QualityEstimate.Mean
+	analysis.SuccessRate = analysis.SuccessEstimate.Mean
+
+	for strategy, bucket := range strategies {
+		estimate := bucket.quality()
+		analysis.StrategyEstimates[strategy] = estimate
+		analysis.StrategyEffectiveness[strategy] = estimate.Mean
+	}
+	for arm, bucket := range arms {
+		analysis.ArmEstimates[arm] = bucket.quality()
+	}
+	for taskType, bucket := range taskTypes {
+		quality, success := bucket.quality(), bucket.successRate()
+		analysis.TaskTypeInsights[taskType] = &TaskTypeInsight{
+			TaskType:        TaskType(taskType),
+			SampleCount:     quality.Samples,
+			AvgQuality:      quality.Mean,
+			SuccessRate:     success.Mean,
+			QualityEstimate: quality,
+			SuccessEstimate: success,
+		}
 	}
 }
 
+// generateRecommendations only acts on figures backed by
+// MinSamplesForInsights samples whose whole confidence interval is on one
+// side of the threshold, so a few unlucky runs don't redirect the adaptive
+// system
 func (f *DefaultFeedbackCollector) generateRecommendations(analysis *FeedbackAnalysis) {
 	recommendations := []string{}
+	minSamples := f.config.MinSamplesForInsights
 
-	// Quality-based recommendations
-	if analysis.AvgContextQuality < f.config.QualityThresholds.Fair {
-		recommendations = append(recommendations, "Context quality is below threshold - consider adjusting selection strategies")
+	// Quality thresholds use the 1-5 rating scale; quality scores are 0-1
+	fairQuality := (f.config.QualityThresholds.Fair - 1) / 4
+	quality := analysis.QualityEstimate
+	if quality.Reliable(minSamples) && quality.Upper < fairQuality {
+		recommendations = append(recommendations, fmt.Sprintf(
+			"Context quality is below threshold (%.2f, 95%% CI %.2f-%.2f over %d samples) - consider adjusting selection strategies",
+			quality.Mean, quality.Lower, quality.Upper, quality.Samples))
 	}
 
 	// Success rate recommendations
-	if analysis.SuccessRate < 0.7 {
-		recommendations = append(recommendations, "Low success rate detected - review task-specific context patterns")
+	success := analysis.SuccessEstimate
+	if success.Reliable(minSamples) && success.Upper < 0.7 {
+		recommendations = append(recommendations, fmt.Sprintf(
+			"Low success rate detected (%.0f%%, 95%% CI %.0f%%-%.0f%% over %d samples) - review task-specific context patterns",
+			success.Mean*100, success.Lower*100, success.Upper*100, success.Samples))
+	}
+
+	// A strategy is only called better when its interval clears the other's
+	best := ""
+	for _, strategy := range sortedEstimateKeys(analysis.StrategyEstimates) {
+		estimate := analysis.StrategyEstimates[strategy]
+		if estimate.Reliable(minSamples) && (best == "" || estimate.Mean > analysis.StrategyEstimates[best].Mean) {
+			best = strategy
+		}
+	}
+	for _, strategy := range sortedEstimateKeys(analysis.StrategyEstimates) {
+		estimate, top := analysis.StrategyEstimates[strategy], analysis.StrategyEstimates[best]
+		if strategy == best || !estimate.Reliable(minSamples) || top.Lower <= estimate.Upper {
+			continue
+		}
+		recommendations = append(recommendations, fmt.Sprintf(
+			"Strategy '%s' outperforms '%s' (quality %.2f vs %.2f over %d and %d samples) - prefer it",
+			best, strategy, top.Mean, estimate.Mean, top.Samples, estimate.Samples))
 	}
 
 	// Sample size recommendations
-	if analysis.TotalSamples < f.config.MinSamplesForInsights {
+	if !quality.Reliable(minSamples) {
 		recommendations = append(recommendations, "Insufficient feedback samples for reliable insights - continue collecting data")
 	}
 
@@ -540,9 +644,9 @@ func (s *SimpleFeedbackStore) StoreFeedback(feedback interface{}) error {
 	filepath := filepath.Join(s.storePath, filename)
 
 	// Marshal and write feedback
-	data, err := json.MarshalIndent(feedback, "", "  ")
+	data, _, err := encodeFeedback(feedback)
 	if err != nil {
-		return fmt.Errorf("failed to marshal feedback: %w", err)
+		return err
 	}
 
 	if err := os.WriteFile(filepath, data, 0644); err != nil {
@@ -554,6 +658,11 @@ func (s *SimpleFeedbackStore) StoreFeedback(feedback interface{}) error {
 
 // GetFeedback retrieves feedback within a time window
 func (s *SimpleFeedbackStore) GetFeedback(timeWindow time.Duration) ([]interface{}, error) {
+	return s.getFeedback("", timeWindow)
+}
+
+// getFeedback reads feedback within a time window, optionally filtered by type
+func (s *SimpleFeedbackStore) getFeedback(feedbackType string, timeWindow time.Duration) ([]interface{}, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
@@ -577,14 +686,12 @@ func (s *SimpleFeedbackStore) GetFeedback(timeWindow time.Duration) ([]interface
 			continue
 		}
 
-		// Read and unmarshal feedback
-		data, err := os.ReadFile(file)
+		// Read and unmarshal feedback into its concrete type
+		feedbackItem, itemType, err := readFeedbackFile(file)
 		if err != nil {
 			continue
 		}
-
-		var feedbackItem interface{}
-		if err := json.Unmarshal(data, &feedbackItem); err != nil {
+		if feedbackType != "" && itemType != feedbackType {
 			continue
 		}
 
@@ -596,9 +703,7 @@ func (s *SimpleFeedbackStore) GetFeedback(timeWindow time.Duration) ([]interface
 
 // GetFeedbackByType retrieves feedback of a specific type
 func (s *SimpleFeedbackStore) GetFeedbackByType(feedbackType string, timeWindow time.Duration) ([]interface{}, error) {
-	// For this simple implementation, return all feedback
-	// A more sophisticated implementation would filter by type
-	return s.GetFeedback(timeWindow)
+	return s.getFeedback(feedbackType, timeWindow)
 }
 
 // CleanOldFeedback removes feedback older than retention days
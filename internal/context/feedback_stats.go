@@ -0,0 +1,118 @@
+package context
+
+import (
+	"math"
+	"sort"
+)
+
+// confidenceZ is the normal quantile of the 95% confidence intervals
+// reported by feedback analysis
+const confidenceZ = 1.96
+
+// Estimate is an average together with how far the samples behind it can be
+// trusted
+type Estimate struct {
+	Mean     float64 `json:"mean"`
+	StdError float64 `json:"std_error"`
+	Lower    float64 `json:"lower"` // Bounds of the 95% confidence interval, within [0, 1]
+	Upper    float64 `json:"upper"`
+	Samples  int     `json:"samples"`
+}
+
+// Reliable reports whether the estimate rests on at least minSamples samples
+func (e Estimate) Reliable(minSamples int) bool {
+	return e.Samples > 0 && e.Samples >= minSamples
+}
+
+// meanEstimate estimates the mean of scores in [0, 1]. With fewer than two
+// samples the spread is unknown, so the interval covers every score.
+func meanEstimate(values []float64) Estimate {
+	estimate := Estimate{Lower: 0, Upper: 1, Samples: len(values)}
+	if len(values) == 0 {
+		return estimate
+	}
+
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	estimate.Mean = sum / float64(len(values))
+	if len(values) < 2 {
+		return estimate
+	}
+
+	variance := 0.0
+	for _, value := range values {
+		variance += (value - estimate.Mean) * (value - estimate.Mean)
+	}
+	variance /= float64(len(values) - 1)
+	estimate.StdError = math.Sqrt(variance / float64(len(values)))
+	estimate.Lower = math.Max(0, estimate.Mean-confidenceZ*estimate.StdError)
+	estimate.Upper = math.Min(1, estimate.Mean+confidenceZ*estimate.StdError)
+	return estimate
+}
+
+// proportionEstimate estimates a success rate with the Wilson score
+// interval, which stays honest for small samples and rates near 0 or 1
+// where the normal approximation collapses to a point
+func proportionEstimate(successes, samples int) Estimate {
+	estimate := Estimate{Lower: 0, Upper: 1, Samples: samples}
+	if samples == 0 {
+		return estimate
+	}
+
+	n := float64(samples)
+	p := float64(successes) / n
+	z2 := confidenceZ * confidenceZ
+	center := (p + z2/(2*n)) / (1 + z2/n)
+	half := confidenceZ * math.Sqrt(p*(1-p)/n+z2/(4*n*n)) / (1 + z2/n)
+
+	estimate.Mean = p
+	estimate.StdError = math.Sqrt(p * (1 - p) / n)
+	estimate.Lower = math.Max(0, center-half)
+	estimate.Upper = math.Min(1, center+half)
+	return estimate
+}
+
+// feedbackBucket gathers the outcomes of one group of feedback records
+type feedbackBucket struct {
+	qualities []float64
+	successes int
+}
+
+// add records one outcome
+func (b *feedbackBucket) add(quality float64, success bool) {
+	b.qualities = append(b.qualities, quality)
+	if success {
+		b.successes++
+	}
+}
+
+// quality estimates the bucket's average context quality
+func (b *feedbackBucket) quality() Estimate {
+	return meanEstimate(b.qualities)
+}
+
+// successRate estimates the bucket's task success rate
+func (b *feedbackBucket) successRate() Estimate {
+	return proportionEstimate(b.successes, len(b.qualities))
+}
+
+// bucketFor returns the bucket for key, creating it on first use
+func bucketFor(buckets map[string]*feedbackBucket, key string) *feedbackBucket {
+	if buckets[key] == nil {
+		buckets[key] = &feedbackBucket{}
+	}
+	return buckets[key]
+}
+
+// sortedEstimateKeys returns the keys of estimates in order, so reports
+// built from them are stable
+func sortedEstimateKeys(estimates map[string]Estimate) []string {
+	keys := make([]string, 0, len(estimates))
+	for key := range estimates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,411 @@
+package context
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feedback types recorded by feedback stores
+const (
+	FeedbackTypeImplicit = "implicit"
+	FeedbackTypeExplicit = "explicit"
+	FeedbackTypeUnknown  = "unknown"
+)
+
+// feedbackIndexFile is the append-only index kept next to the feedback files
+const feedbackIndexFile = "index.jsonl"
+
+// FeedbackIndexEntry describes one stored feedback record
+type FeedbackIndexEntry struct {
+	File      string    `json:"file"`
+	Timestamp time.Time `json:"timestamp"`
+	Type      string    `json:"type"`
+}
+
+// IndexedFeedbackStore is a file-based feedback store that keeps a lightweight
+// index of its records, so queries only read the files they return
+type IndexedFeedbackStore struct {
+	storePath string
+	entries   []FeedbackIndexEntry // Sorted by timestamp
+	loaded    bool
+	now       func() time.Time
+	mutex     sync.Mutex
+}
+
+// NewIndexedFeedbackStore creates a new indexed file-based feedback store
+func NewIndexedFeedbackStore(storePath string) *IndexedFeedbackStore {
+	return &IndexedFeedbackStore{
+		storePath: storePath,
+		now:       time.Now,
+	}
+}
+
+// StoreFeedback stores feedback to a JSON file and records it in the index
+func (s *IndexedFeedbackStore) StoreFeedback(feedback interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.storePath, 0755); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	now := s.now()
+	filename := fmt.Sprintf("feedback_%s_%d.json", now.Format("20060102_150405"), now.UnixNano())
+
+	data, feedbackType, err := encodeFeedback(feedback)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(s.storePath, filename), data, 0644); err != nil {
+		return fmt.Errorf("failed to write feedback file: %w", err)
+	}
+
+	entry := FeedbackIndexEntry{File: filename, Timestamp: now, Type: feedbackType}
+	if err := s.appendIndex(entry); err != nil {
+		return err
+	}
+	s.insertEntry(entry)
+
+	return nil
+}
+
+// GetFeedback retrieves feedback within a time window
+func (s *IndexedFeedbackStore) GetFeedback(timeWindow time.Duration) ([]interface{}, error) {
+	return s.query("", timeWindow)
+}
+
+// GetFeedbackByType retrieves feedback of a specific type within a time window
+func (s *IndexedFeedbackStore) GetFeedbackByType(feedbackType string, timeWindow time.Duration) ([]interface{}, error) {
+	return s.query(feedbackType, timeWindow)
+}
+
+// CleanOldFeedback removes feedback older than retention days
+func (s *IndexedFeedbackStore) CleanOldFeedback(retentionDays int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.ensureLoaded(); err != nil {
+		return err
+	}
+
+	cutoff := s.now().AddDate(0, 0, -retentionDays)
+	first := s.firstAfter(cutoff)
+	for _, entry := range s.entries[:first] {
+		os.Remove(filepath.Join(s.storePath, entry.File)) // Ignore errors for cleanup
+	}
+
+	s.entries = append([]FeedbackIndexEntry(nil), s.entries[first:]...)
+	return s.writeIndex()
+}
+
+// RebuildIndex discards the index and recreates it from the feedback files
+func (s *IndexedFeedbackStore) RebuildIndex() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.rebuildIndex()
+}
+
+// query returns feedback newer than timeWindow, optionally filtered by type
+func (s *IndexedFeedbackStore) query(feedbackType string, timeWindow time.Duration) ([]interface{}, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.ensureLoaded(); err != nil {
+		return nil, err
+	}
+
+	feedback := []interface{}{}
+	for _, entry := range s.entries[s.firstAfter(s.now().Add(-timeWindow)):] {
+		if feedbackType != "" && entry.Type != feedbackType {
+			continue
+		}
+
+		// Files removed behind the index's back are skipped
+		item, _, err := readFeedbackFile(filepath.Join(s.storePath, entry.File))
+		if err != nil {
+			continue
+		}
+		feedback = append(feedback, item)
+	}
+
+	return feedback, nil
+}
+
+// ensureLoaded reads the index on first use, rebuilding it if it is missing
+func (s *IndexedFeedbackStore) ensureLoaded() error {
+	if s.loaded {
+		return nil
+	}
+
+	file, err := os.Open(filepath.Join(s.storePath, feedbackIndexFile))
+	if os.IsNotExist(err) {
+		return s.rebuildIndex()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open feedback index: %w", err)
+	}
+	defer file.Close()
+
+	entries := []FeedbackIndexEntry{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry FeedbackIndexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// A torn or corrupt index can't be trusted
+			return s.rebuildIndex()
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read feedback index: %w", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	s.entries = entries
+	s.loaded = true
+	return nil
+}
+
+// rebuildIndex scans the feedback files and rewrites the index
+func (s *IndexedFeedbackStore) rebuildIndex() error {
+	files, err := filepath.Glob(filepath.Join(s.storePath, "feedback_*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list feedback files: %w", err)
+	}
+
+	entries := make([]FeedbackIndexEntry, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		_, feedbackType, err := readFeedbackFile(file)
+		if err != nil {
+			continue
+		}
+
+		// Prefer the store time encoded in the file name over the mod time
+		timestamp := info.ModTime()
+		if stored, ok := parseFeedbackFileTime(filepath.Base(file)); ok {
+			timestamp = stored
+		}
+
+		entries = append(entries, FeedbackIndexEntry{
+			File:      filepath.Base(file),
+			Timestamp: timestamp,
+			Type:      feedbackType,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+	s.entries = entries
+	s.loaded = true
+
+	if len(files) == 0 {
+		return nil
+	}
+	return s.writeIndex()
+}
+
+// appendIndex appends a single entry to the index file
+func (s *IndexedFeedbackStore) appendIndex(entry FeedbackIndexEntry) error {
+	file, err := os.OpenFile(filepath.Join(s.storePath, feedbackIndexFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open feedback index: %w", err)
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index entry: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write feedback index: %w", err)
+	}
+	return nil
+}
+
+// writeIndex replaces the index file with the in-memory entries
+func (s *IndexedFeedbackStore) writeIndex() error {
+	if err := os.MkdirAll(s.storePath, 0755); err != nil {
+		return fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	var data []byte
+	for _, entry := range s.entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal index entry: %w", err)
+		}
+		data = append(data, line...)
+		data = append(data, '\n')
+	}
+
+	// Write to a temporary file first so a crash never leaves a partial index
+	tmpPath := filepath.Join(s.storePath, feedbackIndexFile+".tmp")
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write feedback index: %w", err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(s.storePath, feedbackIndexFile)); err != nil {
+		return fmt.Errorf("failed to replace feedback index: %w", err)
+	}
+	return nil
+}
+
+// insertEntry adds an entry keeping the index sorted by timestamp
+func (s *IndexedFeedbackStore) insertEntry(entry FeedbackIndexEntry) {
+	i := sort.Search(len(s.entries), func(i int) bool {
+		return s.entries[i].Timestamp.After(entry.Timestamp)
+	})
+	s.entries = append(s.entries, FeedbackIndexEntry{})
+	copy(s.entries[i+1:], s.entries[i:])
+	s.entries[i] = entry
+}
+
+// firstAfter returns the index of the first entry not older than cutoff
+func (s *IndexedFeedbackStore) firstAfter(cutoff time.Time) int {
+	return sort.Search(len(s.entries), func(i int) bool {
+		return !s.entries[i].Timestamp.Before(cutoff)
+	})
+}
+
+// parseFeedbackFileTime extracts the store time from a feedback_<date>_<time>_<nanos>.json name
+func parseFeedbackFileTime(name string) (time.Time, bool) {
+	name = strings.TrimSuffix(name, ".json")
+	sep := strings.LastIndex(name, "_")
+	if sep < 0 {
+		return time.Time{}, false
+	}
+
+	nanos, err := strconv.ParseInt(name[sep+1:], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// feedbackTypeField is the discriminator written into stored feedback records
+const feedbackTypeField = "feedback_type"
+
+// feedbackTypeOf returns the feedback type of a value passed to StoreFeedback
+func feedbackTypeOf(feedback interface{}) string {
+	switch feedback.(type) {
+	case *ContextFeedback, ContextFeedback:
+		return FeedbackTypeImplicit
+	case *ExplicitFeedback, ExplicitFeedback:
+		return FeedbackTypeExplicit
+	default:
+		return FeedbackTypeUnknown
+	}
+}
+
+// inferFeedbackType guesses the feedback type of a record stored without a
+// discriminator from its fields
+func inferFeedbackType(fields map[string]json.RawMessage) string {
+	if _, ok := fields["feedback_id"]; ok {
+		return FeedbackTypeExplicit
+	}
+	if _, ok := fields["quality_score"]; ok {
+		return FeedbackTypeImplicit
+	}
+	return FeedbackTypeUnknown
+}
+
+// encodeFeedback marshals feedback with a discriminator field so it can be
+// decoded back into its concrete type
+func encodeFeedback(feedback interface{}) ([]byte, string, error) {
+	feedbackType := feedbackTypeOf(feedback)
+
+	data, err := json.Marshal(feedback)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal feedback: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		// Not a JSON object, so there's nowhere to put the discriminator
+		indented, err := json.MarshalIndent(feedback, "", "  ")
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal feedback: %w", err)
+		}
+		return indented, feedbackType, nil
+	}
+
+	typeValue, _ := json.Marshal(feedbackType)
+	fields[feedbackTypeField] = typeValue
+
+	data, err = json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal feedback: %w", err)
+	}
+	return data, feedbackType, nil
+}
+
+// decodeFeedback unmarshals a stored record into *ContextFeedback or
+// *ExplicitFeedback. Records of unknown type are returned as generic JSON values.
+func decodeFeedback(data []byte) (interface{}, string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, "", err
+		}
+		return value, FeedbackTypeUnknown, nil
+	}
+
+	var feedbackType string
+	if raw, ok := fields[feedbackTypeField]; ok {
+		json.Unmarshal(raw, &feedbackType)
+	}
+	if feedbackType == "" {
+		feedbackType = inferFeedbackType(fields)
+	}
+
+	switch feedbackType {
+	case FeedbackTypeImplicit:
+		var feedback ContextFeedback
+		if err := json.Unmarshal(data, &feedback); err != nil {
+			return nil, "", err
+		}
+		return &feedback, feedbackType, nil
+	case FeedbackTypeExplicit:
+		var feedback ExplicitFeedback
+		if err := json.Unmarshal(data, &feedback); err != nil {
+			return nil, "", err
+		}
+		return &feedback, feedbackType, nil
+	default:
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, "", err
+		}
+		return value, FeedbackTypeUnknown, nil
+	}
+}
+
+// readFeedbackFile reads and decodes a stored feedback record
+func readFeedbackFile(path string) (interface{}, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return decodeFeedback(data)
+}
@@ -0,0 +1,95 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestIndexedFeedbackStoreFiltering tests type and time window filtering through the index
+func TestIndexedFeedbackStoreFiltering(t *testing.T) {
+	store := NewIndexedFeedbackStore(t.TempDir())
+
+	now := time.Now()
+	store.now = func() time.Time { return now.Add(-48 * time.Hour) }
+	if err := store.StoreFeedback(&ContextFeedback{TaskID: "old", QualityScore: 0.2}); err != nil {
+		t.Fatalf("StoreFeedback failed: %v", err)
+	}
+
+	store.now = func() time.Time { return now }
+	if err := store.StoreFeedback(&ContextFeedback{TaskID: "recent", QualityScore: 0.8}); err != nil {
+		t.Fatalf("StoreFeedback failed: %v", err)
+	}
+	if err := store.StoreFeedback(&ExplicitFeedback{FeedbackID: "fb-1", TaskID: "recent", ContextQuality: 4}); err != nil {
+		t.Fatalf("StoreFeedback failed: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		feedbackType string
+		window       time.Duration
+		expected     int
+	}{
+		{name: "All recent feedback", window: time.Hour, expected: 2},
+		{name: "All feedback", window: 72 * time.Hour, expected: 3},
+		{name: "Recent implicit only", feedbackType: FeedbackTypeImplicit, window: time.Hour, expected: 1},
+		{name: "All implicit", feedbackType: FeedbackTypeImplicit, window: 72 * time.Hour, expected: 2},
+		{name: "Explicit only", feedbackType: FeedbackTypeExplicit, window: 72 * time.Hour, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var feedback []interface{}
+			var err error
+			if tt.feedbackType == "" {
+				feedback, err = store.GetFeedback(tt.window)
+			} else {
+				feedback, err = store.GetFeedbackByType(tt.feedbackType, tt.window)
+			}
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			if len(feedback) != tt.expected {
+				t.Errorf("got %d records, expected %d", len(feedback), tt.expected)
+			}
+		})
+	}
+
+	if err := store.CleanOldFeedback(1); err != nil {
+		t.Fatalf("CleanOldFeedback failed: %v", err)
+	}
+	if all, _ := store.GetFeedback(72 * time.Hour); len(all) != 2 {
+		t.Errorf("got %d records after cleanup, expected 2", len(all))
+	}
+}
+
+// TestIndexedFeedbackStoreRebuild tests that a missing index is rebuilt from the files
+func TestIndexedFeedbackStoreRebuild(t *testing.T) {
+	dir := t.TempDir()
+	store := NewIndexedFeedbackStore(dir)
+
+	if err := store.StoreFeedback(&ContextFeedback{TaskID: "task-1", QualityScore: 0.7}); err != nil {
+		t.Fatalf("StoreFeedback failed: %v", err)
+	}
+	if err := store.StoreFeedback(&ExplicitFeedback{FeedbackID: "fb-1", TaskID: "task-1", ContextQuality: 5}); err != nil {
+		t.Fatalf("StoreFeedback failed: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, feedbackIndexFile)); err != nil {
+		t.Fatalf("failed to remove index: %v", err)
+	}
+
+	reopened := NewIndexedFeedbackStore(dir)
+	explicit, err := reopened.GetFeedbackByType(FeedbackTypeExplicit, time.Hour)
+	if err != nil {
+		t.Fatalf("GetFeedbackByType failed: %v", err)
+	}
+	if len(explicit) != 1 {
+		t.Errorf("got %d explicit records after rebuild, expected 1", len(explicit))
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, feedbackIndexFile)); err != nil {
+		t.Errorf("expected index to be rewritten: %v", err)
+	}
+}
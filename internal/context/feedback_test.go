@@ -0,0 +1,194 @@
+package context
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFeedbackStoresRoundTripTypes tests that stored feedback is read back as
+// concrete types the trend analysis understands
+func TestFeedbackStoresRoundTripTypes(t *testing.T) {
+	stores := map[string]func(dir string) FeedbackStore{
+		"Simple":  func(dir string) FeedbackStore { return NewSimpleFeedbackStore(dir) },
+		"Indexed": func(dir string) FeedbackStore { return NewIndexedFeedbackStore(dir) },
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore(t.TempDir())
+			feedback := &ContextFeedback{
+				TaskID:          "task-1",
+				Task:            &Task{Type: TaskTypeDebug},
+				SelectedContext: &SelectedContext{Strategy: StrategyDependency},
+				TaskSuccess:     true,
+				QualityScore:    0.8,
+				Timestamp:       time.Now(),
+			}
+			if err := store.StoreFeedback(feedback); err != nil {
+				t.Fatalf("StoreFeedback failed: %v", err)
+			}
+			if err := store.StoreFeedback(&ExplicitFeedback{FeedbackID: "fb-1", TaskID: "task-1", ContextQuality: 5}); err != nil {
+				t.Fatalf("StoreFeedback failed: %v", err)
+			}
+
+			items, err := store.GetFeedback(time.Hour)
+			if err != nil {
+				t.Fatalf("GetFeedback failed: %v", err)
+			}
+			implicit, explicit := 0, 0
+			for _, item := range items {
+				switch item.(type) {
+				case *ContextFeedback:
+					implicit++
+				case *ExplicitFeedback:
+					explicit++
+				default:
+					t.Errorf("GetFeedback returned untyped %T", item)
+				}
+			}
+			if implicit != 1 || explicit != 1 {
+				t.Errorf("got %d implicit and %d explicit records, expected 1 of each", implicit, explicit)
+			}
+
+			collector := NewDefaultFeedbackCollector(store, nil, nil)
+			analysis, err := collector.AnalyzeFeedbackTrends(time.Hour)
+			if err != nil {
+				t.Fatalf("AnalyzeFeedbackTrends failed: %v", err)
+			}
+			if analysis.AvgContextQuality <= 0 {
+				t.Errorf("AvgContextQuality = %v, expected non-zero", analysis.AvgContextQuality)
+			}
+			if len(analysis.QualityTrends) != 1 || analysis.QualityTrends[0].Strategy != string(StrategyDependency) {
+				t.Errorf("QualityTrends = %+v, expected one dependency data point", analysis.QualityTrends)
+			}
+
+			summary := collector.GetFeedbackSummary()
+			if summary.ImplicitFeedbackCount != 1 || summary.ExplicitFeedbackCount != 1 {
+				t.Errorf("summary counts = %d implicit, %d explicit, expected 1 each",
+					summary.ImplicitFeedbackCount, summary.ExplicitFeedbackCount)
+			}
+		})
+	}
+}
+
+// TestDecodeFeedbackLegacyRecords tests that records written without a
+// discriminator are still decoded by their fields
+func TestDecodeFeedbackLegacyRecords(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "feedback_20240101_120000_1.json")
+	if err := os.WriteFile(legacy, []byte(`{"task_id": "old", "quality_score": 0.6}`), 0644); err != nil {
+		t.Fatalf("Failed to write legacy record: %v", err)
+	}
+
+	item, feedbackType, err := readFeedbackFile(legacy)
+	if err != nil {
+		t.Fatalf("readFeedbackFile failed: %v", err)
+	}
+	feedback, ok := item.(*ContextFeedback)
+	if !ok || feedbackType != FeedbackTypeImplicit {
+		t.Fatalf("decoded %T (%s), expected *ContextFeedback", item, feedbackType)
+	}
+	if feedback.QualityScore != 0.6 {
+		t.Errorf("QualityScore = %v, expected 0.6", feedback.QualityScore)
+	}
+}
+
+// sliceFeedbackStore serves a fixed set of feedback records
+type sliceFeedbackStore struct {
+	items []interface{}
+}
+
+func (s *sliceFeedbackStore) StoreFeedback(feedback interface{}) error {
+	s.items = append(s.items, feedback)
+	return nil
+}
+
+func (s *sliceFeedbackStore) GetFeedback(timeWindow time.Duration) ([]interface{}, error) {
+	return s.items, nil
+}
+
+func (s *sliceFeedbackStore) GetFeedbackByType(feedbackType string, timeWindow time.Duration) ([]interface{}, error) {
+	return s.items, nil
+}
+
+func (s *sliceFeedbackStore) CleanOldFeedback(retentionDays int) error { return nil }
+
+// TestFeedbackTrendsNeedEnoughSamples tests that analysis figures carry their
+// sample counts and intervals, and that recommendations wait until enough
+// samples back them
+func TestFeedbackTrendsNeedEnoughSamples(t *testing.T) {
+	tests := []struct {
+		samples int
+		strong  bool
+	}{
+		{samples: 3, strong: false},
+		{samples: 50, strong: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d samples", tt.samples), func(t *testing.T) {
+			// Relevance selections go well and dependency selections fail,
+			// with some spread in both
+			store := &sliceFeedbackStore{}
+			for i := 0; i < tt.samples; i++ {
+				feedback := &ContextFeedback{
+					Task:            &Task{Type: TaskTypeDebug},
+					SelectedContext: &SelectedContext{Strategy: StrategyRelevance},
+					TaskSuccess:     true,
+					QualityScore:    0.85 + float64(i%3)*0.05,
+					Timestamp:       time.Now(),
+				}
+				if i%2 == 1 {
+					feedback.SelectedContext.Strategy = StrategyDependency
+					feedback.TaskSuccess = false
+					feedback.QualityScore = 0.15 + float64(i%3)*0.05
+				}
+				store.StoreFeedback(feedback)
+			}
+
+			analysis, err := NewDefaultFeedbackCollector(store, nil, nil).AnalyzeFeedbackTrends(time.Hour)
+			if err != nil {
+				t.Fatalf("AnalyzeFeedbackTrends failed: %v", err)
+			}
+
+			quality := analysis.QualityEstimate
+			if quality.Samples != tt.samples || quality.Mean != analysis.AvgContextQuality {
+				t.Errorf("quality estimate = %+v, expected %d samples around %.2f", quality, tt.samples, analysis.AvgContextQuality)
+			}
+			if quality.Lower > quality.Mean || quality.Upper < quality.Mean || quality.StdError <= 0 {
+				t.Errorf("quality estimate = %+v, expected an interval around the mean", quality)
+			}
+			relevance, dependency := analysis.StrategyEstimates[string(StrategyRelevance)], analysis.StrategyEstimates[string(StrategyDependency)]
+			if relevance.Samples+dependency.Samples != tt.samples {
+				t.Errorf("strategy samples = %d + %d, expected %d", relevance.Samples, dependency.Samples, tt.samples)
+			}
+			if insight := analysis.TaskTypeInsights[string(TaskTypeDebug)]; insight == nil || insight.SampleCount != tt.samples {
+				t.Errorf("debug insight = %+v, expected %d samples", insight, tt.samples)
+			}
+
+			strong := 0
+			insufficient := false
+			for _, recommendation := range analysis.Recommendations {
+				switch {
+				case strings.HasPrefix(recommendation, "Insufficient"):
+					insufficient = true
+				case strings.HasPrefix(recommendation, "Low success rate"),
+					strings.HasPrefix(recommendation, "Strategy 'relevance' outperforms 'dependency'"):
+					strong++
+				default:
+					t.Errorf("unexpected recommendation %q", recommendation)
+				}
+			}
+			if tt.strong && (strong != 2 || insufficient) {
+				t.Errorf("recommendations = %q, expected the success rate and strategy findings", analysis.Recommendations)
+			}
+			if !tt.strong && (strong != 0 || !insufficient) {
+				t.Errorf("recommendations = %q, expected only a request for more samples", analysis.Recommendations)
+			}
+		})
+	}
+}
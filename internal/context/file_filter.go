@@ -0,0 +1,95 @@
+package context
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrBinaryFile is returned when analyzing a file whose content is binary
+// while SkipBinaryFiles is set
+var ErrBinaryFile = errors.New("binary file")
+
+// generatedRelevanceFactor scales the relevance of generated files, which
+// are rarely where a change belongs
+const generatedRelevanceFactor = 0.3
+
+// minifiedLineLength is the line length past which a script or stylesheet is
+// taken to be minified
+const minifiedLineLength = 1000
+
+// generatedSuffixes are file name suffixes of common code generator output
+var generatedSuffixes = []string{
+	".pb.go", ".pb.gw.go", ".gen.go", "_generated.go",
+	"_pb2.py", "_pb2_grpc.py",
+	".min.js", ".min.css", ".bundle.js",
+	".designer.cs", ".g.dart",
+}
+
+// generatedMarker matches the header comments generators leave: Go's
+// convention, the "Code generated by" lines other tools copy, and the tag
+// used by Facebook's tooling
+var generatedMarker = regexp.MustCompile(`(?m)^(// Code generated .* DO NOT EDIT\.|\s*(//|#|/?\*|--)\s*(Code generated by\b|.*\B@generated\b))`)
+
+// isBinaryContent reports whether content looks binary: it has a null byte
+// near the start, or sniffs as a non-text type and isn't valid UTF-8
+func isBinaryContent(content []byte) bool {
+	sample := content
+	if len(sample) > contentSniffLimit {
+		sample = sample[:contentSniffLimit]
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+	if strings.HasPrefix(http.DetectContentType(sample), "text/") {
+		return false
+	}
+	return invalidUTF8(sample, len(sample) < len(content))
+}
+
+// invalidUTF8 reports whether sample has an invalid UTF-8 sequence, other
+// than a rune cut off at its end when the sample was truncated
+func invalidUTF8(sample []byte, truncated bool) bool {
+	for i := 0; i < len(sample); {
+		r, size := utf8.DecodeRune(sample[i:])
+		if r == utf8.RuneError && size == 1 {
+			return !truncated || utf8.FullRune(sample[i:])
+		}
+		i += size
+	}
+	return false
+}
+
+// isGeneratedFile reports whether a file is generator output, by its name, a
+// generator's header comment near the start, or for scripts and stylesheets
+// minified lines
+func isGeneratedFile(filePath string, content []byte) bool {
+	base := strings.ToLower(filepath.Base(filePath))
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(base, suffix) {
+			return true
+		}
+	}
+
+	header := content
+	if len(header) > contentSniffLimit {
+		header = header[:contentSniffLimit]
+	}
+	if generatedMarker.Match(header) {
+		return true
+	}
+
+	switch filepath.Ext(base) {
+	case ".js", ".mjs", ".cjs", ".css":
+		for _, line := range bytes.Split(header, []byte("\n")) {
+			if len(line) > minifiedLineLength {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,285 @@
+package context
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gitCommit is one commit and the files it touched, relative to the project root
+type gitCommit struct {
+	Time  time.Time
+	Files []string
+}
+
+// gitHistory is the part of a project's commit log an analysis needs, newest first
+type gitHistory struct {
+	Head     string
+	Commits  []gitCommit
+	Seen     map[string]bool // Files touched by Commits
+	Since    time.Time       // Every commit from this time on is in Commits
+	Complete bool            // Commits holds the whole log
+	Changed  map[string]bool // Files with uncommitted changes
+}
+
+// gitHistorySource loads the git history of the project at root far enough
+// back to find the last commit of each of files and every commit since the
+// given time, which is zero when no churn is wanted
+type gitHistorySource func(ctx context.Context, root string, files map[string]bool, since time.Time) (*gitHistory, error)
+
+// gitHistoryCache reuses the commit log read for a root until its HEAD moves,
+// so re-analyses only ask git for HEAD and uncommitted changes
+type gitHistoryCache struct {
+	readLog func(ctx context.Context, root string, files map[string]bool, since time.Time) (*gitHistory, error)
+	entries map[string]*gitHistory // By root
+	mutex   sync.Mutex
+}
+
+// newGitHistoryCache creates an empty cache reading logs with git
+func newGitHistoryCache() *gitHistoryCache {
+	return &gitHistoryCache{
+		readLog: readGitLog,
+		entries: make(map[string]*gitHistory),
+	}
+}
+
+// read is a gitHistorySource. Files git doesn't track are left out of the
+// log search, since no commit will ever be found for them.
+func (c *gitHistoryCache) read(ctx context.Context, root string, files map[string]bool, since time.Time) (*gitHistory, error) {
+	head, err := runGit(ctx, root, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	head = strings.TrimSpace(head)
+
+	if len(files) > 0 {
+		tracked, err := runGit(ctx, root, "ls-files", "-z")
+		if err != nil {
+			return nil, err
+		}
+		wanted := make(map[string]bool)
+		for _, name := range strings.Split(tracked, "\x00") {
+			if files[name] {
+				wanted[name] = true
+			}
+		}
+		files = wanted
+	}
+
+	c.mutex.Lock()
+	cached := c.entries[root]
+	c.mutex.Unlock()
+
+	if cached == nil || cached.Head != head || !cached.covers(files, since) {
+		if cached, err = c.readLog(ctx, root, files, since); err != nil {
+			return nil, err
+		}
+		cached.Head = head
+		c.mutex.Lock()
+		c.entries[root] = cached
+		c.mutex.Unlock()
+	}
+
+	changed, err := runGit(ctx, root, "diff", "--relative", "--name-only", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	history := *cached
+	history.Changed = make(map[string]bool)
+	for _, name := range strings.Split(changed, "\n") {
+		if name = strings.TrimSpace(name); name != "" {
+			history.Changed[name] = true
+		}
+	}
+	return &history, nil
+}
+
+// covers reports whether the history holds the last commit of each of files
+// and every commit since the given time
+func (h *gitHistory) covers(files map[string]bool, since time.Time) bool {
+	if h.Complete {
+		return true
+	}
+	if !since.IsZero() && since.Before(h.Since) {
+		return false
+	}
+	for name := range files {
+		if !h.Seen[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// readGitLog reads the log of files under root, newest first, with paths as
+// slash-separated paths relative to root. Reading stops at the first commit
+// older than since once every one of files has been seen, and git stops at
+// since by itself when no files are wanted.
+func readGitLog(ctx context.Context, root string, files map[string]bool, since time.Time) (*gitHistory, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	args := []string{"-C", root, "log", "--relative", "--no-renames", "--name-only", "--format=%x00%ct"}
+	if len(files) == 0 && !since.IsZero() {
+		args = append(args, "--since="+since.Format(time.RFC3339))
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run git log: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run git log: %w", err)
+	}
+
+	history := &gitHistory{Seen: make(map[string]bool), Since: since}
+	remaining := len(files)
+	stopped := false
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "\x00") {
+			if name := strings.TrimSpace(line); name != "" && len(history.Commits) > 0 {
+				commit := &history.Commits[len(history.Commits)-1]
+				commit.Files = append(commit.Files, name)
+				if files[name] && !history.Seen[name] {
+					remaining--
+				}
+				history.Seen[name] = true
+			}
+			continue
+		}
+
+		seconds, err := strconv.ParseInt(strings.TrimSpace(line[1:]), 10, 64)
+		if err != nil {
+			continue
+		}
+		commitTime := time.Unix(seconds, 0)
+		inWindow := !since.IsZero() && !commitTime.Before(since)
+		if remaining <= 0 && !inWindow {
+			stopped = true
+			break
+		}
+		history.Commits = append(history.Commits, gitCommit{Time: commitTime})
+	}
+
+	if stopped {
+		// Without a churn window, the history reaches back to its oldest commit
+		if since.IsZero() && len(history.Commits) > 0 {
+			history.Since = history.Commits[len(history.Commits)-1].Time
+		}
+		cancel()
+		cmd.Wait()
+		return history, nil
+	}
+	if err := scanner.Err(); err != nil {
+		cmd.Wait()
+		return nil, fmt.Errorf("failed to read git log: %w", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("failed to run git log: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	// A log cut off by --since is missing the older commits
+	history.Complete = len(files) > 0 || since.IsZero()
+	return history, nil
+}
+
+// LastCommitTimes returns the time of the most recent commit touching each
+// file. Files with uncommitted changes are left out so callers fall back to
+// their modification time.
+func (h *gitHistory) LastCommitTimes() map[string]time.Time {
+	times := make(map[string]time.Time)
+	for _, commit := range h.Commits {
+		for _, name := range commit.Files {
+			if _, seen := times[name]; !seen && !h.Changed[name] {
+				times[name] = commit.Time
+			}
+		}
+	}
+	return times
+}
+
+// Churn returns the number of commits touching each file since the given time
+func (h *gitHistory) Churn(since time.Time) map[string]int {
+	churn := make(map[string]int)
+	for _, commit := range h.Commits {
+		if commit.Time.Before(since) {
+			continue
+		}
+		for _, name := range commit.Files {
+			churn[name]++
+		}
+	}
+	return churn
+}
+
+// applyGitHistory sets LastCommit and Churn on files tracked by git, as
+// enabled in the config. Files are left untouched when root isn't in a git
+// repository or git isn't installed.
+func (a *DefaultAnalyzer) applyGitHistory(ctx context.Context, root string, files []FileInfo) {
+	if !a.config.EnableGitFreshness && a.config.ChurnWindowDays <= 0 {
+		return
+	}
+
+	relPaths := make([]string, len(files))
+	wanted := make(map[string]bool)
+	for i := range files {
+		if rel, err := filepath.Rel(root, files[i].Path); err == nil {
+			relPaths[i] = filepath.ToSlash(rel)
+			if a.config.EnableGitFreshness {
+				wanted[relPaths[i]] = true
+			}
+		}
+	}
+	var since time.Time
+	if a.config.ChurnWindowDays > 0 {
+		since = time.Now().AddDate(0, 0, -a.config.ChurnWindowDays)
+	}
+
+	history, err := a.gitHistory(ctx, root, wanted, since)
+	if err != nil {
+		return
+	}
+
+	var commitTimes map[string]time.Time
+	if a.config.EnableGitFreshness {
+		commitTimes = history.LastCommitTimes()
+	}
+	var churn map[string]int
+	if !since.IsZero() {
+		churn = history.Churn(since)
+	}
+
+	for i := range files {
+		rel := relPaths[i]
+		if rel == "" {
+			continue
+		}
+		if commitTime, exists := commitTimes[rel]; exists {
+			files[i].LastCommit = commitTime
+		}
+		files[i].Churn = churn[rel]
+	}
+}
+
+// runGit runs a git subcommand in dir and returns its standard output
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(output), nil
+}
@@ -0,0 +1,105 @@
+package context
+
+import (
+	"strings"
+	"unicode"
+)
+
+// keywordStopWords are the words of a task description that say nothing about
+// which files it needs: function words and the verbs every task starts with
+var keywordStopWords = map[string]bool{
+	"the": true, "and": true, "but": true, "for": true, "with": true, "from": true,
+	"was": true, "are": true, "were": true, "been": true, "have": true, "has": true,
+	"had": true, "does": true, "did": true, "will": true, "would": true, "should": true,
+	"could": true, "may": true, "might": true, "must": true, "can": true, "this": true,
+	"that": true, "these": true, "those": true, "you": true, "she": true, "they": true,
+	"not": true, "all": true, "any": true, "some": true, "into": true, "when": true,
+	"then": true, "than": true, "there": true, "its": true, "our": true, "your": true,
+	"their": true, "also": true, "out": true, "more": true, "less": true, "very": true,
+	"please": true, "fix": true, "add": true, "make": true, "update": true,
+	"implement": true, "change": true, "improve": true, "use": true, "using": true,
+	"new": true,
+}
+
+// ExtractKeywords returns the keywords of a task description for relevance
+// scoring, after the explicit keywords given, without repeats. Identifiers
+// are kept whole and also split into their camelCase and snake_case parts;
+// stop words and words under three characters are dropped.
+func ExtractKeywords(description string, explicit []string) []string {
+	seen := make(map[string]bool)
+	var keywords []string
+	add := func(word string) {
+		word = strings.ToLower(word)
+		if len(word) < 3 || keywordStopWords[word] || seen[word] || isNumber(word) {
+			return
+		}
+		seen[word] = true
+		keywords = append(keywords, word)
+	}
+
+	for _, keyword := range explicit {
+		if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" && !seen[keyword] {
+			seen[keyword] = true
+			keywords = append(keywords, keyword)
+		}
+	}
+
+	tokens := strings.FieldsFunc(description, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for _, token := range tokens {
+		token = strings.Trim(token, "_")
+		add(token)
+		if parts := splitIdentifier(token); len(parts) > 1 {
+			for _, part := range parts {
+				add(part)
+			}
+		}
+	}
+	return keywords
+}
+
+// withKeywords returns task with keywords extracted from its description when
+// it has none, leaving the caller's task unchanged
+func withKeywords(task *Task) *Task {
+	if task == nil || len(task.Keywords) > 0 {
+		return task
+	}
+	extracted := *task
+	extracted.Keywords = ExtractKeywords(task.Description, nil)
+	return &extracted
+}
+
+// splitIdentifier splits an identifier at underscores and camelCase
+// boundaries, keeping acronyms together: parseHTTPRequest becomes parse,
+// HTTP and Request
+func splitIdentifier(identifier string) []string {
+	var parts []string
+	for _, word := range strings.Split(identifier, "_") {
+		runes := []rune(word)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+			acronymEnd := unicode.IsUpper(runes[i-1]) && unicode.IsUpper(runes[i]) &&
+				i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if lowerToUpper || acronymEnd {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, string(runes[start:]))
+		}
+	}
+	return parts
+}
+
+// isNumber reports whether a word is all digits
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package context
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// TestExtractKeywords tests that stop words are dropped, identifiers are
+// split into their parts and explicit keywords come first
+func TestExtractKeywords(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		explicit    []string
+		want        []string
+	}{
+		{
+			name:        "plain description",
+			description: "Fix memory leak in request handler",
+			want:        []string{"memory", "leak", "request", "handler"},
+		},
+		{
+			name:        "identifiers",
+			description: "Speed up parseHTTPRequest in token_counter.go",
+			want:        []string{"speed", "parsehttprequest", "parse", "http", "request", "token_counter", "token", "counter"},
+		},
+		{
+			name:        "explicit keywords merged",
+			description: "Handle the session expiry",
+			explicit:    []string{"Session", "auth"},
+			want:        []string{"session", "auth", "handle", "expiry"},
+		},
+		{
+			name:        "nothing to keep",
+			description: "Fix it for 2024",
+			want:        nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractKeywords(tt.description, tt.explicit)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractKeywords(%q) = %v, want %v", tt.description, got, tt.want)
+			}
+		})
+	}
+}
+
+// keywordRecorder is a relevance scorer that records the keywords of every task it scores for
+type keywordRecorder struct {
+	*SemanticRelevanceScorer
+	keywords [][]string
+}
+
+func (r *keywordRecorder) ScoreFile(file *FileInfo, task *Task) float64 {
+	r.keywords = append(r.keywords, task.Keywords)
+	return 0.5
+}
+
+// TestSelectionScoresWithTaskKeywords tests that the scorer sees the task's own
+// keywords, or those extracted from its description once per selection
+func TestSelectionScoresWithTaskKeywords(t *testing.T) {
+	tests := []struct {
+		name     string
+		task     *Task
+		expected []string
+	}{
+		{
+			name:     "explicit keywords",
+			task:     &Task{Type: TaskTypeDebug, Description: "fix the totals", Keywords: []string{"invoice"}},
+			expected: []string{"invoice"},
+		},
+		{
+			name:     "extracted from the description",
+			task:     &Task{Type: TaskTypeDebug, Description: "fix invoice totals"},
+			expected: []string{"invoice", "totals"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &keywordRecorder{SemanticRelevanceScorer: NewSemanticRelevanceScorer(nil)}
+			analyzer := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil)
+			analyzer.SetRelevanceScorer(recorder)
+			optimizer := NewDefaultOptimizer(analyzer, nil, nil, nil)
+			project := newTestProject(map[string]int{"/project/a.go": 100, "/project/b.go": 100})
+			constraints := &ContextConstraints{MaxTokens: 1000, MaxFiles: 10, MinRelevanceScore: 0.1, Strategy: StrategyRelevance}
+
+			if _, err := optimizer.SelectOptimalContext(context.Background(), project, tt.task, constraints); err != nil {
+				t.Fatalf("SelectOptimalContext failed: %v", err)
+			}
+			if len(recorder.keywords) == 0 {
+				t.Fatal("no files were scored")
+			}
+			for _, keywords := range recorder.keywords {
+				if !reflect.DeepEqual(keywords, tt.expected) {
+					t.Fatalf("scored with keywords %v, expected %v", keywords, tt.expected)
+				}
+				if &keywords[0] != &recorder.keywords[0][0] {
+					t.Fatal("keywords were extracted again for a later file")
+				}
+			}
+		})
+	}
+}
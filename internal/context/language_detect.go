@@ -0,0 +1,145 @@
+package context
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+)
+
+// contentSniffLimit bounds how much of a file signature matching looks at
+const contentSniffLimit = 4096
+
+// fileDetection is a file type and language pair
+type fileDetection struct {
+	fileType string
+	language string
+}
+
+// knownFilenames maps conventional extensionless or special file names to
+// their type and language
+var knownFilenames = map[string]fileDetection{
+	"Dockerfile":       {"configuration", "dockerfile"},
+	"Containerfile":    {"configuration", "dockerfile"},
+	"Makefile":         {"script", "makefile"},
+	"makefile":         {"script", "makefile"},
+	"GNUmakefile":      {"script", "makefile"},
+	"Jenkinsfile":      {"script", "groovy"},
+	"Vagrantfile":      {"configuration", "ruby"},
+	"Gemfile":          {"configuration", "ruby"},
+	"Rakefile":         {"script", "ruby"},
+	"Procfile":         {"configuration", "procfile"},
+	"go.mod":           {"configuration", "go-module"},
+	"go.sum":           {"configuration", "go-module"},
+	"go.work":          {"configuration", "go-module"},
+	".gitignore":       {"configuration", "ignore"},
+	".dockerignore":    {"configuration", "ignore"},
+	".editorconfig":    {"configuration", "ini"},
+	"README":           {"documentation", "text"},
+	"LICENSE":          {"documentation", "text"},
+	"CHANGELOG":        {"documentation", "text"},
+	"CODEOWNERS":       {"configuration", "codeowners"},
+	"requirements.txt": {"configuration", "pip-requirements"},
+}
+
+// shebangInterpreters maps interpreter names from a #! line to languages
+var shebangInterpreters = map[string]string{
+	"python":  "python",
+	"node":    "javascript",
+	"deno":    "typescript",
+	"bun":     "javascript",
+	"ts-node": "typescript",
+	"bash":    "shell",
+	"sh":      "shell",
+	"zsh":     "shell",
+	"dash":    "shell",
+	"ksh":     "shell",
+	"ruby":    "ruby",
+	"perl":    "perl",
+	"php":     "php",
+}
+
+// detectKnownFilename identifies files whose names alone say what they are
+func detectKnownFilename(filePath string) (fileDetection, bool) {
+	name := filepath.Base(filePath)
+	if detection, exists := knownFilenames[name]; exists {
+		return detection, true
+	}
+	if strings.HasPrefix(name, "Dockerfile.") || strings.HasSuffix(name, ".Dockerfile") {
+		return knownFilenames["Dockerfile"], true
+	}
+	return fileDetection{}, false
+}
+
+// detectFromContent identifies a file by a shebang line or, failing that, by
+// simple signatures. It reports false when nothing matched.
+func detectFromContent(content []byte) (fileDetection, bool) {
+	if len(content) > contentSniffLimit {
+		content = content[:contentSniffLimit]
+	}
+
+	if language := shebangLanguage(content); language != "" {
+		return fileDetection{fileType: "script", language: language}, true
+	}
+	return detectSignature(content)
+}
+
+// shebangLanguage returns the language named by a #! line, following
+// /usr/bin/env to the actual interpreter and dropping version suffixes
+func shebangLanguage(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+	line := string(content[2:])
+	if end := strings.IndexByte(line, '\n'); end != -1 {
+		line = line[:end]
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			// Skip env's own flags and variable assignments, e.g. "env -S" or "env FOO=1"
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = filepath.Base(field)
+				break
+			}
+		}
+	}
+
+	// python3.11 -> python
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	return shebangInterpreters[interpreter]
+}
+
+// detectSignature recognizes a few formats by how their content begins
+func detectSignature(content []byte) (fileDetection, bool) {
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case len(trimmed) == 0:
+		return fileDetection{}, false
+	case bytes.HasPrefix(trimmed, []byte("<?php")):
+		return fileDetection{fileType: "source", language: "php"}, true
+	case bytes.HasPrefix(trimmed, []byte("<?xml")):
+		return fileDetection{fileType: "configuration", language: "xml"}, true
+	case (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed):
+		return fileDetection{fileType: "configuration", language: "json"}, true
+	}
+
+	// Go files open with a package clause, possibly after comments and build tags
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if strings.HasPrefix(line, "package ") && len(strings.Fields(line)) == 2 {
+			return fileDetection{fileType: "source", language: "go"}, true
+		}
+		break
+	}
+	return fileDetection{}, false
+}
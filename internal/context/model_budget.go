@@ -0,0 +1,140 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultResponseReserve is the share of a model's context window left for
+// the response when no reserve is given
+const defaultResponseReserve = 0.25
+
+// ModelProfile describes a model's context window and tokenizer
+type ModelProfile struct {
+	Name          string `json:"name"`
+	ContextWindow int    `json:"context_window"`     // Total tokens of prompt and response
+	Encoding      string `json:"encoding,omitempty"` // Token encoding, see EncodingCounter
+}
+
+// ModelBudget asks for the context budget that fits a model with room for a
+// response
+type ModelBudget struct {
+	Model           string `json:"model"`
+	ResponseReserve int    `json:"response_reserve"` // Tokens kept free for the response; 0 reserves a quarter of the window
+}
+
+// ModelRegistry maps model names to their context windows
+type ModelRegistry struct {
+	models map[string]ModelProfile
+	mutex  sync.RWMutex
+}
+
+// NewModelRegistry creates a registry of well-known models
+func NewModelRegistry() *ModelRegistry {
+	registry := &ModelRegistry{models: make(map[string]ModelProfile)}
+	for _, profile := range []ModelProfile{
+		{Name: "gpt-4o", ContextWindow: 128000, Encoding: EncodingO200K},
+		{Name: "gpt-4o-mini", ContextWindow: 128000, Encoding: EncodingO200K},
+		{Name: "gpt-4", ContextWindow: 8192, Encoding: EncodingCL100K},
+		{Name: "gpt-4-turbo", ContextWindow: 128000, Encoding: EncodingCL100K},
+		{Name: "claude-3.5-sonnet", ContextWindow: 200000, Encoding: EncodingClaude},
+		{Name: "claude-3.5-haiku", ContextWindow: 200000, Encoding: EncodingClaude},
+		{Name: "claude-3-opus", ContextWindow: 200000, Encoding: EncodingClaude},
+		{Name: "gemini-1.5-pro", ContextWindow: 2000000, Encoding: EncodingGemini},
+		{Name: "gemini-1.5-flash", ContextWindow: 1000000, Encoding: EncodingGemini},
+	} {
+		registry.Register(profile)
+	}
+	return registry
+}
+
+// Register adds a model or overrides a known one
+func (r *ModelRegistry) Register(profile ModelProfile) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.models[normalizeModelName(profile.Name)] = profile
+}
+
+// Lookup finds a model by name. Versioned names such as
+// "claude-3-5-sonnet-20241022" match the longest registered name they start with.
+func (r *ModelRegistry) Lookup(name string) (ModelProfile, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	normalized := normalizeModelName(name)
+	if profile, exists := r.models[normalized]; exists {
+		return profile, true
+	}
+
+	var best string
+	for known := range r.models {
+		if strings.HasPrefix(normalized, known+"-") && len(known) > len(best) {
+			best = known
+		}
+	}
+	if best == "" {
+		return ModelProfile{}, false
+	}
+	return r.models[best], true
+}
+
+// Models returns the registered models sorted by name
+func (r *ModelRegistry) Models() []ModelProfile {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	profiles := make([]ModelProfile, 0, len(r.models))
+	for _, profile := range r.models {
+		profiles = append(profiles, profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles
+}
+
+// AvailableBudget returns the tokens left for context in the model's window
+// after the response reserve
+func (r *ModelRegistry) AvailableBudget(budget ModelBudget) (int, error) {
+	profile, exists := r.Lookup(budget.Model)
+	if !exists {
+		return 0, fmt.Errorf("unknown model: %s", budget.Model)
+	}
+	return ContextBudget(profile.ContextWindow, budget.ResponseReserve)
+}
+
+// ContextBudget returns the tokens left for context in a window after
+// reserving tokens for the response; a reserve of 0 keeps a quarter free
+func ContextBudget(contextWindow, responseReserve int) (int, error) {
+	if responseReserve == 0 {
+		responseReserve = int(float64(contextWindow) * defaultResponseReserve)
+	}
+	if responseReserve < 0 || responseReserve >= contextWindow {
+		return 0, fmt.Errorf("response reserve %d doesn't fit a %d token context window", responseReserve, contextWindow)
+	}
+	return contextWindow - responseReserve, nil
+}
+
+// OptimizeForModel optimizes context to fit the budget a model leaves after
+// its response reserve
+func OptimizeForModel(ctx context.Context, optimizer ContextOptimizer, registry *ModelRegistry, project *ProjectContext, budget ModelBudget, task *Task) (*SelectedContext, error) {
+	tokenBudget, err := registry.AvailableBudget(budget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute context budget: %w", err)
+	}
+	return optimizer.OptimizeForTokenBudget(ctx, project, tokenBudget, task)
+}
+
+// normalizeModelName lowercases a model name and writes version dots as
+// providers' API names use dashes, so "Claude-3-5-Sonnet" finds "claude-3.5-sonnet"
+func normalizeModelName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, version := range []string{"3-5", "1-5"} {
+		name = strings.ReplaceAll(name, "-"+version+"-", "-"+strings.Replace(version, "-", ".", 1)+"-")
+		if strings.HasSuffix(name, "-"+version) {
+			name = strings.TrimSuffix(name, "-"+version) + "-" + strings.Replace(version, "-", ".", 1)
+		}
+	}
+	return name
+}
@@ -0,0 +1,61 @@
+package context
+
+import (
+	"testing"
+)
+
+// TestModelRegistryAvailableBudget tests window lookups, including versioned
+// and dashed API names, overrides, and the response reserve
+func TestModelRegistryAvailableBudget(t *testing.T) {
+	registry := NewModelRegistry()
+	registry.Register(ModelProfile{Name: "local-llama", ContextWindow: 8192})
+	registry.Register(ModelProfile{Name: "gpt-4o", ContextWindow: 64000})
+
+	tests := []struct {
+		name     string
+		budget   ModelBudget
+		expected int
+		wantErr  bool
+	}{
+		{name: "explicit reserve", budget: ModelBudget{Model: "claude-3.5-sonnet", ResponseReserve: 8000}, expected: 192000},
+		{name: "default reserve", budget: ModelBudget{Model: "gemini-1.5-flash"}, expected: 750000},
+		{name: "versioned API name", budget: ModelBudget{Model: "Claude-3-5-Sonnet-20241022", ResponseReserve: 4000}, expected: 196000},
+		{name: "longest prefix wins", budget: ModelBudget{Model: "gpt-4o-mini-2024-07-18", ResponseReserve: 28000}, expected: 100000},
+		{name: "override", budget: ModelBudget{Model: "gpt-4o", ResponseReserve: 4000}, expected: 60000},
+		{name: "custom model", budget: ModelBudget{Model: "local-llama", ResponseReserve: 2048}, expected: 6144},
+		{name: "unknown model", budget: ModelBudget{Model: "mystery-1"}, wantErr: true},
+		{name: "reserve exceeds window", budget: ModelBudget{Model: "local-llama", ResponseReserve: 9000}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registry.AvailableBudget(tt.budget)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AvailableBudget error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("AvailableBudget = %d, expected %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestPredictOptimalBudgetCeiling tests that predictions never exceed the
+// configured ceiling
+func TestPredictOptimalBudgetCeiling(t *testing.T) {
+	largeProject := &ProjectContext{TotalTokens: 500000}
+	task := &Task{Type: TaskTypeFeature}
+
+	manager := NewDefaultAdaptiveManager(nil, nil, nil, nil)
+	if got := manager.PredictOptimalBudget(task, largeProject); got != 12000 {
+		t.Fatalf("uncapped prediction = %d, expected 12000", got)
+	}
+
+	manager.config.BudgetCeiling = 6144
+	if got := manager.PredictOptimalBudget(task, largeProject); got != 6144 {
+		t.Errorf("capped prediction = %d, expected 6144", got)
+	}
+	if got := manager.PredictOptimalBudget(task, &ProjectContext{TotalTokens: 1000}); got != 4000 {
+		t.Errorf("prediction under the ceiling = %d, expected 4000", got)
+	}
+}
@@ -0,0 +1,194 @@
+package context
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Token encodings of the registered models
+const (
+	EncodingCL100K = "cl100k_base" // GPT-4 and GPT-4 Turbo
+	EncodingO200K  = "o200k_base"  // GPT-4o family
+	EncodingClaude = "claude"
+	EncodingGemini = "gemini"
+)
+
+// ModelTokenCounter is a TokenCounter that can also count in a particular
+// model's encoding
+type ModelTokenCounter interface {
+	TokenCounter
+	CountTokensForModel(content, model string) (int, error)
+}
+
+// ModelTokenCount is the token count of some content for one model
+type ModelTokenCount struct {
+	Model    string `json:"model"`
+	Encoding string `json:"encoding"` // Empty when the base estimate was used
+	Tokens   int    `json:"tokens"`
+	Warning  string `json:"warning,omitempty"` // Why the base estimate stood in for the model's encoding
+}
+
+// EncodingCounter converts a base counter's estimate, which tracks
+// cl100k_base, to other encodings using their typical token density relative
+// to it. Models without a known encoding get the base estimate.
+type EncodingCounter struct {
+	base     TokenCounter
+	registry *ModelRegistry
+	scales   map[string]float64
+	mutex    sync.RWMutex
+}
+
+// Ensure EncodingCounter implements ModelTokenCounter interface
+var _ ModelTokenCounter = (*EncodingCounter)(nil)
+
+// NewEncodingCounter creates a counter for the models in registry, using the
+// simple estimate and the default registry when base or registry is nil
+func NewEncodingCounter(base TokenCounter, registry *ModelRegistry) *EncodingCounter {
+	if base == nil {
+		base = NewSimpleTokenCounter()
+	}
+	if registry == nil {
+		registry = NewModelRegistry()
+	}
+	return &EncodingCounter{
+		base:     base,
+		registry: registry,
+		scales: map[string]float64{
+			EncodingCL100K: 1.0,
+			EncodingO200K:  0.9,  // Larger vocabulary merges more identifiers
+			EncodingClaude: 1.1,  // Splits code more finely
+			EncodingGemini: 0.95, // SentencePiece vocabulary, slightly denser on code
+		},
+	}
+}
+
+// SetEncodingScale calibrates an encoding, or adds one, as its token count
+// relative to the base estimate
+func (c *EncodingCounter) SetEncodingScale(encoding string, scale float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.scales[encoding] = scale
+}
+
+// CountTokens returns the base estimate
+func (c *EncodingCounter) CountTokens(content string) (int, error) {
+	return c.base.CountTokens(content)
+}
+
+// CountFile returns the base estimate for a file
+func (c *EncodingCounter) CountFile(filePath string) (int, error) {
+	return c.base.CountFile(filePath)
+}
+
+// CountTokensForModel counts content in the model's encoding
+func (c *EncodingCounter) CountTokensForModel(content, model string) (int, error) {
+	count, err := c.CountForModel(content, model)
+	if err != nil {
+		return 0, err
+	}
+	return count.Tokens, nil
+}
+
+// CountForModel counts content in the model's encoding, falling back to the
+// base estimate with a warning when the model or its encoding is unknown
+func (c *EncodingCounter) CountForModel(content, model string) (ModelTokenCount, error) {
+	tokens, err := c.base.CountTokens(content)
+	if err != nil {
+		return ModelTokenCount{}, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	count := ModelTokenCount{Model: model, Tokens: tokens}
+
+	profile, exists := c.registry.Lookup(model)
+	if !exists {
+		count.Warning = fmt.Sprintf("unknown model %s, using the default estimate", model)
+		return count, nil
+	}
+
+	c.mutex.RLock()
+	scale, known := c.scales[profile.Encoding]
+	c.mutex.RUnlock()
+	if !known {
+		count.Warning = fmt.Sprintf("no encoding available for model %s, using the default estimate", model)
+		return count, nil
+	}
+
+	count.Encoding = profile.Encoding
+	count.Tokens = int(float64(tokens)*scale + 0.5)
+	return count, nil
+}
+
+// MultiModelCount is the token count of some content for several models
+type MultiModelCount struct {
+	Counts    []ModelTokenCount `json:"counts"`     // In the order the models were given
+	WorstCase ModelTokenCount   `json:"worst_case"` // The model needing the most tokens
+}
+
+// Warnings lists the fallback warnings of the counts
+func (m *MultiModelCount) Warnings() []string {
+	var warnings []string
+	for _, count := range m.Counts {
+		if count.Warning != "" {
+			warnings = append(warnings, count.Warning)
+		}
+	}
+	return warnings
+}
+
+// MultiModelCounter counts tokens for several target models at once. As a
+// TokenCounter it reports the worst case, so an analyzer built on it sizes
+// files for the model that needs the most tokens and selections fit every
+// target.
+type MultiModelCounter struct {
+	counter *EncodingCounter
+	models  []string
+}
+
+// Ensure MultiModelCounter implements TokenCounter interface
+var _ TokenCounter = (*MultiModelCounter)(nil)
+
+// NewMultiModelCounter creates a counter for the given models
+func NewMultiModelCounter(counter *EncodingCounter, models []string) *MultiModelCounter {
+	if counter == nil {
+		counter = NewEncodingCounter(nil, nil)
+	}
+	return &MultiModelCounter{counter: counter, models: models}
+}
+
+// Count counts content for every model
+func (m *MultiModelCounter) Count(content string) (*MultiModelCount, error) {
+	result := &MultiModelCount{Counts: make([]ModelTokenCount, 0, len(m.models))}
+	for _, model := range m.models {
+		count, err := m.counter.CountForModel(content, model)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count tokens for %s: %w", model, err)
+		}
+		result.Counts = append(result.Counts, count)
+		if len(result.Counts) == 1 || count.Tokens > result.WorstCase.Tokens {
+			result.WorstCase = count
+		}
+	}
+	return result, nil
+}
+
+// CountTokens returns the worst-case count across the models, or the base
+// estimate when there are none
+func (m *MultiModelCounter) CountTokens(content string) (int, error) {
+	if len(m.models) == 0 {
+		return m.counter.CountTokens(content)
+	}
+	count, err := m.Count(content)
+	if err != nil {
+		return 0, err
+	}
+	return count.WorstCase.Tokens, nil
+}
+
+// CountFile returns the worst-case count for a file
+func (m *MultiModelCounter) CountFile(filePath string) (int, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+	return m.CountTokens(string(content))
+}
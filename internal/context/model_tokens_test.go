@@ -0,0 +1,67 @@
+package context
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestEncodingCounterAcrossModels tests that the same text counts
+// differently in two encodings and that unknown models fall back with a warning
+func TestEncodingCounterAcrossModels(t *testing.T) {
+	content := strings.Repeat("func handleRequest(ctx context.Context, req *Request) error { return nil }\n", 20)
+	base, _ := NewSimpleTokenCounter().CountTokens(content)
+
+	registry := NewModelRegistry()
+	registry.Register(ModelProfile{Name: "local-llama", ContextWindow: 8192, Encoding: "llama"})
+	counter := NewEncodingCounter(nil, registry)
+
+	tests := []struct {
+		name     string
+		model    string
+		encoding string
+		expected int
+		warning  bool
+	}{
+		{name: "cl100k_base", model: "gpt-4-0613", encoding: EncodingCL100K, expected: base},
+		{name: "o200k_base", model: "gpt-4o-2024-08-06", encoding: EncodingO200K, expected: int(float64(base)*0.9 + 0.5)},
+		{name: "unknown model", model: "mystery-1", expected: base, warning: true},
+		{name: "unavailable encoding", model: "local-llama", expected: base, warning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := counter.CountForModel(content, tt.model)
+			if err != nil {
+				t.Fatalf("CountForModel failed: %v", err)
+			}
+			if count.Tokens != tt.expected || count.Encoding != tt.encoding {
+				t.Errorf("count = %+v, expected %d tokens in %q", count, tt.expected, tt.encoding)
+			}
+			if (count.Warning != "") != tt.warning {
+				t.Errorf("warning = %q, expected warning %v", count.Warning, tt.warning)
+			}
+		})
+	}
+
+	gpt4, _ := counter.CountTokensForModel(content, "gpt-4")
+	gpt4o, _ := counter.CountTokensForModel(content, "gpt-4o")
+	if gpt4o >= gpt4 {
+		t.Errorf("gpt-4o count %d, expected fewer tokens than gpt-4's %d", gpt4o, gpt4)
+	}
+
+	// Selections sized by the multi-model counter fit the hungriest model
+	multi := NewMultiModelCounter(counter, []string{"gpt-4o", "claude-3-5-sonnet-20241022", "mystery-1"})
+	result, err := multi.Count(content)
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if result.WorstCase.Model != "claude-3-5-sonnet-20241022" || len(result.Counts) != 3 {
+		t.Errorf("worst case = %+v across %d counts, expected claude", result.WorstCase, len(result.Counts))
+	}
+	if warnings := result.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "mystery-1") {
+		t.Errorf("warnings = %v, expected the unknown model", warnings)
+	}
+	if tokens, _ := multi.CountTokens(content); tokens != result.WorstCase.Tokens {
+		t.Errorf("CountTokens = %d, expected the worst case %d", tokens, result.WorstCase.Tokens)
+	}
+}
@@ -2,8 +2,12 @@ package context
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -25,10 +29,14 @@ type ContextOptimizer interface {
 	
 	// OptimizeForTokenBudget optimizes context to fit within token budget
 	OptimizeForTokenBudget(ctx context.Context, project *ProjectContext, tokenBudget int, task *Task) (*SelectedContext, error)
+	
+	// SelectAlternatives returns up to n distinct selections within the budget, best first
+	SelectAlternatives(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints, n int) ([]*SelectedContext, error)
 }
 
 // Task represents a coding task with context requirements
 type Task struct {
+	ID          string    `json:"id,omitempty"` // Caller's identifier, kept when a task is retried
 	Type        TaskType  `json:"type"`
 	Description string    `json:"description"`
 	Priority    Priority  `json:"priority"`
@@ -65,11 +73,36 @@ type ContextConstraints struct {
 	MinRelevanceScore float64              `json:"min_relevance_score"`
 	PreferredTypes   []string              `json:"preferred_types"`
 	ExcludedPatterns []string              `json:"excluded_patterns"`
+	DeniedExtensions []string              `json:"denied_extensions"` // nil uses DefaultDeniedExtensions, empty denies nothing
 	IncludeTests     bool                   `json:"include_tests"`
 	IncludeDocs      bool                   `json:"include_docs"`
 	FreshnessBias    float64               `json:"freshness_bias"` // 0-1, prefer recently modified files
+	ChurnBias        float64               `json:"churn_bias"`     // 0-1, prefer frequently changed files for debug tasks
+	DiversityWeight  float64               `json:"diversity_weight"` // 0-1, penalize files similar to higher ranked ones (MMR)
 	DependencyDepth  int                   `json:"dependency_depth"` // How deep to follow dependencies
 	Strategy         SelectionStrategy     `json:"strategy"`
+	PackingMode      PackingMode           `json:"packing_mode,omitempty"` // How ranked files are fit into the budget
+	BalancedWeights  *BalancedWeights      `json:"balanced_weights,omitempty"` // Signal weights of the balanced strategy; nil uses DefaultBalancedWeights
+}
+
+// PackingMode defines how ranked files are packed into the token budget
+type PackingMode string
+
+const (
+	PackingGreedy   PackingMode = "greedy"   // Take files in score order until one doesn't fit
+	PackingKnapsack PackingMode = "knapsack" // Maximize total relevance within MaxTokens and MaxFiles
+	PackingAllocate PackingMode = "allocate" // Split MaxTokens across the top MaxFiles by relevance, compressing files to their share
+)
+
+// DefaultDeniedExtensions lists extensions of binary or generated files that
+// are excluded from selection unless constraints override the list
+var DefaultDeniedExtensions = []string{
+	".png", ".jpg", ".jpeg", ".gif", ".ico", ".svg", ".webp",
+	".lock", ".sum",
+	".min.js", ".min.css", ".map",
+	".pdf", ".zip", ".gz", ".tar", ".jar",
+	".exe", ".dll", ".so", ".dylib", ".bin", ".pyc", ".class",
+	".woff", ".woff2", ".ttf", ".eot",
 }
 
 // SelectionStrategy defines different context selection strategies
@@ -104,6 +137,7 @@ type ContextFile struct {
 	InclusionReason string   `json:"inclusion_reason"`
 	Priority       int       `json:"priority"`
 	Content        string    `json:"content,omitempty"` // Actual file content if loaded
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
 }
 
 // CompressionStrategy defines different compression approaches
@@ -115,6 +149,9 @@ const (
 	CompressionSnippet  CompressionStrategy = "snippet"  // Extract relevant snippets
 	CompressionMinify   CompressionStrategy = "minify"   // Remove whitespace/comments
 	CompressionSemantic CompressionStrategy = "semantic" // Semantic compression
+	CompressionAuto     CompressionStrategy = "auto"     // Pick a method per file to reach a target ratio
+	CompressionWindow   CompressionStrategy = "window"   // Keep the regions matching the task's keywords
+	CompressionUsage    CompressionStrategy = "usage"    // Keep the functions other selected files use, reduce the rest to signatures
 )
 
 // CompressedContext represents context after compression
@@ -136,6 +173,7 @@ type CompressedFile struct {
 	CompressedTokens int    `json:"compressed_tokens"`
 	CompressionRatio float64 `json:"compression_ratio"`
 	Method           string `json:"method"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"` // "techniques" applied, measured "quality", plus method-specific details, e.g. "line_ranges" for window
 }
 
 // DefaultOptimizer implements the ContextOptimizer interface
@@ -154,6 +192,17 @@ type OptimizerConfig struct {
 	MaxSelectionTime     time.Duration `json:"max_selection_time"`
 	EnableProfiling      bool    `json:"enable_profiling"`
 	DefaultStrategy      SelectionStrategy `json:"default_strategy"`
+	EnableDeduplication  bool    `json:"enable_deduplication"` // Reads every candidate and compares them pairwise, so it's off by default
+	DedupSimilarityThreshold float64 `json:"dedup_similarity_threshold"` // 1.0 only collapses identical content
+	CostGuard            *CostGuard `json:"cost_guard,omitempty"` // Optional ceiling on projected input cost
+	CompressionAdvisor   CompressionAdvisor `json:"-"` // Optional per-task compression choice for OptimizeForTokenBudget
+	NormalizeConstraints bool    `json:"normalize_constraints"` // Clamp out-of-range weights instead of rejecting them
+	ReportExclusions     bool    `json:"report_exclusions"`     // Debugging aid: record why candidates were left out, see SelectedContext.Exclusions
+}
+
+// CompressionAdvisor recommends how aggressively to compress context for a task type
+type CompressionAdvisor interface {
+	RecommendCompression(taskType TaskType) CompressionStrategy
 }
 
 // ContextCache provides caching capabilities for context selections
@@ -175,6 +224,8 @@ func NewDefaultOptimizer(analyzer ContextAnalyzer, cache ContextCache, compresso
 			MaxSelectionTime:    5 * time.Second,
 			EnableProfiling:     false,
 			DefaultStrategy:     StrategyBalanced,
+			EnableDeduplication: false,
+			DedupSimilarityThreshold: 0.9, // Used once deduplication is enabled
 		}
 	}
 	
@@ -194,17 +245,42 @@ func (o *DefaultOptimizer) SelectOptimalContext(ctx context.Context, project *Pr
 	if constraints == nil {
 		constraints = o.getDefaultConstraints()
 	}
+	if o.config.NormalizeConstraints {
+		constraints = constraints.Normalized()
+	}
+	if err := constraints.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid constraints: %w", err)
+	}
+	
+	// Score by the description's keywords when the caller gave none
+	task = withKeywords(task)
+	
+	// Shrink the budget up front when the guard allows it, so the selection
+	// is affordable by construction
+	if o.config.CostGuard != nil {
+		constraints = o.config.CostGuard.shrinkConstraints(constraints)
+	}
+	
+	// Selections depend on session history when a session memory is present,
+	// so they can't be shared through the cache
+	memory := SessionMemoryFromContext(ctx)
+	useCache := o.config.EnableCaching && memory == nil
 	
 	// Check cache first
-	if o.config.EnableCaching {
+	if useCache {
 		cacheKey := o.generateCacheKey(project, task, constraints)
 		if cached, found := o.GetCachedSelection(cacheKey); found {
 			return cached, nil
 		}
 	}
 	
+	var exclusions *exclusionRecorder
+	if o.config.ReportExclusions {
+		ctx, exclusions = withExclusionRecorder(ctx)
+	}
+	
 	// Select files based on strategy
-	selectedFiles, err := o.selectFilesByStrategy(project, task, constraints)
+	selectedFiles, overflow, err := o.selectFilesByStrategy(ctx, project, task, constraints)
 	if err != nil {
 		return nil, fmt.Errorf("failed to select files: %w", err)
 	}
@@ -225,9 +301,26 @@ func (o *DefaultOptimizer) SelectOptimalContext(ctx context.Context, project *Pr
 		CreatedAt:       time.Now(),
 		SelectionTime:   time.Since(startTime),
 	}
+	if overflow != nil {
+		selection.Metadata[budgetOverflowKey] = overflow
+	}
+	if exclusions != nil {
+		selection.Metadata[exclusionReportKey] = exclusions.report(project, constraints, selectedFiles)
+	}
+	
+	if o.config.CostGuard != nil {
+		if err := o.config.CostGuard.check(selection); err != nil {
+			return nil, err
+		}
+	}
+	
+	// Remember this turn's selection for the rest of the session
+	if memory != nil {
+		memory.Record(selectedFiles)
+	}
 	
 	// Cache the selection
-	if o.config.EnableCaching {
+	if useCache {
 		cacheKey := o.generateCacheKey(project, task, constraints)
 		o.CacheContextSelection(cacheKey, selection)
 	}
@@ -245,6 +338,7 @@ func (o *DefaultOptimizer) OptimizeForTokenBudget(ctx context.Context, project *
 		IncludeTests:      false, // Exclude tests to save tokens
 		IncludeDocs:       false, // Exclude docs to save tokens
 		FreshnessBias:     0.3,
+		ChurnBias:         0.3,
 		DependencyDepth:   2,
 	}
 	
@@ -257,6 +351,9 @@ func (o *DefaultOptimizer) OptimizeForTokenBudget(ctx context.Context, project *
 	// If over budget, progressively tighten constraints
 	if selection.TotalTokens > tokenBudget {
 		// Try increasing relevance threshold
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		constraints.MinRelevanceScore = 0.3
 		selection, err = o.SelectOptimalContext(ctx, project, task, constraints)
 		if err != nil {
@@ -265,6 +362,9 @@ func (o *DefaultOptimizer) OptimizeForTokenBudget(ctx context.Context, project *
 		
 		// If still over budget, reduce dependency depth
 		if selection.TotalTokens > tokenBudget {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			constraints.DependencyDepth = 1
 			selection, err = o.SelectOptimalContext(ctx, project, task, constraints)
 			if err != nil {
@@ -272,21 +372,142 @@ func (o *DefaultOptimizer) OptimizeForTokenBudget(ctx context.Context, project *
 			}
 		}
 		
-		// If still over budget, apply compression
+		// If still over budget, compress with the strategy the task type
+		// tolerates, or a lossier one estimated to fit
 		if selection.TotalTokens > tokenBudget && o.compressor != nil {
-			compressed, err := o.ApplyCompressionStrategy(ctx, selection, CompressionSnippet)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			selection, err = o.compressToBudget(ctx, selection, tokenBudget)
 			if err != nil {
 				return nil, err
 			}
-			
-			// Convert compressed context back to selected context
-			selection = o.convertCompressedToSelected(compressed)
 		}
 	}
 	
+	// A context missing the files the task names would mislead, so those files
+	// get one last chance compressed on their own before the budget is
+	// reported as too small
+	if mandatory := mandatoryFiles(project, task, constraints); !includesFiles(selection, mandatory) {
+		return o.fitMandatoryFiles(ctx, selection, mandatory, tokenBudget)
+	}
+	
+	// Compressing may keep files the budget would otherwise drop
+	if _, compressed := selection.Metadata["budget_compression"]; !compressed && o.compressor != nil {
+		return o.compressForCoverage(ctx, project, task, constraints, selection, tokenBudget)
+	}
+	
 	return selection, nil
 }
 
+// budgetCompressionLadder orders strategies from least to most lossy. Window
+// keeps whole regions around the task's keywords, so it goes before a summary
+// that keeps no code at all.
+var budgetCompressionLadder = []CompressionStrategy{CompressionMinify, CompressionSemantic, CompressionSnippet, CompressionWindow, CompressionSummary}
+
+// compressToBudget compresses selection with the strategy its task type
+// tolerates, or a lossier one estimated to fit tokenBudget, recording the
+// strategy as "budget_compression" metadata
+func (o *DefaultOptimizer) compressToBudget(ctx context.Context, selection *SelectedContext, tokenBudget int) (*SelectedContext, error) {
+	preferred := CompressionMinify
+	if o.config.CompressionAdvisor != nil && selection.Task != nil {
+		preferred = o.config.CompressionAdvisor.RecommendCompression(selection.Task.Type)
+	}
+	compressed, err := o.ApplyCompressionStrategy(ctx, selection, o.chooseCompressionStrategy(selection, tokenBudget, preferred))
+	if err != nil {
+		return nil, err
+	}
+	if len(compressed.CompressedFiles) != len(selection.Files) {
+		return selection, nil
+	}
+	fitted := compressedSelection(selection, selection.Files, compressed)
+	fitted.Metadata["budget_compression"] = string(compressed.Strategy)
+	return fitted, nil
+}
+
+// compressForCoverage decides between dropping files and compressing them.
+// When the budget left ranked files out of selection, it picks the strategy
+// estimated to fit them, selects again for the budget that strategy is
+// estimated to shrink to tokenBudget, and returns the compressed result if it
+// fits with more files than selection. Estimates can be optimistic, so a
+// result over budget is selected again for a proportionally smaller budget.
+func (o *DefaultOptimizer) compressForCoverage(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints, selection *SelectedContext, tokenBudget int) (*SelectedContext, error) {
+	if project.TotalTokens <= tokenBudget {
+		return selection, nil
+	}
+	wide := *constraints
+	wide.MaxTokens = project.TotalTokens
+	candidates, err := o.SelectOptimalContext(ctx, project, task, &wide)
+	if err != nil {
+		return nil, err
+	}
+	if candidates.TotalFiles <= selection.TotalFiles {
+		return selection, nil
+	}
+	
+	preferred := CompressionMinify
+	if o.config.CompressionAdvisor != nil && task != nil {
+		preferred = o.config.CompressionAdvisor.RecommendCompression(task.Type)
+	}
+	strategy := o.chooseCompressionStrategy(candidates, tokenBudget, preferred)
+	ratio, err := o.compressor.EstimateCompression(candidates, strategy)
+	if err != nil || ratio <= 0 || ratio >= 1 {
+		return selection, nil
+	}
+	if widenedBudget := int(float64(tokenBudget) / ratio); widenedBudget < project.TotalTokens {
+		wide.MaxTokens = widenedBudget
+	}
+	
+	for attempt := 0; attempt < 3 && wide.MaxTokens > 0; attempt++ {
+		widened, err := o.SelectOptimalContext(ctx, project, task, &wide)
+		if err != nil {
+			return nil, err
+		}
+		if widened.TotalFiles <= selection.TotalFiles {
+			break
+		}
+		compressed, err := o.ApplyCompressionStrategy(ctx, widened, strategy)
+		if err != nil || len(compressed.CompressedFiles) != len(widened.Files) {
+			break
+		}
+		fitted := compressedSelection(widened, widened.Files, compressed)
+		if fitted.TotalTokens <= tokenBudget {
+			fitted.Metadata["budget_compression"] = string(compressed.Strategy)
+			return fitted, nil
+		}
+		wide.MaxTokens = int(float64(wide.MaxTokens) * float64(tokenBudget) / float64(fitted.TotalTokens))
+	}
+	return selection, nil
+}
+
+// chooseCompressionStrategy picks the least lossy strategy, starting from
+// preferred, estimated to fit selection within tokenBudget, or the most
+// aggressive one when none does
+func (o *DefaultOptimizer) chooseCompressionStrategy(selection *SelectedContext, tokenBudget int, preferred CompressionStrategy) CompressionStrategy {
+	ladder := budgetCompressionLadder
+	for i, strategy := range ladder {
+		if strategy == preferred {
+			ladder = ladder[i:]
+			break
+		}
+	}
+
+	chosen, lowest := preferred, 1.0
+	for _, strategy := range ladder {
+		ratio, err := o.compressor.EstimateCompression(selection, strategy)
+		if err != nil {
+			continue
+		}
+		if float64(selection.TotalTokens)*ratio <= float64(tokenBudget) {
+			return strategy
+		}
+		if ratio < lowest {
+			chosen, lowest = strategy, ratio
+		}
+	}
+	return chosen
+}
+
 // Placeholder implementations
 func (o *DefaultOptimizer) ApplyCompressionStrategy(ctx context.Context, selection *SelectedContext, strategy CompressionStrategy) (*CompressedContext, error) {
 	if o.compressor == nil {
@@ -320,36 +541,91 @@ func (o *DefaultOptimizer) getDefaultConstraints() *ContextConstraints {
 		IncludeTests:      true,
 		IncludeDocs:       true,
 		FreshnessBias:     0.2,
+		ChurnBias:         0.3,
 		DependencyDepth:   3,
 		Strategy:          o.config.DefaultStrategy,
 	}
 }
 
-func (o *DefaultOptimizer) selectFilesByStrategy(project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
+// selectFilesByStrategy ranks candidate files with the configured strategy and
+// then fits the ranked candidates into the token budget
+func (o *DefaultOptimizer) selectFilesByStrategy(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, *BudgetOverflow, error) {
+	candidates, err := o.rankCandidates(ctx, project, task, constraints)
+	if err != nil {
+		return nil, nil, err
+	}
+	if exclusions := exclusionRecorderFrom(ctx); exclusions != nil {
+		exclusions.ranked = candidates
+	}
+	
+	files, overflow := o.applyTokenBudget(candidates, task, constraints)
+	return files, overflow, nil
+}
+
+// rankCandidates returns candidate files ordered by the configured strategy,
+// before the token budget is applied
+func (o *DefaultOptimizer) rankCandidates(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
+	var candidates []ContextFile
+	var err error
+	
 	switch constraints.Strategy {
 	case StrategyRelevance:
-		return o.selectByRelevance(project, task, constraints)
+		candidates, err = o.selectByRelevance(ctx, project, task, constraints)
 	case StrategyDependency:
-		return o.selectByDependency(project, task, constraints)
+		candidates, err = o.selectByDependency(ctx, project, task, constraints)
 	case StrategyFreshness:
-		return o.selectByFreshness(project, task, constraints)
+		candidates, err = o.selectByFreshness(ctx, project, task, constraints)
 	case StrategyCompactness:
-		return o.selectByCompactness(project, task, constraints)
+		candidates, err = o.selectByCompactness(ctx, project, task, constraints)
 	case StrategyBalanced:
-		return o.selectByBalanced(project, task, constraints)
+		candidates, err = o.selectByBalanced(ctx, project, task, constraints)
 	default:
-		return o.selectByBalanced(project, task, constraints)
+		candidates, err = o.selectByBalanced(ctx, project, task, constraints)
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	// Bugs cluster in code that keeps changing. The dependency strategy boosts
+	// before expanding, since re-sorting here would undo its dependency order.
+	if task.Type == TaskTypeDebug && constraints.ChurnBias > 0 && constraints.Strategy != StrategyDependency {
+		candidates = applyChurnBoost(candidates, constraints.ChurnBias)
+	}
+	
+	// Files that were relevant earlier in the session get a decaying boost
+	if memory := SessionMemoryFromContext(ctx); memory != nil {
+		candidates = memory.ApplyBoost(candidates)
 	}
+	
+	// Collapse duplicate content before budgeting so the freed budget goes to distinct files
+	if o.config.EnableDeduplication {
+		candidates = deduplicateFiles(candidates, o.config.DedupSimilarityThreshold)
+	}
+	
+	// Trade some relevance for coverage of different parts of the project
+	if constraints.DiversityWeight > 0 {
+		candidates = diversifyCandidates(candidates, project, constraints.DiversityWeight)
+	}
+	
+	// Files the task names are the backbone of the context, whatever their score
+	candidates = o.seedExplicitFiles(project, task, constraints, candidates)
+	
+	return candidates, nil
 }
 
 // selectByRelevance prioritizes files by semantic relevance to the task
-func (o *DefaultOptimizer) selectByRelevance(project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
+func (o *DefaultOptimizer) selectByRelevance(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
 	contextFiles := []ContextFile{}
+	exclusions := exclusionRecorderFrom(ctx)
 	
 	// Score all files and filter by minimum threshold
-	for _, file := range project.Files {
+	for i, file := range project.Files {
+		if err := checkCancelled(ctx, i); err != nil {
+			return nil, err
+		}
 		if o.shouldIncludeFile(&file, task, constraints) {
-			score := o.analyzer.ScoreFileRelevance(&file, task.Type, task.Description)
+			score := o.analyzer.ScoreFileRelevance(&file, task)
+			exclusions.scored(&file, score)
 			if score >= constraints.MinRelevanceScore {
 				contextFiles = append(contextFiles, ContextFile{
 					FileInfo:        &file,
@@ -362,30 +638,33 @@ func (o *DefaultOptimizer) selectByRelevance(project *ProjectContext, task *Task
 	}
 	
 	// Sort by relevance score (highest first)
-	sort.Slice(contextFiles, func(i, j int) bool {
-		return contextFiles[i].RelevanceScore > contextFiles[j].RelevanceScore
-	})
+	sortByRelevance(contextFiles)
 	
-	return o.applyTokenBudget(contextFiles, constraints), nil
+	return contextFiles, nil
 }
 
 // selectByDependency prioritizes files based on dependency relationships
-func (o *DefaultOptimizer) selectByDependency(project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
+func (o *DefaultOptimizer) selectByDependency(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
 	contextFiles := []ContextFile{}
+	exclusions := exclusionRecorderFrom(ctx)
 	
 	// Score files by dependency centrality and relevance
-	for _, file := range project.Files {
+	for i, file := range project.Files {
+		if err := checkCancelled(ctx, i); err != nil {
+			return nil, err
+		}
 		if o.shouldIncludeFile(&file, task, constraints) {
-			baseScore := o.analyzer.ScoreFileRelevance(&file, task.Type, task.Description)
+			baseScore := o.analyzer.ScoreFileRelevance(&file, task)
 			
 			// Boost score based on dependency centrality
 			var centralityBoost float64 = 0.0
 			if project.DependencyGraph != nil {
-				centralityBoost = o.calculateDependencyCentrality(project.DependencyGraph, file.Path)
+				centralityBoost = o.calculateDependencyCentrality(project.DependencyGraph, project.RootPath, file.Path)
 			}
 			
 			// Combine relevance and centrality (70% relevance, 30% centrality)
 			finalScore := baseScore*0.7 + centralityBoost*0.3
+			exclusions.scored(&file, finalScore)
 			
 			if finalScore >= constraints.MinRelevanceScore {
 				contextFiles = append(contextFiles, ContextFile{
@@ -399,24 +678,184 @@ func (o *DefaultOptimizer) selectByDependency(project *ProjectContext, task *Tas
 	}
 	
 	// Sort by combined score
-	sort.Slice(contextFiles, func(i, j int) bool {
-		return contextFiles[i].RelevanceScore > contextFiles[j].RelevanceScore
-	})
+	sortByRelevance(contextFiles)
+	if task.Type == TaskTypeDebug && constraints.ChurnBias > 0 {
+		contextFiles = applyChurnBoost(contextFiles, constraints.ChurnBias)
+	}
 	
-	return o.applyTokenBudget(contextFiles, constraints), nil
+	return o.expandTransitiveDependencies(project, task, constraints, contextFiles), nil
+}
+
+// transitiveDecay scales a seed's score for each hop to a pulled-in dependency
+const transitiveDecay = 0.9
+
+// expandTransitiveDependencies follows the dependency graph from the files the
+// task names (explicit Files or Keywords) up to DependencyDepth hops. Seeds and
+// the dependencies they pull in are ranked ahead of the other candidates;
+// dependencies are included regardless of their own relevance, as long as
+// they fit in the budget alongside the seeds.
+func (o *DefaultOptimizer) expandTransitiveDependencies(project *ProjectContext, task *Task, constraints *ContextConstraints, ranked []ContextFile) []ContextFile {
+	if project.DependencyGraph == nil || constraints.DependencyDepth <= 0 {
+		return ranked
+	}
+	
+	var seeds []ContextFile
+	rankedByPath := make(map[string]ContextFile, len(ranked))
+	for _, file := range ranked {
+		rankedByPath[file.FileInfo.Path] = file
+	}
+	for i := range project.Files {
+		file := &project.Files[i]
+		if !matchesTask(project.RootPath, file.Path, task) || !o.shouldIncludeFile(file, task, constraints) {
+			continue
+		}
+		// Seeds keep their ranked entry when they have one
+		seed, ok := rankedByPath[file.Path]
+		if !ok {
+			seed = ContextFile{
+				FileInfo:        file,
+				RelevanceScore:  o.analyzer.ScoreFileRelevance(file, task),
+				InclusionReason: "dependency_centrality",
+				Priority:        1,
+			}
+		}
+		seeds = append(seeds, seed)
+	}
+	
+	expanded, _ := o.expandFromSeeds(project, task, constraints, seeds, ranked)
+	return expanded
+}
+
+// expandFromSeeds ranks the seeds that fit in the budget first, followed by
+// the files they depend on up to DependencyDepth hops and then the remaining
+// ranked files. It also returns how many files the seeds and their
+// dependencies account for.
+func (o *DefaultOptimizer) expandFromSeeds(project *ProjectContext, task *Task, constraints *ContextConstraints, seeds, ranked []ContextFile) ([]ContextFile, int) {
+	type queued struct {
+		key   string
+		depth int
+		score float64
+	}
+	
+	var expanded []ContextFile
+	var queue []queued
+	included := make(map[string]bool)
+	tokens := 0
+	
+	for _, seed := range seeds {
+		file := seed.FileInfo
+		if included[file.Path] {
+			continue
+		}
+		if tokens+file.TokenCount > constraints.MaxTokens || len(expanded) >= constraints.MaxFiles {
+			continue
+		}
+		expanded = append(expanded, seed)
+		included[file.Path] = true
+		tokens += file.TokenCount
+		queue = append(queue, queued{key: dependencyNodeKey(project.RootPath, file.Path), score: seed.RelevanceScore})
+	}
+	if len(expanded) == 0 {
+		return ranked, 0
+	}
+	
+	// Without a graph the seeds are only moved to the front
+	if project.DependencyGraph == nil {
+		queue = nil
+	}
+	filesByKey := make(map[string]*FileInfo, len(project.Files))
+	for i := range project.Files {
+		filesByKey[dependencyNodeKey(project.RootPath, project.Files[i].Path)] = &project.Files[i]
+	}
+	
+	// Breadth-first so nearer dependencies claim the budget first
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current.depth >= constraints.DependencyDepth {
+			continue
+		}
+		
+		node, exists := project.DependencyGraph.Nodes[current.key]
+		if !exists {
+			continue
+		}
+		for _, depKey := range node.Dependencies {
+			file, exists := filesByKey[depKey]
+			if !exists || included[file.Path] || !o.shouldIncludeFile(file, task, constraints) {
+				continue
+			}
+			if tokens+file.TokenCount > constraints.MaxTokens || len(expanded) >= constraints.MaxFiles {
+				continue
+			}
+			
+			score := current.score * transitiveDecay
+			expanded = append(expanded, ContextFile{
+				FileInfo:        file,
+				RelevanceScore:  score,
+				InclusionReason: "transitive_dependency",
+				Priority:        2,
+				Metadata: map[string]interface{}{
+					"dependency_depth": current.depth + 1,
+					"required_by":      current.key,
+				},
+			})
+			included[file.Path] = true
+			tokens += file.TokenCount
+			queue = append(queue, queued{key: depKey, depth: current.depth + 1, score: score})
+		}
+	}
+	
+	seeded := len(expanded)
+	for _, file := range ranked {
+		if !included[file.FileInfo.Path] {
+			expanded = append(expanded, file)
+		}
+	}
+	return expanded, seeded
+}
+
+// matchesExplicitFile reports whether name, as the task gives it, refers to
+// path: the same path, its path within the project, or a suffix of it
+func matchesExplicitFile(rootPath, path, name string) bool {
+	key := filepath.ToSlash(dependencyNodeKey(rootPath, path))
+	name = filepath.ToSlash(filepath.Clean(name))
+	return name == filepath.ToSlash(path) || name == key || strings.HasSuffix(key, "/"+name)
+}
+
+// matchesTask reports whether a file is named by the task's explicit files or keywords
+func matchesTask(rootPath, path string, task *Task) bool {
+	for _, name := range task.Files {
+		if matchesExplicitFile(rootPath, path, name) {
+			return true
+		}
+	}
+	
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	for _, keyword := range task.Keywords {
+		if keyword = strings.ToLower(keyword); keyword != "" && strings.Contains(base, keyword) {
+			return true
+		}
+	}
+	return false
 }
 
 // selectByFreshness prioritizes recently modified files
-func (o *DefaultOptimizer) selectByFreshness(project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
+func (o *DefaultOptimizer) selectByFreshness(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
 	contextFiles := []ContextFile{}
+	exclusions := exclusionRecorderFrom(ctx)
 	
-	for _, file := range project.Files {
+	for i, file := range project.Files {
+		if err := checkCancelled(ctx, i); err != nil {
+			return nil, err
+		}
 		if o.shouldIncludeFile(&file, task, constraints) {
-			baseScore := o.analyzer.ScoreFileRelevance(&file, task.Type, task.Description)
+			baseScore := o.analyzer.ScoreFileRelevance(&file, task)
 			
 			// Apply freshness bias
-			freshnessScore := o.calculateFreshnessScore(file.LastModified)
+			freshnessScore := o.calculateFreshnessScore(file.FreshnessTime())
 			finalScore := baseScore*(1-constraints.FreshnessBias) + freshnessScore*constraints.FreshnessBias
+			exclusions.scored(&file, finalScore)
 			
 			if finalScore >= constraints.MinRelevanceScore {
 				contextFiles = append(contextFiles, ContextFile{
@@ -430,20 +869,23 @@ func (o *DefaultOptimizer) selectByFreshness(project *ProjectContext, task *Task
 	}
 	
 	// Sort by combined score
-	sort.Slice(contextFiles, func(i, j int) bool {
-		return contextFiles[i].RelevanceScore > contextFiles[j].RelevanceScore
-	})
+	sortByRelevance(contextFiles)
 	
-	return o.applyTokenBudget(contextFiles, constraints), nil
+	return contextFiles, nil
 }
 
 // selectByCompactness prioritizes information density (tokens per relevance)
-func (o *DefaultOptimizer) selectByCompactness(project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
+func (o *DefaultOptimizer) selectByCompactness(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
 	contextFiles := []ContextFile{}
+	exclusions := exclusionRecorderFrom(ctx)
 	
-	for _, file := range project.Files {
+	for i, file := range project.Files {
+		if err := checkCancelled(ctx, i); err != nil {
+			return nil, err
+		}
 		if o.shouldIncludeFile(&file, task, constraints) {
-			relevanceScore := o.analyzer.ScoreFileRelevance(&file, task.Type, task.Description)
+			relevanceScore := o.analyzer.ScoreFileRelevance(&file, task)
+			exclusions.scored(&file, relevanceScore)
 			
 			if relevanceScore >= constraints.MinRelevanceScore {
 				// Calculate compactness: relevance per token
@@ -463,30 +905,33 @@ func (o *DefaultOptimizer) selectByCompactness(project *ProjectContext, task *Ta
 	}
 	
 	// Sort by compactness (highest first)
-	sort.Slice(contextFiles, func(i, j int) bool {
-		return contextFiles[i].RelevanceScore > contextFiles[j].RelevanceScore
-	})
+	sortByRelevance(contextFiles)
 	
-	return o.applyTokenBudget(contextFiles, constraints), nil
+	return contextFiles, nil
 }
 
 // selectByBalanced uses a balanced approach combining multiple factors
-func (o *DefaultOptimizer) selectByBalanced(project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
+func (o *DefaultOptimizer) selectByBalanced(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints) ([]ContextFile, error) {
 	contextFiles := []ContextFile{}
+	exclusions := exclusionRecorderFrom(ctx)
+	weights := constraints.balancedWeights()
 	
-	for _, file := range project.Files {
+	for i, file := range project.Files {
+		if err := checkCancelled(ctx, i); err != nil {
+			return nil, err
+		}
 		if o.shouldIncludeFile(&file, task, constraints) {
 			// Base relevance score
-			relevanceScore := o.analyzer.ScoreFileRelevance(&file, task.Type, task.Description)
+			relevanceScore := o.analyzer.ScoreFileRelevance(&file, task)
 			
 			// Dependency centrality boost
 			var centralityBoost float64 = 0.0
 			if project.DependencyGraph != nil {
-				centralityBoost = o.calculateDependencyCentrality(project.DependencyGraph, file.Path)
+				centralityBoost = o.calculateDependencyCentrality(project.DependencyGraph, project.RootPath, file.Path)
 			}
 			
 			// Freshness boost
-			freshnessScore := o.calculateFreshnessScore(file.LastModified)
+			freshnessScore := o.calculateFreshnessScore(file.FreshnessTime())
 			
 			// Size penalty for very large files
 			var sizePenalty float64 = 1.0
@@ -494,12 +939,13 @@ func (o *DefaultOptimizer) selectByBalanced(project *ProjectContext, task *Task,
 				sizePenalty = 2000.0 / float64(file.TokenCount)
 			}
 			
-			// Balanced combination:
-			// 50% relevance, 20% centrality, 15% freshness, 15% size efficiency
-			balancedScore := relevanceScore*0.5 + 
-				centralityBoost*0.2 + 
-				freshnessScore*constraints.FreshnessBias*0.15 +
-				sizePenalty*0.15
+			// Balanced combination, 50% relevance, 20% centrality, 15% freshness
+			// and 15% size efficiency unless the constraints weigh them differently
+			balancedScore := relevanceScore*weights.Relevance + 
+				centralityBoost*weights.Centrality + 
+				freshnessScore*constraints.FreshnessBias*weights.Freshness +
+				sizePenalty*weights.Size
+			exclusions.scored(&file, balancedScore)
 			
 			if balancedScore >= constraints.MinRelevanceScore {
 				contextFiles = append(contextFiles, ContextFile{
@@ -513,51 +959,89 @@ func (o *DefaultOptimizer) selectByBalanced(project *ProjectContext, task *Task,
 	}
 	
 	// Sort by balanced score
-	sort.Slice(contextFiles, func(i, j int) bool {
-		return contextFiles[i].RelevanceScore > contextFiles[j].RelevanceScore
-	})
+	sortByRelevance(contextFiles)
 	
-	return o.applyTokenBudget(contextFiles, constraints), nil
+	return contextFiles, nil
+}
+
+// selectionCheckInterval is how many files the selection loops score between
+// checks for cancellation
+const selectionCheckInterval = 64
+
+// checkCancelled returns ctx's error every selectionCheckInterval files, so
+// selection loops stop soon after a request is cancelled without paying for a
+// check per file
+func checkCancelled(ctx context.Context, i int) error {
+	if i%selectionCheckInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// sortByRelevance orders files by relevance score, highest first, breaking
+// ties by path so identical inputs always select the same files
+func sortByRelevance(files []ContextFile) {
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].RelevanceScore != files[j].RelevanceScore {
+			return files[i].RelevanceScore > files[j].RelevanceScore
+		}
+		return files[i].FileInfo.Path < files[j].FileInfo.Path
+	})
 }
 
 // shouldIncludeFile checks if a file should be considered based on constraints
 func (o *DefaultOptimizer) shouldIncludeFile(file *FileInfo, task *Task, constraints *ContextConstraints) bool {
-	// Check file type preferences
-	if len(constraints.PreferredTypes) > 0 {
-		found := false
-		for _, preferredType := range constraints.PreferredTypes {
-			if file.FileType == preferredType {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
+	reason, _ := filterExclusion(file, constraints)
+	return reason == ""
+}
+
+// hasDeniedExtension reports whether path ends with a denied extension. A nil
+// list falls back to DefaultDeniedExtensions.
+func hasDeniedExtension(path string, deniedExtensions []string) bool {
+	if deniedExtensions == nil {
+		deniedExtensions = DefaultDeniedExtensions
 	}
 	
-	// Check excluded patterns
-	for _, pattern := range constraints.ExcludedPatterns {
-		if strings.Contains(file.Path, pattern) {
-			return false
+	name := strings.ToLower(filepath.Base(path))
+	for _, ext := range deniedExtensions {
+		if strings.HasSuffix(name, strings.ToLower(ext)) {
+			return true
 		}
 	}
-	
-	// Check test file inclusion
-	if !constraints.IncludeTests && file.FileType == "test" {
-		return false
+	return false
+}
+
+// applyTokenBudget applies token budget constraints to file selection. When
+// nothing fits because the best candidate alone exceeds MaxTokens, that file
+// is cut down to the budget instead, and the returned overflow explains why.
+func (o *DefaultOptimizer) applyTokenBudget(contextFiles []ContextFile, task *Task, constraints *ContextConstraints) ([]ContextFile, *BudgetOverflow) {
+	// Explicit files and their dependencies lead the ranking and are never
+	// traded for other files
+	if pinned := leadingExplicitFiles(contextFiles); len(pinned) > 0 {
+		remaining := *constraints
+		remaining.MaxTokens -= o.calculateTotalTokens(pinned)
+		remaining.MaxFiles -= len(pinned)
+		rest := o.packFiles(contextFiles[len(pinned):], task, &remaining)
+		return append(append([]ContextFile{}, pinned...), rest...), nil
 	}
 	
-	// Check documentation inclusion
-	if !constraints.IncludeDocs && file.FileType == "documentation" {
-		return false
+	selected := o.packFiles(contextFiles, task, constraints)
+	if len(selected) > 0 || len(contextFiles) == 0 || constraints.MaxTokens <= 0 || constraints.MaxFiles <= 0 ||
+		contextFiles[0].FileInfo.TokenCount <= constraints.MaxTokens {
+		return selected, nil
 	}
-	
-	return true
+	return o.fitOversizedFile(contextFiles[0], task, constraints.MaxTokens)
 }
 
-// applyTokenBudget applies token budget constraints to file selection
-func (o *DefaultOptimizer) applyTokenBudget(contextFiles []ContextFile, constraints *ContextConstraints) []ContextFile {
+// packFiles fits ranked files into the budget with the configured packing mode
+func (o *DefaultOptimizer) packFiles(contextFiles []ContextFile, task *Task, constraints *ContextConstraints) []ContextFile {
+	switch constraints.PackingMode {
+	case PackingKnapsack:
+		return packKnapsack(contextFiles, constraints.MaxTokens, constraints.MaxFiles)
+	case PackingAllocate:
+		return o.allocateBudget(contextFiles, task, constraints)
+	}
+	
 	selectedFiles := []ContextFile{}
 	totalTokens := 0
 	
@@ -575,38 +1059,39 @@ func (o *DefaultOptimizer) applyTokenBudget(contextFiles []ContextFile, constrai
 }
 
 // calculateDependencyCentrality calculates dependency centrality for a file
-func (o *DefaultOptimizer) calculateDependencyCentrality(graph *DependencyGraph, filePath string) float64 {
-	// Use relative path for lookup
-	relPath := filePath
-	if strings.HasPrefix(filePath, "/") {
-		// Strip absolute path if needed
-		parts := strings.Split(filePath, "/")
-		if len(parts) > 2 {
-			relPath = strings.Join(parts[len(parts)-2:], "/")
-		}
-	}
-	
-	node, exists := graph.Nodes[relPath]
+func (o *DefaultOptimizer) calculateDependencyCentrality(graph *DependencyGraph, rootPath, filePath string) float64 {
+	// Graph nodes are keyed by path relative to the project root
+	node, exists := graph.Nodes[dependencyNodeKey(rootPath, filePath)]
 	if !exists {
 		return 0.0
 	}
 	
-	totalNodes := len(graph.Nodes)
-	if totalNodes <= 1 {
-		return 0.5
+	return nodeCentrality(graph, node)
+}
+
+// applyChurnBoost scales each candidate's score by up to 1+bias in proportion
+// to its churn relative to the most-churned candidate, then re-sorts. Scaling
+// rather than blending keeps strategies with unbounded scores comparable.
+func applyChurnBoost(candidates []ContextFile, bias float64) []ContextFile {
+	maxChurn := 0
+	for _, file := range candidates {
+		if file.FileInfo.Churn > maxChurn {
+			maxChurn = file.FileInfo.Churn
+		}
+	}
+	if maxChurn == 0 {
+		return candidates
 	}
 	
-	// Calculate centrality based on incoming and outgoing connections
-	inDegree := float64(len(node.Dependents))
-	outDegree := float64(len(node.Dependencies))
-	
-	// Files that many others depend on are more central
-	centrality := (inDegree*2 + outDegree) / float64(3*(totalNodes-1))
-	
-	return min(1.0, centrality)
+	for i := range candidates {
+		churnScore := float64(candidates[i].FileInfo.Churn) / float64(maxChurn)
+		candidates[i].RelevanceScore *= 1 + bias*churnScore
+	}
+	sortByRelevance(candidates)
+	return candidates
 }
 
-// calculateFreshnessScore calculates freshness score based on modification time
+// calculateFreshnessScore calculates freshness score based on the last change time
 func (o *DefaultOptimizer) calculateFreshnessScore(lastModified time.Time) float64 {
 	age := time.Since(lastModified)
 	
@@ -643,16 +1128,36 @@ func (o *DefaultOptimizer) calculateTotalTokens(files []ContextFile) int {
 	return total
 }
 
+// generateCacheKey hashes everything a selection depends on: the project
+// root, the task apart from its ID and creation time, and every constraint. Lists
+// that act as sets are sorted so their order doesn't split the cache.
 func (o *DefaultOptimizer) generateCacheKey(project *ProjectContext, task *Task, constraints *ContextConstraints) string {
-	return fmt.Sprintf("ctx_%s_%s_%s_%d", 
-		project.RootPath, 
-		string(task.Type), 
-		task.Description, 
-		constraints.MaxTokens)
-}
-
-func (o *DefaultOptimizer) convertCompressedToSelected(compressed *CompressedContext) *SelectedContext {
-	// Convert compressed context back to selected context format
-	// This is a simplified implementation
-	return compressed.Original
-}
\ No newline at end of file
+	keyTask := *task
+	keyTask.ID = ""
+	keyTask.CreatedAt = time.Time{}
+	keyTask.Keywords = sortedCopy(task.Keywords)
+	keyTask.Files = sortedCopy(task.Files)
+	
+	keyConstraints := *constraints
+	keyConstraints.PreferredTypes = sortedCopy(constraints.PreferredTypes)
+	keyConstraints.ExcludedPatterns = sortedCopy(constraints.ExcludedPatterns)
+	keyConstraints.DeniedExtensions = sortedCopy(constraints.DeniedExtensions)
+	
+	data, _ := json.Marshal(struct {
+		Root        string              `json:"root"`
+		Task        *Task               `json:"task"`
+		Constraints *ContextConstraints `json:"constraints"`
+	}{project.RootPath, &keyTask, &keyConstraints})
+	sum := sha256.Sum256(data)
+	return "ctx_" + hex.EncodeToString(sum[:])
+}
+
+// sortedCopy returns a sorted copy of values, keeping nil distinct from empty
+func sortedCopy(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return sorted
+}
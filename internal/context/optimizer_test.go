@@ -0,0 +1,532 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stubAnalyzer scores files from a fixed table so optimizer tests are deterministic
+type stubAnalyzer struct {
+	*DefaultAnalyzer
+	scores map[string]float64
+}
+
+func newStubAnalyzer(scores map[string]float64) *stubAnalyzer {
+	return &stubAnalyzer{
+		DefaultAnalyzer: NewDefaultAnalyzer(NewSimpleTokenCounter(), nil),
+		scores:          scores,
+	}
+}
+
+func (a *stubAnalyzer) ScoreFileRelevance(file *FileInfo, task *Task) float64 {
+	return a.scores[file.Path]
+}
+
+// newTestProject builds an in-memory project from path/token pairs
+func newTestProject(tokens map[string]int) *ProjectContext {
+	project := &ProjectContext{
+		RootPath:  "/project",
+		Languages: make(map[string]int),
+		CreatedAt: time.Now(),
+	}
+	for path, count := range tokens {
+		project.Files = append(project.Files, FileInfo{
+			Path:         path,
+			TokenCount:   count,
+			FileType:     "source",
+			Language:     "go",
+			LastModified: time.Now(),
+			Metadata:     make(map[string]interface{}),
+		})
+		project.TotalTokens += count
+	}
+	project.TotalFiles = len(project.Files)
+	return project
+}
+
+// newTestOptimizer creates an optimizer without caching around a stub analyzer
+func newTestOptimizer(scores map[string]float64) *DefaultOptimizer {
+	return NewDefaultOptimizer(newStubAnalyzer(scores), nil, nil, &OptimizerConfig{
+		DefaultTokenBudget: 8000,
+		MaxSelectionTime:   5 * time.Second,
+		DefaultStrategy:    StrategyRelevance,
+	})
+}
+
+func selectedPaths(selection *SelectedContext) []string {
+	paths := make([]string, 0, len(selection.Files))
+	for _, file := range selection.Files {
+		paths = append(paths, file.FileInfo.Path)
+	}
+	return paths
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// TestShouldIncludeFileDeniedExtensions tests the extension deny-list
+func TestShouldIncludeFileDeniedExtensions(t *testing.T) {
+	optimizer := newTestOptimizer(nil)
+	task := &Task{Type: TaskTypeFeature}
+
+	tests := []struct {
+		name     string
+		path     string
+		denied   []string
+		expected bool
+	}{
+		{name: "Image denied by default", path: "assets/logo.png", expected: false},
+		{name: "Lock file denied by default", path: "yarn.lock", expected: false},
+		{name: "Minified JS denied by default", path: "web/app.min.js", expected: false},
+		{name: "Source map denied by default", path: "web/app.js.map", expected: false},
+		{name: "Uppercase extension denied", path: "assets/LOGO.PNG", expected: false},
+		{name: "Regular JS allowed", path: "web/app.js", expected: true},
+		{name: "Go source allowed", path: "main.go", expected: true},
+		{name: "Cleared list includes image", path: "assets/logo.png", denied: []string{}, expected: true},
+		{name: "Cleared list includes minified JS", path: "web/app.min.js", denied: []string{}, expected: true},
+		{name: "Custom list replaces defaults", path: "assets/logo.png", denied: []string{".go"}, expected: true},
+		{name: "Custom list denies its entries", path: "main.go", denied: []string{".go"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := &FileInfo{Path: tt.path, FileType: "source"}
+			constraints := &ContextConstraints{DeniedExtensions: tt.denied}
+
+			if got := optimizer.shouldIncludeFile(file, task, constraints); got != tt.expected {
+				t.Errorf("shouldIncludeFile(%s) = %v, expected %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSelectByDependencyTransitive tests expansion from task seeds along the dependency graph
+func TestSelectByDependencyTransitive(t *testing.T) {
+	// handler.go -> service.go -> store.go; noise.go scores highly but is unrelated
+	scores := map[string]float64{
+		"handler.go": 0.8,
+		"service.go": 0.3,
+		"store.go":   0.0,
+		"noise.go":   0.9,
+	}
+	project := newTestProject(map[string]int{
+		"handler.go": 100,
+		"service.go": 100,
+		"store.go":   100,
+		"noise.go":   100,
+	})
+	project.DependencyGraph = &DependencyGraph{Nodes: map[string]*DependencyNode{}}
+	for _, path := range []string{"handler.go", "service.go", "store.go", "noise.go"} {
+		project.DependencyGraph.Nodes[path] = &DependencyNode{Path: path}
+	}
+	addDependencyEdge(project.DependencyGraph, "handler.go", "service.go")
+	addDependencyEdge(project.DependencyGraph, "service.go", "store.go")
+
+	tests := []struct {
+		name      string
+		task      *Task
+		depth     int
+		maxTokens int
+		expected  []string
+	}{
+		{
+			name:      "depth 2 pulls in dependency of dependency",
+			task:      &Task{Type: TaskTypeFeature, Keywords: []string{"handler"}},
+			depth:     2,
+			maxTokens: 1000,
+			expected:  []string{"handler.go", "service.go", "store.go", "noise.go"},
+		},
+		{
+			name:      "depth 1 stops at direct dependency",
+			task:      &Task{Type: TaskTypeFeature, Keywords: []string{"handler"}},
+			depth:     1,
+			maxTokens: 1000,
+			expected:  []string{"handler.go", "service.go", "noise.go"},
+		},
+		{
+			name:      "explicit file seeds expansion",
+			task:      &Task{Type: TaskTypeFeature, Files: []string{"service.go"}},
+			depth:     2,
+			maxTokens: 1000,
+			expected:  []string{"service.go", "store.go", "noise.go", "handler.go"},
+		},
+		{
+			name:      "budget limits expansion",
+			task:      &Task{Type: TaskTypeFeature, Keywords: []string{"handler"}},
+			depth:     2,
+			maxTokens: 200,
+			expected:  []string{"handler.go", "service.go"},
+		},
+		{
+			name:      "no seeds keeps ranking",
+			task:      &Task{Type: TaskTypeFeature},
+			depth:     2,
+			maxTokens: 1000,
+			expected:  []string{"noise.go", "handler.go", "service.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optimizer := newTestOptimizer(scores)
+			constraints := &ContextConstraints{
+				MaxTokens:         tt.maxTokens,
+				MaxFiles:          10,
+				MinRelevanceScore: 0.2,
+				DependencyDepth:   tt.depth,
+				Strategy:          StrategyDependency,
+			}
+
+			selection, err := optimizer.SelectOptimalContext(context.Background(), project, tt.task, constraints)
+			if err != nil {
+				t.Fatalf("SelectOptimalContext failed: %v", err)
+			}
+			if got := selectedPaths(selection); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("selected %v, expected %v", got, tt.expected)
+			}
+			for _, file := range selection.Files {
+				if file.FileInfo.Path == "store.go" && file.InclusionReason != "transitive_dependency" {
+					t.Errorf("store.go InclusionReason = %q, expected transitive_dependency", file.InclusionReason)
+				}
+			}
+		})
+	}
+}
+
+// TestChurnBoostKeepsDependencyOrder tests that boosting churn for a debug
+// task doesn't re-rank files ahead of the dependencies the seeds pull in
+func TestChurnBoostKeepsDependencyOrder(t *testing.T) {
+	// handler.go -> store.go; noise.go scores highly and changes all the time
+	scores := map[string]float64{
+		"handler.go": 0.8,
+		"store.go":   0.0,
+		"noise.go":   0.9,
+	}
+	project := newTestProject(map[string]int{
+		"handler.go": 100,
+		"store.go":   100,
+		"noise.go":   100,
+	})
+	project.DependencyGraph = &DependencyGraph{Nodes: map[string]*DependencyNode{}}
+	for i := range project.Files {
+		path := project.Files[i].Path
+		project.DependencyGraph.Nodes[path] = &DependencyNode{Path: path}
+		if path == "noise.go" {
+			project.Files[i].Churn = 10
+		}
+	}
+	addDependencyEdge(project.DependencyGraph, "handler.go", "store.go")
+
+	optimizer := newTestOptimizer(scores)
+	constraints := &ContextConstraints{
+		MaxTokens:         1000,
+		MaxFiles:          10,
+		MinRelevanceScore: 0.2,
+		DependencyDepth:   1,
+		ChurnBias:         1,
+		Strategy:          StrategyDependency,
+	}
+	task := &Task{Type: TaskTypeDebug, Keywords: []string{"handler"}}
+
+	selection, err := optimizer.SelectOptimalContext(context.Background(), project, task, constraints)
+	if err != nil {
+		t.Fatalf("SelectOptimalContext failed: %v", err)
+	}
+	expected := []string{"handler.go", "store.go", "noise.go"}
+	if got := selectedPaths(selection); !reflect.DeepEqual(got, expected) {
+		t.Errorf("selected %v, expected %v", got, expected)
+	}
+}
+
+// TestSelectExplicitFiles tests that files the task names lead the selection
+// with their dependencies even when their own relevance is too low to qualify
+func TestSelectExplicitFiles(t *testing.T) {
+	// handler.go -> store.go; both score below the threshold while noise.go
+	// and big.go score highly
+	scores := map[string]float64{
+		"handler.go":      0.05,
+		"store.go":        0.0,
+		"handler_test.go": 0.3,
+		"noise.go":        0.4,
+		"big.go":          0.9,
+	}
+	project := newTestProject(map[string]int{
+		"handler.go":      100,
+		"store.go":        100,
+		"handler_test.go": 100,
+		"noise.go":        100,
+		"big.go":          200,
+	})
+	project.DependencyGraph = &DependencyGraph{Nodes: map[string]*DependencyNode{}}
+	for _, file := range project.Files {
+		project.DependencyGraph.Nodes[file.Path] = &DependencyNode{Path: file.Path}
+	}
+	addDependencyEdge(project.DependencyGraph, "handler.go", "store.go")
+
+	tests := []struct {
+		name     string
+		strategy SelectionStrategy
+		packing  PackingMode
+		expected []string
+	}{
+		{
+			name:     "relevance greedy",
+			strategy: StrategyRelevance,
+			expected: []string{"handler.go", "store.go", "big.go", "handler_test.go"},
+		},
+		{
+			name:     "dependency knapsack",
+			strategy: StrategyDependency,
+			packing:  PackingKnapsack,
+			expected: []string{"handler.go", "store.go", "big.go", "handler_test.go"},
+		},
+		{
+			name:     "balanced allocate",
+			strategy: StrategyBalanced,
+			packing:  PackingAllocate,
+			expected: []string{"handler.go", "store.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			optimizer := newTestOptimizer(scores)
+			constraints := &ContextConstraints{
+				MaxTokens:         500,
+				MaxFiles:          10,
+				MinRelevanceScore: 0.2,
+				DependencyDepth:   1,
+				Strategy:          tt.strategy,
+				PackingMode:       tt.packing,
+			}
+			task := &Task{Type: TaskTypeDebug, Description: "fix the bug in handler.go", Files: []string{"handler.go", "missing.go"}}
+
+			selection, err := optimizer.SelectOptimalContext(context.Background(), project, task, constraints)
+			if err != nil {
+				t.Fatalf("SelectOptimalContext failed: %v", err)
+			}
+			got := selectedPaths(selection)
+			if len(got) < len(tt.expected) || !reflect.DeepEqual(got[:len(tt.expected)], tt.expected) {
+				t.Fatalf("selected %v, expected to start with %v", got, tt.expected)
+			}
+			if reason := selection.Files[0].InclusionReason; reason != "explicit" {
+				t.Errorf("handler.go InclusionReason = %q, expected explicit", reason)
+			}
+			if reason := selection.Files[1].InclusionReason; reason != "transitive_dependency" {
+				t.Errorf("store.go InclusionReason = %q, expected transitive_dependency", reason)
+			}
+			if selection.TotalTokens > constraints.MaxTokens {
+				t.Errorf("selected %d tokens, over the %d budget", selection.TotalTokens, constraints.MaxTokens)
+			}
+		})
+	}
+}
+
+// TestChooseCompressionStrategy tests that budget fitting starts from the
+// preferred strategy and only gets lossier when the estimate doesn't fit
+func TestChooseCompressionStrategy(t *testing.T) {
+	optimizer := NewDefaultOptimizer(nil, nil, NewDefaultContextCompressor(NewSimpleTokenCounter(), nil), nil)
+	// Unreadable content makes the compressor fall back to its typical ratios
+	selection := &SelectedContext{
+		Files:       []ContextFile{{FileInfo: &FileInfo{Path: "/nonexistent/main.go", Language: "go", TokenCount: 1000}}},
+		TotalTokens: 1000,
+	}
+
+	tests := []struct {
+		name      string
+		budget    int
+		preferred CompressionStrategy
+		expected  CompressionStrategy
+	}{
+		{name: "preferred fits", budget: 900, preferred: CompressionMinify, expected: CompressionMinify},
+		{name: "lossier preference kept", budget: 900, preferred: CompressionSummary, expected: CompressionSummary},
+		{name: "escalates until it fits", budget: 450, preferred: CompressionMinify, expected: CompressionSnippet},
+		{name: "nothing fits", budget: 100, preferred: CompressionMinify, expected: CompressionSummary},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := optimizer.chooseCompressionStrategy(selection, tt.budget, tt.preferred); got != tt.expected {
+				t.Errorf("chooseCompressionStrategy = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSelectionCacheKeyCoversConstraints tests that selections differing in
+// strategy, keywords, or explicit files are cached separately
+func TestSelectionCacheKeyCoversConstraints(t *testing.T) {
+	scores := map[string]float64{"a.go": 0.9, "b.go": 0.5}
+	project := newTestProject(map[string]int{"a.go": 100, "b.go": 100})
+	project.DependencyGraph = &DependencyGraph{Nodes: map[string]*DependencyNode{
+		"a.go": {Path: "a.go"},
+		"b.go": {Path: "b.go"},
+	}}
+
+	cache := NewInMemoryContextCache(nil)
+	optimizer := NewDefaultOptimizer(newStubAnalyzer(scores), cache, nil, &OptimizerConfig{
+		DefaultTokenBudget: 8000,
+		MaxSelectionTime:   5 * time.Second,
+		DefaultStrategy:    StrategyRelevance,
+		EnableCaching:      true,
+		CacheExpiryMinutes: 30,
+	})
+
+	ctx := context.Background()
+	task := &Task{Type: TaskTypeFeature, Description: "add caching"}
+	for _, strategy := range []SelectionStrategy{StrategyRelevance, StrategyDependency} {
+		selection, err := optimizer.SelectOptimalContext(ctx, project, task, &ContextConstraints{MaxTokens: 1000, MaxFiles: 10, Strategy: strategy})
+		if err != nil {
+			t.Fatalf("SelectOptimalContext(%s) failed: %v", strategy, err)
+		}
+		if selection.Strategy != strategy {
+			t.Errorf("selection for %s came back with strategy %s", strategy, selection.Strategy)
+		}
+	}
+	if hits := cache.GetStatistics().Hits; hits != 0 {
+		t.Errorf("cache hits = %d, expected the strategies not to share an entry", hits)
+	}
+
+	base := &ContextConstraints{MaxTokens: 1000, Strategy: StrategyRelevance, PreferredTypes: []string{"source", "test"}}
+	baseKey := optimizer.generateCacheKey(project, &Task{Description: "fix", Keywords: []string{"auth", "token"}}, base)
+
+	tests := []struct {
+		name        string
+		task        *Task
+		constraints *ContextConstraints
+		same        bool
+	}{
+		{
+			name:        "keywords reordered",
+			task:        &Task{Description: "fix", Keywords: []string{"token", "auth"}, CreatedAt: time.Now()},
+			constraints: &ContextConstraints{MaxTokens: 1000, Strategy: StrategyRelevance, PreferredTypes: []string{"test", "source"}},
+			same:        true,
+		},
+		{
+			name:        "different keywords",
+			task:        &Task{Description: "fix", Keywords: []string{"auth"}},
+			constraints: base,
+		},
+		{
+			name:        "explicit files",
+			task:        &Task{Description: "fix", Keywords: []string{"auth", "token"}, Files: []string{"a.go"}},
+			constraints: base,
+		},
+		{
+			name:        "tests included",
+			task:        &Task{Description: "fix", Keywords: []string{"auth", "token"}},
+			constraints: &ContextConstraints{MaxTokens: 1000, Strategy: StrategyRelevance, PreferredTypes: []string{"source", "test"}, IncludeTests: true},
+		},
+		{
+			name:        "packing mode",
+			task:        &Task{Description: "fix", Keywords: []string{"auth", "token"}},
+			constraints: &ContextConstraints{MaxTokens: 1000, Strategy: StrategyRelevance, PreferredTypes: []string{"source", "test"}, PackingMode: PackingKnapsack},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := optimizer.generateCacheKey(project, tt.task, tt.constraints)
+			if (key == baseKey) != tt.same {
+				t.Errorf("key equal to base = %v, expected %v", key == baseKey, tt.same)
+			}
+		})
+	}
+}
+
+// TestStrategiesBreakTiesByPath tests that files with equal scores are
+// selected in path order by every strategy, whatever order the project lists
+// them in
+func TestStrategiesBreakTiesByPath(t *testing.T) {
+	tokens := make(map[string]int)
+	scores := make(map[string]float64)
+	for _, name := range []string{"f.go", "d.go", "b.go", "e.go", "a.go", "c.go"} {
+		tokens["/project/"+name] = 100
+		scores["/project/"+name] = 0.5
+	}
+	modified := time.Now().Add(-time.Hour)
+	expected := []string{"/project/a.go", "/project/b.go", "/project/c.go"}
+
+	for _, strategy := range []SelectionStrategy{StrategyRelevance, StrategyDependency, StrategyFreshness, StrategyCompactness, StrategyBalanced} {
+		t.Run(string(strategy), func(t *testing.T) {
+			for run := 0; run < 10; run++ {
+				project := newTestProject(tokens)
+				for i := range project.Files {
+					project.Files[i].LastModified = modified
+				}
+				selection, err := newTestOptimizer(scores).SelectOptimalContext(context.Background(), project, &Task{Type: TaskTypeFeature}, &ContextConstraints{MaxTokens: 300, MaxFiles: 10, Strategy: strategy})
+				if err != nil {
+					t.Fatalf("SelectOptimalContext failed: %v", err)
+				}
+				if paths := selectedPaths(selection); !reflect.DeepEqual(paths, expected) {
+					t.Fatalf("run %d selected %v, expected %v", run, paths, expected)
+				}
+			}
+		})
+	}
+}
+
+// cancellingAnalyzer cancels the selection's context once it has scored a
+// number of files
+type cancellingAnalyzer struct {
+	*stubAnalyzer
+	cancel context.CancelFunc
+	after  int
+	scored int
+}
+
+func (a *cancellingAnalyzer) ScoreFileRelevance(file *FileInfo, task *Task) float64 {
+	if a.scored++; a.scored == a.after {
+		a.cancel()
+	}
+	return 0.5
+}
+
+// TestSelectionStopsWhenCancelled tests that every strategy, and the retries
+// of OptimizeForTokenBudget, stop scoring soon after the context is cancelled
+func TestSelectionStopsWhenCancelled(t *testing.T) {
+	project := &ProjectContext{RootPath: "/project", Languages: map[string]int{"go": 20000}}
+	for i := 0; i < 20000; i++ {
+		project.Files = append(project.Files, FileInfo{Path: fmt.Sprintf("/project/pkg%d/file%d.go", i%100, i), TokenCount: 100, FileType: "source", Language: "go"})
+	}
+	const cancelAfter = 100
+
+	run := func(t *testing.T, strategy SelectionStrategy, optimize bool) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		analyzer := &cancellingAnalyzer{stubAnalyzer: newStubAnalyzer(nil), cancel: cancel, after: cancelAfter}
+		optimizer := NewDefaultOptimizer(analyzer, nil, nil, &OptimizerConfig{DefaultStrategy: strategy})
+
+		var err error
+		if optimize {
+			_, err = optimizer.OptimizeForTokenBudget(ctx, project, 1000, &Task{Type: TaskTypeFeature})
+		} else {
+			_, err = optimizer.SelectOptimalContext(ctx, project, &Task{Type: TaskTypeFeature}, &ContextConstraints{MaxTokens: 1000, MaxFiles: 10, Strategy: strategy})
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		if analyzer.scored > cancelAfter+selectionCheckInterval {
+			t.Errorf("scored %d files after cancelling at %d", analyzer.scored, cancelAfter)
+		}
+	}
+
+	for _, strategy := range []SelectionStrategy{StrategyRelevance, StrategyDependency, StrategyFreshness, StrategyCompactness, StrategyBalanced} {
+		t.Run(string(strategy), func(t *testing.T) {
+			run(t, strategy, false)
+		})
+	}
+	t.Run("optimize for budget", func(t *testing.T) {
+		run(t, StrategyRelevance, true)
+	})
+}
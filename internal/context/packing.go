@@ -0,0 +1,108 @@
+package context
+
+import "math"
+
+// maxKnapsackCells bounds the size of the knapsack DP table
+const maxKnapsackCells = 4_000_000
+
+// packKnapsack selects the subset of ranked files with the highest total
+// relevance that fits within maxTokens and maxFiles (0/1 knapsack).
+//
+// Token costs are grouped into buckets to keep the DP table small. Costs are
+// rounded up to whole buckets, so the result never exceeds the real budget;
+// the price is that a few near-fit combinations may be missed.
+func packKnapsack(ranked []ContextFile, maxTokens, maxFiles int) []ContextFile {
+	if maxTokens <= 0 || maxFiles <= 0 {
+		return []ContextFile{}
+	}
+
+	// Files larger than the whole budget can never be selected
+	candidates := make([]int, 0, len(ranked))
+	for i, file := range ranked {
+		if file.FileInfo.TokenCount <= maxTokens {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return []ContextFile{}
+	}
+
+	fileSlots := maxFiles
+	if fileSlots > len(candidates) {
+		fileSlots = len(candidates)
+	}
+
+	buckets := maxTokens
+	if limit := maxKnapsackCells / (len(candidates) * (fileSlots + 1)); buckets > limit {
+		buckets = limit
+	}
+	if buckets < 1 {
+		buckets = 1
+	}
+	bucketSize := (maxTokens + buckets - 1) / buckets
+	capacity := maxTokens / bucketSize
+
+	// best[f][b] is the highest relevance using exactly f files and at most b buckets;
+	// taken[i][f][b] records whether candidate i was added to reach that state
+	best := make([][]float64, fileSlots+1)
+	for f := range best {
+		best[f] = make([]float64, capacity+1)
+		if f > 0 {
+			for b := range best[f] {
+				best[f][b] = math.Inf(-1) // Not reachable yet
+			}
+		}
+	}
+	taken := make([][][]bool, len(candidates))
+
+	for i, idx := range candidates {
+		file := ranked[idx]
+		cost := (file.FileInfo.TokenCount + bucketSize - 1) / bucketSize
+		value := file.RelevanceScore
+
+		taken[i] = make([][]bool, fileSlots+1)
+		for f := range taken[i] {
+			taken[i][f] = make([]bool, capacity+1)
+		}
+
+		// Iterate downwards so each file is used at most once
+		for f := fileSlots; f >= 1; f-- {
+			for b := capacity; b >= cost; b-- {
+				if candidate := best[f-1][b-cost] + value; candidate > best[f][b] {
+					best[f][b] = candidate
+					taken[i][f][b] = true
+				}
+			}
+		}
+	}
+
+	// Find the best final state
+	bestFiles, bestValue := 0, best[0][capacity]
+	for f := 1; f <= fileSlots; f++ {
+		if best[f][capacity] > bestValue {
+			bestFiles, bestValue = f, best[f][capacity]
+		}
+	}
+
+	// Walk back through the decisions to recover the chosen files
+	chosen := make([]bool, len(ranked))
+	f, b := bestFiles, capacity
+	for i := len(candidates) - 1; i >= 0 && f > 0; i-- {
+		if taken[i][f][b] {
+			idx := candidates[i]
+			chosen[idx] = true
+			b -= (ranked[idx].FileInfo.TokenCount + bucketSize - 1) / bucketSize
+			f--
+		}
+	}
+
+	// Keep the original ranking order in the result
+	selectedFiles := []ContextFile{}
+	for i, file := range ranked {
+		if chosen[i] {
+			selectedFiles = append(selectedFiles, file)
+		}
+	}
+
+	return selectedFiles
+}
@@ -0,0 +1,272 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestKnapsackPackingBeatsGreedy tests that knapsack packing uses the budget better than greedy fill
+func TestKnapsackPackingBeatsGreedy(t *testing.T) {
+	project := newTestProject(map[string]int{
+		"large.go":  80,
+		"small1.go": 50,
+		"small2.go": 50,
+		"noise.go":  30,
+	})
+	optimizer := newTestOptimizer(map[string]float64{
+		"large.go":  0.9,
+		"small1.go": 0.8,
+		"small2.go": 0.8,
+		"noise.go":  0.2,
+	})
+	task := &Task{Type: TaskTypeFeature, Description: "add feature"}
+
+	selectWith := func(mode PackingMode) *SelectedContext {
+		selection, err := optimizer.SelectOptimalContext(context.Background(), project, task, &ContextConstraints{
+			MaxTokens:         100,
+			MaxFiles:          10,
+			MinRelevanceScore: 0.1,
+			Strategy:          StrategyRelevance,
+			PackingMode:       mode,
+		})
+		if err != nil {
+			t.Fatalf("SelectOptimalContext(%s) failed: %v", mode, err)
+		}
+		return selection
+	}
+
+	totalRelevance := func(selection *SelectedContext) float64 {
+		total := 0.0
+		for _, file := range selection.Files {
+			total += file.RelevanceScore
+		}
+		return total
+	}
+
+	greedy := selectWith(PackingGreedy)
+	knapsack := selectWith(PackingKnapsack)
+
+	if knapsack.TotalTokens > 100 {
+		t.Fatalf("knapsack selection uses %d tokens, exceeding the budget", knapsack.TotalTokens)
+	}
+	if totalRelevance(knapsack) <= totalRelevance(greedy) {
+		t.Errorf("knapsack relevance %.2f (%v) should beat greedy %.2f (%v)",
+			totalRelevance(knapsack), selectedPaths(knapsack), totalRelevance(greedy), selectedPaths(greedy))
+	}
+
+	paths := selectedPaths(knapsack)
+	if !containsPath(paths, "small1.go") || !containsPath(paths, "small2.go") {
+		t.Errorf("knapsack selected %v, expected both small files", paths)
+	}
+}
+
+// TestKnapsackPackingRespectsMaxFiles tests the file count limit
+func TestKnapsackPackingRespectsMaxFiles(t *testing.T) {
+	ranked := []ContextFile{
+		{FileInfo: &FileInfo{Path: "a.go", TokenCount: 10}, RelevanceScore: 0.5},
+		{FileInfo: &FileInfo{Path: "b.go", TokenCount: 10}, RelevanceScore: 0.4},
+		{FileInfo: &FileInfo{Path: "c.go", TokenCount: 10}, RelevanceScore: 0.3},
+		{FileInfo: &FileInfo{Path: "d.go", TokenCount: 25}, RelevanceScore: 0.9},
+	}
+
+	selected := packKnapsack(ranked, 40, 2)
+	if len(selected) != 2 {
+		t.Fatalf("selected %d files, expected 2", len(selected))
+	}
+	if selected[0].FileInfo.Path != "a.go" || selected[1].FileInfo.Path != "d.go" {
+		t.Errorf("selected %s and %s, expected a.go and d.go in ranking order",
+			selected[0].FileInfo.Path, selected[1].FileInfo.Path)
+	}
+}
+
+// TestAllocatePackingCapsFiles tests that allocation spreads the budget over
+// several files, keeping each within its computed cap
+func TestAllocatePackingCapsFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"huge.go": "package huge\n\n" + strings.Repeat("func step() { value := compute(input, options); record(value) }\n", 300),
+		"a.go":    "package a\n\n" + strings.Repeat("var a = 1\n", 60),
+		"b.go":    "package b\n\n" + strings.Repeat("var b = 2\n", 60),
+		"c.go":    "package c\n\n" + strings.Repeat("var c = 3\n", 60),
+	}
+	writeProjectFiles(t, root, files)
+
+	counter := NewSimpleTokenCounter()
+	tokens := make(map[string]int)
+	scores := make(map[string]float64)
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		tokens[path], _ = counter.CountTokens(content)
+		scores[path] = 0.6
+	}
+	scores[filepath.Join(root, "huge.go")] = 0.9
+
+	project := newTestProject(tokens)
+	task := &Task{Type: TaskTypeFeature, Description: "add feature"}
+	constraints := &ContextConstraints{
+		MaxTokens:         1200,
+		MaxFiles:          10,
+		MinRelevanceScore: 0.1,
+		Strategy:          StrategyRelevance,
+		PackingMode:       PackingAllocate,
+	}
+
+	optimizers := map[string]*DefaultOptimizer{
+		"truncation":  newTestOptimizer(scores),
+		"compression": NewDefaultOptimizer(newStubAnalyzer(scores), nil, NewDefaultContextCompressor(counter, nil), &OptimizerConfig{DefaultStrategy: StrategyRelevance}),
+	}
+	for name, optimizer := range optimizers {
+		t.Run(name, func(t *testing.T) {
+			selection, err := optimizer.SelectOptimalContext(context.Background(), project, task, constraints)
+			if err != nil {
+				t.Fatalf("SelectOptimalContext failed: %v", err)
+			}
+
+			if selection.TotalFiles != len(files) {
+				t.Errorf("selected %v, expected all %d files", selectedPaths(selection), len(files))
+			}
+			if selection.TotalTokens > constraints.MaxTokens {
+				t.Errorf("selection uses %d tokens, exceeding the budget of %d", selection.TotalTokens, constraints.MaxTokens)
+			}
+			for _, file := range selection.Files {
+				allocated, _ := file.Metadata["allocated_tokens"].(int)
+				actual, _ := file.Metadata["actual_tokens"].(int)
+				if actual > allocated || actual != file.FileInfo.TokenCount {
+					t.Errorf("%s uses %d tokens (FileInfo %d) of its %d allocation", file.FileInfo.Path, actual, file.FileInfo.TokenCount, allocated)
+				}
+			}
+
+			huge := selection.Files[0]
+			if huge.Metadata["allocation_method"] == string(CompressionNone) || huge.Content == "" {
+				t.Errorf("huge file method = %v, expected it to be shrunk", huge.Metadata["allocation_method"])
+			}
+			for _, file := range project.Files {
+				if file.TokenCount != tokens[file.Path] {
+					t.Errorf("project token count for %s changed to %d", file.Path, file.TokenCount)
+				}
+			}
+		})
+	}
+}
+
+// TestComputeAllocationsDropsTinyShares tests that files whose share would be
+// too small to be useful are dropped in favor of more relevant ones
+func TestComputeAllocationsDropsTinyShares(t *testing.T) {
+	files := make([]ContextFile, 10)
+	for i := range files {
+		files[i] = ContextFile{FileInfo: &FileInfo{TokenCount: 1000}, RelevanceScore: 1.0 - float64(i)*0.05}
+	}
+
+	allocations := computeAllocations(files, 300)
+	total, kept := 0, 0
+	for i, allocation := range allocations {
+		total += allocation
+		if allocation > 0 {
+			kept++
+			if allocation < minAllocationTokens {
+				t.Errorf("file %d allocated %d tokens, below the minimum", i, allocation)
+			}
+		}
+	}
+	if kept == 0 || kept == len(files) || allocations[0] == 0 {
+		t.Errorf("allocations = %v, expected the top files to share the budget", allocations)
+	}
+	if total > 300 {
+		t.Errorf("allocated %d tokens, exceeding the budget", total)
+	}
+}
+
+// TestOversizedFileFitsBudget tests that a best file larger than the whole
+// budget is cut down to it rather than leaving the selection empty, and that
+// the selection says why
+func TestOversizedFileFitsBudget(t *testing.T) {
+	root := t.TempDir()
+	content := "package huge\n\n" + strings.Repeat("func step() { value := compute(input, options); record(value) }\n", 300)
+	writeProjectFiles(t, root, map[string]string{"huge.go": content})
+	huge := filepath.Join(root, "huge.go")
+	tokens, _ := NewSimpleTokenCounter().CountTokens(content)
+
+	tests := []struct {
+		name     string
+		path     string
+		included bool
+	}{
+		{name: "readable file is truncated", path: huge, included: true},
+		{name: "unreadable file is explained", path: filepath.Join(root, "missing.go")},
+	}
+
+	for _, tt := range tests {
+		for _, mode := range []PackingMode{PackingGreedy, PackingKnapsack} {
+			t.Run(tt.name+"/"+string(mode), func(t *testing.T) {
+				project := newTestProject(map[string]int{tt.path: tokens})
+				constraints := &ContextConstraints{MaxTokens: 200, MaxFiles: 10, Strategy: StrategyRelevance, PackingMode: mode}
+				selection, err := newTestOptimizer(map[string]float64{tt.path: 0.9}).SelectOptimalContext(context.Background(), project, &Task{Type: TaskTypeFeature}, constraints)
+				if err != nil {
+					t.Fatalf("SelectOptimalContext failed: %v", err)
+				}
+
+				overflow, ok := selection.Metadata["budget_exceeded_by_single_file"].(*BudgetOverflow)
+				if !ok || overflow.Path != tt.path || overflow.FileTokens != tokens || overflow.MaxTokens != 200 {
+					t.Fatalf("budget_exceeded_by_single_file = %+v, expected it to name %s", selection.Metadata["budget_exceeded_by_single_file"], tt.path)
+				}
+				if selection.TotalTokens > constraints.MaxTokens {
+					t.Errorf("selection uses %d tokens, exceeding the budget of %d", selection.TotalTokens, constraints.MaxTokens)
+				}
+				if !tt.included {
+					if selection.TotalFiles != 0 || overflow.Method != "" {
+						t.Errorf("selected %v with method %q, expected nothing", selectedPaths(selection), overflow.Method)
+					}
+					return
+				}
+				if selection.TotalFiles != 1 || overflow.Method == "" {
+					t.Fatalf("selected %v with method %q, expected the file cut to fit", selectedPaths(selection), overflow.Method)
+				}
+				if content := selection.Files[0].Content; !strings.HasPrefix(content, "package huge") {
+					t.Errorf("content = %.40q, expected the start of the file", content)
+				}
+			})
+		}
+	}
+}
+
+// TestOversizedFileKeepsTaskWindow tests that an oversized file is cut down
+// to the region the task asks about when that's what fits the budget
+func TestOversizedFileKeepsTaskWindow(t *testing.T) {
+	root := t.TempDir()
+	var content strings.Builder
+	content.WriteString("package billing\n\n")
+	for i := 0; i < 40; i++ {
+		name := fmt.Sprintf("Step%d", i)
+		if i == 25 {
+			name = "ParseInvoice"
+		}
+		fmt.Fprintf(&content, "// %s runs one stage of the pipeline\nfunc %s(input string) string {\n", name, name)
+		for j := 0; j < 6; j++ {
+			fmt.Fprintf(&content, "\tinput = transform(input, %d)\n", j)
+		}
+		content.WriteString("\treturn input\n}\n\n")
+	}
+	writeProjectFiles(t, root, map[string]string{"billing.go": content.String()})
+	path := filepath.Join(root, "billing.go")
+	tokens, _ := NewSimpleTokenCounter().CountTokens(content.String())
+
+	optimizer := newTestOptimizer(map[string]float64{path: 0.9})
+	optimizer.compressor = NewDefaultContextCompressor(NewSimpleTokenCounter(), nil)
+	constraints := &ContextConstraints{MaxTokens: 200, MaxFiles: 10, Strategy: StrategyRelevance}
+	task := &Task{Type: TaskTypeDebug, Description: "fix invoice parsing", Keywords: []string{"invoice"}}
+	selection, err := optimizer.SelectOptimalContext(context.Background(), newTestProject(map[string]int{path: tokens}), task, constraints)
+	if err != nil {
+		t.Fatalf("SelectOptimalContext failed: %v", err)
+	}
+
+	overflow, ok := selection.Metadata["budget_exceeded_by_single_file"].(*BudgetOverflow)
+	if !ok || overflow.Method != string(CompressionWindow) {
+		t.Fatalf("budget_exceeded_by_single_file = %+v, expected the file cut to its task window", selection.Metadata["budget_exceeded_by_single_file"])
+	}
+	if selection.TotalFiles != 1 || !strings.Contains(selection.Files[0].Content, "func ParseInvoice(") {
+		t.Errorf("content = %q, expected it to keep ParseInvoice", selection.Files[0].Content)
+	}
+}
@@ -0,0 +1,59 @@
+package context
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// projectFiles reads a project's files through a file system holding the
+// tree under root. Paths stay OS paths under root, as in FileInfo, and are
+// mapped to slash-separated names within fsys. A nil *projectFiles reads the
+// local disk.
+type projectFiles struct {
+	root string
+	fsys fs.FS
+}
+
+// name returns the name of filePath within the file system
+func (p *projectFiles) name(filePath string) (string, error) {
+	rel, err := filepath.Rel(p.root, filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s under %s: %w", filePath, p.root, err)
+	}
+	name := filepath.ToSlash(rel)
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "open", Path: filePath, Err: fs.ErrNotExist}
+	}
+	return name, nil
+}
+
+// ReadFile reads the file at filePath
+func (p *projectFiles) ReadFile(filePath string) ([]byte, error) {
+	if p == nil {
+		return os.ReadFile(filePath)
+	}
+	name, err := p.name(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(p.fsys, name)
+}
+
+// Stat describes the file at filePath
+func (p *projectFiles) Stat(filePath string) (fs.FileInfo, error) {
+	if p == nil {
+		return os.Stat(filePath)
+	}
+	name, err := p.name(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(p.fsys, name)
+}
+
+// readFileContent reads a file from wherever it was analyzed from
+func readFileContent(file *FileInfo) ([]byte, error) {
+	return file.files.ReadFile(file.Path)
+}
@@ -0,0 +1,137 @@
+package context
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// projectSnapshotVersion is bumped whenever the snapshot format changes, so
+// old snapshots are re-analyzed rather than misread
+const projectSnapshotVersion = 1
+
+// ProjectSnapshot is a saved project analysis and what is needed to tell
+// whether the project has changed since
+type ProjectSnapshot struct {
+	Version     int                  `json:"version"`
+	SavedAt     time.Time            `json:"saved_at"`
+	Directories map[string]time.Time `json:"directories"` // Modification times of the directories holding analyzed files
+	Project     *ProjectContext      `json:"project"`
+}
+
+// SaveProjectContext writes project, including its dependency graph and
+// per-file token counts, to path. The file is replaced atomically so a
+// reader never sees a partial snapshot.
+func SaveProjectContext(path string, project *ProjectContext) error {
+	if project == nil {
+		return fmt.Errorf("no project to save")
+	}
+	snapshot := &ProjectSnapshot{
+		Version:     projectSnapshotVersion,
+		SavedAt:     time.Now(),
+		Directories: snapshotDirectories(project),
+		Project:     project,
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode project snapshot: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create project snapshot: %w", err)
+	}
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return fmt.Errorf("failed to write project snapshot: %w", err)
+	}
+	return nil
+}
+
+// LoadProjectContext reads a snapshot written by SaveProjectContext
+func LoadProjectContext(path string) (*ProjectSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project snapshot: %w", err)
+	}
+	var snapshot ProjectSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode project snapshot: %w", err)
+	}
+	if snapshot.Version != projectSnapshotVersion {
+		return nil, fmt.Errorf("unsupported project snapshot version %d", snapshot.Version)
+	}
+	if snapshot.Project == nil {
+		return nil, fmt.Errorf("project snapshot has no project")
+	}
+	return &snapshot, nil
+}
+
+// StalePaths lists the files modified or removed since they were analyzed,
+// and the directories whose entries changed since the snapshot was saved,
+// which is how added files show up. An empty result means the snapshot is
+// current.
+func (s *ProjectSnapshot) StalePaths() []string {
+	var stale []string
+	for _, file := range s.Project.Files {
+		info, err := os.Stat(file.Path)
+		if err != nil || info.ModTime().After(file.LastModified) || info.Size() != file.Size {
+			stale = append(stale, file.Path)
+		}
+	}
+	for dir, modTime := range s.Directories {
+		info, err := os.Stat(dir)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			stale = append(stale, dir)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// snapshotDirectories records the modification time of every directory from
+// the project root down to each analyzed file
+func snapshotDirectories(project *ProjectContext) map[string]time.Time {
+	root := filepath.Clean(project.RootPath)
+	directories := make(map[string]time.Time)
+	for _, file := range project.Files {
+		for dir := filepath.Dir(file.Path); ; dir = filepath.Dir(dir) {
+			if _, seen := directories[dir]; seen {
+				break
+			}
+			if info, err := os.Stat(dir); err == nil {
+				directories[dir] = info.ModTime()
+			}
+			if dir == root || dir == filepath.Dir(dir) {
+				break
+			}
+		}
+	}
+	return directories
+}
+
+// Restore caches the snapshot's analysis, patching it first when the
+// project has changed since it was saved. Unchanged projects are served
+// without touching any file beyond the staleness check.
+func (c *CachingAnalyzer) Restore(ctx context.Context, snapshot *ProjectSnapshot) (*ProjectContext, error) {
+	key := analysisKey(snapshot.Project.RootPath)
+	c.mutex.Lock()
+	c.entries[key] = analysisEntry{project: snapshot.Project, analyzedAt: time.Now()}
+	c.mutex.Unlock()
+
+	if len(snapshot.StalePaths()) == 0 {
+		return snapshot.Project, nil
+	}
+	return c.Refresh(ctx, key)
+}
@@ -0,0 +1,126 @@
+package context
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestProjectSnapshotRoundTrip tests that a saved analysis reloads with its
+// dependency graph intact and is reported stale once files change
+func TestProjectSnapshotRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{
+		"go.mod":         "module example.com/app\n\ngo 1.21\n",
+		"main.go":        "package main\n\nimport \"example.com/app/store\"\n\nfunc main() { store.Open() }\n",
+		"store/store.go": "package store\n\n// Open opens the store\nfunc Open() {}\n",
+	})
+
+	ctx := context.Background()
+	project, err := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil).AnalyzeProject(ctx, root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+	if len(project.DependencyGraph.Edges) == 0 {
+		t.Fatal("analysis found no dependency edges to round-trip")
+	}
+
+	path := filepath.Join(t.TempDir(), "project.json")
+	if err := SaveProjectContext(path, project); err != nil {
+		t.Fatalf("SaveProjectContext failed: %v", err)
+	}
+	snapshot, err := LoadProjectContext(path)
+	if err != nil {
+		t.Fatalf("LoadProjectContext failed: %v", err)
+	}
+
+	loaded := snapshot.Project
+	if !reflect.DeepEqual(loaded.DependencyGraph.Edges, project.DependencyGraph.Edges) {
+		t.Errorf("edges = %v, expected %v", loaded.DependencyGraph.Edges, project.DependencyGraph.Edges)
+	}
+	if len(loaded.DependencyGraph.Nodes) != len(project.DependencyGraph.Nodes) {
+		t.Errorf("%d nodes, expected %d", len(loaded.DependencyGraph.Nodes), len(project.DependencyGraph.Nodes))
+	}
+	for i, file := range loaded.Files {
+		if file.Path != project.Files[i].Path || file.TokenCount != project.Files[i].TokenCount {
+			t.Errorf("file %d = %s with %d tokens, expected %s with %d", i, file.Path, file.TokenCount, project.Files[i].Path, project.Files[i].TokenCount)
+		}
+	}
+	if stale := snapshot.StalePaths(); len(stale) != 0 {
+		t.Errorf("StalePaths = %v for an unchanged project", stale)
+	}
+
+	storePath := filepath.Join(root, "store", "store.go")
+	writeProjectFiles(t, root, map[string]string{"store/store.go": "package store\n\n// Open opens the store\nfunc Open() {}\n\n// Close closes it\nfunc Close() {}\n"})
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(storePath, later, later); err != nil {
+		t.Fatalf("failed to touch %s: %v", storePath, err)
+	}
+	writeProjectFiles(t, root, map[string]string{"util.go": "package main\n"})
+
+	stale := snapshot.StalePaths()
+	sort.Strings(stale)
+	expected := []string{filepath.Clean(root), storePath}
+	if !reflect.DeepEqual(stale, expected) {
+		t.Errorf("StalePaths = %v, expected %v", stale, expected)
+	}
+}
+
+// TestCachingAnalyzerRestore tests that restoring a current snapshot skips
+// analysis and a stale one is patched
+func TestCachingAnalyzerRestore(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{
+		"main.go":   "package main\n",
+		"README.md": "# App\n",
+	})
+
+	ctx := context.Background()
+	project, err := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil).AnalyzeProject(ctx, root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "project.json")
+	if err := SaveProjectContext(path, project); err != nil {
+		t.Fatalf("SaveProjectContext failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		edit  map[string]string
+		files int
+		fresh bool // Restore returned the snapshot's own analysis
+	}{
+		{name: "unchanged", files: 2, fresh: true},
+		{name: "file added", edit: map[string]string{"util.go": "package main\n"}, files: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeProjectFiles(t, root, tt.edit)
+			snapshot, err := LoadProjectContext(path)
+			if err != nil {
+				t.Fatalf("LoadProjectContext failed: %v", err)
+			}
+
+			analyzer := NewCachingAnalyzer(NewDefaultAnalyzer(NewSimpleTokenCounter(), nil), 0)
+			restored, err := analyzer.Restore(ctx, snapshot)
+			if err != nil {
+				t.Fatalf("Restore failed: %v", err)
+			}
+			if (restored == snapshot.Project) != tt.fresh {
+				t.Errorf("restored the snapshot as is = %v, expected %v", restored == snapshot.Project, tt.fresh)
+			}
+			if restored.TotalFiles != tt.files {
+				t.Errorf("TotalFiles = %d, expected %d", restored.TotalFiles, tt.files)
+			}
+			if cached, ok := analyzer.Cached(root); !ok || cached != restored {
+				t.Error("restored analysis not cached")
+			}
+		})
+	}
+}
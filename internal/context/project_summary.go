@@ -0,0 +1,261 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"math"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// summaryHotspots and summaryExports bound the longest sections of a project
+// summary, which the budget would otherwise fill with the least useful lines
+const (
+	summaryHotspots = 10
+	summaryExports  = 8
+)
+
+// entryPointNames are the file names other languages start programs from
+var entryPointNames = map[string]bool{
+	"main.py": true, "__main__.py": true, "app.py": true, "manage.py": true,
+	"index.js": true, "index.ts": true, "main.js": true, "main.ts": true,
+	"main.rs": true, "Main.java": true,
+}
+
+// ProjectSummary is a token-bounded map of a project for orienting a model
+// before it knows which files a task needs
+type ProjectSummary struct {
+	Text        string   `json:"text"`
+	TokenCount  int      `json:"token_count"`
+	Budget      int      `json:"budget"`
+	Truncated   bool     `json:"truncated"`    // Lines were left out to fit the budget
+	EntryPoints []string `json:"entry_points"` // Main package directories, or entry files for other languages
+	Hotspots    []string `json:"hotspots"`     // Graph keys of the most depended-on files
+}
+
+// GenerateProjectSummary describes project within budget tokens: its
+// languages, entry points, the files most others depend on, its packages and
+// their exported APIs, in that order of priority. Lines that would overrun
+// the budget are left out.
+func GenerateProjectSummary(ctx context.Context, project *ProjectContext, budget int) (*ProjectSummary, error) {
+	if budget <= 0 {
+		return nil, fmt.Errorf("summary budget must be positive, got %d", budget)
+	}
+
+	entryPoints, err := projectEntryPoints(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	summary := &ProjectSummary{Budget: budget, EntryPoints: entryPoints, Hotspots: dependencyHotspots(project.DependencyGraph)}
+
+	writer := &summaryWriter{counter: NewSimpleTokenCounter(), budget: budget}
+	header := fmt.Sprintf("Project %s: %d files, %d tokens", filepath.Base(project.RootPath), project.TotalFiles, project.TotalTokens)
+	if !writer.add(header) {
+		return nil, fmt.Errorf("summary budget of %d tokens is too small for the project header", budget)
+	}
+	writer.add("Languages: " + languageBreakdown(project.Languages))
+
+	writer.section("Entry points:", entryPoints)
+	var hotspots []string
+	for _, key := range summary.Hotspots {
+		hotspots = append(hotspots, fmt.Sprintf("%s (used by %d files)", key, len(project.DependencyGraph.Nodes[key].Dependents)))
+	}
+	writer.section("Dependency hotspots:", hotspots)
+	packages, exports := packageOverview(project)
+	writer.section("Packages:", packages)
+	writer.section("Exported APIs:", exports)
+
+	summary.Text = strings.Join(writer.lines, "\n")
+	summary.TokenCount = writer.tokens
+	summary.Truncated = writer.truncated
+	return summary, nil
+}
+
+// summaryWriter collects summary lines while they fit the budget
+type summaryWriter struct {
+	counter   TokenCounter
+	budget    int
+	lines     []string
+	tokens    int
+	truncated bool
+}
+
+// add appends lines when they fit the budget together, and reports whether
+// they did
+func (w *summaryWriter) add(lines ...string) bool {
+	candidate := strings.Join(append(append([]string{}, w.lines...), lines...), "\n")
+	tokens, err := w.counter.CountTokens(candidate)
+	if err != nil || tokens > w.budget {
+		w.truncated = true
+		return false
+	}
+	w.lines = append(w.lines, lines...)
+	w.tokens = tokens
+	return true
+}
+
+// section adds a titled list, stopping at the first item that doesn't fit.
+// The title only goes in with at least one item.
+func (w *summaryWriter) section(title string, items []string) {
+	if len(items) == 0 || !w.add("", title, "- "+items[0]) {
+		return
+	}
+	for _, item := range items[1:] {
+		if !w.add("- " + item) {
+			return
+		}
+	}
+}
+
+// languageBreakdown lists languages by file count, most common first
+func languageBreakdown(languages map[string]int) string {
+	names := make([]string, 0, len(languages))
+	for name := range languages {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if languages[names[i]] != languages[names[j]] {
+			return languages[names[i]] > languages[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s (%d)", name, languages[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// projectEntryPoints returns the directories of Go main packages and the
+// conventional entry files of other languages, relative to the project root
+func projectEntryPoints(ctx context.Context, project *ProjectContext) ([]string, error) {
+	seen := make(map[string]bool)
+	var entryPoints []string
+	for _, file := range project.Files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if file.FileType == "test" || file.Generated {
+			continue
+		}
+
+		key := project.GraphKey(file.Path)
+		entry := ""
+		switch {
+		case file.Language == "go" && !strings.HasSuffix(file.Path, "_test.go"):
+			parsed, err := parser.ParseFile(token.NewFileSet(), file.Path, nil, parser.PackageClauseOnly)
+			if err == nil && parsed.Name.Name == "main" {
+				entry = path.Dir(filepath.ToSlash(key))
+			}
+		case entryPointNames[filepath.Base(file.Path)]:
+			entry = filepath.ToSlash(key)
+		}
+		if entry != "" && !seen[entry] {
+			seen[entry] = true
+			entryPoints = append(entryPoints, entry)
+		}
+	}
+	sort.Strings(entryPoints)
+	return entryPoints, nil
+}
+
+// dependencyHotspots returns the graph keys of the files the most other
+// files depend on
+func dependencyHotspots(graph *DependencyGraph) []string {
+	if graph == nil {
+		return nil
+	}
+	var keys []string
+	for key, node := range graph.Nodes {
+		if len(node.Dependents) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		di, dj := len(graph.Nodes[keys[i]].Dependents), len(graph.Nodes[keys[j]].Dependents)
+		if di != dj {
+			return di > dj
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > summaryHotspots {
+		keys = keys[:summaryHotspots]
+	}
+	return keys
+}
+
+// packageOverview returns a line per source directory with its size, and a
+// line per directory with exported names in the dependency graph, the most
+// central directories first
+func packageOverview(project *ProjectContext) (packages, exports []string) {
+	type packageInfo struct {
+		files, tokens int
+		centrality    float64
+		exports       []string
+	}
+	byDir := make(map[string]*packageInfo)
+	for _, file := range project.Files {
+		if file.FileType != "source" || strings.HasSuffix(file.Path, "_test.go") {
+			continue
+		}
+		key := project.GraphKey(file.Path)
+		dir := path.Dir(filepath.ToSlash(key))
+		info, exists := byDir[dir]
+		if !exists {
+			info = &packageInfo{}
+			byDir[dir] = info
+		}
+		info.files++
+		info.tokens += file.TokenCount
+		if project.DependencyGraph != nil {
+			if node, ok := project.DependencyGraph.Nodes[key]; ok {
+				info.centrality = math.Max(info.centrality, project.DependencyGraph.Centrality(key))
+				info.exports = append(info.exports, node.Exports...)
+			}
+		}
+	}
+
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		packages = append(packages, fmt.Sprintf("%s: %d files, %d tokens", dir, byDir[dir].files, byDir[dir].tokens))
+	}
+
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return byDir[dirs[i]].centrality > byDir[dirs[j]].centrality
+	})
+	for _, dir := range dirs {
+		names := uniqueSorted(byDir[dir].exports)
+		if len(names) == 0 {
+			continue
+		}
+		more := ""
+		if len(names) > summaryExports {
+			more = fmt.Sprintf(" and %d more", len(names)-summaryExports)
+			names = names[:summaryExports]
+		}
+		exports = append(exports, fmt.Sprintf("%s: %s%s", dir, strings.Join(names, ", "), more))
+	}
+	return packages, exports
+}
+
+// uniqueSorted returns names sorted without repeats
+func uniqueSorted(names []string) []string {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, name := range sorted {
+		if i == 0 || name != sorted[i-1] {
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,42 @@
+package context
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGenerateProjectSummary tests that a summary of this repository stays
+// within its budget and names the teeny-orb main package
+func TestGenerateProjectSummary(t *testing.T) {
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatalf("failed to resolve repository root: %v", err)
+	}
+	project, err := NewDefaultAnalyzer(NewSimpleTokenCounter(), nil).AnalyzeProject(context.Background(), root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+
+	for _, budget := range []int{150, 1000} {
+		summary, err := GenerateProjectSummary(context.Background(), project, budget)
+		if err != nil {
+			t.Fatalf("GenerateProjectSummary(%d) failed: %v", budget, err)
+		}
+		tokens, _ := NewSimpleTokenCounter().CountTokens(summary.Text)
+		if summary.TokenCount > budget || tokens > budget {
+			t.Errorf("summary of %d tokens (counted %d) is over the %d budget", summary.TokenCount, tokens, budget)
+		}
+		if !containsPath(summary.EntryPoints, "cmd/teeny-orb") || !strings.Contains(summary.Text, "- cmd/teeny-orb\n") {
+			t.Errorf("summary within %d tokens does not name cmd/teeny-orb:\n%s", budget, summary.Text)
+		}
+		if !summary.Truncated {
+			t.Errorf("summary within %d tokens expected to leave out packages", budget)
+		}
+	}
+
+	if _, err := GenerateProjectSummary(context.Background(), project, 5); err == nil {
+		t.Error("expected a budget too small for the header to fail")
+	}
+}
@@ -0,0 +1,199 @@
+package context
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// PromptOptions controls how RenderPrompt formats a selection
+type PromptOptions struct {
+	IncludeManifest bool               // List every file with its language and tokens before the contents
+	LineNumbers     bool               // Number lines of files rendered in full; compressed or truncated content is left as is
+	BaseDir         string             // Paths are shown relative to this directory when set
+	Compressed      *CompressedContext // Compressed content to render in place of the selected files' own, matched by path
+	TokenCounter    TokenCounter       // Counts the rendered prompt; defaults to SimpleTokenCounter
+}
+
+// RenderedPrompt is a selection rendered as text ready to send to a model
+type RenderedPrompt struct {
+	Text    string   `json:"text"`
+	Tokens  int      `json:"tokens"`
+	Files   int      `json:"files"`
+	Skipped []string `json:"skipped,omitempty"` // Files whose content couldn't be loaded
+}
+
+// renderedFile is a selected file's content and how it was reduced
+type renderedFile struct {
+	path     string
+	language string
+	tokens   int
+	content  string
+	note     string // Explains compression or truncation, empty for full content
+}
+
+// RenderPrompt concatenates the selected files in selection order, each under
+// a header with its path and language and inside a fenced code block.
+// Compressed or truncated content is rendered as is and followed by a note
+// saying how much was left out. The same selection and options always
+// produce the same text.
+func RenderPrompt(selection *SelectedContext, opts *PromptOptions) (*RenderedPrompt, error) {
+	if selection == nil {
+		return nil, fmt.Errorf("selection is nil")
+	}
+	if opts == nil {
+		opts = &PromptOptions{}
+	}
+	counter := opts.TokenCounter
+	if counter == nil {
+		counter = NewSimpleTokenCounter()
+	}
+
+	compressed := make(map[string]CompressedFile)
+	if opts.Compressed != nil {
+		for _, file := range opts.Compressed.CompressedFiles {
+			compressed[file.OriginalPath] = file
+		}
+	}
+
+	result := &RenderedPrompt{}
+	var files []renderedFile
+	for _, file := range selection.Files {
+		if file.FileInfo == nil {
+			continue
+		}
+		rendered, ok := renderContextFile(file, compressed, opts)
+		if !ok {
+			result.Skipped = append(result.Skipped, file.FileInfo.Path)
+			continue
+		}
+		files = append(files, rendered)
+	}
+
+	var builder strings.Builder
+	if opts.IncludeManifest && len(files) > 0 {
+		builder.WriteString("Files:\n")
+		for _, file := range files {
+			fmt.Fprintf(&builder, "- %s (%s, %d tokens)\n", file.path, languageLabel(file.language), file.tokens)
+		}
+		builder.WriteString("\n")
+	}
+
+	for i, file := range files {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		fence := codeFence(file.content)
+		fmt.Fprintf(&builder, "File: %s (%s)\n", file.path, languageLabel(file.language))
+		fmt.Fprintf(&builder, "%s%s\n", fence, file.language)
+		builder.WriteString(file.content)
+		if !strings.HasSuffix(file.content, "\n") {
+			builder.WriteString("\n")
+		}
+		builder.WriteString(fence + "\n")
+		if file.note != "" {
+			fmt.Fprintf(&builder, "Note: %s\n", file.note)
+		}
+	}
+
+	result.Text = builder.String()
+	result.Files = len(files)
+	tokens, err := counter.CountTokens(result.Text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count prompt tokens: %w", err)
+	}
+	result.Tokens = tokens
+	return result, nil
+}
+
+// renderContextFile picks a file's content, preferring compressed content,
+// then content set during selection, then the file on disk
+func renderContextFile(file ContextFile, compressed map[string]CompressedFile, opts *PromptOptions) (renderedFile, bool) {
+	rendered := renderedFile{
+		path:     displayPath(file.FileInfo.Path, opts.BaseDir),
+		language: file.FileInfo.Language,
+		tokens:   file.FileInfo.TokenCount,
+	}
+
+	if compressedFile, exists := compressed[file.FileInfo.Path]; exists {
+		rendered.content = compressedFile.CompressedContent
+		rendered.tokens = compressedFile.CompressedTokens
+		rendered.note = fmt.Sprintf("compressed with %s from %d to %d tokens", compressedFile.Method, compressedFile.OriginalTokens, compressedFile.CompressedTokens)
+		return rendered, true
+	}
+
+	content, ok := loadContextFileContent(file)
+	if !ok {
+		return rendered, false
+	}
+	rendered.content = content
+
+	if method, _ := file.Metadata["allocation_method"].(string); method != "" {
+		original, _ := file.Metadata["original_tokens"].(int)
+		actual, _ := file.Metadata["actual_tokens"].(int)
+		if actual < original {
+			verb := "truncated"
+			if method != "truncate" {
+				verb = "compressed with " + method
+			}
+			rendered.note = fmt.Sprintf("%s from %d to %d tokens", verb, original, actual)
+		}
+	}
+
+	if opts.LineNumbers && rendered.note == "" {
+		rendered.content = numberLines(content)
+	}
+	return rendered, true
+}
+
+// displayPath shows path relative to baseDir when it lies inside it
+func displayPath(path, baseDir string) string {
+	if baseDir == "" {
+		return path
+	}
+	relative, err := filepath.Rel(baseDir, path)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return path
+	}
+	return filepath.ToSlash(relative)
+}
+
+// numberLines prefixes each line with its right-aligned line number
+func numberLines(content string) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	width := len(fmt.Sprint(len(lines)))
+
+	var builder strings.Builder
+	for i, line := range lines {
+		fmt.Fprintf(&builder, "%*d | %s\n", width, i+1, line)
+	}
+	return builder.String()
+}
+
+// codeFence returns a backtick fence longer than any backtick run in content
+// so embedded code blocks can't close it early
+func codeFence(content string) string {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+			continue
+		}
+		run = 0
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// languageLabel names a language for headers, falling back for unknown files
+func languageLabel(language string) string {
+	if language == "" {
+		return "unknown"
+	}
+	return language
+}
@@ -0,0 +1,101 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRenderPrompt tests the manifest, headers, fences, line numbers and
+// truncation notes of a rendered selection
+func TestRenderPrompt(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(mainPath, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("failed to write main.go: %v", err)
+	}
+	readmePath := filepath.Join(dir, "README.md")
+
+	selection := &SelectedContext{
+		Files: []ContextFile{
+			{FileInfo: &FileInfo{Path: mainPath, Language: "go", TokenCount: 8}},
+			{
+				FileInfo: &FileInfo{Path: readmePath, Language: "markdown", TokenCount: 4},
+				Content:  "Run:\n```\ngo run .\n```\n" + allocationTruncationMarker,
+				Metadata: map[string]interface{}{"allocation_method": "truncate", "original_tokens": 40, "actual_tokens": 4},
+			},
+			{FileInfo: &FileInfo{Path: filepath.Join(dir, "missing.go"), Language: "go"}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		opts     *PromptOptions
+		contains []string
+		excludes []string
+	}{
+		{
+			name: "defaults",
+			contains: []string{
+				"File: " + mainPath + " (go)\n```go\npackage main\n",
+				"File: " + readmePath + " (markdown)\n````markdown\nRun:\n```\n",
+				"````\nNote: truncated from 40 to 4 tokens\n",
+			},
+			excludes: []string{"Files:", "1 | package main"},
+		},
+		{
+			name: "manifest and line numbers",
+			opts: &PromptOptions{IncludeManifest: true, LineNumbers: true, BaseDir: dir},
+			contains: []string{
+				"Files:\n- main.go (go, 8 tokens)\n- README.md (markdown, 4 tokens)\n\n",
+				"File: main.go (go)\n```go\n1 | package main\n2 | \n3 | func main() {}\n```\n",
+				"````markdown\nRun:\n",
+			},
+		},
+		{
+			name: "compressed content",
+			opts: &PromptOptions{
+				LineNumbers: true,
+				Compressed: &CompressedContext{CompressedFiles: []CompressedFile{
+					{OriginalPath: mainPath, CompressedContent: "func main() {}", OriginalTokens: 8, CompressedTokens: 3, Method: "extract"},
+				}},
+			},
+			contains: []string{"```go\nfunc main() {}\n```\nNote: compressed with extract from 8 to 3 tokens\n"},
+			excludes: []string{"package main"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rendered, err := RenderPrompt(selection, tt.opts)
+			if err != nil {
+				t.Fatalf("RenderPrompt failed: %v", err)
+			}
+
+			for _, expected := range tt.contains {
+				if !strings.Contains(rendered.Text, expected) {
+					t.Errorf("prompt missing %q:\n%s", expected, rendered.Text)
+				}
+			}
+			for _, unexpected := range tt.excludes {
+				if strings.Contains(rendered.Text, unexpected) {
+					t.Errorf("prompt contains %q:\n%s", unexpected, rendered.Text)
+				}
+			}
+			if rendered.Files != 2 || len(rendered.Skipped) != 1 {
+				t.Errorf("rendered %d files, skipped %v, expected 2 rendered and missing.go skipped", rendered.Files, rendered.Skipped)
+			}
+
+			tokens, _ := NewSimpleTokenCounter().CountTokens(rendered.Text)
+			if rendered.Tokens != tokens || tokens == 0 {
+				t.Errorf("tokens = %d, expected %d", rendered.Tokens, tokens)
+			}
+
+			again, _ := RenderPrompt(selection, tt.opts)
+			if again.Text != rendered.Text {
+				t.Error("rendering the same selection twice produced different text")
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package context
+
+import (
+	"sort"
+	"strings"
+)
+
+// QualityScorerConfig weights the components of a context quality score
+type QualityScorerConfig struct {
+	CoverageWeight   float64 `json:"coverage_weight"`
+	DependencyWeight float64 `json:"dependency_weight"`
+	BudgetWeight     float64 `json:"budget_weight"`
+}
+
+// ContextQuality breaks down the quality of a selection. Components that
+// can't be measured, such as dependency closure without a dependency graph,
+// are left out of the score rather than counted as perfect.
+type ContextQuality struct {
+	Score               float64  `json:"score"`                          // Weighted mean of the measured components, 0-1
+	KeywordCoverage     float64  `json:"keyword_coverage"`               // Share of task keywords found in a selected file's path or content
+	DependencyClosure   float64  `json:"dependency_closure"`             // Share of selected files' in-project dependencies that are selected too
+	BudgetUtilization   float64  `json:"budget_utilization"`             // Selected tokens over the token budget
+	MissingKeywords     []string `json:"missing_keywords,omitempty"`     // Sorted
+	MissingDependencies []string `json:"missing_dependencies,omitempty"` // Sorted dependency graph keys
+	Measured            []string `json:"measured"`                       // Components that contributed to Score
+}
+
+// ContextQualityScorer scores selections deterministically, so the same
+// selection always gets the same score
+type ContextQualityScorer struct {
+	project *ProjectContext
+	config  *QualityScorerConfig
+}
+
+// NewContextQualityScorer creates a scorer. project supplies the dependency
+// graph for closure checks and may be nil.
+func NewContextQualityScorer(project *ProjectContext, config *QualityScorerConfig) *ContextQualityScorer {
+	if config == nil {
+		config = &QualityScorerConfig{
+			CoverageWeight:   0.5,
+			DependencyWeight: 0.3,
+			BudgetWeight:     0.2,
+		}
+	}
+	return &ContextQualityScorer{project: project, config: config}
+}
+
+// SetProject replaces the project whose dependency graph closure is checked against
+func (s *ContextQualityScorer) SetProject(project *ProjectContext) {
+	s.project = project
+}
+
+// Score rates a selection by keyword coverage, dependency closure, and
+// budget utilization
+func (s *ContextQualityScorer) Score(selection *SelectedContext) *ContextQuality {
+	quality := &ContextQuality{Measured: []string{}}
+	if selection == nil {
+		return quality
+	}
+
+	weighted, totalWeight := 0.0, 0.0
+	measure := func(name string, value, weight float64) {
+		quality.Measured = append(quality.Measured, name)
+		weighted += value * weight
+		totalWeight += weight
+	}
+
+	if coverage, missing, ok := keywordCoverage(selection); ok {
+		quality.KeywordCoverage, quality.MissingKeywords = coverage, missing
+		measure("keyword_coverage", coverage, s.config.CoverageWeight)
+	}
+	if closure, missing, ok := s.dependencyClosure(selection); ok {
+		quality.DependencyClosure, quality.MissingDependencies = closure, missing
+		measure("dependency_closure", closure, s.config.DependencyWeight)
+	}
+	if selection.Constraints != nil && selection.Constraints.MaxTokens > 0 {
+		utilization := float64(selection.TotalTokens) / float64(selection.Constraints.MaxTokens)
+		if utilization > 1 {
+			utilization = 1
+		}
+		quality.BudgetUtilization = utilization
+		measure("budget_utilization", utilization, s.config.BudgetWeight)
+	}
+
+	if totalWeight > 0 {
+		quality.Score = weighted / totalWeight
+	}
+	return quality
+}
+
+// keywordCoverage returns the share of the task's keywords that appear in a
+// selected file's path or content, and the keywords that appear in none
+func keywordCoverage(selection *SelectedContext) (float64, []string, bool) {
+	keywords := windowKeywords(selection.Task)
+	if len(keywords) == 0 {
+		return 0, nil, false
+	}
+
+	texts := make([]string, 0, len(selection.Files))
+	for _, file := range selection.Files {
+		if file.FileInfo == nil {
+			continue
+		}
+		text := strings.ToLower(file.FileInfo.Path)
+		if content, ok := loadContextFileContent(file); ok {
+			text += "\n" + strings.ToLower(content)
+		}
+		texts = append(texts, text)
+	}
+
+	var missing []string
+	for _, keyword := range keywords {
+		found := false
+		for _, text := range texts {
+			if strings.Contains(text, keyword) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, keyword)
+		}
+	}
+	sort.Strings(missing)
+	return float64(len(keywords)-len(missing)) / float64(len(keywords)), missing, true
+}
+
+// dependencyClosure returns the share of the selected files' direct
+// in-project dependencies that are selected too, and those that aren't
+func (s *ContextQualityScorer) dependencyClosure(selection *SelectedContext) (float64, []string, bool) {
+	if s.project == nil || s.project.DependencyGraph == nil {
+		return 0, nil, false
+	}
+	graph := s.project.DependencyGraph
+
+	selected := make(map[string]bool, len(selection.Files))
+	for _, file := range selection.Files {
+		if file.FileInfo != nil {
+			selected[dependencyNodeKey(s.project.RootPath, file.FileInfo.Path)] = true
+		}
+	}
+
+	required := make(map[string]bool)
+	for key := range selected {
+		if node, exists := graph.Nodes[key]; exists {
+			for _, dependency := range node.Dependencies {
+				required[dependency] = true
+			}
+		}
+	}
+	if len(required) == 0 {
+		return 1, nil, true
+	}
+
+	var missing []string
+	for dependency := range required {
+		if !selected[dependency] {
+			missing = append(missing, dependency)
+		}
+	}
+	sort.Strings(missing)
+	return float64(len(required)-len(missing)) / float64(len(required)), missing, true
+}
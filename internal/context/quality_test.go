@@ -0,0 +1,80 @@
+package context
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestContextQualityScorer tests each quality component, that unmeasurable
+// components are left out of the score, and that scores are reproducible
+func TestContextQualityScorer(t *testing.T) {
+	project := &ProjectContext{
+		RootPath: "/project",
+		DependencyGraph: &DependencyGraph{Nodes: map[string]*DependencyNode{
+			"handler.go": {Path: "handler.go", Dependencies: []string{"session.go", "store.go"}},
+			"session.go": {Path: "session.go", Dependencies: []string{"store.go"}},
+		}},
+	}
+	selection := &SelectedContext{
+		Task: &Task{Keywords: []string{"session", "token", "expiry"}},
+		Files: []ContextFile{
+			{FileInfo: &FileInfo{Path: "/project/handler.go"}, Content: "func refresh(token string) {}"},
+			{FileInfo: &FileInfo{Path: "/project/session.go"}, Content: "type Session struct{}"},
+		},
+		TotalTokens: 600,
+		Constraints: &ContextConstraints{MaxTokens: 800},
+	}
+
+	scorer := NewContextQualityScorer(project, nil)
+	quality := scorer.Score(selection)
+
+	if quality.KeywordCoverage != 2.0/3.0 || !reflect.DeepEqual(quality.MissingKeywords, []string{"expiry"}) {
+		t.Errorf("coverage = %v missing %v, expected 2/3 missing expiry", quality.KeywordCoverage, quality.MissingKeywords)
+	}
+	if quality.DependencyClosure != 0.5 || !reflect.DeepEqual(quality.MissingDependencies, []string{"store.go"}) {
+		t.Errorf("closure = %v missing %v, expected 0.5 missing store.go", quality.DependencyClosure, quality.MissingDependencies)
+	}
+	if quality.BudgetUtilization != 0.75 {
+		t.Errorf("utilization = %v, expected 0.75", quality.BudgetUtilization)
+	}
+	expected := (2.0/3.0)*0.5 + 0.5*0.3 + 0.75*0.2
+	if diff := quality.Score - expected; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("score = %v, expected %v", quality.Score, expected)
+	}
+	if again := scorer.Score(selection); !reflect.DeepEqual(again, quality) {
+		t.Error("scoring the same selection twice gave different results")
+	}
+
+	// Without a dependency graph closure is left out instead of counted as perfect
+	withoutGraph := NewContextQualityScorer(nil, nil).Score(selection)
+	if !reflect.DeepEqual(withoutGraph.Measured, []string{"keyword_coverage", "budget_utilization"}) {
+		t.Errorf("measured = %v, expected coverage and utilization", withoutGraph.Measured)
+	}
+	if expected := ((2.0/3.0)*0.5 + 0.75*0.2) / 0.7; withoutGraph.Score-expected > 1e-9 || expected-withoutGraph.Score > 1e-9 {
+		t.Errorf("score without graph = %v, expected %v", withoutGraph.Score, expected)
+	}
+}
+
+// TestImplicitFeedbackUsesQualityScorer tests that implicit feedback blends
+// execution-based quality with the selection's scored quality
+func TestImplicitFeedbackUsesQualityScorer(t *testing.T) {
+	collector := NewDefaultFeedbackCollector(NewSimpleFeedbackStore(t.TempDir()), nil, nil)
+
+	selection := &SelectedContext{
+		Task:        &Task{Keywords: []string{"session"}},
+		Files:       []ContextFile{{FileInfo: &FileInfo{Path: "/project/session.go"}, Content: "package session"}},
+		TotalTokens: 500,
+		Constraints: &ContextConstraints{MaxTokens: 1000},
+	}
+	execution := &TaskExecutionData{TaskID: "t1", CompletionStatus: "success", Duration: 10 * time.Minute}
+
+	executionOnly := collector.inferImplicitQuality(execution, selection)
+	collector.SetQualityScorer(NewContextQualityScorer(nil, nil))
+	blended := collector.inferImplicitQuality(execution, selection)
+
+	scored := (1.0*0.5 + 0.5*0.2) / 0.7
+	if expected := (executionOnly + scored) / 2; blended-expected > 1e-9 || expected-blended > 1e-9 {
+		t.Errorf("blended quality = %v, expected %v from execution %v and selection %v", blended, expected, executionOnly, scored)
+	}
+}
@@ -36,6 +36,7 @@ type ScoringFactors struct {
 	DependencyScore   float64 `json:"dependency_score"`
 	TaskTypeScore     float64 `json:"task_type_score"`
 	LanguageScore     float64 `json:"language_score"`
+	EmbeddingSimilarity float64 `json:"embedding_similarity,omitempty"` // Set by embedding-backed scorers
 }
 
 // SemanticRelevanceScorer implements intelligent relevance scoring
@@ -262,9 +263,9 @@ func (s *SemanticRelevanceScorer) calculateFileTypeScore(file *FileInfo, task *T
 	return 0.5 // Default neutral score
 }
 
-// calculateRecencyScore scores based on file modification time
+// calculateRecencyScore scores based on the file's last commit or modification time
 func (s *SemanticRelevanceScorer) calculateRecencyScore(file *FileInfo) float64 {
-	age := time.Since(file.LastModified)
+	age := time.Since(file.FreshnessTime())
 	halfLife := s.config.RecencyHalfLife
 	
 	// Exponential decay based on half-life
@@ -0,0 +1,115 @@
+package context
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// explicitNameBoost scales the score of candidates named after an explicit
+// file, such as its tests
+const explicitNameBoost = 1.5
+
+// seedExplicitFiles moves the project files the task names explicitly to the
+// front of the ranking, marked "explicit", whatever their relevance. The files
+// they depend on follow up to DependencyDepth hops, marked with
+// "explicit_dependency" metadata, and the remaining
+// candidates named after an explicit file are boosted. Explicit files are only
+// left out when the project doesn't have them, a constraint hard-excludes
+// them, or they don't fit in the budget.
+func (o *DefaultOptimizer) seedExplicitFiles(project *ProjectContext, task *Task, constraints *ContextConstraints, ranked []ContextFile) []ContextFile {
+	if len(task.Files) == 0 {
+		return ranked
+	}
+
+	rankedByPath := make(map[string]ContextFile, len(ranked))
+	for _, file := range ranked {
+		rankedByPath[file.FileInfo.Path] = file
+	}
+
+	var seeds []ContextFile
+	seeded := make(map[string]bool)
+	for _, name := range task.Files {
+		for i := range project.Files {
+			file := &project.Files[i]
+			if seeded[file.Path] || !matchesExplicitFile(project.RootPath, file.Path, name) || excludedExplicitly(file, constraints) {
+				continue
+			}
+
+			seed, ok := rankedByPath[file.Path]
+			if !ok {
+				seed = ContextFile{
+					FileInfo:       file,
+					RelevanceScore: o.analyzer.ScoreFileRelevance(file, task),
+					Priority:       1,
+				}
+			}
+			seed.InclusionReason = "explicit"
+			seeds = append(seeds, seed)
+			seeded[file.Path] = true
+		}
+	}
+	if len(seeds) == 0 {
+		return ranked
+	}
+
+	rest := make([]ContextFile, 0, len(ranked))
+	for _, file := range ranked {
+		if seeded[file.FileInfo.Path] {
+			continue
+		}
+		if namedAfterSeed(file.FileInfo.Path, seeds) {
+			file.RelevanceScore *= explicitNameBoost
+		}
+		rest = append(rest, file)
+	}
+	sortByRelevance(rest)
+
+	expanded, backbone := o.expandFromSeeds(project, task, constraints, seeds, rest)
+	for i := len(seeds); i < backbone; i++ {
+		if expanded[i].InclusionReason != "explicit" {
+			expanded[i].Metadata = copyMetadata(expanded[i].Metadata)
+			expanded[i].Metadata["explicit_dependency"] = true
+		}
+	}
+	return expanded
+}
+
+// excludedExplicitly reports whether the constraints rule a file out even
+// when the task names it. File type preferences don't; a named test or doc
+// is wanted.
+func excludedExplicitly(file *FileInfo, constraints *ContextConstraints) bool {
+	for _, pattern := range constraints.ExcludedPatterns {
+		if strings.Contains(file.Path, pattern) {
+			return true
+		}
+	}
+	return hasDeniedExtension(file.Path, constraints.DeniedExtensions)
+}
+
+// namedAfterSeed reports whether a file's name contains the name of a seed,
+// like handler_test.go for handler.go
+func namedAfterSeed(path string, seeds []ContextFile) bool {
+	name := strings.ToLower(filepath.Base(path))
+	for _, seed := range seeds {
+		stem := strings.ToLower(strings.TrimSuffix(filepath.Base(seed.FileInfo.Path), filepath.Ext(seed.FileInfo.Path)))
+		// Very short names would match too much
+		if len(stem) >= 3 && strings.Contains(name, stem) {
+			return true
+		}
+	}
+	return false
+}
+
+// leadingExplicitFiles returns the explicit files and the dependencies they
+// pulled in at the start of a ranking
+func leadingExplicitFiles(ranked []ContextFile) []ContextFile {
+	count := 0
+	for count < len(ranked) {
+		file := ranked[count]
+		if dependency, _ := file.Metadata["explicit_dependency"].(bool); file.InclusionReason != "explicit" && !dependency {
+			break
+		}
+		count++
+	}
+	return ranked[:count]
+}
@@ -0,0 +1,210 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ABVariant identifies one side of an A/B selection comparison
+type ABVariant string
+
+const (
+	VariantA ABVariant = "a"
+	VariantB ABVariant = "b"
+)
+
+// Pairs nobody chooses between are forgotten after pendingPairTTL, and at
+// most maxPendingPairs are kept, oldest evicted first
+const (
+	pendingPairTTL  = time.Hour
+	maxPendingPairs = 1000
+)
+
+// SelectionPair holds two candidate selections produced for the same task
+type SelectionPair struct {
+	ID        string           `json:"id"`
+	Task      *Task            `json:"task"`
+	A         *SelectedContext `json:"a"`
+	B         *SelectedContext `json:"b"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// Variant returns the selection for one side of the pair
+func (p *SelectionPair) Variant(variant ABVariant) (*SelectedContext, error) {
+	switch variant {
+	case VariantA:
+		return p.A, nil
+	case VariantB:
+		return p.B, nil
+	default:
+		return nil, fmt.Errorf("unknown variant: %s", variant)
+	}
+}
+
+// SelectionABHarness produces competing context selections and records which
+// one the user actually used as explicit feedback
+type SelectionABHarness struct {
+	optimizer ContextOptimizer
+	collector FeedbackCollector
+	pending   map[string]*SelectionPair
+	order     []string // Pending pair IDs, oldest first
+	nextID    int64
+	now       func() time.Time
+	mutex     sync.Mutex
+}
+
+// NewSelectionABHarness creates a new A/B harness
+func NewSelectionABHarness(optimizer ContextOptimizer, collector FeedbackCollector) *SelectionABHarness {
+	return &SelectionABHarness{
+		optimizer: optimizer,
+		collector: collector,
+		pending:   make(map[string]*SelectionPair),
+		now:       time.Now,
+	}
+}
+
+// Propose selects context for the task once per strategy and keeps the pair
+// until a choice is recorded or it expires. Nil constraints use the
+// defaults of a DefaultOptimizer; other optimizers need them given.
+func (h *SelectionABHarness) Propose(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints, strategyA, strategyB SelectionStrategy) (*SelectionPair, error) {
+	if task == nil {
+		return nil, fmt.Errorf("task is required")
+	}
+	if constraints == nil {
+		optimizer, ok := h.optimizer.(*DefaultOptimizer)
+		if !ok {
+			return nil, fmt.Errorf("constraints are required for %T", h.optimizer)
+		}
+		constraints = optimizer.getDefaultConstraints()
+	}
+
+	selectionA, err := h.selectWithStrategy(ctx, project, task, constraints, strategyA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select variant A: %w", err)
+	}
+	selectionB, err := h.selectWithStrategy(ctx, project, task, constraints, strategyB)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select variant B: %w", err)
+	}
+
+	now := h.now()
+	pair := &SelectionPair{
+		Task:      task,
+		A:         selectionA,
+		B:         selectionB,
+		CreatedAt: now,
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	// The sequence number keeps IDs of pairs proposed at the same instant apart
+	h.nextID++
+	pair.ID = fmt.Sprintf("ab_%d_%d", now.UnixNano(), h.nextID)
+	h.evictPending(now)
+	h.pending[pair.ID] = pair
+	h.order = append(h.order, pair.ID)
+
+	return pair, nil
+}
+
+// evictPending forgets expired pairs and the oldest ones beyond the limit,
+// making room for one more. Callers hold the mutex.
+func (h *SelectionABHarness) evictPending(now time.Time) {
+	kept := h.order[:0]
+	for _, id := range h.order {
+		pair, exists := h.pending[id]
+		if !exists {
+			continue // Already chosen
+		}
+		if now.Sub(pair.CreatedAt) > pendingPairTTL {
+			delete(h.pending, id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	for len(kept) >= maxPendingPairs {
+		delete(h.pending, kept[0])
+		kept = kept[1:]
+	}
+	h.order = kept
+}
+
+// RecordChoice records that the user used the given variant of a pair. rating
+// is the user's 1-5 rating of the chosen context; 0 records the choice alone.
+// A pair's choice is recorded once, however many callers race to record it.
+func (h *SelectionABHarness) RecordChoice(pairID string, chosen ABVariant, rating int) (*ExplicitFeedback, error) {
+	if rating == 0 {
+		rating = 4 // Choosing a selection signals it was good enough to use
+	}
+	if rating < 1 || rating > 5 {
+		return nil, fmt.Errorf("rating must be between 1 and 5, got %d", rating)
+	}
+
+	// Take the pair out of pending before recording, so no other caller can
+	// record it too
+	h.mutex.Lock()
+	pair, exists := h.pending[pairID]
+	if !exists || h.now().Sub(pair.CreatedAt) > pendingPairTTL {
+		h.mutex.Unlock()
+		return nil, fmt.Errorf("unknown selection pair: %s", pairID)
+	}
+	selection, err := pair.Variant(chosen)
+	if err != nil {
+		h.mutex.Unlock()
+		return nil, err
+	}
+	delete(h.pending, pairID)
+	h.mutex.Unlock()
+
+	rejectedVariant := VariantB
+	if chosen == VariantB {
+		rejectedVariant = VariantA
+	}
+	rejected, _ := pair.Variant(rejectedVariant)
+
+	feedback := &ExplicitFeedback{
+		FeedbackID:        pair.ID,
+		TaskID:            pair.Task.ID,
+		ContextQuality:    rating,
+		PreferredStrategy: string(selection.Strategy),
+		Comments:          fmt.Sprintf("Chose variant %s (%s) over %s (%s)", chosen, selection.Strategy, rejectedVariant, rejected.Strategy),
+		Timestamp:         h.now(),
+		AdditionalMetadata: map[string]interface{}{
+			"task_type":         string(pair.Task.Type),
+			"ab_pair_id":        pair.ID,
+			"chosen_variant":    string(chosen),
+			"rejected_strategy": string(rejected.Strategy),
+		},
+	}
+
+	if err := h.collector.CollectExplicitFeedback(feedback); err != nil {
+		h.restorePending(pair)
+		return nil, fmt.Errorf("failed to record choice: %w", err)
+	}
+
+	return feedback, nil
+}
+
+// restorePending puts back a pair whose choice couldn't be recorded, so it
+// can be chosen again
+func (h *SelectionABHarness) restorePending(pair *SelectionPair) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.pending[pair.ID] = pair
+	for _, id := range h.order {
+		if id == pair.ID {
+			return
+		}
+	}
+	h.order = append(h.order, pair.ID)
+}
+
+// selectWithStrategy runs the optimizer with a copy of constraints using strategy
+func (h *SelectionABHarness) selectWithStrategy(ctx context.Context, project *ProjectContext, task *Task, constraints *ContextConstraints, strategy SelectionStrategy) (*SelectedContext, error) {
+	variantConstraints := *constraints
+	variantConstraints.Strategy = strategy
+	return h.optimizer.SelectOptimalContext(ctx, project, task, &variantConstraints)
+}
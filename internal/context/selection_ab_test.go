@@ -0,0 +1,203 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestABHarness wires an A/B harness to a feedback collector and adaptive manager
+func newTestABHarness(t *testing.T) (*SelectionABHarness, *DefaultAdaptiveManager) {
+	tempDir, err := os.MkdirTemp("", "selection_ab_test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	optimizer := newTestOptimizer(map[string]float64{"a.go": 0.9, "b.go": 0.5})
+	manager := NewDefaultAdaptiveManager(optimizer, optimizer.analyzer, nil, nil)
+	collector := NewDefaultFeedbackCollector(NewIndexedFeedbackStore(tempDir), manager, nil)
+
+	return NewSelectionABHarness(optimizer, collector), manager
+}
+
+// TestSelectionABRecordChoiceUpdatesProfile tests that recorded preferences move
+// the task profile toward the chosen strategy
+func TestSelectionABRecordChoiceUpdatesProfile(t *testing.T) {
+	harness, manager := newTestABHarness(t)
+	project := newTestProject(map[string]int{"a.go": 100, "b.go": 100})
+	task := &Task{Type: TaskTypeDebug, Description: "fix crash"}
+	constraints := &ContextConstraints{MaxTokens: 1000, MaxFiles: 10, MinRelevanceScore: 0.1}
+
+	choose := func(variant ABVariant) {
+		t.Helper()
+		pair, err := harness.Propose(context.Background(), project, task, constraints, StrategyRelevance, StrategyFreshness)
+		if err != nil {
+			t.Fatalf("Propose failed: %v", err)
+		}
+		if pair.A.Strategy != StrategyRelevance || pair.B.Strategy != StrategyFreshness {
+			t.Fatalf("pair strategies = %s/%s, expected relevance/freshness", pair.A.Strategy, pair.B.Strategy)
+		}
+		if _, err := harness.RecordChoice(pair.ID, variant, 0); err != nil {
+			t.Fatalf("RecordChoice failed: %v", err)
+		}
+	}
+
+	choose(VariantB)
+	profile := manager.GetProfileStatistics()[TaskTypeDebug]
+	if profile == nil || profile.PreferredStrategy != StrategyFreshness {
+		t.Fatalf("profile after one choice = %+v, expected freshness preferred", profile)
+	}
+
+	choose(VariantB)
+	choose(VariantA)
+	profile = manager.GetProfileStatistics()[TaskTypeDebug]
+	if profile.PreferredStrategy != StrategyFreshness {
+		t.Errorf("PreferredStrategy = %s, expected freshness after 2 of 3 choices", profile.PreferredStrategy)
+	}
+	freshness := profile.AdaptationFactors["strategy:freshness"]
+	relevance := profile.AdaptationFactors["strategy:relevance"]
+	if freshness <= relevance {
+		t.Errorf("freshness weight %.3f should exceed relevance weight %.3f", freshness, relevance)
+	}
+	if profile.SampleCount != 3 {
+		t.Errorf("SampleCount = %d, expected 3", profile.SampleCount)
+	}
+
+	// Other task types are unaffected
+	if _, exists := manager.GetProfileStatistics()[TaskTypeFeature]; exists {
+		t.Error("expected no feature profile to be created")
+	}
+}
+
+// TestSelectionABRecordChoiceErrors tests invalid choices
+func TestSelectionABRecordChoiceErrors(t *testing.T) {
+	harness, _ := newTestABHarness(t)
+	project := newTestProject(map[string]int{"a.go": 100})
+	task := &Task{Type: TaskTypeFeature}
+
+	pair, err := harness.Propose(context.Background(), project, task, nil, StrategyRelevance, StrategyCompactness)
+	if err != nil {
+		t.Fatalf("Propose failed: %v", err)
+	}
+
+	if _, err := harness.RecordChoice("missing", VariantA, 0); err == nil {
+		t.Error("expected error for unknown pair")
+	}
+	if _, err := harness.RecordChoice(pair.ID, ABVariant("c"), 0); err == nil {
+		t.Error("expected error for unknown variant")
+	}
+	if _, err := harness.RecordChoice(pair.ID, VariantA, 6); err == nil {
+		t.Error("expected error for out of range rating")
+	}
+
+	if _, err := harness.RecordChoice(pair.ID, VariantA, 5); err != nil {
+		t.Fatalf("RecordChoice failed: %v", err)
+	}
+	if _, err := harness.RecordChoice(pair.ID, VariantA, 5); err == nil {
+		t.Error("expected error recording the same pair twice")
+	}
+}
+
+// TestSelectionABPendingPairs tests that pairs proposed at the same instant get
+// distinct IDs and that pairs nobody chooses between expire
+func TestSelectionABPendingPairs(t *testing.T) {
+	harness, _ := newTestABHarness(t)
+	project := newTestProject(map[string]int{"a.go": 100})
+	task := &Task{Type: TaskTypeFeature}
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	harness.now = func() time.Time { return now }
+
+	first, err := harness.Propose(context.Background(), project, task, nil, StrategyRelevance, StrategyCompactness)
+	if err != nil {
+		t.Fatalf("Propose failed: %v", err)
+	}
+	second, err := harness.Propose(context.Background(), project, task, nil, StrategyRelevance, StrategyCompactness)
+	if err != nil {
+		t.Fatalf("Propose failed: %v", err)
+	}
+	if first.ID == second.ID {
+		t.Fatalf("pairs proposed at the same instant share ID %s", first.ID)
+	}
+
+	now = now.Add(pendingPairTTL + time.Minute)
+	if _, err := harness.RecordChoice(first.ID, VariantA, 0); err == nil {
+		t.Error("expected an expired pair to be unknown")
+	}
+	if _, err := harness.Propose(context.Background(), project, task, nil, StrategyRelevance, StrategyCompactness); err != nil {
+		t.Fatalf("Propose failed: %v", err)
+	}
+	if len(harness.pending) != 1 {
+		t.Errorf("%d pairs pending, expected the expired ones evicted", len(harness.pending))
+	}
+}
+
+// TestSelectionABRequiresConstraintsForOtherOptimizers tests that nil
+// constraints aren't replaced by an empty budget when the optimizer has no
+// defaults to offer
+func TestSelectionABRequiresConstraintsForOtherOptimizers(t *testing.T) {
+	harness, _ := newTestABHarness(t)
+	harness.optimizer = struct{ ContextOptimizer }{harness.optimizer}
+	project := newTestProject(map[string]int{"a.go": 100})
+
+	if _, err := harness.Propose(context.Background(), project, &Task{Type: TaskTypeFeature}, nil, StrategyRelevance, StrategyCompactness); err == nil {
+		t.Error("expected an error for nil constraints with a wrapped optimizer")
+	}
+}
+
+// countingCollector counts explicit feedback, failing while fail is set
+type countingCollector struct {
+	FeedbackCollector
+	mutex    sync.Mutex
+	recorded []*ExplicitFeedback
+	fail     bool
+}
+
+func (c *countingCollector) CollectExplicitFeedback(feedback *ExplicitFeedback) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.fail {
+		return errors.New("store unavailable")
+	}
+	c.recorded = append(c.recorded, feedback)
+	return nil
+}
+
+// TestSelectionABRecordChoiceOnce tests that callers racing to record a pair
+// record it once, that a pair whose feedback couldn't be stored can be chosen
+// again, and that the feedback names the task
+func TestSelectionABRecordChoiceOnce(t *testing.T) {
+	collector := &countingCollector{fail: true}
+	harness := NewSelectionABHarness(newTestOptimizer(map[string]float64{"a.go": 0.9}), collector)
+	project := newTestProject(map[string]int{"a.go": 100})
+	task := &Task{ID: "task-42", Type: TaskTypeFeature}
+
+	pair, err := harness.Propose(context.Background(), project, task, nil, StrategyRelevance, StrategyCompactness)
+	if err != nil {
+		t.Fatalf("Propose failed: %v", err)
+	}
+	if _, err := harness.RecordChoice(pair.ID, VariantA, 0); err == nil {
+		t.Fatal("expected an error when the feedback can't be stored")
+	}
+	collector.fail = false
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			harness.RecordChoice(pair.ID, VariantA, 0)
+		}()
+	}
+	wg.Wait()
+
+	if len(collector.recorded) != 1 {
+		t.Fatalf("recorded %d feedback entries, expected 1", len(collector.recorded))
+	}
+	if taskID := collector.recorded[0].TaskID; taskID != "task-42" {
+		t.Errorf("TaskID = %q, expected the task's ID", taskID)
+	}
+}
@@ -0,0 +1,161 @@
+package context
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// significantScoreChange is how far a file's relevance must move between two
+// selections to be reported as rescored
+const significantScoreChange = 0.1
+
+// SelectionDiff describes how one selection differs from an earlier one
+type SelectionDiff struct {
+	Added      []FileChange `json:"added"`
+	Removed    []FileChange `json:"removed"`
+	Rescored   []FileChange `json:"rescored"`  // In both, with relevance moved by at least significantScoreChange
+	Modified   []FileChange `json:"modified"`  // In both, with different content
+	Unchanged  int          `json:"unchanged"` // In both, neither rescored nor modified
+	TokenDelta int          `json:"token_delta"`
+}
+
+// FileChange describes a file that entered, left, or changed within a selection
+type FileChange struct {
+	Path          string  `json:"path"`
+	PreviousScore float64 `json:"previous_score"`
+	CurrentScore  float64 `json:"current_score"`
+	Tokens        int     `json:"tokens"`
+	Reason        string  `json:"reason"`                 // Inclusion reason in the selection the file appears in, the current one when in both
+	RequiredBy    string  `json:"required_by,omitempty"`  // File whose dependency pulled this one in
+	TriggeredBy   string  `json:"triggered_by,omitempty"` // Path of the RequiredBy file when it itself entered, left, or changed
+}
+
+// Empty reports whether the selections contain the same files with the same
+// content and similar scores
+func (d *SelectionDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Rescored) == 0 && len(d.Modified) == 0
+}
+
+// DiffSelections compares two selections of the same task. A nil prev treats
+// every current file as added. Files pulled in as transitive dependencies
+// record the file that required them, and which of those entered, left, or
+// changed, so dependency-driven churn can be told apart from ranking churn.
+func DiffSelections(prev, curr *SelectedContext) *SelectionDiff {
+	diff := &SelectionDiff{}
+	previous := indexSelection(prev)
+	current := indexSelection(curr)
+
+	// Files whose own presence or content changed can trigger dependency churn
+	changed := make(map[string]bool)
+
+	for path, file := range current {
+		old, exists := previous[path]
+		if !exists {
+			diff.Added = append(diff.Added, newFileChange(path, nil, &file))
+			changed[path] = true
+			continue
+		}
+
+		change := newFileChange(path, &old, &file)
+		rescored := math.Abs(change.CurrentScore-change.PreviousScore) >= significantScoreChange
+		modified := old.FileInfo.ContentHash != "" && old.FileInfo.ContentHash != file.FileInfo.ContentHash
+		if rescored {
+			diff.Rescored = append(diff.Rescored, change)
+		}
+		if modified {
+			diff.Modified = append(diff.Modified, change)
+			changed[path] = true
+		}
+		if !rescored && !modified {
+			diff.Unchanged++
+		}
+	}
+	for path, file := range previous {
+		if _, exists := current[path]; !exists {
+			diff.Removed = append(diff.Removed, newFileChange(path, &file, nil))
+			changed[path] = true
+		}
+	}
+
+	changedPaths := make([]string, 0, len(changed))
+	for path := range changed {
+		changedPaths = append(changedPaths, path)
+	}
+	sort.Strings(changedPaths)
+	for _, changes := range [][]FileChange{diff.Added, diff.Removed} {
+		for i := range changes {
+			changes[i].TriggeredBy = resolveRequiredBy(changes[i].RequiredBy, changedPaths)
+		}
+	}
+
+	for _, changes := range [][]FileChange{diff.Added, diff.Removed, diff.Rescored, diff.Modified} {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	}
+
+	if curr != nil {
+		diff.TokenDelta += curr.TotalTokens
+	}
+	if prev != nil {
+		diff.TokenDelta -= prev.TotalTokens
+	}
+	return diff
+}
+
+// indexSelection maps a selection's files by path
+func indexSelection(selection *SelectedContext) map[string]ContextFile {
+	files := make(map[string]ContextFile)
+	if selection == nil {
+		return files
+	}
+	for _, file := range selection.Files {
+		if file.FileInfo != nil {
+			files[file.FileInfo.Path] = file
+		}
+	}
+	return files
+}
+
+// newFileChange describes a file from its previous and current entries,
+// either of which may be nil
+func newFileChange(path string, previous, current *ContextFile) FileChange {
+	change := FileChange{Path: path}
+	latest := current
+	if previous != nil {
+		change.PreviousScore = previous.RelevanceScore
+		latest = previous
+	}
+	if current != nil {
+		change.CurrentScore = current.RelevanceScore
+		latest = current
+	}
+
+	change.Tokens = latest.FileInfo.TokenCount
+	change.Reason = latest.InclusionReason
+	if requiredBy, ok := latest.Metadata["required_by"].(string); ok {
+		change.RequiredBy = requiredBy
+	}
+	return change
+}
+
+// resolveRequiredBy returns the changed file matching a dependency graph key,
+// from changed paths in sorted order. Graph keys are relative to the project
+// root, which selections don't carry, so a key matches a changed path it is
+// a suffix of; of several, the first in order wins so a diff always resolves
+// the same way.
+func resolveRequiredBy(key string, changed []string) string {
+	if key == "" {
+		return ""
+	}
+	if i := sort.SearchStrings(changed, key); i < len(changed) && changed[i] == key {
+		return key
+	}
+	suffix := "/" + filepath.ToSlash(key)
+	for _, path := range changed {
+		if strings.HasSuffix(filepath.ToSlash(path), suffix) {
+			return path
+		}
+	}
+	return ""
+}
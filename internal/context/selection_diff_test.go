@@ -0,0 +1,119 @@
+package context
+
+import (
+	"testing"
+)
+
+// TestDiffSelections tests added, removed, rescored, and modified files along
+// with dependency-driven churn
+func TestDiffSelections(t *testing.T) {
+	file := func(path, hash string, score float64, reason string, metadata map[string]interface{}) ContextFile {
+		return ContextFile{
+			FileInfo:        &FileInfo{Path: path, TokenCount: 100, ContentHash: hash},
+			RelevanceScore:  score,
+			InclusionReason: reason,
+			Metadata:        metadata,
+		}
+	}
+
+	prev := &SelectedContext{
+		Files: []ContextFile{
+			file("/project/api/handler.go", "h1", 0.9, "relevance_score", nil),
+			file("/project/store/store.go", "s1", 0.6, "relevance_score", nil),
+			file("/project/legacy/cache.go", "c1", 0.5, "relevance_score", nil),
+			file("/project/legacy/lru.go", "l1", 0.45, "transitive_dependency", map[string]interface{}{"required_by": "legacy/cache.go"}),
+			file("/project/util/strings.go", "u1", 0.3, "relevance_score", nil),
+		},
+		TotalTokens: 500,
+	}
+	curr := &SelectedContext{
+		Files: []ContextFile{
+			file("/project/api/handler.go", "h2", 0.92, "relevance_score", nil),
+			file("/project/store/store.go", "s1", 0.8, "relevance_score", nil),
+			file("/project/util/strings.go", "u1", 0.32, "relevance_score", nil),
+			file("/project/api/codec.go", "k1", 0.8, "transitive_dependency", map[string]interface{}{"required_by": "api/handler.go"}),
+		},
+		TotalTokens: 400,
+	}
+
+	diff := DiffSelections(prev, curr)
+
+	paths := func(changes []FileChange) []string {
+		result := make([]string, 0, len(changes))
+		for _, change := range changes {
+			result = append(result, change.Path)
+		}
+		return result
+	}
+	tests := []struct {
+		name     string
+		changes  []FileChange
+		expected []string
+	}{
+		{name: "added", changes: diff.Added, expected: []string{"/project/api/codec.go"}},
+		{name: "removed", changes: diff.Removed, expected: []string{"/project/legacy/cache.go", "/project/legacy/lru.go"}},
+		{name: "rescored", changes: diff.Rescored, expected: []string{"/project/store/store.go"}},
+		{name: "modified", changes: diff.Modified, expected: []string{"/project/api/handler.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := paths(tt.changes)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("%s = %v, expected %v", tt.name, got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("%s = %v, expected %v", tt.name, got, tt.expected)
+				}
+			}
+		})
+	}
+
+	if diff.Unchanged != 1 || diff.TokenDelta != -100 || diff.Empty() {
+		t.Errorf("unchanged = %d, token delta = %d, empty = %v", diff.Unchanged, diff.TokenDelta, diff.Empty())
+	}
+
+	// The new codec file came in because the handler it serves changed
+	if codec := diff.Added[0]; codec.RequiredBy != "api/handler.go" || codec.TriggeredBy != "/project/api/handler.go" {
+		t.Errorf("codec required by %q, triggered by %q", codec.RequiredBy, codec.TriggeredBy)
+	}
+	// The LRU left along with the cache that required it
+	if lru := diff.Removed[1]; lru.TriggeredBy != "/project/legacy/cache.go" || lru.PreviousScore != 0.45 || lru.Reason != "transitive_dependency" {
+		t.Errorf("lru = %+v, expected it to be triggered by the removed cache", lru)
+	}
+	if store := diff.Rescored[0]; store.PreviousScore != 0.6 || store.CurrentScore != 0.8 {
+		t.Errorf("store scores = %v -> %v", store.PreviousScore, store.CurrentScore)
+	}
+
+	if !DiffSelections(curr, curr).Empty() {
+		t.Error("a selection diffed against itself should be empty")
+	}
+	if added := DiffSelections(nil, curr).Added; len(added) != len(curr.Files) {
+		t.Errorf("diff from nil added %d files, expected %d", len(added), len(curr.Files))
+	}
+}
+
+// TestDiffSelectionsResolvesRequiredByDeterministically tests that a graph
+// key matching several changed paths always resolves to the same one
+func TestDiffSelectionsResolvesRequiredByDeterministically(t *testing.T) {
+	curr := &SelectedContext{
+		Files: []ContextFile{
+			{FileInfo: &FileInfo{Path: "/project/b/store.go"}},
+			{FileInfo: &FileInfo{Path: "/project/a/store.go"}},
+			{FileInfo: &FileInfo{Path: "/project/c/store.go"}},
+			{
+				FileInfo:        &FileInfo{Path: "/project/codec.go"},
+				InclusionReason: "transitive_dependency",
+				Metadata:        map[string]interface{}{"required_by": "store.go"},
+			},
+		},
+	}
+
+	for i := 0; i < 20; i++ {
+		for _, change := range DiffSelections(nil, curr).Added {
+			if change.Path == "/project/codec.go" && change.TriggeredBy != "/project/a/store.go" {
+				t.Fatalf("codec triggered by %q, expected the first matching path /project/a/store.go", change.TriggeredBy)
+			}
+		}
+	}
+}
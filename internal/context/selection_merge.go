@@ -0,0 +1,116 @@
+package context
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// mergedFromKey is the ContextFile metadata key listing the selections that
+// contributed a merged file
+const mergedFromKey = "merged_from"
+
+// MergeSelections combines the selections made for the subtasks of a larger
+// task into one context. Each file appears once, with the highest relevance
+// any selection gave it, and records in its "merged_from" metadata the
+// subtasks that selected it: their task IDs, or "selection N" by position
+// when a task has no ID. Files are then taken by descending relevance, ties
+// broken by path, while they fit within budget; budget 0 keeps them all.
+// Files that didn't fit are listed in the result's "dropped_files"
+// metadata. Nil selections are skipped and the inputs aren't modified.
+func MergeSelections(budget int, sels ...*SelectedContext) *SelectedContext {
+	start := time.Now()
+	merged := make(map[string]*ContextFile)
+	strategies := make(map[SelectionStrategy]bool)
+
+	for i, sel := range sels {
+		if sel == nil {
+			continue
+		}
+		strategies[sel.Strategy] = true
+		label := fmt.Sprintf("selection %d", i+1)
+		if sel.Task != nil && sel.Task.ID != "" {
+			label = sel.Task.ID
+		}
+
+		for _, file := range sel.Files {
+			if file.FileInfo == nil {
+				continue
+			}
+			path := file.FileInfo.Path
+			existing, exists := merged[path]
+			if !exists {
+				existing = copyContextFile(file)
+				merged[path] = existing
+			} else if file.RelevanceScore > existing.RelevanceScore {
+				sources := existing.Metadata[mergedFromKey]
+				existing = copyContextFile(file)
+				existing.Metadata[mergedFromKey] = sources
+				merged[path] = existing
+			}
+			sources, _ := existing.Metadata[mergedFromKey].([]string)
+			if len(sources) == 0 || sources[len(sources)-1] != label {
+				// Never append into a list owned by an input selection
+				existing.Metadata[mergedFromKey] = append(sources[:len(sources):len(sources)], label)
+			}
+		}
+	}
+
+	ranked := make([]ContextFile, 0, len(merged))
+	for _, file := range merged {
+		ranked = append(ranked, *file)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].RelevanceScore != ranked[j].RelevanceScore {
+			return ranked[i].RelevanceScore > ranked[j].RelevanceScore
+		}
+		return ranked[i].FileInfo.Path < ranked[j].FileInfo.Path
+	})
+
+	files := make([]ContextFile, 0, len(ranked))
+	dropped := []string{}
+	totalTokens, scoreSum := 0, 0.0
+	for _, file := range ranked {
+		if budget > 0 && totalTokens+file.FileInfo.TokenCount > budget {
+			dropped = append(dropped, file.FileInfo.Path)
+			continue
+		}
+		files = append(files, file)
+		totalTokens += file.FileInfo.TokenCount
+		scoreSum += file.RelevanceScore
+	}
+
+	result := &SelectedContext{
+		Files:       files,
+		TotalTokens: totalTokens,
+		TotalFiles:  len(files),
+		Constraints: &ContextConstraints{MaxTokens: budget},
+		Metadata: map[string]interface{}{
+			"merged_selections": len(sels),
+			"dropped_files":     dropped,
+		},
+		CreatedAt:     time.Now(),
+		SelectionTime: time.Since(start),
+	}
+	if len(files) > 0 {
+		result.SelectionScore = scoreSum / float64(len(files))
+	}
+	// The strategy only carries over when every selection used the same one
+	if len(strategies) == 1 {
+		for strategy := range strategies {
+			result.Strategy = strategy
+		}
+	}
+	return result
+}
+
+// copyContextFile copies file with metadata of its own, so merging doesn't
+// write into the selection it came from
+func copyContextFile(file ContextFile) *ContextFile {
+	metadata := make(map[string]interface{}, len(file.Metadata)+1)
+	for key, value := range file.Metadata {
+		metadata[key] = value
+	}
+	file.Metadata = metadata
+	return &file
+}
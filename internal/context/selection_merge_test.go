@@ -0,0 +1,81 @@
+package context
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMergeSelections tests that overlapping selections merge into one
+// deduplicated, budget-capped context that remembers where files came from
+func TestMergeSelections(t *testing.T) {
+	file := func(path string, tokens int, score float64, reason string) ContextFile {
+		return ContextFile{
+			FileInfo:        &FileInfo{Path: path, TokenCount: tokens},
+			RelevanceScore:  score,
+			InclusionReason: reason,
+		}
+	}
+
+	parse := &SelectedContext{
+		Task:     &Task{ID: "parse"},
+		Strategy: StrategyRelevance,
+		Files: []ContextFile{
+			file("/project/parser.go", 300, 0.9, "relevance_score"),
+			file("/project/token.go", 200, 0.4, "relevance_score"),
+			file("/project/ast.go", 250, 0.6, "relevance_score"),
+		},
+	}
+	eval := &SelectedContext{
+		Task:     &Task{Description: "evaluate expressions"},
+		Strategy: StrategyRelevance,
+		Files: []ContextFile{
+			file("/project/eval.go", 300, 0.9, "relevance_score"),
+			file("/project/ast.go", 250, 0.8, "dependency"),
+			file("/project/builtins.go", 400, 0.3, "relevance_score"),
+		},
+	}
+
+	merged := MergeSelections(1200, parse, nil, eval)
+
+	var paths []string
+	for _, f := range merged.Files {
+		paths = append(paths, f.FileInfo.Path)
+	}
+	// eval.go and parser.go tie on score and are ordered by path; builtins.go
+	// no longer fits once token.go is in
+	expected := []string{"/project/eval.go", "/project/parser.go", "/project/ast.go", "/project/token.go"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("merged files = %v, expected %v", paths, expected)
+	}
+	if merged.TotalTokens != 1050 || merged.TotalTokens > 1200 || merged.TotalFiles != 4 {
+		t.Errorf("merged totals = %d tokens in %d files, expected 1050 in 4", merged.TotalTokens, merged.TotalFiles)
+	}
+	if dropped := merged.Metadata["dropped_files"]; !reflect.DeepEqual(dropped, []string{"/project/builtins.go"}) {
+		t.Errorf("dropped_files = %v, expected builtins.go", dropped)
+	}
+	if merged.Strategy != StrategyRelevance {
+		t.Errorf("strategy = %q, expected the shared relevance strategy", merged.Strategy)
+	}
+
+	ast := merged.Files[2]
+	if ast.RelevanceScore != 0.8 || ast.InclusionReason != "dependency" {
+		t.Errorf("ast.go kept score %v with reason %q, expected the higher 0.8 from eval", ast.RelevanceScore, ast.InclusionReason)
+	}
+	if sources := ast.Metadata["merged_from"]; !reflect.DeepEqual(sources, []string{"parse", "selection 3"}) {
+		t.Errorf("ast.go merged_from = %v, expected both subtasks", sources)
+	}
+	if sources := merged.Files[0].Metadata["merged_from"]; !reflect.DeepEqual(sources, []string{"selection 3"}) {
+		t.Errorf("eval.go merged_from = %v, expected only the eval subtask", sources)
+	}
+	if parse.Files[2].Metadata != nil || eval.Files[1].Metadata != nil {
+		t.Error("merging wrote metadata into the input selections")
+	}
+
+	// Merging in either order gives the same files
+	reversed := MergeSelections(1200, eval, parse)
+	for i := range reversed.Files {
+		if reversed.Files[i].FileInfo.Path != paths[i] {
+			t.Fatalf("reversed merge ordered %s at %d, expected %s", reversed.Files[i].FileInfo.Path, i, paths[i])
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package context
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// SessionMemory remembers files selected in earlier turns of a session and
+// boosts them in later selections. Entries decay every turn and the memory is
+// bounded, so only recently relevant files keep influencing selection.
+type SessionMemory struct {
+	entries map[string]*sessionMemoryEntry
+	turn    int
+	config  *SessionMemoryConfig
+	mutex   sync.RWMutex
+}
+
+// SessionMemoryConfig contains configuration for session memory
+type SessionMemoryConfig struct {
+	MaxEntries  int     `json:"max_entries"`  // Maximum number of remembered files
+	DecayFactor float64 `json:"decay_factor"` // Per-turn multiplier applied to remembered strength (0-1)
+	BoostWeight float64 `json:"boost_weight"` // Maximum score added to a remembered file
+	MinStrength float64 `json:"min_strength"` // Entries weaker than this are forgotten
+}
+
+type sessionMemoryEntry struct {
+	path     string
+	strength float64
+	turn     int
+}
+
+type sessionMemoryKey struct{}
+
+// sessionBoostKey is the ContextFile metadata key holding the boost ApplyBoost
+// added to its score, so Record can remember the score without it
+const sessionBoostKey = "session_boost"
+
+// NewSessionMemory creates a new session memory
+func NewSessionMemory(config *SessionMemoryConfig) *SessionMemory {
+	if config == nil {
+		config = &SessionMemoryConfig{
+			MaxEntries:  50,
+			DecayFactor: 0.6,
+			BoostWeight: 0.3,
+			MinStrength: 0.05,
+		}
+	}
+
+	return &SessionMemory{
+		entries: make(map[string]*sessionMemoryEntry),
+		config:  config,
+	}
+}
+
+// WithSessionMemory returns a context carrying the given session memory
+func WithSessionMemory(ctx context.Context, memory *SessionMemory) context.Context {
+	return context.WithValue(ctx, sessionMemoryKey{}, memory)
+}
+
+// SessionMemoryFromContext returns the session memory carried by ctx, if any
+func SessionMemoryFromContext(ctx context.Context) *SessionMemory {
+	if ctx == nil {
+		return nil
+	}
+	memory, _ := ctx.Value(sessionMemoryKey{}).(*SessionMemory)
+	return memory
+}
+
+// Record remembers the files of a selection and advances the session by one turn
+func (m *SessionMemory) Record(files []ContextFile) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.turn++
+	for _, file := range files {
+		if file.FileInfo == nil {
+			continue
+		}
+
+		// Remembering a boosted score would boost it again next turn
+		score := file.RelevanceScore
+		if boost, ok := file.Metadata[sessionBoostKey].(float64); ok {
+			score -= boost
+		}
+		strength := min(1.0, score)
+		if entry, exists := m.entries[file.FileInfo.Path]; exists {
+			// Reinforce what was already remembered instead of replacing it
+			strength = min(1.0, m.decayedStrength(entry)+strength)
+		}
+
+		m.entries[file.FileInfo.Path] = &sessionMemoryEntry{
+			path:     file.FileInfo.Path,
+			strength: strength,
+			turn:     m.turn,
+		}
+	}
+
+	m.prune()
+}
+
+// Boost returns the score boost for a file based on how recently and strongly it was selected
+func (m *SessionMemory) Boost(path string) float64 {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	entry, exists := m.entries[path]
+	if !exists {
+		return 0.0
+	}
+
+	return m.decayedStrength(entry) * m.config.BoostWeight
+}
+
+// ApplyBoost adds the remembered boost to each candidate and re-sorts them by score
+func (m *SessionMemory) ApplyBoost(candidates []ContextFile) []ContextFile {
+	boosted := false
+	for i := range candidates {
+		if candidates[i].FileInfo == nil {
+			continue
+		}
+		if boost := m.Boost(candidates[i].FileInfo.Path); boost > 0 {
+			metadata := make(map[string]interface{}, len(candidates[i].Metadata)+1)
+			for key, value := range candidates[i].Metadata {
+				metadata[key] = value
+			}
+			metadata[sessionBoostKey] = boost
+			candidates[i].Metadata = metadata
+			candidates[i].RelevanceScore += boost
+			boosted = true
+		}
+	}
+
+	if boosted {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].RelevanceScore > candidates[j].RelevanceScore
+		})
+	}
+
+	return candidates
+}
+
+// Len returns the number of remembered files
+func (m *SessionMemory) Len() int {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return len(m.entries)
+}
+
+// Reset forgets everything remembered in the session
+func (m *SessionMemory) Reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.entries = make(map[string]*sessionMemoryEntry)
+	m.turn = 0
+}
+
+// decayedStrength returns the entry strength after decaying it for each elapsed turn
+func (m *SessionMemory) decayedStrength(entry *sessionMemoryEntry) float64 {
+	strength := entry.strength
+	for i := entry.turn; i < m.turn; i++ {
+		strength *= m.config.DecayFactor
+	}
+	return strength
+}
+
+// prune drops forgotten entries and evicts the weakest ones beyond the size limit
+func (m *SessionMemory) prune() {
+	entries := make([]*sessionMemoryEntry, 0, len(m.entries))
+	for path, entry := range m.entries {
+		if m.decayedStrength(entry) < m.config.MinStrength {
+			delete(m.entries, path)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	if m.config.MaxEntries <= 0 || len(entries) <= m.config.MaxEntries {
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		si, sj := m.decayedStrength(entries[i]), m.decayedStrength(entries[j])
+		if si != sj {
+			return si > sj
+		}
+		return entries[i].path < entries[j].path
+	})
+	for _, entry := range entries[m.config.MaxEntries:] {
+		delete(m.entries, entry.path)
+	}
+}
@@ -0,0 +1,235 @@
+// Package sqlitestore provides a SQLite backed feedback store. It lives apart
+// from the context package so only binaries that import it link SQLite.
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	contextpkg "github.com/rcliao/teeny-orb/internal/context"
+	_ "modernc.org/sqlite" // Registers the pure Go SQLite driver
+)
+
+// DriverName is the database/sql driver name registered by modernc.org/sqlite
+const DriverName = "sqlite"
+
+// sqliteFeedbackSchema creates one table per feedback type. Records are kept as
+// JSON alongside the columns used for filtering.
+var sqliteFeedbackSchema = []string{
+	`CREATE TABLE IF NOT EXISTS implicit_feedback (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id TEXT NOT NULL,
+		task_type TEXT NOT NULL,
+		strategy TEXT NOT NULL,
+		task_success INTEGER NOT NULL,
+		quality_score REAL NOT NULL,
+		created_at INTEGER NOT NULL,
+		data TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_implicit_feedback_created_at ON implicit_feedback(created_at)`,
+	`CREATE TABLE IF NOT EXISTS explicit_feedback (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		feedback_id TEXT NOT NULL,
+		task_id TEXT NOT NULL,
+		context_quality INTEGER NOT NULL,
+		preferred_strategy TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		data TEXT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_explicit_feedback_created_at ON explicit_feedback(created_at)`,
+}
+
+// FeedbackStore stores feedback in a SQLite database so several sessions
+// or server instances can share one store safely
+type FeedbackStore struct {
+	db     *sql.DB
+	ownsDB bool
+	now    func() time.Time
+}
+
+// Open opens or creates a SQLite feedback database at path
+func Open(path string) (*FeedbackStore, error) {
+	// WAL lets readers proceed during writes; busy_timeout makes concurrent
+	// writers from other processes wait for the lock instead of failing
+	dsn := url.URL{
+		Scheme:   "file",
+		Opaque:   url.PathEscape(path),
+		RawQuery: "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+	}
+
+	db, err := sql.Open(DriverName, dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open feedback database: %w", err)
+	}
+
+	store, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	store.ownsDB = true
+	return store, nil
+}
+
+// New creates a feedback store on an open database, creating the schema if
+// needed
+func New(db *sql.DB) (*FeedbackStore, error) {
+	store := &FeedbackStore{
+		db:  db,
+		now: time.Now,
+	}
+
+	if err := store.withTx(func(tx *sql.Tx) error {
+		for _, statement := range sqliteFeedbackSchema {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create feedback schema: %w", err)
+	}
+
+	return store, nil
+}
+
+// StoreFeedback inserts a *ContextFeedback or *ExplicitFeedback record
+func (s *FeedbackStore) StoreFeedback(feedback interface{}) error {
+	createdAt := s.now().UnixNano()
+
+	data, err := json.Marshal(feedback)
+	if err != nil {
+		return fmt.Errorf("failed to marshal feedback: %w", err)
+	}
+
+	var query string
+	var args []interface{}
+	switch fb := feedback.(type) {
+	case *contextpkg.ContextFeedback:
+		taskType, strategy := "", ""
+		if fb.Task != nil {
+			taskType = string(fb.Task.Type)
+		}
+		if fb.SelectedContext != nil {
+			strategy = string(fb.SelectedContext.Strategy)
+		}
+		query = `INSERT INTO implicit_feedback (task_id, task_type, strategy, task_success, quality_score, created_at, data)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`
+		args = []interface{}{fb.TaskID, taskType, strategy, fb.TaskSuccess, fb.QualityScore, createdAt, string(data)}
+	case *contextpkg.ExplicitFeedback:
+		query = `INSERT INTO explicit_feedback (feedback_id, task_id, context_quality, preferred_strategy, created_at, data)
+			VALUES (?, ?, ?, ?, ?, ?)`
+		args = []interface{}{fb.FeedbackID, fb.TaskID, fb.ContextQuality, fb.PreferredStrategy, createdAt, string(data)}
+	default:
+		return fmt.Errorf("unsupported feedback type: %T", feedback)
+	}
+
+	if err := s.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(query, args...)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to store feedback: %w", err)
+	}
+	return nil
+}
+
+// GetFeedback retrieves feedback of all types within a time window, oldest first
+func (s *FeedbackStore) GetFeedback(timeWindow time.Duration) ([]interface{}, error) {
+	cutoff := s.now().Add(-timeWindow).UnixNano()
+	return s.query(`SELECT 'implicit', data, created_at FROM implicit_feedback WHERE created_at >= ?
+		UNION ALL
+		SELECT 'explicit', data, created_at FROM explicit_feedback WHERE created_at >= ?
+		ORDER BY created_at`, cutoff, cutoff)
+}
+
+// GetFeedbackByType retrieves feedback of a specific type within a time window
+func (s *FeedbackStore) GetFeedbackByType(feedbackType string, timeWindow time.Duration) ([]interface{}, error) {
+	cutoff := s.now().Add(-timeWindow).UnixNano()
+
+	switch feedbackType {
+	case contextpkg.FeedbackTypeImplicit:
+		return s.query(`SELECT 'implicit', data, created_at FROM implicit_feedback WHERE created_at >= ? ORDER BY created_at`, cutoff)
+	case contextpkg.FeedbackTypeExplicit:
+		return s.query(`SELECT 'explicit', data, created_at FROM explicit_feedback WHERE created_at >= ? ORDER BY created_at`, cutoff)
+	default:
+		return []interface{}{}, nil
+	}
+}
+
+// CleanOldFeedback deletes feedback older than retention days
+func (s *FeedbackStore) CleanOldFeedback(retentionDays int) error {
+	cutoff := s.now().AddDate(0, 0, -retentionDays).UnixNano()
+
+	if err := s.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM implicit_feedback WHERE created_at < ?`, cutoff); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM explicit_feedback WHERE created_at < ?`, cutoff)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to clean old feedback: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database if the store opened it
+func (s *FeedbackStore) Close() error {
+	if !s.ownsDB {
+		return nil
+	}
+	return s.db.Close()
+}
+
+// query runs a select returning (type, data, created_at) rows and decodes them
+func (s *FeedbackStore) query(query string, args ...interface{}) ([]interface{}, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback: %w", err)
+	}
+	defer rows.Close()
+
+	feedback := []interface{}{}
+	for rows.Next() {
+		var feedbackType, data string
+		var createdAt int64
+		if err := rows.Scan(&feedbackType, &data, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to read feedback row: %w", err)
+		}
+
+		var item interface{}
+		switch feedbackType {
+		case contextpkg.FeedbackTypeImplicit:
+			item = &contextpkg.ContextFeedback{}
+		case contextpkg.FeedbackTypeExplicit:
+			item = &contextpkg.ExplicitFeedback{}
+		}
+		if err := json.Unmarshal([]byte(data), item); err != nil {
+			return nil, fmt.Errorf("failed to decode %s feedback: %w", feedbackType, err)
+		}
+		feedback = append(feedback, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query feedback: %w", err)
+	}
+
+	return feedback, nil
+}
+
+// withTx runs fn in a transaction, committing on success
+func (s *FeedbackStore) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Ensure FeedbackStore implements the context FeedbackStore interface
+var _ contextpkg.FeedbackStore = (*FeedbackStore)(nil)
@@ -0,0 +1,144 @@
+package sqlitestore
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	contextpkg "github.com/rcliao/teeny-orb/internal/context"
+)
+
+// openTestSQLiteStore opens a store in a temp dir
+func openTestSQLiteStore(t *testing.T) *FeedbackStore {
+	store, err := Open(filepath.Join(t.TempDir(), "feedback.db"))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// TestFeedbackStoreQueries tests time window, type filtering and cleanup
+func TestFeedbackStoreQueries(t *testing.T) {
+	store := openTestSQLiteStore(t)
+
+	now := time.Now()
+	store.now = func() time.Time { return now.Add(-48 * time.Hour) }
+	if err := store.StoreFeedback(&contextpkg.ContextFeedback{TaskID: "old", QualityScore: 0.2}); err != nil {
+		t.Fatalf("StoreFeedback failed: %v", err)
+	}
+
+	store.now = func() time.Time { return now }
+	if err := store.StoreFeedback(&contextpkg.ContextFeedback{TaskID: "recent", QualityScore: 0.8}); err != nil {
+		t.Fatalf("StoreFeedback failed: %v", err)
+	}
+	if err := store.StoreFeedback(&contextpkg.ExplicitFeedback{FeedbackID: "fb-1", TaskID: "recent", ContextQuality: 4}); err != nil {
+		t.Fatalf("StoreFeedback failed: %v", err)
+	}
+	if err := store.StoreFeedback(map[string]string{"task_id": "unknown"}); err == nil {
+		t.Error("expected error storing an unsupported feedback type")
+	}
+
+	tests := []struct {
+		name         string
+		feedbackType string
+		window       time.Duration
+		expected     int
+	}{
+		{name: "All recent feedback", window: time.Hour, expected: 2},
+		{name: "All feedback", window: 72 * time.Hour, expected: 3},
+		{name: "Recent implicit only", feedbackType: contextpkg.FeedbackTypeImplicit, window: time.Hour, expected: 1},
+		{name: "Explicit only", feedbackType: contextpkg.FeedbackTypeExplicit, window: 72 * time.Hour, expected: 1},
+		{name: "Unknown type", feedbackType: "other", window: 72 * time.Hour, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var items []interface{}
+			var err error
+			if tt.feedbackType == "" {
+				items, err = store.GetFeedback(tt.window)
+			} else {
+				items, err = store.GetFeedbackByType(tt.feedbackType, tt.window)
+			}
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			if len(items) != tt.expected {
+				t.Errorf("got %d records, expected %d", len(items), tt.expected)
+			}
+		})
+	}
+
+	// Records come back as the types they were stored as
+	explicit, err := store.GetFeedbackByType(contextpkg.FeedbackTypeExplicit, time.Hour)
+	if err != nil {
+		t.Fatalf("GetFeedbackByType failed: %v", err)
+	}
+	if len(explicit) != 1 {
+		t.Fatalf("got %d explicit records, expected 1", len(explicit))
+	}
+	if feedback, ok := explicit[0].(*contextpkg.ExplicitFeedback); !ok || feedback.FeedbackID != "fb-1" || feedback.ContextQuality != 4 {
+		t.Errorf("explicit record = %+v, expected fb-1 rated 4", explicit[0])
+	}
+
+	if err := store.CleanOldFeedback(1); err != nil {
+		t.Fatalf("CleanOldFeedback failed: %v", err)
+	}
+	items, err := store.GetFeedback(72 * time.Hour)
+	if err != nil {
+		t.Fatalf("GetFeedback failed: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("got %d records after cleanup, expected 2", len(items))
+	}
+}
+
+// TestFeedbackStoreConcurrentWrites tests that concurrent writers don't lose records
+func TestFeedbackStoreConcurrentWrites(t *testing.T) {
+	store := openTestSQLiteStore(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.StoreFeedback(&contextpkg.ContextFeedback{TaskID: "concurrent", QualityScore: 0.5}); err != nil {
+				t.Errorf("StoreFeedback failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	items, err := store.GetFeedbackByType(contextpkg.FeedbackTypeImplicit, time.Hour)
+	if err != nil {
+		t.Fatalf("GetFeedbackByType failed: %v", err)
+	}
+	if len(items) != 20 {
+		t.Errorf("got %d records, expected 20", len(items))
+	}
+}
+
+// TestOpenEscapesPath tests that characters with meaning in a URI stay part of
+// the database file name
+func TestOpenEscapesPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team #1", "feedback?100%.db")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := store.StoreFeedback(&contextpkg.ContextFeedback{TaskID: "escaped"}); err != nil {
+		t.Fatalf("StoreFeedback failed: %v", err)
+	}
+	store.Close()
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected the database at %q: %v", path, err)
+	}
+}
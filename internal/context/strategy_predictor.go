@@ -0,0 +1,84 @@
+package context
+
+// ProjectMetrics is the coarse shape of a project that strategy prediction
+// looks at
+type ProjectMetrics struct {
+	FileCount     int     `json:"file_count"`
+	AvgFileTokens float64 `json:"avg_file_tokens"`
+	GraphDensity  float64 `json:"graph_density"` // Dependency edges between project files per file
+}
+
+// MeasureProject computes the metrics of an analyzed project
+func MeasureProject(project *ProjectContext) ProjectMetrics {
+	metrics := ProjectMetrics{FileCount: len(project.Files)}
+	if metrics.FileCount == 0 {
+		return metrics
+	}
+	metrics.AvgFileTokens = float64(project.TotalTokens) / float64(metrics.FileCount)
+	if project.DependencyGraph != nil {
+		metrics.GraphDensity = float64(len(project.DependencyGraph.Edges)) / float64(metrics.FileCount)
+	}
+	return metrics
+}
+
+// StrategyPredictorConfig holds the thresholds a StrategyPredictor applies
+type StrategyPredictorConfig struct {
+	DenseGraphDensity float64 `json:"dense_graph_density"` // Density at or above which following dependencies pays off
+	FlatGraphDensity  float64 `json:"flat_graph_density"`  // Density at or below which files are mostly independent
+	LargeFileTokens   float64 `json:"large_file_tokens"`   // Average file size above which a flat project favors compactness
+	MinFiles          int     `json:"min_files"`           // Projects with fewer files get the task type's default
+}
+
+// StrategyPredictor recommends a selection strategy from the task and the
+// project's shape. Tightly coupled code is best explored along its
+// dependencies, while independent files are best picked by relevance alone;
+// in between, the task type decides.
+type StrategyPredictor struct {
+	config *StrategyPredictorConfig
+}
+
+// NewStrategyPredictor creates a strategy predictor
+func NewStrategyPredictor(config *StrategyPredictorConfig) *StrategyPredictor {
+	if config == nil {
+		config = &StrategyPredictorConfig{
+			DenseGraphDensity: 1.5,
+			FlatGraphDensity:  0.3,
+			LargeFileTokens:   2000,
+			MinFiles:          10,
+		}
+	}
+	return &StrategyPredictor{config: config}
+}
+
+// Predict recommends a strategy for task in a project with the given metrics
+func (p *StrategyPredictor) Predict(task *Task, metrics ProjectMetrics) SelectionStrategy {
+	fallback := defaultTaskStrategy(task.Type)
+	// Documentation rarely appears in the dependency graph, and a handful of
+	// files says little about how the code is structured
+	if task.Type == TaskTypeDocumentation || metrics.FileCount < p.config.MinFiles {
+		return fallback
+	}
+
+	switch {
+	case metrics.GraphDensity >= p.config.DenseGraphDensity:
+		return StrategyDependency
+	case metrics.GraphDensity <= p.config.FlatGraphDensity:
+		if metrics.AvgFileTokens > p.config.LargeFileTokens {
+			return StrategyCompactness
+		}
+		return StrategyRelevance
+	}
+	return fallback
+}
+
+// defaultTaskStrategy is the strategy each task type uses when nothing more
+// is known about the project
+func defaultTaskStrategy(taskType TaskType) SelectionStrategy {
+	switch taskType {
+	case TaskTypeDebug, TaskTypeRefactor:
+		return StrategyDependency
+	case TaskTypeFeature, TaskTypeTest, TaskTypeDocumentation:
+		return StrategyRelevance
+	}
+	return StrategyBalanced
+}
@@ -0,0 +1,92 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrWarmupPending is returned by Warmer.Ready until warmup has finished
+var ErrWarmupPending = errors.New("warmup in progress")
+
+// WarmupConfig configures what is prepared before the first request
+type WarmupConfig struct {
+	RootPath string `json:"root_path"`
+}
+
+// WarmupResult summarizes a finished warmup
+type WarmupResult struct {
+	Project  *ProjectContext `json:"-"`
+	Duration time.Duration   `json:"duration"`
+}
+
+// Warmer analyzes a workspace ahead of the first request so later analyses
+// hit the analyzer's cache. Selections aren't warmed: they are cached by the
+// task's description and constraints, which no warmup can predict.
+type Warmer struct {
+	analyzer ContextAnalyzer
+	config   *WarmupConfig
+
+	once   sync.Once
+	done   chan struct{}
+	result *WarmupResult
+	err    error
+}
+
+// NewWarmer creates a warmer
+func NewWarmer(analyzer ContextAnalyzer, config *WarmupConfig) *Warmer {
+	if config == nil {
+		config = &WarmupConfig{RootPath: "."}
+	}
+	return &Warmer{
+		analyzer: analyzer,
+		config:   config,
+		done:     make(chan struct{}),
+	}
+}
+
+// Run performs the warmup. Only the first call does any work; later calls
+// wait for it and return its result.
+func (w *Warmer) Run(ctx context.Context) (*WarmupResult, error) {
+	w.once.Do(func() {
+		defer close(w.done)
+		w.result, w.err = w.warmup(ctx)
+	})
+	<-w.done
+	return w.result, w.err
+}
+
+func (w *Warmer) warmup(ctx context.Context) (*WarmupResult, error) {
+	startTime := time.Now()
+
+	// Analysis builds the dependency graph along with the file inventory
+	project, err := w.analyzer.AnalyzeProject(ctx, w.config.RootPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze project: %w", err)
+	}
+
+	return &WarmupResult{Project: project, Duration: time.Since(startTime)}, nil
+}
+
+// Ready returns ErrWarmupPending until warmup has finished. A failed warmup
+// still counts as finished, since requests work without it, only slower.
+func (w *Warmer) Ready(ctx context.Context) error {
+	select {
+	case <-w.done:
+		return nil
+	default:
+		return ErrWarmupPending
+	}
+}
+
+// Err returns the error warmup failed with, if it has finished
+func (w *Warmer) Err() error {
+	select {
+	case <-w.done:
+		return w.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestWarmerPopulatesCaches tests that warmup leaves the project analysis cached
+func TestWarmerPopulatesCaches(t *testing.T) {
+	root := t.TempDir()
+	writeProjectFiles(t, root, map[string]string{
+		"go.mod":         "module example.com/app\n\ngo 1.21\n",
+		"main.go":        "package main\n\nimport \"example.com/app/store\"\n\nfunc main() { store.Open() }\n",
+		"store/store.go": "package store\n\n// Open opens the store\nfunc Open() {}\n",
+		"docs/README.md": "# App\n",
+	})
+
+	analyzer := NewCachingAnalyzer(NewDefaultAnalyzer(NewSimpleTokenCounter(), nil), 0)
+	warmer := NewWarmer(analyzer, &WarmupConfig{RootPath: root})
+
+	ctx := context.Background()
+	if err := warmer.Ready(ctx); !errors.Is(err, ErrWarmupPending) {
+		t.Errorf("Ready before warmup = %v, expected ErrWarmupPending", err)
+	}
+	if _, cached := analyzer.Cached(root); cached {
+		t.Fatal("analysis cached before warmup")
+	}
+
+	result, err := warmer.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if err := warmer.Ready(ctx); err != nil {
+		t.Errorf("Ready after warmup = %v", err)
+	}
+
+	project, cached := analyzer.Cached(root)
+	if !cached {
+		t.Fatal("warmup did not cache the analysis")
+	}
+	if project != result.Project {
+		t.Error("cached analysis differs from the warmed-up project")
+	}
+	if project.DependencyGraph == nil || len(project.DependencyGraph.Edges) == 0 {
+		t.Error("warmup did not build the dependency graph")
+	}
+
+	// Later analyses are served from the cache
+	again, err := analyzer.AnalyzeProject(ctx, root)
+	if err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+	if again != project {
+		t.Error("AnalyzeProject after warmup re-analyzed the project")
+	}
+}
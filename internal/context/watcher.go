@@ -0,0 +1,194 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatcherConfig configures a ProjectWatcher
+type WatcherConfig struct {
+	Debounce   time.Duration `json:"debounce"`    // Quiet period after the last change before re-analyzing
+	IgnoreDirs []string      `json:"ignore_dirs"` // Directory names that are never watched
+}
+
+// ProjectChange reports a re-analysis triggered by file changes
+type ProjectChange struct {
+	Paths   []string        // Changed paths since the previous re-analysis, sorted
+	Project *ProjectContext // The fresh analysis, nil when Err is set
+	Err     error
+}
+
+// ProjectWatcher keeps a cached project analysis current by re-analyzing
+// the project after its files change. Bursts of changes, such as an editor
+// saving several files, are coalesced into one re-analysis.
+type ProjectWatcher struct {
+	analyzer *CachingAnalyzer
+	rootPath string
+	config   *WatcherConfig
+	watcher  *fsnotify.Watcher
+	onEvent  func(path string)
+	onChange func(ctx context.Context, change ProjectChange)
+}
+
+// NewProjectWatcher watches every directory under rootPath except ignored ones
+func NewProjectWatcher(analyzer *CachingAnalyzer, rootPath string, config *WatcherConfig) (*ProjectWatcher, error) {
+	if config == nil {
+		config = &WatcherConfig{
+			IgnoreDirs: []string{".git", "node_modules", "vendor", "build", "dist"},
+		}
+	}
+	if config.Debounce <= 0 {
+		config.Debounce = 500 * time.Millisecond
+	}
+
+	root, err := filepath.Abs(rootPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve project path: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	w := &ProjectWatcher{
+		analyzer: analyzer,
+		rootPath: root,
+		config:   config,
+		watcher:  watcher,
+	}
+	if err := w.watchTree(root); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// OnChange sets the function called after each re-analysis
+func (w *ProjectWatcher) OnChange(handler func(ctx context.Context, change ProjectChange)) {
+	w.onChange = handler
+}
+
+// OnEvent sets the function called as soon as a path changes, before the
+// quiet period and re-analysis, so anything caching file contents can drop
+// them without waiting
+func (w *ProjectWatcher) OnEvent(handler func(path string)) {
+	w.onEvent = handler
+}
+
+// Run re-analyzes the project as changes arrive until ctx is done, then
+// stops watching
+func (w *ProjectWatcher) Run(ctx context.Context) error {
+	defer w.watcher.Close()
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(w.config.Debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return nil
+			}
+			if w.ignored(event.Name) {
+				continue
+			}
+			// New directories need watches of their own
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					w.watchTree(event.Name)
+				}
+			}
+			if w.onEvent != nil {
+				w.onEvent(event.Name)
+			}
+			pending[event.Name] = true
+			timer.Reset(w.config.Debounce)
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			// Events may have been dropped, so treat the whole project as changed
+			if err != nil {
+				if w.onEvent != nil {
+					w.onEvent(w.rootPath)
+				}
+				pending[w.rootPath] = true
+				timer.Reset(w.config.Debounce)
+			}
+
+		case <-timer.C:
+			w.refresh(ctx, pending)
+			pending = make(map[string]bool)
+		}
+	}
+}
+
+// refresh re-analyzes the project and reports the changed paths
+func (w *ProjectWatcher) refresh(ctx context.Context, pending map[string]bool) {
+	paths := make([]string, 0, len(pending))
+	for path := range pending {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	project, err := w.analyzer.Refresh(ctx, w.rootPath)
+	if err != nil {
+		err = fmt.Errorf("failed to re-analyze project: %w", err)
+	}
+	if w.onChange != nil {
+		w.onChange(ctx, ProjectChange{Paths: paths, Project: project, Err: err})
+	}
+}
+
+// watchTree adds a watch for dir and every directory below it that isn't ignored
+func (w *ProjectWatcher) watchTree(dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Directories may vanish between listing and watching
+			if path == dir {
+				return fmt.Errorf("failed to walk %s: %w", path, err)
+			}
+			return nil
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != w.rootPath && w.ignored(path) {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// ignored reports whether path lies in an ignored directory of the project
+func (w *ProjectWatcher) ignored(path string) bool {
+	relative, err := filepath.Rel(w.rootPath, path)
+	if err != nil {
+		return false
+	}
+	for _, part := range strings.Split(filepath.ToSlash(relative), "/") {
+		if slices.Contains(w.config.IgnoreDirs, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package context
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestProjectWatcherRefreshesAnalysis tests that a burst of file changes
+// triggers one re-analysis that the cached analysis picks up, and that
+// changes in ignored directories are not reported
+func TestProjectWatcherRefreshesAnalysis(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	writeFile("main.go", "package main\n\nfunc main() {}\n")
+	writeFile("vendor/lib/lib.go", "package lib\n")
+
+	analyzer := NewCachingAnalyzer(NewDefaultAnalyzer(NewSimpleTokenCounter(), nil), time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := analyzer.AnalyzeProject(ctx, dir); err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+
+	watcher, err := NewProjectWatcher(analyzer, dir, &WatcherConfig{Debounce: 50 * time.Millisecond, IgnoreDirs: []string{"vendor"}})
+	if err != nil {
+		t.Fatalf("NewProjectWatcher failed: %v", err)
+	}
+	changes := make(chan ProjectChange, 4)
+	watcher.OnChange(func(ctx context.Context, change ProjectChange) {
+		changes <- change
+	})
+	go watcher.Run(ctx)
+
+	writeFile("vendor/lib/lib.go", "package lib\n\nfunc Lib() {}\n")
+	writeFile("util.go", "package main\n\nfunc util() {}\n")
+	writeFile("pkg/helper.go", "package pkg\n")
+
+	// Collect until the helper in the new directory has been picked up; slow
+	// machines may split the burst across re-analyses
+	deadline := time.After(5 * time.Second)
+	var reported []string
+	var project *ProjectContext
+	for project == nil || !slices.ContainsFunc(project.Files, func(file FileInfo) bool { return filepath.Base(file.Path) == "helper.go" }) {
+		select {
+		case change := <-changes:
+			if change.Err != nil {
+				t.Fatalf("re-analysis failed: %v", change.Err)
+			}
+			reported = append(reported, change.Paths...)
+			project = change.Project
+		case <-deadline:
+			t.Fatalf("timed out waiting for re-analysis, reported %v", reported)
+		}
+	}
+
+	if !slices.Contains(reported, filepath.Join(dir, "util.go")) {
+		t.Errorf("reported %v, expected util.go", reported)
+	}
+	for _, path := range reported {
+		if filepath.Base(filepath.Dir(path)) == "lib" {
+			t.Errorf("reported %s inside an ignored directory", path)
+		}
+	}
+
+	cached, ok := analyzer.Cached(dir)
+	if !ok || cached != project {
+		t.Error("cached analysis is not the refreshed one")
+	}
+}
+
+// TestProjectWatcherReportsEventsBeforeRefresh tests that changes are reported
+// as they arrive, without waiting out the quiet period
+func TestProjectWatcherReportsEventsBeforeRefresh(t *testing.T) {
+	dir := t.TempDir()
+	analyzer := NewCachingAnalyzer(NewDefaultAnalyzer(NewSimpleTokenCounter(), nil), time.Hour)
+	watcher, err := NewProjectWatcher(analyzer, dir, &WatcherConfig{Debounce: time.Hour})
+	if err != nil {
+		t.Fatalf("NewProjectWatcher failed: %v", err)
+	}
+	events := make(chan string, 16)
+	watcher.OnEvent(func(path string) {
+		events <- path
+	})
+	watcher.OnChange(func(ctx context.Context, change ProjectChange) {
+		t.Error("re-analyzed before the quiet period ended")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("failed to write main.go: %v", err)
+	}
+
+	select {
+	case got := <-events:
+		if got != path {
+			t.Errorf("event for %s, expected %s", got, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the change event")
+	}
+}
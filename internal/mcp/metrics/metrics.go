@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics holds the Prometheus collectors for an MCP server
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	toolDuration    *prometheus.HistogramVec
+	toolErrors      *prometheus.CounterVec
+	errors          *prometheus.CounterVec
+	securityDenials *prometheus.CounterVec
+}
+
+// New creates MCP server metrics registered with registry
+func New(registry *prometheus.Registry) (*Metrics, error) {
+	m := &Metrics{
+		registry: registry,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mcp_requests_total",
+			Help: "MCP requests handled, by JSON-RPC method.",
+		}, []string{"method"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mcp_tool_call_duration_seconds",
+			Help:    "Tool call latency, by tool name.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"tool"}),
+		toolErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mcp_tool_errors_total",
+			Help: "Tool calls that failed or returned an error result, by tool name.",
+		}, []string{"tool"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mcp_errors_total",
+			Help: "JSON-RPC error responses, by error code.",
+		}, []string{"code"}),
+		securityDenials: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mcp_security_denials_total",
+			Help: "Operations denied by the security validator, by rule.",
+		}, []string{"rule"}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.requests, m.toolDuration, m.toolErrors, m.errors, m.securityDenials} {
+		if err := registry.Register(collector); err != nil {
+			return nil, fmt.Errorf("failed to register metric: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest counts a request for method. A nil Metrics records nothing,
+// as do the other Observe methods.
+func (m *Metrics) ObserveRequest(method string) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(method).Inc()
+}
+
+// ObserveToolCall records the latency of a tool call and whether it failed
+func (m *Metrics) ObserveToolCall(tool string, duration time.Duration, failed bool) {
+	if m == nil {
+		return
+	}
+	m.toolDuration.WithLabelValues(tool).Observe(duration.Seconds())
+	if failed {
+		m.toolErrors.WithLabelValues(tool).Inc()
+	}
+}
+
+// ObserveError counts a JSON-RPC error response
+func (m *Metrics) ObserveError(code int) {
+	if m == nil {
+		return
+	}
+	m.errors.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+// ObserveDenial counts an operation denied by the named security rule
+func (m *Metrics) ObserveDenial(rule string) {
+	if m == nil {
+		return
+	}
+	m.securityDenials.WithLabelValues(rule).Inc()
+}
@@ -0,0 +1,252 @@
+package security
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// AuditFileConfig configures a FileAuditSink
+type AuditFileConfig struct {
+	Path       string `json:"path"`        // Live log file; rotated segments are Path.1, Path.2, ... with Path.1 the newest
+	MaxBytes   int64  `json:"max_bytes"`   // Size at which the live file is rotated
+	MaxBackups int    `json:"max_backups"` // Rotated segments kept; older ones are deleted
+	Compress   bool   `json:"compress"`    // Gzip rotated segments, which then end in .gz
+}
+
+// FileAuditSink appends audit events to a file as JSON lines, rotating it by
+// size. One sink may be shared by every session's validator. Rotated segments
+// are compressed in the background so recording doesn't wait on gzip.
+type FileAuditSink struct {
+	config      *AuditFileConfig
+	mutex       sync.Mutex
+	file        *os.File
+	size        int64
+	err         error
+	compress    func(path string) error
+	compressing chan error // Result of the compression in flight, nil when none
+}
+
+// Ensure FileAuditSink implements AuditSink interface
+var _ AuditSink = (*FileAuditSink)(nil)
+
+// NewFileAuditSink opens config.Path for appending. MaxBytes defaults to
+// 100MB and MaxBackups to 5.
+func NewFileAuditSink(config *AuditFileConfig) (*FileAuditSink, error) {
+	if config == nil || config.Path == "" {
+		return nil, fmt.Errorf("audit file path is required")
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = 100 * 1024 * 1024
+	}
+	if config.MaxBackups <= 0 {
+		config.MaxBackups = 5
+	}
+
+	sink := &FileAuditSink{config: config, compress: compressSegment}
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+	return sink, nil
+}
+
+// Record appends event, rotating first when it would take the live file
+// past MaxBytes. A failure is kept for Err rather than interrupting the
+// audited operation.
+func (s *FileAuditSink) Record(ctx context.Context, event AuditEvent) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		s.fail(fmt.Errorf("failed to encode audit event: %w", err))
+		return
+	}
+	line = append(line, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		s.err = fmt.Errorf("audit file is closed")
+		return
+	}
+	// A single event larger than MaxBytes still gets a file of its own
+	if s.size > 0 && s.size+int64(len(line)) > s.config.MaxBytes {
+		if err := s.rotate(); err != nil {
+			s.err = err
+			// Keep appending to the live file rather than losing events
+			if s.file == nil && s.open() != nil {
+				return
+			}
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		s.err = fmt.Errorf("failed to write audit event: %w", err)
+	}
+}
+
+// Err returns the most recent failure to write, rotate or compress, if any
+func (s *FileAuditSink) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	select {
+	case err := <-s.compressing:
+		s.compressed(err)
+	default:
+	}
+	return s.err
+}
+
+// Close waits for any compression in flight and closes the live file
+func (s *FileAuditSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.compressing != nil {
+		s.compressed(<-s.compressing)
+	}
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// fail records err under the lock
+func (s *FileAuditSink) fail(err error) {
+	s.mutex.Lock()
+	s.err = err
+	s.mutex.Unlock()
+}
+
+// open opens the live file for appending and notes its size
+func (s *FileAuditSink) open() error {
+	file, err := os.OpenFile(s.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat audit file: %w", err)
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// compressed notes the result of a finished compression. Callers hold the lock.
+func (s *FileAuditSink) compressed(err error) {
+	s.compressing = nil
+	if err != nil {
+		s.err = err
+	}
+}
+
+// rotate moves the live file to segment 1, shifting older segments up and
+// deleting the oldest, then starts an empty live file and compresses the new
+// segment in the background. Callers hold the lock.
+func (s *FileAuditSink) rotate() error {
+	// Segment 1 is about to move, so the previous compression must finish first
+	if s.compressing != nil {
+		s.compressed(<-s.compressing)
+	}
+
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit file: %w", err)
+	}
+	s.file = nil
+
+	// Segments may be compressed or not depending on past configuration, so
+	// both forms are shifted
+	for n := s.config.MaxBackups; n >= 1; n-- {
+		for _, suffix := range []string{"", ".gz"} {
+			from := s.segmentPath(n) + suffix
+			if n == s.config.MaxBackups {
+				if err := os.Remove(from); err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("failed to remove old audit segment: %w", err)
+				}
+				continue
+			}
+			if err := os.Rename(from, s.segmentPath(n+1)+suffix); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to shift audit segment: %w", err)
+			}
+		}
+	}
+
+	if err := os.Rename(s.config.Path, s.segmentPath(1)); err != nil {
+		return fmt.Errorf("failed to rotate audit file: %w", err)
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+	if s.config.Compress {
+		done := make(chan error, 1)
+		s.compressing = done
+		go func(path string) {
+			done <- s.compress(path)
+		}(s.segmentPath(1))
+	}
+	return nil
+}
+
+// segmentPath names the nth newest rotated segment, without a .gz suffix
+func (s *FileAuditSink) segmentPath(n int) string {
+	return fmt.Sprintf("%s.%d", s.config.Path, n)
+}
+
+// compressSegment replaces path with path.gz
+func compressSegment(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open audit segment: %w", err)
+	}
+	defer source.Close()
+
+	temp := path + ".gz.tmp"
+	target, err := os.OpenFile(temp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create compressed audit segment: %w", err)
+	}
+	writer := gzip.NewWriter(target)
+	_, err = io.Copy(writer, source)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("failed to compress audit segment: %w", err)
+	}
+
+	if err := os.Rename(temp, path+".gz"); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("failed to compress audit segment: %w", err)
+	}
+	return os.Remove(path)
+}
+
+// MultiAuditSink forwards each event to every non-nil sink in order
+func MultiAuditSink(sinks ...AuditSink) AuditSink {
+	var active []AuditSink
+	for _, sink := range sinks {
+		if sink != nil {
+			active = append(active, sink)
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	return AuditSinkFunc(func(ctx context.Context, event AuditEvent) {
+		for _, sink := range active {
+			sink.Record(ctx, event)
+		}
+	})
+}
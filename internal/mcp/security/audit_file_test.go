@@ -0,0 +1,154 @@
+package security
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestFileAuditSinkRotation tests that writing past the size limit from
+// several sessions at once rotates the live file into numbered segments of
+// whole events and keeps only MaxBackups of them
+func TestFileAuditSinkRotation(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compress=%v", compress), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.log")
+			sink, err := NewFileAuditSink(&AuditFileConfig{Path: path, MaxBytes: 2048, MaxBackups: 2, Compress: compress})
+			if err != nil {
+				t.Fatalf("NewFileAuditSink failed: %v", err)
+			}
+
+			policy := &SecurityPolicy{AllowedPermissions: []Permission{PermissionReadFile}}
+			var wg sync.WaitGroup
+			for session := 0; session < 4; session++ {
+				validator := NewSecurityValidator(policy, "user", fmt.Sprintf("session-%d", session))
+				validator.SetAuditSink(sink)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 25; i++ {
+						validator.ValidateFileOperation(context.Background(), "read", fmt.Sprintf("/workspace/file%d.go", i))
+					}
+				}()
+			}
+			wg.Wait()
+			if err := sink.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+			if err := sink.Err(); err != nil {
+				t.Fatalf("sink reported an error: %v", err)
+			}
+
+			suffix, otherSuffix := "", ".gz"
+			if compress {
+				suffix, otherSuffix = ".gz", ""
+			}
+			for _, segment := range []string{path, path + ".1" + suffix, path + ".2" + suffix} {
+				lines := readAuditLines(t, segment, compress && segment != path)
+				if len(lines) == 0 {
+					t.Errorf("%s holds no events", filepath.Base(segment))
+				}
+			}
+			for _, missing := range []string{path + ".3", path + ".3.gz", path + ".1" + otherSuffix} {
+				if _, err := os.Stat(missing); !os.IsNotExist(err) {
+					t.Errorf("%s exists, expected it not to", filepath.Base(missing))
+				}
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("failed to stat live file: %v", err)
+			}
+			if info.Size() > 2048 {
+				t.Errorf("live file is %d bytes, expected rotation to keep it under 2048", info.Size())
+			}
+		})
+	}
+}
+
+// readAuditLines reads a segment and checks that every line is a whole event
+func readAuditLines(t *testing.T, path string, compressed bool) []AuditEvent {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", filepath.Base(path), err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if compressed {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("%s is not gzipped: %v", filepath.Base(path), err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var events []AuditEvent
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var event AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("%s has a malformed line %q: %v", filepath.Base(path), scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// TestFileAuditSinkCompressesInBackground tests that events keep being
+// recorded while a rotated segment is still being compressed
+func TestFileAuditSinkCompressesInBackground(t *testing.T) {
+	event := func(i int) AuditEvent {
+		return AuditEvent{Operation: "read", Resource: fmt.Sprintf("/workspace/file%d.go", i), Result: "allowed"}
+	}
+	line, err := json.Marshal(event(0))
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	// Three events fill the live file, so the fourth rotates it once
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileAuditSink(&AuditFileConfig{Path: path, MaxBytes: int64(3 * (len(line) + 1)), MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("NewFileAuditSink failed: %v", err)
+	}
+	release := make(chan struct{})
+	sink.compress = func(path string) error {
+		<-release
+		return compressSegment(path)
+	}
+
+	recorded := make(chan struct{})
+	go func() {
+		defer close(recorded)
+		for i := 0; i < 4; i++ {
+			sink.Record(context.Background(), event(i))
+		}
+	}()
+	select {
+	case <-recorded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("recording blocked on compressing the rotated segment")
+	}
+
+	close(release)
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := sink.Err(); err != nil {
+		t.Fatalf("sink reported an error: %v", err)
+	}
+	if lines := readAuditLines(t, path+".1.gz", true); len(lines) == 0 {
+		t.Error("compressed segment holds no events")
+	}
+}
@@ -0,0 +1,39 @@
+package security
+
+import (
+	"context"
+	"time"
+)
+
+// AuditEvent is an audited operation as delivered to an AuditSink. Resource
+// and Reason are redacted like audit trail entries.
+type AuditEvent struct {
+	Time       time.Time  `json:"time"`
+	UserID     string     `json:"user_id"`
+	SessionID  string     `json:"session_id"`
+	Operation  string     `json:"operation"`
+	Permission Permission `json:"permission"`
+	Resource   string     `json:"resource"`
+	Result     string     `json:"result"`           // "allowed" or "denied"
+	Rule       string     `json:"rule,omitempty"`   // Check that denied the operation
+	Reason     string     `json:"reason,omitempty"` // Why it was denied
+}
+
+// Denied reports whether the event records a denied operation
+func (e AuditEvent) Denied() bool {
+	return e.Result == "denied"
+}
+
+// AuditSink receives every audited operation as it happens, whether or not
+// the policy keeps an audit trail
+type AuditSink interface {
+	Record(ctx context.Context, event AuditEvent)
+}
+
+// AuditSinkFunc adapts a function to an AuditSink
+type AuditSinkFunc func(ctx context.Context, event AuditEvent)
+
+// Record calls f
+func (f AuditSinkFunc) Record(ctx context.Context, event AuditEvent) {
+	f(ctx, event)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package security
+
+import "os"
+
+// hardLinkCount returns 1, as link counts aren't available on this platform
+func hardLinkCount(info os.FileInfo) uint64 {
+	return 1
+}
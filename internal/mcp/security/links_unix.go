@@ -0,0 +1,16 @@
+//go:build unix
+
+package security
+
+import (
+	"os"
+	"syscall"
+)
+
+// hardLinkCount returns the number of names info's file has
+func hardLinkCount(info os.FileInfo) uint64 {
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Nlink)
+	}
+	return 1
+}
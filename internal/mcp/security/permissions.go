@@ -2,9 +2,12 @@ package security
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Permission represents a security permission
@@ -36,6 +39,7 @@ type SecurityPolicy struct {
 	PathRestrictions   PathRestrictions      `json:"path_restrictions"`
 	CommandWhitelist   []string              `json:"command_whitelist"`
 	ResourceLimits     ResourceLimits        `json:"resource_limits"`
+	RateLimits         RateLimits            `json:"rate_limits"`
 	AuditLog          bool                  `json:"audit_log"`
 }
 
@@ -72,13 +76,44 @@ type AuditEntry struct {
 	Error       string     `json:"error,omitempty"`
 }
 
+// Denial rules identify which check rejected an operation
+const (
+	RulePermission       = "permission"
+	RulePathRestriction  = "path_restriction"
+	RuleCommandWhitelist = "command_whitelist"
+	RuleSystemCommand    = "system_command"
+	RuleRateLimit        = "rate_limit"
+)
+
+// DenialObserver is notified with the rule behind each denied operation
+type DenialObserver func(rule string)
+
 // SecurityValidator validates operations against security policies
 type SecurityValidator struct {
-	context *SecurityContext
+	context  *SecurityContext
+	redactor *PathRedactor
+	observer DenialObserver
+	sink     AuditSink
+
+	commandLimiter *tokenBucket
+	fileLimiter    *tokenBucket
+	now            func() time.Time
+	mutex          sync.Mutex // Guards the audit trail
 }
 
-// NewSecurityValidator creates a new security validator
+// NewSecurityValidator creates a new security validator. Audit entries redact
+// paths under the policy's required base path. The policy's rate limits apply
+// to this validator's session.
 func NewSecurityValidator(policy *SecurityPolicy, userID, sessionID string) *SecurityValidator {
+	var redactor *PathRedactor
+	if policy != nil && policy.PathRestrictions.RequireBasePath != "" {
+		redactor = NewPathRedactor(policy.PathRestrictions.RequireBasePath)
+	}
+	var limits RateLimits
+	if policy != nil {
+		limits = policy.RateLimits
+	}
+
 	return &SecurityValidator{
 		context: &SecurityContext{
 			Policy:     policy,
@@ -86,9 +121,30 @@ func NewSecurityValidator(policy *SecurityPolicy, userID, sessionID string) *Sec
 			SessionID:  sessionID,
 			AuditTrail: make([]AuditEntry, 0),
 		},
+		redactor:       redactor,
+		commandLimiter: newTokenBucket(limits.CommandsPerMinute),
+		fileLimiter:    newTokenBucket(limits.FileOperationsPerMinute),
+		now:            time.Now,
 	}
 }
 
+// SetPathRedactor sets the redactor applied to audit entries; nil disables redaction
+func (sv *SecurityValidator) SetPathRedactor(redactor *PathRedactor) {
+	sv.redactor = redactor
+}
+
+// SetDenialObserver sets a callback run for every denial, whether or not the
+// policy keeps an audit log; nil removes it
+func (sv *SecurityValidator) SetDenialObserver(observer DenialObserver) {
+	sv.observer = observer
+}
+
+// SetAuditSink sets where audited operations are forwarded as they happen;
+// nil removes it
+func (sv *SecurityValidator) SetAuditSink(sink AuditSink) {
+	sv.sink = sink
+}
+
 // ValidateFileOperation validates file system operations
 func (sv *SecurityValidator) ValidateFileOperation(ctx context.Context, operation string, path string) error {
 	// Determine required permission
@@ -108,18 +164,22 @@ func (sv *SecurityValidator) ValidateFileOperation(ctx context.Context, operatio
 	
 	// Check permission
 	if !sv.hasPermission(requiredPerm) {
-		sv.auditDenied(operation, requiredPerm, path, "permission denied")
+		sv.auditDenied(ctx, RulePermission, operation, requiredPerm, path, "permission denied")
 		return fmt.Errorf("permission denied: %s on %s", operation, path)
 	}
 	
 	// Check path restrictions
 	if err := sv.validatePath(path); err != nil {
-		sv.auditDenied(operation, requiredPerm, path, err.Error())
+		sv.auditDenied(ctx, RulePathRestriction, operation, requiredPerm, path, err.Error())
 		return fmt.Errorf("path restriction: %w", err)
 	}
 	
+	if err := sv.checkRateLimit(ctx, sv.fileLimiter, operation, requiredPerm, path); err != nil {
+		return err
+	}
+	
 	// Audit success
-	sv.auditAllowed(operation, requiredPerm, path)
+	sv.auditAllowed(ctx, operation, requiredPerm, path)
 	return nil
 }
 
@@ -127,40 +187,77 @@ func (sv *SecurityValidator) ValidateFileOperation(ctx context.Context, operatio
 func (sv *SecurityValidator) ValidateCommandExecution(ctx context.Context, command string, args []string) error {
 	// Check basic execution permission
 	if !sv.hasPermission(PermissionExecCommand) {
-		sv.auditDenied("exec", PermissionExecCommand, command, "permission denied")
+		sv.auditDenied(ctx, RulePermission, "exec", PermissionExecCommand, command, "permission denied")
 		return fmt.Errorf("command execution permission denied")
 	}
 	
 	// Check command whitelist
 	if !sv.isCommandAllowed(command) {
-		sv.auditDenied("exec", PermissionExecCommand, command, "command not in whitelist")
+		sv.auditDenied(ctx, RuleCommandWhitelist, "exec", PermissionExecCommand, command, "command not in whitelist")
 		return fmt.Errorf("command not allowed: %s", command)
 	}
 	
 	// Check for dangerous system commands
 	if sv.isDangerousCommand(command, args) {
 		if !sv.hasPermission(PermissionExecSystem) {
-			sv.auditDenied("exec", PermissionExecSystem, command, "system command permission denied")
+			sv.auditDenied(ctx, RuleSystemCommand, "exec", PermissionExecSystem, command, "system command permission denied")
 			return fmt.Errorf("system command permission denied: %s", command)
 		}
 	}
 	
+	if err := sv.checkRateLimit(ctx, sv.commandLimiter, "exec", PermissionExecCommand, command); err != nil {
+		return err
+	}
+	
 	// Audit success
-	sv.auditAllowed("exec", PermissionExecCommand, command)
+	sv.auditAllowed(ctx, "exec", PermissionExecCommand, command)
 	return nil
 }
 
+// ValidateWorkingDirectory checks that a command may run in dir, which must
+// resolve within baseDir and pass the path restrictions. It returns dir
+// resolved. A dir that doesn't exist is reported without an audit entry.
+func (sv *SecurityValidator) ValidateWorkingDirectory(ctx context.Context, baseDir, dir string) (string, error) {
+	resolved, err := ResolveWithin(baseDir, dir)
+	var outside *OutsideBaseError
+	if errors.As(err, &outside) {
+		sv.auditDenied(ctx, RulePathRestriction, "exec", PermissionExecCommand, dir, "working directory outside workspace")
+		return "", fmt.Errorf("path restriction: %w", err)
+	}
+	if err != nil {
+		return "", err
+	}
+	
+	if err := sv.validatePath(resolved); err != nil {
+		sv.auditDenied(ctx, RulePathRestriction, "exec", PermissionExecCommand, dir, err.Error())
+		return "", fmt.Errorf("path restriction: %w", err)
+	}
+	return resolved, nil
+}
+
 // ValidateResourceAccess validates resource access
 func (sv *SecurityValidator) ValidateResourceAccess(ctx context.Context, resourceURI string) error {
 	if !sv.hasPermission(PermissionResourceRead) {
-		sv.auditDenied("resource", PermissionResourceRead, resourceURI, "permission denied")
+		sv.auditDenied(ctx, RulePermission, "resource", PermissionResourceRead, resourceURI, "permission denied")
 		return fmt.Errorf("resource access permission denied")
 	}
 	
-	sv.auditAllowed("resource", PermissionResourceRead, resourceURI)
+	sv.auditAllowed(ctx, "resource", PermissionResourceRead, resourceURI)
 	return nil
 }
 
+// checkRateLimit spends a token from limiter for an operation that passed
+// every other check, auditing a denial when the limit is exhausted
+func (sv *SecurityValidator) checkRateLimit(ctx context.Context, limiter *tokenBucket, operation string, permission Permission, resource string) error {
+	allowed, retryAfter := limiter.take(sv.now())
+	if allowed {
+		return nil
+	}
+	err := &RateLimitError{Operation: operation, RetryAfter: retryAfter}
+	sv.auditDenied(ctx, RuleRateLimit, operation, permission, resource, err.Error())
+	return err
+}
+
 // hasPermission checks if a permission is granted
 func (sv *SecurityValidator) hasPermission(perm Permission) bool {
 	// Check denied permissions first
@@ -180,6 +277,18 @@ func (sv *SecurityValidator) hasPermission(perm Permission) bool {
 	return false
 }
 
+// MaxFileSize returns the largest file in bytes the policy lets tools
+// handle, or 0 for no limit
+func (sv *SecurityValidator) MaxFileSize() int64 {
+	return int64(sv.context.Policy.ResourceLimits.MaxFileSize)
+}
+
+// PathAllowed reports whether the path restrictions allow path, without
+// auditing or rate limiting the check
+func (sv *SecurityValidator) PathAllowed(path string) bool {
+	return sv.validatePath(path) == nil
+}
+
 // validatePath checks path restrictions
 func (sv *SecurityValidator) validatePath(path string) error {
 	// Clean and resolve path
@@ -197,7 +306,7 @@ func (sv *SecurityValidator) validatePath(path string) error {
 			return fmt.Errorf("invalid base path: %w", err)
 		}
 		
-		if !strings.HasPrefix(cleanPath, basePath) {
+		if _, ok := relativeWithin(basePath, cleanPath); !ok {
 			return fmt.Errorf("path outside allowed base: %s", cleanPath)
 		}
 	}
@@ -209,7 +318,7 @@ func (sv *SecurityValidator) validatePath(path string) error {
 			continue
 		}
 		
-		if strings.HasPrefix(cleanPath, deniedAbs) {
+		if _, ok := relativeWithin(deniedAbs, cleanPath); ok {
 			return fmt.Errorf("path explicitly denied: %s", cleanPath)
 		}
 	}
@@ -223,7 +332,7 @@ func (sv *SecurityValidator) validatePath(path string) error {
 				continue
 			}
 			
-			if strings.HasPrefix(cleanPath, allowedAbs) {
+			if _, ok := relativeWithin(allowedAbs, cleanPath); ok {
 				allowed = true
 				break
 			}
@@ -280,37 +389,79 @@ func (sv *SecurityValidator) isDangerousCommand(command string, args []string) b
 }
 
 // auditAllowed records successful operation
-func (sv *SecurityValidator) auditAllowed(operation string, permission Permission, resource string) {
-	if sv.context.Policy.AuditLog {
-		entry := AuditEntry{
-			Timestamp:  "2025-06-22T08:00:00Z", // Simplified for testing
-			Operation:  operation,
-			Permission: permission,
-			Resource:   resource,
-			Result:     "allowed",
-		}
-		sv.context.AuditTrail = append(sv.context.AuditTrail, entry)
+func (sv *SecurityValidator) auditAllowed(ctx context.Context, operation string, permission Permission, resource string) {
+	sv.audit(ctx, AuditEvent{
+		Operation:  operation,
+		Permission: permission,
+		Resource:   resource,
+		Result:     "allowed",
+	})
+}
+
+// auditDenied records denied operation and notifies the observer of the rule
+func (sv *SecurityValidator) auditDenied(ctx context.Context, rule string, operation string, permission Permission, resource string, reason string) {
+	if sv.observer != nil {
+		sv.observer(rule)
 	}
+	sv.audit(ctx, AuditEvent{
+		Operation:  operation,
+		Permission: permission,
+		Resource:   resource,
+		Result:     "denied",
+		Rule:       rule,
+		Reason:     reason,
+	})
 }
 
-// auditDenied records denied operation
-func (sv *SecurityValidator) auditDenied(operation string, permission Permission, resource string, reason string) {
+// audit redacts an event, appends it to the audit trail when the policy keeps
+// one, and forwards it to the sink
+func (sv *SecurityValidator) audit(ctx context.Context, event AuditEvent) {
+	event.Resource = sv.redactor.Redact(event.Resource)
+	if event.Reason != "" {
+		event.Reason = sv.redactor.Redact(event.Reason)
+	}
+
 	if sv.context.Policy.AuditLog {
 		entry := AuditEntry{
 			Timestamp:  "2025-06-22T08:00:00Z", // Simplified for testing
-			Operation:  operation,
-			Permission: permission,
-			Resource:   resource,
-			Result:     "denied",
-			Error:      reason,
+			Operation:  event.Operation,
+			Permission: event.Permission,
+			Resource:   event.Resource,
+			Result:     event.Result,
+			Error:      event.Reason,
 		}
+		sv.mutex.Lock()
 		sv.context.AuditTrail = append(sv.context.AuditTrail, entry)
+		sv.mutex.Unlock()
+	}
+
+	if sv.sink != nil {
+		event.Time = sv.now()
+		event.UserID = sv.context.UserID
+		event.SessionID = sv.context.SessionID
+		sv.sink.Record(ctx, event)
+	}
+}
+
+// CheckPolicy reports whether the validator has a policy that allows anything
+func (sv *SecurityValidator) CheckPolicy() error {
+	policy := sv.context.Policy
+	if policy == nil {
+		return fmt.Errorf("no security policy loaded")
+	}
+	if len(policy.AllowedPermissions) == 0 {
+		return fmt.Errorf("security policy allows no operations")
 	}
+	return nil
 }
 
-// GetAuditTrail returns the current audit trail
+// GetAuditTrail returns a copy of the current audit trail
 func (sv *SecurityValidator) GetAuditTrail() []AuditEntry {
-	return sv.context.AuditTrail
+	sv.mutex.Lock()
+	defer sv.mutex.Unlock()
+	trail := make([]AuditEntry, len(sv.context.AuditTrail))
+	copy(trail, sv.context.AuditTrail)
+	return trail
 }
 
 // GetSecurityContext returns the current security context
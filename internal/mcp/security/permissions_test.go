@@ -0,0 +1,156 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSecurityValidatorDenialObserver tests that each denial reports the rule that caused it
+func TestSecurityValidatorDenialObserver(t *testing.T) {
+	policy := &SecurityPolicy{
+		AllowedPermissions: []Permission{PermissionReadFile, PermissionExecCommand},
+		PathRestrictions:   PathRestrictions{DeniedPaths: []string{"/etc"}},
+		CommandWhitelist:   []string{"ls"},
+	}
+	validator := NewSecurityValidator(policy, "user", "session")
+
+	var rules []string
+	validator.SetDenialObserver(func(rule string) {
+		rules = append(rules, rule)
+	})
+
+	ctx := context.Background()
+	validator.ValidateFileOperation(ctx, "write", "/workspace/main.go")
+	validator.ValidateFileOperation(ctx, "read", "/etc/passwd")
+	validator.ValidateFileOperation(ctx, "read", "/workspace/main.go")
+	validator.ValidateCommandExecution(ctx, "curl", nil)
+
+	expected := []string{RulePermission, RulePathRestriction, RuleCommandWhitelist}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("rules = %v, expected %v", rules, expected)
+	}
+}
+
+// TestSecurityValidatorPathBoundaries tests that path restrictions match
+// whole path elements, so a sibling sharing a prefix isn't inside the base
+// or a denied directory
+func TestSecurityValidatorPathBoundaries(t *testing.T) {
+	policy := &SecurityPolicy{
+		PathRestrictions: PathRestrictions{RequireBasePath: "/work", DeniedPaths: []string{"/work/secret"}},
+	}
+	validator := NewSecurityValidator(policy, "user", "session")
+
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{path: "/work", allowed: true},
+		{path: "/work/main.go", allowed: true},
+		{path: "/work2/main.go", allowed: false},
+		{path: "/work/../etc/passwd", allowed: false},
+		{path: "/work/secret/key", allowed: false},
+		{path: "/work/secrets.go", allowed: true},
+	}
+	for _, tt := range tests {
+		if allowed := validator.PathAllowed(tt.path); allowed != tt.allowed {
+			t.Errorf("PathAllowed(%q) = %v, expected %v", tt.path, allowed, tt.allowed)
+		}
+	}
+}
+
+// TestSecurityValidatorRateLimits tests that commands and file operations
+// have separate token buckets that deny with a retry delay when empty and
+// refill over time
+func TestSecurityValidatorRateLimits(t *testing.T) {
+	policy := &SecurityPolicy{
+		AllowedPermissions: []Permission{PermissionReadFile, PermissionExecCommand},
+		CommandWhitelist:   []string{"ls"},
+		RateLimits:         RateLimits{CommandsPerMinute: 2, FileOperationsPerMinute: 3},
+		AuditLog:           true,
+	}
+	validator := NewSecurityValidator(policy, "user", "session")
+	now := time.Date(2025, 6, 22, 8, 0, 0, 0, time.UTC)
+	validator.now = func() time.Time { return now }
+
+	var rules []string
+	validator.SetDenialObserver(func(rule string) {
+		rules = append(rules, rule)
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := validator.ValidateCommandExecution(ctx, "ls", nil); err != nil {
+			t.Fatalf("command %d denied: %v", i, err)
+		}
+	}
+
+	err := validator.ValidateCommandExecution(ctx, "ls", nil)
+	var limited *RateLimitError
+	if !errors.As(err, &limited) {
+		t.Fatalf("third command = %v, expected a rate limit error", err)
+	}
+	if limited.RetryAfter != 30*time.Second {
+		t.Errorf("retry after = %s, expected 30s", limited.RetryAfter)
+	}
+	if !reflect.DeepEqual(rules, []string{RuleRateLimit}) {
+		t.Errorf("rules = %v, expected a rate limit denial", rules)
+	}
+	trail := validator.GetAuditTrail()
+	if last := trail[len(trail)-1]; last.Result != "denied" || !strings.Contains(last.Error, "retry after 30s") {
+		t.Errorf("last audit entry = %+v, expected the throttled command", last)
+	}
+
+	// File operations draw from their own bucket
+	for i := 0; i < 3; i++ {
+		if err := validator.ValidateFileOperation(ctx, "read", "main.go"); err != nil {
+			t.Fatalf("file operation %d denied: %v", i, err)
+		}
+	}
+	if err := validator.ValidateFileOperation(ctx, "read", "main.go"); !errors.As(err, &limited) {
+		t.Errorf("fourth file operation = %v, expected a rate limit error", err)
+	}
+
+	// A command token refills every 30 seconds
+	now = now.Add(30 * time.Second)
+	if err := validator.ValidateCommandExecution(ctx, "ls", nil); err != nil {
+		t.Errorf("command after refill denied: %v", err)
+	}
+	if err := validator.ValidateCommandExecution(ctx, "ls", nil); !errors.As(err, &limited) {
+		t.Errorf("second command after refill = %v, expected a rate limit error", err)
+	}
+}
+
+// TestSecurityValidatorAuditTrailConcurrency tests that concurrent operations
+// all reach the audit trail and that callers get a copy of it
+func TestSecurityValidatorAuditTrailConcurrency(t *testing.T) {
+	policy := DefaultPermissivePolicy()
+	if policy.RateLimits != (RateLimits{}) || DefaultRestrictivePolicy("/work").RateLimits != (RateLimits{}) {
+		t.Error("expected the default policies to leave operations unlimited")
+	}
+	validator := NewSecurityValidator(policy, "user", "session")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			validator.ValidateFileOperation(context.Background(), "read", "/work/main.go")
+			validator.GetAuditTrail()
+		}()
+	}
+	wg.Wait()
+
+	trail := validator.GetAuditTrail()
+	if len(trail) != 50 {
+		t.Fatalf("audit trail has %d entries, expected 50", len(trail))
+	}
+	trail[0].Result = "changed"
+	if validator.GetAuditTrail()[0].Result != "allowed" {
+		t.Error("changing the returned trail changed the validator's")
+	}
+}
@@ -0,0 +1,69 @@
+package security
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimits cap how often a session may run operations, as a number per
+// minute with bursts up to the same number. Zero leaves an operation unlimited.
+type RateLimits struct {
+	CommandsPerMinute       int `json:"commands_per_minute"`
+	FileOperationsPerMinute int `json:"file_operations_per_minute"`
+}
+
+// RateLimitError denies an operation because its rate limit is exhausted
+type RateLimitError struct {
+	Operation  string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s: retry after %s", e.Operation, e.RetryAfter)
+}
+
+// tokenBucket allows capacity operations at once, refilling continuously at
+// capacity per minute
+type tokenBucket struct {
+	capacity  float64
+	tokens    float64
+	perSecond float64
+	last      time.Time
+	mutex     sync.Mutex
+}
+
+// newTokenBucket creates a full bucket, or returns nil for an unlimited rate
+func newTokenBucket(perMinute int) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity:  float64(perMinute),
+		tokens:    float64(perMinute),
+		perSecond: float64(perMinute) / 60,
+	}
+}
+
+// take spends a token, or reports how long until one is available. A nil
+// bucket always allows.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	if b == nil {
+		return true, 0
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.capacity, b.tokens+now.Sub(b.last).Seconds()*b.perSecond)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / b.perSecond * float64(time.Second))
+	return false, wait.Round(time.Millisecond)
+}
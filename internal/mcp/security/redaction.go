@@ -0,0 +1,88 @@
+package security
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PathRedactor rewrites absolute workspace paths to workspace-relative ones so
+// emitted text doesn't reveal the host's directory layout
+type PathRedactor struct {
+	roots []string // Longest first, so nested roots win
+}
+
+// NewPathRedactor creates a redactor for the given workspace roots
+func NewPathRedactor(roots ...string) *PathRedactor {
+	redactor := &PathRedactor{}
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+		root = filepath.Clean(root)
+		if root == string(filepath.Separator) {
+			continue // Redacting "/" would mangle every path
+		}
+		redactor.roots = append(redactor.roots, root)
+	}
+
+	sort.Slice(redactor.roots, func(i, j int) bool {
+		return len(redactor.roots[i]) > len(redactor.roots[j])
+	})
+	return redactor
+}
+
+// Redact replaces absolute paths under a workspace root with relative paths.
+// The root itself becomes ".". A nil redactor returns text unchanged.
+func (r *PathRedactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+
+	for _, root := range r.roots {
+		text = redactRoot(text, root)
+	}
+	return text
+}
+
+// redactRoot rewrites occurrences of root that end at a path boundary
+func redactRoot(text, root string) string {
+	var result strings.Builder
+	sep := string(filepath.Separator)
+
+	for {
+		idx := strings.Index(text, root)
+		if idx < 0 {
+			result.WriteString(text)
+			return result.String()
+		}
+
+		result.WriteString(text[:idx])
+		rest := text[idx+len(root):]
+
+		switch {
+		case idx > 0 && (isPathChar(text[idx-1]) || text[idx-1] == filepath.Separator):
+			// Part of a longer path such as /home/user/workspace
+			result.WriteString(root)
+		case strings.HasPrefix(rest, sep):
+			// root/child -> child
+			rest = rest[len(sep):]
+		case rest == "" || !isPathChar(rest[0]) || (rest[0] == '.' && (len(rest) == 1 || !isPathChar(rest[1]))):
+			// The root on its own, possibly ending a sentence
+			result.WriteString(".")
+		default:
+			// A sibling such as /workspace-other; leave it alone
+			result.WriteString(root)
+		}
+		text = rest
+	}
+}
+
+// isPathChar reports whether c can continue a path segment
+func isPathChar(c byte) bool {
+	return c == '-' || c == '_' || c == '.' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,75 @@
+package security
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestPathRedactorRedact tests rewriting of absolute workspace paths
+func TestPathRedactorRedact(t *testing.T) {
+	redactor := NewPathRedactor("/home/alice/workspace", "/home/alice/workspace/vendor/lib")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "file under root", input: "read /home/alice/workspace/main.go", expected: "read main.go"},
+		{name: "root alone", input: "cwd: /home/alice/workspace", expected: "cwd: ."},
+		{name: "root ending a sentence", input: "Working in /home/alice/workspace.", expected: "Working in .."},
+		{name: "quoted in JSON", input: `{"root_path": "/home/alice/workspace"}`, expected: `{"root_path": "."}`},
+		{name: "nested root wins", input: "/home/alice/workspace/vendor/lib/x.go", expected: "x.go"},
+		{name: "sibling directory", input: "/home/alice/workspace-old/a.go", expected: "/home/alice/workspace-old/a.go"},
+		{name: "embedded in longer path", input: "/mnt/home/alice/workspace/a.go", expected: "/mnt/home/alice/workspace/a.go"},
+		{name: "unrelated path", input: "/etc/passwd", expected: "/etc/passwd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactor.Redact(tt.input); got != tt.expected {
+				t.Errorf("Redact(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	var disabled *PathRedactor
+	if got := disabled.Redact("/home/alice/workspace/a.go"); got != "/home/alice/workspace/a.go" {
+		t.Errorf("nil redactor changed text: %q", got)
+	}
+}
+
+// TestSecurityValidatorRedactsAuditTrail tests that audit entries use paths
+// relative to the policy's base path
+func TestSecurityValidatorRedactsAuditTrail(t *testing.T) {
+	policy := &SecurityPolicy{
+		AllowedPermissions: []Permission{PermissionReadFile},
+		PathRestrictions: PathRestrictions{
+			DeniedPaths:     []string{"/srv/app/secrets"},
+			RequireBasePath: "/srv/app",
+		},
+		AuditLog: true,
+	}
+	validator := NewSecurityValidator(policy, "user", "session")
+
+	if err := validator.ValidateFileOperation(context.Background(), "read", "/srv/app/main.go"); err != nil {
+		t.Fatalf("ValidateFileOperation failed: %v", err)
+	}
+	if err := validator.ValidateFileOperation(context.Background(), "read", "/srv/app/secrets/key"); err == nil {
+		t.Fatal("expected denied path to be rejected")
+	}
+
+	trail := validator.GetAuditTrail()
+	if len(trail) != 2 {
+		t.Fatalf("got %d audit entries, expected 2", len(trail))
+	}
+	expected := []string{"main.go", "secrets/key"}
+	for i, entry := range trail {
+		if entry.Resource != expected[i] {
+			t.Errorf("Resource = %q, expected %q", entry.Resource, expected[i])
+		}
+		if strings.Contains(entry.Error, "/srv/app") {
+			t.Errorf("Error leaks base path: %q", entry.Error)
+		}
+	}
+}
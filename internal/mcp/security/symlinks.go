@@ -0,0 +1,116 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SymlinkPolicy decides whether file operations may follow links within a
+// workspace
+type SymlinkPolicy string
+
+const (
+	// SymlinkPolicyDenyOutside follows symlinks that resolve inside the
+	// workspace and rejects those leading out of it
+	SymlinkPolicyDenyOutside SymlinkPolicy = "deny_outside"
+	// SymlinkPolicyDeny rejects every symlink on the path, and files with
+	// more than one hard link, whose other names may be anywhere
+	SymlinkPolicyDeny SymlinkPolicy = "deny"
+	// SymlinkPolicyAllow follows links wherever they lead
+	SymlinkPolicyAllow SymlinkPolicy = "allow"
+)
+
+// ErrLinkDenied is matched by errors from CheckLinks
+var ErrLinkDenied = errors.New("link denied by policy")
+
+// ParseSymlinkPolicy returns the policy named s, or deny_outside for ""
+func ParseSymlinkPolicy(s string) (SymlinkPolicy, error) {
+	switch policy := SymlinkPolicy(s); policy {
+	case "":
+		return SymlinkPolicyDenyOutside, nil
+	case SymlinkPolicyDenyOutside, SymlinkPolicyDeny, SymlinkPolicyAllow:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown symlink policy %q, expected deny_outside, deny or allow", s)
+	}
+}
+
+// CheckLinks enforces policy on path, which need not exist yet. Paths outside
+// baseDir to begin with are left to the path restrictions. A dangling symlink
+// is rejected by both deny policies, since writing through it would create
+// its target.
+func CheckLinks(baseDir, path string, policy SymlinkPolicy) error {
+	if policy == SymlinkPolicyAllow {
+		return nil
+	}
+
+	base, err := filepath.Abs(baseDir)
+	if err != nil {
+		return fmt.Errorf("invalid base directory: %w", err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	path = filepath.Clean(path)
+	rel, ok := relativeWithin(base, path)
+	if !ok {
+		return nil
+	}
+
+	resolvedBase, err := filepath.EvalSymlinks(base)
+	if err != nil {
+		return fmt.Errorf("failed to resolve base directory: %w", err)
+	}
+	resolved, err := resolveExisting(path)
+	if err != nil {
+		return err
+	}
+
+	if policy == SymlinkPolicyDeny {
+		if resolved != filepath.Join(resolvedBase, rel) {
+			return fmt.Errorf("%w: %s goes through a symlink", ErrLinkDenied, path)
+		}
+		if info, err := os.Lstat(resolved); err == nil && info.Mode().IsRegular() && hardLinkCount(info) > 1 {
+			return fmt.Errorf("%w: %s has %d hard links", ErrLinkDenied, path, hardLinkCount(info))
+		}
+		return nil
+	}
+	if _, ok := relativeWithin(resolvedBase, resolved); !ok {
+		return fmt.Errorf("%w: %s resolves to %s outside %s", ErrLinkDenied, path, resolved, baseDir)
+	}
+	return nil
+}
+
+// resolveExisting follows the symlinks of path's longest existing prefix and
+// appends the rest of path unchanged
+func resolveExisting(path string) (string, error) {
+	var missing []string
+	for current := path; ; current = filepath.Dir(current) {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		if info, lerr := os.Lstat(current); lerr == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %s is a dangling symlink", ErrLinkDenied, current)
+		}
+		if filepath.Dir(current) == current {
+			return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		missing = append([]string{filepath.Base(current)}, missing...)
+	}
+}
+
+// relativeWithin returns path relative to base, and whether it lies within it
+func relativeWithin(base, path string) (string, bool) {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
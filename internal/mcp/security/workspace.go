@@ -0,0 +1,101 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Workspace is the directory and validator the tools of one MCP session work
+// under. Tools given a context carrying a workspace use it in place of their
+// own base directory and validator.
+type Workspace struct {
+	BaseDir   string
+	Validator *SecurityValidator
+
+	// Cleanup runs when the session ends; nil does nothing
+	Cleanup func() error
+}
+
+// workspaceKey carries a Workspace in a context
+type workspaceKey struct{}
+
+// WithWorkspace returns a context whose tool calls run in workspace
+func WithWorkspace(ctx context.Context, workspace *Workspace) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, workspace)
+}
+
+// WorkspaceFromContext returns the workspace of a tool call, or nil
+func WorkspaceFromContext(ctx context.Context) *Workspace {
+	workspace, _ := ctx.Value(workspaceKey{}).(*Workspace)
+	return workspace
+}
+
+// ResolveWithin resolves dir against baseDir, following symlinks, and fails
+// when the result lies outside baseDir
+func ResolveWithin(baseDir, dir string) (string, error) {
+	base, err := filepath.EvalSymlinks(baseDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve base directory: %w", err)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(baseDir, dir)
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	base, _ = filepath.Abs(base)
+	resolved, _ = filepath.Abs(resolved)
+	if rel, err := filepath.Rel(base, resolved); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &OutsideBaseError{Path: dir, BaseDir: baseDir}
+	}
+	return resolved, nil
+}
+
+// OutsideBaseError rejects a path that resolves outside the directory it
+// must stay in
+type OutsideBaseError struct {
+	Path    string
+	BaseDir string
+}
+
+func (e *OutsideBaseError) Error() string {
+	return fmt.Sprintf("%s is outside %s", e.Path, e.BaseDir)
+}
+
+// NewWorkspace creates a workspace rooted at baseDir with its own validator.
+// The validator enforces policy with the required base path moved to
+// baseDir, so sessions can't reach each other's directories, and keeps its
+// own audit trail and rate limits.
+func NewWorkspace(policy *SecurityPolicy, userID, sessionID, baseDir string) *Workspace {
+	sessionPolicy := *policy
+	sessionPolicy.PathRestrictions.RequireBasePath = baseDir
+	return &Workspace{
+		BaseDir:   baseDir,
+		Validator: NewSecurityValidator(&sessionPolicy, userID, sessionID),
+	}
+}
+
+// NewScratchWorkspace is NewWorkspace for a directory created now and
+// removed by Close
+func NewScratchWorkspace(policy *SecurityPolicy, userID, sessionID, baseDir string) (*Workspace, error) {
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session workspace: %w", err)
+	}
+	workspace := NewWorkspace(policy, userID, sessionID, baseDir)
+	workspace.Cleanup = func() error {
+		return os.RemoveAll(baseDir)
+	}
+	return workspace, nil
+}
+
+// Close runs the workspace's cleanup
+func (w *Workspace) Close() error {
+	if w == nil || w.Cleanup == nil {
+		return nil
+	}
+	return w.Cleanup()
+}
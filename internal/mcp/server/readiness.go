@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+)
+
+// ReadinessProbe is a self-check; a non-nil error explains what isn't ready
+type ReadinessProbe func(ctx context.Context) error
+
+// ReadinessChecker is implemented by tools that can check they are able to
+// run, such as a command tool finding the executables it needs
+type ReadinessChecker interface {
+	CheckReady(ctx context.Context) error
+}
+
+// AddReadinessProbe registers a named self-check, replacing any probe of the
+// same name
+func (s *Server) AddReadinessProbe(name string, probe ReadinessProbe) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.probes[name] = probe
+}
+
+// CheckReadiness runs every probe and a self-check per registered tool,
+// returning the failures by check name. Tools check themselves through
+// ReadinessChecker when they implement it; others only have to describe
+// themselves, which costs them nothing.
+func (s *Server) CheckReadiness(ctx context.Context) map[string]error {
+	s.mutex.RLock()
+	checks := make(map[string]ReadinessProbe, len(s.probes)+len(s.tools))
+	for name, probe := range s.probes {
+		checks[name] = probe
+	}
+	for name, handler := range s.tools {
+		checks["tool:"+name] = toolProbe(handler)
+	}
+	s.mutex.RUnlock()
+
+	failed := make(map[string]error)
+	for name, check := range checks {
+		if err := ctx.Err(); err != nil {
+			failed[name] = err
+			continue
+		}
+		if err := check(ctx); err != nil {
+			failed[name] = err
+		}
+	}
+	return failed
+}
+
+// toolProbe checks a tool through its own readiness check, or by describing it
+func toolProbe(handler mcp.MCPToolHandler) ReadinessProbe {
+	return func(ctx context.Context) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("tool panicked: %v", r)
+			}
+		}()
+
+		if checker, ok := handler.(ReadinessChecker); ok {
+			return checker.CheckReady(ctx)
+		}
+		if handler.InputSchema().Type == "" {
+			return fmt.Errorf("tool has no input schema")
+		}
+		return nil
+	}
+}
+
+// WritableDirProbe checks that files can be created in dir
+func WritableDirProbe(dir string) ReadinessProbe {
+	return func(ctx context.Context) error {
+		file, err := os.CreateTemp(dir, ".ready-*")
+		if err != nil {
+			return fmt.Errorf("workspace is not writable: %w", err)
+		}
+		file.Close()
+		if err := os.Remove(file.Name()); err != nil {
+			return fmt.Errorf("failed to remove readiness check file: %w", err)
+		}
+		return nil
+	}
+}
@@ -3,10 +3,18 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/metrics"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
 )
 
 // Server implements the MCP server interface
@@ -14,10 +22,57 @@ type Server struct {
 	info         mcp.ServerInfo
 	capabilities mcp.ServerCapabilities
 	tools        map[string]mcp.MCPToolHandler
-	initialized  bool
+	disabled     map[string]bool     // Tools disabled for every session
+	sessions     map[string]*Session // By session ID; a session exists once its initialize request succeeds
+	workspaces   WorkspaceFactory
+	notify       NotificationSender
+	metrics      *metrics.Metrics
+	probes       map[string]ReadinessProbe
+	toolTimeout  time.Duration // For tools that don't advertise their own
+	maxTimeout   time.Duration // No call runs longer, whatever its tool or request asks
+	cache        *toolCache    // nil unless enabled with SetToolCacheTTL
+	sessionIdle  time.Duration // Sessions unused for longer are closed; zero keeps them
+	maxSessions  int           // Most sessions open at once; zero for no limit
+	now          func() time.Time
 	mutex        sync.RWMutex
 }
 
+// Session is the state negotiated by the initialize handshake
+type Session struct {
+	ID                 string
+	ProtocolVersion    string
+	ClientInfo         mcp.ClientInfo
+	ClientCapabilities mcp.ClientCapabilities
+	Workspace          *security.Workspace // nil when tools use their own base directory and validator
+	disabledTools      map[string]bool     // Tools disabled for this session only
+	lastActive         atomic.Int64        // Unix nanoseconds of the session's last request
+}
+
+// WorkspaceFactory creates the workspace for a new session
+type WorkspaceFactory func(sessionID string) (*security.Workspace, error)
+
+// NotificationSender delivers a server-initiated notification to the client
+type NotificationSender func(ctx context.Context, msg *mcp.Message) error
+
+const (
+	DefaultToolTimeout    = 2 * time.Minute  // Timeout of tools that don't advertise one
+	DefaultMaxToolTimeout = 10 * time.Minute // Longest any tool call may run
+)
+
+// ErrDuplicateTool is returned by RegisterTool for a name already registered
+var ErrDuplicateTool = errors.New("tool already registered")
+
+// UnsupportedVersionError rejects an initialize request for a protocol version
+// the server doesn't speak
+type UnsupportedVersionError struct {
+	Requested string
+	Supported []string
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("unsupported protocol version %q (supported: %s)", e.Requested, strings.Join(e.Supported, ", "))
+}
+
 // NewServer creates a new MCP server
 func NewServer(name, version string) *Server {
 	return &Server{
@@ -31,34 +86,204 @@ func NewServer(name, version string) *Server {
 			},
 			Logging: &mcp.LoggingCapability{},
 		},
-		tools: make(map[string]mcp.MCPToolHandler),
+		tools:       make(map[string]mcp.MCPToolHandler),
+		disabled:    make(map[string]bool),
+		sessions:    make(map[string]*Session),
+		probes:      make(map[string]ReadinessProbe),
+		toolTimeout: DefaultToolTimeout,
+		maxTimeout:  DefaultMaxToolTimeout,
+		now:         time.Now,
 	}
 }
 
-// Initialize handles the initialization request
-func (s *Server) Initialize(ctx context.Context, req *mcp.InitializeRequest) (*mcp.InitializeResponse, error) {
+// SetMetrics sets the collectors fed by request handling and tool calls; nil disables metrics
+func (s *Server) SetMetrics(m *metrics.Metrics) {
+	s.metrics = m
+}
+
+// SetToolTimeouts sets the timeout of tools that don't advertise their own
+// and the maximum no call may exceed, even when its tool or request asks for
+// longer. Zero leaves calls unbounded by either.
+func (s *Server) SetToolTimeouts(defaultTimeout, maxTimeout time.Duration) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	s.toolTimeout = defaultTimeout
+	s.maxTimeout = maxTimeout
+}
 
-	// Accept any reasonable protocol version for maximum compatibility
-	// Log for debugging but don't reject - DO NOT log to stdout for stdio transport!
-	// Only log if this is an HTTP server (not stdio)
+// callTimeout returns how long a call to handler may run: the request's
+// timeout, else the tool's, else the server default, capped by the maximum.
+// Zero means no limit.
+func (s *Server) callTimeout(handler mcp.MCPToolHandler, requested time.Duration) time.Duration {
+	s.mutex.RLock()
+	timeout, maxTimeout := s.toolTimeout, s.maxTimeout
+	s.mutex.RUnlock()
+
+	if timed, ok := handler.(mcp.TimeoutToolHandler); ok && timed.Timeout() > 0 {
+		timeout = timed.Timeout()
+	}
+	if requested > 0 {
+		timeout = requested
+	}
+	if maxTimeout > 0 && (timeout <= 0 || timeout > maxTimeout) {
+		timeout = maxTimeout
+	}
+	return timeout
+}
+
+// SetWorkspaceFactory gives each new session its own workspace, created on
+// initialize and closed with the session; nil shares the tools' defaults
+func (s *Server) SetWorkspaceFactory(factory WorkspaceFactory) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.workspaces = factory
+}
+
+// SetNotificationSender sets how Notify reaches the client; nil drops
+// notifications
+func (s *Server) SetNotificationSender(send NotificationSender) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.notify = send
+}
+
+// EnableResourceNotifications advertises that the server sends
+// notifications/resources/list_changed when the project changes
+func (s *Server) EnableResourceNotifications() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.capabilities.Resources = &mcp.ResourcesCapability{ListChanged: true}
+}
+
+// Notify sends a notification to the client. While handling a request whose
+// transport streams notifications, they go out ahead of its response;
+// otherwise they use the notification sender. Notifications before any
+// session is initialized, or without a sender, are dropped.
+func (s *Server) Notify(ctx context.Context, method string, params interface{}) error {
+	s.mutex.RLock()
+	send := s.notify
+	ready := len(s.sessions) > 0
+	s.mutex.RUnlock()
+	if notify := mcp.RequestNotifierFromContext(ctx); notify != nil {
+		send = NotificationSender(notify)
+	}
+	if send == nil || !ready {
+		return nil
+	}
+
+	msg := &mcp.Message{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s params: %w", method, err)
+		}
+		msg.Params = data
+	}
+	if err := send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send %s: %w", method, err)
+	}
+	return nil
+}
+
+// SecurityDeniedNotification is the method of notifications about operations
+// blocked by a security check
+const SecurityDeniedNotification = "notifications/security/denied"
+
+// DenialNotifier returns an audit sink that tells the client about each denied
+// operation, with the rule that blocked it, as a notifications/security/denied
+// notification
+func (s *Server) DenialNotifier() security.AuditSink {
+	return security.AuditSinkFunc(func(ctx context.Context, event security.AuditEvent) {
+		if !event.Denied() {
+			return
+		}
+		// The tool call itself reports the failure, so a lost notification only
+		// costs the client detail
+		s.Notify(ctx, SecurityDeniedNotification, event)
+	})
+}
+
+// Initialize handles the initialization request for the session named by
+// ctx, creating the session's workspace the first time. A new session past
+// the session limit first closes idle ones, and is refused with
+// ErrTooManySessions when none are.
+func (s *Server) Initialize(ctx context.Context, req *mcp.InitializeRequest) (*mcp.InitializeResponse, error) {
+	// Expired sessions are closed once the mutex is released
+	var expired []*Session
+	defer func() { closeSessions(expired) }()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-	s.initialized = true
+	// Clients that don't name a version get our default
+	version := req.ProtocolVersion
+	if version == "" {
+		version = mcp.MCPVersion
+	}
+	if !slices.Contains(mcp.SupportedMCPVersions, version) {
+		return nil, &UnsupportedVersionError{Requested: version, Supported: mcp.SupportedMCPVersions}
+	}
 
-	// Respond with the client's requested version if supported, otherwise use our default
-	responseVersion := req.ProtocolVersion
-	if responseVersion == "" {
-		responseVersion = mcp.MCPVersion
+	// Re-initializing, e.g. after an HTTP client reconnects, renegotiates the
+	// session but keeps its workspace
+	id := mcp.SessionIDFromContext(ctx)
+	session := &Session{
+		ID:                 id,
+		ProtocolVersion:    version,
+		ClientInfo:         req.ClientInfo,
+		ClientCapabilities: req.Capabilities,
+	}
+	existing, exists := s.sessions[id]
+	if !exists && s.maxSessions > 0 && len(s.sessions) >= s.maxSessions {
+		expired = s.expireSessions()
+		if len(s.sessions) >= s.maxSessions {
+			return nil, fmt.Errorf("%w: %d open", ErrTooManySessions, len(s.sessions))
+		}
+	}
+	if exists {
+		session.Workspace = existing.Workspace
+		session.disabledTools = existing.disabledTools
+	} else if s.workspaces != nil {
+		workspace, err := s.workspaces(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create workspace for session %q: %w", id, err)
+		}
+		session.Workspace = workspace
 	}
+	session.touch(s.now())
+	s.sessions[id] = session
 
 	return &mcp.InitializeResponse{
-		ProtocolVersion: responseVersion,
+		ProtocolVersion: version,
 		Capabilities:    s.capabilities,
 		ServerInfo:      s.info,
 	}, nil
 }
 
+// Session returns the negotiated session with the given ID, or nil before
+// its initialization
+func (s *Server) Session(id string) *Session {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.sessions[id]
+}
+
+// CloseSession ends a session, closing its workspace. Its client must
+// initialize again before further requests.
+func (s *Server) CloseSession(id string) error {
+	s.mutex.Lock()
+	session, exists := s.sessions[id]
+	delete(s.sessions, id)
+	s.mutex.Unlock()
+
+	if !exists {
+		return nil
+	}
+	if err := session.Workspace.Close(); err != nil {
+		return fmt.Errorf("failed to close workspace for session %q: %w", id, err)
+	}
+	return nil
+}
+
 // RegisterTool registers a tool handler
 func (s *Server) RegisterTool(handler mcp.MCPToolHandler) error {
 	s.mutex.Lock()
@@ -66,24 +291,99 @@ func (s *Server) RegisterTool(handler mcp.MCPToolHandler) error {
 
 	name := handler.Name()
 	if _, exists := s.tools[name]; exists {
-		return fmt.Errorf("tool already registered: %s", name)
+		return fmt.Errorf("%w: %s", ErrDuplicateTool, name)
 	}
 
 	s.tools[name] = handler
 	return nil
 }
 
-// ListTools lists all available tools
+// DisableTool hides a tool from every session's tools/list and rejects calls
+// to it with a tool_disabled error until EnableTool. Calls already running
+// finish.
+func (s *Server) DisableTool(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.tools[name]; !exists {
+		return fmt.Errorf("tool not found: %s", name)
+	}
+	s.disabled[name] = true
+	return nil
+}
+
+// EnableTool lifts a DisableTool. Sessions that disabled the tool for
+// themselves still don't see it.
+func (s *Server) EnableTool(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.tools[name]; !exists {
+		return fmt.Errorf("tool not found: %s", name)
+	}
+	delete(s.disabled, name)
+	return nil
+}
+
+// DisableSessionTool disables a tool for one initialized session, as
+// DisableTool does for all of them. It lasts until EnableSessionTool or the
+// session closes.
+func (s *Server) DisableSessionTool(sessionID, name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.tools[name]; !exists {
+		return fmt.Errorf("tool not found: %s", name)
+	}
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	if session.disabledTools == nil {
+		session.disabledTools = make(map[string]bool)
+	}
+	session.disabledTools[name] = true
+	return nil
+}
+
+// EnableSessionTool lifts a DisableSessionTool. A tool disabled globally
+// stays disabled.
+func (s *Server) EnableSessionTool(sessionID, name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.tools[name]; !exists {
+		return fmt.Errorf("tool not found: %s", name)
+	}
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	delete(session.disabledTools, name)
+	return nil
+}
+
+// toolDisabled reports whether name is disabled globally or for session,
+// which may be nil. Callers hold the lock.
+func (s *Server) toolDisabled(session *Session, name string) bool {
+	return s.disabled[name] || session != nil && session.disabledTools[name]
+}
+
+// ListTools lists the tools available to the session
 func (s *Server) ListTools(ctx context.Context, req *mcp.ListToolsRequest) (*mcp.ListToolsResponse, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	if !s.initialized {
+	session := s.sessions[mcp.SessionIDFromContext(ctx)]
+	if session == nil {
 		return nil, fmt.Errorf("server not initialized")
 	}
 
 	tools := make([]mcp.Tool, 0, len(s.tools))
-	for _, handler := range s.tools {
+	for name, handler := range s.tools {
+		if s.toolDisabled(session, name) {
+			continue
+		}
 		tools = append(tools, mcp.Tool{
 			Name:        handler.Name(),
 			Description: handler.Description(),
@@ -96,6 +396,102 @@ func (s *Server) ListTools(ctx context.Context, req *mcp.ListToolsRequest) (*mcp
 	}, nil
 }
 
+// ListRegisteredTools returns every registered tool not disabled globally,
+// sorted by name. Unlike ListTools it doesn't require an initialized MCP
+// session.
+func (s *Server) ListRegisteredTools() []mcp.Tool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tools := make([]mcp.Tool, 0, len(s.tools))
+	for name, handler := range s.tools {
+		if s.toolDisabled(nil, name) {
+			continue
+		}
+		tools = append(tools, mcp.Tool{
+			Name:        handler.Name(),
+			Description: handler.Description(),
+			InputSchema: handler.InputSchema(),
+		})
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name < tools[j].Name
+	})
+	return tools
+}
+
+// InvokeTool runs a registered tool for callers such as the REST endpoints
+// that don't perform the initialize handshake. A call naming an initialized
+// session in ctx runs in that session's workspace. Without one it's refused
+// when sessions have workspaces of their own, since it would escape their
+// isolation and rate limits.
+func (s *Server) InvokeTool(ctx context.Context, name string, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	s.mutex.RLock()
+	handler, exists := s.tools[name]
+	session := s.sessions[mcp.SessionIDFromContext(ctx)]
+	isolated := s.workspaces != nil
+	disabled := s.toolDisabled(session, name)
+	s.mutex.RUnlock()
+	if session != nil {
+		session.touch(s.now())
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("tool not found: %s", name)
+	}
+	if session == nil && isolated {
+		return mcp.NewToolErrorResponse(mcp.ErrorCodeUnavailable,
+			"Sessions have their own workspaces: call tools from an initialized session", map[string]interface{}{"tool": name}), nil
+	}
+	if disabled {
+		return disabledToolResponse(name), nil
+	}
+
+	scope := ""
+	if session != nil && session.Workspace != nil {
+		ctx = security.WithWorkspace(ctx, session.Workspace)
+		scope = session.ID
+	}
+	return s.runCached(ctx, scope, name, handler, arguments, 0)
+}
+
+// runTool validates arguments against the tool's input schema, runs the
+// handler when they match, and records its latency and outcome. Invalid
+// arguments are reported as a tool error listing every problem, so clients
+// can correct the call; tools remain free to check meaning beyond the schema.
+// The handler's context expires after the call's timeout, and a handler that
+// fails once it has is reported as timed out.
+func (s *Server) runTool(ctx context.Context, name string, handler mcp.MCPToolHandler, arguments map[string]interface{}, requested time.Duration) (*mcp.CallToolResponse, error) {
+	start := time.Now()
+	if problems := validateArguments(handler.InputSchema(), arguments); len(problems) > 0 {
+		s.metrics.ObserveToolCall(name, time.Since(start), true)
+		return mcp.NewToolErrorResponse(mcp.ErrorCodeInvalidArgument,
+			fmt.Sprintf("Invalid arguments for tool %s: %s", name, strings.Join(problems, "; ")),
+			map[string]interface{}{"problems": problems}), nil
+	}
+
+	timeout := s.callTimeout(handler, requested)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	resp, err := handler.Handle(ctx, arguments)
+	if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) && (err != nil || resp == nil) {
+		resp, err = mcp.NewToolErrorResponse(mcp.ErrorCodeTimeout, fmt.Sprintf("Tool %s timed out after %s", name, timeout),
+			map[string]interface{}{"tool": name, "timeout_ms": timeout.Milliseconds()}), nil
+	}
+	s.metrics.ObserveToolCall(name, time.Since(start), err != nil || (resp != nil && resp.IsError))
+	return resp, err
+}
+
+// disabledToolResponse rejects a call to a disabled tool
+func disabledToolResponse(name string) *mcp.CallToolResponse {
+	return mcp.NewToolErrorResponse(mcp.ErrorCodeToolDisabled, fmt.Sprintf("Tool disabled: %s", name),
+		map[string]interface{}{"tool": name})
+}
+
 // CallTool executes a tool call
 func (s *Server) CallTool(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResponse, error) {
 	s.mutex.RLock()
@@ -103,34 +499,123 @@ func (s *Server) CallTool(ctx context.Context, req *mcp.CallToolRequest) (*mcp.C
 	s.mutex.RUnlock()
 
 	if !exists {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: fmt.Sprintf("Tool not found: %s", req.Name),
-				},
-			},
-			IsError: true,
-		}, nil
+		return mcp.NewToolErrorResponse(mcp.ErrorCodeNotFound, fmt.Sprintf("Tool not found: %s", req.Name),
+			map[string]interface{}{"tool": req.Name}), nil
 	}
 
-	if !s.initialized {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: "Server not initialized",
-				},
-			},
-			IsError: true,
-		}, nil
+	s.mutex.RLock()
+	session := s.sessions[mcp.SessionIDFromContext(ctx)]
+	disabled := s.toolDisabled(session, req.Name)
+	s.mutex.RUnlock()
+
+	if session == nil {
+		return mcp.NewToolErrorResponse(mcp.ErrorCodeUnavailable, "Server not initialized", nil), nil
+	}
+	if disabled {
+		return disabledToolResponse(req.Name), nil
+	}
+
+	if session.Workspace != nil {
+		ctx = security.WithWorkspace(ctx, session.Workspace)
+	}
+	// Events go out the way Notify sends them, so only when it can
+	s.mutex.RLock()
+	canNotify := s.notify != nil || mcp.RequestNotifierFromContext(ctx) != nil
+	s.mutex.RUnlock()
+	if canNotify {
+		ctx = mcp.WithEventEmitter(ctx, func(ctx context.Context, event mcp.ToolEvent) error {
+			event.Tool = req.Name
+			return s.Notify(ctx, mcp.ToolEventNotification, event)
+		})
+	}
+	var requested time.Duration
+	if req.Meta != nil {
+		requested = time.Duration(req.Meta.TimeoutMs) * time.Millisecond
 	}
+	// Sessions with workspaces of their own read different files
+	scope := ""
+	if session.Workspace != nil {
+		scope = session.ID
+	}
+	return s.runCached(ctx, scope, req.Name, handler, req.Arguments, requested)
+}
 
-	return handler.Handle(ctx, req.Arguments)
+// knownMethods are the JSON-RPC methods counted under their own name in metrics
+var knownMethods = map[string]bool{
+	"initialize":                true,
+	"notifications/initialized": true,
+	"tools/list":                true,
+	"tools/call":                true,
+}
+
+// methodLabel names a request's method for metrics. Clients choose the method, so
+// anything the server doesn't handle is counted as unknown rather than
+// growing a label value per name sent.
+func methodLabel(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return "unknown"
 }
 
 // HandleMessage processes incoming MCP messages
 func (s *Server) HandleMessage(ctx context.Context, msg *mcp.Message) (*mcp.Message, error) {
+	s.metrics.ObserveRequest(methodLabel(msg.Method))
+
+	response, err := s.handleMessage(ctx, msg)
+	switch {
+	case err != nil:
+		s.metrics.ObserveError(mcp.InternalError)
+	case response != nil && response.Error != nil:
+		s.metrics.ObserveError(response.Error.Code)
+	}
+	return response, err
+}
+
+// HandleBatch processes each message of a JSON-RPC batch independently and
+// returns the responses in request order. Notifications get no response, so
+// the result is empty for a batch of only notifications. Elements that aren't
+// messages are answered with an invalid request error.
+func (s *Server) HandleBatch(ctx context.Context, batch mcp.Batch) []*mcp.Message {
+	var responses []*mcp.Message
+	for _, raw := range batch {
+		var msg mcp.Message
+		if err := json.Unmarshal(raw, &msg); err != nil || msg.Method == "" {
+			s.metrics.ObserveError(mcp.InvalidRequest)
+			responses = append(responses, &mcp.Message{
+				JSONRPC: "2.0",
+				ID:      msg.ID,
+				Error: &mcp.Error{
+					Code:    mcp.InvalidRequest,
+					Message: "Invalid request in batch",
+				},
+			})
+			continue
+		}
+
+		response, err := s.HandleMessage(ctx, &msg)
+		if err != nil {
+			if msg.ID == nil {
+				continue
+			}
+			response = &mcp.Message{
+				JSONRPC: "2.0",
+				ID:      msg.ID,
+				Error: &mcp.Error{
+					Code:    mcp.InternalError,
+					Message: err.Error(),
+				},
+			}
+		}
+		if response != nil {
+			responses = append(responses, response)
+		}
+	}
+	return responses
+}
+
+// handleMessage dispatches a message to the handler for its method
+func (s *Server) handleMessage(ctx context.Context, msg *mcp.Message) (*mcp.Message, error) {
 	// Handle notifications (no ID means no response expected)
 	if msg.ID == nil {
 		switch msg.Method {
@@ -143,6 +628,22 @@ func (s *Server) HandleMessage(ctx context.Context, msg *mcp.Message) (*mcp.Mess
 		}
 	}
 
+	// Everything but initialize waits for the handshake
+	session := s.Session(mcp.SessionIDFromContext(ctx))
+	if session != nil {
+		session.touch(s.now())
+	}
+	if msg.Method != "initialize" && session == nil {
+		return &mcp.Message{
+			JSONRPC: "2.0",
+			ID:      msg.ID,
+			Error: &mcp.Error{
+				Code:    mcp.ServerNotInitialized,
+				Message: fmt.Sprintf("Server not initialized: send initialize before %s", msg.Method),
+			},
+		}, nil
+	}
+
 	// Handle requests (have ID, need response)
 	switch msg.Method {
 	case "initialize":
@@ -189,6 +690,21 @@ func (s *Server) handleInitialize(ctx context.Context, msg *mcp.Message) (*mcp.M
 	}
 
 	resp, err := s.Initialize(ctx, &req)
+	var unsupported *UnsupportedVersionError
+	if errors.As(err, &unsupported) {
+		return &mcp.Message{
+			JSONRPC: "2.0",
+			ID:      msg.ID,
+			Error: &mcp.Error{
+				Code:    mcp.InvalidParams,
+				Message: "Unsupported protocol version",
+				Data: map[string]interface{}{
+					"requested": unsupported.Requested,
+					"supported": unsupported.Supported,
+				},
+			},
+		}, nil
+	}
 	if err != nil {
 		return &mcp.Message{
 			JSONRPC: "2.0",
@@ -302,6 +818,18 @@ func (s *Server) handleCallTool(ctx context.Context, msg *mcp.Message) (*mcp.Mes
 		}, nil
 	}
 
+	if req.Meta != nil && req.Meta.ProgressToken != nil {
+		ctx = mcp.WithProgressToken(ctx, req.Meta.ProgressToken)
+		// Transports that can't stream with the response deliver progress
+		// through the notification sender
+		s.mutex.RLock()
+		send := s.notify
+		s.mutex.RUnlock()
+		if mcp.RequestNotifierFromContext(ctx) == nil && send != nil {
+			ctx = mcp.WithRequestNotifier(ctx, mcp.RequestNotifier(send))
+		}
+	}
+
 	resp, err := s.CallTool(ctx, &req)
 	if err != nil {
 		return &mcp.Message{
@@ -346,7 +874,13 @@ func (s *Server) Close() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	
-	s.initialized = false
+	var errs []error
+	for id, session := range s.sessions {
+		if err := session.Workspace.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close workspace for session %q: %w", id, err))
+		}
+	}
+	s.sessions = make(map[string]*Session)
 	s.tools = make(map[string]mcp.MCPToolHandler)
-	return nil
+	return errors.Join(errs...)
 }
\ No newline at end of file
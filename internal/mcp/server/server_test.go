@@ -0,0 +1,786 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
+)
+
+// noopTool returns an empty result
+type noopTool struct{}
+
+func (noopTool) Name() string                 { return "noop" }
+func (noopTool) Description() string          { return "Does nothing" }
+func (noopTool) InputSchema() mcp.InputSchema { return mcp.InputSchema{Type: "object"} }
+func (noopTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	return &mcp.CallToolResponse{Content: []mcp.Content{{Type: "text", Text: "ok"}}}, nil
+}
+
+// request builds a JSON-RPC request message
+func request(id int, method string, params string) *mcp.Message {
+	msg := &mcp.Message{JSONRPC: "2.0", ID: float64(id), Method: method}
+	if params != "" {
+		msg.Params = json.RawMessage(params)
+	}
+	return msg
+}
+
+// initializeParams builds initialize params requesting a protocol version
+func initializeParams(version string) string {
+	return fmt.Sprintf(`{"protocolVersion":%q,"capabilities":{"experimental":{"streaming":true}},"clientInfo":{"name":"test-client","version":"1.0"}}`, version)
+}
+
+// TestHandshakeOrder tests that requests other than initialize are rejected
+// until the handshake completes
+func TestHandshakeOrder(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("test", "0.0.0")
+	if err := s.RegisterTool(noopTool{}); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+
+	for _, msg := range []*mcp.Message{
+		request(1, "tools/call", `{"name":"noop","arguments":{}}`),
+		request(2, "tools/list", ""),
+	} {
+		response, err := s.HandleMessage(ctx, msg)
+		if err != nil {
+			t.Fatalf("%s: HandleMessage failed: %v", msg.Method, err)
+		}
+		if response.Error == nil || response.Error.Code != mcp.ServerNotInitialized {
+			t.Errorf("%s before initialize = %+v, expected a not initialized error", msg.Method, response)
+		}
+		if response.ID != msg.ID {
+			t.Errorf("%s error id = %v, expected %v", msg.Method, response.ID, msg.ID)
+		}
+	}
+	if s.Session("") != nil {
+		t.Fatal("session exists before initialize")
+	}
+
+	response, err := s.HandleMessage(ctx, request(3, "initialize", initializeParams(mcp.MCPVersion)))
+	if err != nil || response.Error != nil {
+		t.Fatalf("initialize failed: %+v, %v", response, err)
+	}
+	session := s.Session("")
+	if session == nil || session.ProtocolVersion != mcp.MCPVersion || session.ClientInfo.Name != "test-client" {
+		t.Fatalf("session = %+v, expected the negotiated version and client", session)
+	}
+	if session.ClientCapabilities.Experimental["streaming"] != true {
+		t.Errorf("client capabilities = %+v, expected experimental streaming", session.ClientCapabilities)
+	}
+
+	response, err = s.HandleMessage(ctx, request(4, "tools/call", `{"name":"noop","arguments":{}}`))
+	if err != nil || response.Error != nil {
+		t.Errorf("tools/call after initialize = %+v, %v", response, err)
+	}
+}
+
+// TestProtocolVersionNegotiation tests that supported versions are echoed and
+// unsupported ones rejected without initializing the server
+func TestProtocolVersionNegotiation(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested string
+		expected  string // Negotiated version, empty when rejected
+	}{
+		{name: "default version", requested: "2024-11-05", expected: "2024-11-05"},
+		{name: "newer supported version", requested: "2025-03-26", expected: "2025-03-26"},
+		{name: "missing version", requested: "", expected: mcp.MCPVersion},
+		{name: "unsupported version", requested: "2023-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer("test", "0.0.0")
+			response, err := s.HandleMessage(context.Background(), request(1, "initialize", initializeParams(tt.requested)))
+			if err != nil {
+				t.Fatalf("HandleMessage failed: %v", err)
+			}
+
+			if tt.expected == "" {
+				if response.Error == nil || response.Error.Code != mcp.InvalidParams {
+					t.Fatalf("response = %+v, expected an invalid params error", response)
+				}
+				data, _ := response.Error.Data.(map[string]interface{})
+				if data["requested"] != tt.requested || data["supported"] == nil {
+					t.Errorf("error data = %v, expected the requested and supported versions", response.Error.Data)
+				}
+				if s.Session("") != nil {
+					t.Error("rejected initialize created a session")
+				}
+				return
+			}
+
+			if response.Error != nil {
+				t.Fatalf("initialize failed: %+v", response.Error)
+			}
+			var result mcp.InitializeResponse
+			if err := json.Unmarshal(response.Result, &result); err != nil {
+				t.Fatalf("invalid initialize result: %v", err)
+			}
+			if result.ProtocolVersion != tt.expected {
+				t.Errorf("negotiated %q, expected %q", result.ProtocolVersion, tt.expected)
+			}
+		})
+	}
+}
+
+// operationTool has a required enum argument and counts the calls that reach it
+type operationTool struct {
+	calls int
+}
+
+func (t *operationTool) Name() string        { return "files" }
+func (t *operationTool) Description() string { return "File operations" }
+func (t *operationTool) InputSchema() mcp.InputSchema {
+	return mcp.InputSchema{
+		Type: "object",
+		Properties: map[string]interface{}{
+			"operation": map[string]interface{}{"type": "string", "enum": []string{"read", "write", "list"}},
+			"limit":     map[string]interface{}{"type": "integer"},
+			"paths":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		Required: []string{"operation"},
+	}
+}
+func (t *operationTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	t.calls++
+	return &mcp.CallToolResponse{Content: []mcp.Content{{Type: "text", Text: "ok"}}}, nil
+}
+
+// TestToolArgumentValidation tests that arguments violating the input schema
+// are rejected with the problems listed, without reaching the tool
+func TestToolArgumentValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		problems  []string // Substrings expected in the error, none when valid
+	}{
+		{name: "valid", arguments: map[string]interface{}{"operation": "read", "limit": float64(3), "paths": []interface{}{"a.go"}}},
+		{name: "missing required field", arguments: map[string]interface{}{}, problems: []string{"operation is required"}},
+		{name: "nil arguments", arguments: nil, problems: []string{"operation is required"}},
+		{name: "out of enum operation", arguments: map[string]interface{}{"operation": "delete"}, problems: []string{"operation must be one of read, write, list, got delete"}},
+		{name: "wrong type", arguments: map[string]interface{}{"operation": 5}, problems: []string{"operation must be a string, got number"}},
+		{name: "fractional integer", arguments: map[string]interface{}{"operation": "list", "limit": 2.5}, problems: []string{"limit must be an integer"}},
+		{
+			name:      "several problems",
+			arguments: map[string]interface{}{"limit": "ten", "paths": []interface{}{"a.go", 7}},
+			problems:  []string{"operation is required", "limit must be an integer, got string", "paths[1] must be a string, got number"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &operationTool{}
+			s := NewServer("test", "0.0.0")
+			if err := s.RegisterTool(tool); err != nil {
+				t.Fatalf("RegisterTool failed: %v", err)
+			}
+
+			response, err := s.InvokeTool(context.Background(), "files", tt.arguments)
+			if err != nil {
+				t.Fatalf("InvokeTool failed: %v", err)
+			}
+
+			if len(tt.problems) == 0 {
+				if response.IsError || tool.calls != 1 {
+					t.Errorf("valid call = %+v with %d handler calls, expected success", response, tool.calls)
+				}
+				return
+			}
+			if !response.IsError || tool.calls != 0 {
+				t.Fatalf("invalid call = %+v with %d handler calls, expected a validation error", response, tool.calls)
+			}
+			text := response.Content[0].Text
+			if !strings.HasPrefix(text, "Invalid arguments for tool files: ") {
+				t.Errorf("error = %q, expected the standard prefix", text)
+			}
+			for _, problem := range tt.problems {
+				if !strings.Contains(text, problem) {
+					t.Errorf("error = %q, expected it to mention %q", text, problem)
+				}
+			}
+		})
+	}
+}
+
+// TestNotify tests that notifications are held back until a session is
+// initialized and then sent without an ID
+func TestNotify(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("test", "0.0.0")
+	var sent []*mcp.Message
+	s.SetNotificationSender(func(ctx context.Context, msg *mcp.Message) error {
+		sent = append(sent, msg)
+		return nil
+	})
+	s.EnableResourceNotifications()
+
+	if err := s.Notify(ctx, "notifications/resources/list_changed", nil); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("sent %d notifications before initialize", len(sent))
+	}
+
+	response, err := s.HandleMessage(ctx, request(1, "initialize", initializeParams(mcp.MCPVersion)))
+	if err != nil || response.Error != nil {
+		t.Fatalf("initialize failed: %+v, %v", response, err)
+	}
+	var result mcp.InitializeResponse
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		t.Fatalf("invalid initialize result: %v", err)
+	}
+	if result.Capabilities.Resources == nil || !result.Capabilities.Resources.ListChanged {
+		t.Errorf("capabilities = %+v, expected resource list changes", result.Capabilities)
+	}
+
+	if err := s.Notify(ctx, "notifications/resources/list_changed", nil); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if len(sent) != 1 || sent[0].Method != "notifications/resources/list_changed" || sent[0].ID != nil || sent[0].Params != nil {
+		t.Errorf("sent %+v, expected one notification without id or params", sent)
+	}
+}
+
+// execTool runs no command but asks its validator whether it may
+type execTool struct {
+	validator *security.SecurityValidator
+}
+
+func (execTool) Name() string        { return "exec" }
+func (execTool) Description() string { return "Checks a command" }
+func (execTool) InputSchema() mcp.InputSchema {
+	return mcp.InputSchema{
+		Type:       "object",
+		Properties: map[string]interface{}{"command": map[string]interface{}{"type": "string"}},
+		Required:   []string{"command"},
+	}
+}
+func (t execTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	if err := t.validator.ValidateCommandExecution(ctx, arguments["command"].(string), nil); err != nil {
+		return &mcp.CallToolResponse{Content: []mcp.Content{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	return &mcp.CallToolResponse{Content: []mcp.Content{{Type: "text", Text: "ok"}}}, nil
+}
+
+// TestDenialNotifier tests that a denied command reaches the client as a
+// security notification naming the rule, and allowed ones don't
+func TestDenialNotifier(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("test", "0.0.0")
+	var sent []*mcp.Message
+	s.SetNotificationSender(func(ctx context.Context, msg *mcp.Message) error {
+		sent = append(sent, msg)
+		return nil
+	})
+
+	validator := security.NewSecurityValidator(&security.SecurityPolicy{
+		AllowedPermissions: []security.Permission{security.PermissionExecCommand},
+		CommandWhitelist:   []string{"ls"},
+	}, "user", "session")
+	validator.SetAuditSink(s.DenialNotifier())
+	if err := s.RegisterTool(execTool{validator: validator}); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+	if response, err := s.HandleMessage(ctx, request(1, "initialize", initializeParams(mcp.MCPVersion))); err != nil || response.Error != nil {
+		t.Fatalf("initialize failed: %+v, %v", response, err)
+	}
+
+	for i, command := range []string{"ls", "curl"} {
+		if _, err := s.HandleMessage(ctx, request(i+2, "tools/call", fmt.Sprintf(`{"name":"exec","arguments":{"command":%q}}`, command))); err != nil {
+			t.Fatalf("tools/call %s failed: %v", command, err)
+		}
+	}
+
+	if len(sent) != 1 || sent[0].Method != SecurityDeniedNotification {
+		t.Fatalf("sent %+v, expected one security notification", sent)
+	}
+	var event security.AuditEvent
+	if err := json.Unmarshal(sent[0].Params, &event); err != nil {
+		t.Fatalf("invalid notification params: %v", err)
+	}
+	if event.Rule != security.RuleCommandWhitelist || event.Resource != "curl" || event.Operation != "exec" ||
+		event.Reason != "command not in whitelist" || event.SessionID != "session" || event.Time.IsZero() {
+		t.Errorf("notification params = %+v, expected the whitelist denial of curl", event)
+	}
+}
+
+// progressTool reports one step of progress
+type progressTool struct{}
+
+func (progressTool) Name() string                 { return "progress" }
+func (progressTool) Description() string          { return "Reports progress" }
+func (progressTool) InputSchema() mcp.InputSchema { return mcp.InputSchema{Type: "object"} }
+func (progressTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	if err := mcp.ReportProgress(ctx, 1, 1, "halfway"); err != nil {
+		return nil, err
+	}
+	return &mcp.CallToolResponse{Content: []mcp.Content{{Type: "text", Text: "ok"}}}, nil
+}
+
+// TestProgressThroughNotificationSender tests that progress reaches clients
+// of transports without per-request streams, and only when requested
+func TestProgressThroughNotificationSender(t *testing.T) {
+	ctx := context.Background()
+	s := NewServer("test", "0.0.0")
+	if err := s.RegisterTool(progressTool{}); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+	var sent []*mcp.Message
+	s.SetNotificationSender(func(ctx context.Context, msg *mcp.Message) error {
+		sent = append(sent, msg)
+		return nil
+	})
+	if response, err := s.HandleMessage(ctx, request(1, "initialize", initializeParams(mcp.MCPVersion))); err != nil || response.Error != nil {
+		t.Fatalf("initialize failed: %+v, %v", response, err)
+	}
+
+	tests := []struct {
+		name   string
+		params string
+		sent   int
+	}{
+		{name: "no token", params: `{"name":"progress"}`, sent: 0},
+		{name: "token", params: `{"name":"progress","_meta":{"progressToken":5}}`, sent: 1},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent = nil
+			response, err := s.HandleMessage(ctx, request(i+2, "tools/call", tt.params))
+			if err != nil || response.Error != nil {
+				t.Fatalf("tools/call failed: %+v, %v", response, err)
+			}
+			if len(sent) != tt.sent {
+				t.Fatalf("sent %d notifications, expected %d", len(sent), tt.sent)
+			}
+			if tt.sent > 0 && (sent[0].Method != mcp.ProgressNotification || !strings.Contains(string(sent[0].Params), `"progressToken":5`)) {
+				t.Errorf("sent %s %s", sent[0].Method, sent[0].Params)
+			}
+		})
+	}
+}
+
+// renamedTool is a noopTool registered under another name
+type renamedTool struct {
+	noopTool
+	name string
+}
+
+func (t renamedTool) Name() string { return t.name }
+
+// TestRegisterToolDuplicate tests that a second tool with a taken name is
+// rejected with ErrDuplicateTool and leaves the first in place
+func TestRegisterToolDuplicate(t *testing.T) {
+	s := NewServer("test", "0.0.0")
+	if err := s.RegisterTool(noopTool{}); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+	if err := s.RegisterTool(renamedTool{name: "other"}); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+
+	err := s.RegisterTool(renamedTool{name: "noop"})
+	if !errors.Is(err, ErrDuplicateTool) {
+		t.Fatalf("RegisterTool = %v, expected ErrDuplicateTool", err)
+	}
+	if !strings.Contains(err.Error(), "noop") {
+		t.Errorf("error %q doesn't name the tool", err)
+	}
+
+	registered := s.ListRegisteredTools()
+	if len(registered) != 2 || registered[0].Name != "noop" || registered[1].Name != "other" {
+		t.Fatalf("registered = %+v, expected noop and other", registered)
+	}
+	if registered[0].Description != "Does nothing" || registered[0].InputSchema.Type != "object" {
+		t.Errorf("noop registered as %+v, expected the first tool's description and schema", registered[0])
+	}
+}
+
+// TestDisableTool tests that disabled tools leave tools/list and reject
+// calls, globally or for a single session
+func TestDisableTool(t *testing.T) {
+	s := NewServer("test", "0.0.0")
+	for _, tool := range []mcp.MCPToolHandler{noopTool{}, renamedTool{name: "other"}} {
+		if err := s.RegisterTool(tool); err != nil {
+			t.Fatalf("RegisterTool failed: %v", err)
+		}
+	}
+	alice := mcp.WithSessionID(context.Background(), "alice")
+	bob := mcp.WithSessionID(context.Background(), "bob")
+	for _, ctx := range []context.Context{alice, bob} {
+		if response, err := s.HandleMessage(ctx, request(1, "initialize", initializeParams(mcp.MCPVersion))); err != nil || response.Error != nil {
+			t.Fatalf("initialize failed: %+v, %v", response, err)
+		}
+	}
+
+	listed := func(ctx context.Context) []string {
+		t.Helper()
+		resp, err := s.ListTools(ctx, &mcp.ListToolsRequest{})
+		if err != nil {
+			t.Fatalf("ListTools failed: %v", err)
+		}
+		var names []string
+		for _, tool := range resp.Tools {
+			names = append(names, tool.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	disabled := func(ctx context.Context) bool {
+		t.Helper()
+		resp, err := s.CallTool(ctx, &mcp.CallToolRequest{Name: "noop", Arguments: map[string]interface{}{}})
+		if err != nil {
+			t.Fatalf("CallTool failed: %v", err)
+		}
+		return resp.IsError && resp.Error != nil && resp.Error.Code == mcp.ErrorCodeToolDisabled
+	}
+
+	if err := s.DisableTool("missing"); err == nil {
+		t.Error("DisableTool accepted an unregistered tool")
+	}
+	if err := s.DisableSessionTool("carol", "noop"); err == nil {
+		t.Error("DisableSessionTool accepted an unknown session")
+	}
+
+	if err := s.DisableSessionTool("alice", "noop"); err != nil {
+		t.Fatalf("DisableSessionTool failed: %v", err)
+	}
+	if names := listed(alice); strings.Join(names, ",") != "other" {
+		t.Errorf("alice lists %v, expected only other", names)
+	}
+	if names := listed(bob); strings.Join(names, ",") != "noop,other" {
+		t.Errorf("bob lists %v, expected both tools", names)
+	}
+	if !disabled(alice) || disabled(bob) {
+		t.Error("a session-disabled tool should only be rejected for that session")
+	}
+
+	if err := s.DisableTool("noop"); err != nil {
+		t.Fatalf("DisableTool failed: %v", err)
+	}
+	if names := listed(bob); strings.Join(names, ",") != "other" {
+		t.Errorf("bob lists %v, expected only other", names)
+	}
+	if !disabled(bob) {
+		t.Error("a globally disabled tool should be rejected for every session")
+	}
+	if resp, err := s.InvokeTool(context.Background(), "noop", nil); err != nil || resp.Error == nil || resp.Error.Code != mcp.ErrorCodeToolDisabled {
+		t.Errorf("InvokeTool = %+v, %v, expected a tool_disabled error", resp, err)
+	}
+	if tools := s.ListRegisteredTools(); len(tools) != 1 || tools[0].Name != "other" {
+		t.Errorf("ListRegisteredTools = %+v, expected only other", tools)
+	}
+
+	if err := s.EnableTool("noop"); err != nil {
+		t.Fatalf("EnableTool failed: %v", err)
+	}
+	if disabled(bob) || !disabled(alice) {
+		t.Error("enabling globally should restore the tool except where a session disabled it")
+	}
+
+	// Re-initializing keeps the session's choices
+	if response, err := s.HandleMessage(alice, request(2, "initialize", initializeParams(mcp.MCPVersion))); err != nil || response.Error != nil {
+		t.Fatalf("initialize failed: %+v, %v", response, err)
+	}
+	if !disabled(alice) {
+		t.Error("re-initializing re-enabled a session-disabled tool")
+	}
+	if err := s.EnableSessionTool("alice", "noop"); err != nil {
+		t.Fatalf("EnableSessionTool failed: %v", err)
+	}
+	if disabled(alice) {
+		t.Error("EnableSessionTool didn't restore the tool")
+	}
+}
+
+// blockingTool waits until its context ends, advertising timeout
+type blockingTool struct {
+	noopTool
+	name    string
+	timeout time.Duration
+}
+
+func (t blockingTool) Name() string           { return t.name }
+func (t blockingTool) Timeout() time.Duration { return t.timeout }
+func (t blockingTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// TestToolTimeouts tests that each tool's advertised timeout applies under
+// the same server config, and that a request's timeout overrides it within
+// the server's maximum
+func TestToolTimeouts(t *testing.T) {
+	ctx := mcp.WithSessionID(context.Background(), "timeouts")
+	s := NewServer("test", "0.0.0")
+	s.SetToolTimeouts(time.Minute, 300*time.Millisecond)
+	for _, tool := range []mcp.MCPToolHandler{
+		blockingTool{name: "fast", timeout: 20 * time.Millisecond},
+		blockingTool{name: "slow", timeout: 150 * time.Millisecond},
+	} {
+		if err := s.RegisterTool(tool); err != nil {
+			t.Fatalf("RegisterTool failed: %v", err)
+		}
+	}
+	if response, err := s.HandleMessage(ctx, request(1, "initialize", initializeParams(mcp.MCPVersion))); err != nil || response.Error != nil {
+		t.Fatalf("initialize failed: %+v, %v", response, err)
+	}
+
+	call := func(params string) (*mcp.CallToolResponse, time.Duration) {
+		t.Helper()
+		start := time.Now()
+		response, err := s.HandleMessage(ctx, request(2, "tools/call", params))
+		elapsed := time.Since(start)
+		if err != nil || response.Error != nil {
+			t.Fatalf("tools/call failed: %+v, %v", response, err)
+		}
+		var result mcp.CallToolResponse
+		if err := json.Unmarshal(response.Result, &result); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		if !result.IsError || result.Error == nil || result.Error.Code != mcp.ErrorCodeTimeout {
+			t.Fatalf("result = %+v, expected a timeout error", result)
+		}
+		return &result, elapsed
+	}
+
+	_, fast := call(`{"name":"fast"}`)
+	_, slow := call(`{"name":"slow"}`)
+	if fast >= slow {
+		t.Errorf("fast tool took %v, slow tool %v; expected the fast one to time out first", fast, slow)
+	}
+	if slow < 150*time.Millisecond {
+		t.Errorf("slow tool timed out after %v, before its 150ms timeout", slow)
+	}
+
+	// The request lengthens the fast tool's timeout, but not past the maximum
+	result, elapsed := call(`{"name":"fast","_meta":{"timeoutMs":10000}}`)
+	if elapsed < 300*time.Millisecond || elapsed > 5*time.Second {
+		t.Errorf("request timeout ran %v, expected it capped at the 300ms maximum", elapsed)
+	}
+	if !strings.Contains(result.Content[0].Text, "300ms") {
+		t.Errorf("message = %q, expected it to name the capped timeout", result.Content[0].Text)
+	}
+}
+
+// memoryFilesTool reads and writes an in-memory file system, declaring reads
+// cacheable, and counts the calls that reach it
+type memoryFilesTool struct {
+	files map[string]string
+	calls int
+}
+
+func (t *memoryFilesTool) Name() string                 { return "files" }
+func (t *memoryFilesTool) Description() string          { return "In-memory files" }
+func (t *memoryFilesTool) InputSchema() mcp.InputSchema { return mcp.InputSchema{Type: "object"} }
+func (t *memoryFilesTool) Cacheable(arguments map[string]interface{}) bool {
+	return arguments["operation"] == "read"
+}
+func (t *memoryFilesTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	t.calls++
+	path, _ := arguments["path"].(string)
+	if arguments["operation"] == "write" {
+		t.files[path], _ = arguments["content"].(string)
+	}
+	return &mcp.CallToolResponse{Content: []mcp.Content{{Type: "text", Text: t.files[path]}}}, nil
+}
+
+// TestToolCache tests that repeated cacheable calls are answered from the
+// cache until a write, an invalidation or expiry
+func TestToolCache(t *testing.T) {
+	tool := &memoryFilesTool{files: map[string]string{"a.go": "v1", "b.go": "b"}}
+	s := NewServer("test", "0.0.0")
+	if err := s.RegisterTool(tool); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+	s.SetToolCacheTTL(time.Minute)
+
+	call := func(arguments map[string]interface{}, want string, wantCalls int) {
+		t.Helper()
+		response, err := s.InvokeTool(context.Background(), "files", arguments)
+		if err != nil {
+			t.Fatalf("InvokeTool failed: %v", err)
+		}
+		if got := response.Content[0].Text; got != want || tool.calls != wantCalls {
+			t.Fatalf("%v = %q after %d handler calls, expected %q after %d", arguments, got, tool.calls, want, wantCalls)
+		}
+	}
+	readA := map[string]interface{}{"operation": "read", "path": "a.go"}
+	readB := map[string]interface{}{"operation": "read", "path": "b.go"}
+
+	call(readA, "v1", 1)
+	call(readA, "v1", 1)
+	call(readB, "b", 2)
+
+	call(map[string]interface{}{"operation": "write", "path": "a.go", "content": "v2"}, "v2", 3)
+	call(readA, "v2", 4)
+	call(readA, "v2", 4)
+
+	tool.files["a.go"] = "v3"
+	s.InvalidateToolCache()
+	call(readA, "v3", 5)
+
+	s.SetToolCacheTTL(time.Nanosecond)
+	call(readA, "v3", 6)
+	time.Sleep(time.Millisecond)
+	call(readA, "v3", 7)
+}
+
+// authorizingFilesTool is a memoryFilesTool that checks calls answered from
+// the cache, denying reads of the paths in denied
+type authorizingFilesTool struct {
+	memoryFilesTool
+	denied     map[string]bool
+	authorized int
+}
+
+func (t *authorizingFilesTool) AuthorizeCachedCall(ctx context.Context, arguments map[string]interface{}) *mcp.CallToolResponse {
+	t.authorized++
+	if path, _ := arguments["path"].(string); t.denied[path] {
+		return mcp.NewToolErrorResponse(mcp.ErrorCodeRateLimited, "rate limited", nil)
+	}
+	return nil
+}
+
+// TestToolCacheAuthorizesHits tests that a call answered from the cache is
+// still checked by a tool that authorizes cached calls, and refused when the
+// check fails
+func TestToolCacheAuthorizesHits(t *testing.T) {
+	tool := &authorizingFilesTool{memoryFilesTool: memoryFilesTool{files: map[string]string{"a.go": "v1"}}, denied: map[string]bool{}}
+	s := NewServer("test", "0.0.0")
+	if err := s.RegisterTool(tool); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+	s.SetToolCacheTTL(time.Minute)
+
+	readA := map[string]interface{}{"operation": "read", "path": "a.go"}
+	for i := 0; i < 2; i++ {
+		response, err := s.InvokeTool(context.Background(), "files", readA)
+		if err != nil || response.IsError || response.Content[0].Text != "v1" {
+			t.Fatalf("read %d = %+v, %v, expected v1", i, response, err)
+		}
+	}
+	if tool.calls != 1 || tool.authorized != 1 {
+		t.Errorf("%d handler calls and %d authorized hits, expected 1 of each", tool.calls, tool.authorized)
+	}
+
+	tool.denied["a.go"] = true
+	response, err := s.InvokeTool(context.Background(), "files", readA)
+	if err != nil {
+		t.Fatalf("InvokeTool failed: %v", err)
+	}
+	if !response.IsError || tool.calls != 1 {
+		t.Errorf("response = %+v after %d handler calls, expected the denial without running the tool", response, tool.calls)
+	}
+}
+
+// workspaceTool reports the base directory of the workspace it runs in
+type workspaceTool struct{}
+
+func (workspaceTool) Name() string                 { return "workspace" }
+func (workspaceTool) Description() string          { return "Reports its workspace" }
+func (workspaceTool) InputSchema() mcp.InputSchema { return mcp.InputSchema{Type: "object"} }
+func (workspaceTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	text := "none"
+	if workspace := security.WorkspaceFromContext(ctx); workspace != nil {
+		text = workspace.BaseDir
+	}
+	return &mcp.CallToolResponse{Content: []mcp.Content{{Type: "text", Text: text}}}, nil
+}
+
+// TestInvokeToolSessions tests that tools invoked outside the handshake run
+// in the session ctx names, and are refused without one when sessions have
+// workspaces of their own
+func TestInvokeToolSessions(t *testing.T) {
+	s := NewServer("test", "0.0.0")
+	if err := s.RegisterTool(workspaceTool{}); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+
+	// Without per-session workspaces there's no isolation to escape
+	response, err := s.InvokeTool(context.Background(), "workspace", nil)
+	if err != nil || response.IsError || response.Content[0].Text != "none" {
+		t.Fatalf("InvokeTool without workspaces = %+v, %v, expected a call outside any workspace", response, err)
+	}
+
+	s.SetWorkspaceFactory(func(sessionID string) (*security.Workspace, error) {
+		return security.NewWorkspace(&security.SecurityPolicy{}, "test", sessionID, "/work/"+sessionID), nil
+	})
+	response, err = s.InvokeTool(context.Background(), "workspace", nil)
+	if err != nil || response.Error == nil || response.Error.Code != mcp.ErrorCodeUnavailable {
+		t.Fatalf("InvokeTool without a session = %+v, %v, expected an unavailable error", response, err)
+	}
+	unknown := mcp.WithSessionID(context.Background(), "unknown")
+	if response, err = s.InvokeTool(unknown, "workspace", nil); err != nil || response.Error == nil {
+		t.Fatalf("InvokeTool for an uninitialized session = %+v, %v, expected an error", response, err)
+	}
+
+	ctx := mcp.WithSessionID(context.Background(), "s1")
+	if init, err := s.HandleMessage(ctx, request(1, "initialize", initializeParams(mcp.MCPVersion))); err != nil || init.Error != nil {
+		t.Fatalf("initialize failed: %+v, %v", init, err)
+	}
+	response, err = s.InvokeTool(ctx, "workspace", nil)
+	if err != nil || response.IsError || response.Content[0].Text != "/work/s1" {
+		t.Errorf("InvokeTool in session s1 = %+v, %v, expected it to run in /work/s1", response, err)
+	}
+}
+
+// TestSessionLimits tests that initializing past the session limit is refused
+// until a session has been idle past the timeout, which closes it, and that
+// requests keep a session from going idle
+func TestSessionLimits(t *testing.T) {
+	s := NewServer("test", "0.0.0")
+	now := time.Date(2025, 6, 22, 8, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	closed := make(map[string]bool)
+	s.SetWorkspaceFactory(func(sessionID string) (*security.Workspace, error) {
+		workspace := security.NewWorkspace(&security.SecurityPolicy{}, "test", sessionID, "/work/"+sessionID)
+		workspace.Cleanup = func() error {
+			closed[sessionID] = true
+			return nil
+		}
+		return workspace, nil
+	})
+	s.SetSessionLimits(time.Minute, 2)
+
+	initialize := func(id string) *mcp.Message {
+		t.Helper()
+		response, err := s.HandleMessage(mcp.WithSessionID(context.Background(), id), request(1, "initialize", initializeParams(mcp.MCPVersion)))
+		if err != nil {
+			t.Fatalf("HandleMessage failed: %v", err)
+		}
+		return response
+	}
+	initialize("s1")
+	initialize("s2")
+	if response := initialize("s3"); response.Error == nil {
+		t.Fatal("expected a third session refused while two are active")
+	}
+
+	// s2 stays busy while s1 goes idle
+	now = now.Add(45 * time.Second)
+	if response, _ := s.HandleMessage(mcp.WithSessionID(context.Background(), "s2"), request(2, "tools/list", "")); response.Error != nil {
+		t.Fatalf("tools/list failed: %+v", response.Error)
+	}
+	now = now.Add(30 * time.Second)
+	if response := initialize("s3"); response.Error != nil {
+		t.Fatalf("initialize after s1 went idle failed: %+v", response.Error)
+	}
+	if s.Session("s1") != nil || !closed["s1"] {
+		t.Error("expected the idle session closed with its workspace")
+	}
+	if s.Session("s2") == nil || closed["s2"] {
+		t.Error("expected the busy session kept")
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrTooManySessions rejects an initialize request when the server already
+// holds its maximum number of sessions and none has been idle long enough to
+// be closed
+var ErrTooManySessions = errors.New("too many sessions")
+
+// SetSessionLimits closes sessions idle for longer than idleTimeout and caps
+// how many may be open at once, so clients that never end their sessions
+// can't pile up workspaces. Zero leaves either unlimited.
+func (s *Server) SetSessionLimits(idleTimeout time.Duration, maxSessions int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessionIdle = idleTimeout
+	s.maxSessions = maxSessions
+}
+
+// RunSessionReaper closes idle sessions as they pass the idle timeout until
+// ctx is done
+func (s *Server) RunSessionReaper(ctx context.Context) {
+	s.mutex.RLock()
+	idle := s.sessionIdle
+	s.mutex.RUnlock()
+	if idle <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(max(idle/2, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mutex.Lock()
+			expired := s.expireSessions()
+			s.mutex.Unlock()
+			closeSessions(expired)
+		}
+	}
+}
+
+// touch records that the session was just used
+func (session *Session) touch(now time.Time) {
+	session.lastActive.Store(now.UnixNano())
+}
+
+// expireSessions removes the sessions idle for longer than the idle timeout
+// and returns them for their workspaces to be closed. Callers hold the mutex.
+func (s *Server) expireSessions() []*Session {
+	if s.sessionIdle <= 0 {
+		return nil
+	}
+	cutoff := s.now().Add(-s.sessionIdle).UnixNano()
+	var expired []*Session
+	for id, session := range s.sessions {
+		if session.lastActive.Load() < cutoff {
+			delete(s.sessions, id)
+			expired = append(expired, session)
+		}
+	}
+	return expired
+}
+
+// closeSessions closes the workspaces of sessions already removed, reporting
+// failures since nobody is waiting on them
+func closeSessions(sessions []*Session) {
+	for _, session := range sessions {
+		if err := session.Workspace.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to close workspace for session %q: %v\n", session.ID, err)
+		}
+	}
+}
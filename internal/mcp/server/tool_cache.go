@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+)
+
+// toolCache holds the responses of cacheable tool calls by scope, tool name
+// and arguments until they expire
+type toolCache struct {
+	ttl        time.Duration
+	entries    map[string]toolCacheEntry
+	generation int64 // Counts clears, so a call that overlapped one isn't cached
+	mutex      sync.Mutex
+}
+
+// toolCacheEntry is a cached tool response
+type toolCacheEntry struct {
+	response *mcp.CallToolResponse
+	expires  time.Time
+}
+
+// SetToolCacheTTL answers repeated calls that a tool declares cacheable, like
+// reading or listing files, from a cache for up to ttl. Any other call, which
+// may change files, empties the cache, as does InvalidateToolCache. Changes
+// made outside the server are only seen once an entry expires, unless
+// something like a file watcher invalidates the cache. Cache hits skip the
+// tool, so only tools implementing mcp.CachedCallAuthorizer have them
+// audited and rate limited. Zero disables the cache.
+func (s *Server) SetToolCacheTTL(ttl time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if ttl <= 0 {
+		s.cache = nil
+		return
+	}
+	s.cache = &toolCache{ttl: ttl, entries: make(map[string]toolCacheEntry)}
+}
+
+// InvalidateToolCache drops every cached tool response, e.g. when a file
+// watcher sees the workspace change
+func (s *Server) InvalidateToolCache() {
+	s.mutex.RLock()
+	cache := s.cache
+	s.mutex.RUnlock()
+	cache.clear()
+}
+
+// runCached runs a tool call through the cache when it has one. scope
+// separates callers that see different files, such as sessions with their own
+// workspaces.
+func (s *Server) runCached(ctx context.Context, scope, name string, handler mcp.MCPToolHandler, arguments map[string]interface{}, requested time.Duration) (*mcp.CallToolResponse, error) {
+	s.mutex.RLock()
+	cache := s.cache
+	s.mutex.RUnlock()
+	if cache == nil {
+		return s.runTool(ctx, name, handler, arguments, requested)
+	}
+
+	cacheable, ok := handler.(mcp.CacheableToolHandler)
+	if !ok || !cacheable.Cacheable(arguments) {
+		defer cache.clear()
+		return s.runTool(ctx, name, handler, arguments, requested)
+	}
+
+	key, err := json.Marshal(struct {
+		Scope     string                 `json:"scope"`
+		Tool      string                 `json:"tool"`
+		Arguments map[string]interface{} `json:"arguments"`
+	}{scope, name, arguments})
+	if err != nil {
+		return s.runTool(ctx, name, handler, arguments, requested)
+	}
+	resp, generation, ok := cache.get(string(key))
+	if ok {
+		if authorizer, isAuthorizer := handler.(mcp.CachedCallAuthorizer); isAuthorizer {
+			if denied := authorizer.AuthorizeCachedCall(ctx, arguments); denied != nil {
+				return denied, nil
+			}
+		}
+		return resp, nil
+	}
+
+	resp, err = s.runTool(ctx, name, handler, arguments, requested)
+	if err == nil && resp != nil && !resp.IsError {
+		cache.put(string(key), resp, generation)
+	}
+	return resp, err
+}
+
+// get returns a copy of the unexpired response cached under key, if any,
+// and the cache's generation to put a fresh response with
+func (c *toolCache) get(key string) (*mcp.CallToolResponse, int64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, exists := c.entries[key]
+	if !exists {
+		return nil, c.generation, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, c.generation, false
+	}
+	return copyResponse(entry.response), c.generation, true
+}
+
+// put caches a copy of resp under key, unless the cache was cleared since
+// generation, when resp may predate the change that cleared it
+func (c *toolCache) put(key string, resp *mcp.CallToolResponse, generation int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if generation != c.generation {
+		return
+	}
+	c.entries[key] = toolCacheEntry{response: copyResponse(resp), expires: time.Now().Add(c.ttl)}
+}
+
+// clear drops every entry; a nil cache has none
+func (c *toolCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[string]toolCacheEntry)
+	c.generation++
+}
+
+// copyResponse copies a response deeply enough that its holder's changes to
+// the content list don't reach the cache
+func copyResponse(resp *mcp.CallToolResponse) *mcp.CallToolResponse {
+	copied := *resp
+	copied.Content = append([]mcp.Content(nil), resp.Content...)
+	return &copied
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+)
+
+// validateArguments checks tool call arguments against the tool's input
+// schema: required properties, JSON types and enum membership, recursing into
+// array items and nested objects. It returns every problem found, sorted.
+// Properties the schema doesn't declare are left to the tool.
+func validateArguments(schema mcp.InputSchema, arguments map[string]interface{}) []string {
+	root := map[string]interface{}{
+		"type":       schema.Type,
+		"properties": schema.Properties,
+		"required":   schema.Required,
+	}
+
+	var problems []string
+	validateValue("", root, arguments, &problems)
+	sort.Strings(problems)
+	return problems
+}
+
+// validateValue checks value against a JSON schema fragment, appending
+// problems prefixed with the value's path
+func validateValue(path string, schema map[string]interface{}, value interface{}, problems *[]string) {
+	name := path
+	if name == "" {
+		name = "arguments"
+	}
+
+	if expected, ok := schema["type"].(string); ok && expected != "" && !matchesType(expected, value) {
+		*problems = append(*problems, fmt.Sprintf("%s must be %s, got %s", name, withArticle(expected), jsonType(value)))
+		return
+	}
+
+	if enum := toSlice(schema["enum"]); enum != nil && !containsValue(enum, value) {
+		allowed := make([]string, len(enum))
+		for i, option := range enum {
+			allowed[i] = fmt.Sprint(option)
+		}
+		*problems = append(*problems, fmt.Sprintf("%s must be one of %s, got %v", name, strings.Join(allowed, ", "), value))
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for _, required := range toSlice(schema["required"]) {
+			if _, exists := typed[fmt.Sprint(required)]; !exists {
+				*problems = append(*problems, fmt.Sprintf("%s is required", joinPath(path, fmt.Sprint(required))))
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for key, property := range typed {
+			if propertySchema, ok := properties[key].(map[string]interface{}); ok {
+				validateValue(joinPath(path, key), propertySchema, property, problems)
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range typed {
+				validateValue(fmt.Sprintf("%s[%d]", name, i), items, item, problems)
+			}
+		}
+	}
+}
+
+// matchesType reports whether value has the given JSON schema type. Go
+// numeric types count as numbers so tools can be called directly too.
+func matchesType(expected string, value interface{}) bool {
+	switch expected {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		number, ok := toFloat(value)
+		return ok && number == math.Trunc(number)
+	case "array":
+		return value != nil && reflect.TypeOf(value).Kind() == reflect.Slice
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "null":
+		return value == nil
+	}
+	// Types the validator doesn't know are the tool's to check
+	return true
+}
+
+// jsonType names the JSON type of a decoded value for error messages
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	}
+	if _, ok := toFloat(value); ok {
+		return "number"
+	}
+	if reflect.TypeOf(value).Kind() == reflect.Slice {
+		return "array"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// toFloat converts JSON and Go numeric values to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case float64:
+		return number, true
+	case float32:
+		return float64(number), true
+	case int:
+		return float64(number), true
+	case int32:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	}
+	return 0, false
+}
+
+// toSlice converts a schema list such as []string or []interface{} to
+// []interface{}, returning nil for anything else
+func toSlice(value interface{}) []interface{} {
+	if value == nil {
+		return nil
+	}
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice {
+		return nil
+	}
+	result := make([]interface{}, list.Len())
+	for i := range result {
+		result[i] = list.Index(i).Interface()
+	}
+	return result
+}
+
+// containsValue reports whether options holds value, comparing numbers by
+// value regardless of their Go type
+func containsValue(options []interface{}, value interface{}) bool {
+	number, isNumber := toFloat(value)
+	for _, option := range options {
+		if optionNumber, ok := toFloat(option); ok && isNumber {
+			if optionNumber == number {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(option, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPath appends a property name to a dotted path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// withArticle prefixes a type name with "a" or "an"
+func withArticle(name string) string {
+	if strings.ContainsRune("aeiou", rune(name[0])) {
+		return "an " + name
+	}
+	return "a " + name
+}
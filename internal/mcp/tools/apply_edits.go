@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
+)
+
+// ApplyEditsTool writes a set of whole-file contents or patches as one
+// transaction: every edit is validated before anything is written, and files
+// already written are restored if a later write fails
+type ApplyEditsTool struct {
+	refactor *RefactorTool
+}
+
+// NewApplyEditsTool creates an apply edits tool rooted at baseDir
+func NewApplyEditsTool(baseDir string, validator *security.SecurityValidator) *ApplyEditsTool {
+	return &ApplyEditsTool{refactor: NewRefactorTool(baseDir, validator)}
+}
+
+// SetPathRedaction enables or disables rewriting absolute workspace paths in results
+func (a *ApplyEditsTool) SetPathRedaction(enabled bool) {
+	a.refactor.SetPathRedaction(enabled)
+}
+
+// SetSymlinkPolicy sets which links within the workspace writes may follow
+func (a *ApplyEditsTool) SetSymlinkPolicy(policy security.SymlinkPolicy) {
+	a.refactor.SetSymlinkPolicy(policy)
+}
+
+// Name returns the tool name
+func (a *ApplyEditsTool) Name() string {
+	return "apply_edits"
+}
+
+// Description returns the tool description
+func (a *ApplyEditsTool) Description() string {
+	return "Writes new content or patches to multiple files all-or-nothing: every edit is checked before any file is written, and written files are restored if a write fails"
+}
+
+// InputSchema returns the JSON schema for tool inputs
+func (a *ApplyEditsTool) InputSchema() mcp.InputSchema {
+	return mcp.InputSchema{
+		Type: "object",
+		Properties: map[string]interface{}{
+			"edits": map[string]interface{}{
+				"type":        "array",
+				"description": "Edits applied in order; each gives either content or old_text and new_text",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "File path relative to the workspace",
+						},
+						"content": map[string]interface{}{
+							"type":        "string",
+							"description": "New content of the whole file, created if missing",
+						},
+						"old_text": map[string]interface{}{
+							"type":        "string",
+							"description": "Text to replace, which must occur exactly once",
+						},
+						"new_text": map[string]interface{}{
+							"type":        "string",
+							"description": "Replacement for old_text",
+						},
+					},
+					"required": []string{"path"},
+				},
+			},
+		},
+		Required: []string{"edits"},
+	}
+}
+
+// Handle applies the edits
+func (a *ApplyEditsTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	tool := a.refactor.inWorkspace(ctx)
+	response, err := tool.applyEdits(ctx, arguments)
+	return redactResponse(tool.redactor, response), err
+}
+
+// applyEdits plans every edit, then writes the files that change
+func (r *RefactorTool) applyEdits(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	patches, err := parseEdits(arguments["edits"])
+	if err != nil {
+		return refactorResponse(fmt.Sprintf("Error: %v", err), true), nil
+	}
+
+	planned, err := r.planChanges(ctx, patches)
+	if err != nil {
+		return refactorResponse(fmt.Sprintf("Edits not applied: %v", err), true), nil
+	}
+
+	var changes []*fileChange
+	var unchanged []string
+	for _, change := range planned {
+		if change.existed && bytes.Equal(change.original, change.updated) {
+			unchanged = append(unchanged, change.display)
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	for i, change := range changes {
+		if err := r.applyChange(change); err != nil {
+			text := fmt.Sprintf("Edits rolled back: failed to write %s: %v\n", change.display, err)
+			if rollbackErr := r.rollback(changes[:i+1]); rollbackErr != nil {
+				text += fmt.Sprintf("Rollback incomplete: %v", rollbackErr)
+			} else {
+				text += fmt.Sprintf("Restored %d files: %s", i+1, strings.Join(changePaths(changes[:i+1]), ", "))
+			}
+			return refactorResponse(text, true), nil
+		}
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Changed %d files:\n", len(changes)))
+	for _, path := range changePaths(changes) {
+		result.WriteString(fmt.Sprintf("- %s\n", path))
+	}
+	if len(unchanged) > 0 {
+		result.WriteString(fmt.Sprintf("Unchanged: %s\n", strings.Join(unchanged, ", ")))
+	}
+	return refactorResponse(result.String(), false), nil
+}
+
+// parseEdits converts the edits argument into patches, a whole-file edit
+// becoming a patch with no old_text
+func parseEdits(value interface{}) ([]refactorPatch, error) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, errors.New("edits parameter is required and must be a non-empty array")
+	}
+
+	patches := make([]refactorPatch, 0, len(items))
+	for i, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("edit %d must be an object", i)
+		}
+		path, ok := fields["path"].(string)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("edit %d is missing path", i)
+		}
+
+		content, hasContent := fields["content"].(string)
+		oldText, hasOldText := fields["old_text"].(string)
+		newText, hasNewText := fields["new_text"].(string)
+		switch {
+		case hasContent && (hasOldText || hasNewText):
+			return nil, fmt.Errorf("edit %d sets both content and old_text/new_text", i)
+		case hasContent:
+			patches = append(patches, refactorPatch{Path: path, NewText: content})
+		case hasOldText && oldText != "" && hasNewText:
+			patches = append(patches, refactorPatch{Path: path, OldText: oldText, NewText: newText})
+		default:
+			return nil, fmt.Errorf("edit %d needs content, or old_text and new_text", i)
+		}
+	}
+	return patches, nil
+}
+
+// changePaths returns the paths of changes as the caller gave them
+func changePaths(changes []*fileChange) []string {
+	paths := make([]string, 0, len(changes))
+	for _, change := range changes {
+		paths = append(paths, change.display)
+	}
+	return paths
+}
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestApplyEditsRollsBackOnWriteFailure tests that when the second write
+// fails, the first file is restored and the response reports the rollback
+func TestApplyEditsRollsBackOnWriteFailure(t *testing.T) {
+	workspace := t.TempDir()
+	original := map[string]string{
+		"a.go": "package app\n\nfunc OldName() {}\n",
+		"b.go": "package app\n\nvar _ = OldName\n",
+	}
+	for name, content := range original {
+		if err := os.WriteFile(filepath.Join(workspace, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	tool := NewApplyEditsTool(workspace, nil)
+	writes := 0
+	tool.refactor.writeFile = func(name string, data []byte, perm os.FileMode) error {
+		writes++
+		if writes == 2 {
+			return errors.New("disk full")
+		}
+		return os.WriteFile(name, data, perm)
+	}
+
+	response, err := tool.Handle(context.Background(), map[string]interface{}{"edits": []interface{}{
+		map[string]interface{}{"path": "a.go", "content": "package app\n\nfunc NewName() {}\n"},
+		map[string]interface{}{"path": "b.go", "old_text": "= OldName", "new_text": "= NewName"},
+	}})
+	if err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	text := response.Content[0].Text
+	if !response.IsError || !strings.Contains(text, "failed to write b.go") || !strings.Contains(text, "Restored 2 files: a.go, b.go") {
+		t.Errorf("response = %q, expected a write failure with both files restored", text)
+	}
+
+	for name, content := range original {
+		data, err := os.ReadFile(filepath.Join(workspace, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, expected it restored to %q", name, data, content)
+		}
+	}
+}
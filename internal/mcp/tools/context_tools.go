@@ -4,25 +4,39 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"path/filepath"
 	"strings"
 
 	contextpkg "github.com/rcliao/teeny-orb/internal/context"
 	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
 )
 
 // ContextAnalysisHandler implements MCP tool for project context analysis
 type ContextAnalysisHandler struct {
-	analyzer contextpkg.ContextAnalyzer
+	analyzer    contextpkg.ContextAnalyzer
+	redactPaths bool
+	scope       workspaceScope
 }
 
 // NewContextAnalysisHandler creates a new context analysis MCP tool handler
 func NewContextAnalysisHandler(analyzer contextpkg.ContextAnalyzer) *ContextAnalysisHandler {
 	return &ContextAnalysisHandler{
-		analyzer: analyzer,
+		analyzer:    analyzer,
+		redactPaths: true,
 	}
 }
 
+// SetPathRedaction enables or disables rewriting absolute paths relative to the analyzed project
+func (h *ContextAnalysisHandler) SetPathRedaction(enabled bool) {
+	h.redactPaths = enabled
+}
+
+// SetWorkspace confines the projects the tool analyzes to baseDir, checked by
+// validator, for sessions without a workspace of their own
+func (h *ContextAnalysisHandler) SetWorkspace(baseDir string, validator *security.SecurityValidator) {
+	h.scope = workspaceScope{baseDir: baseDir, validator: validator}
+}
+
 // Name returns the tool name
 func (h *ContextAnalysisHandler) Name() string {
 	return "analyze_context"
@@ -70,20 +84,14 @@ func (h *ContextAnalysisHandler) Handle(ctx context.Context, arguments map[strin
 		}, nil
 	}
 
-	// Make path absolute
-	absPath, err := filepath.Abs(projectPath)
+	// Make path absolute, within the workspace when there is one
+	absPath, err := h.scope.resolve(ctx, "list", projectPath)
 	if err != nil {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{{
-				Type: "text",
-				Text: fmt.Sprintf("Error: invalid project path: %v", err),
-			}},
-			IsError: true,
-		}, nil
+		return scopeError("Error: invalid project path", projectPath, err), nil
 	}
 
 	// Perform analysis
-	projectContext, err := h.analyzer.AnalyzeProject(ctx, absPath)
+	projectContext, err := h.analyzer.AnalyzeProject(analysisProgress(ctx), absPath)
 	if err != nil {
 		return &mcp.CallToolResponse{
 			Content: []mcp.Content{{
@@ -98,7 +106,7 @@ func (h *ContextAnalysisHandler) Handle(ctx context.Context, arguments map[strin
 	analysisText := h.formatAnalysisResults(projectContext)
 	analysisJSON, _ := json.MarshalIndent(projectContext, "", "  ")
 
-	return &mcp.CallToolResponse{
+	return redactResponse(projectRedactor(h.redactPaths, absPath), &mcp.CallToolResponse{
 		Content: []mcp.Content{
 			{
 				Type: "text",
@@ -110,7 +118,7 @@ func (h *ContextAnalysisHandler) Handle(ctx context.Context, arguments map[strin
 				MimeType: "application/json",
 			},
 		},
-	}, nil
+	}), nil
 }
 
 func (h *ContextAnalysisHandler) formatAnalysisResults(projectCtx *contextpkg.ProjectContext) string {
@@ -171,18 +179,32 @@ func (h *ContextAnalysisHandler) formatAnalysisResults(projectCtx *contextpkg.Pr
 
 // ContextOptimizationHandler implements MCP tool for context optimization
 type ContextOptimizationHandler struct {
-	optimizer contextpkg.ContextOptimizer
-	analyzer  contextpkg.ContextAnalyzer
+	optimizer   contextpkg.ContextOptimizer
+	analyzer    contextpkg.ContextAnalyzer
+	redactPaths bool
+	scope       workspaceScope
 }
 
 // NewContextOptimizationHandler creates a new context optimization MCP tool handler
 func NewContextOptimizationHandler(optimizer contextpkg.ContextOptimizer, analyzer contextpkg.ContextAnalyzer) *ContextOptimizationHandler {
 	return &ContextOptimizationHandler{
-		optimizer: optimizer,
-		analyzer:  analyzer,
+		optimizer:   optimizer,
+		analyzer:    analyzer,
+		redactPaths: true,
 	}
 }
 
+// SetPathRedaction enables or disables rewriting absolute paths relative to the optimized project
+func (h *ContextOptimizationHandler) SetPathRedaction(enabled bool) {
+	h.redactPaths = enabled
+}
+
+// SetWorkspace confines the projects the tool optimizes for to baseDir,
+// checked by validator, for sessions without a workspace of their own
+func (h *ContextOptimizationHandler) SetWorkspace(baseDir string, validator *security.SecurityValidator) {
+	h.scope = workspaceScope{baseDir: baseDir, validator: validator}
+}
+
 // Name returns the tool name
 func (h *ContextOptimizationHandler) Name() string {
 	return "optimize_context"
@@ -233,6 +255,11 @@ func (h *ContextOptimizationHandler) InputSchema() mcp.InputSchema {
 				"enum":        []string{"relevance", "dependency", "freshness", "compactness", "balanced"},
 				"default":     "balanced",
 			},
+			"diversity": map[string]interface{}{
+				"type":        "number",
+				"description": "0-1, how strongly to avoid files similar to ones already selected",
+				"default":     0,
+			},
 		},
 		Required: []string{"project_path", "task_description"},
 	}
@@ -294,20 +321,19 @@ func (h *ContextOptimizationHandler) Handle(ctx context.Context, arguments map[s
 		strategy = s
 	}
 
-	// Make path absolute
-	absPath, err := filepath.Abs(projectPath)
+	diversity := 0.0
+	if d, ok := arguments["diversity"].(float64); ok {
+		diversity = d
+	}
+
+	// Make path absolute, within the workspace when there is one
+	absPath, err := h.scope.resolve(ctx, "list", projectPath)
 	if err != nil {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{{
-				Type: "text",
-				Text: fmt.Sprintf("Error: invalid project path: %v", err),
-			}},
-			IsError: true,
-		}, nil
+		return scopeError("Error: invalid project path", projectPath, err), nil
 	}
 
 	// Analyze project first
-	projectContext, err := h.analyzer.AnalyzeProject(ctx, absPath)
+	projectContext, err := h.analyzer.AnalyzeProject(analysisProgress(ctx), absPath)
 	if err != nil {
 		return &mcp.CallToolResponse{
 			Content: []mcp.Content{{
@@ -335,6 +361,7 @@ func (h *ContextOptimizationHandler) Handle(ctx context.Context, arguments map[s
 		IncludeTests:      includeTests,
 		IncludeDocs:       includeDocs,
 		FreshnessBias:     0.2,
+		DiversityWeight:   diversity,
 		DependencyDepth:   2,
 		Strategy:          contextpkg.SelectionStrategy(strategy),
 	}
@@ -355,7 +382,7 @@ func (h *ContextOptimizationHandler) Handle(ctx context.Context, arguments map[s
 	optimizationText := h.formatOptimizationResults(selectedContext, projectContext)
 	optimizationJSON, _ := json.MarshalIndent(selectedContext, "", "  ")
 
-	return &mcp.CallToolResponse{
+	return redactResponse(projectRedactor(h.redactPaths, absPath), &mcp.CallToolResponse{
 		Content: []mcp.Content{
 			{
 				Type: "text",
@@ -367,7 +394,7 @@ func (h *ContextOptimizationHandler) Handle(ctx context.Context, arguments map[s
 				MimeType: "application/json",
 			},
 		},
-	}, nil
+	}), nil
 }
 
 func (h *ContextOptimizationHandler) formatOptimizationResults(selectedCtx *contextpkg.SelectedContext, projectCtx *contextpkg.ProjectContext) string {
@@ -412,6 +439,7 @@ func (h *ContextOptimizationHandler) formatOptimizationResults(selectedCtx *cont
 // TokenCountHandler implements MCP tool for token counting
 type TokenCountHandler struct {
 	analyzer contextpkg.ContextAnalyzer
+	scope    workspaceScope
 }
 
 // NewTokenCountHandler creates a new token counting MCP tool handler
@@ -421,6 +449,12 @@ func NewTokenCountHandler(analyzer contextpkg.ContextAnalyzer) *TokenCountHandle
 	}
 }
 
+// SetWorkspace confines the files the tool counts to baseDir, checked by
+// validator, for sessions without a workspace of their own
+func (h *TokenCountHandler) SetWorkspace(baseDir string, validator *security.SecurityValidator) {
+	h.scope = workspaceScope{baseDir: baseDir, validator: validator}
+}
+
 // Name returns the tool name
 func (h *TokenCountHandler) Name() string {
 	return "count_tokens"
@@ -458,7 +492,11 @@ func (h *TokenCountHandler) Handle(ctx context.Context, arguments map[string]int
 		tokenCount, err = h.analyzer.CountTokens(content)
 		source = "provided content"
 	} else if filePath, ok := arguments["file_path"].(string); ok {
-		fileInfo, fileErr := h.analyzer.GetFileInfo(ctx, filePath)
+		absPath, scopeErr := h.scope.resolve(ctx, "read", filePath)
+		if scopeErr != nil {
+			return scopeError("Error reading file", filePath, scopeErr), nil
+		}
+		fileInfo, fileErr := h.analyzer.GetFileInfo(ctx, absPath)
 		if fileErr != nil {
 			return &mcp.CallToolResponse{
 				Content: []mcp.Content{{
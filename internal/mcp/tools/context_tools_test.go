@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	contextpkg "github.com/rcliao/teeny-orb/internal/context"
+)
+
+// countingAnalyzer counts full analyses and refreshes of an earlier analysis
+type countingAnalyzer struct {
+	*contextpkg.DefaultAnalyzer
+	analyses  int
+	refreshes int
+}
+
+func (a *countingAnalyzer) AnalyzeProject(ctx context.Context, rootPath string) (*contextpkg.ProjectContext, error) {
+	a.analyses++
+	return a.DefaultAnalyzer.AnalyzeProject(ctx, rootPath)
+}
+
+func (a *countingAnalyzer) RefreshProject(ctx context.Context, project *contextpkg.ProjectContext) (*contextpkg.ProjectContext, error) {
+	a.refreshes++
+	return a.DefaultAnalyzer.RefreshProject(ctx, project)
+}
+
+// TestContextOptimizationUsesWarmedAnalysis tests that a context_optimization
+// request after warmup builds on the warmed analysis, wired as the HTTP
+// server wires it, instead of analyzing the project from scratch
+func TestContextOptimizationUsesWarmedAnalysis(t *testing.T) {
+	project := t.TempDir()
+	for name, content := range map[string]string{
+		"main.go":  "package main\n\nfunc main() { openStore() }\n",
+		"store.go": "package main\n\n// openStore opens the store\nfunc openStore() {}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(project, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	counting := &countingAnalyzer{DefaultAnalyzer: contextpkg.NewDefaultAnalyzer(contextpkg.NewSimpleTokenCounter(), nil)}
+	analyzer := contextpkg.NewCachingAnalyzer(counting, 0)
+	optimizer := contextpkg.NewDefaultOptimizer(analyzer, contextpkg.NewInMemoryContextCache(nil), nil, nil)
+	if _, err := contextpkg.NewWarmer(analyzer, &contextpkg.WarmupConfig{RootPath: project}).Run(context.Background()); err != nil {
+		t.Fatalf("warmup failed: %v", err)
+	}
+
+	handler := NewContextOptimizationHandler(optimizer, analyzer.Revalidating())
+	resp, err := handler.Handle(context.Background(), map[string]interface{}{
+		"project_path":     project,
+		"task_description": "fix the store failing to open",
+		"task_type":        "debug",
+		"token_budget":     float64(2000),
+	})
+	if err != nil || resp.IsError {
+		t.Fatalf("Handle failed: %v %+v", err, resp)
+	}
+
+	if counting.analyses != 1 || counting.refreshes != 1 {
+		t.Errorf("got %d analyses and %d refreshes, expected the request to refresh the warmed analysis", counting.analyses, counting.refreshes)
+	}
+}
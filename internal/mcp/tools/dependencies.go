@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	contextpkg "github.com/rcliao/teeny-orb/internal/context"
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
+)
+
+// maxDependencyDepth bounds transitive expansion so one call can't walk an
+// entire large graph
+const maxDependencyDepth = 10
+
+// DependenciesHandler implements an MCP tool that reports a file's place in
+// the dependency graph of an analyzed project
+type DependenciesHandler struct {
+	analyzer    *contextpkg.CachingAnalyzer
+	redactPaths bool
+	scope       workspaceScope
+}
+
+// NewDependenciesHandler creates a dependencies tool reading analyses from
+// analyzer, which should be shared with the context tools so every tool
+// sees the same graph
+func NewDependenciesHandler(analyzer *contextpkg.CachingAnalyzer) *DependenciesHandler {
+	return &DependenciesHandler{
+		analyzer:    analyzer,
+		redactPaths: true,
+	}
+}
+
+// SetPathRedaction enables or disables rewriting absolute paths relative to the analyzed project
+func (h *DependenciesHandler) SetPathRedaction(enabled bool) {
+	h.redactPaths = enabled
+}
+
+// SetWorkspace confines the projects the tool looks up to baseDir, checked by
+// validator, for sessions without a workspace of their own
+func (h *DependenciesHandler) SetWorkspace(baseDir string, validator *security.SecurityValidator) {
+	h.scope = workspaceScope{baseDir: baseDir, validator: validator}
+}
+
+// Name returns the tool name
+func (h *DependenciesHandler) Name() string {
+	return "dependencies"
+}
+
+// Description returns the tool description
+func (h *DependenciesHandler) Description() string {
+	return "Returns what a file depends on and what depends on it in an analyzed project's dependency graph, with its centrality score"
+}
+
+// InputSchema returns the tool input schema
+func (h *DependenciesHandler) InputSchema() mcp.InputSchema {
+	return mcp.InputSchema{
+		Type: "object",
+		Properties: map[string]interface{}{
+			"project_path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the project root, which must have been analyzed with analyze_context",
+			},
+			"file_path": map[string]interface{}{
+				"type":        "string",
+				"description": "File to look up, absolute or relative to the project root",
+			},
+			"depth": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("How many hops of transitive dependencies and dependents to include, up to %d", maxDependencyDepth),
+				"default":     1,
+				"minimum":     1,
+				"maximum":     maxDependencyDepth,
+			},
+		},
+		Required: []string{"project_path", "file_path"},
+	}
+}
+
+// dependencyEntry is a file reached from the requested one
+type dependencyEntry struct {
+	Path  string `json:"path"`
+	Depth int    `json:"depth"` // Hops from the requested file
+}
+
+// dependencySubgraph is the tool's JSON result
+type dependencySubgraph struct {
+	File         string            `json:"file"`
+	Centrality   float64           `json:"centrality"`
+	Dependencies []dependencyEntry `json:"dependencies"`
+	Dependents   []dependencyEntry `json:"dependents"`
+}
+
+// Handle looks the file up in the cached analysis of the project
+func (h *DependenciesHandler) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	projectPath, _ := arguments["project_path"].(string)
+	filePath, _ := arguments["file_path"].(string)
+	if projectPath == "" || filePath == "" {
+		return dependenciesError("Error: project_path and file_path are required and must be strings"), nil
+	}
+
+	depth := 1
+	if value, ok := arguments["depth"].(float64); ok {
+		depth = int(value)
+	}
+	if depth < 1 || depth > maxDependencyDepth {
+		return dependenciesError(fmt.Sprintf("Error: depth must be between 1 and %d", maxDependencyDepth)), nil
+	}
+
+	absPath, err := h.scope.resolve(ctx, "list", projectPath)
+	if err != nil {
+		return scopeError("Error: invalid project path", projectPath, err), nil
+	}
+	project, ok := h.analyzer.Cached(absPath)
+	if !ok || project.DependencyGraph == nil {
+		return dependenciesError(fmt.Sprintf("Error: project %s has not been analyzed yet; run analyze_context first", projectPath)), nil
+	}
+
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(project.RootPath, filePath)
+	}
+	graph := project.DependencyGraph
+	key := project.GraphKey(filePath)
+	if _, exists := graph.Nodes[key]; !exists {
+		return dependenciesError(fmt.Sprintf("Error: %s is not in the dependency graph of %s", key, projectPath)), nil
+	}
+
+	subgraph := dependencySubgraph{
+		File:       key,
+		Centrality: graph.Centrality(key),
+		Dependencies: walkDependencyGraph(graph, key, depth, func(node *contextpkg.DependencyNode) []string {
+			return node.Dependencies
+		}),
+		Dependents: walkDependencyGraph(graph, key, depth, func(node *contextpkg.DependencyNode) []string {
+			return node.Dependents
+		}),
+	}
+	subgraphJSON, _ := json.MarshalIndent(subgraph, "", "  ")
+
+	return redactResponse(projectRedactor(h.redactPaths, project.RootPath), &mcp.CallToolResponse{
+		Content: []mcp.Content{
+			{
+				Type: "text",
+				Text: formatDependencySubgraph(subgraph),
+			},
+			{
+				Type:     "text",
+				Text:     string(subgraphJSON),
+				MimeType: "application/json",
+			},
+		},
+	}), nil
+}
+
+// walkDependencyGraph follows next from start breadth-first for up to depth
+// hops, returning each file reached at its shortest distance
+func walkDependencyGraph(graph *contextpkg.DependencyGraph, start string, depth int, next func(node *contextpkg.DependencyNode) []string) []dependencyEntry {
+	entries := []dependencyEntry{}
+	seen := map[string]bool{start: true}
+	frontier := []string{start}
+	for hop := 1; hop <= depth && len(frontier) > 0; hop++ {
+		var reached []string
+		for _, key := range frontier {
+			node, exists := graph.Nodes[key]
+			if !exists {
+				continue
+			}
+			for _, neighbor := range next(node) {
+				if !seen[neighbor] {
+					seen[neighbor] = true
+					reached = append(reached, neighbor)
+				}
+			}
+		}
+		sort.Strings(reached)
+		for _, key := range reached {
+			entries = append(entries, dependencyEntry{Path: key, Depth: hop})
+		}
+		frontier = reached
+	}
+	return entries
+}
+
+// formatDependencySubgraph summarizes a subgraph for reading
+func formatDependencySubgraph(subgraph dependencySubgraph) string {
+	var result strings.Builder
+	fmt.Fprintf(&result, "# Dependencies of %s\n\n", subgraph.File)
+	fmt.Fprintf(&result, "**Centrality:** %.3f\n\n", subgraph.Centrality)
+
+	for _, section := range []struct {
+		title   string
+		entries []dependencyEntry
+	}{
+		{"Depends on", subgraph.Dependencies},
+		{"Depended on by", subgraph.Dependents},
+	} {
+		fmt.Fprintf(&result, "## %s (%d)\n", section.title, len(section.entries))
+		if len(section.entries) == 0 {
+			result.WriteString("- none\n")
+		}
+		for _, entry := range section.entries {
+			if entry.Depth == 1 {
+				fmt.Fprintf(&result, "- %s\n", entry.Path)
+			} else {
+				fmt.Fprintf(&result, "- %s (%d hops)\n", entry.Path, entry.Depth)
+			}
+		}
+		result.WriteString("\n")
+	}
+	return result.String()
+}
+
+// dependenciesError reports a tool-level failure
+func dependenciesError(text string) *mcp.CallToolResponse {
+	return &mcp.CallToolResponse{
+		Content: []mcp.Content{{Type: "text", Text: text}},
+		IsError: true,
+	}
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	contextpkg "github.com/rcliao/teeny-orb/internal/context"
+)
+
+// TestDependenciesTool tests direct and transitive lookups and the error for
+// a project that hasn't been analyzed
+func TestDependenciesTool(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":             "module example.com/app\n\ngo 1.24\n",
+		"main.go":            "package main\n\nimport \"example.com/app/service\"\n\nfunc main() { service.Run() }\n",
+		"service/service.go": "package service\n\nimport \"example.com/app/store\"\n\nfunc Run() { store.Open() }\n",
+		"store/store.go":     "package store\n\nfunc Open() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	analyzer := contextpkg.NewCachingAnalyzer(contextpkg.NewDefaultAnalyzer(contextpkg.NewSimpleTokenCounter(), nil), 0)
+	handler := NewDependenciesHandler(analyzer)
+
+	resp, err := handler.Handle(context.Background(), map[string]interface{}{"project_path": dir, "file_path": "main.go"})
+	if err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	if !resp.IsError || !strings.Contains(resp.Content[0].Text, "has not been analyzed") {
+		t.Fatalf("expected a not analyzed error, got %+v", resp)
+	}
+
+	if _, err := analyzer.AnalyzeProject(context.Background(), dir); err != nil {
+		t.Fatalf("AnalyzeProject failed: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		arguments    map[string]interface{}
+		dependencies []dependencyEntry
+		dependents   []dependencyEntry
+	}{
+		{
+			name:         "direct",
+			arguments:    map[string]interface{}{"file_path": filepath.Join("service", "service.go")},
+			dependencies: []dependencyEntry{{Path: filepath.Join("store", "store.go"), Depth: 1}},
+			dependents:   []dependencyEntry{{Path: "main.go", Depth: 1}},
+		},
+		{
+			name:      "transitive",
+			arguments: map[string]interface{}{"file_path": filepath.Join(dir, "main.go"), "depth": float64(2)},
+			dependencies: []dependencyEntry{
+				{Path: filepath.Join("service", "service.go"), Depth: 1},
+				{Path: filepath.Join("store", "store.go"), Depth: 2},
+			},
+			dependents: []dependencyEntry{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.arguments["project_path"] = dir
+			resp, err := handler.Handle(context.Background(), tt.arguments)
+			if err != nil {
+				t.Fatalf("Handle failed: %v", err)
+			}
+			if resp.IsError || len(resp.Content) != 2 {
+				t.Fatalf("unexpected response: %+v", resp)
+			}
+
+			var subgraph dependencySubgraph
+			if err := json.Unmarshal([]byte(resp.Content[1].Text), &subgraph); err != nil {
+				t.Fatalf("failed to parse result: %v", err)
+			}
+			if !reflect.DeepEqual(subgraph.Dependencies, tt.dependencies) {
+				t.Errorf("dependencies = %v, expected %v", subgraph.Dependencies, tt.dependencies)
+			}
+			if !reflect.DeepEqual(subgraph.Dependents, tt.dependents) {
+				t.Errorf("dependents = %v, expected %v", subgraph.Dependents, tt.dependents)
+			}
+			if subgraph.Centrality <= 0 {
+				t.Errorf("centrality = %v, expected it to be positive", subgraph.Centrality)
+			}
+		})
+	}
+}
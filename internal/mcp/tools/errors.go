@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"syscall"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
+)
+
+// invalidArgument reports arguments the tool can't use
+func invalidArgument(message string) *mcp.CallToolResponse {
+	return mcp.NewToolErrorResponse(mcp.ErrorCodeInvalidArgument, message, nil)
+}
+
+// accessDenied reports a security validator denial. An exhausted rate limit
+// gets its own code since retrying later can succeed.
+func accessDenied(err error) *mcp.CallToolResponse {
+	message := fmt.Sprintf("Access denied: %v", err)
+	var rateLimit *security.RateLimitError
+	if errors.As(err, &rateLimit) {
+		return mcp.NewToolErrorResponse(mcp.ErrorCodeRateLimited, message, map[string]interface{}{
+			"retry_after_ms": rateLimit.RetryAfter.Milliseconds(),
+		})
+	}
+	return mcp.NewToolErrorResponse(mcp.ErrorCodePermissionDenied, message, nil)
+}
+
+// fileError reports a failed filesystem operation on path
+func fileError(message, path string, err error) *mcp.CallToolResponse {
+	return mcp.NewToolErrorResponse(fileErrorCode(err), message, map[string]interface{}{"path": path})
+}
+
+// fileErrorCode classifies a filesystem error
+func fileErrorCode(err error) mcp.ErrorCode {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return mcp.ErrorCodeNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return mcp.ErrorCodePermissionDenied
+	case errors.Is(err, syscall.ENOTDIR), errors.Is(err, syscall.EISDIR):
+		return mcp.ErrorCodeInvalidArgument
+	case errors.Is(err, context.DeadlineExceeded):
+		return mcp.ErrorCodeTimeout
+	default:
+		return mcp.ErrorCodeInternal
+	}
+}
+
+// commandError reports a command that couldn't start or exited with an
+// error. ctx tells a timeout apart from the command failing on its own,
+// since a killed command only reports the signal.
+func commandError(ctx context.Context, message string, err error) *mcp.CallToolResponse {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return mcp.NewToolErrorResponse(mcp.ErrorCodeTimeout, message, nil)
+	}
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return mcp.NewToolErrorResponse(mcp.ErrorCodeNotFound, message, nil)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return mcp.NewToolErrorResponse(mcp.ErrorCodeExecutionFailed, message, map[string]interface{}{
+			"exit_code": exitErr.ExitCode(),
+		})
+	}
+	return mcp.NewToolErrorResponse(mcp.ErrorCodeExecutionFailed, message, nil)
+}
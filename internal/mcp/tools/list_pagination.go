@@ -0,0 +1,195 @@
+package tools
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const (
+	defaultListLimit      = 200
+	maxListLimit          = 1000
+	defaultListMaxEntries = 5000
+	maxListMaxEntries     = 50000
+)
+
+// listOptions are the validated pagination arguments of a list operation
+type listOptions struct {
+	offset     int
+	limit      int
+	recursive  bool
+	maxEntries int
+}
+
+// listEntry is a directory entry, named relative to the listed directory
+type listEntry struct {
+	name string // Slash-separated; directories end in "/" when listing recursively
+	dir  bool
+	size int64
+}
+
+// listPagination describes which part of a listing a response holds
+type listPagination struct {
+	Total      int    `json:"total"`                 // Entries in the listing, up to max_entries when capped
+	Offset     int    `json:"offset"`                // Index of the first returned entry
+	Returned   int    `json:"returned"`              // Entries in this response
+	Capped     bool   `json:"capped"`                // The recursive walk stopped at max_entries
+	NextCursor string `json:"next_cursor,omitempty"` // Pass as cursor to get the following entries
+}
+
+// listCursor is the state a continuation token carries. The path and
+// recursive flag are checked so a token can't continue a different listing.
+type listCursor struct {
+	Path      string `json:"p"`
+	Recursive bool   `json:"r"`
+	Offset    int    `json:"o"`
+}
+
+// encodeListCursor returns an opaque continuation token for cursor
+func encodeListCursor(cursor listCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeListCursor parses a continuation token
+func decodeListCursor(token string) (listCursor, error) {
+	var cursor listCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Offset < 0 {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// parseListOptions validates the pagination arguments of a list of path
+func parseListOptions(path string, arguments map[string]interface{}) (listOptions, error) {
+	opts := listOptions{limit: defaultListLimit, maxEntries: defaultListMaxEntries}
+
+	if value, exists := arguments["recursive"]; exists {
+		recursive, ok := value.(bool)
+		if !ok {
+			return opts, fmt.Errorf("recursive must be a boolean")
+		}
+		opts.recursive = recursive
+	}
+
+	for _, arg := range []struct {
+		name     string
+		target   *int
+		min, max int // max 0 means unbounded
+	}{
+		{"offset", &opts.offset, 0, 0},
+		{"limit", &opts.limit, 1, maxListLimit},
+		{"max_entries", &opts.maxEntries, 1, maxListMaxEntries},
+	} {
+		value, exists := arguments[arg.name]
+		if !exists {
+			continue
+		}
+		number, ok := value.(float64)
+		if !ok || number != float64(int(number)) {
+			return opts, fmt.Errorf("%s must be an integer", arg.name)
+		}
+		if arg.max == 0 && int(number) < arg.min {
+			return opts, fmt.Errorf("%s must be at least %d", arg.name, arg.min)
+		}
+		if arg.max > 0 && (int(number) < arg.min || int(number) > arg.max) {
+			return opts, fmt.Errorf("%s must be between %d and %d", arg.name, arg.min, arg.max)
+		}
+		*arg.target = int(number)
+	}
+
+	if value, exists := arguments["cursor"]; exists {
+		token, ok := value.(string)
+		if !ok {
+			return opts, fmt.Errorf("cursor must be a string")
+		}
+		if _, hasOffset := arguments["offset"]; hasOffset {
+			return opts, fmt.Errorf("cursor and offset can't be combined")
+		}
+		cursor, err := decodeListCursor(token)
+		if err != nil {
+			return opts, err
+		}
+		if cursor.Path != path || cursor.Recursive != opts.recursive {
+			return opts, fmt.Errorf("cursor belongs to a different listing")
+		}
+		opts.offset = cursor.Offset
+	}
+
+	return opts, nil
+}
+
+// collectEntries lists dir, walking subdirectories when recursive. A
+// recursive walk skips directories the path restrictions deny and stops
+// after maxEntries entries, reporting whether it did.
+func (f *RealFileSystemTool) collectEntries(dir string, opts listOptions) ([]listEntry, bool, error) {
+	if !opts.recursive {
+		dirEntries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, false, err
+		}
+		entries := make([]listEntry, 0, len(dirEntries))
+		for _, entry := range dirEntries {
+			entries = append(entries, newListEntry(entry.Name(), entry))
+		}
+		return entries, false, nil
+	}
+
+	var entries []listEntry
+	capped := false
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil // Skip entries that vanish or can't be read mid-walk
+		}
+		if path == dir {
+			if !entry.IsDir() {
+				return fmt.Errorf("%s: %w", filepath.Base(dir), syscall.ENOTDIR)
+			}
+			return nil
+		}
+		if f.validator != nil && !f.validator.PathAllowed(path) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(entries) == opts.maxEntries {
+			capped = true
+			return filepath.SkipAll
+		}
+
+		relative, _ := filepath.Rel(dir, path)
+		name := filepath.ToSlash(relative)
+		if entry.IsDir() {
+			name += "/"
+		}
+		entries = append(entries, newListEntry(name, entry))
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return entries, capped, nil
+}
+
+// newListEntry describes entry under name
+func newListEntry(name string, entry fs.DirEntry) listEntry {
+	listed := listEntry{name: name, dir: entry.IsDir()}
+	if !listed.dir {
+		if info, err := entry.Info(); err == nil {
+			listed.size = info.Size()
+		}
+	}
+	return listed
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	contextpkg "github.com/rcliao/teeny-orb/internal/context"
+	"github.com/rcliao/teeny-orb/internal/mcp"
+)
+
+// progressInterval is the least time between two progress reports of a
+// tool call
+var progressInterval = time.Second
+
+// Types of the events tools publish while they run
+const (
+	searchMatchEvent   = "search_match"   // A matching line, as soon as a search finds it
+	commandOutputEvent = "command_output" // A chunk of a running command's output
+)
+
+// outputBuffer collects a command's combined output while progress reports
+// read how much there is
+type outputBuffer struct {
+	mutex   sync.Mutex
+	buffer  bytes.Buffer
+	onWrite func(p []byte) // Called with each chunk written, when set
+}
+
+func (b *outputBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.onWrite != nil {
+		b.onWrite(p)
+	}
+	return b.buffer.Write(p)
+}
+
+// Len returns the number of bytes written so far
+func (b *outputBuffer) Len() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Len()
+}
+
+// Bytes returns the output; call it once the command has finished
+func (b *outputBuffer) Bytes() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Bytes()
+}
+
+// reportCommandProgress reports the elapsed time and output size of a
+// running command every progressInterval until the returned stop is called.
+// Progress counts elapsed seconds since the command's length is unknown.
+func reportCommandProgress(ctx context.Context, command string, start time.Time, output *outputBuffer) (stop func()) {
+	if !mcp.ProgressRequested(ctx) {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				elapsed := now.Sub(start)
+				message := fmt.Sprintf("%s running for %s, %d bytes of output", command, elapsed.Round(time.Second), output.Len())
+				mcp.ReportProgress(ctx, elapsed.Seconds(), 0, message)
+			}
+		}
+	}()
+	// Waiting for the reporter keeps progress from arriving after the result
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// analysisProgress returns a context under which a project analysis reports
+// the number of files processed, at most once per progressInterval
+func analysisProgress(ctx context.Context) context.Context {
+	if !mcp.ProgressRequested(ctx) {
+		return ctx
+	}
+	var last time.Time
+	return contextpkg.WithAnalysisProgress(ctx, func(progress contextpkg.AnalysisProgress) {
+		if time.Since(last) < progressInterval {
+			return
+		}
+		last = time.Now()
+		message := fmt.Sprintf("%d files analyzed of %d scanned, %d bytes read", progress.FilesAnalyzed, progress.FilesScanned, progress.BytesRead)
+		mcp.ReportProgress(ctx, float64(progress.FilesAnalyzed), 0, message)
+	})
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	contextpkg "github.com/rcliao/teeny-orb/internal/context"
+	"github.com/rcliao/teeny-orb/internal/mcp"
+)
+
+// TestToolsReportProgress tests that long-running tools send progress
+// notifications carrying the request's progress token
+func TestToolsReportProgress(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	interval := progressInterval
+	progressInterval = 10 * time.Millisecond
+	t.Cleanup(func() { progressInterval = interval })
+
+	project := t.TempDir()
+	for _, name := range []string{"main.go", "util.go"} {
+		if err := os.WriteFile(filepath.Join(project, name), []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		tool      mcp.MCPToolHandler
+		arguments map[string]interface{}
+		message   string
+	}{
+		{
+			name:      "command",
+			tool:      NewRealCommandTool(nil, project),
+			arguments: map[string]interface{}{"command": "sh", "args": []interface{}{"-c", "echo started; sleep 0.2"}},
+			message:   "bytes of output",
+		},
+		{
+			name:      "analyze_context",
+			tool:      NewContextAnalysisHandler(contextpkg.NewDefaultAnalyzer(contextpkg.NewSimpleTokenCounter(), nil)),
+			arguments: map[string]interface{}{"project_path": project},
+			message:   "files analyzed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var notifications []*mcp.Message
+			ctx := mcp.WithRequestNotifier(context.Background(), func(ctx context.Context, msg *mcp.Message) error {
+				notifications = append(notifications, msg)
+				return nil
+			})
+			ctx = mcp.WithProgressToken(ctx, "call-7")
+
+			response, err := tt.tool.Handle(ctx, tt.arguments)
+			if err != nil || response.IsError {
+				t.Fatalf("Handle failed: %v %+v", err, response)
+			}
+			if len(notifications) == 0 {
+				t.Fatal("no progress reported")
+			}
+			for _, notification := range notifications {
+				var params struct {
+					ProgressToken string `json:"progressToken"`
+					Message       string `json:"message"`
+				}
+				json.Unmarshal(notification.Params, &params)
+				if notification.Method != mcp.ProgressNotification || params.ProgressToken != "call-7" || !strings.Contains(params.Message, tt.message) {
+					t.Errorf("notification = %s %s", notification.Method, notification.Params)
+				}
+			}
+		})
+	}
+}
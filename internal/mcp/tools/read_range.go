@@ -0,0 +1,189 @@
+package tools
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+)
+
+// readRange is the validated part of a file a read asks for, by bytes or by
+// lines. The zero value reads the whole file.
+type readRange struct {
+	offset    int64 // First byte, 0-based
+	length    int64 // Bytes to read; 0 reads to the end
+	startLine int   // First line, 1-based; 0 when reading by bytes
+	endLine   int   // Last line, inclusive; 0 reads to the end
+}
+
+// byLines reports whether the range is of lines rather than bytes
+func (r readRange) byLines() bool {
+	return r.startLine > 0
+}
+
+// whole reports whether the range covers the whole file
+func (r readRange) whole() bool {
+	return r == readRange{}
+}
+
+// parseReadRange validates the offset and length, or start_line and
+// end_line, arguments of a read
+func parseReadRange(arguments map[string]interface{}) (readRange, error) {
+	var rng readRange
+	values := make(map[string]int64)
+	for _, arg := range []struct {
+		name string
+		min  int64
+	}{
+		{"offset", 0},
+		{"length", 1},
+		{"start_line", 1},
+		{"end_line", 1},
+	} {
+		value, exists := arguments[arg.name]
+		if !exists {
+			continue
+		}
+		number, ok := value.(float64)
+		if !ok || number != float64(int64(number)) {
+			return rng, fmt.Errorf("%s must be an integer", arg.name)
+		}
+		if int64(number) < arg.min {
+			return rng, fmt.Errorf("%s must be at least %d", arg.name, arg.min)
+		}
+		values[arg.name] = int64(number)
+	}
+
+	// A zero offset is the default clients may fill in, so it doesn't count
+	// as asking for a byte range
+	hasOffset := values["offset"] > 0
+	_, hasLength := values["length"]
+	startLine, hasStart := values["start_line"]
+	endLine, hasEnd := values["end_line"]
+	if (hasOffset || hasLength) && (hasStart || hasEnd) {
+		return rng, errors.New("a byte range (offset, length) and a line range (start_line, end_line) can't be combined")
+	}
+	if hasStart || hasEnd {
+		if !hasStart {
+			startLine = 1
+		}
+		if hasEnd && endLine < startLine {
+			return rng, fmt.Errorf("end_line %d is before start_line %d", endLine, startLine)
+		}
+		rng.startLine, rng.endLine = int(startLine), int(endLine)
+		return rng, nil
+	}
+	rng.offset, rng.length = values["offset"], values["length"]
+	return rng, nil
+}
+
+// readFileRange answers a read of part of the file at fullPath. The part
+// returned is held to limit bytes, so a client can page through a file too
+// large to read whole.
+func readFileRange(fullPath, path string, rng readRange, limit int64) *mcp.CallToolResponse {
+	if limit > 0 && rng.length > limit {
+		return invalidArgument(fmt.Sprintf("Error: length %d is over the %d byte read limit", rng.length, limit))
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return fileError(fmt.Sprintf("Failed to read file '%s': %v", path, err), path, err)
+	}
+	defer file.Close()
+
+	var content []byte
+	var header string
+	var truncated bool
+	if rng.byLines() {
+		var lastLine int
+		content, lastLine, truncated, err = readLines(file, rng.startLine, rng.endLine, limit)
+		if err == nil && lastLine < rng.startLine {
+			return invalidArgument(fmt.Sprintf("Error: start_line %d is past the end of '%s' (%d lines)", rng.startLine, path, lastLine))
+		}
+		header = fmt.Sprintf("lines %d-%d", rng.startLine, lastLine)
+	} else {
+		var size int64
+		content, size, truncated, err = readBytes(file, rng.offset, rng.length, limit)
+		if err == nil && rng.offset > size {
+			return invalidArgument(fmt.Sprintf("Error: offset %d is past the end of '%s' (%d bytes)", rng.offset, path, size))
+		}
+		header = fmt.Sprintf("bytes %d-%d of %d", rng.offset, rng.offset+int64(len(content)), size)
+	}
+	if err != nil {
+		return fileError(fmt.Sprintf("Failed to read file '%s': %v", path, err), path, err)
+	}
+
+	text := fmt.Sprintf("File: %s (%s)\n%s", path, header, string(content))
+	if truncated {
+		text += fmt.Sprintf("\n[truncated at the %d byte read limit]", limit)
+	}
+	return &mcp.CallToolResponse{
+		Content: []mcp.Content{
+			{
+				Type: "text",
+				Text: text,
+			},
+		},
+	}
+}
+
+// readBytes reads length bytes from offset, or to the end when length is 0,
+// holding the result to limit. It returns the file's size alongside.
+func readBytes(file *os.File, offset, length, limit int64) ([]byte, int64, bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	size := info.Size()
+	if offset > size {
+		return nil, size, false, nil
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, size, false, err
+	}
+
+	want := size - offset
+	if length > 0 && length < want {
+		want = length
+	}
+	truncated := limit > 0 && want > limit
+	if truncated {
+		want = limit
+	}
+	content, err := io.ReadAll(io.LimitReader(file, want))
+	return content, size, truncated, err
+}
+
+// readLines reads lines startLine through endLine, or to the end when
+// endLine is 0, holding the result to limit bytes. It returns the number of
+// the last line read, which is below startLine when the file is shorter.
+func readLines(file *os.File, startLine, endLine int, limit int64) ([]byte, int, bool, error) {
+	reader := bufio.NewReader(file)
+	var content []byte
+	line, lastLine := 1, 0
+	for endLine == 0 || line <= endLine {
+		chunk, err := reader.ReadSlice('\n')
+		if len(chunk) > 0 {
+			lastLine = line
+		}
+		if line >= startLine {
+			content = append(content, chunk...)
+			if limit > 0 && int64(len(content)) > limit {
+				return content[:limit], lastLine, true, nil
+			}
+		}
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue // The line goes on past the buffer
+		case errors.Is(err, io.EOF):
+			return content, lastLine, false, nil
+		case err != nil:
+			return nil, lastLine, false, err
+		}
+		line++
+	}
+	return content, lastLine, false, nil
+}
@@ -2,7 +2,11 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,8 +20,11 @@ import (
 
 // RealFileSystemTool provides actual file system operations with security
 type RealFileSystemTool struct {
-	baseDir   string
-	validator *security.SecurityValidator
+	baseDir       string
+	validator     *security.SecurityValidator
+	redactor      *security.PathRedactor
+	symlinkPolicy security.SymlinkPolicy
+	maxReadSize   int64 // Overrides the policy's MaxFileSize for reads when positive
 }
 
 // NewRealFileSystemTool creates a new real filesystem tool
@@ -29,19 +36,68 @@ func NewRealFileSystemTool(baseDir string, validator *security.SecurityValidator
 	}
 	
 	return &RealFileSystemTool{
-		baseDir:   absBaseDir,
-		validator: validator,
+		baseDir:       absBaseDir,
+		validator:     validator,
+		redactor:      security.NewPathRedactor(absBaseDir),
+		symlinkPolicy: security.SymlinkPolicyDenyOutside,
 	}
 }
 
+// SetSymlinkPolicy sets which links within the workspace operations may follow
+func (f *RealFileSystemTool) SetSymlinkPolicy(policy security.SymlinkPolicy) {
+	f.symlinkPolicy = policy
+}
+
+// SetPathRedaction enables or disables rewriting absolute workspace paths in results
+func (f *RealFileSystemTool) SetPathRedaction(enabled bool) {
+	f.redactor = nil
+	if enabled {
+		f.redactor = security.NewPathRedactor(f.baseDir)
+	}
+}
+
+// SetMaxReadSize sets the largest file in bytes a read returns whole, in
+// place of the security policy's MaxFileSize
+func (f *RealFileSystemTool) SetMaxReadSize(bytes int64) {
+	f.maxReadSize = bytes
+}
+
 // Name returns the tool name
 func (f *RealFileSystemTool) Name() string {
 	return "filesystem"
 }
 
+// Timeout returns how long a file operation may run
+func (f *RealFileSystemTool) Timeout() time.Duration {
+	return 30 * time.Second
+}
+
+// Cacheable reports whether a call may be answered from the server's cache:
+// reads and listings may, writes change files and searches stream their
+// matches as events
+func (f *RealFileSystemTool) Cacheable(arguments map[string]interface{}) bool {
+	operation, _ := arguments["operation"].(string)
+	return operation == "read" || operation == "list"
+}
+
+// AuthorizeCachedCall validates a read or listing the server answers from
+// its cache, so the validator audits and rate limits it like one that runs
+func (f *RealFileSystemTool) AuthorizeCachedCall(ctx context.Context, arguments map[string]interface{}) *mcp.CallToolResponse {
+	tool := f.inWorkspace(ctx)
+	if tool.validator == nil {
+		return nil
+	}
+	operation, _ := arguments["operation"].(string)
+	path, _ := arguments["path"].(string)
+	if err := tool.validator.ValidateFileOperation(ctx, operation, tool.resolvePath(path)); err != nil {
+		return redactResponse(tool.redactor, accessDenied(err))
+	}
+	return nil
+}
+
 // Description returns the tool description
 func (f *RealFileSystemTool) Description() string {
-	return "Provides real file system operations including read, write, and list with security validation"
+	return "Provides real file system operations including read, write, list, and search with security validation"
 }
 
 // InputSchema returns the JSON schema for tool inputs
@@ -51,35 +107,122 @@ func (f *RealFileSystemTool) InputSchema() mcp.InputSchema {
 		Properties: map[string]interface{}{
 			"operation": map[string]interface{}{
 				"type":        "string",
-				"enum":        []string{"read", "write", "list"},
+				"enum":        []string{"read", "write", "list", "search"},
 				"description": "The file system operation to perform",
 			},
 			"path": map[string]interface{}{
 				"type":        "string",
-				"description": "The file or directory path relative to the workspace",
+				"description": "The file or directory path relative to the workspace; for search, the directory or file to search in",
 			},
 			"content": map[string]interface{}{
 				"type":        "string",
 				"description": "Content to write (required for write operation)",
 			},
+			"truncate": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Return the start of a file over the size limit instead of refusing it (read operation)",
+				"default":     false,
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of entries to skip (list operation), or byte to start reading at (read operation)",
+				"minimum":     0,
+			},
+			"length": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of bytes to read from offset; reads to the end when omitted (read operation)",
+				"minimum":     1,
+			},
+			"start_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "First line to read, counting from 1; can't be combined with a nonzero offset or a length (read operation)",
+				"minimum":     1,
+			},
+			"end_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "Last line to read, inclusive; reads to the end when omitted (read operation)",
+				"minimum":     1,
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of entries to return (list operation)",
+				"default":     defaultListLimit,
+				"minimum":     1,
+				"maximum":     maxListLimit,
+			},
+			"recursive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "List entries of subdirectories too (list operation)",
+				"default":     false,
+			},
+			"max_entries": map[string]interface{}{
+				"type":        "integer",
+				"description": "Stop a recursive listing after this many entries (list operation)",
+				"default":     defaultListMaxEntries,
+				"minimum":     1,
+				"maximum":     maxListMaxEntries,
+			},
+			"cursor": map[string]interface{}{
+				"type":        "string",
+				"description": "Continuation token from a truncated listing; replaces offset (list operation)",
+			},
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to find (required for search operation)",
+			},
+			"regex": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Treat query as a regular expression (search operation)",
+				"default":     false,
+			},
+			"file_types": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Only search files with these extensions, such as [\"go\", \"md\"] (search operation)",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": "Stop after this many matching lines (search operation)",
+				"default":     defaultSearchResults,
+				"minimum":     1,
+				"maximum":     maxSearchResults,
+			},
 		},
 		Required: []string{"operation"},
 	}
 }
 
+// contentOperations return file content, which must reach the client as it
+// is on disk; they redact the paths in their own output instead
+var contentOperations = map[string]bool{"read": true, "search": true}
+
 // Handle executes the filesystem operation
 func (f *RealFileSystemTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	tool := f.inWorkspace(ctx)
+	response, err := tool.handle(ctx, arguments)
+	if operation, _ := arguments["operation"].(string); contentOperations[operation] && response != nil && !response.IsError {
+		return response, err
+	}
+	return redactResponse(tool.redactor, response), err
+}
+
+// inWorkspace returns the tool rebased onto the session workspace in ctx, if any
+func (f *RealFileSystemTool) inWorkspace(ctx context.Context) *RealFileSystemTool {
+	workspace := security.WorkspaceFromContext(ctx)
+	if workspace == nil {
+		return f
+	}
+	rebased := *f
+	rebased.baseDir = workspace.BaseDir
+	rebased.validator = workspace.Validator
+	rebased.redactor = projectRedactor(f.redactor != nil, workspace.BaseDir)
+	return &rebased
+}
+
+func (f *RealFileSystemTool) handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
 	operation, ok := arguments["operation"].(string)
 	if !ok {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: "Error: operation parameter is required and must be a string",
-				},
-			},
-			IsError: true,
-		}, nil
+		return invalidArgument("Error: operation parameter is required and must be a string"), nil
 	}
 
 	switch operation {
@@ -89,31 +232,21 @@ func (f *RealFileSystemTool) Handle(ctx context.Context, arguments map[string]in
 		return f.handleWrite(ctx, arguments)
 	case "list":
 		return f.handleList(ctx, arguments)
+	case "search":
+		return f.handleSearch(ctx, arguments)
 	default:
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: fmt.Sprintf("Error: unsupported operation '%s'. Supported operations: read, write, list", operation),
-				},
-			},
-			IsError: true,
-		}, nil
+		return invalidArgument(fmt.Sprintf("Error: unsupported operation '%s'. Supported operations: read, write, list, search", operation)), nil
 	}
 }
 
 func (f *RealFileSystemTool) handleRead(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
 	path, ok := arguments["path"].(string)
 	if !ok {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: "Error: path parameter is required for read operation",
-				},
-			},
-			IsError: true,
-		}, nil
+		return invalidArgument("Error: path parameter is required for read operation"), nil
+	}
+	rng, err := parseReadRange(arguments)
+	if err != nil {
+		return invalidArgument(fmt.Sprintf("Error: %v", err)), nil
 	}
 
 	// Resolve path relative to base directory
@@ -122,68 +255,101 @@ func (f *RealFileSystemTool) handleRead(ctx context.Context, arguments map[strin
 	// Validate security permissions
 	if f.validator != nil {
 		if err := f.validator.ValidateFileOperation(ctx, "read", fullPath); err != nil {
-			return &mcp.CallToolResponse{
-				Content: []mcp.Content{
-					{
-						Type: "text",
-						Text: fmt.Sprintf("Access denied: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
+			return accessDenied(err), nil
 		}
 	}
+	if response := f.checkLinks(fullPath, path); response != nil {
+		return response, nil
+	}
 
-	// Read the actual file
-	content, err := os.ReadFile(fullPath)
+	// Read the actual file, up to the size limit
+	shown := f.redactor.Redact(path)
+	limit := f.readLimit()
+	if !rng.whole() {
+		return readFileRange(fullPath, shown, rng, limit), nil
+	}
+	truncate, _ := arguments["truncate"].(bool)
+	content, size, err := readLimited(fullPath, limit, truncate)
 	if err != nil {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: fmt.Sprintf("Failed to read file '%s': %v", path, err),
-				},
-			},
-			IsError: true,
-		}, nil
+		return fileError(fmt.Sprintf("Failed to read file '%s': %v", path, err), path, err), nil
+	}
+	if size > limit && limit > 0 && !truncate {
+		return mcp.NewToolErrorResponse(mcp.ErrorCodeTooLarge,
+			fmt.Sprintf("File '%s' is %d bytes, over the %d byte limit; pass truncate to read its start", path, size, limit),
+			map[string]interface{}{"path": path, "size": size, "limit": limit}), nil
+	}
+
+	text := fmt.Sprintf("File: %s\n%s", shown, string(content))
+	if int64(len(content)) < size {
+		text += fmt.Sprintf("\n[truncated: showing %d of %d bytes]", len(content), size)
 	}
 
 	return &mcp.CallToolResponse{
 		Content: []mcp.Content{
 			{
 				Type: "text",
-				Text: fmt.Sprintf("File: %s\n%s", path, string(content)),
+				Text: text,
 			},
 		},
 		IsError: false,
 	}, nil
 }
 
+// readLimit returns the largest file a read returns whole, or 0 for no limit
+func (f *RealFileSystemTool) readLimit() int64 {
+	if f.maxReadSize > 0 {
+		return f.maxReadSize
+	}
+	if f.validator != nil {
+		return f.validator.MaxFileSize()
+	}
+	return 0
+}
+
+// readLimited reads at most limit bytes of the file at path and returns
+// them with the file's size. A file over the limit isn't read at all unless
+// truncate is set. Files that report no size, such as those under /proc, are
+// sized by reading one byte past the limit.
+func readLimited(path string, limit int64, truncate bool) ([]byte, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	size := info.Size()
+	if limit <= 0 {
+		content, err := io.ReadAll(file)
+		return content, int64(len(content)), err
+	}
+	if size > limit && !truncate {
+		return nil, size, nil
+	}
+
+	content, err := io.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return nil, 0, err
+	}
+	if int64(len(content)) > limit {
+		content = content[:limit]
+		size = max(size, limit+1)
+	}
+	return content, max(size, int64(len(content))), nil
+}
+
 func (f *RealFileSystemTool) handleWrite(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
 	path, ok := arguments["path"].(string)
 	if !ok {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: "Error: path parameter is required for write operation",
-				},
-			},
-			IsError: true,
-		}, nil
+		return invalidArgument("Error: path parameter is required for write operation"), nil
 	}
 
 	content, ok := arguments["content"].(string)
 	if !ok {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: "Error: content parameter is required for write operation",
-				},
-			},
-			IsError: true,
-		}, nil
+		return invalidArgument("Error: content parameter is required for write operation"), nil
 	}
 
 	// Resolve path relative to base directory
@@ -192,44 +358,23 @@ func (f *RealFileSystemTool) handleWrite(ctx context.Context, arguments map[stri
 	// Validate security permissions
 	if f.validator != nil {
 		if err := f.validator.ValidateFileOperation(ctx, "write", fullPath); err != nil {
-			return &mcp.CallToolResponse{
-				Content: []mcp.Content{
-					{
-						Type: "text",
-						Text: fmt.Sprintf("Access denied: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
+			return accessDenied(err), nil
 		}
 	}
+	if response := f.checkLinks(fullPath, path); response != nil {
+		return response, nil
+	}
 
 	// Ensure directory exists
 	dir := filepath.Dir(fullPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: fmt.Sprintf("Failed to create directory '%s': %v", dir, err),
-				},
-			},
-			IsError: true,
-		}, nil
+		return fileError(fmt.Sprintf("Failed to create directory '%s': %v", dir, err), path, err), nil
 	}
 
 	// Write the actual file
 	err := os.WriteFile(fullPath, []byte(content), 0644)
 	if err != nil {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: fmt.Sprintf("Failed to write file '%s': %v", path, err),
-				},
-			},
-			IsError: true,
-		}, nil
+		return fileError(fmt.Sprintf("Failed to write file '%s': %v", path, err), path, err), nil
 	}
 
 	return &mcp.CallToolResponse{
@@ -249,36 +394,42 @@ func (f *RealFileSystemTool) handleList(ctx context.Context, arguments map[strin
 		path = "." // Default to current directory
 	}
 
+	opts, err := parseListOptions(path, arguments)
+	if err != nil {
+		return invalidArgument(fmt.Sprintf("Error: %v", err)), nil
+	}
+
 	// Resolve path relative to base directory
 	fullPath := f.resolvePath(path)
 
 	// Validate security permissions
 	if f.validator != nil {
 		if err := f.validator.ValidateFileOperation(ctx, "list", fullPath); err != nil {
-			return &mcp.CallToolResponse{
-				Content: []mcp.Content{
-					{
-						Type: "text",
-						Text: fmt.Sprintf("Access denied: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
+			return accessDenied(err), nil
 		}
 	}
+	if response := f.checkLinks(fullPath, path); response != nil {
+		return response, nil
+	}
 
 	// Read directory contents
-	entries, err := os.ReadDir(fullPath)
+	entries, capped, err := f.collectEntries(fullPath, opts)
 	if err != nil {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: fmt.Sprintf("Failed to list directory '%s': %v", path, err),
-				},
-			},
-			IsError: true,
-		}, nil
+		return fileError(fmt.Sprintf("Failed to list directory '%s': %v", path, err), path, err), nil
+	}
+	if opts.offset > 0 && opts.offset >= len(entries) {
+		return invalidArgument(fmt.Sprintf("Error: offset %d is past the end of the listing (%d entries)", opts.offset, len(entries))), nil
+	}
+
+	page := entries[opts.offset:min(opts.offset+opts.limit, len(entries))]
+	pagination := listPagination{
+		Total:    len(entries),
+		Offset:   opts.offset,
+		Returned: len(page),
+		Capped:   capped,
+	}
+	if next := opts.offset + len(page); next < len(entries) {
+		pagination.NextCursor = encodeListCursor(listCursor{Path: path, Recursive: opts.recursive, Offset: next})
 	}
 
 	// Format directory listing
@@ -288,35 +439,59 @@ func (f *RealFileSystemTool) handleList(ctx context.Context, arguments map[strin
 	if len(entries) == 0 {
 		result.WriteString("(empty directory)")
 	} else {
-		for _, entry := range entries {
+		for _, entry := range page {
 			entryType := "file"
 			var size string
 			
-			if entry.IsDir() {
+			if entry.dir {
 				entryType = "directory"
-				size = ""
 			} else {
-				// Get file size
-				if info, err := entry.Info(); err == nil {
-					size = fmt.Sprintf(" (%d bytes)", info.Size())
-				}
+				size = fmt.Sprintf(" (%d bytes)", entry.size)
 			}
 			
-			result.WriteString(fmt.Sprintf("- %s (%s)%s\n", entry.Name(), entryType, size))
+			result.WriteString(fmt.Sprintf("- %s (%s)%s\n", entry.name, entryType, size))
+		}
+		result.WriteString(fmt.Sprintf("\nShowing entries %d-%d of %d", opts.offset+1, opts.offset+len(page), len(entries)))
+		if capped {
+			result.WriteString(fmt.Sprintf(" (listing stopped at max_entries %d)", opts.maxEntries))
 		}
+		if pagination.NextCursor != "" {
+			result.WriteString(fmt.Sprintf("\nMore entries available; pass cursor %q to continue", pagination.NextCursor))
+		}
+		result.WriteString("\n")
 	}
 
+	paginationJSON, _ := json.Marshal(pagination)
 	return &mcp.CallToolResponse{
 		Content: []mcp.Content{
 			{
 				Type: "text",
 				Text: result.String(),
 			},
+			{
+				Type:     "text",
+				Text:     string(paginationJSON),
+				MimeType: "application/json",
+			},
 		},
 		IsError: false,
 	}, nil
 }
 
+// checkLinks enforces the symlink policy on fullPath, returning the error
+// response when it's denied
+func (f *RealFileSystemTool) checkLinks(fullPath, path string) *mcp.CallToolResponse {
+	err := security.CheckLinks(f.baseDir, fullPath, f.symlinkPolicy)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, security.ErrLinkDenied):
+		return accessDenied(err)
+	default:
+		return fileError(fmt.Sprintf("Failed to resolve '%s': %v", path, err), path, err)
+	}
+}
+
 // resolvePath resolves a path relative to the base directory
 func (f *RealFileSystemTool) resolvePath(path string) string {
 	if filepath.IsAbs(path) {
@@ -329,6 +504,8 @@ func (f *RealFileSystemTool) resolvePath(path string) string {
 type RealCommandTool struct {
 	validator *security.SecurityValidator
 	workDir   string
+	redactor  *security.PathRedactor
+	required  []string // Executables that must be on PATH for the tool to be ready
 }
 
 // NewRealCommandTool creates a new real command tool
@@ -340,14 +517,57 @@ func NewRealCommandTool(validator *security.SecurityValidator, workDir string) *
 	return &RealCommandTool{
 		validator: validator,
 		workDir:   workDir,
+		redactor:  security.NewPathRedactor(workDir),
 	}
 }
 
+// SetPathRedaction enables or disables rewriting absolute workspace paths in output
+func (c *RealCommandTool) SetPathRedaction(enabled bool) {
+	c.redactor = nil
+	if enabled {
+		c.redactor = security.NewPathRedactor(c.workDir)
+	}
+}
+
+// SetRequiredCommands sets the executables CheckReady looks for on PATH
+func (c *RealCommandTool) SetRequiredCommands(commands []string) {
+	c.required = commands
+}
+
+// CheckReady reports whether commands can run: the working directory exists
+// and every required executable is on PATH
+func (c *RealCommandTool) CheckReady(ctx context.Context) error {
+	info, err := os.Stat(c.workDir)
+	if err != nil {
+		return fmt.Errorf("working directory unavailable: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("working directory %s is not a directory", c.workDir)
+	}
+
+	var missing []string
+	for _, command := range c.required {
+		if _, err := exec.LookPath(command); err != nil {
+			missing = append(missing, command)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("commands not found on PATH: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
 // Name returns the tool name
 func (c *RealCommandTool) Name() string {
 	return "command"
 }
 
+// Timeout returns how long a command may run; builds and test suites take
+// minutes
+func (c *RealCommandTool) Timeout() time.Duration {
+	return 5 * time.Minute
+}
+
 // Description returns the tool description
 func (c *RealCommandTool) Description() string {
 	return "Executes shell commands with security validation"
@@ -372,6 +592,14 @@ func (c *RealCommandTool) InputSchema() mcp.InputSchema {
 				"description": "Environment variables to set for the command (optional)",
 				"additionalProperties": map[string]interface{}{"type": "string"},
 			},
+			"cwd": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory to run the command in, relative to the workspace and within it (optional)",
+			},
+			"stdin": map[string]interface{}{
+				"type":        "string",
+				"description": "Input piped to the command, such as answers to its prompts (optional). Without it the command reads end of file, so prompts fail instead of waiting.",
+			},
 		},
 		Required: []string{"command"},
 	}
@@ -379,17 +607,28 @@ func (c *RealCommandTool) InputSchema() mcp.InputSchema {
 
 // Handle executes the command with enhanced cross-platform support
 func (c *RealCommandTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	tool := c.inWorkspace(ctx)
+	response, err := tool.handle(ctx, arguments)
+	return redactResponse(tool.redactor, response), err
+}
+
+// inWorkspace returns the tool running in the session workspace in ctx, if any
+func (c *RealCommandTool) inWorkspace(ctx context.Context) *RealCommandTool {
+	workspace := security.WorkspaceFromContext(ctx)
+	if workspace == nil {
+		return c
+	}
+	rebased := *c
+	rebased.workDir = workspace.BaseDir
+	rebased.validator = workspace.Validator
+	rebased.redactor = projectRedactor(c.redactor != nil, workspace.BaseDir)
+	return &rebased
+}
+
+func (c *RealCommandTool) handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
 	command, ok := arguments["command"].(string)
 	if !ok {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: "Error: command parameter is required and must be a string",
-				},
-			},
-			IsError: true,
-		}, nil
+		return invalidArgument("Error: command parameter is required and must be a string"), nil
 	}
 
 	// Extract args if provided
@@ -421,30 +660,36 @@ func (c *RealCommandTool) Handle(ctx context.Context, arguments map[string]inter
 	// Validate security permissions
 	if c.validator != nil {
 		if err := c.validator.ValidateCommandExecution(ctx, command, args); err != nil {
-			return &mcp.CallToolResponse{
-				Content: []mcp.Content{
-					{
-						Type: "text",
-						Text: fmt.Sprintf("Access denied: %v", err),
-					},
-				},
-				IsError: true,
-			}, nil
+			return accessDenied(err), nil
 		}
 	}
 
+	dir := c.workDir
+	if value, exists := arguments["cwd"]; exists {
+		cwd, ok := value.(string)
+		if !ok || cwd == "" {
+			return invalidArgument("Error: cwd must be a non-empty string"), nil
+		}
+		resolved, response := c.resolveWorkingDirectory(ctx, cwd)
+		if response != nil {
+			return response, nil
+		}
+		dir = resolved
+	}
+
+	var stdin *string
+	if value, exists := arguments["stdin"]; exists {
+		input, ok := value.(string)
+		if !ok {
+			return invalidArgument("Error: stdin must be a string"), nil
+		}
+		stdin = &input
+	}
+
 	// Execute the command with enhanced configuration
-	result, err := c.executeCommand(ctx, command, args, envVars)
+	result, err := c.executeCommand(ctx, dir, command, args, envVars, stdin)
 	if err != nil {
-		return &mcp.CallToolResponse{
-			Content: []mcp.Content{
-				{
-					Type: "text",
-					Text: result,
-				},
-			},
-			IsError: true,
-		}, nil
+		return commandError(ctx, result, err), nil
 	}
 
 	return &mcp.CallToolResponse{
@@ -458,8 +703,36 @@ func (c *RealCommandTool) Handle(ctx context.Context, arguments map[string]inter
 	}, nil
 }
 
-// executeCommand performs cross-platform command execution with enhanced environment management
-func (c *RealCommandTool) executeCommand(ctx context.Context, command string, args []string, envVars map[string]string) (string, error) {
+// resolveWorkingDirectory resolves a cwd argument, which must be a directory
+// within the workspace even after following symlinks
+func (c *RealCommandTool) resolveWorkingDirectory(ctx context.Context, cwd string) (string, *mcp.CallToolResponse) {
+	var resolved string
+	var err error
+	if c.validator != nil {
+		resolved, err = c.validator.ValidateWorkingDirectory(ctx, c.workDir, cwd)
+	} else {
+		resolved, err = security.ResolveWithin(c.workDir, cwd)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", fileError(fmt.Sprintf("Error: working directory '%s' does not exist", cwd), cwd, err)
+		}
+		return "", accessDenied(err)
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", fileError(fmt.Sprintf("Error: failed to access working directory '%s': %v", cwd, err), cwd, err)
+	}
+	if !info.IsDir() {
+		return "", invalidArgument(fmt.Sprintf("Error: working directory '%s' is not a directory", cwd))
+	}
+	return resolved, nil
+}
+
+// executeCommand performs cross-platform command execution in dir with enhanced environment management.
+// The command reads stdin when given, and the null device otherwise.
+func (c *RealCommandTool) executeCommand(ctx context.Context, dir, command string, args []string, envVars map[string]string, stdin *string) (string, error) {
 	// Prepare command execution based on platform
 	cmd, err := c.prepareCommand(ctx, command, args)
 	if err != nil {
@@ -467,23 +740,38 @@ func (c *RealCommandTool) executeCommand(ctx context.Context, command string, ar
 	}
 
 	// Set working directory
-	cmd.Dir = c.workDir
+	cmd.Dir = dir
 
 	// Configure environment
 	if err := c.configureEnvironment(cmd, command, envVars); err != nil {
 		return "", fmt.Errorf("failed to configure environment: %w", err)
 	}
 
-	// Execute with timeout
+	// Execute with timeout, reporting progress while the command runs
+	var output outputBuffer
+	if mcp.EventsEnabled(ctx) {
+		output.onWrite = func(p []byte) {
+			mcp.EmitEvent(ctx, commandOutputEvent, map[string]interface{}{"output": c.redactor.Redact(string(p))})
+		}
+	}
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	// Left nil, Stdin is the null device rather than the server's own stdin,
+	// which carries the protocol over stdio, so prompts fail fast
+	if stdin != nil {
+		cmd.Stdin = strings.NewReader(*stdin)
+	}
 	start := time.Now()
-	output, err := cmd.CombinedOutput()
+	stop := reportCommandProgress(ctx, command, start, &output)
+	err = cmd.Run()
+	stop()
 	duration := time.Since(start)
 
 	// Format result
-	result := c.formatCommandResult(command, args, output, err, duration)
+	result := c.formatCommandResult(dir, command, args, output.Bytes(), err, duration)
 
 	if err != nil {
-		return result, fmt.Errorf("command execution failed")
+		return result, fmt.Errorf("command execution failed: %w", err)
 	}
 
 	return result, nil
@@ -587,7 +875,7 @@ func (c *RealCommandTool) addCommandSpecificEnv(cmd *exec.Cmd, command string) {
 }
 
 // formatCommandResult creates a standardized command result format
-func (c *RealCommandTool) formatCommandResult(command string, args []string, output []byte, err error, duration time.Duration) string {
+func (c *RealCommandTool) formatCommandResult(dir, command string, args []string, output []byte, err error, duration time.Duration) string {
 	var result strings.Builder
 
 	// Command header
@@ -596,7 +884,7 @@ func (c *RealCommandTool) formatCommandResult(command string, args []string, out
 		result.WriteString(fmt.Sprintf(" %s", strings.Join(args, " ")))
 	}
 	result.WriteString(fmt.Sprintf("\nDuration: %v\n", duration.Round(time.Millisecond)))
-	result.WriteString(fmt.Sprintf("Working Directory: %s\n", c.workDir))
+	result.WriteString(fmt.Sprintf("Working Directory: %s\n", dir))
 
 	// Output section
 	if len(output) > 0 {
@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+)
+
+const (
+	defaultSearchResults = 100
+	maxSearchResults     = 1000
+	maxSearchFileSize    = 1 << 20 // Larger files are skipped
+	maxSnippetLength     = 200
+)
+
+// searchSkipDirs are directory names a search never descends into
+var searchSkipDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true}
+
+// searchMatch is a line matching a search query
+type searchMatch struct {
+	Path string `json:"path"` // Slash-separated, relative to the workspace
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// searchResult is the JSON result of a search operation
+type searchResult struct {
+	Matches      []searchMatch `json:"matches"`
+	FilesMatched int           `json:"files_matched"`
+	FilesScanned int           `json:"files_scanned"`
+	Denied       int           `json:"denied"`    // Paths skipped because the security policy denies them
+	Truncated    bool          `json:"truncated"` // The search stopped at max_results
+}
+
+// handleSearch finds lines matching a literal or regular expression query
+// in the files under path
+func (f *RealFileSystemTool) handleSearch(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	query, _ := arguments["query"].(string)
+	if query == "" {
+		return invalidArgument("Error: query parameter is required for search operation"), nil
+	}
+	path, ok := arguments["path"].(string)
+	if !ok {
+		path = "."
+	}
+
+	pattern, err := searchPattern(query, arguments)
+	if err != nil {
+		return invalidArgument(fmt.Sprintf("Error: %v", err)), nil
+	}
+	extensions, err := searchExtensions(arguments)
+	if err != nil {
+		return invalidArgument(fmt.Sprintf("Error: %v", err)), nil
+	}
+	maxResults := defaultSearchResults
+	if value, exists := arguments["max_results"]; exists {
+		number, ok := value.(float64)
+		if !ok || number != float64(int(number)) || number < 1 || number > maxSearchResults {
+			return invalidArgument(fmt.Sprintf("Error: max_results must be an integer between 1 and %d", maxSearchResults)), nil
+		}
+		maxResults = int(number)
+	}
+
+	fullPath := f.resolvePath(path)
+
+	// The scope is validated like a read, which also counts toward the rate
+	// limit; every path below it is then checked against the path restrictions
+	if f.validator != nil {
+		if err := f.validator.ValidateFileOperation(ctx, "read", fullPath); err != nil {
+			return accessDenied(err), nil
+		}
+	}
+	if response := f.checkLinks(fullPath, path); response != nil {
+		return response, nil
+	}
+
+	result := searchResult{Matches: []searchMatch{}}
+	err = filepath.WalkDir(fullPath, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if filePath == fullPath {
+				return err
+			}
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if filePath != fullPath && f.validator != nil && !f.validator.PathAllowed(filePath) {
+			result.Denied++
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			if filePath != fullPath && searchSkipDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || !searchableExtension(filePath, extensions) {
+			return nil
+		}
+
+		matches, scanned := searchFile(filePath, pattern, maxResults-len(result.Matches)+1)
+		if !scanned {
+			return nil
+		}
+		result.FilesScanned++
+		if len(matches) == 0 {
+			return nil
+		}
+
+		relative := filePath
+		if rel, err := filepath.Rel(f.baseDir, filePath); err == nil && !strings.HasPrefix(rel, "..") {
+			relative = rel
+		}
+		for i, match := range matches {
+			if len(result.Matches) == maxResults {
+				result.Truncated = true
+				return filepath.SkipAll
+			}
+			if i == 0 {
+				result.FilesMatched++
+			}
+			match.Path = filepath.ToSlash(relative)
+			result.Matches = append(result.Matches, match)
+			mcp.EmitEvent(ctx, searchMatchEvent, searchMatch{Path: match.Path, Line: match.Line, Text: match.Text})
+		}
+		return nil
+	})
+	if err != nil {
+		return fileError(fmt.Sprintf("Failed to search '%s': %v", path, err), path, err), nil
+	}
+
+	resultJSON, _ := json.Marshal(result)
+	return &mcp.CallToolResponse{
+		Content: []mcp.Content{
+			{
+				Type: "text",
+				Text: formatSearchResult(f.redactor.Redact(query), f.redactor.Redact(path), result, maxResults),
+			},
+			{
+				Type:     "text",
+				Text:     string(resultJSON),
+				MimeType: "application/json",
+			},
+		},
+	}, nil
+}
+
+// searchPattern compiles query, quoting it unless regex is set
+func searchPattern(query string, arguments map[string]interface{}) (*regexp.Regexp, error) {
+	isRegex := false
+	if value, exists := arguments["regex"]; exists {
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("regex must be a boolean")
+		}
+		isRegex = flag
+	}
+	if !isRegex {
+		query = regexp.QuoteMeta(query)
+	}
+	pattern, err := regexp.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return pattern, nil
+}
+
+// searchExtensions returns the file_types filter as lowercase extensions
+// with a leading dot, or nil to search every file
+func searchExtensions(arguments map[string]interface{}) (map[string]bool, error) {
+	value, exists := arguments["file_types"]
+	if !exists {
+		return nil, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("file_types must be an array of strings")
+	}
+	extensions := make(map[string]bool, len(items))
+	for _, item := range items {
+		extension, ok := item.(string)
+		if !ok || strings.TrimPrefix(extension, ".") == "" {
+			return nil, fmt.Errorf("file_types must be an array of non-empty strings")
+		}
+		extensions["."+strings.ToLower(strings.TrimPrefix(extension, "."))] = true
+	}
+	return extensions, nil
+}
+
+// searchableExtension reports whether path passes the extension filter
+func searchableExtension(path string, extensions map[string]bool) bool {
+	return len(extensions) == 0 || extensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// searchFile returns up to limit lines of path matching pattern. Files that
+// are too large, binary, or unreadable aren't scanned.
+func searchFile(path string, pattern *regexp.Regexp, limit int) ([]searchMatch, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxSearchFileSize {
+		return nil, false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(content, 0) >= 0 {
+		return nil, false
+	}
+
+	var matches []searchMatch
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSearchFileSize)
+	for line := 1; scanner.Scan() && len(matches) < limit; line++ {
+		text := scanner.Text()
+		if pattern.MatchString(text) {
+			matches = append(matches, searchMatch{Line: line, Text: searchSnippet(text)})
+		}
+	}
+	return matches, true
+}
+
+// searchSnippet trims a matched line for display
+func searchSnippet(line string) string {
+	line = strings.TrimSpace(line)
+	if len(line) <= maxSnippetLength {
+		return line
+	}
+	cut := maxSnippetLength
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut] + "..."
+}
+
+// formatSearchResult lists matches as path:line: text
+func formatSearchResult(query, path string, result searchResult, maxResults int) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "Search results for %q in %s:\n", query, path)
+	if len(result.Matches) == 0 {
+		text.WriteString("(no matches)\n")
+	}
+	for _, match := range result.Matches {
+		fmt.Fprintf(&text, "%s:%d: %s\n", match.Path, match.Line, match.Text)
+	}
+
+	fmt.Fprintf(&text, "\n%d matches in %d of %d files searched", len(result.Matches), result.FilesMatched, result.FilesScanned)
+	if result.Truncated {
+		fmt.Fprintf(&text, " (stopped at max_results %d)", maxResults)
+	}
+	if result.Denied > 0 {
+		fmt.Fprintf(&text, "; %d paths skipped by security policy", result.Denied)
+	}
+	text.WriteString("\n")
+	return text.String()
+}
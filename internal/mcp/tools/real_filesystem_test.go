@@ -0,0 +1,601 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
+)
+
+// TestRealFileSystemListPagination tests paging through flat and recursive
+// listings with offsets and continuation tokens
+func TestRealFileSystemListPagination(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.txt", i)), []byte("x"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "sub", "deep"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "deep", "nested.txt"), []byte("nested"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tool := NewRealFileSystemTool(dir, nil)
+	list := func(t *testing.T, arguments map[string]interface{}) (string, listPagination) {
+		t.Helper()
+		arguments["operation"] = "list"
+		resp, err := tool.Handle(context.Background(), arguments)
+		if err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+		if resp.IsError {
+			return resp.Content[0].Text, listPagination{}
+		}
+		var pagination listPagination
+		if err := json.Unmarshal([]byte(resp.Content[1].Text), &pagination); err != nil {
+			t.Fatalf("failed to parse pagination: %v", err)
+		}
+		return resp.Content[0].Text, pagination
+	}
+
+	t.Run("pages with cursor", func(t *testing.T) {
+		var names []string
+		arguments := map[string]interface{}{"limit": float64(4)}
+		for pages := 0; pages < 3; pages++ {
+			text, pagination := list(t, arguments)
+			if pagination.Total != 6 {
+				t.Fatalf("total = %d, expected 6:\n%s", pagination.Total, text)
+			}
+			for _, line := range strings.Split(text, "\n") {
+				if strings.HasPrefix(line, "- ") {
+					names = append(names, strings.Fields(line)[1])
+				}
+			}
+			if pagination.NextCursor == "" {
+				break
+			}
+			arguments = map[string]interface{}{"limit": float64(4), "cursor": pagination.NextCursor}
+		}
+		expected := "file0.txt file1.txt file2.txt file3.txt file4.txt sub"
+		if strings.Join(names, " ") != expected {
+			t.Errorf("listed %v, expected %s", names, expected)
+		}
+	})
+
+	t.Run("recursive with cap", func(t *testing.T) {
+		text, pagination := list(t, map[string]interface{}{"path": "sub", "recursive": true})
+		if pagination.Total != 2 || pagination.Capped || !strings.Contains(text, "- deep/nested.txt (file) (6 bytes)") {
+			t.Errorf("unexpected recursive listing %+v:\n%s", pagination, text)
+		}
+
+		text, pagination = list(t, map[string]interface{}{"recursive": true, "max_entries": float64(3), "limit": float64(2)})
+		if pagination.Total != 3 || !pagination.Capped || pagination.NextCursor == "" || !strings.Contains(text, "max_entries 3") {
+			t.Errorf("unexpected capped listing %+v:\n%s", pagination, text)
+		}
+	})
+
+	errorTests := []struct {
+		name      string
+		arguments map[string]interface{}
+		contains  string
+	}{
+		{"limit too large", map[string]interface{}{"limit": float64(maxListLimit + 1)}, "limit must be between"},
+		{"fractional offset", map[string]interface{}{"offset": 1.5}, "offset must be an integer"},
+		{"offset past end", map[string]interface{}{"offset": float64(6)}, "past the end"},
+		{"malformed cursor", map[string]interface{}{"cursor": "!!"}, "invalid cursor"},
+		{"cursor for another listing", map[string]interface{}{"cursor": encodeListCursor(listCursor{Path: "sub", Offset: 1})}, "different listing"},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, _ := list(t, tt.arguments)
+			if !strings.Contains(text, tt.contains) {
+				t.Errorf("expected error containing %q, got %q", tt.contains, text)
+			}
+		})
+	}
+}
+
+// TestRealFileSystemSearch tests literal and regex queries, type filters,
+// the result cap, and that denied paths are skipped
+func TestRealFileSystemSearch(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":          "package main\n\nfunc main() {\n\tRunServer()\n}\n",
+		"server/server.go": "package server\n\n// RunServer starts serving\nfunc RunServer() {}\n",
+		"docs/notes.md":    "Call RunServer() to start.\n",
+		"secrets/token.go": "package secrets\n\nvar RunServer = 1\n",
+		"assets/image.bin": "RunServer\x00\x01",
+		".git/HEAD":        "RunServer\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	policy := security.DefaultRestrictivePolicy(dir)
+	policy.PathRestrictions.DeniedPaths = append(policy.PathRestrictions.DeniedPaths, filepath.Join(dir, "secrets"))
+	tool := NewRealFileSystemTool(dir, security.NewSecurityValidator(policy, "user", "session"))
+
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		matches   []string
+		truncated bool
+		isError   string
+	}{
+		{
+			name:      "literal",
+			arguments: map[string]interface{}{"query": "RunServer()"},
+			matches:   []string{"docs/notes.md:1", "main.go:4", "server/server.go:4"},
+		},
+		{
+			name:      "regex with type filter and scope",
+			arguments: map[string]interface{}{"query": `^func \w+\(`, "regex": true, "file_types": []interface{}{".GO"}, "path": "server"},
+			matches:   []string{"server/server.go:4"},
+		},
+		{
+			name:      "capped",
+			arguments: map[string]interface{}{"query": "RunServer", "max_results": float64(2)},
+			matches:   []string{"docs/notes.md:1", "main.go:4"},
+			truncated: true,
+		},
+		{
+			name:      "invalid regex",
+			arguments: map[string]interface{}{"query": "(", "regex": true},
+			isError:   "invalid regular expression",
+		},
+		{
+			name:      "denied scope",
+			arguments: map[string]interface{}{"query": "RunServer", "path": "secrets"},
+			isError:   "Access denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.arguments["operation"] = "search"
+			resp, err := tool.Handle(context.Background(), tt.arguments)
+			if err != nil {
+				t.Fatalf("Handle failed: %v", err)
+			}
+			if tt.isError != "" {
+				if !resp.IsError || !strings.Contains(resp.Content[0].Text, tt.isError) {
+					t.Errorf("expected error containing %q, got %+v", tt.isError, resp.Content)
+				}
+				return
+			}
+			if resp.IsError {
+				t.Fatalf("unexpected error: %s", resp.Content[0].Text)
+			}
+
+			var result searchResult
+			if err := json.Unmarshal([]byte(resp.Content[1].Text), &result); err != nil {
+				t.Fatalf("failed to parse result: %v", err)
+			}
+			var matches []string
+			for _, match := range result.Matches {
+				matches = append(matches, fmt.Sprintf("%s:%d", match.Path, match.Line))
+			}
+			if strings.Join(matches, " ") != strings.Join(tt.matches, " ") {
+				t.Errorf("matches = %v, expected %v", matches, tt.matches)
+			}
+			if result.Truncated != tt.truncated {
+				t.Errorf("truncated = %v, expected %v", result.Truncated, tt.truncated)
+			}
+			if tt.arguments["path"] == nil && result.Denied != 1 {
+				t.Errorf("denied = %d, expected secrets/ to be skipped", result.Denied)
+			}
+		})
+	}
+}
+
+// TestRealToolErrorCodes tests that each kind of filesystem and command
+// failure carries its error code
+func TestRealToolErrorCodes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "private"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	policy := security.DefaultPermissivePolicy()
+	policy.PathRestrictions.DeniedPaths = []string{filepath.Join(dir, "private")}
+	policy.CommandWhitelist = append(policy.CommandWhitelist, "sleep", "teeny-orb-missing-command")
+	validator := security.NewSecurityValidator(policy, "user", "session")
+	files := NewRealFileSystemTool(dir, validator)
+	commands := NewRealCommandTool(validator, dir)
+
+	limitedPolicy := security.DefaultPermissivePolicy()
+	limitedPolicy.RateLimits.FileOperationsPerMinute = 1
+	limited := NewRealFileSystemTool(dir, security.NewSecurityValidator(limitedPolicy, "user", "session"))
+	limited.Handle(context.Background(), map[string]interface{}{"operation": "read", "path": "file.txt"})
+
+	tests := []struct {
+		name      string
+		tool      mcp.MCPToolHandler
+		arguments map[string]interface{}
+		timeout   time.Duration
+		code      mcp.ErrorCode
+		details   map[string]interface{}
+	}{
+		{"missing operation", files, map[string]interface{}{}, 0, mcp.ErrorCodeInvalidArgument, nil},
+		{"unsupported operation", files, map[string]interface{}{"operation": "delete"}, 0, mcp.ErrorCodeInvalidArgument, nil},
+		{"missing path", files, map[string]interface{}{"operation": "read"}, 0, mcp.ErrorCodeInvalidArgument, nil},
+		{"missing file", files, map[string]interface{}{"operation": "read", "path": "missing.txt"}, 0, mcp.ErrorCodeNotFound, map[string]interface{}{"path": "missing.txt"}},
+		{"read a directory", files, map[string]interface{}{"operation": "read", "path": "."}, 0, mcp.ErrorCodeInvalidArgument, nil},
+		{"list a file", files, map[string]interface{}{"operation": "list", "path": "file.txt"}, 0, mcp.ErrorCodeInvalidArgument, nil},
+		{"denied path", files, map[string]interface{}{"operation": "list", "path": "private"}, 0, mcp.ErrorCodePermissionDenied, nil},
+		{"rate limited", limited, map[string]interface{}{"operation": "read", "path": "file.txt"}, 0, mcp.ErrorCodeRateLimited, nil},
+		{"missing command argument", commands, map[string]interface{}{}, 0, mcp.ErrorCodeInvalidArgument, nil},
+		{"command not allowed", commands, map[string]interface{}{"command": "rm"}, 0, mcp.ErrorCodePermissionDenied, nil},
+		{"command not installed", commands, map[string]interface{}{"command": "teeny-orb-missing-command"}, 0, mcp.ErrorCodeNotFound, nil},
+		{"command fails", commands, map[string]interface{}{"command": "cat", "args": []interface{}{"missing.txt"}}, 0, mcp.ErrorCodeExecutionFailed, map[string]interface{}{"exit_code": 1}},
+		{"command times out", commands, map[string]interface{}{"command": "sleep", "args": []interface{}{"5"}}, 50 * time.Millisecond, mcp.ErrorCodeTimeout, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			resp, err := tt.tool.Handle(ctx, tt.arguments)
+			if err != nil {
+				t.Fatalf("Handle failed: %v", err)
+			}
+			if !resp.IsError || resp.Error == nil {
+				t.Fatalf("expected a coded error, got %+v", resp)
+			}
+			if resp.Error.Code != tt.code {
+				t.Errorf("code = %q, expected %q (%s)", resp.Error.Code, tt.code, resp.Content[0].Text)
+			}
+			if resp.Content[0].Text == "" {
+				t.Error("expected a message for people alongside the code")
+			}
+			for key, expected := range tt.details {
+				if resp.Error.Details[key] != expected {
+					t.Errorf("details[%q] = %v, expected %v", key, resp.Error.Details[key], expected)
+				}
+			}
+		})
+	}
+}
+
+// TestRealFileSystemAuthorizeCachedCall tests that a read answered from the
+// server's cache counts against the file operation rate limit
+func TestRealFileSystemAuthorizeCachedCall(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("x"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	policy := security.DefaultPermissivePolicy()
+	policy.RateLimits.FileOperationsPerMinute = 2
+	tool := NewRealFileSystemTool(dir, security.NewSecurityValidator(policy, "user", "session"))
+	read := map[string]interface{}{"operation": "read", "path": "file.txt"}
+
+	if resp, err := tool.Handle(context.Background(), read); err != nil || resp.IsError {
+		t.Fatalf("read = %+v, %v", resp, err)
+	}
+	if resp := tool.AuthorizeCachedCall(context.Background(), read); resp != nil {
+		t.Fatalf("expected the first cached read allowed, got %+v", resp)
+	}
+	resp := tool.AuthorizeCachedCall(context.Background(), read)
+	if resp == nil || resp.Error == nil || resp.Error.Code != mcp.ErrorCodeRateLimited {
+		t.Errorf("expected the cached read over the limit refused as rate limited, got %+v", resp)
+	}
+}
+
+// TestRealCommandToolWorkingDirectory tests running commands in a
+// subdirectory and rejecting working directories outside the workspace
+func TestRealCommandToolWorkingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "packages", "web"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	for path, content := range map[string]string{"packages/web/package.json": "{}", "notes.txt": "x"} {
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "escape")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	validator := security.NewSecurityValidator(security.DefaultPermissivePolicy(), "user", "session")
+	commands := NewRealCommandTool(validator, dir)
+
+	tests := []struct {
+		name   string
+		cwd    string
+		code   mcp.ErrorCode // Empty when the command runs
+		output string
+	}{
+		{name: "subdirectory", cwd: "packages/web", output: "package.json"},
+		{name: "absolute subdirectory", cwd: filepath.Join(dir, "packages"), output: "web"},
+		{name: "parent", cwd: "..", code: mcp.ErrorCodePermissionDenied},
+		{name: "dot-dot through subdirectory", cwd: "packages/../../", code: mcp.ErrorCodePermissionDenied},
+		{name: "absolute outside", cwd: outside, code: mcp.ErrorCodePermissionDenied},
+		{name: "symlink outside", cwd: "escape", code: mcp.ErrorCodePermissionDenied},
+		{name: "missing", cwd: "packages/api", code: mcp.ErrorCodeNotFound},
+		{name: "file", cwd: "notes.txt", code: mcp.ErrorCodeInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := commands.Handle(context.Background(), map[string]interface{}{"command": "ls", "cwd": tt.cwd})
+			if err != nil {
+				t.Fatalf("Handle failed: %v", err)
+			}
+			if tt.code == "" {
+				if response.IsError || !strings.Contains(response.Content[0].Text, tt.output) {
+					t.Errorf("ls in %s = %+v, expected %s listed", tt.cwd, response, tt.output)
+				}
+				return
+			}
+			if !response.IsError || response.Error == nil || response.Error.Code != tt.code {
+				t.Errorf("ls in %s = %+v, expected error code %s", tt.cwd, response, tt.code)
+			}
+		})
+	}
+
+	denials := 0
+	for _, entry := range validator.GetAuditTrail() {
+		if entry.Result == "denied" && entry.Permission == security.PermissionExecCommand {
+			denials++
+		}
+	}
+	if denials != 4 {
+		t.Errorf("audited %d working directory denials, expected 4", denials)
+	}
+}
+
+// TestRealCommandToolStdin tests that input reaches a prompting command and
+// that without input the prompt fails instead of waiting
+func TestRealCommandToolStdin(t *testing.T) {
+	policy := security.DefaultPermissivePolicy()
+	policy.CommandWhitelist = append(policy.CommandWhitelist, "sh")
+	policy.AllowedPermissions = append(policy.AllowedPermissions, security.PermissionExecSystem)
+	policy.DeniedPermissions = []security.Permission{security.PermissionDeleteFile}
+	commands := NewRealCommandTool(security.NewSecurityValidator(policy, "user", "session"), t.TempDir())
+	prompt := []interface{}{"-c", `printf 'Continue? '; read answer && echo "answered $answer"`}
+
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		code      mcp.ErrorCode // Empty when the command succeeds
+		output    string
+	}{
+		{name: "input", arguments: map[string]interface{}{"command": "sh", "args": prompt, "stdin": "yes\n"}, output: "answered yes"},
+		{name: "no input", arguments: map[string]interface{}{"command": "sh", "args": prompt}, code: mcp.ErrorCodeExecutionFailed},
+		{name: "not a string", arguments: map[string]interface{}{"command": "sh", "args": prompt, "stdin": 1.0}, code: mcp.ErrorCodeInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			response, err := commands.Handle(ctx, tt.arguments)
+			if err != nil {
+				t.Fatalf("Handle failed: %v", err)
+			}
+			if tt.code == "" {
+				if response.IsError || !strings.Contains(response.Content[0].Text, tt.output) {
+					t.Errorf("response = %+v, expected output %q", response, tt.output)
+				}
+				return
+			}
+			if !response.IsError || response.Error == nil || response.Error.Code != tt.code {
+				t.Errorf("response = %+v, expected error code %s", response, tt.code)
+			}
+		})
+	}
+}
+
+// TestRealFileSystemSymlinkPolicy tests that operations through a symlink
+// leading out of the workspace are blocked unless the policy allows them
+func TestRealFileSystemSymlinkPolicy(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	links := map[string]string{
+		"escape":      outside,
+		"secret.txt":  filepath.Join(outside, "secret.txt"),
+		"notes-link":  filepath.Join(dir, "notes.txt"),
+		"dangling.go": filepath.Join(outside, "created.go"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+	}
+	if err := os.Link(filepath.Join(dir, "notes.txt"), filepath.Join(dir, "notes-hardlink")); err != nil {
+		t.Fatalf("failed to create hard link: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		policy    security.SymlinkPolicy
+		arguments map[string]interface{}
+		denied    bool
+	}{
+		{name: "read through file link", policy: security.SymlinkPolicyDenyOutside, arguments: map[string]interface{}{"operation": "read", "path": "secret.txt"}, denied: true},
+		{name: "read through directory link", policy: security.SymlinkPolicyDenyOutside, arguments: map[string]interface{}{"operation": "read", "path": "escape/secret.txt"}, denied: true},
+		{name: "write through directory link", policy: security.SymlinkPolicyDenyOutside, arguments: map[string]interface{}{"operation": "write", "path": "escape/new.txt", "content": "x"}, denied: true},
+		{name: "write through dangling link", policy: security.SymlinkPolicyDenyOutside, arguments: map[string]interface{}{"operation": "write", "path": "dangling.go", "content": "x"}, denied: true},
+		{name: "list through directory link", policy: security.SymlinkPolicyDenyOutside, arguments: map[string]interface{}{"operation": "list", "path": "escape"}, denied: true},
+		{name: "search through directory link", policy: security.SymlinkPolicyDenyOutside, arguments: map[string]interface{}{"operation": "search", "path": "escape", "query": "secret"}, denied: true},
+		{name: "link within workspace", policy: security.SymlinkPolicyDenyOutside, arguments: map[string]interface{}{"operation": "read", "path": "notes-link"}},
+		{name: "link within workspace denied", policy: security.SymlinkPolicyDeny, arguments: map[string]interface{}{"operation": "read", "path": "notes-link"}, denied: true},
+		{name: "hard link denied", policy: security.SymlinkPolicyDeny, arguments: map[string]interface{}{"operation": "read", "path": "notes-hardlink"}, denied: true},
+		{name: "write new file", policy: security.SymlinkPolicyDeny, arguments: map[string]interface{}{"operation": "write", "path": "sub/new.txt", "content": "x"}},
+		{name: "outside allowed", policy: security.SymlinkPolicyAllow, arguments: map[string]interface{}{"operation": "read", "path": "escape/secret.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := NewRealFileSystemTool(dir, nil)
+			tool.SetSymlinkPolicy(tt.policy)
+			response, err := tool.Handle(context.Background(), tt.arguments)
+			if err != nil {
+				t.Fatalf("Handle failed: %v", err)
+			}
+			if !tt.denied {
+				if response.IsError {
+					t.Errorf("response = %q, expected success", response.Content[0].Text)
+				}
+				return
+			}
+			if !response.IsError || response.Error == nil || response.Error.Code != mcp.ErrorCodePermissionDenied {
+				t.Errorf("response = %+v, expected permission denied", response)
+			}
+		})
+	}
+
+	if entries, err := os.ReadDir(outside); err != nil || len(entries) != 1 {
+		t.Errorf("outside directory has %d entries (%v), expected only secret.txt", len(entries), err)
+	}
+}
+
+// TestRealFileSystemReadSizeLimit tests that reads of files over the
+// policy's MaxFileSize are refused, or cut short when truncate is set
+func TestRealFileSystemReadSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	large := strings.Repeat("0123456789abcdef", 4)
+	for name, content := range map[string]string{"large.txt": large, "small.txt": "small"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	policy := security.DefaultPermissivePolicy()
+	policy.ResourceLimits.MaxFileSize = 16
+	tool := NewRealFileSystemTool(dir, security.NewSecurityValidator(policy, "user", "session"))
+
+	read := func(t *testing.T, arguments map[string]interface{}) *mcp.CallToolResponse {
+		t.Helper()
+		arguments["operation"] = "read"
+		resp, err := tool.Handle(context.Background(), arguments)
+		if err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("over the limit", func(t *testing.T) {
+		resp := read(t, map[string]interface{}{"path": "large.txt"})
+		if !resp.IsError || resp.Error == nil || resp.Error.Code != mcp.ErrorCodeTooLarge {
+			t.Fatalf("response = %+v, expected a too_large error", resp)
+		}
+		if resp.Error.Details["size"] != int64(len(large)) || resp.Error.Details["limit"] != int64(16) {
+			t.Errorf("details = %v, expected the size and limit", resp.Error.Details)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		resp := read(t, map[string]interface{}{"path": "large.txt", "truncate": true})
+		if resp.IsError {
+			t.Fatalf("read failed: %s", resp.Content[0].Text)
+		}
+		expected := "File: large.txt\n" + large[:16] + "\n[truncated: showing 16 of 64 bytes]"
+		if resp.Content[0].Text != expected {
+			t.Errorf("text = %q, expected %q", resp.Content[0].Text, expected)
+		}
+	})
+
+	t.Run("under the limit", func(t *testing.T) {
+		resp := read(t, map[string]interface{}{"path": "small.txt"})
+		if resp.IsError || resp.Content[0].Text != "File: small.txt\nsmall" {
+			t.Errorf("response = %+v, expected the whole file", resp)
+		}
+	})
+
+	t.Run("tool limit overrides policy", func(t *testing.T) {
+		tool.SetMaxReadSize(1024)
+		defer tool.SetMaxReadSize(0)
+		resp := read(t, map[string]interface{}{"path": "large.txt"})
+		if resp.IsError || !strings.HasSuffix(resp.Content[0].Text, large) {
+			t.Errorf("response = %+v, expected the whole file", resp)
+		}
+	})
+}
+
+// TestRealFileSystemReadRange tests reading byte and line ranges of a file,
+// and rejecting ranges outside it
+func TestRealFileSystemReadRange(t *testing.T) {
+	dir := t.TempDir()
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %02d", i))
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "app.log"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	policy := security.DefaultPermissivePolicy()
+	policy.ResourceLimits.MaxFileSize = 32
+	tool := NewRealFileSystemTool(dir, security.NewSecurityValidator(policy, "user", "session"))
+
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		expected  string        // Full text of a successful read
+		code      mcp.ErrorCode // Set when the read fails
+	}{
+		{name: "middle lines", arguments: map[string]interface{}{"start_line": float64(4), "end_line": float64(6)}, expected: "File: app.log (lines 4-6)\nline 04\nline 05\nline 06\n"},
+		{name: "tail lines", arguments: map[string]interface{}{"start_line": float64(9)}, expected: "File: app.log (lines 9-10)\nline 09\nline 10\n"},
+		{name: "end past the file", arguments: map[string]interface{}{"start_line": float64(10), "end_line": float64(20)}, expected: "File: app.log (lines 10-10)\nline 10\n"},
+		{name: "byte range", arguments: map[string]interface{}{"offset": float64(8), "length": float64(7)}, expected: "File: app.log (bytes 8-15 of 80)\nline 02"},
+		{name: "bytes to the end held to the limit", arguments: map[string]interface{}{"offset": float64(40)}, expected: "File: app.log (bytes 40-72 of 80)\n" + content[40:72] + "\n[truncated at the 32 byte read limit]"},
+		{name: "start past the file", arguments: map[string]interface{}{"start_line": float64(11)}, code: mcp.ErrorCodeInvalidArgument},
+		{name: "offset past the file", arguments: map[string]interface{}{"offset": float64(81)}, code: mcp.ErrorCodeInvalidArgument},
+		{name: "end before start", arguments: map[string]interface{}{"start_line": float64(5), "end_line": float64(4)}, code: mcp.ErrorCodeInvalidArgument},
+		{name: "bytes and lines", arguments: map[string]interface{}{"offset": float64(8), "start_line": float64(1)}, code: mcp.ErrorCodeInvalidArgument},
+		{name: "lines with the default offset", arguments: map[string]interface{}{"offset": float64(0), "start_line": float64(9)}, expected: "File: app.log (lines 9-10)\nline 09\nline 10\n"},
+		{name: "length over the limit", arguments: map[string]interface{}{"length": float64(64)}, code: mcp.ErrorCodeInvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.arguments["operation"] = "read"
+			tt.arguments["path"] = "app.log"
+			resp, err := tool.Handle(context.Background(), tt.arguments)
+			if err != nil {
+				t.Fatalf("Handle failed: %v", err)
+			}
+			if tt.code != "" {
+				if !resp.IsError || resp.Error == nil || resp.Error.Code != tt.code {
+					t.Errorf("response = %+v, expected error code %s", resp, tt.code)
+				}
+				return
+			}
+			if resp.IsError || resp.Content[0].Text != tt.expected {
+				t.Errorf("text = %q, expected %q", resp.Content[0].Text, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package tools
+
+import (
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
+)
+
+// redactResponse rewrites absolute workspace paths in every text content item
+func redactResponse(redactor *security.PathRedactor, response *mcp.CallToolResponse) *mcp.CallToolResponse {
+	if redactor == nil || response == nil {
+		return response
+	}
+
+	for i := range response.Content {
+		response.Content[i].Text = redactor.Redact(response.Content[i].Text)
+	}
+	if response.Error != nil {
+		for key, value := range response.Error.Details {
+			if text, ok := value.(string); ok {
+				response.Error.Details[key] = redactor.Redact(text)
+			}
+		}
+	}
+	return response
+}
+
+// projectRedactor returns a redactor for the project root, or nil when disabled
+func projectRedactor(enabled bool, root string) *security.PathRedactor {
+	if !enabled {
+		return nil
+	}
+	return security.NewPathRedactor(root)
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	contextpkg "github.com/rcliao/teeny-orb/internal/context"
+)
+
+// TestRealFileSystemToolRedactsReadResult tests that the path a read reports
+// is rewritten relative to the workspace while the file's content is returned
+// as it is, even where it mentions the workspace
+func TestRealFileSystemToolRedactsReadResult(t *testing.T) {
+	workspace := t.TempDir()
+	filePath := filepath.Join(workspace, "notes", "todo.txt")
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte("config lives in "+workspace+"/config.yaml\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		redact   bool
+		contains string
+	}{
+		{name: "enabled by default", redact: true, contains: "File: notes/todo.txt\nconfig lives in " + workspace + "/config.yaml"},
+		{name: "disabled", redact: false, contains: "File: " + filePath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := NewRealFileSystemTool(workspace, nil)
+			if !tt.redact {
+				tool.SetPathRedaction(false)
+			}
+
+			response, err := tool.Handle(context.Background(), map[string]interface{}{
+				"operation": "read",
+				"path":      filePath,
+			})
+			if err != nil || response.IsError {
+				t.Fatalf("read failed: %v %+v", err, response)
+			}
+
+			text := response.Content[0].Text
+			if !strings.Contains(text, tt.contains) {
+				t.Errorf("result %q does not contain %q", text, tt.contains)
+			}
+			if tt.redact && strings.Contains(text, "File: "+workspace) {
+				t.Errorf("result header leaks workspace path %s: %q", workspace, text)
+			}
+		})
+	}
+
+	// A line range reads the same content under the same redacted header
+	response, err := NewRealFileSystemTool(workspace, nil).Handle(context.Background(), map[string]interface{}{
+		"operation":  "read",
+		"path":       filePath,
+		"start_line": float64(1),
+	})
+	if err != nil || response.IsError {
+		t.Fatalf("ranged read failed: %v %+v", err, response)
+	}
+	if text := response.Content[0].Text; !strings.HasPrefix(text, "File: notes/todo.txt (lines 1-1)\nconfig lives in "+workspace+"/config.yaml") {
+		t.Errorf("ranged read = %q, expected a redacted header over the unaltered line", text)
+	}
+}
+
+// TestContextAnalysisHandlerRedactsEmittedContext tests that emitted analysis
+// reports paths relative to the analyzed project
+func TestContextAnalysisHandlerRedactsEmittedContext(t *testing.T) {
+	project := t.TempDir()
+	if err := os.WriteFile(filepath.Join(project, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	analyzer := contextpkg.NewDefaultAnalyzer(contextpkg.NewSimpleTokenCounter(), nil)
+	handler := NewContextAnalysisHandler(analyzer)
+
+	response, err := handler.Handle(context.Background(), map[string]interface{}{
+		"project_path": project,
+	})
+	if err != nil || response.IsError {
+		t.Fatalf("analyze_context failed: %v %+v", err, response)
+	}
+
+	for i, content := range response.Content {
+		if strings.Contains(content.Text, project) {
+			t.Errorf("content %d leaks project path %s", i, project)
+		}
+	}
+	if !strings.Contains(response.Content[0].Text, "**Project Path:** .\n") {
+		t.Errorf("summary should report the project root as \".\": %q", response.Content[0].Text)
+	}
+	if !strings.Contains(response.Content[1].Text, `"path": "main.go"`) {
+		t.Errorf("JSON should list main.go relative to the project: %s", response.Content[1].Text)
+	}
+}
@@ -0,0 +1,381 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
+)
+
+// defaultVerifyTimeout bounds how long a refactor's verification command may run
+const defaultVerifyTimeout = 5 * time.Minute
+
+// RefactorTool applies a set of patches across files as one transaction,
+// optionally verifying the result with a command
+type RefactorTool struct {
+	baseDir       string
+	validator     *security.SecurityValidator
+	redactor      *security.PathRedactor
+	symlinkPolicy security.SymlinkPolicy
+	verifyTimeout time.Duration
+	writeFile     func(name string, data []byte, perm os.FileMode) error
+}
+
+// NewRefactorTool creates a refactor tool rooted at baseDir
+func NewRefactorTool(baseDir string, validator *security.SecurityValidator) *RefactorTool {
+	absBaseDir, err := filepath.Abs(baseDir)
+	if err != nil {
+		absBaseDir = baseDir
+	}
+
+	return &RefactorTool{
+		baseDir:       absBaseDir,
+		validator:     validator,
+		redactor:      security.NewPathRedactor(absBaseDir),
+		symlinkPolicy: security.SymlinkPolicyDenyOutside,
+		verifyTimeout: defaultVerifyTimeout,
+		writeFile:     os.WriteFile,
+	}
+}
+
+// SetPathRedaction enables or disables rewriting absolute workspace paths in results
+func (r *RefactorTool) SetPathRedaction(enabled bool) {
+	r.redactor = projectRedactor(enabled, r.baseDir)
+}
+
+// SetSymlinkPolicy sets which links within the workspace writes may follow
+func (r *RefactorTool) SetSymlinkPolicy(policy security.SymlinkPolicy) {
+	r.symlinkPolicy = policy
+}
+
+// Name returns the tool name
+func (r *RefactorTool) Name() string {
+	return "refactor_apply"
+}
+
+// Timeout returns how long a refactor may run, leaving time to roll back
+// after the verification command times out
+func (r *RefactorTool) Timeout() time.Duration {
+	return r.verifyTimeout + time.Minute
+}
+
+// Description returns the tool description
+func (r *RefactorTool) Description() string {
+	return "Applies patches to multiple files atomically, optionally runs a verification command, and rolls every file back if any patch or the verification fails"
+}
+
+// InputSchema returns the JSON schema for tool inputs
+func (r *RefactorTool) InputSchema() mcp.InputSchema {
+	return mcp.InputSchema{
+		Type: "object",
+		Properties: map[string]interface{}{
+			"patches": map[string]interface{}{
+				"type":        "array",
+				"description": "Patches applied in order; several patches may target the same file",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"path": map[string]interface{}{
+							"type":        "string",
+							"description": "File path relative to the workspace",
+						},
+						"old_text": map[string]interface{}{
+							"type":        "string",
+							"description": "Text to replace, which must occur exactly once. Empty replaces the whole file or creates it.",
+						},
+						"new_text": map[string]interface{}{
+							"type":        "string",
+							"description": "Replacement text",
+						},
+					},
+					"required": []string{"path", "new_text"},
+				},
+			},
+			"verify_command": map[string]interface{}{
+				"type":        "string",
+				"description": "Command run in the workspace after patching, e.g. go (optional)",
+			},
+			"verify_args": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Arguments for the verification command, e.g. [\"build\", \"./...\"] (optional)",
+			},
+		},
+		Required: []string{"patches"},
+	}
+}
+
+// refactorPatch is one search-and-replace edit
+type refactorPatch struct {
+	Path    string
+	OldText string
+	NewText string
+}
+
+// fileChange is the planned new content of one file and what it replaces
+type fileChange struct {
+	path       string // Absolute path
+	display    string // Path as given by the caller
+	existed    bool
+	original   []byte
+	mode       os.FileMode
+	updated    []byte
+	createdDir string // Topmost directory created for the file, removed on rollback
+}
+
+// Handle applies the refactor
+func (r *RefactorTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	tool := r.inWorkspace(ctx)
+	response, err := tool.handle(ctx, arguments)
+	return redactResponse(tool.redactor, response), err
+}
+
+// inWorkspace returns the tool rebased onto the session workspace in ctx, if any
+func (r *RefactorTool) inWorkspace(ctx context.Context) *RefactorTool {
+	workspace := security.WorkspaceFromContext(ctx)
+	if workspace == nil {
+		return r
+	}
+	rebased := *r
+	rebased.baseDir = workspace.BaseDir
+	rebased.validator = workspace.Validator
+	rebased.redactor = projectRedactor(r.redactor != nil, workspace.BaseDir)
+	return &rebased
+}
+
+func (r *RefactorTool) handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	patches, err := parseRefactorPatches(arguments["patches"])
+	if err != nil {
+		return refactorResponse(fmt.Sprintf("Error: %v", err), true), nil
+	}
+
+	verifyCommand, _ := arguments["verify_command"].(string)
+	var verifyArgs []string
+	if argsSlice, ok := arguments["verify_args"].([]interface{}); ok {
+		for _, arg := range argsSlice {
+			if argStr, ok := arg.(string); ok {
+				verifyArgs = append(verifyArgs, argStr)
+			}
+		}
+	}
+
+	if r.validator != nil && verifyCommand != "" {
+		if err := r.validator.ValidateCommandExecution(ctx, verifyCommand, verifyArgs); err != nil {
+			return refactorResponse(fmt.Sprintf("Access denied: %v", err), true), nil
+		}
+	}
+
+	changes, err := r.planChanges(ctx, patches)
+	if err != nil {
+		return refactorResponse(fmt.Sprintf("Refactor not applied: %v", err), true), nil
+	}
+
+	if err := r.applyChanges(changes); err != nil {
+		return refactorResponse(fmt.Sprintf("Refactor rolled back: %v", err), true), nil
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Applied %d patches to %d files:\n", len(patches), len(changes)))
+	for _, change := range changes {
+		result.WriteString(fmt.Sprintf("- %s\n", change.display))
+	}
+
+	if verifyCommand != "" {
+		output, err := r.verify(ctx, verifyCommand, verifyArgs)
+		if err != nil {
+			rollbackErr := r.rollback(changes)
+			text := fmt.Sprintf("Refactor rolled back: verification failed: %v\n%s", err, output)
+			if rollbackErr != nil {
+				text += fmt.Sprintf("\nRollback incomplete: %v", rollbackErr)
+			}
+			return refactorResponse(text, true), nil
+		}
+		result.WriteString(fmt.Sprintf("\nVerification passed: %s\n%s", strings.TrimSpace(verifyCommand+" "+strings.Join(verifyArgs, " ")), output))
+	}
+
+	return refactorResponse(result.String(), false), nil
+}
+
+// parseRefactorPatches converts the patches argument into patches
+func parseRefactorPatches(value interface{}) ([]refactorPatch, error) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, errors.New("patches parameter is required and must be a non-empty array")
+	}
+
+	patches := make([]refactorPatch, 0, len(items))
+	for i, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("patch %d must be an object", i)
+		}
+		path, ok := fields["path"].(string)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("patch %d is missing path", i)
+		}
+		newText, ok := fields["new_text"].(string)
+		if !ok {
+			return nil, fmt.Errorf("patch %d is missing new_text", i)
+		}
+		oldText, _ := fields["old_text"].(string)
+		patches = append(patches, refactorPatch{Path: path, OldText: oldText, NewText: newText})
+	}
+	return patches, nil
+}
+
+// planChanges validates every patch and computes the new file contents
+// without touching the filesystem
+func (r *RefactorTool) planChanges(ctx context.Context, patches []refactorPatch) ([]*fileChange, error) {
+	var changes []*fileChange
+	byPath := make(map[string]*fileChange)
+
+	for i, patch := range patches {
+		fullPath := patch.Path
+		if !filepath.IsAbs(fullPath) {
+			fullPath = filepath.Join(r.baseDir, fullPath)
+		}
+		fullPath = filepath.Clean(fullPath)
+
+		change, exists := byPath[fullPath]
+		if !exists {
+			if err := r.checkWriteTarget(fullPath); err != nil {
+				return nil, fmt.Errorf("patch %d: %w", i, err)
+			}
+			if r.validator != nil {
+				if err := r.validator.ValidateFileOperation(ctx, "write", fullPath); err != nil {
+					return nil, fmt.Errorf("patch %d: %w", i, err)
+				}
+			}
+
+			change = &fileChange{path: fullPath, display: patch.Path, mode: 0644}
+			content, err := os.ReadFile(fullPath)
+			switch {
+			case err == nil:
+				change.existed = true
+				change.original = content
+				if info, err := os.Stat(fullPath); err == nil {
+					change.mode = info.Mode().Perm()
+				}
+			case !os.IsNotExist(err):
+				return nil, fmt.Errorf("patch %d: failed to read %s: %w", i, patch.Path, err)
+			}
+			change.updated = change.original
+			byPath[fullPath] = change
+			changes = append(changes, change)
+		}
+
+		current := string(change.updated)
+		if patch.OldText == "" {
+			change.updated = []byte(patch.NewText)
+			continue
+		}
+		switch count := strings.Count(current, patch.OldText); {
+		case !change.existed && change.updated == nil:
+			return nil, fmt.Errorf("patch %d: %s does not exist", i, patch.Path)
+		case count == 0:
+			return nil, fmt.Errorf("patch %d: old_text not found in %s", i, patch.Path)
+		case count > 1:
+			return nil, fmt.Errorf("patch %d: old_text occurs %d times in %s", i, count, patch.Path)
+		}
+		change.updated = []byte(strings.Replace(current, patch.OldText, patch.NewText, 1))
+	}
+
+	return changes, nil
+}
+
+// applyChanges writes every change, restoring all files if any write fails
+func (r *RefactorTool) applyChanges(changes []*fileChange) error {
+	for i, change := range changes {
+		if err := r.applyChange(change); err != nil {
+			if rollbackErr := r.rollback(changes[:i+1]); rollbackErr != nil {
+				return fmt.Errorf("failed to write %s: %w (rollback incomplete: %v)", change.display, err, rollbackErr)
+			}
+			return fmt.Errorf("failed to write %s: %w", change.display, err)
+		}
+	}
+	return nil
+}
+
+// checkWriteTarget confines a write to the workspace: path must lie within
+// baseDir and stay there once the symlinks the policy allows are followed
+func (r *RefactorTool) checkWriteTarget(path string) error {
+	rel, err := filepath.Rel(r.baseDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return &security.OutsideBaseError{Path: path, BaseDir: r.baseDir}
+	}
+	return security.CheckLinks(r.baseDir, path, r.symlinkPolicy)
+}
+
+// applyChange writes one file, creating missing parent directories. The
+// target is checked again in case a link was swapped in since planning.
+func (r *RefactorTool) applyChange(change *fileChange) error {
+	if err := r.checkWriteTarget(change.path); err != nil {
+		return err
+	}
+	dir := filepath.Dir(change.path)
+	for missing := dir; ; missing = filepath.Dir(missing) {
+		if _, err := os.Stat(missing); err == nil || filepath.Dir(missing) == missing {
+			break
+		}
+		change.createdDir = missing
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return r.writeFile(change.path, change.updated, change.mode)
+}
+
+// rollback restores changed files to their original content and removes
+// files and directories the refactor created
+func (r *RefactorTool) rollback(changes []*fileChange) error {
+	var errs []error
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		var err error
+		switch {
+		case change.existed:
+			err = os.WriteFile(change.path, change.original, change.mode)
+		case change.createdDir != "":
+			err = os.RemoveAll(change.createdDir)
+		default:
+			if err = os.Remove(change.path); os.IsNotExist(err) {
+				err = nil
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore %s: %w", change.display, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verify runs the verification command in the workspace
+func (r *RefactorTool) verify(ctx context.Context, command string, args []string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.verifyTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.Dir = r.baseDir
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+// refactorResponse wraps text in a tool response
+func refactorResponse(text string, isError bool) *mcp.CallToolResponse {
+	return &mcp.CallToolResponse{
+		Content: []mcp.Content{
+			{
+				Type: "text",
+				Text: text,
+			},
+		},
+		IsError: isError,
+	}
+}
@@ -0,0 +1,165 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+)
+
+// TestRefactorToolRollback tests that a failed patch, write, or verification
+// leaves every file as it was
+func TestRefactorToolRollback(t *testing.T) {
+	original := map[string]string{
+		"a.go": "package app\n\nfunc OldName() {}\n",
+		"b.go": "package app\n\nvar _ = OldName\n",
+	}
+	renameA := map[string]interface{}{"path": "a.go", "old_text": "func OldName", "new_text": "func NewName"}
+	renameB := map[string]interface{}{"path": "b.go", "old_text": "= OldName", "new_text": "= NewName"}
+	create := map[string]interface{}{"path": "internal/new/c.go", "new_text": "package new\n"}
+
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		failWrite int // 1-based write to fail, 0 for none
+		isError   bool
+		contains  string
+		expected  map[string]string
+	}{
+		{
+			name:      "applies all patches",
+			arguments: map[string]interface{}{"patches": []interface{}{renameA, renameB, create}, "verify_command": "true"},
+			contains:  "Verification passed",
+			expected: map[string]string{
+				"a.go":              "package app\n\nfunc NewName() {}\n",
+				"b.go":              "package app\n\nvar _ = NewName\n",
+				"internal/new/c.go": "package new\n",
+			},
+		},
+		{
+			name: "patch does not match",
+			arguments: map[string]interface{}{"patches": []interface{}{
+				renameA,
+				map[string]interface{}{"path": "b.go", "old_text": "Missing", "new_text": "x"},
+			}},
+			isError:  true,
+			contains: "old_text not found in b.go",
+			expected: original,
+		},
+		{
+			name:      "write fails",
+			arguments: map[string]interface{}{"patches": []interface{}{renameA, create, renameB}},
+			failWrite: 3,
+			isError:   true,
+			contains:  "failed to write b.go",
+			expected:  original,
+		},
+		{
+			name:      "verification fails",
+			arguments: map[string]interface{}{"patches": []interface{}{renameA, renameB, create}, "verify_command": "false"},
+			isError:   true,
+			contains:  "verification failed",
+			expected:  original,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace := t.TempDir()
+			for name, content := range original {
+				if err := os.WriteFile(filepath.Join(workspace, name), []byte(content), 0644); err != nil {
+					t.Fatalf("Failed to write %s: %v", name, err)
+				}
+			}
+
+			tool := NewRefactorTool(workspace, nil)
+			writes := 0
+			tool.writeFile = func(name string, data []byte, perm os.FileMode) error {
+				writes++
+				if writes == tt.failWrite {
+					return errors.New("disk full")
+				}
+				return os.WriteFile(name, data, perm)
+			}
+
+			response, err := tool.Handle(context.Background(), tt.arguments)
+			if err != nil {
+				t.Fatalf("Handle failed: %v", err)
+			}
+			if response.IsError != tt.isError {
+				t.Errorf("IsError = %v, expected %v: %s", response.IsError, tt.isError, response.Content[0].Text)
+			}
+			if !strings.Contains(response.Content[0].Text, tt.contains) {
+				t.Errorf("response %q does not contain %q", response.Content[0].Text, tt.contains)
+			}
+
+			for name, content := range tt.expected {
+				data, err := os.ReadFile(filepath.Join(workspace, name))
+				if err != nil {
+					t.Errorf("Failed to read %s: %v", name, err)
+				} else if string(data) != content {
+					t.Errorf("%s = %q, expected %q", name, data, content)
+				}
+			}
+			if _, exists := tt.expected["internal/new/c.go"]; !exists {
+				if _, err := os.Stat(filepath.Join(workspace, "internal")); !os.IsNotExist(err) {
+					t.Errorf("created directory was not removed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestWriteToolsStayInWorkspace tests that neither refactor_apply nor
+// apply_edits writes through a symlink leading out of the workspace, or to a
+// sibling directory sharing the workspace's name as a prefix
+func TestWriteToolsStayInWorkspace(t *testing.T) {
+	root := t.TempDir()
+	workspace := filepath.Join(root, "work")
+	outside := filepath.Join(root, "outside")
+	sibling := filepath.Join(root, "work2")
+	for _, dir := range []string{workspace, outside, sibling} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(workspace, "out")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+
+	targets := map[string]string{
+		"symlink out of the workspace": filepath.Join("out", "escaped.go"),
+		"sibling directory":            filepath.Join(sibling, "escaped.go"),
+	}
+	for name, target := range targets {
+		t.Run(name, func(t *testing.T) {
+			edit := map[string]interface{}{"path": target, "content": "package escaped\n", "new_text": "package escaped\n"}
+			responses := map[string]func() (*mcp.CallToolResponse, error){
+				"refactor_apply": func() (*mcp.CallToolResponse, error) {
+					return NewRefactorTool(workspace, nil).Handle(context.Background(), map[string]interface{}{"patches": []interface{}{edit}})
+				},
+				"apply_edits": func() (*mcp.CallToolResponse, error) {
+					return NewApplyEditsTool(workspace, nil).Handle(context.Background(), map[string]interface{}{"edits": []interface{}{edit}})
+				},
+			}
+			for tool, handle := range responses {
+				response, err := handle()
+				if err != nil {
+					t.Fatalf("%s failed: %v", tool, err)
+				}
+				if !response.IsError {
+					t.Errorf("%s wrote %s: %q", tool, target, response.Content[0].Text)
+				}
+			}
+			for _, dir := range []string{outside, sibling} {
+				if _, err := os.Stat(filepath.Join(dir, "escaped.go")); !os.IsNotExist(err) {
+					t.Errorf("expected nothing written to %s, got %v", dir, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
+)
+
+// workspaceScope confines the paths a context tool reads to a base
+// directory, checked by its validator, or to the session workspace in ctx
+// when there is one. The zero value confines nothing, for local servers
+// that analyze whatever path they're given.
+type workspaceScope struct {
+	baseDir   string
+	validator *security.SecurityValidator
+}
+
+// resolve returns path made absolute, after checking that it stays in the
+// workspace once symlinks are followed and that the validator allows
+// operation on it. Relative paths are taken from the workspace.
+func (s workspaceScope) resolve(ctx context.Context, operation, path string) (string, error) {
+	baseDir, validator := s.baseDir, s.validator
+	if workspace := security.WorkspaceFromContext(ctx); workspace != nil {
+		baseDir, validator = workspace.BaseDir, workspace.Validator
+	}
+	if baseDir == "" {
+		return filepath.Abs(path)
+	}
+
+	if _, err := security.ResolveWithin(baseDir, path); err != nil {
+		return "", err
+	}
+	// Keep the path under baseDir as given, so the validator and redaction
+	// see the same prefix as the workspace
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if validator != nil {
+		if err := validator.ValidateFileOperation(ctx, operation, path); err != nil {
+			return "", err
+		}
+	}
+	return path, nil
+}
+
+// scopeError reports a path resolve refused: as not found when it doesn't
+// exist, and otherwise as a denial
+func scopeError(message, path string, err error) *mcp.CallToolResponse {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fileError(fmt.Sprintf("%s: %v", message, err), path, err)
+	}
+	return accessDenied(err)
+}
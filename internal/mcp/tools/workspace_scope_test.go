@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	contextpkg "github.com/rcliao/teeny-orb/internal/context"
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
+)
+
+// TestContextToolsWorkspace tests that context tools confined to a workspace
+// refuse projects and files outside it, including through symlinks and
+// sibling directories sharing its prefix
+func TestContextToolsWorkspace(t *testing.T) {
+	root := t.TempDir()
+	base := filepath.Join(root, "work")
+	for name, content := range map[string]string{
+		"work/app/main.go":    "package main\n",
+		"work2/secret.go":     "package secret\n",
+		"outside/password.go": "package outside\n",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(root, "outside"), filepath.Join(base, "link")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	analyzer := contextpkg.NewCachingAnalyzer(contextpkg.NewDefaultAnalyzer(contextpkg.NewSimpleTokenCounter(), nil), 0)
+	analysis := NewContextAnalysisHandler(analyzer)
+	analysis.SetWorkspace(base, security.NewSecurityValidator(security.DefaultRestrictivePolicy(base), "user", "session"))
+	tokens := NewTokenCountHandler(analyzer)
+	tokens.SetWorkspace(base, security.NewSecurityValidator(security.DefaultRestrictivePolicy(base), "user", "session"))
+
+	tests := []struct {
+		name      string
+		handler   mcp.MCPToolHandler
+		arguments map[string]interface{}
+		code      mcp.ErrorCode // Empty when the call succeeds
+	}{
+		{name: "project in workspace", handler: analysis, arguments: map[string]interface{}{"project_path": "app"}},
+		{name: "project through symlink", handler: analysis, arguments: map[string]interface{}{"project_path": "link"}, code: mcp.ErrorCodePermissionDenied},
+		{name: "parent project", handler: analysis, arguments: map[string]interface{}{"project_path": ".."}, code: mcp.ErrorCodePermissionDenied},
+		{name: "sibling project", handler: analysis, arguments: map[string]interface{}{"project_path": filepath.Join(root, "work2")}, code: mcp.ErrorCodePermissionDenied},
+		{name: "missing project", handler: analysis, arguments: map[string]interface{}{"project_path": "missing"}, code: mcp.ErrorCodeNotFound},
+		{name: "file in workspace", handler: tokens, arguments: map[string]interface{}{"file_path": filepath.Join("app", "main.go")}},
+		{name: "file through symlink", handler: tokens, arguments: map[string]interface{}{"file_path": filepath.Join("link", "password.go")}, code: mcp.ErrorCodePermissionDenied},
+		{name: "file outside", handler: tokens, arguments: map[string]interface{}{"file_path": filepath.Join(root, "work2", "secret.go")}, code: mcp.ErrorCodePermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.handler.Handle(context.Background(), tt.arguments)
+			if err != nil {
+				t.Fatalf("Handle failed: %v", err)
+			}
+			if tt.code == "" {
+				if resp.IsError {
+					t.Errorf("expected success, got %+v", resp)
+				}
+				return
+			}
+			if !resp.IsError || resp.Error == nil || resp.Error.Code != tt.code {
+				t.Errorf("expected a %s error, got %+v", tt.code, resp)
+			}
+		})
+	}
+
+	// A session's workspace replaces the tool's own
+	session := security.NewWorkspace(security.DefaultRestrictivePolicy(filepath.Join(root, "outside")), "user", "other", filepath.Join(root, "outside"))
+	ctx := security.WithWorkspace(context.Background(), session)
+	if resp, _ := tokens.Handle(ctx, map[string]interface{}{"file_path": "password.go"}); resp.IsError {
+		t.Errorf("expected the session workspace to allow its own file, got %+v", resp)
+	}
+	if resp, _ := tokens.Handle(ctx, map[string]interface{}{"file_path": filepath.Join(base, "app", "main.go")}); !resp.IsError {
+		t.Error("expected the session workspace to refuse the tool's own workspace")
+	}
+}
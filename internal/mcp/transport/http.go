@@ -3,11 +3,13 @@ package transport
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -26,6 +28,11 @@ type HTTPTransport struct {
 // HTTPHandler handles HTTP requests for MCP
 type HTTPHandler struct {
 	mcpServer MCPMessageHandler
+	tools     ToolRegistry // nil when the REST tool endpoints are disabled
+	ready     ReadinessCheck
+	readiness ReadinessReporter // nil when the MCP server has no self-checks
+	started   time.Time
+	sessions  bool
 	debug     bool
 	mutex     sync.RWMutex
 }
@@ -35,18 +42,102 @@ type MCPMessageHandler interface {
 	HandleMessage(ctx context.Context, msg *mcp.Message) (*mcp.Message, error)
 }
 
+// BatchHandler processes JSON-RPC batches; MCP servers that implement it
+// accept array payloads at /mcp
+type BatchHandler interface {
+	HandleBatch(ctx context.Context, batch mcp.Batch) []*mcp.Message
+}
+
+// SessionCloser ends MCP sessions; servers that implement it have their
+// sessions closed by DELETE /mcp
+type SessionCloser interface {
+	CloseSession(id string) error
+}
+
+// sessionHeader carries the MCP session ID of streamable HTTP requests
+const sessionHeader = "Mcp-Session-Id"
+
+// ToolRegistry provides direct access to registered tools for the REST endpoints
+type ToolRegistry interface {
+	ListRegisteredTools() []mcp.Tool
+	InvokeTool(ctx context.Context, name string, arguments map[string]interface{}) (*mcp.CallToolResponse, error)
+}
+
+// ReadinessCheck reports whether the server can take traffic; a non-nil
+// error explains why not
+type ReadinessCheck func(ctx context.Context) error
+
+// ReadinessReporter runs named self-checks and returns the failures by name;
+// MCP servers that implement it have /ready list every failing check
+type ReadinessReporter interface {
+	CheckReadiness(ctx context.Context) map[string]error
+}
+
+// HTTPTransportConfig contains optional HTTP transport features
+type HTTPTransportConfig struct {
+	// EnableToolEndpoints serves GET /tools and POST /tools/{name} as a plain
+	// REST alternative to JSON-RPC. The MCP server must implement ToolRegistry.
+	EnableToolEndpoints bool `json:"enable_tool_endpoints"`
+
+	// MetricsHandler is served at GET /metrics when set
+	MetricsHandler http.Handler `json:"-"`
+
+	// Ready gates GET /ready, for example on startup warmup, alongside the
+	// MCP server's own self-checks. Without either the server reports ready
+	// as soon as it is listening.
+	Ready ReadinessCheck `json:"-"`
+
+	// Sessions gives each client that initializes its own MCP session,
+	// identified by the Mcp-Session-Id header. Without it every client
+	// shares one session.
+	Sessions bool `json:"sessions"`
+}
+
+// maxToolRequestBytes bounds the arguments body accepted by POST /tools/{name}
+const maxToolRequestBytes = 10 * 1024 * 1024
+
 // NewHTTPTransport creates a new HTTP transport
 func NewHTTPTransport(addr string, mcpServer MCPMessageHandler, debug bool) *HTTPTransport {
+	return NewHTTPTransportWithConfig(addr, mcpServer, debug, nil)
+}
+
+// NewHTTPTransportWithConfig creates a new HTTP transport with optional features.
+// A nil config enables none of them.
+func NewHTTPTransportWithConfig(addr string, mcpServer MCPMessageHandler, debug bool, config *HTTPTransportConfig) *HTTPTransport {
+	if config == nil {
+		config = &HTTPTransportConfig{}
+	}
+
 	handler := &HTTPHandler{
 		mcpServer: mcpServer,
+		ready:     config.Ready,
+		started:   time.Now(),
+		sessions:  config.Sessions,
 		debug:     debug,
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/mcp", handler.handleMCP)
+	mux.HandleFunc("/mcp/stream", handler.handleStream)
 	mux.HandleFunc("/health", handler.handleHealth)
+	mux.HandleFunc("GET /ready", handler.handleReady)
+	mux.HandleFunc("GET /readyz", handler.handleReady)
 	mux.HandleFunc("/status", handler.handleStatus)
 
+	if reporter, ok := mcpServer.(ReadinessReporter); ok {
+		handler.readiness = reporter
+	}
+
+	if registry, ok := mcpServer.(ToolRegistry); ok && config.EnableToolEndpoints {
+		handler.tools = registry
+		mux.HandleFunc("GET /tools", handler.handleListTools)
+		mux.HandleFunc("POST /tools/{name}", handler.handleCallTool)
+	}
+
+	if config.MetricsHandler != nil {
+		mux.Handle("GET /metrics", config.MetricsHandler)
+	}
+
 	server := &http.Server{
 		Addr:         addr,
 		Handler:      mux,
@@ -122,8 +213,9 @@ func (h *HTTPTransport) Close() error {
 func (h *HTTPHandler) handleMCP(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers for web clients
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+sessionHeader)
+	w.Header().Set("Access-Control-Expose-Headers", sessionHeader)
 	w.Header().Set("Content-Type", "application/json")
 	// Keep connection alive for mcp-remote
 	w.Header().Set("Connection", "keep-alive")
@@ -134,6 +226,11 @@ func (h *HTTPHandler) handleMCP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.Method == "DELETE" && h.sessions {
+		h.closeSession(w, r)
+		return
+	}
+
 	// Only allow POST requests for MCP
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -155,6 +252,15 @@ func (h *HTTPHandler) handleMCP(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(os.Stderr, "Received HTTP MCP request: %s\n", string(body))
 	}
 
+	if h.sessions {
+		r = r.WithContext(mcp.WithSessionID(r.Context(), h.sessionID(w, r, body)))
+	}
+
+	if mcp.IsBatch(body) {
+		h.handleBatch(w, r, body)
+		return
+	}
+
 	// Parse MCP message
 	var mcpRequest mcp.Message
 	if err := json.Unmarshal(body, &mcpRequest); err != nil {
@@ -222,7 +328,105 @@ func (h *HTTPHandler) handleMCP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleHealth handles health check requests
+// sessionID returns the session a request belongs to. An initialize request
+// always starts a new session, so clients can't choose their IDs; the ID is
+// returned in the response header for the client to send with later requests.
+func (h *HTTPHandler) sessionID(w http.ResponseWriter, r *http.Request, body []byte) string {
+	if !containsInitialize(body) {
+		return r.Header.Get(sessionHeader)
+	}
+
+	id := rand.Text()
+	w.Header().Set(sessionHeader, id)
+	return id
+}
+
+// closeSession ends the session named by the request header
+func (h *HTTPHandler) closeSession(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(sessionHeader)
+	closer, ok := h.mcpServer.(SessionCloser)
+	if id == "" || !ok {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if err := closer.CloseSession(id); err != nil {
+		if h.debug {
+			fmt.Fprintf(os.Stderr, "Error closing session: %v\n", err)
+		}
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// containsInitialize reports whether a message or batch includes an
+// initialize request
+func containsInitialize(body []byte) bool {
+	type method struct {
+		Method string `json:"method"`
+	}
+	var messages []method
+	if mcp.IsBatch(body) {
+		json.Unmarshal(body, &messages)
+	} else {
+		var single method
+		json.Unmarshal(body, &single)
+		messages = append(messages, single)
+	}
+	for _, message := range messages {
+		if message.Method == "initialize" {
+			return true
+		}
+	}
+	return false
+}
+
+// handleBatch answers a JSON-RPC batch with an array of the responses, or an
+// empty 202 when the batch held only notifications
+func (h *HTTPHandler) handleBatch(w http.ResponseWriter, r *http.Request, body []byte) {
+	var batch mcp.Batch
+	if err := json.Unmarshal(body, &batch); err != nil {
+		writeJSON(w, http.StatusOK, &mcp.Message{
+			JSONRPC: "2.0",
+			Error: &mcp.Error{
+				Code:    mcp.ParseError,
+				Message: "Invalid JSON-RPC batch",
+			},
+		})
+		return
+	}
+
+	batcher, ok := h.mcpServer.(BatchHandler)
+	if len(batch) == 0 || !ok {
+		message := "Empty batch"
+		if !ok {
+			message = "Batch requests are not supported"
+		}
+		writeJSON(w, http.StatusOK, &mcp.Message{
+			JSONRPC: "2.0",
+			Error: &mcp.Error{
+				Code:    mcp.InvalidRequest,
+				Message: message,
+			},
+		})
+		return
+	}
+
+	responses := batcher.HandleBatch(r.Context(), batch)
+	if len(responses) == 0 {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if h.debug {
+		fmt.Fprintf(os.Stderr, "Sending %d HTTP MCP batch responses\n", len(responses))
+	}
+	writeJSON(w, http.StatusOK, responses)
+}
+
+// handleHealth is the liveness probe: it answers as long as the process can
+// serve requests, whatever the state of its tools
 func (h *HTTPHandler) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -231,27 +435,70 @@ func (h *HTTPHandler) handleHealth(w http.ResponseWriter, r *http.Request) {
 		"status":    "healthy",
 		"service":   "teeny-orb-mcp-server",
 		"timestamp": time.Now().Format(time.RFC3339),
-		"uptime":    time.Since(time.Now()).String(),
+		"uptime":    time.Since(h.started).String(),
 	}
 	
 	json.NewEncoder(w).Encode(healthResponse)
 }
 
+// failedCheck is a readiness check that didn't pass
+type failedCheck struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// handleReady is the readiness probe: 503 with the failing checks listed
+// until the startup check and every self-check pass
+func (h *HTTPHandler) handleReady(w http.ResponseWriter, r *http.Request) {
+	failures := make(map[string]error)
+	if h.readiness != nil {
+		for name, err := range h.readiness.CheckReadiness(r.Context()) {
+			failures[name] = err
+		}
+	}
+	if h.ready != nil {
+		if err := h.ready(r.Context()); err != nil {
+			failures["startup"] = err
+		}
+	}
+
+	if len(failures) > 0 {
+		failed := make([]failedCheck, 0, len(failures))
+		for name, err := range failures {
+			failed = append(failed, failedCheck{Name: name, Error: err.Error()})
+		}
+		sort.Slice(failed, func(i, j int) bool { return failed[i].Name < failed[j].Name })
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status": "not ready",
+			"failed": failed,
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ready"})
+}
+
 // handleStatus handles status requests with detailed information
 func (h *HTTPHandler) handleStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	
+	endpoints := map[string]string{
+		"mcp":    "/mcp",
+		"health": "/health",
+		"ready":  "/ready",
+		"status": "/status",
+	}
+	if h.tools != nil {
+		endpoints["tools"] = "/tools"
+	}
+	
 	statusResponse := map[string]interface{}{
 		"service":   "teeny-orb-mcp-server",
 		"version":   "0.1.0",
 		"protocol":  "MCP 2024-11-05",
 		"transport": "HTTP",
-		"endpoints": map[string]string{
-			"mcp":    "/mcp",
-			"health": "/health",
-			"status": "/status",
-		},
+		"endpoints": endpoints,
 		"capabilities": []string{
 			"tools",
 			"filesystem",
@@ -264,6 +511,68 @@ func (h *HTTPHandler) handleStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(statusResponse)
 }
 
+// handleListTools returns the registered tools and their input schemas
+func (h *HTTPHandler) handleListTools(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, mcp.ListToolsResponse{Tools: h.tools.ListRegisteredTools()})
+}
+
+// handleCallTool invokes a tool with the JSON object in the request body as its arguments
+func (h *HTTPHandler) handleCallTool(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	registered := false
+	for _, tool := range h.tools.ListRegisteredTools() {
+		if tool.Name == name {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("tool not found: %s", name)})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxToolRequestBytes))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("failed to read request body: %v", err)})
+		return
+	}
+	defer r.Body.Close()
+
+	arguments := map[string]interface{}{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &arguments); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("arguments must be a JSON object: %v", err)})
+			return
+		}
+	}
+
+	if h.debug {
+		fmt.Fprintf(os.Stderr, "REST tool call %s: %s\n", name, string(body))
+	}
+
+	// Calls run in the session the header names, as over JSON-RPC
+	ctx := r.Context()
+	if h.sessions {
+		ctx = mcp.WithSessionID(ctx, r.Header.Get(sessionHeader))
+	}
+	response, err := h.tools.InvokeTool(ctx, name, arguments)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Tool-level failures are reported in the body through IsError, as over JSON-RPC
+	writeJSON(w, http.StatusOK, response)
+}
+
+// writeJSON writes value as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
 // HTTPClient provides a client for making HTTP requests to MCP server
 type HTTPClient struct {
 	baseURL    string
@@ -0,0 +1,465 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	contextpkg "github.com/rcliao/teeny-orb/internal/context"
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/metrics"
+	"github.com/rcliao/teeny-orb/internal/mcp/security"
+	"github.com/rcliao/teeny-orb/internal/mcp/server"
+	"github.com/rcliao/teeny-orb/internal/mcp/tools"
+)
+
+// echoTool returns its "text" argument
+type echoTool struct{}
+
+func (echoTool) Name() string        { return "echo" }
+func (echoTool) Description() string { return "Echoes text" }
+func (echoTool) InputSchema() mcp.InputSchema {
+	return mcp.InputSchema{
+		Type:       "object",
+		Properties: map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+		Required:   []string{"text"},
+	}
+}
+func (echoTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	text, ok := arguments["text"].(string)
+	if !ok {
+		return &mcp.CallToolResponse{Content: []mcp.Content{{Type: "text", Text: "text is required"}}, IsError: true}, nil
+	}
+	return &mcp.CallToolResponse{Content: []mcp.Content{{Type: "text", Text: text}}}, nil
+}
+
+// TestHTTPToolEndpoints tests listing and invoking tools over REST
+func TestHTTPToolEndpoints(t *testing.T) {
+	mcpServer := server.NewServer("test", "0.0.0")
+	if err := mcpServer.RegisterTool(echoTool{}); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+
+	newHandler := func(enabled bool) http.Handler {
+		transport := NewHTTPTransportWithConfig("localhost:0", mcpServer, false, &HTTPTransportConfig{EnableToolEndpoints: enabled})
+		return transport.server.Handler
+	}
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		method   string
+		path     string
+		body     string
+		status   int
+		contains string
+	}{
+		{name: "list tools", enabled: true, method: "GET", path: "/tools", status: http.StatusOK, contains: `"inputSchema":{"type":"object"`},
+		{name: "call tool", enabled: true, method: "POST", path: "/tools/echo", body: `{"text":"hi"}`, status: http.StatusOK, contains: `"text":"hi"`},
+		{name: "tool error", enabled: true, method: "POST", path: "/tools/echo", body: "", status: http.StatusOK, contains: `"isError":true`},
+		{name: "unknown tool", enabled: true, method: "POST", path: "/tools/missing", body: `{}`, status: http.StatusNotFound, contains: "tool not found"},
+		{name: "non-object body", enabled: true, method: "POST", path: "/tools/echo", body: `["hi"]`, status: http.StatusBadRequest, contains: "JSON object"},
+		{name: "disabled", enabled: false, method: "GET", path: "/tools", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			newHandler(tt.enabled).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, expected %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.contains != "" && !strings.Contains(rec.Body.String(), tt.contains) {
+				t.Errorf("body %s does not contain %s", rec.Body.String(), tt.contains)
+			}
+		})
+	}
+
+	// The listing reports registered tools by name
+	rec := httptest.NewRecorder()
+	newHandler(true).ServeHTTP(rec, httptest.NewRequest("GET", "/tools", nil))
+	var listing mcp.ListToolsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil {
+		t.Fatalf("invalid listing: %v", err)
+	}
+	if len(listing.Tools) != 1 || listing.Tools[0].Name != "echo" {
+		t.Errorf("listing = %+v, expected the echo tool", listing.Tools)
+	}
+}
+
+// TestHTTPMetrics tests that JSON-RPC traffic is counted and served at /metrics
+func TestHTTPMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	serverMetrics, err := metrics.New(registry)
+	if err != nil {
+		t.Fatalf("metrics.New failed: %v", err)
+	}
+
+	mcpServer := server.NewServer("test", "0.0.0")
+	mcpServer.SetMetrics(serverMetrics)
+	if err := mcpServer.RegisterTool(echoTool{}); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+	handler := NewHTTPTransportWithConfig("localhost:0", mcpServer, false, &HTTPTransportConfig{
+		MetricsHandler: serverMetrics.Handler(),
+	}).server.Handler
+
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"missing"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"missing/2"}`,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("POST", "/mcp", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d for %s: %s", rec.Code, body, rec.Body.String())
+		}
+	}
+
+	expected := `
+# HELP mcp_requests_total MCP requests handled, by JSON-RPC method.
+# TYPE mcp_requests_total counter
+mcp_requests_total{method="initialize"} 1
+mcp_requests_total{method="tools/call"} 2
+mcp_requests_total{method="unknown"} 2
+# HELP mcp_tool_errors_total Tool calls that failed or returned an error result, by tool name.
+# TYPE mcp_tool_errors_total counter
+mcp_tool_errors_total{tool="echo"} 1
+# HELP mcp_errors_total JSON-RPC error responses, by error code.
+# TYPE mcp_errors_total counter
+mcp_errors_total{code="-32601"} 2
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"mcp_requests_total", "mcp_tool_errors_total", "mcp_errors_total"); err != nil {
+		t.Error(err)
+	}
+	if count := testutil.CollectAndCount(registry, "mcp_tool_call_duration_seconds"); count != 1 {
+		t.Errorf("got %d tool latency series, expected 1", count)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), `mcp_tool_call_duration_seconds_count{tool="echo"} 2`) {
+		t.Errorf("/metrics is missing tool latency: %s", rec.Body.String())
+	}
+}
+
+// blockingAnalyzer holds AnalyzeProject until release is closed
+type blockingAnalyzer struct {
+	contextpkg.ContextAnalyzer
+	release chan struct{}
+}
+
+func (a *blockingAnalyzer) AnalyzeProject(ctx context.Context, rootPath string) (*contextpkg.ProjectContext, error) {
+	<-a.release
+	return a.ContextAnalyzer.AnalyzeProject(ctx, rootPath)
+}
+
+// TestHTTPReadyz tests that /readyz reports 503 until startup warmup finishes
+func TestHTTPReadyz(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	analyzer := &blockingAnalyzer{
+		ContextAnalyzer: contextpkg.NewDefaultAnalyzer(contextpkg.NewSimpleTokenCounter(), nil),
+		release:         make(chan struct{}),
+	}
+	warmer := contextpkg.NewWarmer(analyzer, &contextpkg.WarmupConfig{RootPath: root})
+
+	newHandler := func(ready ReadinessCheck) http.Handler {
+		return NewHTTPTransportWithConfig("localhost:0", server.NewServer("test", "0.0.0"), false, &HTTPTransportConfig{
+			Ready: ready,
+		}).server.Handler
+	}
+	readyz := func(handler http.Handler) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+		return rec
+	}
+
+	if rec := readyz(newHandler(nil)); rec.Code != http.StatusOK {
+		t.Errorf("status without a readiness check = %d, expected 200", rec.Code)
+	}
+
+	handler := newHandler(warmer.Ready)
+	done := make(chan error)
+	go func() {
+		_, err := warmer.Run(context.Background())
+		done <- err
+	}()
+
+	rec := readyz(handler)
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "warmup in progress") {
+		t.Errorf("status during warmup = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	close(analyzer.release)
+	if err := <-done; err != nil {
+		t.Fatalf("warmup failed: %v", err)
+	}
+
+	rec = readyz(handler)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"ready"`) {
+		t.Errorf("status after warmup = %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// probedTool is an echo tool with a readiness check that fails until ready is set
+type probedTool struct {
+	echoTool
+	ready *bool
+}
+
+func (probedTool) Name() string { return "probed" }
+
+func (t probedTool) CheckReady(ctx context.Context) error {
+	if !*t.ready {
+		return fmt.Errorf("backend unreachable")
+	}
+	return nil
+}
+
+// TestHTTPReady tests that /ready lists each failing self-check while /health
+// keeps reporting the process alive
+func TestHTTPReady(t *testing.T) {
+	mcpServer := server.NewServer("test", "0.0.0")
+	toolReady := false
+	for _, tool := range []mcp.MCPToolHandler{echoTool{}, probedTool{ready: &toolReady}} {
+		if err := mcpServer.RegisterTool(tool); err != nil {
+			t.Fatalf("RegisterTool failed: %v", err)
+		}
+	}
+	workspace := filepath.Join(t.TempDir(), "workspace")
+	mcpServer.AddReadinessProbe("workspace", server.WritableDirProbe(workspace))
+	handler := NewHTTPTransport("localhost:0", mcpServer, false).server.Handler
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	rec := get("/ready")
+	var body struct {
+		Status string `json:"status"`
+		Failed []struct {
+			Name  string `json:"name"`
+			Error string `json:"error"`
+		} `json:"failed"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode /ready body %q: %v", rec.Body.String(), err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || body.Status != "not ready" {
+		t.Errorf("/ready = %d %q, expected 503 not ready", rec.Code, body.Status)
+	}
+	var names []string
+	for _, check := range body.Failed {
+		names = append(names, check.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"tool:probed", "workspace"}) {
+		t.Errorf("failed checks = %v, expected tool:probed and workspace", body.Failed)
+	}
+	if rec := get("/health"); rec.Code != http.StatusOK {
+		t.Errorf("/health = %d while not ready, expected 200", rec.Code)
+	}
+
+	toolReady = true
+	if err := os.Mkdir(workspace, 0755); err != nil {
+		t.Fatalf("Failed to create workspace: %v", err)
+	}
+	if rec := get("/ready"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"ready"`) {
+		t.Errorf("/ready after fixing checks = %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestHTTPBatch tests that a JSON-RPC batch is answered with one response per
+// request, in order, and that a batch of only notifications gets no body
+func TestHTTPBatch(t *testing.T) {
+	mcpServer := server.NewServer("test", "0.0.0")
+	if err := mcpServer.RegisterTool(echoTool{}); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+	handler := NewHTTPTransport("localhost:0", mcpServer, false).server.Handler
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		ids    []interface{}
+		codes  []int // Error code per response, 0 for success
+	}{
+		{
+			name: "mixed batch",
+			body: `[
+				{"jsonrpc":"2.0","id":0,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}},
+				{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"text":"a"}}},
+				{"jsonrpc":"2.0","method":"notifications/initialized"},
+				{"jsonrpc":"2.0","id":2,"method":"no/such/method"},
+				42,
+				{"jsonrpc":"2.0","id":"three","method":"tools/list"}
+			]`,
+			status: http.StatusOK,
+			ids:    []interface{}{float64(0), float64(1), float64(2), nil, "three"},
+			codes:  []int{0, 0, mcp.MethodNotFound, mcp.InvalidRequest, 0},
+		},
+		{
+			name:   "only notifications",
+			body:   `[{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","method":"notifications/cancelled"}]`,
+			status: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest("POST", "/mcp", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, expected %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.ids == nil {
+				if rec.Body.Len() != 0 {
+					t.Errorf("body = %q, expected none", rec.Body.String())
+				}
+				return
+			}
+
+			var responses []mcp.Message
+			if err := json.Unmarshal(rec.Body.Bytes(), &responses); err != nil {
+				t.Fatalf("response is not an array: %s", rec.Body.String())
+			}
+			if len(responses) != len(tt.ids) {
+				t.Fatalf("got %d responses, expected %d: %s", len(responses), len(tt.ids), rec.Body.String())
+			}
+			for i, response := range responses {
+				if response.ID != tt.ids[i] {
+					t.Errorf("response %d id = %v, expected %v", i, response.ID, tt.ids[i])
+				}
+				code := 0
+				if response.Error != nil {
+					code = response.Error.Code
+				}
+				if code != tt.codes[i] {
+					t.Errorf("response %d error code = %d, expected %d", i, code, tt.codes[i])
+				}
+			}
+		})
+	}
+}
+
+// TestHTTPSessionsIsolateWorkspaces tests that two sessions with their own
+// base directories resolve paths in their own workspace and can't read each
+// other's files
+func TestHTTPSessionsIsolateWorkspaces(t *testing.T) {
+	shared := t.TempDir()
+	policy := &security.SecurityPolicy{
+		AllowedPermissions: []security.Permission{security.PermissionReadFile, security.PermissionWriteFile},
+	}
+
+	mcpServer := server.NewServer("test", "0.0.0")
+	if err := mcpServer.RegisterTool(tools.NewRealFileSystemTool(shared, security.NewSecurityValidator(policy, "test", "shared"))); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+	dirs := map[string]string{}
+	mcpServer.SetWorkspaceFactory(func(sessionID string) (*security.Workspace, error) {
+		dirs[sessionID] = filepath.Join(t.TempDir(), "workspace")
+		return security.NewScratchWorkspace(policy, "test", sessionID, dirs[sessionID])
+	})
+	handler := NewHTTPTransportWithConfig("localhost:0", mcpServer, false, &HTTPTransportConfig{Sessions: true}).server.Handler
+
+	post := func(sessionID, body string) (*httptest.ResponseRecorder, string) {
+		req := httptest.NewRequest("POST", "/mcp", strings.NewReader(body))
+		if sessionID != "" {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var response mcp.Message
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("invalid response to %s: %s", body, rec.Body.String())
+		}
+		if response.Error != nil {
+			return rec, response.Error.Message
+		}
+		var result mcp.CallToolResponse
+		json.Unmarshal(response.Result, &result)
+		if len(result.Content) == 0 {
+			return rec, ""
+		}
+		return rec, result.Content[0].Text
+	}
+	callFilesystem := func(sessionID, arguments string) string {
+		_, text := post(sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"filesystem","arguments":`+arguments+`}}`)
+		return text
+	}
+
+	var sessions []string
+	for _, secret := range []string{"alpha", "beta"} {
+		rec, _ := post("", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`)
+		id := rec.Header().Get("Mcp-Session-Id")
+		if id == "" {
+			t.Fatal("initialize did not return a session ID")
+		}
+		sessions = append(sessions, id)
+
+		if text := callFilesystem(id, `{"operation":"write","path":"secret.txt","content":"`+secret+`"}`); !strings.Contains(text, "Successfully wrote") {
+			t.Fatalf("write in session %s failed: %s", secret, text)
+		}
+	}
+	alpha, beta := sessions[0], sessions[1]
+	if alpha == beta {
+		t.Fatal("sessions share an ID")
+	}
+
+	// Relative paths resolve in each session's own workspace
+	if text := callFilesystem(alpha, `{"operation":"read","path":"secret.txt"}`); !strings.HasSuffix(text, "alpha") {
+		t.Errorf("alpha read %q, expected its own file", text)
+	}
+	if text := callFilesystem(beta, `{"operation":"read","path":"secret.txt"}`); !strings.HasSuffix(text, "beta") {
+		t.Errorf("beta read %q, expected its own file", text)
+	}
+	if _, err := os.Stat(filepath.Join(shared, "secret.txt")); !os.IsNotExist(err) {
+		t.Errorf("session write reached the shared directory: %v", err)
+	}
+
+	// Absolute paths into the other session's workspace are denied
+	otherFile, _ := json.Marshal(filepath.Join(dirs[beta], "secret.txt"))
+	if text := callFilesystem(alpha, `{"operation":"read","path":`+string(otherFile)+`}`); !strings.Contains(text, "Access denied") {
+		t.Errorf("alpha reading beta's file = %q, expected access denied", text)
+	}
+
+	// Ending a session removes its workspace and requires a new initialize
+	req := httptest.NewRequest("DELETE", "/mcp", nil)
+	req.Header.Set("Mcp-Session-Id", beta)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d, expected %d", rec.Code, http.StatusNoContent)
+	}
+	if _, err := os.Stat(dirs[beta]); !os.IsNotExist(err) {
+		t.Errorf("closed session's workspace still exists: %v", err)
+	}
+	if text := callFilesystem(beta, `{"operation":"read","path":"secret.txt"}`); !strings.Contains(text, "not initialized") {
+		t.Errorf("call after DELETE = %q, expected not initialized", text)
+	}
+}
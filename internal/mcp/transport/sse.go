@@ -0,0 +1,230 @@
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+)
+
+// errStreamClosed is returned for notifications sent after a stream ended
+var errStreamClosed = errors.New("event stream closed")
+
+// sseStream writes JSON-RPC messages as Server-Sent Events. Notifications may
+// come from any goroutine handling the request, so writes are serialized.
+type sseStream struct {
+	mutex      sync.Mutex
+	writer     io.Writer
+	controller *http.ResponseController
+	cancel     context.CancelFunc // Cancels the request once the client is gone
+	closed     bool
+}
+
+// send writes msg as a "message" event and flushes it to the client
+func (s *sseStream) send(ctx context.Context, msg *mcp.Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return errStreamClosed
+	}
+	if _, err := fmt.Fprintf(s.writer, "event: message\ndata: %s\n\n", data); err != nil {
+		s.cancel()
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	if err := s.controller.Flush(); err != nil {
+		s.cancel()
+		return fmt.Errorf("failed to flush event: %w", err)
+	}
+	return nil
+}
+
+// close stops later notifications from writing to a finished response
+func (s *sseStream) close() {
+	s.mutex.Lock()
+	s.closed = true
+	s.mutex.Unlock()
+}
+
+// handleStream answers a JSON-RPC message or batch with a Server-Sent Events
+// stream of the notifications raised while handling it, then its responses.
+// The request is cancelled when the client closes the stream.
+func (h *HTTPHandler) handleStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+sessionHeader)
+	w.Header().Set("Access-Control-Expose-Headers", sessionHeader)
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if h.debug {
+		fmt.Fprintf(os.Stderr, "Received streaming MCP request: %s\n", string(body))
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	if h.sessions {
+		ctx = mcp.WithSessionID(ctx, h.sessionID(w, r, body))
+	}
+
+	controller := http.NewResponseController(w)
+	// A tool call may outlast the server's write timeout; the client closing
+	// the stream ends it instead
+	controller.SetWriteDeadline(time.Time{})
+
+	stream := &sseStream{writer: w, controller: controller, cancel: cancel}
+	defer stream.close()
+	ctx = mcp.WithRequestNotifier(ctx, stream.send)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	controller.Flush()
+
+	for _, response := range h.streamResponses(ctx, body) {
+		if err := stream.send(ctx, response); err != nil {
+			if h.debug {
+				fmt.Fprintf(os.Stderr, "Error streaming MCP response: %v\n", err)
+			}
+			return
+		}
+	}
+}
+
+// streamResponses handles a message or batch, returning the responses to
+// send; notifications have none
+func (h *HTTPHandler) streamResponses(ctx context.Context, body []byte) []*mcp.Message {
+	if mcp.IsBatch(body) {
+		var batch mcp.Batch
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return []*mcp.Message{rpcError(nil, mcp.ParseError, "Invalid JSON-RPC batch")}
+		}
+		batcher, ok := h.mcpServer.(BatchHandler)
+		if !ok {
+			return []*mcp.Message{rpcError(nil, mcp.InvalidRequest, "Batch requests are not supported")}
+		}
+		if len(batch) == 0 {
+			return []*mcp.Message{rpcError(nil, mcp.InvalidRequest, "Empty batch")}
+		}
+		return batcher.HandleBatch(ctx, batch)
+	}
+
+	var request mcp.Message
+	if err := json.Unmarshal(body, &request); err != nil {
+		return []*mcp.Message{rpcError(nil, mcp.ParseError, "Invalid JSON-RPC message")}
+	}
+	response, err := h.mcpServer.HandleMessage(ctx, &request)
+	if err != nil {
+		return []*mcp.Message{rpcError(request.ID, mcp.InternalError, err.Error())}
+	}
+	if response == nil {
+		return nil
+	}
+	return []*mcp.Message{response}
+}
+
+// rpcError builds a JSON-RPC error response
+func rpcError(id interface{}, code int, message string) *mcp.Message {
+	return &mcp.Message{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error: &mcp.Error{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+// StreamMessage sends an MCP message to the streaming endpoint, passing each
+// notification that arrives before the response to onNotification.
+// Cancelling ctx closes the stream, which cancels the request on the server.
+// A notification sent alone yields a nil response.
+func (c *HTTPClient) StreamMessage(ctx context.Context, message *mcp.Message, onNotification func(*mcp.Message)) (*mcp.Message, error) {
+	requestData, err := json.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/mcp/stream", bytes.NewBuffer(requestData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+
+	// The stream lasts as long as the request takes, so only ctx bounds it
+	client := *c.httpClient
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("HTTP error: %d - %s", resp.StatusCode, string(data))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxToolRequestBytes)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(value, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+
+		var event mcp.Message
+		if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+			return nil, fmt.Errorf("failed to parse event: %w", err)
+		}
+		data.Reset()
+		if c.debug {
+			fmt.Fprintf(os.Stderr, "Received streamed message: %+v\n", event)
+		}
+		if event.ID == nil && event.Method != "" {
+			if onNotification != nil {
+				onNotification(&event)
+			}
+			continue
+		}
+		return &event, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	return nil, nil
+}
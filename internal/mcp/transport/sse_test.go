@@ -0,0 +1,200 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/server"
+)
+
+// progressTool reports two steps of progress before finishing
+type progressTool struct{}
+
+func (progressTool) Name() string                 { return "progress" }
+func (progressTool) Description() string          { return "Reports progress" }
+func (progressTool) InputSchema() mcp.InputSchema { return mcp.InputSchema{Type: "object"} }
+func (progressTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	for step := 1; step <= 2; step++ {
+		if err := mcp.ReportProgress(ctx, float64(step), 2, "working"); err != nil {
+			return nil, err
+		}
+	}
+	return &mcp.CallToolResponse{Content: []mcp.Content{{Type: "text", Text: "done"}}}, nil
+}
+
+// blockingTool runs until its call is cancelled, then reports why
+type blockingTool struct {
+	started chan struct{}
+	stopped chan error
+}
+
+func (blockingTool) Name() string                 { return "block" }
+func (blockingTool) Description() string          { return "Blocks until cancelled" }
+func (blockingTool) InputSchema() mcp.InputSchema { return mcp.InputSchema{Type: "object"} }
+func (b blockingTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	close(b.started)
+	<-ctx.Done()
+	b.stopped <- ctx.Err()
+	return nil, ctx.Err()
+}
+
+// TestHTTPStream tests that the streaming endpoint delivers progress before
+// the response, that /mcp still answers with a single JSON response, and
+// that closing the stream cancels the tool call
+func TestHTTPStream(t *testing.T) {
+	blocking := blockingTool{started: make(chan struct{}), stopped: make(chan error, 1)}
+	mcpServer := server.NewServer("test", "0.0.0")
+	for _, tool := range []mcp.MCPToolHandler{progressTool{}, blocking} {
+		if err := mcpServer.RegisterTool(tool); err != nil {
+			t.Fatalf("RegisterTool failed: %v", err)
+		}
+	}
+	httpServer := httptest.NewServer(NewHTTPTransport("localhost:0", mcpServer, false).server.Handler)
+	defer httpServer.Close()
+	client := NewHTTPClient(httpServer.URL, false)
+
+	ctx := context.Background()
+	if _, err := client.SendMessage(ctx, &mcp.Message{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: json.RawMessage(`{"protocolVersion":"2024-11-05"}`)}); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	call := &mcp.Message{JSONRPC: "2.0", ID: 2, Method: "tools/call", Params: json.RawMessage(`{"name":"progress","_meta":{"progressToken":"task-1"}}`)}
+
+	t.Run("stream", func(t *testing.T) {
+		var notifications []*mcp.Message
+		response, err := client.StreamMessage(ctx, call, func(msg *mcp.Message) {
+			notifications = append(notifications, msg)
+		})
+		if err != nil {
+			t.Fatalf("StreamMessage failed: %v", err)
+		}
+
+		if len(notifications) != 2 {
+			t.Fatalf("received %d notifications, expected 2", len(notifications))
+		}
+		for i, notification := range notifications {
+			var params struct {
+				ProgressToken string  `json:"progressToken"`
+				Progress      float64 `json:"progress"`
+				Total         float64 `json:"total"`
+			}
+			json.Unmarshal(notification.Params, &params)
+			if notification.Method != mcp.ProgressNotification || params.ProgressToken != "task-1" || params.Progress != float64(i+1) || params.Total != 2 {
+				t.Errorf("notification %d = %s %s", i, notification.Method, notification.Params)
+			}
+		}
+		if text := resultText(t, response); text != "done" {
+			t.Errorf("result = %q, expected done", text)
+		}
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		response, err := client.SendMessage(ctx, call)
+		if err != nil {
+			t.Fatalf("SendMessage failed: %v", err)
+		}
+		if text := resultText(t, response); text != "done" {
+			t.Errorf("result = %q, expected done", text)
+		}
+	})
+
+	t.Run("client closes stream", func(t *testing.T) {
+		streamCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			_, err := client.StreamMessage(streamCtx, &mcp.Message{JSONRPC: "2.0", ID: 3, Method: "tools/call", Params: json.RawMessage(`{"name":"block"}`)}, nil)
+			done <- err
+		}()
+
+		select {
+		case <-blocking.started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("tool call never started")
+		}
+		cancel()
+
+		select {
+		case err := <-blocking.stopped:
+			if err != context.Canceled {
+				t.Errorf("tool stopped with %v, expected context.Canceled", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("closing the stream did not cancel the tool call")
+		}
+		if err := <-done; err == nil {
+			t.Error("StreamMessage succeeded after its context was cancelled")
+		}
+	})
+}
+
+// resultText returns the first text content of a tools/call response
+func resultText(t *testing.T, response *mcp.Message) string {
+	t.Helper()
+	if response == nil || response.Error != nil {
+		t.Fatalf("tools/call failed: %+v", response)
+	}
+	var result mcp.CallToolResponse
+	if err := json.Unmarshal(response.Result, &result); err != nil || len(result.Content) == 0 {
+		t.Fatalf("invalid result %s", response.Result)
+	}
+	return result.Content[0].Text
+}
+
+// eventTool publishes two events before finishing
+type eventTool struct{}
+
+func (eventTool) Name() string                 { return "events" }
+func (eventTool) Description() string          { return "Publishes events" }
+func (eventTool) InputSchema() mcp.InputSchema { return mcp.InputSchema{Type: "object"} }
+func (eventTool) Handle(ctx context.Context, arguments map[string]interface{}) (*mcp.CallToolResponse, error) {
+	for _, finding := range []string{"first", "second"} {
+		if err := mcp.EmitEvent(ctx, "finding", finding); err != nil {
+			return nil, err
+		}
+	}
+	return &mcp.CallToolResponse{Content: []mcp.Content{{Type: "text", Text: "done"}}}, nil
+}
+
+// TestHTTPStreamToolEvents tests that events a tool publishes reach the
+// client as notifications ahead of the tool's response
+func TestHTTPStreamToolEvents(t *testing.T) {
+	mcpServer := server.NewServer("test", "0.0.0")
+	if err := mcpServer.RegisterTool(eventTool{}); err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+	httpServer := httptest.NewServer(NewHTTPTransport("localhost:0", mcpServer, false).server.Handler)
+	defer httpServer.Close()
+	client := NewHTTPClient(httpServer.URL, false)
+
+	ctx := context.Background()
+	if _, err := client.SendMessage(ctx, &mcp.Message{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: json.RawMessage(`{"protocolVersion":"2024-11-05"}`)}); err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+
+	var notifications []*mcp.Message
+	call := &mcp.Message{JSONRPC: "2.0", ID: 2, Method: "tools/call", Params: json.RawMessage(`{"name":"events"}`)}
+	response, err := client.StreamMessage(ctx, call, func(msg *mcp.Message) {
+		notifications = append(notifications, msg)
+	})
+	if err != nil {
+		t.Fatalf("StreamMessage failed: %v", err)
+	}
+
+	if len(notifications) != 2 {
+		t.Fatalf("received %d notifications before the response, expected 2", len(notifications))
+	}
+	for i, expected := range []string{"first", "second"} {
+		var event mcp.ToolEvent
+		json.Unmarshal(notifications[i].Params, &event)
+		if notifications[i].Method != mcp.ToolEventNotification || event.Tool != "events" || event.Type != "finding" || event.Data != expected {
+			t.Errorf("notification %d = %s %s, expected the %s finding", i, notifications[i].Method, notifications[i].Params, expected)
+		}
+	}
+	if text := resultText(t, response); text != "done" {
+		t.Errorf("result = %q, expected done", text)
+	}
+}
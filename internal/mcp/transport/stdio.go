@@ -2,11 +2,14 @@ package transport
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"regexp"
+	"sync"
 
 	"github.com/rcliao/teeny-orb/internal/mcp"
 )
@@ -16,8 +19,12 @@ type StdioTransport struct {
 	stdin  io.Reader
 	stdout io.Writer
 	scanner *bufio.Scanner
+	writeMutex sync.Mutex // Server notifications are written alongside responses
 }
 
+// Ensure StdioTransport implements BatchTransport interface
+var _ mcp.BatchTransport = (*StdioTransport)(nil)
+
 // NewStdioTransport creates a new stdio transport
 func NewStdioTransport() *StdioTransport {
 	return &StdioTransport{
@@ -44,7 +51,7 @@ func (s *StdioTransport) Send(ctx context.Context, msg *mcp.Message) error {
 	}
 	
 	// Write JSON-RPC message followed by newline
-	_, err = fmt.Fprintf(s.stdout, "%s\n", data)
+	_, err = s.writeLine(data)
 	if err != nil {
 		return fmt.Errorf("failed to write message: %w", err)
 	}
@@ -52,35 +59,147 @@ func (s *StdioTransport) Send(ctx context.Context, msg *mcp.Message) error {
 	return nil
 }
 
-// Receive receives a message from stdin
+// Receive receives a message from stdin. Lines that aren't valid JSON-RPC
+// messages are answered with a parse error and skipped.
 func (s *StdioTransport) Receive(ctx context.Context) (*mcp.Message, error) {
-	// Check context cancellation
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	default:
+	for {
+		line, err := s.readLine(ctx)
+		if err != nil {
+			return nil, err
+		}
+		
+		if msg, ok, err := s.decodeMessage(line); err != nil || ok {
+			return msg, err
+		}
 	}
-	
-	// Read line from stdin
-	if !s.scanner.Scan() {
-		if err := s.scanner.Err(); err != nil {
-			return nil, fmt.Errorf("scanner error: %w", err)
+}
+
+// ReceiveFrame receives the next message or batch from stdin, skipping
+// malformed lines like Receive. An empty batch is answered with an invalid
+// request error.
+func (s *StdioTransport) ReceiveFrame(ctx context.Context) (*mcp.Message, mcp.Batch, error) {
+	for {
+		line, err := s.readLine(ctx)
+		if err != nil {
+			return nil, nil, err
 		}
-		return nil, io.EOF
+		
+		if !mcp.IsBatch(line) {
+			if msg, ok, err := s.decodeMessage(line); err != nil || ok {
+				return msg, nil, err
+			}
+			continue
+		}
+		
+		var batch mcp.Batch
+		decodeErr := json.Unmarshal(line, &batch)
+		if decodeErr == nil && len(batch) == 0 {
+			decodeErr = fmt.Errorf("empty batch")
+		}
+		if decodeErr != nil {
+			if sendErr := s.sendMalformedFrameError(line, decodeErr); sendErr != nil {
+				return nil, nil, sendErr
+			}
+			continue
+		}
+		return nil, batch, nil
 	}
-	
-	line := s.scanner.Text()
-	if line == "" {
-		return s.Receive(ctx) // Skip empty lines
+}
+
+// SendBatch sends the responses to a batch as one JSON array line
+func (s *StdioTransport) SendBatch(ctx context.Context, responses []*mcp.Message) error {
+	data, err := json.Marshal(responses)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
 	}
 	
-	// Parse JSON-RPC message
+	if _, err := s.writeLine(data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
+}
+
+// readLine returns the next non-empty line from stdin
+func (s *StdioTransport) readLine(ctx context.Context) ([]byte, error) {
+	for {
+		// Check context cancellation
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		
+		// Read line from stdin
+		if !s.scanner.Scan() {
+			if err := s.scanner.Err(); err != nil {
+				return nil, fmt.Errorf("scanner error: %w", err)
+			}
+			return nil, io.EOF
+		}
+		
+		line := s.scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue // Skip empty lines
+		}
+		return line, nil
+	}
+}
+
+// decodeMessage parses a JSON-RPC message, answering a malformed line with an
+// error response. It reports false when the line was skipped.
+func (s *StdioTransport) decodeMessage(line []byte) (*mcp.Message, bool, error) {
 	var msg mcp.Message
-	if err := json.Unmarshal([]byte(line), &msg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
+	if err := json.Unmarshal(line, &msg); err != nil {
+		if sendErr := s.sendMalformedFrameError(line, err); sendErr != nil {
+			return nil, false, sendErr
+		}
+		return nil, false, nil
+	}
+	return &msg, true, nil
+}
+
+// errorFrame is an error response whose ID is serialized as null when unknown,
+// as JSON-RPC requires for errors on requests that couldn't be read
+type errorFrame struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      interface{} `json:"id"`
+	Error   *mcp.Error  `json:"error"`
+}
+
+// frameIDPattern finds a request ID in a frame too broken to decode
+var frameIDPattern = regexp.MustCompile(`"id"\s*:\s*("(?:[^"\\]|\\.)*"|-?\d+)`)
+
+// sendMalformedFrameError reports a line that couldn't be decoded. Valid JSON
+// that isn't a message is an invalid request; anything else is a parse error.
+func (s *StdioTransport) sendMalformedFrameError(line []byte, decodeErr error) error {
+	frame := errorFrame{
+		JSONRPC: "2.0",
+		Error: &mcp.Error{
+			Code:    mcp.ParseError,
+			Message: fmt.Sprintf("Parse error: %v", decodeErr),
+		},
+	}
+	if json.Valid(line) {
+		frame.Error.Code = mcp.InvalidRequest
+		frame.Error.Message = fmt.Sprintf("Invalid request: %v", decodeErr)
 	}
 	
-	return &msg, nil
+	// Answer the request the client meant to send when its ID survived
+	if match := frameIDPattern.FindSubmatch(line); match != nil {
+		var id interface{}
+		if err := json.Unmarshal(match[1], &id); err == nil {
+			frame.ID = id
+		}
+	}
+	
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return fmt.Errorf("failed to marshal error response: %w", err)
+	}
+	if _, err := s.writeLine(data); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	return nil
 }
 
 // Close closes the transport
@@ -88,4 +207,11 @@ func (s *StdioTransport) Close() error {
 	// For stdio transport, we don't close stdin/stdout
 	// as they might be used by other parts of the application
 	return nil
-}
\ No newline at end of file
+}
+
+// writeLine writes one newline-terminated frame to stdout
+func (s *StdioTransport) writeLine(data []byte) (int, error) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return fmt.Fprintf(s.stdout, "%s\n", data)
+}
@@ -0,0 +1,102 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/mcp/server"
+)
+
+// TestStdioTransportSkipsMalformedFrames tests that a garbage line is answered
+// with an error and the following valid request is still processed
+func TestStdioTransportSkipsMalformedFrames(t *testing.T) {
+	validRequest := `{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`
+
+	tests := []struct {
+		name    string
+		garbage string
+		code    int
+		errorID interface{}
+	}{
+		{name: "not JSON", garbage: "hello there", code: mcp.ParseError, errorID: nil},
+		{name: "truncated with numeric ID", garbage: `{"jsonrpc":"2.0","id":7,"method":"tools/li`, code: mcp.ParseError, errorID: float64(7)},
+		{name: "truncated with string ID", garbage: `{"jsonrpc":"2.0","id":"req-\"1\"","params":{`, code: mcp.ParseError, errorID: `req-"1"`},
+		{name: "valid JSON but not a message", garbage: `[1, 2, 3]`, code: mcp.InvalidRequest, errorID: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			stdin := strings.NewReader(tt.garbage + "\n\n" + validRequest + "\n")
+			transport := NewStdioTransportWithStreams(stdin, &stdout)
+			mcpServer := server.NewServer("test", "0.0.0")
+			ctx := context.Background()
+
+			msg, err := transport.Receive(ctx)
+			if err != nil {
+				t.Fatalf("Receive failed: %v", err)
+			}
+			if msg.Method != "initialize" || msg.ID != float64(2) {
+				t.Fatalf("received %+v, expected the valid initialize request", msg)
+			}
+
+			response, err := mcpServer.HandleMessage(ctx, msg)
+			if err != nil || response == nil || response.Error != nil {
+				t.Fatalf("valid request was not processed: %+v, %v", response, err)
+			}
+
+			// The garbage line was answered before the valid request was returned
+			var frame map[string]interface{}
+			if err := json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &frame); err != nil {
+				t.Fatalf("error response is not JSON: %q", stdout.String())
+			}
+			id, hasID := frame["id"]
+			if !hasID || id != tt.errorID {
+				t.Errorf("error response id = %v (present %v), expected %v", id, hasID, tt.errorID)
+			}
+			errObj, _ := frame["error"].(map[string]interface{})
+			if errObj == nil || errObj["code"] != float64(tt.code) {
+				t.Errorf("error response = %v, expected code %d", frame["error"], tt.code)
+			}
+
+			if _, err := transport.Receive(ctx); err != io.EOF {
+				t.Errorf("Receive after input = %v, expected io.EOF", err)
+			}
+		})
+	}
+}
+
+// TestStdioTransportBatch tests that ReceiveFrame returns a batch line whole
+// and that an empty batch is answered with an error and skipped
+func TestStdioTransportBatch(t *testing.T) {
+	var stdout bytes.Buffer
+	stdin := strings.NewReader("[]\n" + `[{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}},{"jsonrpc":"2.0","method":"notifications/initialized"}]` + "\n")
+	transport := NewStdioTransportWithStreams(stdin, &stdout)
+	mcpServer := server.NewServer("test", "0.0.0")
+	ctx := context.Background()
+
+	msg, batch, err := transport.ReceiveFrame(ctx)
+	if err != nil || msg != nil || len(batch) != 2 {
+		t.Fatalf("ReceiveFrame = %+v, %d messages, %v; expected a batch of 2", msg, len(batch), err)
+	}
+	if !strings.Contains(stdout.String(), `"code":-32600`) {
+		t.Errorf("empty batch was not answered with an invalid request error: %q", stdout.String())
+	}
+
+	stdout.Reset()
+	if err := transport.SendBatch(ctx, mcpServer.HandleBatch(ctx, batch)); err != nil {
+		t.Fatalf("SendBatch failed: %v", err)
+	}
+	var responses []mcp.Message
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &responses); err != nil {
+		t.Fatalf("batch response is not an array: %q", stdout.String())
+	}
+	if len(responses) != 1 || responses[0].ID != float64(1) || responses[0].Error != nil {
+		t.Errorf("responses = %+v, expected one initialize result", responses)
+	}
+}
@@ -3,6 +3,8 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"time"
 )
 
 // MCPVersion represents the MCP protocol version
@@ -24,6 +26,143 @@ type Message struct {
 	Error   *Error          `json:"error,omitempty"`
 }
 
+// Batch is a JSON-RPC batch: several messages sent as one JSON array. Elements
+// stay raw so one malformed element doesn't spoil the rest.
+type Batch []json.RawMessage
+
+// IsBatch reports whether a payload is a JSON array, i.e. a batch
+func IsBatch(data []byte) bool {
+	for _, b := range data {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return b == '['
+	}
+	return false
+}
+
+// sessionIDKey carries the MCP session ID of a request in its context
+type sessionIDKey struct{}
+
+// WithSessionID returns a context for requests of the given MCP session
+func WithSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, id)
+}
+
+// SessionIDFromContext returns the MCP session ID of a request, or "" for
+// transports with a single implicit session
+func SessionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionIDKey{}).(string)
+	return id
+}
+
+// RequestNotifier delivers a notification about the request being handled,
+// such as its progress, ahead of that request's response
+type RequestNotifier func(ctx context.Context, msg *Message) error
+
+// requestNotifierKey carries a request's RequestNotifier in its context
+type requestNotifierKey struct{}
+
+// WithRequestNotifier returns a context whose notifications are streamed to
+// the client along with the response to the current request
+func WithRequestNotifier(ctx context.Context, notify RequestNotifier) context.Context {
+	return context.WithValue(ctx, requestNotifierKey{}, notify)
+}
+
+// RequestNotifierFromContext returns the request's notifier, or nil when the
+// transport can only answer with a single response
+func RequestNotifierFromContext(ctx context.Context) RequestNotifier {
+	notify, _ := ctx.Value(requestNotifierKey{}).(RequestNotifier)
+	return notify
+}
+
+// ProgressNotification is the method of notifications reporting the
+// progress of a request that asked for it with a progress token
+const ProgressNotification = "notifications/progress"
+
+// progressTokenKey carries a request's progress token in its context
+type progressTokenKey struct{}
+
+// WithProgressToken returns a context for a request that asked for progress
+// notifications under token
+func WithProgressToken(ctx context.Context, token interface{}) context.Context {
+	return context.WithValue(ctx, progressTokenKey{}, token)
+}
+
+// ProgressRequested reports whether ReportProgress reaches the client, so
+// tools can skip the work of measuring progress nobody sees
+func ProgressRequested(ctx context.Context) bool {
+	return ctx.Value(progressTokenKey{}) != nil && RequestNotifierFromContext(ctx) != nil
+}
+
+// ReportProgress is how a tool tells the client how far the current request
+// has come: it sends a notifications/progress carrying the token the client
+// put in the request's _meta.progressToken. progress must increase with each
+// call; total is zero when unknown and message is an optional description.
+// Without a progress token or a way to notify the client, it does nothing.
+func ReportProgress(ctx context.Context, progress, total float64, message string) error {
+	if !ProgressRequested(ctx) {
+		return nil
+	}
+	token := ctx.Value(progressTokenKey{})
+	notify := RequestNotifierFromContext(ctx)
+
+	params := map[string]interface{}{"progressToken": token, "progress": progress}
+	if total > 0 {
+		params["total"] = total
+	}
+	if message != "" {
+		params["message"] = message
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress: %w", err)
+	}
+	return notify(ctx, &Message{JSONRPC: "2.0", Method: ProgressNotification, Params: data})
+}
+
+// ToolEventNotification is the method of notifications carrying the events
+// a tool publishes while handling a call
+const ToolEventNotification = "notifications/tools/event"
+
+// ToolEvent is an intermediate result a tool publishes before its response,
+// such as a search match as soon as it's found
+type ToolEvent struct {
+	Tool string      `json:"tool"`
+	Type string      `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// EventEmitter delivers a tool's events to the client as notifications
+type EventEmitter func(ctx context.Context, event ToolEvent) error
+
+// eventEmitterKey carries a tool call's EventEmitter in its context
+type eventEmitterKey struct{}
+
+// WithEventEmitter returns a context whose tool events are delivered by emit
+func WithEventEmitter(ctx context.Context, emit EventEmitter) context.Context {
+	return context.WithValue(ctx, eventEmitterKey{}, emit)
+}
+
+// EventsEnabled reports whether EmitEvent reaches anyone, so tools can skip
+// building events nobody receives
+func EventsEnabled(ctx context.Context) bool {
+	emit, _ := ctx.Value(eventEmitterKey{}).(EventEmitter)
+	return emit != nil
+}
+
+// EmitEvent publishes an event of eventType about the current tool call.
+// Without an emitter in ctx it does nothing, so tools can call it
+// unconditionally.
+func EmitEvent(ctx context.Context, eventType string, data interface{}) error {
+	emit, _ := ctx.Value(eventEmitterKey{}).(EventEmitter)
+	if emit == nil {
+		return nil
+	}
+	return emit(ctx, ToolEvent{Type: eventType, Data: data})
+}
+
 // Error represents an MCP error
 type Error struct {
 	Code    int         `json:"code"`
@@ -124,12 +263,53 @@ type InputSchema struct {
 type CallToolRequest struct {
 	Name      string                 `json:"name"`
 	Arguments map[string]interface{} `json:"arguments,omitempty"`
+	Meta      *RequestMeta           `json:"_meta,omitempty"`
+}
+
+// RequestMeta is the metadata a client attaches to a request
+type RequestMeta struct {
+	ProgressToken interface{} `json:"progressToken,omitempty"` // Asks for progress notifications carrying this token
+	TimeoutMs     int64       `json:"timeoutMs,omitempty"`     // Overrides the tool's timeout, within the server's maximum
 }
 
 // CallToolResponse represents a tool call response
 type CallToolResponse struct {
-	Content []Content `json:"content"`
-	IsError bool      `json:"isError,omitempty"`
+	Content []Content  `json:"content"`
+	IsError bool       `json:"isError,omitempty"`
+	Error   *ToolError `json:"error,omitempty"` // Classifies the failure when IsError is set
+}
+
+// ErrorCode is a machine-readable reason a tool call failed
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidArgument  ErrorCode = "invalid_argument"  // The arguments are missing, malformed, or out of range
+	ErrorCodeNotFound         ErrorCode = "not_found"         // The file, tool, or other target doesn't exist
+	ErrorCodePermissionDenied ErrorCode = "permission_denied" // The security policy or the OS refused the operation
+	ErrorCodeRateLimited      ErrorCode = "rate_limited"      // The session's rate limit is exhausted; retrying later may succeed
+	ErrorCodeExecutionFailed  ErrorCode = "execution_failed"  // A command ran and failed
+	ErrorCodeTimeout          ErrorCode = "timeout"           // The operation didn't finish in time
+	ErrorCodeUnavailable      ErrorCode = "unavailable"       // The server can't serve the call yet
+	ErrorCodeToolDisabled     ErrorCode = "tool_disabled"     // An operator disabled the tool, globally or for the session
+	ErrorCodeTooLarge         ErrorCode = "too_large"         // The file exceeds a size limit
+	ErrorCodeInternal         ErrorCode = "internal"          // Any other failure
+)
+
+// ToolError is the structured form of a tool failure. The response's text
+// content still carries the message for people.
+type ToolError struct {
+	Code    ErrorCode              `json:"code"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// NewToolErrorResponse creates a failed tool response with message as its
+// text and code and details as its structured error
+func NewToolErrorResponse(code ErrorCode, message string, details map[string]interface{}) *CallToolResponse {
+	return &CallToolResponse{
+		Content: []Content{{Type: "text", Text: message}},
+		IsError: true,
+		Error:   &ToolError{Code: code, Details: details},
+	}
 }
 
 // Content represents content in MCP responses
@@ -186,6 +366,29 @@ type MCPToolHandler interface {
 	Handle(ctx context.Context, arguments map[string]interface{}) (*CallToolResponse, error)
 }
 
+// TimeoutToolHandler is a tool handler that advertises how long its calls
+// should be allowed to run, when that differs from the server's default
+type TimeoutToolHandler interface {
+	MCPToolHandler
+	Timeout() time.Duration
+}
+
+// CacheableToolHandler is a tool handler whose calls with the given
+// arguments only read, so a server may answer a repeated call from its cache
+// until something changes files
+type CacheableToolHandler interface {
+	MCPToolHandler
+	Cacheable(arguments map[string]interface{}) bool
+}
+
+// CachedCallAuthorizer is a cacheable tool handler that checks a call the
+// server answers from its cache, so the call is audited and rate limited as
+// if the tool had run. A non-nil response is returned instead of the cached
+// one.
+type CachedCallAuthorizer interface {
+	AuthorizeCachedCall(ctx context.Context, arguments map[string]interface{}) *CallToolResponse
+}
+
 // MCPServer defines the interface for MCP servers
 type MCPServer interface {
 	// Initialize initializes the server
@@ -235,4 +438,15 @@ type Transport interface {
 	
 	// Close closes the transport
 	Close() error
+}
+
+// BatchTransport is a Transport that can also carry JSON-RPC batches
+type BatchTransport interface {
+	Transport
+
+	// ReceiveFrame receives the next message or batch; exactly one is non-nil
+	ReceiveFrame(ctx context.Context) (*Message, Batch, error)
+
+	// SendBatch sends the responses to a batch as one array
+	SendBatch(ctx context.Context, responses []*Message) error
 }
\ No newline at end of file
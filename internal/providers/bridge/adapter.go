@@ -0,0 +1,103 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/providers"
+)
+
+// HandlerTool serves an MCP tool handler as a providers.Tool, calling it in
+// process. Registered with an MCPToolProvider it is unwrapped, so the same
+// handler backs both the direct and the MCP path.
+type HandlerTool struct {
+	handler mcp.MCPToolHandler
+}
+
+// Ensure HandlerTool implements ParameterizedTool interface
+var _ providers.ParameterizedTool = (*HandlerTool)(nil)
+
+// NewHandlerTool adapts an MCP tool handler
+func NewHandlerTool(handler mcp.MCPToolHandler) *HandlerTool {
+	return &HandlerTool{handler: handler}
+}
+
+// Handler returns the adapted MCP tool handler
+func (t *HandlerTool) Handler() mcp.MCPToolHandler {
+	return t.handler
+}
+
+// Name returns the handler's name
+func (t *HandlerTool) Name() string {
+	return t.handler.Name()
+}
+
+// Description returns the handler's description
+func (t *HandlerTool) Description() string {
+	return t.handler.Description()
+}
+
+// Parameters returns the handler's input schema as a JSON schema object
+func (t *HandlerTool) Parameters() map[string]interface{} {
+	schema := t.handler.InputSchema()
+	parameters := map[string]interface{}{
+		"type":       schema.Type,
+		"properties": schema.Properties,
+	}
+	if parameters["properties"] == nil {
+		parameters["properties"] = map[string]interface{}{}
+	}
+	if len(schema.Required) > 0 {
+		parameters["required"] = schema.Required
+	}
+	return parameters
+}
+
+// Execute runs the handler
+func (t *HandlerTool) Execute(ctx context.Context, args map[string]interface{}) (*providers.ToolResult, error) {
+	response, err := t.handler.Handle(ctx, args)
+	if err != nil {
+		return &providers.ToolResult{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	return toolResult(response), nil
+}
+
+// toolResult converts an MCP tool response to a ToolResult, joining its
+// text content into the output
+func toolResult(response *mcp.CallToolResponse) *providers.ToolResult {
+	var texts []string
+	data := make(map[string]interface{})
+	for _, content := range response.Content {
+		if content.Type == "text" {
+			texts = append(texts, content.Text)
+		}
+		if content.Data != nil {
+			data["mcp_data"] = content.Data
+		}
+	}
+
+	if response.IsError {
+		message := "Unknown MCP error"
+		if len(texts) > 0 {
+			message = strings.Join(texts, "")
+		}
+		if response.Error != nil {
+			data["error_code"] = string(response.Error.Code)
+		}
+		return &providers.ToolResult{
+			Success: false,
+			Data:    data,
+			Error:   message,
+		}
+	}
+
+	return &providers.ToolResult{
+		Success: true,
+		Data:    data,
+		Output:  strings.Join(texts, ""),
+	}
+}
@@ -20,18 +20,27 @@ type MCPToolProvider struct {
 
 // NewMCPToolProvider creates a new MCP tool provider bridge
 func NewMCPToolProvider() *MCPToolProvider {
-	mcpServer := server.NewServer("teeny-orb-experiment", "0.1.0")
-	
 	// Register default tools
 	fsTools := tools.NewFileSystemTool("/workspace")
 	cmdTool := tools.NewCommandTool([]string{"ls", "pwd", "echo", "cat"})
 	
-	mcpServer.RegisterTool(fsTools)
-	mcpServer.RegisterTool(cmdTool)
+	provider, _ := NewMCPToolProviderWithTools(fsTools, cmdTool)
+	return provider
+}
+
+// NewMCPToolProviderWithTools creates an MCP tool provider bridge serving
+// the given handlers
+func NewMCPToolProviderWithTools(handlers ...mcp.MCPToolHandler) (*MCPToolProvider, error) {
+	mcpServer := server.NewServer("teeny-orb-experiment", "0.1.0")
+	for _, handler := range handlers {
+		if err := mcpServer.RegisterTool(handler); err != nil {
+			return nil, fmt.Errorf("failed to register %s: %w", handler.Name(), err)
+		}
+	}
 	
 	return &MCPToolProvider{
 		server: mcpServer,
-	}
+	}, nil
 }
 
 // initialize performs MCP initialization if not already done
@@ -64,12 +73,17 @@ func (m *MCPToolProvider) initialize() error {
 	return nil
 }
 
-// RegisterTool registers a tool by bridging it to MCP
+// RegisterTool registers a tool by bridging it to MCP. Tools adapted from
+// MCP handlers register the handler itself.
 func (m *MCPToolProvider) RegisterTool(tool providers.Tool) error {
 	if err := m.initialize(); err != nil {
 		return err
 	}
 	
+	if handlerTool, ok := tool.(*HandlerTool); ok {
+		return m.server.RegisterTool(handlerTool.Handler())
+	}
+	
 	// Create MCP tool wrapper
 	mcpTool := &toolBridge{tool: tool}
 	return m.server.RegisterTool(mcpTool)
@@ -126,34 +140,7 @@ func (m *MCPToolProvider) CallTool(ctx context.Context, name string, args map[st
 	}
 	
 	// Convert MCP response back to ToolResult
-	if callResp.IsError {
-		errorMsg := "Unknown MCP error"
-		if len(callResp.Content) > 0 {
-			errorMsg = callResp.Content[0].Text
-		}
-		return &providers.ToolResult{
-			Success: false,
-			Error:   errorMsg,
-		}, nil
-	}
-	
-	// Aggregate output from content
-	output := ""
-	data := make(map[string]interface{})
-	for _, content := range callResp.Content {
-		if content.Type == "text" {
-			output += content.Text
-		}
-		if content.Data != nil {
-			data["mcp_data"] = content.Data
-		}
-	}
-	
-	return &providers.ToolResult{
-		Success: true,
-		Data:    data,
-		Output:  output,
-	}, nil
+	return toolResult(callResp), nil
 }
 
 // Close closes the MCP server
@@ -0,0 +1,67 @@
+package bridge
+
+import (
+	"fmt"
+
+	"github.com/rcliao/teeny-orb/internal/mcp"
+	"github.com/rcliao/teeny-orb/internal/providers"
+	"github.com/rcliao/teeny-orb/internal/providers/direct"
+)
+
+// Registry holds MCP tool handlers once and serves them through either
+// tool provider, so a tool is written against mcp.MCPToolHandler alone
+type Registry struct {
+	handlers []mcp.MCPToolHandler
+	names    map[string]bool
+}
+
+// NewRegistry creates an empty tool registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// Register adds handlers, rejecting names already registered or repeated
+// among handlers. Nothing is registered when any name is rejected.
+func (r *Registry) Register(handlers ...mcp.MCPToolHandler) error {
+	seen := make(map[string]bool, len(handlers))
+	for _, handler := range handlers {
+		name := handler.Name()
+		if r.names[name] || seen[name] {
+			return fmt.Errorf("tool already registered: %s", name)
+		}
+		seen[name] = true
+	}
+
+	for _, handler := range handlers {
+		r.names[handler.Name()] = true
+		r.handlers = append(r.handlers, handler)
+	}
+	return nil
+}
+
+// Tools returns the registered handlers adapted as provider tools, in
+// registration order
+func (r *Registry) Tools() []providers.Tool {
+	tools := make([]providers.Tool, len(r.handlers))
+	for i, handler := range r.handlers {
+		tools[i] = NewHandlerTool(handler)
+	}
+	return tools
+}
+
+// DirectProvider returns a provider calling the registered tools in process
+func (r *Registry) DirectProvider() (*direct.DirectToolProvider, error) {
+	provider := direct.NewDirectToolProvider()
+	for _, tool := range r.Tools() {
+		if err := provider.RegisterTool(tool); err != nil {
+			return nil, fmt.Errorf("failed to register %s: %w", tool.Name(), err)
+		}
+	}
+	return provider, nil
+}
+
+// MCPProvider returns a provider calling the registered tools through an
+// MCP server
+func (r *Registry) MCPProvider() (*MCPToolProvider, error) {
+	return NewMCPToolProviderWithTools(r.handlers...)
+}
@@ -0,0 +1,90 @@
+package bridge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rcliao/teeny-orb/internal/mcp/tools"
+	"github.com/rcliao/teeny-orb/internal/providers"
+)
+
+// TestRegistryServesToolThroughBothProviders tests that one registered MCP
+// tool behaves the same called directly and through an MCP server
+func TestRegistryServesToolThroughBothProviders(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello from the workspace"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	registry := NewRegistry()
+	if err := registry.Register(tools.NewRealFileSystemTool(dir, nil)); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := registry.Register(tools.NewRealFileSystemTool(dir, nil)); err == nil {
+		t.Error("Register accepted a second filesystem tool")
+	}
+	// A rejected call registers none of its tools
+	command := tools.NewRealCommandTool(nil, dir)
+	if err := registry.Register(command, command); err == nil {
+		t.Error("Register accepted the same tool twice in one call")
+	}
+	if err := registry.Register(command, tools.NewRealFileSystemTool(dir, nil)); err == nil {
+		t.Error("Register accepted a call including an already registered tool")
+	}
+	if registered := registry.Tools(); len(registered) != 1 {
+		t.Errorf("registered %d tools, expected only the first filesystem tool", len(registered))
+	}
+
+	directProvider, err := registry.DirectProvider()
+	if err != nil {
+		t.Fatalf("DirectProvider failed: %v", err)
+	}
+	mcpProvider, err := registry.MCPProvider()
+	if err != nil {
+		t.Fatalf("MCPProvider failed: %v", err)
+	}
+
+	// The model sees the handler's own schema rather than a guessed one
+	definitions := directProvider.GetToolDefinitions()
+	if len(definitions) != 1 {
+		t.Fatalf("definitions = %+v, expected the filesystem tool", definitions)
+	}
+	operation, _ := definitions[0].Parameters["properties"].(map[string]interface{})["operation"].(map[string]interface{})
+	if enum, _ := operation["enum"].([]string); len(enum) == 0 || enum[len(enum)-1] != "search" {
+		t.Errorf("operation schema = %v, expected the real tool's operations", operation)
+	}
+
+	for name, provider := range map[string]providers.ToolProvider{"direct": directProvider, "mcp": mcpProvider} {
+		t.Run(name, func(t *testing.T) {
+			listed := provider.ListTools()
+			if len(listed) != 1 || listed[0].Name() != "filesystem" {
+				t.Fatalf("ListTools = %v, expected the filesystem tool", listed)
+			}
+
+			result, err := provider.CallTool(context.Background(), "filesystem", map[string]interface{}{
+				"operation": "read",
+				"path":      "hello.txt",
+			})
+			if err != nil {
+				t.Fatalf("CallTool failed: %v", err)
+			}
+			if !result.Success || !strings.Contains(result.Output, "hello from the workspace") {
+				t.Errorf("read = %+v, expected the file content", result)
+			}
+
+			result, err = provider.CallTool(context.Background(), "filesystem", map[string]interface{}{
+				"operation": "read",
+				"path":      "missing.txt",
+			})
+			if err != nil {
+				t.Fatalf("CallTool failed: %v", err)
+			}
+			if result.Success || result.Error == "" || result.Data["error_code"] != "not_found" {
+				t.Errorf("read of a missing file = %+v, expected a not_found failure", result)
+			}
+		})
+	}
+}
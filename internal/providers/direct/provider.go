@@ -92,6 +92,10 @@ func (d *DirectToolProvider) GetToolDefinitions() []providers.ToolDefinition {
 
 // generateParameters creates parameter schema for a tool
 func generateParameters(tool providers.Tool) map[string]interface{} {
+	if parameterized, ok := tool.(providers.ParameterizedTool); ok {
+		return parameterized.Parameters()
+	}
+	
 	// Basic parameter schema - in a real implementation, this would be more sophisticated
 	switch tool.Name() {
 	case "filesystem":
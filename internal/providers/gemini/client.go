@@ -1,8 +1,12 @@
 package gemini
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math/rand"
 	"net/http"
 	"time"
 
@@ -16,6 +20,25 @@ type GeminiClient struct {
 	model       string
 	httpClient  *http.Client
 	toolProvider providers.ToolProvider
+	retry       *RetryConfig
+}
+
+// RetryConfig controls retries of rate limited and failed API calls
+type RetryConfig struct {
+	MaxRetries     int           `json:"max_retries"`
+	InitialBackoff time.Duration `json:"initial_backoff"`
+	MaxBackoff     time.Duration `json:"max_backoff"`
+}
+
+// APIError is returned when the Gemini API responds with a non-success status
+type APIError struct {
+	StatusCode int    `json:"code"`
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Gemini API error %d (%s): %s", e.StatusCode, e.Status, e.Message)
 }
 
 // NewGeminiClient creates a new Gemini API client
@@ -27,6 +50,11 @@ func NewGeminiClient(apiKey, model string) *GeminiClient {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		retry: &RetryConfig{
+			MaxRetries:     4,
+			InitialBackoff: 500 * time.Millisecond,
+			MaxBackoff:     10 * time.Second,
+		},
 	}
 }
 
@@ -35,8 +63,22 @@ func (g *GeminiClient) SetToolProvider(provider providers.ToolProvider) {
 	g.toolProvider = provider
 }
 
+// SetBaseURL overrides the API endpoint, e.g. for proxies or tests
+func (g *GeminiClient) SetBaseURL(baseURL string) {
+	g.baseURL = baseURL
+}
+
+// SetRetryConfig sets the retry behaviour for API calls
+func (g *GeminiClient) SetRetryConfig(config *RetryConfig) {
+	g.retry = config
+}
+
 // Chat sends a chat request to Gemini
 func (g *GeminiClient) Chat(ctx context.Context, request *providers.ChatRequest) (*providers.ChatResponse, error) {
+	if err := providers.ValidateToolDefinitions("gemini", request.Tools); err != nil {
+		return nil, err
+	}
+	
 	// Convert provider request to Gemini format
 	geminiRequest := g.convertToGeminiRequest(request)
 	
@@ -106,53 +148,137 @@ func (g *GeminiClient) GetModel() *providers.ModelInfo {
 	}
 }
 
-// makeAPICall performs the actual HTTP request to Gemini API
+// makeAPICall performs the actual HTTP request to Gemini API, retrying
+// rate limited (429) and server error (5xx) responses with exponential backoff
 func (g *GeminiClient) makeAPICall(ctx context.Context, request *GeminiRequest) (*GeminiResponse, error) {
-	// For testing purposes, return simulated response instead of real API call
-	// In production, this would make actual HTTP requests
+	if g.apiKey == "" {
+		return nil, fmt.Errorf("Gemini API key is not configured")
+	}
 	
-	return &GeminiResponse{
-		Candidates: []Candidate{
-			{
-				Content: Content{
-					Parts: []Part{
-						{
-							Text: "This is a simulated Gemini response for cross-provider testing. " +
-								 "In a real implementation, this would call the actual Gemini API.",
-						},
-					},
-				},
-				FinishReason: "STOP",
-			},
-		},
-		UsageMetadata: UsageMetadata{
-			PromptTokenCount:     estimateTokens(request),
-			CandidatesTokenCount: 50,
-			TotalTokenCount:      estimateTokens(request) + 50,
-		},
-	}, nil
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	
+	url := fmt.Sprintf("%s/models/%s:generateContent", g.baseURL, g.model)
+	
+	maxRetries := 0
+	if g.retry != nil {
+		maxRetries = g.retry.MaxRetries
+	}
+	
+	for attempt := 0; ; attempt++ {
+		response, err := g.doRequest(ctx, url, body)
+		if err == nil {
+			return response, nil
+		}
+		
+		apiErr, ok := err.(*APIError)
+		if !ok || !isRetryableStatus(apiErr.StatusCode) || attempt >= maxRetries {
+			return nil, err
+		}
+		
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(g.backoff(attempt)):
+		}
+	}
+}
+
+// doRequest sends a single generateContent request
+func (g *GeminiClient) doRequest(ctx context.Context, url string, body []byte) (*GeminiResponse, error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("x-goog-api-key", g.apiKey)
+	
+	httpResponse, err := g.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer httpResponse.Body.Close()
+	
+	respBody, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	
+	if httpResponse.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: httpResponse.StatusCode, Status: http.StatusText(httpResponse.StatusCode)}
+		var errorBody struct {
+			Error *APIError `json:"error"`
+		}
+		if json.Unmarshal(respBody, &errorBody) == nil && errorBody.Error != nil {
+			apiErr.Message = errorBody.Error.Message
+			if errorBody.Error.Status != "" {
+				apiErr.Status = errorBody.Error.Status
+			}
+		}
+		return nil, apiErr
+	}
+	
+	var response GeminiResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	
+	return &response, nil
+}
+
+// backoff returns the delay before the next retry: exponential growth capped
+// at MaxBackoff, with jitter so concurrent clients don't retry in lockstep
+func (g *GeminiClient) backoff(attempt int) time.Duration {
+	delay := g.retry.InitialBackoff << attempt
+	if delay <= 0 || (g.retry.MaxBackoff > 0 && delay > g.retry.MaxBackoff) {
+		delay = g.retry.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// isRetryableStatus reports whether a status code is worth retrying
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
 }
 
 // convertToGeminiRequest converts provider request to Gemini API format
 func (g *GeminiClient) convertToGeminiRequest(request *providers.ChatRequest) *GeminiRequest {
-	contents := make([]Content, len(request.Messages))
+	contents := make([]Content, 0, len(request.Messages))
+	var systemInstruction *Content
 	
-	for i, msg := range request.Messages {
+	for _, msg := range request.Messages {
+		// Gemini takes system prompts separately from the conversation
+		if msg.Role == "system" {
+			if systemInstruction == nil {
+				systemInstruction = &Content{}
+			}
+			systemInstruction.Parts = append(systemInstruction.Parts, Part{Text: msg.Content})
+			continue
+		}
+		
 		role := msg.Role
 		if role == "assistant" {
 			role = "model"
 		}
 		
-		contents[i] = Content{
+		contents = append(contents, Content{
 			Role: role,
 			Parts: []Part{
 				{Text: msg.Content},
 			},
-		}
+		})
 	}
 	
 	geminiRequest := &GeminiRequest{
-		Contents: contents,
+		Contents:          contents,
+		SystemInstruction: systemInstruction,
 		GenerationConfig: GenerationConfig{
 			Temperature:     0.7,
 			TopK:           40,
@@ -224,23 +350,12 @@ func (g *GeminiClient) convertTools(tools []providers.ToolDefinition) []Tool {
 	return geminiTools
 }
 
-// estimateTokens provides a rough token estimate for testing
-func estimateTokens(request *GeminiRequest) int {
-	totalChars := 0
-	for _, content := range request.Contents {
-		for _, part := range content.Parts {
-			totalChars += len(part.Text)
-		}
-	}
-	// Rough estimation: ~4 characters per token
-	return totalChars / 4
-}
-
 // Gemini API request/response structures
 
 type GeminiRequest struct {
-	Contents         []Content        `json:"contents"`
-	Tools            []Tool           `json:"tools,omitempty"`
+	Contents          []Content        `json:"contents"`
+	SystemInstruction *Content         `json:"systemInstruction,omitempty"`
+	Tools             []Tool           `json:"tools,omitempty"`
 	GenerationConfig GenerationConfig `json:"generationConfig"`
 }
 
@@ -250,8 +365,9 @@ type Content struct {
 }
 
 type Part struct {
-	Text         string        `json:"text,omitempty"`
-	FunctionCall *FunctionCall `json:"functionCall,omitempty"`
+	Text             string            `json:"text,omitempty"`
+	FunctionCall     *FunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *FunctionResponse `json:"functionResponse,omitempty"`
 }
 
 type FunctionCall struct {
@@ -259,6 +375,11 @@ type FunctionCall struct {
 	Args map[string]interface{} `json:"args"`
 }
 
+type FunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
 type Tool struct {
 	FunctionDeclarations []FunctionDeclaration `json:"function_declarations"`
 }
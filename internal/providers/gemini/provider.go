@@ -2,16 +2,38 @@ package gemini
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/rcliao/teeny-orb/internal/providers"
 )
 
+// DefaultMaxToolIterations caps the model/tool round trips of a single ChatWithTools call
+const DefaultMaxToolIterations = 10
+
+// Default circuit breaker thresholds of a tool loop
+const (
+	DefaultMaxRepeatedToolCalls = 5 // Identical calls in a row
+	DefaultMaxToolErrors        = 3 // Failed calls in a row
+)
+
+// ErrMaxToolIterations is returned when the model keeps requesting tools past the iteration cap
+var ErrMaxToolIterations = errors.New("exceeded maximum tool iterations")
+
+// ErrToolLoopBroken is returned when a tool loop stops making progress,
+// repeating the same call or failing call after call
+var ErrToolLoopBroken = errors.New("tool loop circuit breaker tripped")
+
 // GeminiToolProvider integrates Gemini with tool calling through MCP or direct
 type GeminiToolProvider struct {
-	client       *GeminiClient
-	toolProvider providers.ToolProvider
-	mode         string // "direct" or "mcp"
+	client        *GeminiClient
+	toolProvider  providers.ToolProvider
+	mode          string // "direct" or "mcp"
+	maxIterations int
+	maxRepeated   int // 0 disables the check
+	maxErrors     int // 0 disables the check
 }
 
 // NewGeminiToolProvider creates a new Gemini tool provider
@@ -20,13 +42,33 @@ func NewGeminiToolProvider(apiKey, model, mode string, toolProvider providers.To
 	client.SetToolProvider(toolProvider)
 	
 	return &GeminiToolProvider{
-		client:       client,
-		toolProvider: toolProvider,
-		mode:         mode,
+		client:        client,
+		toolProvider:  toolProvider,
+		mode:          mode,
+		maxIterations: DefaultMaxToolIterations,
+		maxRepeated:   DefaultMaxRepeatedToolCalls,
+		maxErrors:     DefaultMaxToolErrors,
 	}
 }
 
-// ChatWithTools performs a chat request with tool calling capability
+// SetMaxIterations sets how many model turns a tool loop may take before giving up
+func (g *GeminiToolProvider) SetMaxIterations(maxIterations int) {
+	g.maxIterations = maxIterations
+}
+
+// SetCircuitBreaker sets how many identical tool calls in a row a tool loop
+// may make, and after how many failed calls in a row it gives up; 0 disables
+// either check
+func (g *GeminiToolProvider) SetCircuitBreaker(maxRepeatedCalls, maxConsecutiveErrors int) {
+	g.maxRepeated = maxRepeatedCalls
+	g.maxErrors = maxConsecutiveErrors
+}
+
+// ChatWithTools performs a chat request with tool calling capability. Function
+// calls returned by the model are executed through the tool provider and their
+// results fed back until the model produces a final answer. The loop ends
+// with ErrToolLoopBroken when the model keeps repeating a call or calls keep
+// failing, and with ErrMaxToolIterations after too many turns.
 func (g *GeminiToolProvider) ChatWithTools(ctx context.Context, messages []providers.Message) (*providers.ChatResponse, error) {
 	// Get available tools
 	tools := g.toolProvider.ListTools()
@@ -41,75 +83,142 @@ func (g *GeminiToolProvider) ChatWithTools(ctx context.Context, messages []provi
 		}
 	}
 	
-	// Create chat request
-	request := &providers.ChatRequest{
+	// Catch schemas Gemini can't represent before the model sees a mangled version
+	if err := providers.ValidateToolDefinitions("gemini", toolDefs); err != nil {
+		return nil, err
+	}
+	
+	request := g.client.convertToGeminiRequest(&providers.ChatRequest{
 		Messages: messages,
 		Tools:    toolDefs,
 		Model:    g.client.model,
-	}
+	})
 	
-	// Make initial request
-	response, err := g.client.Chat(ctx, request)
-	if err != nil {
-		return nil, fmt.Errorf("Gemini chat failed: %w", err)
-	}
-	
-	// If no tool calls, return response directly
-	if len(response.ToolCalls) == 0 {
-		return response, nil
-	}
+	combined := &providers.ChatResponse{Model: g.client.model}
+	var textParts []string
+	breaker := &toolLoopBreaker{maxRepeated: g.maxRepeated, maxErrors: g.maxErrors}
 	
-	// Execute tool calls
-	toolResults := make([]string, 0, len(response.ToolCalls))
-	for _, toolCall := range response.ToolCalls {
-		result, err := g.toolProvider.CallTool(ctx, toolCall.Name, toolCall.Arguments)
+	for iteration := 0; iteration < g.maxIterations; iteration++ {
+		response, err := g.client.makeAPICall(ctx, request)
 		if err != nil {
-			toolResults = append(toolResults, fmt.Sprintf("Error calling %s: %v", toolCall.Name, err))
-		} else if !result.Success {
-			toolResults = append(toolResults, fmt.Sprintf("Tool %s failed: %s", toolCall.Name, result.Error))
-		} else {
-			toolResults = append(toolResults, result.Output)
+			return nil, fmt.Errorf("Gemini chat failed: %w", err)
+		}
+		if len(response.Candidates) == 0 {
+			return nil, fmt.Errorf("no candidates in Gemini response")
+		}
+		
+		combined.Usage.PromptTokens += response.UsageMetadata.PromptTokenCount
+		combined.Usage.CompletionTokens += response.UsageMetadata.CandidatesTokenCount
+		combined.Usage.TotalTokens += response.UsageMetadata.TotalTokenCount
+		
+		candidate := response.Candidates[0]
+		var calls []*FunctionCall
+		for _, part := range candidate.Content.Parts {
+			if part.Text != "" {
+				textParts = append(textParts, part.Text)
+			}
+			if part.FunctionCall != nil {
+				calls = append(calls, part.FunctionCall)
+			}
+		}
+		
+		// No more function calls means the model has given its final answer
+		if len(calls) == 0 {
+			combined.Content = strings.Join(textParts, "\n\n")
+			return combined, nil
+		}
+		
+		// Echo the model turn back and answer each function call
+		request.Contents = append(request.Contents, Content{Role: "model", Parts: candidate.Content.Parts})
+		
+		responseParts := make([]Part, 0, len(calls))
+		for _, call := range calls {
+			// A call repeated too often isn't run again
+			if err := breaker.beforeCall(call); err != nil {
+				return nil, fmt.Errorf("Gemini tool loop stopped: %w", err)
+			}
+			combined.ToolCalls = append(combined.ToolCalls, providers.ToolCall{
+				ID:        fmt.Sprintf("call_%d", len(combined.ToolCalls)),
+				Name:      call.Name,
+				Arguments: call.Args,
+			})
+			result := g.executeToolCall(ctx, call)
+			if err := breaker.afterCall(call, result); err != nil {
+				return nil, fmt.Errorf("Gemini tool loop stopped: %w", err)
+			}
+			responseParts = append(responseParts, Part{
+				FunctionResponse: &FunctionResponse{
+					Name:     call.Name,
+					Response: result,
+				},
+			})
 		}
+		request.Contents = append(request.Contents, Content{Role: "user", Parts: responseParts})
 	}
 	
-	// Create follow-up message with tool results
-	toolResultMessage := "Tool execution results:\n"
-	for i, result := range toolResults {
-		toolResultMessage += fmt.Sprintf("%d. %s\n", i+1, result)
+	return nil, fmt.Errorf("Gemini tool loop stopped after %d iterations: %w", g.maxIterations, ErrMaxToolIterations)
+}
+
+// toolLoopBreaker watches the calls of one tool loop for signs it is stuck
+type toolLoopBreaker struct {
+	maxRepeated int
+	maxErrors   int
+	lastCall    string
+	repeated    int // Calls in a row identical to lastCall
+	errors      int // Failed calls in a row
+}
+
+// beforeCall counts call against the run of identical calls, failing once
+// running it would exceed maxRepeated
+func (b *toolLoopBreaker) beforeCall(call *FunctionCall) error {
+	// Maps marshal with sorted keys, so equal arguments give equal keys
+	args, _ := json.Marshal(call.Args)
+	key := call.Name + "\x00" + string(args)
+	if key == b.lastCall {
+		b.repeated++
+	} else {
+		b.lastCall, b.repeated = key, 1
 	}
-	
-	// Make follow-up request to get final response
-	followUpMessages := append(messages, 
-		providers.Message{Role: "assistant", Content: response.Content},
-		providers.Message{Role: "user", Content: toolResultMessage},
-	)
-	
-	followUpRequest := &providers.ChatRequest{
-		Messages: followUpMessages,
-		Model:    g.client.model,
+	if b.maxRepeated > 0 && b.repeated > b.maxRepeated {
+		return fmt.Errorf("%w: %s called %d times in a row with the same arguments", ErrToolLoopBroken, call.Name, b.repeated)
 	}
-	
-	finalResponse, err := g.client.Chat(ctx, followUpRequest)
+	return nil
+}
+
+// afterCall counts a failed result against the run of errors, failing once
+// it reaches maxErrors
+func (b *toolLoopBreaker) afterCall(call *FunctionCall, result map[string]interface{}) error {
+	message, failed := result["error"]
+	if !failed {
+		b.errors = 0
+		return nil
+	}
+	b.errors++
+	if b.maxErrors > 0 && b.errors >= b.maxErrors {
+		return fmt.Errorf("%w: %d tool calls in a row failed, the last with: %v", ErrToolLoopBroken, b.errors, message)
+	}
+	return nil
+}
+
+// executeToolCall runs a function call through the tool provider and shapes the
+// outcome as a function response payload
+func (g *GeminiToolProvider) executeToolCall(ctx context.Context, call *FunctionCall) map[string]interface{} {
+	result, err := g.toolProvider.CallTool(ctx, call.Name, call.Args)
 	if err != nil {
-		return nil, fmt.Errorf("Gemini follow-up failed: %w", err)
+		return map[string]interface{}{"error": fmt.Sprintf("Error calling %s: %v", call.Name, err)}
 	}
-	
-	// Combine responses
-	combinedResponse := &providers.ChatResponse{
-		Content: response.Content + "\n\n" + finalResponse.Content,
-		Usage: providers.Usage{
-			PromptTokens:     response.Usage.PromptTokens + finalResponse.Usage.PromptTokens,
-			CompletionTokens: response.Usage.CompletionTokens + finalResponse.Usage.CompletionTokens,
-			TotalTokens:      response.Usage.TotalTokens + finalResponse.Usage.TotalTokens,
-		},
-		Model: finalResponse.Model,
+	if !result.Success {
+		return map[string]interface{}{"error": fmt.Sprintf("Tool %s failed: %s", call.Name, result.Error)}
 	}
-	
-	return combinedResponse, nil
+	return map[string]interface{}{"output": result.Output}
 }
 
 // generateToolSchema creates a JSON schema for a tool
 func (g *GeminiToolProvider) generateToolSchema(tool providers.Tool) map[string]interface{} {
+	if parameterized, ok := tool.(providers.ParameterizedTool); ok {
+		return parameterized.Parameters()
+	}
+	
 	// Basic schema generation based on tool type
 	switch tool.Name() {
 	case "filesystem":
@@ -0,0 +1,267 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rcliao/teeny-orb/internal/providers"
+)
+
+// echoTool returns its "text" argument as output
+type echoTool struct{}
+
+func (t *echoTool) Name() string        { return "echo" }
+func (t *echoTool) Description() string { return "Echo text back" }
+func (t *echoTool) Execute(ctx context.Context, args map[string]interface{}) (*providers.ToolResult, error) {
+	text, _ := args["text"].(string)
+	return &providers.ToolResult{Success: true, Output: text}, nil
+}
+
+// fakeToolProvider serves a fixed set of tools
+type fakeToolProvider struct {
+	tools map[string]providers.Tool
+}
+
+func newFakeToolProvider(tools ...providers.Tool) *fakeToolProvider {
+	p := &fakeToolProvider{tools: make(map[string]providers.Tool)}
+	for _, tool := range tools {
+		p.tools[tool.Name()] = tool
+	}
+	return p
+}
+
+func (p *fakeToolProvider) RegisterTool(tool providers.Tool) error {
+	p.tools[tool.Name()] = tool
+	return nil
+}
+
+func (p *fakeToolProvider) ListTools() []providers.Tool {
+	tools := make([]providers.Tool, 0, len(p.tools))
+	for _, tool := range p.tools {
+		tools = append(tools, tool)
+	}
+	return tools
+}
+
+func (p *fakeToolProvider) CallTool(ctx context.Context, name string, args map[string]interface{}) (*providers.ToolResult, error) {
+	tool, exists := p.tools[name]
+	if !exists {
+		return nil, errors.New("tool not found")
+	}
+	return tool.Execute(ctx, args)
+}
+
+func (p *fakeToolProvider) Close() error { return nil }
+
+func newTestProvider(t *testing.T, handler http.HandlerFunc) *GeminiToolProvider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	provider := NewGeminiToolProvider("test-key", "gemini-test", "direct", newFakeToolProvider(&echoTool{}))
+	provider.GetClient().SetBaseURL(server.URL)
+	provider.GetClient().SetRetryConfig(&RetryConfig{
+		MaxRetries:     3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	})
+	return provider
+}
+
+func writeResponse(t *testing.T, w http.ResponseWriter, parts ...Part) {
+	t.Helper()
+	response := GeminiResponse{
+		Candidates: []Candidate{{Content: Content{Role: "model", Parts: parts}, FinishReason: "STOP"}},
+		UsageMetadata: UsageMetadata{
+			PromptTokenCount:     10,
+			CandidatesTokenCount: 5,
+			TotalTokenCount:      15,
+		},
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		t.Errorf("failed to encode response: %v", err)
+	}
+}
+
+// TestChatWithToolsLoop tests that function calls are executed and fed back to the model
+func TestChatWithToolsLoop(t *testing.T) {
+	var calls int32
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("missing API key header")
+		}
+
+		var request GeminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+
+		if atomic.AddInt32(&calls, 1) == 1 {
+			writeResponse(t, w, Part{FunctionCall: &FunctionCall{Name: "echo", Args: map[string]interface{}{"text": "pong"}}})
+			return
+		}
+
+		// The second turn must carry the tool output back to the model
+		last := request.Contents[len(request.Contents)-1]
+		if len(last.Parts) != 1 || last.Parts[0].FunctionResponse == nil {
+			t.Errorf("expected a function response in the follow-up request, got %+v", last)
+			return
+		}
+		if output := last.Parts[0].FunctionResponse.Response["output"]; output != "pong" {
+			t.Errorf("function response output = %v, expected pong", output)
+		}
+		writeResponse(t, w, Part{Text: "The tool said pong"})
+	})
+
+	response, err := provider.ChatWithTools(context.Background(), []providers.Message{{Role: "user", Content: "ping"}})
+	if err != nil {
+		t.Fatalf("ChatWithTools failed: %v", err)
+	}
+
+	if response.Content != "The tool said pong" {
+		t.Errorf("Content = %q, expected final model answer", response.Content)
+	}
+	if len(response.ToolCalls) != 1 || response.ToolCalls[0].Name != "echo" {
+		t.Errorf("ToolCalls = %+v, expected one echo call", response.ToolCalls)
+	}
+	if response.Usage.TotalTokens != 30 {
+		t.Errorf("TotalTokens = %d, expected usage summed over both turns", response.Usage.TotalTokens)
+	}
+}
+
+// TestChatWithToolsRetriesTransientErrors tests backoff on 429 and 5xx responses
+func TestChatWithToolsRetriesTransientErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int32
+		status      int
+		expectError bool
+	}{
+		{name: "rate limited then succeeds", failures: 2, status: http.StatusTooManyRequests},
+		{name: "server error then succeeds", failures: 1, status: http.StatusServiceUnavailable},
+		{name: "retries exhausted", failures: 10, status: http.StatusInternalServerError, expectError: true},
+		{name: "client error not retried", failures: 1, status: http.StatusBadRequest, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) <= tt.failures {
+					w.WriteHeader(tt.status)
+					fmt.Fprintf(w, `{"error":{"code":%d,"message":"try later"}}`, tt.status)
+					return
+				}
+				writeResponse(t, w, Part{Text: "ok"})
+			})
+
+			_, err := provider.ChatWithTools(context.Background(), []providers.Message{{Role: "user", Content: "hi"}})
+			if tt.expectError {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+					t.Fatalf("expected APIError with status %d, got %v", tt.status, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChatWithTools failed: %v", err)
+			}
+			if calls != tt.failures+1 {
+				t.Errorf("server called %d times, expected %d", calls, tt.failures+1)
+			}
+		})
+	}
+}
+
+// TestChatWithToolsMaxIterations tests that an endless tool loop is cut off
+func TestChatWithToolsMaxIterations(t *testing.T) {
+	var calls int32
+	provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		writeResponse(t, w, Part{FunctionCall: &FunctionCall{Name: "echo", Args: map[string]interface{}{"text": "again"}}})
+	})
+	provider.SetMaxIterations(3)
+
+	_, err := provider.ChatWithTools(context.Background(), []providers.Message{{Role: "user", Content: "loop"}})
+	if !errors.Is(err, ErrMaxToolIterations) {
+		t.Fatalf("expected ErrMaxToolIterations, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("server called %d times, expected 3", calls)
+	}
+}
+
+// countingTool counts its calls and fails when asked to
+type countingTool struct {
+	calls int32
+}
+
+func (t *countingTool) Name() string        { return "count" }
+func (t *countingTool) Description() string { return "Count calls" }
+func (t *countingTool) Execute(ctx context.Context, args map[string]interface{}) (*providers.ToolResult, error) {
+	atomic.AddInt32(&t.calls, 1)
+	if fail, _ := args["fail"].(bool); fail {
+		return &providers.ToolResult{Success: false, Error: "asked to fail"}, nil
+	}
+	return &providers.ToolResult{Success: true, Output: "counted"}, nil
+}
+
+// TestChatWithToolsCircuitBreaker tests that a loop repeating one call, or
+// failing call after call, is broken before the iteration cap
+func TestChatWithToolsCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          func(turn int32) map[string]interface{}
+		expectTurns   int32 // Model requests before the breaker trips
+		expectRuns    int32 // Tool executions before it trips
+		expectMessage string
+	}{
+		{
+			name:          "same call repeated",
+			args:          func(turn int32) map[string]interface{} { return map[string]interface{}{"n": 1} },
+			expectTurns:   4,
+			expectRuns:    3,
+			expectMessage: "count called 4 times in a row with the same arguments",
+		},
+		{
+			name:          "consecutive failures",
+			args:          func(turn int32) map[string]interface{} { return map[string]interface{}{"n": turn, "fail": true} },
+			expectTurns:   2,
+			expectRuns:    2,
+			expectMessage: "2 tool calls in a row failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var turns int32
+			tool := &countingTool{}
+			provider := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				turn := atomic.AddInt32(&turns, 1)
+				writeResponse(t, w, Part{FunctionCall: &FunctionCall{Name: "count", Args: tt.args(turn)}})
+			})
+			provider.toolProvider = newFakeToolProvider(tool)
+			provider.SetCircuitBreaker(3, 2)
+
+			_, err := provider.ChatWithTools(context.Background(), []providers.Message{{Role: "user", Content: "loop"}})
+			if !errors.Is(err, ErrToolLoopBroken) {
+				t.Fatalf("expected ErrToolLoopBroken, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.expectMessage) {
+				t.Errorf("error %q doesn't explain the trip, expected %q", err, tt.expectMessage)
+			}
+			if turns != tt.expectTurns || tool.calls != tt.expectRuns {
+				t.Errorf("%d model turns and %d tool runs, expected %d and %d", turns, tool.calls, tt.expectTurns, tt.expectRuns)
+			}
+		})
+	}
+}
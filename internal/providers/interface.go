@@ -13,6 +13,13 @@ type Tool interface {
 	Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error)
 }
 
+// ParameterizedTool is a Tool that describes its arguments with a JSON schema
+// object, which providers pass to the model instead of guessing one
+type ParameterizedTool interface {
+	Tool
+	Parameters() map[string]interface{}
+}
+
 // ToolResult represents the result of a tool execution
 type ToolResult struct {
 	Success bool                   `json:"success"`
@@ -116,117 +123,6 @@ type ModelInfo struct {
 	SupportsTools bool   `json:"supports_tools"`
 }
 
-// FileSystemTool provides basic file system operations
-type FileSystemTool struct {
-	baseDir string
-}
-
-// NewFileSystemTool creates a new file system tool
-func NewFileSystemTool(baseDir string) *FileSystemTool {
-	return &FileSystemTool{baseDir: baseDir}
-}
-
-// Name returns the tool name
-func (f *FileSystemTool) Name() string {
-	return "filesystem"
-}
-
-// Description returns the tool description
-func (f *FileSystemTool) Description() string {
-	return "Provides file system operations like read, write, and list files"
-}
-
-// Execute performs the file system operation
-func (f *FileSystemTool) Execute(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
-	operation, ok := args["operation"].(string)
-	if !ok {
-		return &ToolResult{
-			Success: false,
-			Error:   "operation parameter is required",
-		}, nil
-	}
-
-	switch operation {
-	case "read":
-		return f.readFile(args)
-	case "write":
-		return f.writeFile(args)
-	case "list":
-		return f.listFiles(args)
-	default:
-		return &ToolResult{
-			Success: false,
-			Error:   "unsupported operation: " + operation,
-		}, nil
-	}
-}
-
-func (f *FileSystemTool) readFile(args map[string]interface{}) (*ToolResult, error) {
-	path, ok := args["path"].(string)
-	if !ok {
-		return &ToolResult{
-			Success: false,
-			Error:   "path parameter is required for read operation",
-		}, nil
-	}
-
-	// In a real implementation, this would read the file
-	// For now, return a placeholder
-	return &ToolResult{
-		Success: true,
-		Data: map[string]interface{}{
-			"path":    path,
-			"content": "// File content would be here",
-		},
-		Output: fmt.Sprintf("Successfully read file: %s", path),
-	}, nil
-}
-
-func (f *FileSystemTool) writeFile(args map[string]interface{}) (*ToolResult, error) {
-	path, ok := args["path"].(string)
-	if !ok {
-		return &ToolResult{
-			Success: false,
-			Error:   "path parameter is required for write operation",
-		}, nil
-	}
-
-	content, ok := args["content"].(string)
-	if !ok {
-		return &ToolResult{
-			Success: false,
-			Error:   "content parameter is required for write operation",
-		}, nil
-	}
-
-	// In a real implementation, this would write the file
-	return &ToolResult{
-		Success: true,
-		Data: map[string]interface{}{
-			"path":    path,
-			"size":    len(content),
-		},
-		Output: fmt.Sprintf("Successfully wrote %d bytes to: %s", len(content), path),
-	}, nil
-}
-
-func (f *FileSystemTool) listFiles(args map[string]interface{}) (*ToolResult, error) {
-	path, ok := args["path"].(string)
-	if !ok {
-		path = "."
-	}
-
-	// In a real implementation, this would list the directory
-	return &ToolResult{
-		Success: true,
-		Data: map[string]interface{}{
-			"path":  path,
-			"files": []string{"main.go", "README.md", "Makefile"},
-		},
-		Output: fmt.Sprintf("Successfully listed directory: %s", path),
-	}, nil
-}
-
 // CommandTool provides command execution capabilities
 type CommandTool struct {
 	allowedCommands []string
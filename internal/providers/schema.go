@@ -0,0 +1,245 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaIssue describes a part of a tool schema that a provider can't represent
+type SchemaIssue struct {
+	Tool    string `json:"tool"`
+	Path    string `json:"path"` // Location within the schema, e.g. "parameters.properties.mode"
+	Message string `json:"message"`
+}
+
+func (i SchemaIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Tool, i.Path, i.Message)
+}
+
+// SchemaValidationError reports every incompatibility found for a provider
+type SchemaValidationError struct {
+	Provider string
+	Issues   []SchemaIssue
+}
+
+func (e *SchemaValidationError) Error() string {
+	messages := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		messages[i] = issue.String()
+	}
+	return fmt.Sprintf("tool schemas not translatable to %s: %s", e.Provider, strings.Join(messages, "; "))
+}
+
+// schemaDialect lists what a provider's function-calling schema supports
+type schemaDialect struct {
+	types             map[string]bool
+	unsupportedKeys   map[string]bool
+	allowTypeUnions   bool // "type": ["string", "null"]
+	enumOnlyOnStrings bool
+	requireObjectRoot bool
+}
+
+var schemaDialects = map[string]*schemaDialect{
+	// Gemini accepts an OpenAPI 3.0 subset
+	"gemini": {
+		types: map[string]bool{
+			"string": true, "number": true, "integer": true, "boolean": true, "array": true, "object": true,
+		},
+		unsupportedKeys: map[string]bool{
+			"$ref": true, "$defs": true, "definitions": true, "$schema": true,
+			"oneOf": true, "anyOf": true, "allOf": true, "not": true,
+			"additionalProperties": true, "patternProperties": true,
+			"const": true, "if": true, "then": true, "else": true,
+		},
+		enumOnlyOnStrings: true,
+		requireObjectRoot: true,
+	},
+	// OpenAI accepts JSON Schema, with an object at the root
+	"openai": {
+		types: map[string]bool{
+			"string": true, "number": true, "integer": true, "boolean": true, "array": true, "object": true, "null": true,
+		},
+		unsupportedKeys: map[string]bool{
+			"if": true, "then": true, "else": true, "patternProperties": true,
+		},
+		allowTypeUnions:   true,
+		requireObjectRoot: true,
+	},
+}
+
+// SupportedSchemaProviders returns the providers schemas can be validated for
+func SupportedSchemaProviders() []string {
+	names := make([]string, 0, len(schemaDialects))
+	for name := range schemaDialects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateToolSchema checks that a tool's parameter schema can be translated
+// to the given provider's function-calling format
+func ValidateToolSchema(provider string, tool ToolDefinition) ([]SchemaIssue, error) {
+	dialect, exists := schemaDialects[provider]
+	if !exists {
+		return nil, fmt.Errorf("unknown schema provider: %s", provider)
+	}
+
+	v := &schemaValidator{tool: tool.Name, dialect: dialect}
+	if tool.Name == "" {
+		v.report("name", "tool name is required")
+	}
+	if tool.Parameters == nil {
+		return v.issues, nil
+	}
+
+	if dialect.requireObjectRoot {
+		if rootType, _ := tool.Parameters["type"].(string); rootType != "object" {
+			v.report("parameters.type", fmt.Sprintf("root schema must be an object, got %v", tool.Parameters["type"]))
+		}
+	}
+	v.validate("parameters", tool.Parameters)
+
+	return v.issues, nil
+}
+
+// ValidateToolDefinitions validates every tool and returns a *SchemaValidationError
+// listing all incompatibilities, or nil if the tools translate cleanly
+func ValidateToolDefinitions(provider string, tools []ToolDefinition) error {
+	var issues []SchemaIssue
+	for _, tool := range tools {
+		toolIssues, err := ValidateToolSchema(provider, tool)
+		if err != nil {
+			return err
+		}
+		issues = append(issues, toolIssues...)
+	}
+
+	if len(issues) > 0 {
+		return &SchemaValidationError{Provider: provider, Issues: issues}
+	}
+	return nil
+}
+
+// schemaValidator walks a schema collecting issues
+type schemaValidator struct {
+	tool    string
+	dialect *schemaDialect
+	issues  []SchemaIssue
+}
+
+func (v *schemaValidator) report(path, message string) {
+	v.issues = append(v.issues, SchemaIssue{Tool: v.tool, Path: path, Message: message})
+}
+
+func (v *schemaValidator) validate(path string, schema map[string]interface{}) {
+	// Visit keys in a stable order so issues are reported deterministically
+	keys := make([]string, 0, len(schema))
+	for key := range schema {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if v.dialect.unsupportedKeys[key] {
+			v.report(path+"."+key, fmt.Sprintf("%q is not supported", key))
+		}
+	}
+
+	schemaType := v.validateType(path, schema["type"])
+
+	if enum, exists := schema["enum"]; exists {
+		if v.dialect.enumOnlyOnStrings && schemaType != "string" {
+			v.report(path+".enum", "enum is only supported on string types")
+		}
+		if len(toSlice(enum)) == 0 {
+			v.report(path+".enum", "enum must be a non-empty list")
+		}
+	}
+
+	properties, hasProperties := schema["properties"]
+	propertyMap, _ := properties.(map[string]interface{})
+	if hasProperties && propertyMap == nil {
+		v.report(path+".properties", "properties must be an object")
+	}
+
+	names := make([]string, 0, len(propertyMap))
+	for name := range propertyMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propertySchema, ok := propertyMap[name].(map[string]interface{})
+		if !ok {
+			v.report(path+".properties."+name, "property schema must be an object")
+			continue
+		}
+		v.validate(path+".properties."+name, propertySchema)
+	}
+
+	if required, exists := schema["required"]; exists {
+		for _, item := range toSlice(required) {
+			name, ok := item.(string)
+			if !ok {
+				v.report(path+".required", fmt.Sprintf("required entry %v is not a string", item))
+				continue
+			}
+			if _, defined := propertyMap[name]; !defined {
+				v.report(path+".required", fmt.Sprintf("required property %q is not defined", name))
+			}
+		}
+	}
+
+	if schemaType == "array" {
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			v.report(path+".items", "array schema must define items")
+		} else {
+			v.validate(path+".items", items)
+		}
+	}
+}
+
+// validateType checks the type keyword and returns the single type name, if any
+func (v *schemaValidator) validateType(path string, value interface{}) string {
+	switch t := value.(type) {
+	case nil:
+		v.report(path+".type", "type is required")
+	case string:
+		if !v.dialect.types[t] {
+			v.report(path+".type", fmt.Sprintf("type %q is not supported", t))
+		}
+		return t
+	default:
+		union := toSlice(value)
+		if union == nil {
+			v.report(path+".type", fmt.Sprintf("type must be a string, got %T", value))
+			return ""
+		}
+		if !v.dialect.allowTypeUnions {
+			v.report(path+".type", "type unions are not supported")
+		}
+		for _, item := range union {
+			if name, ok := item.(string); !ok || !v.dialect.types[name] {
+				v.report(path+".type", fmt.Sprintf("type %v is not supported", item))
+			}
+		}
+	}
+	return ""
+}
+
+// toSlice normalizes []string and []interface{} values from decoded or hand-built schemas
+func toSlice(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package providers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestValidateToolSchema tests provider-specific schema translation checks
+func TestValidateToolSchema(t *testing.T) {
+	valid := ToolDefinition{
+		Name: "filesystem",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"operation": map[string]interface{}{"type": "string", "enum": []string{"read", "write"}},
+				"paths": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "string"},
+				},
+			},
+			"required": []string{"operation"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		provider   string
+		tool       ToolDefinition
+		expectPath string // Empty means no issues expected
+	}{
+		{name: "Valid schema for gemini", provider: "gemini", tool: valid},
+		{name: "Valid schema for openai", provider: "openai", tool: valid},
+		{
+			name:     "oneOf unsupported by gemini",
+			provider: "gemini",
+			tool: ToolDefinition{Name: "search", Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{
+						"type":  "string",
+						"oneOf": []interface{}{map[string]interface{}{"type": "string"}},
+					},
+				},
+			}},
+			expectPath: "parameters.properties.query.oneOf",
+		},
+		{
+			name:     "Nullable type union unsupported by gemini",
+			provider: "gemini",
+			tool: ToolDefinition{Name: "search", Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"limit": map[string]interface{}{"type": []interface{}{"integer", "null"}},
+				},
+			}},
+			expectPath: "parameters.properties.limit.type",
+		},
+		{
+			name:     "Nullable type union accepted by openai",
+			provider: "openai",
+			tool: ToolDefinition{Name: "search", Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"limit": map[string]interface{}{"type": []interface{}{"integer", "null"}},
+				},
+			}},
+		},
+		{
+			name:     "Missing required property",
+			provider: "openai",
+			tool: ToolDefinition{Name: "command", Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"command": map[string]interface{}{"type": "string"}},
+				"required":   []interface{}{"command", "args"},
+			}},
+			expectPath: "parameters.required",
+		},
+		{
+			name:     "Array without items",
+			provider: "gemini",
+			tool: ToolDefinition{Name: "command", Parameters: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"args": map[string]interface{}{"type": "array"}},
+			}},
+			expectPath: "parameters.properties.args.items",
+		},
+		{
+			name:     "Non-object root",
+			provider: "openai",
+			tool: ToolDefinition{Name: "echo", Parameters: map[string]interface{}{
+				"type": "string",
+			}},
+			expectPath: "parameters.type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues, err := ValidateToolSchema(tt.provider, tt.tool)
+			if err != nil {
+				t.Fatalf("ValidateToolSchema failed: %v", err)
+			}
+
+			if tt.expectPath == "" {
+				if len(issues) != 0 {
+					t.Errorf("expected no issues, got %v", issues)
+				}
+				return
+			}
+
+			found := false
+			for _, issue := range issues {
+				if issue.Path == tt.expectPath {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected an issue at %s, got %v", tt.expectPath, issues)
+			}
+		})
+	}
+}
+
+// TestValidateToolDefinitions tests the aggregated error returned before chat calls
+func TestValidateToolDefinitions(t *testing.T) {
+	tools := []ToolDefinition{{
+		Name: "lookup",
+		Parameters: map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": false,
+		},
+	}}
+
+	err := ValidateToolDefinitions("gemini", tools)
+	var schemaErr *SchemaValidationError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("expected SchemaValidationError, got %v", err)
+	}
+	if len(schemaErr.Issues) != 1 || !strings.Contains(err.Error(), "additionalProperties") {
+		t.Errorf("unexpected issues: %v", err)
+	}
+
+	if err := ValidateToolDefinitions("openai", tools); err != nil {
+		t.Errorf("openai should accept additionalProperties, got %v", err)
+	}
+
+	if err := ValidateToolDefinitions("unknown", tools); err == nil {
+		t.Errorf("expected error for unknown provider")
+	}
+}